@@ -15,6 +15,7 @@ type Proposal interface {
 	TsAndCs() string
 	ProducerPolicy() string
 	ConsumerId() string
+	Signature() string
 }
 
 // A concrete Proposal object that implements all the functions of a Proposal interface. This represents the base protocol object for a proposal. Other
@@ -24,6 +25,7 @@ type BaseProposal struct {
 	TsandCs        string `json:"tsandcs"` // This is a JSON serialized policy file, merged between consumer and producer. It has 1 workload array element.
 	Producerpolicy string `json:"producerPolicy"`
 	Consumerid     string `json:"consumerId"`
+	Sig            string `json:"signature,omitempty"` // The consumer's signature of the proposal, see SignProposal.
 }
 
 func NewProposal(name string, version int, tsandcs string, pPol string, agId string, cId string) *BaseProposal {
@@ -67,3 +69,7 @@ func (bp *BaseProposal) ProducerPolicy() string {
 func (bp *BaseProposal) ConsumerId() string {
 	return bp.Consumerid
 }
+
+func (bp *BaseProposal) Signature() string {
+	return bp.Sig
+}
@@ -16,6 +16,8 @@ type ProposalReply interface {
 	DeviceId() string
 	AcceptProposal()
 	DoNotAcceptProposal()
+	Signature() string
+	SetSignature(sig string)
 }
 
 // A concrete ProposalReply object that implements all the functions of a ProposalReply interface. This represents the base protocol
@@ -24,6 +26,7 @@ type BaseProposalReply struct {
 	*BaseProtocolMessage
 	Decision bool   `json:"decision"`
 	Deviceid string `json:"deviceId"`
+	Sig      string `json:"signature,omitempty"` // The producer's signature of the reply, see SignReply.
 }
 
 func (bp *BaseProposalReply) IsValid() bool {
@@ -54,6 +57,14 @@ func (bp *BaseProposalReply) DoNotAcceptProposal() {
 	bp.Decision = false
 }
 
+func (bp *BaseProposalReply) Signature() string {
+	return bp.Sig
+}
+
+func (bp *BaseProposalReply) SetSignature(sig string) {
+	bp.Sig = sig
+}
+
 func NewProposalReply(name string, version int, id string, deviceId string) *BaseProposalReply {
 	return &BaseProposalReply{
 		BaseProtocolMessage: &BaseProtocolMessage{
@@ -1,6 +1,7 @@
 package abstractprotocol
 
 import (
+	"crypto/rsa"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -157,6 +158,9 @@ type ProtocolHandler interface {
 	RecordMeter(agreementId string,
 		mn *metering.MeteringNotification) error
 
+	// Returns the key that proposals and replies are signed with, nil if they are not signed.
+	SigningKey() (*rsa.PrivateKey, error)
+
 	// Protocol message validators
 	ValidateProposal(proposal string) (Proposal, error)
 	ValidateReply(reply string) (ProposalReply, error)
@@ -172,6 +176,7 @@ type BaseProtocolHandler struct {
 	version    int
 	httpClient *http.Client
 	pm         *policy.PolicyManager
+	keySource  SigningKeySource
 }
 
 func (bp *BaseProtocolHandler) Name() string {
@@ -190,6 +195,18 @@ func (bp *BaseProtocolHandler) HTTPClient() *http.Client {
 	return bp.httpClient
 }
 
+// Sign the proposals and replies sent by this protocol handler with the key returned by the key source.
+func (bp *BaseProtocolHandler) SetSigningKeySource(keySource SigningKeySource) {
+	bp.keySource = keySource
+}
+
+func (bp *BaseProtocolHandler) SigningKey() (*rsa.PrivateKey, error) {
+	if bp.keySource == nil {
+		return nil, nil
+	}
+	return bp.keySource()
+}
+
 func NewBaseProtocolHandler(n string, v int, h *http.Client, p *policy.PolicyManager) *BaseProtocolHandler {
 	return &BaseProtocolHandler{
 		name:       n,
@@ -222,7 +239,15 @@ func CreateProposal(p ProtocolHandler,
 		} else if pBytes, err := json.Marshal(producerPolicy); err != nil {
 			return nil, errors.New(fmt.Sprintf("Protocol %v error marshalling producer policy %v, error: %v", p.Name(), *producerPolicy, err))
 		} else {
-			return NewProposal(p.Name(), version, string(tcBytes), string(pBytes), agreementId, myId), nil
+			proposal := NewProposal(p.Name(), version, string(tcBytes), string(pBytes), agreementId, myId)
+			if key, err := p.SigningKey(); err != nil {
+				return nil, errors.New(fmt.Sprintf("Protocol %v error getting the key to sign the proposal, error: %v", p.Name(), err))
+			} else if key != nil {
+				if err := SignProposal(proposal, key); err != nil {
+					return nil, errors.New(fmt.Sprintf("Protocol %v error signing proposal, error: %v", p.Name(), err))
+				}
+			}
+			return proposal, nil
 		}
 	}
 }
@@ -342,6 +367,17 @@ func SendResponse(p ProtocolHandler,
 	messageTarget interface{},
 	sendMessage func(mt interface{}, pay []byte) error) (ProposalReply, error) {
 
+	// Sign the reply once the decision is made. A reply that cannot be signed declines the proposal.
+	if key, err := p.SigningKey(); err != nil {
+		newReply.DoNotAcceptProposal()
+		replyErr = errors.New(fmt.Sprintf("Protocol %v decide on proposal received error getting the key to sign the reply, error: %v", p.Name(), err))
+	} else if key != nil {
+		if err := SignReply(newReply, proposal.Signature(), key); err != nil {
+			newReply.DoNotAcceptProposal()
+			replyErr = errors.New(fmt.Sprintf("Protocol %v decide on proposal received error signing the reply, error: %v", p.Name(), err))
+		}
+	}
+
 	if err := SendProtocolMessage(messageTarget, newReply, sendMessage); err != nil {
 		newReply.DoNotAcceptProposal()
 		replyErr = errors.New(fmt.Sprintf("Protocol %v decide on proposal received error trying to send proposal response, error: %v", p.Name(), err))
@@ -0,0 +1,141 @@
+package abstractprotocol
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/sha3"
+)
+
+// Proposals and proposal replies are signed by their sender with its messaging key. The receiver verifies the signature
+// before it acts on the message, so that a proposal or reply that was changed after it was sent is rejected. The
+// signatures are kept with the agreement on both sides, as a record of what each side sent. The exchange message that
+// carries a protocol message is signed too, but that signature is discarded once the message has been decrypted.
+//
+// A reply signature covers the signature of the proposal it replies to, tying the decision to that proposal. Messages
+// from older agents and agbots are not signed, they are accepted without verification unless the receiver is configured
+// to require signatures (RequireSignedProposals on the node, RequireSignedReplies on the agbot).
+
+// Returns the private key that protocol messages are signed with, or nil when messages are not signed.
+type SigningKeySource func() (*rsa.PrivateKey, error)
+
+// The content of a proposal that is signed.
+type signedProposal struct {
+	Protocol       string `json:"protocol"`
+	Version        int    `json:"version"`
+	AgreementId    string `json:"agreementId"`
+	TsandCs        string `json:"tsandcs"`
+	Producerpolicy string `json:"producerPolicy"`
+	Consumerid     string `json:"consumerId"`
+}
+
+// The content of a proposal reply that is signed.
+type signedReply struct {
+	Protocol          string `json:"protocol"`
+	Version           int    `json:"version"`
+	AgreementId       string `json:"agreementId"`
+	Decision          bool   `json:"decision"`
+	Deviceid          string `json:"deviceId"`
+	ProposalSignature string `json:"proposalSignature"`
+}
+
+func proposalDigest(p Proposal) ([]byte, error) {
+	return digest(signedProposal{
+		Protocol:       p.Protocol(),
+		Version:        p.Version(),
+		AgreementId:    p.AgreementId(),
+		TsandCs:        p.TsAndCs(),
+		Producerpolicy: p.ProducerPolicy(),
+		Consumerid:     p.ConsumerId(),
+	})
+}
+
+func replyDigest(r ProposalReply, proposalSig string) ([]byte, error) {
+	return digest(signedReply{
+		Protocol:          r.Protocol(),
+		Version:           r.Version(),
+		AgreementId:       r.AgreementId(),
+		Decision:          r.ProposalAccepted(),
+		Deviceid:          r.DeviceId(),
+		ProposalSignature: proposalSig,
+	})
+}
+
+func digest(content interface{}) ([]byte, error) {
+	if cBytes, err := json.Marshal(content); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to marshal signed content, error: %v", err))
+	} else {
+		d := sha3.Sum256(cBytes)
+		return d[:], nil
+	}
+}
+
+func sign(digest []byte, key *rsa.PrivateKey) (string, error) {
+	if sig, err := rsa.SignPSS(rand.Reader, key, crypto.SHA3_256, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto}); err != nil {
+		return "", errors.New(fmt.Sprintf("unable to sign, error: %v", err))
+	} else {
+		return base64.StdEncoding.EncodeToString(sig), nil
+	}
+}
+
+func verify(digest []byte, signature string, pubKey *rsa.PublicKey) error {
+	if pubKey == nil {
+		return errors.New(fmt.Sprintf("there is no public key to verify the signature with"))
+	} else if sig, err := base64.StdEncoding.DecodeString(signature); err != nil {
+		return errors.New(fmt.Sprintf("signature is not base64 encoded, error: %v", err))
+	} else if err := rsa.VerifyPSS(pubKey, crypto.SHA3_256, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto}); err != nil {
+		return errors.New(fmt.Sprintf("signature is not valid, error: %v", err))
+	}
+	return nil
+}
+
+// Sign the proposal with the private key of the sender.
+func SignProposal(proposal *BaseProposal, key *rsa.PrivateKey) error {
+	if d, err := proposalDigest(proposal); err != nil {
+		return err
+	} else if sig, err := sign(d, key); err != nil {
+		return err
+	} else {
+		proposal.Sig = sig
+		return nil
+	}
+}
+
+// Verify the signature of the proposal with the public key of the sender. Returns false when the proposal is not signed.
+func VerifyProposalSignature(proposal Proposal, pubKey *rsa.PublicKey) (bool, error) {
+	if proposal.Signature() == "" {
+		return false, nil
+	} else if d, err := proposalDigest(proposal); err != nil {
+		return true, err
+	} else {
+		return true, verify(d, proposal.Signature(), pubKey)
+	}
+}
+
+// Sign the reply to a proposal with the private key of the sender. The proposalSig is the signature of the proposal
+// being replied to, it is empty if the proposal was not signed.
+func SignReply(reply ProposalReply, proposalSig string, key *rsa.PrivateKey) error {
+	if d, err := replyDigest(reply, proposalSig); err != nil {
+		return err
+	} else if sig, err := sign(d, key); err != nil {
+		return err
+	} else {
+		reply.SetSignature(sig)
+		return nil
+	}
+}
+
+// Verify the signature of the reply with the public key of the sender. Returns false when the reply is not signed.
+func VerifyReplySignature(reply ProposalReply, proposalSig string, pubKey *rsa.PublicKey) (bool, error) {
+	if reply.Signature() == "" {
+		return false, nil
+	} else if d, err := replyDigest(reply, proposalSig); err != nil {
+		return true, err
+	} else {
+		return true, verify(d, reply.Signature(), pubKey)
+	}
+}
@@ -0,0 +1,80 @@
+// +build unit
+
+package abstractprotocol
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+)
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate a key, error: %v", err)
+	}
+	return key
+}
+
+func Test_ProposalSignature(t *testing.T) {
+	key := generateKey(t)
+	otherKey := generateKey(t)
+
+	// a proposal from an older agbot is not signed
+	proposal := NewProposal("Basic", 2, `{"header":{"name":"pol"}}`, `{"header":{"name":"node"}}`, "ag1", "myorg/agbot1")
+	if signed, err := VerifyProposalSignature(proposal, &key.PublicKey); signed || err != nil {
+		t.Errorf("an unsigned proposal should be reported as not signed, found %v %v", signed, err)
+	}
+
+	if err := SignProposal(proposal, key); err != nil {
+		t.Fatalf("unable to sign the proposal, error: %v", err)
+	}
+	if signed, err := VerifyProposalSignature(proposal, &key.PublicKey); !signed || err != nil {
+		t.Errorf("the signature of the proposal should be valid, found %v %v", signed, err)
+	}
+	if signed, err := VerifyProposalSignature(proposal, &otherKey.PublicKey); !signed || err == nil {
+		t.Errorf("the signature should not be valid with another key, found %v %v", signed, err)
+	}
+	if _, err := VerifyProposalSignature(proposal, nil); err == nil {
+		t.Errorf("the signature should not be valid without a key")
+	}
+
+	// the terms of the proposal are changed after it was signed
+	proposal.TsandCs = `{"header":{"name":"otherpol"}}`
+	if signed, err := VerifyProposalSignature(proposal, &key.PublicKey); !signed || err == nil {
+		t.Errorf("the signature of a tampered proposal should not be valid, found %v %v", signed, err)
+	}
+
+	proposal.Sig = "not base64!"
+	if _, err := VerifyProposalSignature(proposal, &key.PublicKey); err == nil {
+		t.Errorf("a signature that is not base64 should not be valid")
+	}
+}
+
+func Test_ReplySignature(t *testing.T) {
+	key := generateKey(t)
+
+	reply := NewProposalReply("Basic", 2, "ag1", "myorg/node1")
+	reply.AcceptProposal()
+	if signed, err := VerifyReplySignature(reply, "proposalsig", &key.PublicKey); signed || err != nil {
+		t.Errorf("an unsigned reply should be reported as not signed, found %v %v", signed, err)
+	}
+
+	if err := SignReply(reply, "proposalsig", key); err != nil {
+		t.Fatalf("unable to sign the reply, error: %v", err)
+	}
+	if signed, err := VerifyReplySignature(reply, "proposalsig", &key.PublicKey); !signed || err != nil {
+		t.Errorf("the signature of the reply should be valid, found %v %v", signed, err)
+	}
+
+	// the reply signature covers the signature of the proposal it replies to
+	if _, err := VerifyReplySignature(reply, "otherproposalsig", &key.PublicKey); err == nil {
+		t.Errorf("the reply signature should not be valid for another proposal")
+	}
+
+	// the decision is changed after the reply was signed
+	reply.DoNotAcceptProposal()
+	if signed, err := VerifyReplySignature(reply, "proposalsig", &key.PublicKey); !signed || err == nil {
+		t.Errorf("the signature of a tampered reply should not be valid, found %v %v", signed, err)
+	}
+}
@@ -139,6 +139,12 @@ func (w *AgreementWorker) NewEvent(incoming events.Message) {
 		msg, _ := incoming.(*events.EdgeRegisteredExchangeMessage)
 		w.Commands <- NewDeviceRegisteredCommand(msg)
 
+	case *events.NodeTokenUpdatedMessage:
+		// The exchange context already has the new token.
+		if w.EC != nil {
+			w.limitedRetryEC = newLimitedRetryExchangeContext(w.EC)
+		}
+
 	case *events.PolicyCreatedMessage:
 		msg, _ := incoming.(*events.PolicyCreatedMessage)
 
@@ -578,7 +584,7 @@ func (w *AgreementWorker) syncOnInit() error {
 		// an agreement that is not yet recorded in the exchange (this case is handled later in this function),
 		// but the reverse should not occur normally. Agreements in the exchange must have a record on our local DB.
 		for exchangeAg, _ := range exchangeDeviceAgreements {
-			if agreements, err := persistence.FindEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.IdEAFilter(exchangeAg), persistence.UnarchivedEAFilter()}); err != nil {
+			if agreements, err := persistence.FindActiveEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.IdEAFilter(exchangeAg)}); err != nil {
 				glog.Errorf(logString(fmt.Sprintf("error searching for agreement %v from exchange agreements. %v", exchangeAg, err)))
 			} else if len(agreements) == 0 {
 				glog.V(3).Infof(logString(fmt.Sprintf("found agreement %v in the exchange that is not in our DB.", exchangeAg)))
@@ -593,7 +599,7 @@ func (w *AgreementWorker) syncOnInit() error {
 
 	// Now perform the reverse set of checks, looping through our database and checking each record for accuracy with the exchange
 	// and the blockchain.
-	if agreements, err := persistence.FindEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter()}); err == nil {
+	if agreements, err := persistence.FindActiveEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{}); err == nil {
 
 		neededBCInstances := make(map[string]map[string]map[string]bool)
 
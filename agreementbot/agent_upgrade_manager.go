@@ -0,0 +1,362 @@
+package agreementbot
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/open-horizon/anax/nodemanagement"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The Agent Upgrade manager's job is to roll out the agent upgrade jobs of the node management policies in the orgs
+// that this agbot serves. The nodes a policy applies to are split into waves. The nodes of a wave are scheduled by
+// saving the scheduled status for the policy on each node in the exchange, which tells the agent to run the upgrade.
+// The agents save the outcome of the upgrade in the same place, which is how the manager knows that a wave is done.
+// The state of each node in a rollout is kept in the database, so that a rollout continues where it was when the
+// agbot is restarted, and so that the agbot instances that share a database share the rollout. Only the instance that
+// holds the lease on a rollout in the database moves it forward, so that two instances do not schedule the same wave.
+// The lease is renewed every time the rollout is moved forward, and another instance takes the rollout over when the
+// lease has expired.
+
+// How often the agent upgrade rollouts are moved forward.
+const AGENT_UPGRADE_INTERVAL_S = 60
+
+// How long the lease on a rollout lasts, a few intervals so that a slow exchange does not move the rollout around.
+const AGENT_UPGRADE_LEASE_S = 3 * AGENT_UPGRADE_INTERVAL_S
+
+type AgentUpgradeManager struct {
+	lock sync.RWMutex
+	nmps map[string]exchange.ExchangeNodeManagementPolicy // the agent upgrade policies being rolled out, keyed by org/name
+}
+
+func NewAgentUpgradeManager() *AgentUpgradeManager {
+	return &AgentUpgradeManager{
+		nmps: make(map[string]exchange.ExchangeNodeManagementPolicy),
+	}
+}
+
+// The progress of the rollout of an agent upgrade. The node states are only filled in when they are asked for.
+type AgentUpgradeProgress struct {
+	NMPName     string                     `json:"nmpName"`
+	Version     string                     `json:"version"`
+	Nodes       int                        `json:"nodes"`
+	Waves       int                        `json:"waves"`
+	CurrentWave int                        `json:"currentWave"` // -1 when every node is done
+	Pending     int                        `json:"pending"`
+	Scheduled   int                        `json:"scheduled"`
+	Successful  int                        `json:"successful"`
+	Failed      int                        `json:"failed"`
+	Halted      bool                       `json:"halted"` // too many nodes failed, no more waves are scheduled
+	NodeStates  []persistence.AgentUpgrade `json:"nodeStates,omitempty"`
+}
+
+func (p AgentUpgradeProgress) String() string {
+	return fmt.Sprintf("NMPName: %v, Version: %v, Nodes: %v, Waves: %v, CurrentWave: %v, Pending: %v, Scheduled: %v, Successful: %v, Failed: %v, Halted: %v",
+		p.NMPName, p.Version, p.Nodes, p.Waves, p.CurrentWave, p.Pending, p.Scheduled, p.Successful, p.Failed, p.Halted)
+}
+
+// Compute the progress of a rollout from the states of its nodes. The job is nil when the rollout is not known to this
+// agbot, in which case the rollout is never reported as halted.
+func NewAgentUpgradeProgress(nmpName string, job *exchange.AgentUpgradeJob, upgrades []persistence.AgentUpgrade) *AgentUpgradeProgress {
+	p := &AgentUpgradeProgress{NMPName: nmpName, Nodes: len(upgrades), CurrentWave: -1}
+	if job != nil {
+		p.Version = job.Version
+	}
+
+	for _, au := range upgrades {
+		if p.Version == "" {
+			p.Version = au.Version
+		}
+		if au.Wave+1 > p.Waves {
+			p.Waves = au.Wave + 1
+		}
+		if !au.IsFinished() && (p.CurrentWave == -1 || au.Wave < p.CurrentWave) {
+			p.CurrentWave = au.Wave
+		}
+		switch au.State {
+		case persistence.AU_STATE_PENDING:
+			p.Pending++
+		case persistence.AU_STATE_SCHEDULED:
+			p.Scheduled++
+		case persistence.AU_STATE_SUCCESSFUL:
+			p.Successful++
+		case persistence.AU_STATE_FAILED:
+			p.Failed++
+		}
+	}
+
+	if job != nil && p.Failed != 0 {
+		p.Halted = p.Failed*100 > job.FailureThreshold*(p.Successful+p.Failed)
+	}
+	return p
+}
+
+// Return the indexes of the nodes to schedule now. The next wave is scheduled when every node of the earlier waves is
+// done, the wave interval has passed since the last of them finished, and the rollout is not halted.
+func nextAgentUpgradeWave(job *exchange.AgentUpgradeJob, upgrades []persistence.AgentUpgrade, now uint64) []int {
+	progress := NewAgentUpgradeProgress("", job, upgrades)
+	if progress.Halted || progress.CurrentWave == -1 {
+		return nil
+	}
+
+	lastFinished := uint64(0)
+	toSchedule := make([]int, 0)
+	for ix, au := range upgrades {
+		if au.Wave < progress.CurrentWave && au.FinishedTime > lastFinished {
+			lastFinished = au.FinishedTime
+		} else if au.Wave == progress.CurrentWave {
+			if au.State == persistence.AU_STATE_SCHEDULED {
+				// the wave is still running
+				return nil
+			} else if au.State == persistence.AU_STATE_PENDING {
+				toSchedule = append(toSchedule, ix)
+			}
+		}
+	}
+
+	if lastFinished != 0 && lastFinished+uint64(job.WaveIntervalS) > now {
+		return nil
+	}
+	return toSchedule
+}
+
+// Return the progress of the rollouts, keyed by policy name, or just the one rollout if a name is given.
+func (m *AgentUpgradeManager) GetProgress(db persistence.AgbotDatabase, nmpName string, withNodes bool) (map[string]*AgentUpgradeProgress, error) {
+	upgrades, err := db.FindAgentUpgrades(nmpName)
+	if err != nil {
+		return nil, err
+	}
+
+	byNMP := make(map[string][]persistence.AgentUpgrade)
+	for _, au := range upgrades {
+		byNMP[au.NMPName] = append(byNMP[au.NMPName], au)
+	}
+
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	progress := make(map[string]*AgentUpgradeProgress)
+	for name, states := range byNMP {
+		var job *exchange.AgentUpgradeJob
+		if nmp, ok := m.nmps[name]; ok {
+			job, _ = nmp.Job.GetAgentUpgrade()
+		}
+		progress[name] = NewAgentUpgradeProgress(name, job, states)
+		if withNodes {
+			sort.Slice(states, func(i, j int) bool {
+				return states[i].Wave < states[j].Wave || (states[i].Wave == states[j].Wave && states[i].NodeId < states[j].NodeId)
+			})
+			progress[name].NodeStates = states
+		}
+	}
+	return progress, nil
+}
+
+// Move the rollouts of the agent upgrade policies in the node orgs forward.
+func (m *AgentUpgradeManager) Rollout(db persistence.AgbotDatabase, ec exchange.ExchangeContext, nodeOrgs []string) {
+
+	nmps := make(map[string]exchange.ExchangeNodeManagementPolicy)
+	for _, org := range nodeOrgs {
+		policies, err := exchange.GetNodeManagementPolicies(ec, org)
+		if err != nil {
+			glog.Errorf(AUMlogString(fmt.Sprintf("unable to get the node management policies of org %v, error %v", org, err)))
+			return
+		}
+		for name, nmp := range policies {
+			if nmp.Enabled && nmp.Job.IsScheduledByAgbot() {
+				nmps[name] = nmp
+			}
+		}
+	}
+
+	m.lock.Lock()
+	m.nmps = nmps
+	m.lock.Unlock()
+
+	// Forget the rollouts of the policies that were removed or disabled, only in the orgs this agbot serves because
+	// the other rollouts belong to other agbots.
+	if upgrades, err := db.FindAgentUpgrades(""); err != nil {
+		glog.Errorf(AUMlogString(fmt.Sprintf("unable to read the agent upgrades, error %v", err)))
+		return
+	} else {
+		removed := make(map[string]bool)
+		for _, au := range upgrades {
+			if _, ok := nmps[au.NMPName]; !ok && cutil.SliceContains(nodeOrgs, exchange.GetOrg(au.NMPName)) {
+				removed[au.NMPName] = true
+			}
+		}
+		for name := range removed {
+			glog.V(3).Infof(AUMlogString(fmt.Sprintf("node management policy %v was removed, removing its agent upgrade rollout", name)))
+			if err := db.DeleteAgentUpgrades(name); err != nil {
+				glog.Errorf(AUMlogString(fmt.Sprintf("unable to delete the agent upgrades of %v, error %v", name, err)))
+			}
+		}
+	}
+
+	for name, nmp := range nmps {
+		if leased, err := db.ObtainAgentUpgradeLease(name, AGENT_UPGRADE_LEASE_S); err != nil {
+			glog.Errorf(AUMlogString(fmt.Sprintf("unable to obtain the lease on the agent upgrade rollout of %v, error %v", name, err)))
+			continue
+		} else if !leased {
+			glog.V(5).Infof(AUMlogString(fmt.Sprintf("the agent upgrade rollout of %v is moved forward by another agbot instance", name)))
+			continue
+		}
+		if err := m.rollout(db, ec, name, nmp); err != nil {
+			glog.Errorf(AUMlogString(fmt.Sprintf("unable to roll out the agent upgrade of node management policy %v, error %v", name, err)))
+		}
+	}
+}
+
+// Move the rollout of one agent upgrade policy forward: add the nodes that are new to the rollout, find out which of
+// the scheduled nodes are done, and schedule the next wave when it is its turn.
+func (m *AgentUpgradeManager) rollout(db persistence.AgbotDatabase, ec exchange.ExchangeContext, name string, nmp exchange.ExchangeNodeManagementPolicy) error {
+
+	job, err := nmp.Job.GetAgentUpgrade()
+	if err != nil {
+		return err
+	} else if start, err := nmp.StartTime(); err != nil {
+		return err
+	} else if start.After(time.Now()) {
+		glog.V(5).Infof(AUMlogString(fmt.Sprintf("the agent upgrade of %v starts at %v", name, nmp.Start)))
+		return nil
+	}
+
+	upgrades, err := db.FindAgentUpgrades(name)
+	if err != nil {
+		return err
+	}
+
+	// the rollout starts over when the policy changes
+	for _, au := range upgrades {
+		if au.NMPLastUpdated != nmp.LastUpdated {
+			glog.V(3).Infof(AUMlogString(fmt.Sprintf("node management policy %v changed, restarting its agent upgrade rollout", name)))
+			if err := db.DeleteAgentUpgrades(name); err != nil {
+				return err
+			}
+			upgrades = make([]persistence.AgentUpgrade, 0)
+			break
+		}
+	}
+
+	if upgrades, err = m.addNodes(db, ec, name, nmp, job, upgrades); err != nil {
+		return err
+	}
+
+	now := uint64(time.Now().Unix())
+	for ix := range upgrades {
+		au := &upgrades[ix]
+		if au.State != persistence.AU_STATE_SCHEDULED {
+			continue
+		}
+
+		if status, err := exchange.GetNodeManagementPolicyStatus(ec, au.NodeId, name); err != nil {
+			glog.Errorf(AUMlogString(fmt.Sprintf("unable to get the agent upgrade status of node %v, error %v", au.NodeId, err)))
+			continue
+		} else if status != nil && status.Status == exchange.NMP_STATUS_SUCCESSFUL {
+			au.State = persistence.AU_STATE_SUCCESSFUL
+		} else if status != nil && status.Status == exchange.NMP_STATUS_FAILED {
+			au.State = persistence.AU_STATE_FAILED
+			au.Message = status.ErrorMessage
+		} else if now > au.ScheduledTime+uint64(job.NodeTimeoutS) {
+			au.State = persistence.AU_STATE_FAILED
+			au.Message = fmt.Sprintf("the node did not finish the upgrade within %v seconds", job.NodeTimeoutS)
+		} else {
+			continue
+		}
+
+		glog.V(3).Infof(AUMlogString(fmt.Sprintf("the agent upgrade of node %v for %v is %v", au.NodeId, name, au.State)))
+		au.FinishedTime = now
+		if err := db.SaveAgentUpgrade(au); err != nil {
+			return err
+		}
+	}
+
+	if progress := NewAgentUpgradeProgress(name, job, upgrades); progress.Halted {
+		glog.Warningf(AUMlogString(fmt.Sprintf("the agent upgrade rollout of %v is halted, %v of %v nodes failed", name, progress.Failed, progress.Failed+progress.Successful)))
+		return nil
+	}
+
+	for _, ix := range nextAgentUpgradeWave(job, upgrades, now) {
+		au := &upgrades[ix]
+		if err := exchange.PutNodeManagementPolicyStatus(ec, au.NodeId, name, &exchange.NodeManagementPolicyStatus{Status: exchange.NMP_STATUS_SCHEDULED}); err != nil {
+			glog.Errorf(AUMlogString(fmt.Sprintf("unable to schedule the agent upgrade of node %v, error %v", au.NodeId, err)))
+			continue
+		}
+		glog.V(3).Infof(AUMlogString(fmt.Sprintf("scheduled the agent upgrade of node %v in wave %v of %v", au.NodeId, au.Wave, name)))
+		au.State = persistence.AU_STATE_SCHEDULED
+		au.ScheduledTime = now
+		if err := db.SaveAgentUpgrade(au); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Add the registered nodes of the org that the policy applies to and that are not in the rollout yet. The new nodes
+// are put in the waves after the nodes that are already in the rollout.
+func (m *AgentUpgradeManager) addNodes(db persistence.AgbotDatabase, ec exchange.ExchangeContext, name string, nmp exchange.ExchangeNodeManagementPolicy,
+	job *exchange.AgentUpgradeJob, upgrades []persistence.AgentUpgrade) ([]persistence.AgentUpgrade, error) {
+
+	org := exchange.GetOrg(name)
+	nodes, err := exchange.GetOrgNodes(ec, org)
+	if err != nil {
+		return nil, err
+	}
+
+	tracked := make(map[string]bool)
+	for _, au := range upgrades {
+		tracked[au.NodeId] = true
+	}
+
+	ids := make([]string, 0, len(nodes))
+	for id := range nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		node := nodes[id]
+		if tracked[id] || node.PublicKey == "" {
+			continue
+		}
+
+		var nodePolicy *externalpolicy.ExternalPolicy
+		if node.Pattern == "" {
+			if pol, err := exchange.GetNodePolicy(ec, id); err != nil {
+				glog.Errorf(AUMlogString(fmt.Sprintf("unable to get the policy of node %v, error %v", id, err)))
+				continue
+			} else if pol != nil {
+				extPol := pol.GetExternalPolicy()
+				nodePolicy = &extPol
+			}
+		}
+		if compatible, _ := nodemanagement.IsNMPCompatible(nmp, node.Pattern, org, nodePolicy); !compatible {
+			continue
+		}
+
+		au := persistence.AgentUpgrade{
+			NMPName:        name,
+			NMPLastUpdated: nmp.LastUpdated,
+			NodeId:         id,
+			Version:        job.Version,
+			Wave:           len(upgrades) / job.WaveSize,
+			State:          persistence.AU_STATE_PENDING,
+		}
+		if err := db.SaveAgentUpgrade(&au); err != nil {
+			return nil, err
+		}
+		glog.V(5).Infof(AUMlogString(fmt.Sprintf("added node %v to wave %v of %v", id, au.Wave, name)))
+		upgrades = append(upgrades, au)
+	}
+	return upgrades, nil
+}
+
+// Logging function
+var AUMlogString = func(v interface{}) string {
+	return fmt.Sprintf("Agent Upgrade Manager: %v", v)
+}
@@ -0,0 +1,83 @@
+// +build unit
+
+package agreementbot
+
+import (
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/exchange"
+	"testing"
+)
+
+func Test_NewAgentUpgradeProgress(t *testing.T) {
+
+	job := &exchange.AgentUpgradeJob{Version: "2.30.0", WaveSize: 2, FailureThreshold: 50}
+	upgrades := []persistence.AgentUpgrade{
+		{NodeId: "n1", Wave: 0, State: persistence.AU_STATE_SUCCESSFUL},
+		{NodeId: "n2", Wave: 0, State: persistence.AU_STATE_FAILED},
+		{NodeId: "n3", Wave: 1, State: persistence.AU_STATE_SCHEDULED},
+		{NodeId: "n4", Wave: 1, State: persistence.AU_STATE_PENDING},
+		{NodeId: "n5", Wave: 2, State: persistence.AU_STATE_PENDING},
+	}
+
+	p := NewAgentUpgradeProgress("myorg/upgrade", job, upgrades)
+	if p.Nodes != 5 || p.Waves != 3 || p.CurrentWave != 1 {
+		t.Errorf("wrong nodes, waves or current wave: %v", p)
+	} else if p.Pending != 2 || p.Scheduled != 1 || p.Successful != 1 || p.Failed != 1 {
+		t.Errorf("wrong state counts: %v", p)
+	} else if p.Halted {
+		t.Errorf("half of the nodes failed, which is not above the threshold: %v", p)
+	}
+
+	upgrades[0].State = persistence.AU_STATE_FAILED
+	if p := NewAgentUpgradeProgress("myorg/upgrade", job, upgrades); !p.Halted {
+		t.Errorf("every node that is done failed, the rollout should be halted: %v", p)
+	} else if p := NewAgentUpgradeProgress("myorg/upgrade", nil, upgrades); p.Halted {
+		t.Errorf("a rollout without a job should not be halted: %v", p)
+	}
+
+	for ix := range upgrades {
+		upgrades[ix].State = persistence.AU_STATE_SUCCESSFUL
+	}
+	if p := NewAgentUpgradeProgress("myorg/upgrade", job, upgrades); p.CurrentWave != -1 {
+		t.Errorf("every node is done, there should be no current wave: %v", p)
+	}
+}
+
+func Test_nextAgentUpgradeWave(t *testing.T) {
+
+	job := &exchange.AgentUpgradeJob{Version: "2.30.0", WaveSize: 2, WaveIntervalS: 60, FailureThreshold: 50}
+	upgrades := []persistence.AgentUpgrade{
+		{NodeId: "n1", Wave: 0, State: persistence.AU_STATE_PENDING},
+		{NodeId: "n2", Wave: 0, State: persistence.AU_STATE_PENDING},
+		{NodeId: "n3", Wave: 1, State: persistence.AU_STATE_PENDING},
+	}
+
+	// the first wave is scheduled right away
+	if next := nextAgentUpgradeWave(job, upgrades, 1000); len(next) != 2 || next[0] != 0 || next[1] != 1 {
+		t.Errorf("the first wave should be scheduled, found %v", next)
+	}
+
+	// a wave with scheduled nodes is still running
+	upgrades[0].State = persistence.AU_STATE_SCHEDULED
+	upgrades[1].State = persistence.AU_STATE_SUCCESSFUL
+	upgrades[1].FinishedTime = 1000
+	if next := nextAgentUpgradeWave(job, upgrades, 2000); len(next) != 0 {
+		t.Errorf("no wave should be scheduled while the current wave runs, found %v", next)
+	}
+
+	// the next wave waits for the wave interval
+	upgrades[0].State = persistence.AU_STATE_SUCCESSFUL
+	upgrades[0].FinishedTime = 1100
+	if next := nextAgentUpgradeWave(job, upgrades, 1130); len(next) != 0 {
+		t.Errorf("the next wave should wait for the wave interval, found %v", next)
+	} else if next := nextAgentUpgradeWave(job, upgrades, 1160); len(next) != 1 || next[0] != 2 {
+		t.Errorf("the second wave should be scheduled, found %v", next)
+	}
+
+	// a halted rollout schedules no more waves
+	upgrades[0].State = persistence.AU_STATE_FAILED
+	upgrades[1].State = persistence.AU_STATE_FAILED
+	if next := nextAgentUpgradeWave(job, upgrades, 5000); len(next) != 0 {
+		t.Errorf("a halted rollout should not schedule a wave, found %v", next)
+	}
+}
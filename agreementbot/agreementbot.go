@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/abstractprotocol"
+	"github.com/open-horizon/anax/agreementbot/alerting"
+	"github.com/open-horizon/anax/agreementbot/backup"
 	"github.com/open-horizon/anax/agreementbot/persistence"
 	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/cutil"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/externalpolicy"
@@ -30,6 +33,8 @@ const GOVERN_ARCHIVED_AGREEMENTS = "AgBotGovernArchivedAgreements"
 const POLICY_WATCHER = "AgBotPolicyWatcher"
 const STALE_PARTITIONS = "AgbotStaleDatabasePartition"
 const MESSAGE_KEY_CHECK = "AgbotMessageKeyCheck"
+const DATABASE_BACKUP = "AgbotDatabaseBackup"
+const AGENT_UPGRADE = "AgbotAgentUpgrade"
 
 // Agreement governance timing state. Used in the GovernAgreements subworker.
 type DVState struct {
@@ -40,6 +45,7 @@ type DVState struct {
 //package level variable
 var patternManager *PatternManager
 var businessPolManager *BusinessPolicyManager
+var agentUpgradeManager *AgentUpgradeManager
 
 // must be safely-constructed!!
 type AgreementBotWorker struct {
@@ -76,6 +82,7 @@ func NewAgreementBotWorker(name string, cfg *config.HorizonConfig, db persistenc
 	}
 
 	patternManager = NewPatternManager()
+	agentUpgradeManager = NewAgentUpgradeManager()
 
 	glog.Info("Starting AgreementBot worker")
 	worker.Start(worker, int(cfg.AgreementBot.NewContractIntervalS))
@@ -374,6 +381,21 @@ func (w *AgreementBotWorker) Initialize() bool {
 	w.DispatchSubworker(GOVERN_ARCHIVED_AGREEMENTS, w.GovernArchivedAgreements, 1800, false)
 	//w.DispatchSubworker(GOVERN_BC_NEEDS, w.GovernBlockchainNeeds, 60, false)
 	w.DispatchSubworker(MESSAGE_KEY_CHECK, w.messageKeyCheck, w.BaseWorker.Manager.Config.AgreementBot.MessageKeyCheck, false)
+	w.DispatchSubworker(AGENT_UPGRADE, w.agentUpgrades, AGENT_UPGRADE_INTERVAL_S, false)
+
+	// Start the scheduled backups of the database, if they are configured.
+	if w.Config.AgreementBot.Backup.IsConfigured() {
+		if _, ok := w.db.(backup.Snapshotter); !ok {
+			glog.Errorf(AWlogString(fmt.Sprintf("backups are configured but are not supported for database %v", w.db)))
+		} else {
+			w.DispatchSubworker(DATABASE_BACKUP, w.databaseBackup, w.Config.AgreementBot.Backup.IntervalS, false)
+		}
+	}
+
+	// Start sending alerts for repeated agreement failures, if they are configured.
+	if w.Config.AgreementBot.Alert.IsConfigured() {
+		alerting.NewAlertManager(w.Config.AgreementBot.Alert, w.GetExchangeId(), w.httpClient).Start()
+	}
 
 	if w.Config.AgreementBot.CheckUpdatedPolicyS != 0 {
 		// Use custom subworker APIs for the policy watcher because it is stateful and already does its own time management.
@@ -1277,6 +1299,38 @@ func (w *AgreementBotWorker) databaseHeartBeat() int {
 	return 0
 }
 
+// Backup the database to object storage. This function is called by the database backup subworker.
+func (w *AgreementBotWorker) databaseBackup() int {
+
+	if name, err := backup.Run(w.db, w.Config.AgreementBot.Backup, w.httpClient); err != nil {
+		glog.Errorf(AWlogString(fmt.Sprintf("Error backing up the database, error: %v", err)))
+	} else {
+		glog.V(3).Infof(AWlogString(fmt.Sprintf("backed up the database to %v", name)))
+	}
+
+	return 0
+}
+
+// Move the agent upgrade rollouts in the node orgs this agbot serves forward. This function is called by the agent
+// upgrade subworker.
+func (w *AgreementBotWorker) agentUpgrades() int {
+
+	nodeOrgs := make([]string, 0)
+	for _, sp := range patternManager.GetServedPatterns() {
+		if !cutil.SliceContains(nodeOrgs, sp.NodeOrg) {
+			nodeOrgs = append(nodeOrgs, sp.NodeOrg)
+		}
+	}
+	for _, sp := range businessPolManager.GetServedPolicies() {
+		if !cutil.SliceContains(nodeOrgs, sp.NodeOrg) {
+			nodeOrgs = append(nodeOrgs, sp.NodeOrg)
+		}
+	}
+
+	agentUpgradeManager.Rollout(w.db, w, nodeOrgs)
+	return 0
+}
+
 // Ask the database to check for stale partitions and move them into our partition if one is found.
 func (w *AgreementBotWorker) stalePartitions() int {
 
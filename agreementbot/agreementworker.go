@@ -791,6 +791,9 @@ func (b *BaseAgreementWorker) HandleAgreementReply(cph ConsumerProtocolHandler,
 		} else if pol, err := policy.DemarshalPolicy(proposal.TsAndCs()); err != nil {
 			glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error demarshalling tsandcs policy from pending agreement %v, error: %v", reply.AgreementId(), err)))
 
+		} else if err := verifyReplySignature(reply, proposal, wi, b.config.AgreementBot.RequireSignedReplies, workerId); err != nil {
+			glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("rejecting reply %v for agreement %v, %v", wi.MessageId, reply.AgreementId(), err)))
+
 		} else if err := cph.PersistReply(reply, pol, workerId); err != nil {
 			glog.Errorf(err.Error())
 
@@ -1003,7 +1006,7 @@ func (b *BaseAgreementWorker) HandleWorkloadUpgrade(cph ConsumerProtocolHandler,
 	// grab the agreement id lock, cancel the agreement and delete the workload usage record.
 
 	if wi.AgreementId == "" {
-		if ags, err := b.db.FindAgreements([]persistence.AFilter{persistence.DevPolAFilter(wi.Device, wi.PolicyName)}, cph.Name()); err != nil {
+		if ags, err := b.db.FindAgreementsByDeviceId(wi.Device, cph.Name(), []persistence.AFilter{persistence.DevPolAFilter(wi.Device, wi.PolicyName)}); err != nil {
 			glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error finding agreement for device %v and policyName %v, error: %v", wi.Device, wi.PolicyName, err)))
 		} else if len(ags) == 0 {
 			// If there is no agreement found, is it a problem? We could have caught the system in a state where there is no
@@ -1160,6 +1163,22 @@ func (b *BaseAgreementWorker) DoAsyncCancel(cph ConsumerProtocolHandler, ag *per
 
 }
 
+// Verify the node's signature of the reply before the agreement is recorded. The reply signature covers the signature
+// of the proposal that this agbot sent. Replies from older agents are not signed, they are accepted unless signatures
+// are required.
+func verifyReplySignature(reply abstractprotocol.ProposalReply, proposal abstractprotocol.Proposal, wi *HandleReply, required bool, workerId string) error {
+	if pubKey, err := exchange.DemarshalPublicKey(wi.SenderPubKey); err != nil {
+		return errors.New(fmt.Sprintf("unable to demarshal the node %v public key, error: %v", wi.SenderId, err))
+	} else if signed, err := abstractprotocol.VerifyReplySignature(reply, proposal.Signature(), pubKey); err != nil {
+		return errors.New(fmt.Sprintf("reply signature from node %v is not valid: %v", wi.SenderId, err))
+	} else if !signed && required {
+		return errors.New(fmt.Sprintf("reply from node %v is not signed and signed replies are required", wi.SenderId))
+	} else if !signed {
+		glog.Warningf(BAWlogstring(workerId, fmt.Sprintf("reply for agreement %v from node %v is not signed", reply.AgreementId(), wi.SenderId)))
+	}
+	return nil
+}
+
 var BAWlogstring = func(workerID string, v interface{}) string {
 	return fmt.Sprintf("Base Agreement Worker (%v): %v", workerID, v)
 }
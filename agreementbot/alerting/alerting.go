@@ -0,0 +1,209 @@
+package alerting
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/config"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Alerts for repeated agreement failures. The alert manager watches the agreement changes committed to the agbot
+// database and counts the agreements that are cancelled for each workload. When more than the configured number of
+// cancellations for one workload happen within the window, an alert is posted to the configured webhooks. Each
+// cancelled agreement is counted once, and a workload is not alerted again until its cool-down has passed.
+
+const ALERT_REPEATED_CANCELLATIONS = "repeated_agreement_cancellations"
+
+// The number of alerts that can be waiting to be sent, further alerts are dropped until the queue drains.
+const ALERT_QUEUE_SIZE = 100
+
+// The alert that is posted to the webhook as JSON.
+type Alert struct {
+	Alert         string   `json:"alert"`
+	Agbot         string   `json:"agbot"`
+	Workload      string   `json:"workload"`
+	Count         int      `json:"count"`
+	WindowMinutes int      `json:"window_minutes"`
+	AgreementIds  []string `json:"agreement_ids"`
+	Time          string   `json:"time"`
+}
+
+func (a Alert) String() string {
+	return fmt.Sprintf("Alert: %v, Agbot: %v, Workload: %v, Count: %v, WindowMinutes: %v, AgreementIds: %v, Time: %v",
+		a.Alert, a.Agbot, a.Workload, a.Count, a.WindowMinutes, a.AgreementIds, a.Time)
+}
+
+// The message posted to a Slack compatible incoming webhook.
+func (a Alert) SlackText() string {
+	return fmt.Sprintf("Agbot %v: %v agreements for workload %v were cancelled in the last %v minutes. Agreements: %v",
+		a.Agbot, a.Count, a.Workload, a.WindowMinutes, a.AgreementIds)
+}
+
+type cancellation struct {
+	agreementId string
+	time        time.Time
+}
+
+type AlertManager struct {
+	config     config.AlertConfig
+	agbotId    string
+	httpClient *http.Client
+	lock       sync.Mutex
+	counted    map[string]time.Time      // the cancelled agreements that have been counted, and when
+	cancels    map[string][]cancellation // the cancellations within the window for each workload
+	lastAlert  map[string]time.Time      // when each workload was last alerted
+	queue      chan Alert
+}
+
+func NewAlertManager(cfg config.AlertConfig, agbotId string, httpClient *http.Client) *AlertManager {
+	return &AlertManager{
+		config:     cfg,
+		agbotId:    agbotId,
+		httpClient: httpClient,
+		counted:    make(map[string]time.Time),
+		cancels:    make(map[string][]cancellation),
+		lastAlert:  make(map[string]time.Time),
+		queue:      make(chan Alert, ALERT_QUEUE_SIZE),
+	}
+}
+
+// Start watching the agreement changes and sending alerts.
+func (m *AlertManager) Start() {
+	persistence.WatchAgreements(m.agreementChanged)
+	go m.send()
+	glog.V(3).Infof(alString(fmt.Sprintf("started with config %v", m.config)))
+}
+
+// Called with each committed agreement change, it must not block.
+func (m *AlertManager) agreementChanged(change persistence.AgreementChange) {
+	if change.Agreement == nil || change.Agreement.AgreementTimedout == 0 {
+		return
+	}
+
+	if alert := m.recordCancellation(change.AgreementId, persistence.AgreementWorkload(change.Agreement), time.Now()); alert != nil {
+		select {
+		case m.queue <- *alert:
+		default:
+			glog.Warningf(alString(fmt.Sprintf("alert queue is full, dropping alert %v", alert)))
+		}
+	}
+}
+
+// Count the cancellation of an agreement for a workload, and return the alert to send if the threshold has been crossed.
+// An agreement is counted only once, no matter how many times it is updated while it is being cancelled.
+func (m *AlertManager) recordCancellation(agreementId string, workload string, now time.Time) *Alert {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	window := time.Duration(m.config.GetWindowM()) * time.Minute
+	m.expire(now.Add(-window))
+
+	if _, ok := m.counted[agreementId]; ok {
+		return nil
+	}
+	m.counted[agreementId] = now
+	m.cancels[workload] = append(m.cancels[workload], cancellation{agreementId: agreementId, time: now})
+
+	if len(m.cancels[workload]) <= m.config.CancelThreshold {
+		return nil
+	} else if last, ok := m.lastAlert[workload]; ok && now.Sub(last) < time.Duration(m.config.GetCooldownM())*time.Minute {
+		return nil
+	}
+	m.lastAlert[workload] = now
+
+	ids := make([]string, 0, len(m.cancels[workload]))
+	for _, c := range m.cancels[workload] {
+		ids = append(ids, c.agreementId)
+	}
+	sort.Strings(ids)
+
+	return &Alert{
+		Alert:         ALERT_REPEATED_CANCELLATIONS,
+		Agbot:         m.agbotId,
+		Workload:      workload,
+		Count:         len(ids),
+		WindowMinutes: m.config.GetWindowM(),
+		AgreementIds:  ids,
+		Time:          now.UTC().Format(time.RFC3339),
+	}
+}
+
+// Forget the cancellations that happened before the start of the window. The agreement ids are remembered for the
+// length of the cool-down too, so that an agreement that is updated again while it is being cancelled is not recounted.
+func (m *AlertManager) expire(windowStart time.Time) {
+	for workload, cancels := range m.cancels {
+		i := 0
+		for i < len(cancels) && cancels[i].time.Before(windowStart) {
+			i += 1
+		}
+		if i == len(cancels) {
+			delete(m.cancels, workload)
+		} else {
+			m.cancels[workload] = cancels[i:]
+		}
+	}
+
+	keepSince := windowStart.Add(-time.Duration(m.config.GetCooldownM()) * time.Minute)
+	for id, t := range m.counted {
+		if t.Before(keepSince) {
+			delete(m.counted, id)
+		}
+	}
+	for workload, t := range m.lastAlert {
+		if t.Before(keepSince) {
+			delete(m.lastAlert, workload)
+		}
+	}
+}
+
+func (m *AlertManager) send() {
+	for alert := range m.queue {
+		glog.Warningf(alString(fmt.Sprintf("sending alert %v", alert)))
+		if m.config.WebhookURL != "" {
+			if err := m.post(m.config.WebhookURL, alert); err != nil {
+				glog.Errorf(alString(fmt.Sprintf("unable to send alert to %v, error: %v", config.MaskWebhookURL(m.config.WebhookURL), err)))
+			}
+		}
+		if m.config.SlackWebhookURL != "" {
+			if err := m.post(m.config.SlackWebhookURL, map[string]string{"text": alert.SlackText()}); err != nil {
+				glog.Errorf(alString(fmt.Sprintf("unable to send alert to %v, error: %v", config.MaskWebhookURL(m.config.SlackWebhookURL), err)))
+			}
+		}
+	}
+}
+
+func (m *AlertManager) post(url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to marshal alert %v, error: %v", payload, err))
+	}
+
+	resp, err := m.httpClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// the error of the client contains the url, which is not logged
+		if urlErr, ok := err.(*neturl.Error); ok {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		out, _ := ioutil.ReadAll(resp.Body)
+		return errors.New(fmt.Sprintf("HTTP status %v, response: %v", resp.StatusCode, string(out)))
+	}
+	return nil
+}
+
+var alString = func(v interface{}) string {
+	return fmt.Sprintf("Alerting: %v", v)
+}
@@ -0,0 +1,87 @@
+// +build unit
+
+package alerting
+
+import (
+	"encoding/json"
+	"github.com/open-horizon/anax/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_recordCancellation(t *testing.T) {
+	m := NewAlertManager(config.AlertConfig{WebhookURL: "http://localhost", CancelThreshold: 2, WindowM: 10, CooldownM: 30}, "myorg/agbot", http.DefaultClient)
+	now := time.Date(2020, 10, 20, 12, 0, 0, 0, time.UTC)
+
+	// The threshold is not crossed until the third cancellation within the window.
+	if alert := m.recordCancellation("a1", "myorg/svc_1.0.0_amd64", now); alert != nil {
+		t.Errorf("unexpected alert %v", alert)
+	} else if alert := m.recordCancellation("a2", "myorg/svc_1.0.0_amd64", now.Add(time.Minute)); alert != nil {
+		t.Errorf("unexpected alert %v", alert)
+	}
+
+	// An agreement is counted only once.
+	if alert := m.recordCancellation("a2", "myorg/svc_1.0.0_amd64", now.Add(2*time.Minute)); alert != nil {
+		t.Errorf("unexpected alert for a recounted agreement %v", alert)
+	}
+
+	// Cancellations of other workloads are counted separately.
+	if alert := m.recordCancellation("b1", "myorg/other_1.0.0_amd64", now.Add(2*time.Minute)); alert != nil {
+		t.Errorf("unexpected alert %v", alert)
+	}
+
+	alert := m.recordCancellation("a3", "myorg/svc_1.0.0_amd64", now.Add(3*time.Minute))
+	if alert == nil {
+		t.Fatalf("expected an alert")
+	} else if alert.Count != 3 || alert.Workload != "myorg/svc_1.0.0_amd64" || alert.Agbot != "myorg/agbot" || len(alert.AgreementIds) != 3 {
+		t.Errorf("unexpected alert %v", alert)
+	}
+
+	// The workload is not alerted again during the cool-down.
+	if alert := m.recordCancellation("a4", "myorg/svc_1.0.0_amd64", now.Add(4*time.Minute)); alert != nil {
+		t.Errorf("unexpected alert during the cool-down %v", alert)
+	}
+
+	// After the cool-down the old cancellations are outside the window, so 3 new ones are needed.
+	later := now.Add(40 * time.Minute)
+	for i, id := range []string{"a5", "a6"} {
+		if alert := m.recordCancellation(id, "myorg/svc_1.0.0_amd64", later.Add(time.Duration(i)*time.Minute)); alert != nil {
+			t.Errorf("unexpected alert %v", alert)
+		}
+	}
+	if alert := m.recordCancellation("a7", "myorg/svc_1.0.0_amd64", later.Add(2*time.Minute)); alert == nil {
+		t.Errorf("expected an alert after the cool-down")
+	} else if alert.Count != 3 {
+		t.Errorf("expected 3 cancellations in the window, got %v", alert.Count)
+	}
+}
+
+func Test_send(t *testing.T) {
+	received := make(chan map[string]interface{}, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		received <- payload
+	}))
+	defer server.Close()
+
+	m := NewAlertManager(config.AlertConfig{WebhookURL: server.URL, SlackWebhookURL: server.URL, CancelThreshold: 1}, "myorg/agbot", http.DefaultClient)
+	go m.send()
+	m.queue <- Alert{Alert: ALERT_REPEATED_CANCELLATIONS, Workload: "myorg/svc_1.0.0_amd64", Count: 2}
+	close(m.queue)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case payload := <-received:
+			if _, ok := payload["text"]; ok {
+				continue
+			} else if payload["alert"] != ALERT_REPEATED_CANCELLATIONS || payload["workload"] != "myorg/svc_1.0.0_amd64" {
+				t.Errorf("unexpected alert payload %v", payload)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the alerts")
+		}
+	}
+}
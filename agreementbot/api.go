@@ -8,6 +8,7 @@ import (
 	"github.com/open-horizon/anax/agreementbot/persistence"
 	"github.com/open-horizon/anax/apicommon"
 	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/cutil"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/policy"
@@ -15,6 +16,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -23,6 +25,7 @@ type API struct {
 	worker.Manager // embedded field
 	name           string
 	db             persistence.AgbotDatabase
+	reporting      persistence.AgreementReader // the agreement replica used by reporting queries, or db when there is no replica
 	pm             *policy.PolicyManager
 	bcState        map[string]map[string]apicommon.BlockchainState
 	bcStateLock    sync.Mutex
@@ -32,7 +35,7 @@ type API struct {
 	configFile     string
 }
 
-func NewAPIListener(name string, config *config.HorizonConfig, db persistence.AgbotDatabase, configFile string) *API {
+func NewAPIListener(name string, config *config.HorizonConfig, db persistence.AgbotDatabase, reporting persistence.AgreementReader, configFile string) *API {
 	messages := make(chan events.Message)
 
 	listener := &API{
@@ -43,10 +46,14 @@ func NewAPIListener(name string, config *config.HorizonConfig, db persistence.Ag
 
 		name:       name,
 		db:         db,
+		reporting:  reporting,
 		EC:         worker.NewExchangeContext(config.AgreementBot.ExchangeId, config.AgreementBot.ExchangeToken, config.AgreementBot.ExchangeURL, config.GetAgbotCSSURL(), config.Collaborators.HTTPClientFactory),
 		em:         events.NewEventStateManager(),
 		configFile: configFile,
 	}
+	if reporting == nil {
+		listener.reporting = db
+	}
 
 	listener.listen(config.AgreementBot.APIListen)
 	return listener
@@ -323,17 +330,22 @@ func (a *API) listen(apiListen string) {
 		router := mux.NewRouter()
 
 		router.HandleFunc("/agreement", a.agreement).Methods("GET", "OPTIONS")
+		router.HandleFunc("/agreement/statistics", a.agreementStatistics).Methods("GET", "OPTIONS")
 		router.HandleFunc("/agreement/{id}", a.agreement).Methods("GET", "DELETE", "OPTIONS")
+		router.HandleFunc("/agreement/{id}/dataverification", a.agreementDataVerification).Methods("GET", "OPTIONS")
 		router.HandleFunc("/partition", a.partition).Methods("GET", "OPTIONS")
 		router.HandleFunc("/policy", a.policy).Methods("GET", "OPTIONS")
 		router.HandleFunc("/policy/{org}", a.policy).Methods("GET", "OPTIONS")
 		router.HandleFunc("/policy/{org}/{name}", a.policy).Methods("GET", "OPTIONS")
 		router.HandleFunc("/policy/{name}/upgrade", a.policy).Methods("POST", "OPTIONS")
 		router.HandleFunc("/workloadusage", a.workloadusage).Methods("GET", "OPTIONS")
+		router.HandleFunc("/agentupgrade", a.agentupgrade).Methods("GET", "OPTIONS")
+		router.HandleFunc("/agentupgrade/{org}/{name}", a.agentupgrade).Methods("GET", "OPTIONS")
 		router.HandleFunc("/status", a.status).Methods("GET", "OPTIONS")
 		router.HandleFunc("/health", a.health).Methods("GET", "OPTIONS")
 		router.HandleFunc("/status/workers", a.workerstatus).Methods("GET", "OPTIONS")
 		router.HandleFunc("/node", a.node).Methods("GET", "DELETE", "OPTIONS")
+		router.HandleFunc("/node/{org}/{id}/purge", a.nodePurge).Methods("POST", "OPTIONS")
 		router.HandleFunc("/config", a.config).Methods("GET", "OPTIONS")
 		router.HandleFunc("/cache/servedorg", a.ListServedOrgs).Methods("GET", "OPTIONS")
 		router.HandleFunc("/cache/pattern", a.ListPatterns).Methods("GET", "OPTIONS")
@@ -377,7 +389,7 @@ func (a *API) agreement(w http.ResponseWriter, r *http.Request) {
 			wrap[agreementsKey][activeKey] = []persistence.Agreement{}
 
 			for _, agp := range policy.AllAgreementProtocols() {
-				if ags, err := a.db.FindAgreements([]persistence.AFilter{}, agp); err != nil {
+				if ags, err := a.reporting.FindAgreements([]persistence.AFilter{}, agp); err != nil {
 					glog.Error(APIlogString(fmt.Sprintf("error finding all agreements, error: %v", err)))
 					http.Error(w, "Internal server error", http.StatusInternalServerError)
 					return
@@ -439,6 +451,160 @@ func (a *API) agreement(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// The data verification state and history of an agreement, so that the reason for a cancellation due to lack of data can be seen.
+type AgreementDataVerification struct {
+	AgreementId      string                              `json:"agreement_id"`
+	Enabled          bool                                `json:"enabled"`
+	CheckRate        int                                 `json:"check_rate"`      // seconds between checks
+	NoDataInterval   int                                 `json:"nodata_interval"` // seconds without data before the agreement is cancelled, 0 means the agbot default
+	MissedCount      uint64                              `json:"missed_count"`
+	LastVerified     uint64                              `json:"last_verified"`
+	TerminatedReason uint                                `json:"terminated_reason"`
+	TerminatedDesc   string                              `json:"terminated_description"`
+	History          []persistence.DataVerificationCheck `json:"history"`
+}
+
+func (a *API) agreementDataVerification(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "GET":
+		id := mux.Vars(r)["id"]
+
+		if ag, err := a.reporting.FindSingleAgreementByAgreementIdAllProtocols(id, policy.AllAgreementProtocols(), []persistence.AFilter{}); err != nil {
+			glog.Error(APIlogString(fmt.Sprintf("error finding agreement %v, error: %v", id, err)))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		} else if ag == nil {
+			writeInputErr(w, http.StatusBadRequest, &APIUserInputError{Input: "id", Error: "agreement id not found"})
+		} else {
+			dv := AgreementDataVerification{
+				AgreementId:      ag.CurrentAgreementId,
+				Enabled:          !ag.DisableDataVerificationChecks,
+				CheckRate:        ag.DataVerificationCheckRate,
+				NoDataInterval:   ag.DataVerificationNoDataInterval,
+				MissedCount:      ag.DataVerificationMissedCount,
+				LastVerified:     ag.DataVerifiedTime,
+				TerminatedReason: ag.TerminatedReason,
+				TerminatedDesc:   ag.TerminatedDescription,
+				History:          ag.DataVerificationHistory,
+			}
+			if dv.History == nil {
+				dv.History = []persistence.DataVerificationCheck{}
+			}
+			writeResponse(w, dv, http.StatusOK)
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Return agreement counts and rates grouped by state, protocol, org or workload. The time window is given by since and until
+// (in seconds since the epoch), or by window (the number of seconds before now). The default window is all time.
+func (a *API) agreementStatistics(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "GET":
+		groupBy := r.URL.Query().Get("group_by")
+		if groupBy != "" && !persistence.ValidStatisticsGroupBy(groupBy) {
+			writeInputErr(w, http.StatusBadRequest, &APIUserInputError{Input: "group_by", Error: fmt.Sprintf("must be one of %v, %v, %v or %v", persistence.STATS_GROUP_BY_STATE, persistence.STATS_GROUP_BY_PROTOCOL, persistence.STATS_GROUP_BY_ORG, persistence.STATS_GROUP_BY_WORKLOAD)})
+			return
+		}
+
+		// Parse the time window parameters
+		times := make(map[string]uint64)
+		for _, param := range []string{"since", "until", "window"} {
+			if value := r.URL.Query().Get(param); value != "" {
+				if t, err := strconv.ParseUint(value, 10, 64); err != nil {
+					writeInputErr(w, http.StatusBadRequest, &APIUserInputError{Input: param, Error: "must be a number of seconds"})
+					return
+				} else {
+					times[param] = t
+				}
+			}
+		}
+
+		since, until := times["since"], times["until"]
+		if window, ok := times["window"]; ok {
+			if since != 0 {
+				writeInputErr(w, http.StatusBadRequest, &APIUserInputError{Input: "window", Error: "can not be used with since"})
+				return
+			}
+			if until == 0 {
+				until = uint64(time.Now().Unix())
+			}
+			if window < until {
+				since = until - window
+			}
+		}
+		if until != 0 && since > until {
+			writeInputErr(w, http.StatusBadRequest, &APIUserInputError{Input: "since", Error: "must not be after until"})
+			return
+		}
+
+		if stats, err := a.reporting.GetAgreementStatistics(groupBy, since, until); err != nil {
+			glog.Error(APIlogString(fmt.Sprintf("error getting agreement statistics, error: %v", err)))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		} else {
+			writeResponse(w, stats, http.StatusOK)
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Remove the archived agreements and the workload usages this agbot has for a node, and return a report of what was
+// removed, signed with the agbot's messaging key. The node must not have an active agreement with this agbot.
+func (a *API) nodePurge(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "POST":
+		deviceId := fmt.Sprintf("%v/%v", mux.Vars(r)["org"], mux.Vars(r)["id"])
+
+		for _, protocol := range policy.AllAgreementProtocols() {
+			if ags, err := a.db.FindAgreementsByDeviceId(deviceId, protocol, []persistence.AFilter{persistence.UnarchivedAFilter()}); err != nil {
+				glog.Error(APIlogString(fmt.Sprintf("error finding agreements of node %v, error: %v", deviceId, err)))
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			} else if len(ags) != 0 {
+				writeInputErr(w, http.StatusConflict, &APIUserInputError{Input: "id", Error: fmt.Sprintf("the node has %v active agreements, they must be cancelled before the node data can be purged", len(ags))})
+				return
+			}
+		}
+
+		// Get the signing key before anything is removed, a report that can not be signed is of no use.
+		_, privKey, err := exchange.GetKeys(a.Config.AgreementBot.MessageKeyPath)
+		if err != nil {
+			glog.Error(APIlogString(fmt.Sprintf("error getting the messaging key to sign the deletion report, error: %v", err)))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		report := cutil.NewDeletionReport(deviceId, a.Config.AgreementBot.ExchangeId, uint64(time.Now().Unix()))
+		if err := persistence.PurgeDeviceRecords(a.db, deviceId, policy.AllAgreementProtocols(), report); err != nil {
+			glog.Error(APIlogString(fmt.Sprintf("error purging the records of node %v, error: %v", deviceId, err)))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		} else if err := report.Sign(privKey); err != nil {
+			glog.Error(APIlogString(fmt.Sprintf("error signing the deletion report %v, error: %v", report, err)))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		} else {
+			writeResponse(w, report, http.StatusOK)
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "POST, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
 func (a *API) policy(w http.ResponseWriter, r *http.Request) {
 
 	serviceResolver := func(wURL string, wOrg string, wVersion string, wArch string) (*policy.APISpecList, error) {
@@ -611,6 +777,42 @@ func (a *API) workloadusage(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// The progress of the agent upgrade rollouts, with the state of each node when long is set.
+func (a *API) agentupgrade(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "GET":
+		pathVars := mux.Vars(r)
+		nmpName := ""
+		if pathVars["org"] != "" {
+			nmpName = fmt.Sprintf("%v/%v", pathVars["org"], pathVars["name"])
+		}
+		long := r.URL.Query().Get("long") != ""
+
+		manager := agentUpgradeManager
+		if manager == nil {
+			manager = NewAgentUpgradeManager()
+		}
+
+		if progress, err := manager.GetProgress(a.db, nmpName, long); err != nil {
+			glog.Error(APIlogString(fmt.Sprintf("error finding the agent upgrades, error: %v", err)))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		} else if nmpName == "" {
+			writeResponse(w, progress, http.StatusOK)
+		} else if p, ok := progress[nmpName]; !ok {
+			writeResponse(w, fmt.Sprintf("no agent upgrade rollout found for %v", nmpName), http.StatusNotFound)
+		} else {
+			writeResponse(w, p, http.StatusOK)
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
 func (a *API) status(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "GET":
@@ -0,0 +1,282 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	agbotbolt "github.com/open-horizon/anax/agreementbot/persistence/bolt"
+	"github.com/open-horizon/anax/config"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Scheduled backups of the agbot's bolt database. Each backup is a consistent snapshot of the database file, encrypted
+// with AES-256-GCM and uploaded to S3 compatible object storage. The backup object names contain the time of the
+// backup, so that they sort from oldest to newest.
+
+const BACKUP_OBJECT_PREFIX = "agreementbot-"
+const BACKUP_OBJECT_SUFFIX = ".db.enc"
+const BACKUP_TIME_FORMAT = "20060102T150405Z"
+
+// The first bytes of every encrypted backup, used to identify the format.
+var backupMagic = []byte("HZNAGBK1")
+
+// The database implementations that can be backed up.
+type Snapshotter interface {
+	Snapshot(w io.Writer) (int64, error)
+}
+
+func BackupName(prefix string, t time.Time) string {
+	return prefix + BACKUP_OBJECT_PREFIX + t.UTC().Format(BACKUP_TIME_FORMAT) + BACKUP_OBJECT_SUFFIX
+}
+
+// Returns the time of the backup, and false if the object is not a backup made with this prefix.
+func backupTime(prefix string, name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, prefix+BACKUP_OBJECT_PREFIX) || !strings.HasSuffix(name, BACKUP_OBJECT_SUFFIX) {
+		return time.Time{}, false
+	}
+	ts := strings.TrimSuffix(strings.TrimPrefix(name, prefix+BACKUP_OBJECT_PREFIX), BACKUP_OBJECT_SUFFIX)
+	if t, err := time.Parse(BACKUP_TIME_FORMAT, ts); err != nil {
+		return time.Time{}, false
+	} else {
+		return t, true
+	}
+}
+
+// The AES key file contains either the 32 raw bytes of the key, or the key hex encoded.
+func ReadEncryptionKey(keyFile string) ([]byte, error) {
+	if keyFile == "" {
+		return nil, errors.New("an encryption key file must be configured for agbot backups")
+	}
+
+	content, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to read backup encryption key file %v, error: %v", keyFile, err))
+	}
+
+	if len(content) == 32 {
+		return content, nil
+	} else if key, err := hex.DecodeString(strings.TrimSpace(string(content))); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	return nil, errors.New(fmt.Sprintf("backup encryption key file %v must contain a 32 byte key, raw or hex encoded", keyFile))
+}
+
+func Encrypt(key []byte, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to generate nonce, error: %v", err))
+	}
+
+	out := append([]byte{}, backupMagic...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plaintext, backupMagic), nil
+}
+
+func Decrypt(key []byte, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(ciphertext, backupMagic) {
+		return nil, errors.New("the backup is not an encrypted agbot backup")
+	}
+	ciphertext = ciphertext[len(backupMagic):]
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("the encrypted backup is truncated")
+	}
+
+	plaintext, err := gcm.Open(nil, ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():], backupMagic)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to decrypt the backup, it is corrupted or was encrypted with a different key, error: %v", err))
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to create backup cipher, error: %v", err))
+	}
+	return cipher.NewGCM(block)
+}
+
+// Snapshot the database, encrypt the snapshot and upload it, then delete the backups that are no longer retained.
+// Returns the name of the backup object.
+func Run(db persistence.AgbotDatabase, cfg config.BackupConfig, httpClient *http.Client) (string, error) {
+	snapshotter, ok := db.(Snapshotter)
+	if !ok {
+		return "", errors.New(fmt.Sprintf("backups are not supported for database %v", db))
+	}
+
+	key, err := ReadEncryptionKey(cfg.EncryptionKeyFile)
+	if err != nil {
+		return "", err
+	}
+
+	store, err := NewObjectStore(cfg, httpClient)
+	if err != nil {
+		return "", err
+	}
+
+	snapshot := new(bytes.Buffer)
+	if _, err := snapshotter.Snapshot(snapshot); err != nil {
+		return "", errors.New(fmt.Sprintf("unable to snapshot the database, error: %v", err))
+	}
+
+	encrypted, err := Encrypt(key, snapshot.Bytes())
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	name := BackupName(cfg.Prefix, now)
+	if err := store.PutObject(name, encrypted); err != nil {
+		return "", err
+	}
+	glog.V(3).Infof("Uploaded agbot backup %v, %v bytes, to %v", name, len(encrypted), store)
+
+	if deleted, err := applyRetention(store, cfg, now); err != nil {
+		glog.Errorf("Unable to delete the expired agbot backups in %v, error: %v", store, err)
+	} else if len(deleted) != 0 {
+		glog.V(3).Infof("Deleted expired agbot backups %v", deleted)
+	}
+
+	return name, nil
+}
+
+// Return the names of the backups with the prefix, oldest first.
+func listBackups(store *ObjectStore, prefix string) ([]string, error) {
+	objects, err := store.ListObjects(prefix + BACKUP_OBJECT_PREFIX)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		if _, ok := backupTime(prefix, obj.Key); ok {
+			names = append(names, obj.Key)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Return the backups that are not retained. The newest RetentionCount backups are always retained, the others are
+// retained until they are older than RetentionDays.
+func expiredBackups(names []string, cfg config.BackupConfig, now time.Time) []string {
+	if cfg.RetentionCount == 0 && cfg.RetentionDays == 0 {
+		return []string{}
+	}
+
+	expired := make([]string, 0)
+	for i := 0; i < len(names)-cfg.RetentionCount; i++ {
+		if t, ok := backupTime(cfg.Prefix, names[i]); !ok {
+			continue
+		} else if cfg.RetentionDays == 0 || now.Sub(t) > time.Duration(cfg.RetentionDays)*24*time.Hour {
+			expired = append(expired, names[i])
+		}
+	}
+	return expired
+}
+
+func applyRetention(store *ObjectStore, cfg config.BackupConfig, now time.Time) ([]string, error) {
+	names, err := listBackups(store, cfg.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	expired := expiredBackups(names, cfg, now)
+	for _, name := range expired {
+		if err := store.DeleteObject(name); err != nil {
+			return nil, err
+		}
+	}
+	return expired, nil
+}
+
+// Download and decrypt the backup, check that it is a valid agbot database and then swap it in for the agbot's bolt
+// database. The current database file is kept next to it with a .pre-restore suffix. The agbot must not be running.
+// An empty name or "latest" restores the newest backup. Returns the name of the backup that was restored.
+func Restore(cfg *config.HorizonConfig, httpClient *http.Client, name string) (string, error) {
+	backupCfg := cfg.AgreementBot.Backup
+	if !cfg.IsBoltDBConfigured() {
+		return "", errors.New("backups can only be restored to a bolt database, DBPath is not configured")
+	}
+
+	key, err := ReadEncryptionKey(backupCfg.EncryptionKeyFile)
+	if err != nil {
+		return "", err
+	}
+
+	store, err := NewObjectStore(backupCfg, httpClient)
+	if err != nil {
+		return "", err
+	}
+
+	if name == "" || name == "latest" {
+		if names, err := listBackups(store, backupCfg.Prefix); err != nil {
+			return "", err
+		} else if len(names) == 0 {
+			return "", errors.New(fmt.Sprintf("there are no agbot backups with prefix '%v' in %v", backupCfg.Prefix, store))
+		} else {
+			name = names[len(names)-1]
+		}
+	}
+
+	encrypted, err := store.GetObject(name)
+	if err != nil {
+		return "", err
+	}
+	snapshot, err := Decrypt(key, encrypted)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(cfg.AgreementBot.DBPath, 0700); err != nil {
+		return "", errors.New(fmt.Sprintf("unable to create directory %v, error: %v", cfg.AgreementBot.DBPath, err))
+	}
+
+	dbFile := path.Join(cfg.AgreementBot.DBPath, agbotbolt.BOLTDB_DATABASE_NAME)
+	restoreFile := dbFile + ".restore"
+	if err := ioutil.WriteFile(restoreFile, snapshot, 0600); err != nil {
+		return "", errors.New(fmt.Sprintf("unable to write %v, error: %v", restoreFile, err))
+	}
+
+	// Nothing is swapped in unless the snapshot is valid.
+	if count, err := agbotbolt.ValidateSnapshot(restoreFile, cfg.AgreementBot.DBBucketPrefix); err != nil {
+		os.Remove(restoreFile)
+		return "", err
+	} else {
+		glog.V(3).Infof("Backup %v is valid and contains %v agreements", name, count)
+	}
+
+	if _, err := os.Stat(dbFile); err == nil {
+		if err := os.Rename(dbFile, dbFile+".pre-restore"); err != nil {
+			return "", errors.New(fmt.Sprintf("unable to move the current database %v aside, error: %v", dbFile, err))
+		}
+	}
+	if err := os.Rename(restoreFile, dbFile); err != nil {
+		return "", errors.New(fmt.Sprintf("unable to move %v to %v, error: %v", restoreFile, dbFile, err))
+	}
+
+	return name, nil
+}
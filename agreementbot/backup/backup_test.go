@@ -0,0 +1,227 @@
+// +build unit
+
+package backup
+
+import (
+	"encoding/xml"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	agbotbolt "github.com/open-horizon/anax/agreementbot/persistence/bolt"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/policy"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_encrypt_decrypt(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	plaintext := []byte("agbot database snapshot")
+
+	if ciphertext, err := Encrypt(key, plaintext); err != nil {
+		t.Errorf("unexpected error encrypting: %v", err)
+	} else if decrypted, err := Decrypt(key, ciphertext); err != nil {
+		t.Errorf("unexpected error decrypting: %v", err)
+	} else if string(decrypted) != string(plaintext) {
+		t.Errorf("decrypted %v, expected %v", string(decrypted), string(plaintext))
+	} else if _, err := Decrypt([]byte("fedcba9876543210fedcba9876543210"), ciphertext); err == nil {
+		t.Errorf("expected an error decrypting with the wrong key")
+	} else if _, err := Decrypt(key, plaintext); err == nil {
+		t.Errorf("expected an error decrypting an unencrypted backup")
+	}
+}
+
+func Test_read_encryption_key(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agbotbackup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	hexKey := path.Join(dir, "hex.key")
+	ioutil.WriteFile(hexKey, []byte(strings.Repeat("ab", 32)+"\n"), 0600)
+	shortKey := path.Join(dir, "short.key")
+	ioutil.WriteFile(shortKey, []byte("tooshort"), 0600)
+
+	if key, err := ReadEncryptionKey(hexKey); err != nil {
+		t.Errorf("unexpected error reading hex key: %v", err)
+	} else if len(key) != 32 {
+		t.Errorf("expected a 32 byte key, got %v bytes", len(key))
+	} else if _, err := ReadEncryptionKey(shortKey); err == nil {
+		t.Errorf("expected an error reading a short key")
+	}
+}
+
+func Test_expired_backups(t *testing.T) {
+	now := time.Date(2020, 10, 20, 12, 0, 0, 0, time.UTC)
+	names := []string{
+		BackupName("p/", now.AddDate(0, 0, -10)),
+		BackupName("p/", now.AddDate(0, 0, -5)),
+		BackupName("p/", now.AddDate(0, 0, -1)),
+		BackupName("p/", now),
+	}
+
+	if expired := expiredBackups(names, config.BackupConfig{Prefix: "p/"}, now); len(expired) != 0 {
+		t.Errorf("expected no expired backups without retention rules, got %v", expired)
+	}
+	if expired := expiredBackups(names, config.BackupConfig{Prefix: "p/", RetentionCount: 2}, now); len(expired) != 2 || expired[0] != names[0] || expired[1] != names[1] {
+		t.Errorf("expected the 2 oldest backups to expire, got %v", expired)
+	}
+	if expired := expiredBackups(names, config.BackupConfig{Prefix: "p/", RetentionDays: 7}, now); len(expired) != 1 || expired[0] != names[0] {
+		t.Errorf("expected the backup older than 7 days to expire, got %v", expired)
+	}
+	if expired := expiredBackups(names, config.BackupConfig{Prefix: "p/", RetentionCount: 4, RetentionDays: 1}, now); len(expired) != 0 {
+		t.Errorf("expected the 4 newest backups to be retained, got %v", expired)
+	}
+}
+
+func Test_backup_and_restore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agbotbackup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyFile := path.Join(dir, "backup.key")
+	ioutil.WriteFile(keyFile, []byte(strings.Repeat("01", 32)), 0600)
+
+	server := newFakeObjectStore()
+	defer server.Close()
+
+	cfg := &config.HorizonConfig{
+		AgreementBot: config.AGConfig{
+			DBPath: path.Join(dir, "db"),
+			Backup: config.BackupConfig{
+				IntervalS:         60,
+				Endpoint:          server.URL,
+				Bucket:            "backups",
+				Prefix:            "agbot1/",
+				AccessKeyId:       "id",
+				SecretAccessKey:   "secret",
+				EncryptionKeyFile: keyFile,
+				RetentionCount:    1,
+			},
+		},
+	}
+
+	db := new(agbotbolt.AgbotBoltDB)
+	if err := db.Initialize(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AgreementAttempt("a1", "myorg", "myorg/d1", "device", "pol", "", "", "", policy.BasicProtocol, "", []string{"svc"}, policy.NodeHealth{}); err != nil {
+		t.Fatal(err)
+	}
+
+	name, err := Run(db, cfg.AgreementBot.Backup, http.DefaultClient)
+	if err != nil {
+		t.Fatalf("unexpected error backing up: %v", err)
+	} else if !strings.HasPrefix(name, "agbot1/"+BACKUP_OBJECT_PREFIX) {
+		t.Errorf("unexpected backup name %v", name)
+	}
+
+	// An older backup is deleted by the retention rules on the next backup.
+	server.put(BackupName("agbot1/", time.Now().AddDate(0, 0, -1)), []byte("old"))
+	time.Sleep(1100 * time.Millisecond)
+	if _, err := Run(db, cfg.AgreementBot.Backup, http.DefaultClient); err != nil {
+		t.Fatalf("unexpected error backing up: %v", err)
+	} else if keys := server.keys(); len(keys) != 1 {
+		t.Errorf("expected 1 retained backup, got %v", keys)
+	}
+
+	db.DeleteAgreement("a1", policy.BasicProtocol)
+	db.Close()
+
+	if restored, err := Restore(cfg, http.DefaultClient, "latest"); err != nil {
+		t.Fatalf("unexpected error restoring: %v", err)
+	} else if restored != server.keys()[0] {
+		t.Errorf("restored %v, expected the latest backup %v", restored, server.keys()[0])
+	}
+
+	restoredDB := new(agbotbolt.AgbotBoltDB)
+	if err := restoredDB.Initialize(cfg); err != nil {
+		t.Fatal(err)
+	}
+	defer restoredDB.Close()
+	if ag, err := restoredDB.FindSingleAgreementByAgreementId("a1", policy.BasicProtocol, []persistence.AFilter{}); err != nil {
+		t.Errorf("unexpected error finding the restored agreement: %v", err)
+	} else if ag == nil {
+		t.Errorf("expected the agreement to be restored")
+	}
+
+	// A corrupted backup is not swapped in.
+	server.put(BackupName("agbot1/", time.Now().Add(time.Hour)), []byte("corrupted"))
+	if _, err := Restore(cfg, http.DefaultClient, ""); err == nil {
+		t.Errorf("expected an error restoring a corrupted backup")
+	}
+}
+
+// A fake S3 compatible object store that keeps the objects in memory. It does not check the request signatures.
+type fakeObjectStore struct {
+	*httptest.Server
+	objects map[string][]byte
+	lock    sync.Mutex
+}
+
+func newFakeObjectStore() *fakeObjectStore {
+	f := &fakeObjectStore{objects: make(map[string][]byte)}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	return f
+}
+
+func (f *fakeObjectStore) put(key string, body []byte) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.objects[key] = body
+}
+
+func (f *fakeObjectStore) keys() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	keys := make([]string, 0, len(f.objects))
+	for k := range f.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (f *fakeObjectStore) handle(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=id/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/backups"), "/")
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		result := listBucketResult{}
+		for k, v := range f.objects {
+			if strings.HasPrefix(k, r.URL.Query().Get("prefix")) {
+				result.Contents = append(result.Contents, ObjectInfo{Key: k, Size: int64(len(v))})
+			}
+		}
+		out, _ := xml.Marshal(result)
+		w.Write(out)
+	case r.Method == http.MethodGet:
+		if body, ok := f.objects[key]; ok {
+			w.Write(body)
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case r.Method == http.MethodPut:
+		body, _ := ioutil.ReadAll(r.Body)
+		f.objects[key] = body
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,220 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/open-horizon/anax/config"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A minimal client for S3 compatible object storage. Only the operations needed by the backups are implemented, the
+// requests are signed with AWS signature version 4, using path style URLs so that any S3 compatible service works.
+
+type ObjectInfo struct {
+	Key          string    `xml:"Key"`
+	LastModified time.Time `xml:"LastModified"`
+	Size         int64     `xml:"Size"`
+}
+
+type listBucketResult struct {
+	Contents              []ObjectInfo `xml:"Contents"`
+	IsTruncated           bool         `xml:"IsTruncated"`
+	NextContinuationToken string       `xml:"NextContinuationToken"`
+}
+
+type ObjectStore struct {
+	endpoint   *url.URL
+	region     string
+	bucket     string
+	accessKey  string
+	secretKey  string
+	httpClient *http.Client
+}
+
+func NewObjectStore(cfg config.BackupConfig, httpClient *http.Client) (*ObjectStore, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("the object storage endpoint and bucket must be configured for agbot backups")
+	}
+
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to parse object storage endpoint %v, error: %v", cfg.Endpoint, err))
+	}
+
+	return &ObjectStore{
+		endpoint:   endpoint,
+		region:     cfg.GetRegion(),
+		bucket:     cfg.Bucket,
+		accessKey:  cfg.AccessKeyId,
+		secretKey:  cfg.SecretAccessKey,
+		httpClient: httpClient,
+	}, nil
+}
+
+func (s *ObjectStore) String() string {
+	return fmt.Sprintf("Endpoint: %v, Region: %v, Bucket: %v", s.endpoint, s.region, s.bucket)
+}
+
+func (s *ObjectStore) PutObject(key string, body []byte) error {
+	_, err := s.do(http.MethodPut, key, nil, body)
+	return err
+}
+
+func (s *ObjectStore) GetObject(key string) ([]byte, error) {
+	return s.do(http.MethodGet, key, nil, nil)
+}
+
+func (s *ObjectStore) DeleteObject(key string) error {
+	_, err := s.do(http.MethodDelete, key, nil, nil)
+	return err
+}
+
+// List all the objects whose key starts with the prefix.
+func (s *ObjectStore) ListObjects(prefix string) ([]ObjectInfo, error) {
+	objects := make([]ObjectInfo, 0)
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		body, err := s.do(http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result listBucketResult
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, errors.New(fmt.Sprintf("unable to demarshal the object list of bucket %v, error: %v", s.bucket, err))
+		}
+		objects = append(objects, result.Contents...)
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (s *ObjectStore) do(method string, key string, query url.Values, body []byte) ([]byte, error) {
+	if body == nil {
+		body = []byte{}
+	}
+
+	target := *s.endpoint
+	target.Path = s.endpoint.Path + "/" + s.bucket
+	if key != "" {
+		target.Path += "/" + key
+	}
+	target.RawPath = s.endpoint.Path + "/" + uriEncode(s.bucket, false)
+	if key != "" {
+		target.RawPath += "/" + uriEncode(key, false)
+	}
+	target.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, target.EscapedPath(), body, time.Now().UTC())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to %v %v in bucket %v, error: %v", method, key, s.bucket, err))
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to read the response to %v %v in bucket %v, error: %v", method, key, s.bucket, err))
+	} else if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, errors.New(fmt.Sprintf("unable to %v %v in bucket %v, HTTP status %v, response: %v", method, key, s.bucket, resp.StatusCode, string(respBody)))
+	}
+	return respBody, nil
+}
+
+// Add the AWS signature version 4 headers to the request.
+func (s *ObjectStore) sign(req *http.Request, canonicalURI string, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := hashHex(body)
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+
+	if canonicalURI == "" {
+		canonicalURI = "/"
+	}
+	canonicalRequest := strings.Join([]string{req.Method, canonicalURI, req.URL.RawQuery, canonicalHeaders, signedHeaders, payloadHash}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hashHex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%v/%v, SignedHeaders=%v, Signature=%v", s.accessKey, scope, signedHeaders, signature))
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// Encode the string as required by the signature, every byte except the unreserved characters is percent encoded.
+// The slashes in an object key are not encoded.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	params := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			params = append(params, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(params, "&")
+}
@@ -1,6 +1,7 @@
 package agreementbot
 
 import (
+	"crypto/rsa"
 	"errors"
 	"fmt"
 	"github.com/golang/glog"
@@ -25,6 +26,13 @@ type BasicProtocolHandler struct {
 
 func NewBasicProtocolHandler(name string, cfg *config.HorizonConfig, db persistence.AgbotDatabase, pm *policy.PolicyManager, messages chan events.Message, mmsObjMgr *MMSObjectPolicyManager) *BasicProtocolHandler {
 	if name == basicprotocol.PROTOCOL_NAME {
+		// Proposals are signed with the agbot's messaging key.
+		agreementPH := basicprotocol.NewProtocolHandler(cfg.Collaborators.HTTPClientFactory.NewHTTPClient(nil), pm)
+		agreementPH.SetSigningKeySource(func() (*rsa.PrivateKey, error) {
+			_, key, err := exchange.GetKeys(cfg.AgreementBot.MessageKeyPath)
+			return key, err
+		})
+
 		return &BasicProtocolHandler{
 			BaseConsumerProtocolHandler: &BaseConsumerProtocolHandler{
 				name:             name,
@@ -38,7 +46,7 @@ func NewBasicProtocolHandler(name string, cfg *config.HorizonConfig, db persiste
 				messages:         messages,
 				mmsObjMgr:        mmsObjMgr,
 			},
-			agreementPH: basicprotocol.NewProtocolHandler(cfg.Collaborators.HTTPClientFactory.NewHTTPClient(nil), pm),
+			agreementPH: agreementPH,
 			// Allow the main agbot thread to distribute protocol msgs and agreement handling to the worker pool.
 			Work: NewPrioritizedWorkQueue(cfg.GetAgbotAgreementQueueSize()),
 		}
@@ -108,7 +116,7 @@ func (c *BasicProtocolHandler) AcceptCommand(cmd worker.Command) bool {
 
 func (c *BasicProtocolHandler) PersistAgreement(wi *InitiateAgreement, proposal abstractprotocol.Proposal, workerID string) error {
 
-	return c.BaseConsumerProtocolHandler.PersistBaseAgreement(wi, proposal, workerID, "", "")
+	return c.BaseConsumerProtocolHandler.PersistBaseAgreement(wi, proposal, workerID, "", proposal.Signature())
 }
 
 func (c *BasicProtocolHandler) PersistReply(r abstractprotocol.ProposalReply, pol *policy.Policy, workerID string) error {
@@ -262,7 +262,7 @@ func (n *NodeSearch) searchNodesAndMakeAgreements(consumerPolicy *policy.Policy,
 		for _, agp := range policy.AllAgreementProtocols() {
 			// Find all agreements that are in progress. They might be waiting for a reply or not yet finalized.
 			// TODO: To support more than 1 agreement (maxagreements > 1) with this device for this policy, we need to adjust this logic.
-			if agreements, err := n.db.FindAgreements([]persistence.AFilter{persistence.UnarchivedAFilter(), pendingAgreementFilter()}, agp); err != nil {
+			if agreements, err := n.db.FindAgreementsByOrg(org, []persistence.AFilter{persistence.UnarchivedAFilter(), pendingAgreementFilter()}, agp); err != nil {
 				glog.Errorf(AWlogString(fmt.Sprintf("received error trying to find pending agreements for protocol %v: %v", agp, err)))
 			} else {
 				ags[agp] = agreements
@@ -0,0 +1,37 @@
+package persistence
+
+import (
+	"fmt"
+)
+
+// The states of a node in the rollout of an agent upgrade.
+const (
+	AU_STATE_PENDING    = "pending"    // the wave of the node has not been scheduled yet
+	AU_STATE_SCHEDULED  = "scheduled"  // the node was told to run the upgrade
+	AU_STATE_SUCCESSFUL = "successful" // the node upgraded its agent
+	AU_STATE_FAILED     = "failed"     // the node failed to upgrade its agent, or did not finish in time
+)
+
+// The state of one node in the rollout of the agent upgrade job of a node management policy. The LastUpdated time of
+// the policy is kept with the state so that the rollout starts over when the policy changes.
+type AgentUpgrade struct {
+	NMPName        string `json:"nmp_name"` // the node management policy, in the form org/name
+	NMPLastUpdated string `json:"nmp_last_updated"`
+	NodeId         string `json:"node_id"` // in the form org/id
+	Version        string `json:"version"` // the agent version the node is upgraded to
+	Wave           int    `json:"wave"`    // the waves are numbered from 0
+	State          string `json:"state"`
+	ScheduledTime  uint64 `json:"scheduled_time"`
+	FinishedTime   uint64 `json:"finished_time"`
+	Message        string `json:"message"` // the reason the upgrade failed
+}
+
+func (a AgentUpgrade) String() string {
+	return fmt.Sprintf("NMPName: %v, NMPLastUpdated: %v, NodeId: %v, Version: %v, Wave: %v, State: %v, ScheduledTime: %v, FinishedTime: %v, Message: %v",
+		a.NMPName, a.NMPLastUpdated, a.NodeId, a.Version, a.Wave, a.State, a.ScheduledTime, a.FinishedTime, a.Message)
+}
+
+// Returns true if the node is done with the upgrade, either successful or failed.
+func (a AgentUpgrade) IsFinished() bool {
+	return a.State == AU_STATE_SUCCESSFUL || a.State == AU_STATE_FAILED
+}
@@ -56,8 +56,24 @@ type Agreement struct {
 	NHCheckAgreementStatus         int      `json:"check_agreement_status"`            // How often to check that the node agreement entry still exists in the exchange (in seconds)
 	Pattern                        string   `json:"pattern"`                           // The pattern used to make the agreement, used for pattern case only
 	ServiceId                      []string `json:"service_id"`                        // All the service ids whose policy is used to make the agreement, used for policy case only
+
+	// The most recent data verification checks, oldest first, at most MAX_DATA_VERIFICATION_HISTORY of them
+	DataVerificationHistory []DataVerificationCheck `json:"data_verification_history"`
+}
+
+// The outcome of one data verification check of an agreement.
+type DataVerificationCheck struct {
+	Time     uint64 `json:"time"`     // when the check was done
+	Verified bool   `json:"verified"` // true if data was being received
+}
+
+func (d DataVerificationCheck) String() string {
+	return fmt.Sprintf("Time: %v, Verified: %v", d.Time, d.Verified)
 }
 
+// The number of data verification checks kept for each agreement. Older checks are discarded.
+const MAX_DATA_VERIFICATION_HISTORY = 50
+
 func (a Agreement) String() string {
 	return fmt.Sprintf("Archived: %v, "+
 		"CurrentAgreementId: %v, "+
@@ -98,7 +114,8 @@ func (a Agreement) String() string {
 		"NHMissingHBInterval: %v, "+
 		"NHCheckAgreementStatus: %v, "+
 		"Pattern: %v, "+
-		"ServiceId: %v",
+		"ServiceId: %v, "+
+		"DataVerificationHistory: %v",
 		a.Archived, a.CurrentAgreementId, a.Org, a.AgreementProtocol, a.AgreementProtocolVersion, a.DeviceId, a.DeviceType, a.HAPartners,
 		a.AgreementInceptionTime, a.AgreementCreationTime, a.AgreementFinalizedTime,
 		a.AgreementTimedout, a.ProposalSig, a.ProposalHash, a.ConsumerProposalSig, a.PolicyName, a.CounterPartyAddress,
@@ -106,7 +123,7 @@ func (a Agreement) String() string {
 		a.DisableDataVerificationChecks, a.DataVerifiedTime, a.DataNotificationSent,
 		a.MeteringTokens, a.MeteringPerTimeUnit, a.MeteringNotificationInterval, a.MeteringNotificationSent, a.MeteringNotificationMsgs,
 		a.TerminatedReason, a.TerminatedDescription, a.BlockchainType, a.BlockchainName, a.BlockchainOrg, a.BCUpdateAckTime,
-		a.NHMissingHBInterval, a.NHCheckAgreementStatus, a.Pattern, a.ServiceId)
+		a.NHMissingHBInterval, a.NHCheckAgreementStatus, a.Pattern, a.ServiceId, a.DataVerificationHistory)
 }
 
 // Factory method for agreement w/out persistence safety.
@@ -157,6 +174,7 @@ func NewAgreement(agreementid string, org string, deviceid string, deviceType st
 			NHCheckAgreementStatus:         nhPolicy.CheckAgreementStatus,
 			Pattern:                        pattern,
 			ServiceId:                      serviceId,
+			DataVerificationHistory:        []DataVerificationCheck{},
 		}, nil
 	}
 }
@@ -270,9 +288,18 @@ func AgreementTimedout(db AgbotDatabase, agreementid string, protocol string) (*
 	}
 }
 
+// Add the outcome of a data verification check to the history, keeping only the newest MAX_DATA_VERIFICATION_HISTORY checks.
+func (a *Agreement) addDataVerificationCheck(verified bool) {
+	a.DataVerificationHistory = append(a.DataVerificationHistory, DataVerificationCheck{Time: uint64(time.Now().Unix()), Verified: verified})
+	if len(a.DataVerificationHistory) > MAX_DATA_VERIFICATION_HISTORY {
+		a.DataVerificationHistory = a.DataVerificationHistory[len(a.DataVerificationHistory)-MAX_DATA_VERIFICATION_HISTORY:]
+	}
+}
+
 func DataVerified(db AgbotDatabase, agreementid string, protocol string) (*Agreement, error) {
 	if agreement, err := db.SingleAgreementUpdate(agreementid, protocol, func(a Agreement) *Agreement {
 		a.DataVerifiedTime = uint64(time.Now().Unix())
+		a.addDataVerificationCheck(true)
 		return &a
 	}); err != nil {
 		return nil, err
@@ -284,6 +311,7 @@ func DataVerified(db AgbotDatabase, agreementid string, protocol string) (*Agree
 func DataNotVerified(db AgbotDatabase, agreementid string, protocol string) (*Agreement, error) {
 	if agreement, err := db.SingleAgreementUpdate(agreementid, protocol, func(a Agreement) *Agreement {
 		a.DataVerificationMissedCount += 1
+		a.addDataVerificationCheck(false)
 		return &a
 	}); err != nil {
 		return nil, err
@@ -432,6 +460,35 @@ func ValidateStateTransition(mod *Agreement, update *Agreement) {
 	if mod.BCUpdateAckTime == 0 { // 1 transition from zero to non-zero
 		mod.BCUpdateAckTime = update.BCUpdateAckTime
 	}
+	mergeDataVerificationHistory(mod, update) // Valid transitions only add newer checks
+}
+
+// Append the checks in the update that are not older than the newest check in the current record, then trim the
+// history to its maximum size. Several checks can be done in the same second, so the checks of the newest second that
+// the current record already has are not appended again.
+func mergeDataVerificationHistory(mod *Agreement, update *Agreement) {
+	var newest uint64
+	existing := make(map[DataVerificationCheck]int)
+	if len(mod.DataVerificationHistory) != 0 {
+		newest = mod.DataVerificationHistory[len(mod.DataVerificationHistory)-1].Time
+	}
+	for _, check := range mod.DataVerificationHistory {
+		if check.Time == newest {
+			existing[check] += 1
+		}
+	}
+	for _, check := range update.DataVerificationHistory {
+		if check.Time < newest {
+			continue
+		} else if existing[check] > 0 {
+			existing[check] -= 1
+			continue
+		}
+		mod.DataVerificationHistory = append(mod.DataVerificationHistory, check)
+	}
+	if len(mod.DataVerificationHistory) > MAX_DATA_VERIFICATION_HISTORY {
+		mod.DataVerificationHistory = mod.DataVerificationHistory[len(mod.DataVerificationHistory)-MAX_DATA_VERIFICATION_HISTORY:]
+	}
 }
 
 // Filters used by the caller to control what comes back from the database.
@@ -449,6 +506,10 @@ func IdAFilter(id string) AFilter {
 	return func(a Agreement) bool { return a.CurrentAgreementId == id }
 }
 
+func OrgAFilter(org string) AFilter {
+	return func(a Agreement) bool { return a.Org == org }
+}
+
 func DevPolAFilter(deviceId string, policyName string) AFilter {
 	return func(a Agreement) bool { return a.DeviceId == deviceId && a.PolicyName == policyName }
 }
@@ -0,0 +1,188 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The ways that agreement statistics can be grouped.
+const STATS_GROUP_BY_STATE = "state"
+const STATS_GROUP_BY_PROTOCOL = "protocol"
+const STATS_GROUP_BY_ORG = "org"
+const STATS_GROUP_BY_WORKLOAD = "workload"
+
+// The lifecycle states that an agreement is counted in.
+const AG_STATE_ATTEMPTED = "attempted"     // the agreement record exists but a proposal has not been sent
+const AG_STATE_PROPOSED = "proposed"       // the proposal was sent, waiting for the node to reply and finalize
+const AG_STATE_FINALIZED = "finalized"     // the agreement is in effect
+const AG_STATE_TERMINATING = "terminating" // the agreement was cancelled or timed out but is not archived yet
+const AG_STATE_ARCHIVED = "archived"       // the agreement has ended
+
+func ValidStatisticsGroupBy(groupBy string) bool {
+	return groupBy == STATS_GROUP_BY_STATE || groupBy == STATS_GROUP_BY_PROTOCOL || groupBy == STATS_GROUP_BY_ORG || groupBy == STATS_GROUP_BY_WORKLOAD
+}
+
+// The aggregated counts and rates for one group of agreements. The counts by state include all agreements that
+// existed at some point in the time window. The rates are per hour over the time window.
+type AgreementStatisticsGroup struct {
+	Total              int64            `json:"total"`
+	States             map[string]int64 `json:"states"`
+	Started            int64            `json:"started"`              // agreements that were started in the window
+	Finalized          int64            `json:"finalized"`            // agreements that were finalized in the window
+	Terminated         int64            `json:"terminated"`           // agreements that were cancelled or timed out in the window
+	StartedPerHour     float64          `json:"started_per_hour"`     // rate at which agreements were started
+	TerminatedPerHour  float64          `json:"terminated_per_hour"`  // rate at which agreements were terminated
+	FinalizedRatio     float64          `json:"finalized_ratio"`      // the fraction of the agreements started in the window that have been finalized
+	AvgFinalizeSeconds float64          `json:"avg_finalize_seconds"` // average time from start to finalization, for agreements finalized in the window
+}
+
+type AgreementStatistics struct {
+	GroupBy string                               `json:"group_by"`
+	Since   uint64                               `json:"since"` // the start of the time window
+	Until   uint64                               `json:"until"` // the end of the time window
+	Groups  map[string]*AgreementStatisticsGroup `json:"groups"`
+}
+
+func (s AgreementStatistics) String() string {
+	return fmt.Sprintf("GroupBy: %v, Since: %v, Until: %v, Groups: %v", s.GroupBy, s.Since, s.Until, s.Groups)
+}
+
+// Return the lifecycle state of the agreement, used to group agreements by state.
+func AgreementState(a *Agreement) string {
+	if a.Archived {
+		return AG_STATE_ARCHIVED
+	} else if a.AgreementTimedout != 0 {
+		return AG_STATE_TERMINATING
+	} else if a.AgreementFinalizedTime != 0 {
+		return AG_STATE_FINALIZED
+	} else if a.AgreementCreationTime != 0 {
+		return AG_STATE_PROPOSED
+	}
+	return AG_STATE_ATTEMPTED
+}
+
+// The workload of an agreement is the list of services whose policies were used to make it (policy case), otherwise
+// the name of the policy, which identifies the pattern and service for the pattern case.
+func AgreementWorkload(a *Agreement) string {
+	if len(a.ServiceId) != 0 {
+		return strings.Join(a.ServiceId, ",")
+	}
+	return a.PolicyName
+}
+
+func agreementGroupKey(a *Agreement, groupBy string) string {
+	switch groupBy {
+	case STATS_GROUP_BY_PROTOCOL:
+		return a.AgreementProtocol
+	case STATS_GROUP_BY_ORG:
+		return a.Org
+	case STATS_GROUP_BY_WORKLOAD:
+		return AgreementWorkload(a)
+	default:
+		return AgreementState(a)
+	}
+}
+
+// Returns true if the agreement existed at some point in the time window.
+func StatisticsWindowAFilter(since uint64, until uint64) AFilter {
+	return func(a Agreement) bool {
+		return a.AgreementInceptionTime <= until && (a.AgreementTimedout == 0 || a.AgreementTimedout >= since)
+	}
+}
+
+// Aggregate the agreements in the given protocols that existed in the time window [since, until]. A since of 0
+// means the window starts with the oldest agreement. This function is up called from the database implementations
+// so that the aggregation is the same for all of them.
+func GetAgreementStatistics(db AgbotDatabase, protocols []string, groupBy string, since uint64, until uint64) (*AgreementStatistics, error) {
+
+	if groupBy == "" {
+		groupBy = STATS_GROUP_BY_STATE
+	} else if !ValidStatisticsGroupBy(groupBy) {
+		return nil, errors.New(fmt.Sprintf("unsupported agreement statistics group by %v", groupBy))
+	}
+
+	if until == 0 {
+		until = uint64(time.Now().Unix())
+	}
+	if since > until {
+		return nil, errors.New(fmt.Sprintf("the start of the agreement statistics time window %v is after the end %v", since, until))
+	}
+
+	stats := &AgreementStatistics{
+		GroupBy: groupBy,
+		Since:   since,
+		Until:   until,
+		Groups:  make(map[string]*AgreementStatisticsGroup),
+	}
+
+	all := make([]Agreement, 0)
+	for _, protocol := range protocols {
+		if ags, err := db.FindAgreements([]AFilter{StatisticsWindowAFilter(since, until)}, protocol); err != nil {
+			return nil, err
+		} else {
+			all = append(all, ags...)
+		}
+	}
+
+	// When the window is all time, it starts with the oldest agreement so that the rates are meaningful.
+	if since == 0 {
+		stats.Since = until
+		for _, ag := range all {
+			if ag.AgreementInceptionTime < stats.Since {
+				stats.Since = ag.AgreementInceptionTime
+			}
+		}
+	}
+
+	inWindow := func(t uint64) bool { return t != 0 && t >= stats.Since && t <= stats.Until }
+	finalizeSeconds := make(map[string]uint64)
+	startedAndFinalized := make(map[string]int64)
+
+	for _, ag := range all {
+		key := agreementGroupKey(&ag, groupBy)
+		group, ok := stats.Groups[key]
+		if !ok {
+			group = &AgreementStatisticsGroup{States: make(map[string]int64)}
+			stats.Groups[key] = group
+		}
+
+		group.Total += 1
+		group.States[AgreementState(&ag)] += 1
+		if inWindow(ag.AgreementInceptionTime) {
+			group.Started += 1
+			if ag.AgreementFinalizedTime != 0 {
+				startedAndFinalized[key] += 1
+			}
+		}
+		if inWindow(ag.AgreementFinalizedTime) {
+			group.Finalized += 1
+			if ag.AgreementFinalizedTime >= ag.AgreementInceptionTime {
+				finalizeSeconds[key] += ag.AgreementFinalizedTime - ag.AgreementInceptionTime
+			}
+		}
+		if inWindow(ag.AgreementTimedout) {
+			group.Terminated += 1
+		}
+	}
+
+	// Compute the rates now that all the counts are known. A window shorter than a second is treated as one second.
+	hours := float64(stats.Until-stats.Since) / 3600
+	if stats.Until == stats.Since {
+		hours = float64(1) / 3600
+	}
+
+	for key, group := range stats.Groups {
+		group.StartedPerHour = float64(group.Started) / hours
+		group.TerminatedPerHour = float64(group.Terminated) / hours
+		if group.Started != 0 {
+			group.FinalizedRatio = float64(startedAndFinalized[key]) / float64(group.Started)
+		}
+		if group.Finalized != 0 {
+			group.AvgFinalizeSeconds = float64(finalizeSeconds[key]) / float64(group.Finalized)
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,96 @@
+// +build unit
+
+package persistence
+
+import (
+	"testing"
+)
+
+// An agbot database that only holds a list of agreements for each protocol.
+type statisticsDB struct {
+	AgbotDatabase
+	agreements map[string][]Agreement
+}
+
+func (db statisticsDB) FindAgreements(filters []AFilter, protocol string) ([]Agreement, error) {
+	ags := make([]Agreement, 0)
+	for _, ag := range db.agreements[protocol] {
+		a := ag
+		if RunFilters(&a, filters) != nil {
+			ags = append(ags, ag)
+		}
+	}
+	return ags, nil
+}
+
+func Test_AgreementState(t *testing.T) {
+	for expected, ag := range map[string]Agreement{
+		AG_STATE_ATTEMPTED:   {AgreementInceptionTime: 10},
+		AG_STATE_PROPOSED:    {AgreementInceptionTime: 10, AgreementCreationTime: 11},
+		AG_STATE_FINALIZED:   {AgreementInceptionTime: 10, AgreementCreationTime: 11, AgreementFinalizedTime: 12},
+		AG_STATE_TERMINATING: {AgreementInceptionTime: 10, AgreementCreationTime: 11, AgreementTimedout: 13},
+		AG_STATE_ARCHIVED:    {AgreementInceptionTime: 10, AgreementTimedout: 13, Archived: true},
+	} {
+		if state := AgreementState(&ag); state != expected {
+			t.Errorf("expected state %v, found %v for %v", expected, state, ag)
+		}
+	}
+}
+
+func Test_GetAgreementStatistics(t *testing.T) {
+	db := statisticsDB{agreements: map[string][]Agreement{
+		"Basic": {
+			{CurrentAgreementId: "a1", AgreementProtocol: "Basic", Org: "org1", PolicyName: "pol1", AgreementInceptionTime: 1000, AgreementCreationTime: 1001, AgreementFinalizedTime: 1100},
+			{CurrentAgreementId: "a2", AgreementProtocol: "Basic", Org: "org1", ServiceId: []string{"svc1", "svc2"}, AgreementInceptionTime: 2000, AgreementCreationTime: 2001},
+			{CurrentAgreementId: "a3", AgreementProtocol: "Basic", Org: "org2", PolicyName: "pol1", AgreementInceptionTime: 3000, AgreementCreationTime: 3001, AgreementTimedout: 3500, Archived: true},
+			// the agreement ended before the window
+			{CurrentAgreementId: "a0", AgreementProtocol: "Basic", Org: "org2", PolicyName: "pol1", AgreementInceptionTime: 100, AgreementTimedout: 200, Archived: true},
+		},
+		"Citizen Scientist": {
+			{CurrentAgreementId: "c1", AgreementProtocol: "Citizen Scientist", Org: "org2", PolicyName: "pol2", AgreementInceptionTime: 4000},
+		},
+	}}
+	protocols := []string{"Basic", "Citizen Scientist"}
+
+	stats, err := GetAgreementStatistics(db, protocols, "", 1000, 4600)
+	if err != nil {
+		t.Fatal(err)
+	} else if stats.GroupBy != STATS_GROUP_BY_STATE || stats.Since != 1000 || stats.Until != 4600 {
+		t.Errorf("unexpected window %v", stats)
+	}
+	if group := stats.Groups[AG_STATE_FINALIZED]; group == nil || group.Total != 1 || group.Finalized != 1 || group.FinalizedRatio != 1 || group.AvgFinalizeSeconds != 100 {
+		t.Errorf("unexpected finalized statistics %v", group)
+	}
+	if group := stats.Groups[AG_STATE_ARCHIVED]; group == nil || group.Total != 1 || group.Terminated != 1 || group.TerminatedPerHour != 1 {
+		t.Errorf("unexpected archived statistics %v", group)
+	}
+	if _, ok := stats.Groups[AG_STATE_ATTEMPTED]; !ok {
+		t.Errorf("the agreement of the other protocol should be counted, found %v", stats.Groups)
+	}
+
+	// the window of all time starts with the oldest agreement
+	if stats, err := GetAgreementStatistics(db, protocols, STATS_GROUP_BY_ORG, 0, 3700); err != nil {
+		t.Fatal(err)
+	} else if stats.Since != 100 || stats.Groups["org1"].Total != 2 || stats.Groups["org2"].Total != 2 || stats.Groups["org1"].Started != 2 {
+		t.Errorf("unexpected org statistics %v", stats)
+	}
+
+	if stats, err := GetAgreementStatistics(db, protocols, STATS_GROUP_BY_WORKLOAD, 0, 0); err != nil {
+		t.Fatal(err)
+	} else if stats.Groups["pol1"].Total != 3 || stats.Groups["svc1,svc2"].Total != 1 || stats.Groups["pol2"].Total != 1 {
+		t.Errorf("unexpected workload statistics %v", stats)
+	}
+
+	if stats, err := GetAgreementStatistics(db, protocols, STATS_GROUP_BY_PROTOCOL, 0, 0); err != nil {
+		t.Fatal(err)
+	} else if stats.Groups["Basic"].Total != 4 || stats.Groups["Citizen Scientist"].Total != 1 {
+		t.Errorf("unexpected protocol statistics %v", stats)
+	}
+
+	if _, err := GetAgreementStatistics(db, protocols, "color", 0, 0); err == nil {
+		t.Errorf("expected an error for an unsupported group by")
+	}
+	if _, err := GetAgreementStatistics(db, protocols, "", 200, 100); err == nil {
+		t.Errorf("expected an error for a window that ends before it starts")
+	}
+}
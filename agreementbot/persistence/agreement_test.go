@@ -0,0 +1,56 @@
+// +build unit
+
+package persistence
+
+import (
+	"testing"
+)
+
+func Test_mergeDataVerificationHistory(t *testing.T) {
+	checks := func(cs ...DataVerificationCheck) []DataVerificationCheck { return cs }
+
+	// the update has the current history and a new check in the same second as the newest one
+	mod := &Agreement{DataVerificationHistory: checks(DataVerificationCheck{10, true}, DataVerificationCheck{20, false})}
+	update := &Agreement{DataVerificationHistory: checks(DataVerificationCheck{10, true}, DataVerificationCheck{20, false}, DataVerificationCheck{20, false})}
+	mergeDataVerificationHistory(mod, update)
+	if len(mod.DataVerificationHistory) != 3 || mod.DataVerificationHistory[2] != (DataVerificationCheck{20, false}) {
+		t.Errorf("expected the check in the same second to be added once, found %v", mod.DataVerificationHistory)
+	}
+
+	// the checks that the record already has are not added again, the older ones are ignored
+	update = &Agreement{DataVerificationHistory: checks(DataVerificationCheck{5, true}, DataVerificationCheck{20, false}, DataVerificationCheck{20, true}, DataVerificationCheck{30, true})}
+	mergeDataVerificationHistory(mod, update)
+	if len(mod.DataVerificationHistory) != 5 || mod.DataVerificationHistory[3] != (DataVerificationCheck{20, true}) || mod.DataVerificationHistory[4] != (DataVerificationCheck{30, true}) {
+		t.Errorf("expected only the new checks to be added, found %v", mod.DataVerificationHistory)
+	}
+
+	// an empty record takes the whole history of the update
+	mod = &Agreement{}
+	mergeDataVerificationHistory(mod, update)
+	if len(mod.DataVerificationHistory) != 4 {
+		t.Errorf("expected the history of the update, found %v", mod.DataVerificationHistory)
+	}
+}
+
+func Test_addDataVerificationCheck(t *testing.T) {
+	a := &Agreement{}
+	for i := 0; i < MAX_DATA_VERIFICATION_HISTORY+5; i++ {
+		a.addDataVerificationCheck(i%2 == 0)
+	}
+	if len(a.DataVerificationHistory) != MAX_DATA_VERIFICATION_HISTORY {
+		t.Errorf("expected the history to be bounded to %v checks, found %v", MAX_DATA_VERIFICATION_HISTORY, len(a.DataVerificationHistory))
+	} else if last := a.DataVerificationHistory[MAX_DATA_VERIFICATION_HISTORY-1]; last.Verified != ((MAX_DATA_VERIFICATION_HISTORY+4)%2 == 0) {
+		t.Errorf("expected the newest check to be kept, found %v", last)
+	}
+
+	// the history is bounded when it is merged too
+	mod := &Agreement{DataVerificationHistory: []DataVerificationCheck{{Time: 1, Verified: true}}}
+	update := &Agreement{}
+	for i := 0; i < MAX_DATA_VERIFICATION_HISTORY; i++ {
+		update.DataVerificationHistory = append(update.DataVerificationHistory, DataVerificationCheck{Time: uint64(2 + i), Verified: false})
+	}
+	mergeDataVerificationHistory(mod, update)
+	if len(mod.DataVerificationHistory) != MAX_DATA_VERIFICATION_HISTORY || mod.DataVerificationHistory[0].Time != 2 {
+		t.Errorf("expected the oldest check to be discarded, found %v", mod.DataVerificationHistory)
+	}
+}
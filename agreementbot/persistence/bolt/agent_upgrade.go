@@ -0,0 +1,130 @@
+package bolt
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"strings"
+	"time"
+)
+
+const AGENT_UPGRADE_BUCKET = "agent_upgrade"             // The bolt DB bucket name for the agent upgrade rollout objects.
+const AGENT_UPGRADE_LEASE_BUCKET = "agent_upgrade_lease" // The bolt DB bucket name for the leases on the rollouts, keyed by policy name.
+
+// The lease of an agbot instance on a rollout, see ObtainAgentUpgradeLease.
+type agentUpgradeLease struct {
+	Owner   string `json:"owner"`
+	Expires int64  `json:"expires"`
+}
+
+// The node management policy and node ids can not contain spaces, so the key of a rollout object starts with the
+// policy name followed by a space.
+func agentUpgradeKey(nmpName string, nodeId string) string {
+	return nmpName + " " + nodeId
+}
+
+func (db *AgbotBoltDB) FindAgentUpgrades(nmpName string) ([]persistence.AgentUpgrade, error) {
+	upgrades := make([]persistence.AgentUpgrade, 0)
+
+	readErr := db.db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(db.auBucketName())); b != nil {
+			return b.ForEach(func(k, v []byte) error {
+				if nmpName != "" && !strings.HasPrefix(string(k), agentUpgradeKey(nmpName, "")) {
+					return nil
+				}
+
+				var au persistence.AgentUpgrade
+				if err := json.Unmarshal(v, &au); err != nil {
+					return fmt.Errorf("Unable to deserialize agent upgrade record: %v", v)
+				}
+				upgrades = append(upgrades, au)
+				return nil
+			})
+		}
+
+		return nil // end transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return upgrades, nil
+}
+
+func (db *AgbotBoltDB) SaveAgentUpgrade(au *persistence.AgentUpgrade) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(db.auBucketName()))
+		if err != nil {
+			return err
+		}
+
+		if serial, err := json.Marshal(au); err != nil {
+			return fmt.Errorf("Failed to serialize agent upgrade: %v. Error: %v", *au, err)
+		} else {
+			return b.Put([]byte(agentUpgradeKey(au.NMPName, au.NodeId)), serial)
+		}
+	})
+}
+
+func (db *AgbotBoltDB) DeleteAgentUpgrades(nmpName string) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(db.auBucketName()))
+		if b == nil {
+			return nil
+		}
+
+		// collect the keys first, the bucket can not be changed while it is iterated
+		keys := make([][]byte, 0)
+		if err := b.ForEach(func(k, v []byte) error {
+			if strings.HasPrefix(string(k), agentUpgradeKey(nmpName, "")) {
+				keys = append(keys, append([]byte{}, k...))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// The lease is obtained when there is none, when this instance already holds it or when it has expired. A bolt DB file
+// is only used by one process, but the lease is kept the same way as in the shared databases.
+func (db *AgbotBoltDB) ObtainAgentUpgradeLease(nmpName string, leaseS uint64) (bool, error) {
+	obtained := false
+	err := db.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(db.prefix + AGENT_UPGRADE_LEASE_BUCKET))
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().Unix()
+		if v := b.Get([]byte(nmpName)); v != nil {
+			var lease agentUpgradeLease
+			if err := json.Unmarshal(v, &lease); err != nil {
+				return fmt.Errorf("Unable to deserialize agent upgrade lease record: %v", v)
+			} else if lease.Owner != db.identity && lease.Expires >= now {
+				return nil
+			}
+		}
+
+		if serial, err := json.Marshal(agentUpgradeLease{Owner: db.identity, Expires: now + int64(leaseS)}); err != nil {
+			return fmt.Errorf("Failed to serialize agent upgrade lease of %v. Error: %v", nmpName, err)
+		} else if err := b.Put([]byte(nmpName), serial); err != nil {
+			return err
+		}
+		obtained = true
+		return nil
+	})
+	return obtained, err
+}
+
+func (db *AgbotBoltDB) auBucketName() string {
+	return db.prefix + AGENT_UPGRADE_BUCKET
+}
@@ -0,0 +1,51 @@
+// +build unit
+
+package bolt
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_ObtainAgentUpgradeLease(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agbot-lease-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db := openTestDB(t, dir, "", false)
+	defer db.Close()
+
+	if leased, err := db.ObtainAgentUpgradeLease("org1/nmp1", 60); err != nil || !leased {
+		t.Errorf("the lease on a new rollout should be obtained, found %v %v", leased, err)
+	}
+	if leased, err := db.ObtainAgentUpgradeLease("org1/nmp1", 60); err != nil || !leased {
+		t.Errorf("the owner of the lease should be able to renew it, found %v %v", leased, err)
+	}
+
+	// another agbot instance with the same buckets
+	other := &AgbotBoltDB{db: db.db, prefix: db.prefix, identity: "other"}
+	if leased, err := other.ObtainAgentUpgradeLease("org1/nmp1", 60); err != nil || leased {
+		t.Errorf("the lease held by another instance should not be obtained, found %v %v", leased, err)
+	}
+	if leased, err := other.ObtainAgentUpgradeLease("org1/nmp2", 60); err != nil || !leased {
+		t.Errorf("the lease on another rollout should be obtained, found %v %v", leased, err)
+	}
+
+	// the lease expires when it is not renewed
+	if leased, err := db.ObtainAgentUpgradeLease("org1/nmp3", 0); err != nil || !leased {
+		t.Errorf("the lease on a new rollout should be obtained, found %v %v", leased, err)
+	}
+	for i := 0; i < 30; i++ {
+		if leased, err := other.ObtainAgentUpgradeLease("org1/nmp3", 60); err != nil {
+			t.Fatal(err)
+		} else if leased {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Errorf("the expired lease should have been taken over")
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/agreementbot/persistence"
 	"github.com/open-horizon/anax/policy"
+	"sync"
 )
 
 func init() {
@@ -17,15 +18,23 @@ func init() {
 
 // Constants used in this package.
 const BOLTDB_DATABASE_NAME = "agreementbot.db"
-const AGREEMENTS = "agreements" // The bolt DB bucket name for agreement objects.
+const AGREEMENTS = "agreements"                     // The bolt DB bucket name for agreement objects.
+const AGREEMENTS_BY_DEVICE = "agreements-by-device" // The bolt DB bucket name for the index of agreement ids by device id.
 
 // This is the object that represents the handle to the bolt func (db *AgbotBoltDB)
 type AgbotBoltDB struct {
-	db *bolt.DB
+	db       *bolt.DB
+	dbFile   string // the path of the bolt DB file, which can be shared with other agbot instances in the same process
+	prefix   string // the prefix of the names of all the buckets used by this agbot instance
+	identity string // the identity of this agbot instance in the agent upgrade leases
+
+	shardByOrg bool                // when true, the agreements of each org are kept in their own bolt DB file
+	shards     map[string]*bolt.DB // the agreement shards, keyed by org
+	shardsLock sync.RWMutex
 }
 
 func (db *AgbotBoltDB) String() string {
-	return fmt.Sprintf("DB Handle: %v", db.db)
+	return fmt.Sprintf("DB Handle: %v, Bucket prefix: %v", db.db, db.prefix)
 }
 
 func (db *AgbotBoltDB) GetAgreementCount(partition string) (int64, int64, error) {
@@ -43,18 +52,52 @@ func (db *AgbotBoltDB) GetAgreementCount(partition string) (int64, int64, error)
 	return activeNum, archivedNum, nil
 }
 
+func (db *AgbotBoltDB) GetAgreementStatistics(groupBy string, since uint64, until uint64) (*persistence.AgreementStatistics, error) {
+	return persistence.GetAgreementStatistics(db, policy.AllAgreementProtocols(), groupBy, since, until)
+}
+
 func (db *AgbotBoltDB) FindAgreements(filters []persistence.AFilter, protocol string) ([]persistence.Agreement, error) {
 	agreements := make([]persistence.Agreement, 0)
 
-	readErr := db.db.View(func(tx *bolt.Tx) error {
+	for _, agdb := range db.agreementDBs() {
+		if err := db.findAgreementsInDB(agdb, filters, protocol, &agreements); err != nil {
+			return nil, err
+		}
+	}
+	return agreements, nil
+}
+
+// Find the agreements for an org. When the agreements are sharded by org, only the org's shard (and the main database,
+// which holds the agreements made before sharding was enabled) is scanned.
+func (db *AgbotBoltDB) FindAgreementsByOrg(org string, filters []persistence.AFilter, protocol string) ([]persistence.Agreement, error) {
+	filters = append(filters, persistence.OrgAFilter(org))
+	if !db.shardByOrg {
+		return db.FindAgreements(filters, protocol)
+	}
+
+	agreements := make([]persistence.Agreement, 0)
+	if err := db.findAgreementsInDB(db.db, filters, protocol, &agreements); err != nil {
+		return nil, err
+	} else if shard := db.getShard(org); shard != nil {
+		if err := db.findAgreementsInDB(shard, filters, protocol, &agreements); err != nil {
+			return nil, err
+		}
+	}
+	return agreements, nil
+}
 
-		if b := tx.Bucket([]byte(bucketName(protocol))); b != nil {
+// Scan the agreements in one of the agreement databases and append the ones that pass the filters.
+func (db *AgbotBoltDB) findAgreementsInDB(agdb *bolt.DB, filters []persistence.AFilter, protocol string, agreements *[]persistence.Agreement) error {
+
+	return agdb.View(func(tx *bolt.Tx) error {
+
+		if b := tx.Bucket([]byte(db.bucketName(protocol))); b != nil {
 			b.ForEach(func(k, v []byte) error {
 
 				var a persistence.Agreement
 
-				if err := json.Unmarshal(v, &a); err != nil {
-					glog.Errorf("Unable to deserialize db record: %v", v)
+				if err := persistence.UnmarshalAgreement(v, &a); err != nil {
+					glog.Errorf("Unable to deserialize db record: %v, error: %v", v, err)
 				} else {
 					if !a.Archived {
 						glog.V(5).Infof("Demarshalled agreement in DB: %v", a)
@@ -66,7 +109,7 @@ func (db *AgbotBoltDB) FindAgreements(filters []persistence.AFilter, protocol st
 						}
 					}
 					if !exclude {
-						agreements = append(agreements, a)
+						*agreements = append(*agreements, a)
 					}
 				}
 				return nil
@@ -75,18 +118,12 @@ func (db *AgbotBoltDB) FindAgreements(filters []persistence.AFilter, protocol st
 
 		return nil // end the transaction
 	})
-
-	if readErr != nil {
-		return nil, readErr
-	} else {
-		return agreements, nil
-	}
 }
 
 func (db *AgbotBoltDB) AgreementAttempt(agreementid string, org string, deviceid string, deviceType string, policyName string, bcType string, bcName string, bcOrg string, agreementProto string, pattern string, serviceId []string, nhPolicy policy.NodeHealth) error {
 	if agreement, err := persistence.NewAgreement(agreementid, org, deviceid, deviceType, policyName, bcType, bcName, bcOrg, agreementProto, pattern, serviceId, nhPolicy); err != nil {
 		return err
-	} else if err := db.persistNew(agreement.CurrentAgreementId, bucketName(agreementProto), &agreement); err != nil {
+	} else if err := db.persistNewAgreement(agreement, agreementProto); err != nil {
 		return err
 	} else {
 		return nil
@@ -137,11 +174,60 @@ func (db *AgbotBoltDB) ArchiveAgreement(agreementid string, protocol string, rea
 	return persistence.ArchiveAgreement(db, agreementid, protocol, reason, desc)
 }
 
+// Find the agreements with a given device by looking up their ids in the device index, rather than scanning
+// every agreement in the protocol bucket. The filters are applied to the agreements that are found.
+func (db *AgbotBoltDB) FindAgreementsByDeviceId(deviceid string, protocol string, filters []persistence.AFilter) ([]persistence.Agreement, error) {
+	agreements := make([]persistence.Agreement, 0)
+
+	for _, agdb := range db.agreementDBs() {
+		if err := db.findDeviceAgreementsInDB(agdb, deviceid, protocol, filters, &agreements); err != nil {
+			return nil, err
+		}
+	}
+	return agreements, nil
+}
+
+func (db *AgbotBoltDB) findDeviceAgreementsInDB(agdb *bolt.DB, deviceid string, protocol string, filters []persistence.AFilter, agreements *[]persistence.Agreement) error {
+
+	return agdb.View(func(tx *bolt.Tx) error {
+
+		b := tx.Bucket([]byte(db.bucketName(protocol)))
+		ib := tx.Bucket([]byte(db.deviceIndexBucketName(protocol)))
+		if b == nil || ib == nil {
+			return nil
+		}
+
+		ids, err := getDeviceIndexEntry(ib, deviceid)
+		if err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			v := b.Get([]byte(id))
+			if v == nil {
+				glog.Warningf("Agreement %v for device %v is in the device index but not in the database", id, deviceid)
+				continue
+			}
+
+			var a persistence.Agreement
+			if err := persistence.UnmarshalAgreement(v, &a); err != nil {
+				glog.Errorf("Unable to deserialize db record: %v, error: %v", v, err)
+			} else if persistence.RunFilters(&a, filters) != nil {
+				*agreements = append(*agreements, a)
+			}
+		}
+
+		return nil // end the transaction
+	})
+}
+
 // no error on not found, only nil
 func (db *AgbotBoltDB) FindSingleAgreementByAgreementId(agreementid string, protocol string, filters []persistence.AFilter) (*persistence.Agreement, error) {
 	filters = append(filters, persistence.IdAFilter(agreementid))
 
-	if agreements, err := db.FindAgreements(filters, protocol); err != nil {
+	// Only the database that holds the agreement is read when the agreements are sharded.
+	agreements := make([]persistence.Agreement, 0)
+	if err := db.findAgreementsInDB(db.agreementDBForId(agreementid, protocol), filters, protocol, &agreements); err != nil {
 		return nil, err
 	} else if len(agreements) > 1 {
 		return nil, fmt.Errorf("Expected only one record for agreementid: %v, but retrieved: %v", agreementid, agreements)
@@ -183,17 +269,17 @@ func (db *AgbotBoltDB) SingleAgreementUpdate(agreementid string, protocol string
 
 // does whole-member replacements of values that are legal to change during the course of an agreement's life
 func (db *AgbotBoltDB) persistUpdatedAgreement(agreementid string, protocol string, update *persistence.Agreement) error {
-	return db.db.Update(func(tx *bolt.Tx) error {
-		if b, err := tx.CreateBucketIfNotExists([]byte(AGREEMENTS + "-" + protocol)); err != nil {
+	var mod persistence.Agreement
+	err := db.agreementDBForId(agreementid, protocol).Update(func(tx *bolt.Tx) error {
+		if b, err := tx.CreateBucketIfNotExists([]byte(db.bucketName(protocol))); err != nil {
 			return err
 		} else {
 			current := b.Get([]byte(agreementid))
-			var mod persistence.Agreement
 
 			if current == nil {
 				return fmt.Errorf("No agreement with given id available to update: %v", agreementid)
-			} else if err := json.Unmarshal(current, &mod); err != nil {
-				return fmt.Errorf("Failed to unmarshal agreement DB data: %v", string(current))
+			} else if err := persistence.UnmarshalAgreement(current, &mod); err != nil {
+				return fmt.Errorf("Failed to unmarshal agreement DB data: %v, error: %v", string(current), err)
 			} else {
 
 				// This code is running in a database transaction. Within the tx, the current record (mod) is
@@ -201,8 +287,8 @@ func (db *AgbotBoltDB) persistUpdatedAgreement(agreementid string, protocol stri
 				// to check for correct data transitions within the tx.
 				persistence.ValidateStateTransition(&mod, update)
 
-				if serialized, err := json.Marshal(mod); err != nil {
-					return fmt.Errorf("Failed to serialize agreement record: %v", mod)
+				if serialized, err := persistence.MarshalAgreement(&mod); err != nil {
+					return fmt.Errorf("Failed to serialize agreement record: %v, error: %v", mod, err)
 				} else if err := b.Put([]byte(agreementid), serialized); err != nil {
 					return fmt.Errorf("Failed to write record with key: %v", agreementid)
 				} else {
@@ -212,6 +298,10 @@ func (db *AgbotBoltDB) persistUpdatedAgreement(agreementid string, protocol stri
 		}
 		return nil
 	})
+	if err == nil {
+		persistence.NotifyAgreementChange(persistence.AGREEMENT_CHANGE_UPDATED, protocol, agreementid, &mod)
+	}
+	return err
 }
 
 func (db *AgbotBoltDB) DeleteAgreement(pk string, protocol string) error {
@@ -219,10 +309,12 @@ func (db *AgbotBoltDB) DeleteAgreement(pk string, protocol string) error {
 		return fmt.Errorf("Missing required arg pk")
 	} else {
 
-		return db.db.Update(func(tx *bolt.Tx) error {
-			b := tx.Bucket([]byte(bucketName(protocol)))
+		deleted := false
+		agdb := db.agreementDBForId(pk, protocol)
+		err := agdb.Update(func(tx *bolt.Tx) error {
+			b := tx.Bucket([]byte(db.bucketName(protocol)))
 			if b == nil {
-				return fmt.Errorf("Unknown bucket: %v", bucketName(protocol))
+				return fmt.Errorf("Unknown bucket: %v", db.bucketName(protocol))
 			} else if existing := b.Get([]byte(pk)); existing == nil {
 				glog.Errorf("Warning: record deletion requested, but record does not exist: %v", pk)
 				return nil // handle already-deleted agreement as success
@@ -231,14 +323,65 @@ func (db *AgbotBoltDB) DeleteAgreement(pk string, protocol string) error {
 
 				if err := json.Unmarshal(existing, &record); err != nil {
 					glog.Errorf("Error deserializing agreement: %v. This is a pre-deletion warning message function so deletion will still proceed", record)
-				} else if record.CurrentAgreementId != "" && !record.Archived {
-					glog.Warningf("Warning! Deleting an agreement record with an agreement id, this operation should only be done after cancelling on the blockchain.")
+				} else {
+					if record.CurrentAgreementId != "" && !record.Archived {
+						glog.Warningf("Warning! Deleting an agreement record with an agreement id, this operation should only be done after cancelling on the blockchain.")
+					}
+					if err := db.removeFromDeviceIndex(tx, protocol, record.DeviceId, pk); err != nil {
+						return err
+					}
 				}
 			}
 
+			deleted = true
 			return b.Delete([]byte(pk))
 		})
+		if err == nil && deleted && agdb != db.db {
+			err = db.removeFromShardIndex(protocol, pk)
+		}
+		if err == nil && deleted {
+			persistence.NotifyAgreementChange(persistence.AGREEMENT_CHANGE_DELETED, protocol, pk, nil)
+		}
+		return err
+	}
+}
+
+// Write a new agreement and add it to the device index in the same transaction, so that the index always matches
+// the agreements bucket.
+func (db *AgbotBoltDB) persistNewAgreement(agreement *persistence.Agreement, protocol string) error {
+	pk := agreement.CurrentAgreementId
+	bucket := db.bucketName(protocol)
+
+	agdb, err := db.agreementDBForOrg(agreement.Org)
+	if err != nil {
+		return err
+	} else if agdb != db.db {
+		if err := db.addToShardIndex(protocol, pk, agreement.Org); err != nil {
+			return err
+		}
 	}
+
+	err = agdb.Update(func(tx *bolt.Tx) error {
+
+		if b, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil {
+			return err
+		} else if existing := b.Get([]byte(pk)); existing != nil {
+			return fmt.Errorf("Bucket %v already contains record with primary key: %v", bucket, pk)
+		} else if bytes, err := persistence.MarshalAgreement(agreement); err != nil {
+			return fmt.Errorf("Unable to serialize record %v. Error: %v", agreement, err)
+		} else if err := b.Put([]byte(pk), bytes); err != nil {
+			return fmt.Errorf("Unable to write to record to bucket %v. Primary key of record: %v", bucket, pk)
+		} else if err := db.addToDeviceIndex(tx, protocol, agreement.DeviceId, pk); err != nil {
+			return err
+		} else {
+			glog.V(2).Infof("Succeeded writing record identified by %v in %v", pk, bucket)
+			return nil
+		}
+	})
+	if err == nil {
+		persistence.NotifyAgreementChange(persistence.AGREEMENT_CHANGE_CREATED, protocol, pk, agreement)
+	}
+	return err
 }
 
 func (db *AgbotBoltDB) persistNew(pk string, bucket string, record interface{}) error {
@@ -267,11 +410,113 @@ func (db *AgbotBoltDB) persistNew(pk string, bucket string, record interface{})
 
 func (db *AgbotBoltDB) Close() {
 	glog.V(2).Infof("Closing bolt database")
-	db.db.Close()
+	db.closeShards()
+	releaseSharedDB(db.dbFile, db.prefix)
 	glog.V(2).Infof("Closed bolt database")
 }
 
 // Utility functions specific to the bolt database implementation
-func bucketName(protocol string) string {
-	return AGREEMENTS + "-" + protocol
+func (db *AgbotBoltDB) bucketName(protocol string) string {
+	return db.prefix + AGREEMENTS + "-" + protocol
+}
+
+func (db *AgbotBoltDB) deviceIndexBucketName(protocol string) string {
+	return db.prefix + AGREEMENTS_BY_DEVICE + "-" + protocol
+}
+
+// The device index maps a device id to the list of ids of the agreements (active and archived) with that device.
+func getDeviceIndexEntry(ib *bolt.Bucket, deviceid string) ([]string, error) {
+	ids := make([]string, 0)
+	if v := ib.Get([]byte(deviceid)); v != nil {
+		if err := json.Unmarshal(v, &ids); err != nil {
+			return nil, fmt.Errorf("Unable to deserialize device index record for %v: %v, error: %v", deviceid, string(v), err)
+		}
+	}
+	return ids, nil
+}
+
+func putDeviceIndexEntry(ib *bolt.Bucket, deviceid string, ids []string) error {
+	if len(ids) == 0 {
+		return ib.Delete([]byte(deviceid))
+	} else if bytes, err := json.Marshal(ids); err != nil {
+		return fmt.Errorf("Unable to serialize device index record for %v: %v, error: %v", deviceid, ids, err)
+	} else if err := ib.Put([]byte(deviceid), bytes); err != nil {
+		return fmt.Errorf("Unable to write device index record for %v, error: %v", deviceid, err)
+	}
+	return nil
+}
+
+// Must be called within a read-write transaction.
+func (db *AgbotBoltDB) addToDeviceIndex(tx *bolt.Tx, protocol string, deviceid string, agreementid string) error {
+	if ib, err := tx.CreateBucketIfNotExists([]byte(db.deviceIndexBucketName(protocol))); err != nil {
+		return err
+	} else if ids, err := getDeviceIndexEntry(ib, deviceid); err != nil {
+		return err
+	} else {
+		for _, id := range ids {
+			if id == agreementid {
+				return nil
+			}
+		}
+		return putDeviceIndexEntry(ib, deviceid, append(ids, agreementid))
+	}
+}
+
+// Must be called within a read-write transaction.
+func (db *AgbotBoltDB) removeFromDeviceIndex(tx *bolt.Tx, protocol string, deviceid string, agreementid string) error {
+	ib := tx.Bucket([]byte(db.deviceIndexBucketName(protocol)))
+	if ib == nil {
+		return nil
+	}
+
+	ids, err := getDeviceIndexEntry(ib, deviceid)
+	if err != nil {
+		return err
+	}
+
+	newIds := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id != agreementid {
+			newIds = append(newIds, id)
+		}
+	}
+	return putDeviceIndexEntry(ib, deviceid, newIds)
+}
+
+// Build the device index for the agreements that were written before the index existed. This only happens once
+// for each protocol, when its index bucket does not exist yet.
+func (db *AgbotBoltDB) InitDeviceIndex() error {
+	for _, agdb := range db.agreementDBs() {
+		if err := db.initDeviceIndexInDB(agdb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *AgbotBoltDB) initDeviceIndexInDB(agdb *bolt.DB) error {
+	return agdb.Update(func(tx *bolt.Tx) error {
+		for _, protocol := range policy.AllAgreementProtocols() {
+			b := tx.Bucket([]byte(db.bucketName(protocol)))
+			if b == nil || tx.Bucket([]byte(db.deviceIndexBucketName(protocol))) != nil {
+				continue
+			}
+
+			glog.V(3).Infof("Building the device index for %v agreements", protocol)
+			if _, err := tx.CreateBucket([]byte(db.deviceIndexBucketName(protocol))); err != nil {
+				return err
+			}
+			if err := b.ForEach(func(k, v []byte) error {
+				var a persistence.Agreement
+				if err := json.Unmarshal(v, &a); err != nil {
+					glog.Errorf("Unable to deserialize db record: %v", v)
+					return nil
+				}
+				return db.addToDeviceIndex(tx, protocol, a.DeviceId, string(k))
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
@@ -0,0 +1,40 @@
+// +build unit
+
+package bolt
+
+import (
+	"github.com/open-horizon/anax/policy"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func Test_data_verification_history(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agbot-dv-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db := openTestDB(t, dir, "", false)
+	defer db.Close()
+	addTestAgreement(t, db, "a1", "org1")
+
+	// the checks are usually done in the same second in this test, each of them is kept
+	if _, err := db.DataVerified("a1", policy.BasicProtocol); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := db.DataNotVerified("a1", policy.BasicProtocol); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if ag, err := db.FindSingleAgreementByAgreementId("a1", policy.BasicProtocol, nil); err != nil || ag == nil {
+		t.Fatalf("agreement a1 should be found, found %v %v", ag, err)
+	} else if h := ag.DataVerificationHistory; len(h) != 3 || !h[0].Verified || h[1].Verified || h[2].Verified {
+		t.Errorf("expected a verified check and 2 missed checks, found %v", h)
+	} else if ag.DataVerificationMissedCount != 2 {
+		t.Errorf("expected 2 missed checks, found %v", ag.DataVerificationMissedCount)
+	}
+}
@@ -3,15 +3,15 @@ package bolt
 import (
 	"errors"
 	"fmt"
-	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
 	"github.com/open-horizon/anax/config"
+	"github.com/satori/go.uuid"
 	"os"
 	"path"
-	"time"
 )
 
 // Setup everything bolt DB needs to be able to run an agbot. Since bolt is a simple document based database,
-// all we need to setup is database file itself. There are no tables or indexes to create for bolt DB.
+// all we need to setup is database file itself and the agreement device index. There are no tables to create for bolt DB.
 func (db *AgbotBoltDB) Initialize(cfg *config.HorizonConfig) error {
 
 	if err := os.MkdirAll(cfg.AgreementBot.DBPath, 0700); err != nil {
@@ -20,11 +20,43 @@ func (db *AgbotBoltDB) Initialize(cfg *config.HorizonConfig) error {
 
 	dbname := path.Join(cfg.AgreementBot.DBPath, BOLTDB_DATABASE_NAME)
 
-	if agdb, err := bolt.Open(dbname, 0600, &bolt.Options{Timeout: 10 * time.Second}); err != nil {
+	// The bucket prefix allows several agbots in the same process to share the database file.
+	if agdb, err := acquireSharedDB(dbname, cfg.AgreementBot.DBBucketPrefix); err != nil {
 		return errors.New(fmt.Sprintf("unable to open bolt database %v, error: %v", dbname, err))
 	} else {
 		db.db = agdb
+		db.dbFile = dbname
+		db.prefix = cfg.AgreementBot.DBBucketPrefix
+	}
+
+	if id, err := uuid.NewV4(); err != nil {
+		return errors.New(fmt.Sprintf("unable to get UUID identity for this agbot, error: %v", err))
+	} else {
+		db.identity = id.String()
+	}
+
+	// Move the objects of an agbot that used this database before bucket prefixes were configured, if requested.
+	if db.prefix != "" && cfg.AgreementBot.DBBucketPrefixMigrate {
+		if moved, err := migrateBucketPrefix(db.db, "", db.prefix); err != nil {
+			return errors.New(fmt.Sprintf("unable to move the unprefixed buckets to prefix %v in database %v, error: %v", db.prefix, dbname, err))
+		} else if moved != 0 {
+			glog.V(1).Infof("Moved %v bolt buckets to prefix %v in database %v", moved, db.prefix, dbname)
+		}
+	}
+
+	// Open the existing agreement shards, if the agreements are sharded by org.
+	if cfg.AgreementBot.DBShardByOrg {
+		db.shardByOrg = true
+		if err := db.initShards(cfg.AgreementBot.DBPath); err != nil {
+			return err
+		} else if err := db.initShardIndex(); err != nil {
+			return errors.New(fmt.Sprintf("unable to init agreement shard index in database %v, error: %v", dbname, err))
+		}
+	}
 
+	// Build the agreement device index if this database was created before the index existed
+	if err := db.InitDeviceIndex(); err != nil {
+		return errors.New(fmt.Sprintf("unable to init agreement device index in database %v, error: %v", dbname, err))
 	}
 
 	// Initialize the one and only search session object
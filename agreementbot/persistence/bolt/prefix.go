@@ -0,0 +1,117 @@
+package bolt
+
+import (
+	"errors"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/policy"
+	"sync"
+	"time"
+)
+
+// Several agbot instances in the same process (for example one per tenant) can share a bolt DB file when each of them
+// is configured with a different bucket prefix. A bolt DB file can only be opened once per process, so the open
+// handles are shared and reference counted by the instances using them.
+type sharedBoltDB struct {
+	db       *bolt.DB
+	prefixes map[string]bool // the bucket prefixes of the agbot instances that are using the handle
+}
+
+var sharedDBsLock sync.Mutex
+var sharedDBs = make(map[string]*sharedBoltDB)
+
+// Get the shared handle for the bolt DB file, opening it if this is the first agbot instance to use it. It is an error
+// for two instances to use the same bucket prefix in the same file, they would overwrite each other's objects.
+func acquireSharedDB(dbFile string, prefix string) (*bolt.DB, error) {
+	sharedDBsLock.Lock()
+	defer sharedDBsLock.Unlock()
+
+	shared, ok := sharedDBs[dbFile]
+	if !ok {
+		agdb, err := bolt.Open(dbFile, 0600, &bolt.Options{Timeout: 10 * time.Second})
+		if err != nil {
+			return nil, err
+		}
+		shared = &sharedBoltDB{db: agdb, prefixes: make(map[string]bool)}
+		sharedDBs[dbFile] = shared
+	} else if shared.prefixes[prefix] {
+		return nil, errors.New(fmt.Sprintf("bolt database %v is already in use by an agbot with bucket prefix '%v'", dbFile, prefix))
+	}
+
+	shared.prefixes[prefix] = true
+	return shared.db, nil
+}
+
+// Release the shared handle and close the bolt DB file when the last agbot instance using it is done.
+func releaseSharedDB(dbFile string, prefix string) {
+	sharedDBsLock.Lock()
+	defer sharedDBsLock.Unlock()
+
+	if shared, ok := sharedDBs[dbFile]; ok {
+		delete(shared.prefixes, prefix)
+		if len(shared.prefixes) == 0 {
+			shared.db.Close()
+			delete(sharedDBs, dbFile)
+		}
+	}
+}
+
+// The names of all the buckets used by an agbot, without a prefix.
+func agbotBucketNames() []string {
+	names := []string{WORKLOAD_USAGE, SEARCH_SESSION_BUCKET, AGENT_UPGRADE_BUCKET, AGENT_UPGRADE_LEASE_BUCKET}
+	for _, protocol := range policy.AllAgreementProtocols() {
+		names = append(names, AGREEMENTS+"-"+protocol, AGREEMENTS_BY_DEVICE+"-"+protocol)
+	}
+	return names
+}
+
+// Move the agbot buckets with the fromPrefix to the same bucket names with the toPrefix, within a single transaction.
+// This is used to move the objects of an existing agbot, which has no prefix, into a prefix so that other agbots can
+// share the same bolt DB file. The objects of each bucket are copied first and the old bucket is deleted after. When the
+// target bucket already exists, only the objects that it does not have are copied. The buckets that were already moved
+// are skipped, so it is safe to run the migration every time the agbot starts. Returns the number of buckets that were
+// moved.
+func migrateBucketPrefix(agdb *bolt.DB, fromPrefix string, toPrefix string) (int, error) {
+	if fromPrefix == toPrefix {
+		return 0, nil
+	}
+
+	moved := 0
+	err := agdb.Update(func(tx *bolt.Tx) error {
+		for _, name := range agbotBucketNames() {
+			from := tx.Bucket([]byte(fromPrefix + name))
+			if from == nil {
+				continue
+			}
+
+			to, err := tx.CreateBucketIfNotExists([]byte(toPrefix + name))
+			if err != nil {
+				return err
+			}
+			if err := from.ForEach(func(k, v []byte) error {
+				if to.Get(k) != nil {
+					return nil
+				}
+				return to.Put(k, v)
+			}); err != nil {
+				return err
+			}
+			if from.Sequence() > to.Sequence() {
+				if err := to.SetSequence(from.Sequence()); err != nil {
+					return err
+				}
+			}
+			if err := tx.DeleteBucket([]byte(fromPrefix + name)); err != nil {
+				return err
+			}
+			glog.V(3).Infof("Moved bolt bucket %v to %v", fromPrefix+name, toPrefix+name)
+			moved += 1
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return moved, nil
+}
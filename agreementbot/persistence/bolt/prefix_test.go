@@ -0,0 +1,139 @@
+// +build unit
+
+package bolt
+
+import (
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/policy"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// Open an agbot bolt DB in the dir, with the bucket prefix.
+func openTestDB(t *testing.T, dir string, prefix string, migrate bool) *AgbotBoltDB {
+	cfg := &config.HorizonConfig{AgreementBot: config.AGConfig{DBPath: dir, DBBucketPrefix: prefix, DBBucketPrefixMigrate: migrate}}
+	db := &AgbotBoltDB{}
+	if err := db.Initialize(cfg); err != nil {
+		t.Fatalf("unable to open the database with prefix '%v', error: %v", prefix, err)
+	}
+	return db
+}
+
+func addTestAgreement(t *testing.T, db *AgbotBoltDB, agreementId string, org string) {
+	if err := db.AgreementAttempt(agreementId, org, org+"/node1", "device", "policy", "", "", "", policy.BasicProtocol, "", []string{"svc"}, policy.NodeHealth{}); err != nil {
+		t.Fatalf("unable to save agreement %v, error: %v", agreementId, err)
+	}
+}
+
+func countTestAgreements(t *testing.T, db *AgbotBoltDB) int {
+	if ags, err := db.FindAgreements(nil, policy.BasicProtocol); err != nil {
+		t.Fatalf("unable to find the agreements, error: %v", err)
+		return 0
+	} else {
+		return len(ags)
+	}
+}
+
+func hasBucket(t *testing.T, db *AgbotBoltDB, name string) bool {
+	found := false
+	if err := db.db.View(func(tx *bolt.Tx) error {
+		found = tx.Bucket([]byte(name)) != nil
+		return nil
+	}); err != nil {
+		t.Fatalf("unable to read the database, error: %v", err)
+	}
+	return found
+}
+
+func Test_prefix_open(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agbot-prefix-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db1 := openTestDB(t, dir, "tenant1-", false)
+	defer db1.Close()
+	db2 := openTestDB(t, dir, "tenant2-", false)
+	defer db2.Close()
+
+	addTestAgreement(t, db1, "a1", "org1")
+	if n := countTestAgreements(t, db1); n != 1 {
+		t.Errorf("tenant1 should have 1 agreement, found %v", n)
+	}
+	if n := countTestAgreements(t, db2); n != 0 {
+		t.Errorf("tenant2 should not see the agreements of tenant1, found %v", n)
+	}
+
+	// two agbots with the same prefix would overwrite each other's objects
+	cfg := &config.HorizonConfig{AgreementBot: config.AGConfig{DBPath: dir, DBBucketPrefix: "tenant1-"}}
+	if err := (&AgbotBoltDB{}).Initialize(cfg); err == nil {
+		t.Errorf("opening the database twice with the same prefix should fail")
+	}
+}
+
+func Test_prefix_migrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agbot-prefix-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// an agbot that was running before the bucket prefixes were configured
+	db := openTestDB(t, dir, "", false)
+	addTestAgreement(t, db, "a1", "org1")
+	addTestAgreement(t, db, "a2", "org1")
+	db.Close()
+
+	db = openTestDB(t, dir, "tenant1-", true)
+	if n := countTestAgreements(t, db); n != 2 {
+		t.Errorf("the 2 agreements should have been moved to the prefix, found %v", n)
+	}
+	if hasBucket(t, db, AGREEMENTS+"-"+policy.BasicProtocol) {
+		t.Errorf("the unprefixed agreement bucket should have been deleted")
+	}
+	if !hasBucket(t, db, "tenant1-"+SEARCH_SESSION_BUCKET) {
+		t.Errorf("the search session bucket should have been moved")
+	}
+	addTestAgreement(t, db, "a3", "org1")
+	db.Close()
+
+	// the migration runs every time the agbot starts, nothing is left to move
+	db = openTestDB(t, dir, "tenant1-", true)
+	if n := countTestAgreements(t, db); n != 3 {
+		t.Errorf("the agreements should be kept when the migration runs again, found %v", n)
+	}
+	if moved, err := migrateBucketPrefix(db.db, "", "tenant1-"); err != nil {
+		t.Errorf("migrating again should not fail, error: %v", err)
+	} else if moved != 0 {
+		t.Errorf("no bucket should be moved again, found %v", moved)
+	}
+	db.Close()
+}
+
+func Test_prefix_migrate_existing_target(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agbot-prefix-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the agbot ran with the prefix before the migration was turned on, so both buckets exist
+	db := openTestDB(t, dir, "", false)
+	addTestAgreement(t, db, "a1", "org1")
+	db.Close()
+	db = openTestDB(t, dir, "tenant1-", false)
+	addTestAgreement(t, db, "a2", "org1")
+	db.Close()
+
+	db = openTestDB(t, dir, "tenant1-", true)
+	defer db.Close()
+	if n := countTestAgreements(t, db); n != 2 {
+		t.Errorf("the agreements of both buckets should be kept, found %v", n)
+	}
+	if hasBucket(t, db, AGREEMENTS+"-"+policy.BasicProtocol) {
+		t.Errorf("the unprefixed agreement bucket should have been deleted")
+	}
+}
@@ -0,0 +1,175 @@
+package bolt
+
+import (
+	"errors"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/policy"
+	"os"
+	"path"
+	"sync"
+	"time"
+)
+
+func init() {
+	persistence.RegisterReplicaProvider(NewAgreementReplica)
+}
+
+// The agreements can be replicated to a read only bolt DB file, so that reporting and dashboard queries read the replica
+// instead of contending with the agbot's write path. Changes are received through the agreement watch API and applied
+// to the replica asynchronously, so the replica is eventually consistent with the agbot's database. The replica can be
+// made from either database implementation.
+
+const REPLICA_DATABASE_NAME = "agreementbot-replica.db"
+const REPLICA_QUEUE_SIZE = 1000 // The number of changes that can be waiting to be applied before the replica is copied again
+
+type agreementReplicator struct {
+	source     persistence.AgbotDatabase
+	replica    *bolt.DB
+	changes    chan persistence.AgreementChange
+	resync     bool // set when a change could not be queued or applied, the whole replica is copied again
+	resyncLock sync.Mutex
+}
+
+// Create the replica and start applying the agreement changes to it. The returned reader reads the replica.
+func NewAgreementReplica(source persistence.AgbotDatabase, cfg *config.HorizonConfig) (persistence.AgreementReader, error) {
+
+	if err := os.MkdirAll(cfg.AgreementBot.ReplicaDBPath, 0700); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to create directory %v for the agreement replica, error: %v", cfg.AgreementBot.ReplicaDBPath, err))
+	}
+
+	dbname := path.Join(cfg.AgreementBot.ReplicaDBPath, REPLICA_DATABASE_NAME)
+	replica, err := bolt.Open(dbname, 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to open agreement replica %v, error: %v", dbname, err))
+	}
+
+	r := &agreementReplicator{
+		source:  source,
+		replica: replica,
+		changes: make(chan persistence.AgreementChange, REPLICA_QUEUE_SIZE),
+	}
+
+	// The watcher is registered only once the initial copy has succeeded, so that a replica that failed to start is not
+	// left behind receiving changes. The changes committed during the initial copy are picked up by a second full copy,
+	// which the replicator makes as soon as it starts, the replica converges on the latest committed state of each
+	// agreement.
+	if err := r.copyAll(); err != nil {
+		replica.Close()
+		return nil, errors.New(fmt.Sprintf("unable to copy the agreements to replica %v, error: %v", dbname, err))
+	}
+
+	r.setResync(true)
+	persistence.WatchAgreements(r.queueChange)
+	go r.run()
+
+	glog.V(1).Infof("Agreements are replicated to %v", dbname)
+	return &AgbotBoltDB{db: replica, dbFile: dbname}, nil
+}
+
+// Called by the watch API on the goroutine that changed the agreement, so it must not block.
+func (r *agreementReplicator) queueChange(change persistence.AgreementChange) {
+	select {
+	case r.changes <- change:
+	default:
+		glog.Warningf("Agreement replica queue is full, dropping change %v and scheduling a full copy", change)
+		r.setResync(true)
+	}
+}
+
+func (r *agreementReplicator) setResync(resync bool) bool {
+	r.resyncLock.Lock()
+	defer r.resyncLock.Unlock()
+	previous := r.resync
+	r.resync = resync
+	return previous
+}
+
+func (r *agreementReplicator) run() {
+	if r.setResync(false) {
+		r.resynchronize()
+	}
+	for change := range r.changes {
+		if r.setResync(false) {
+			r.resynchronize()
+		} else if err := r.applyChange(change); err != nil {
+			glog.Errorf("Unable to apply change %v to the agreement replica, scheduling a full copy, error: %v", change, err)
+			r.setResync(true)
+		}
+	}
+}
+
+// Discard the queued changes, they are all included in a full copy of the agreements.
+func (r *agreementReplicator) resynchronize() {
+	for drained := false; !drained; {
+		select {
+		case <-r.changes:
+		default:
+			drained = true
+		}
+	}
+
+	if err := r.copyAll(); err != nil {
+		glog.Errorf("Unable to copy the agreements to the replica, error: %v", err)
+		r.setResync(true)
+	}
+}
+
+func (r *agreementReplicator) applyChange(change persistence.AgreementChange) error {
+	return r.replica.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(AGREEMENTS + "-" + change.Protocol))
+		if err != nil {
+			return err
+		}
+
+		if change.Type == persistence.AGREEMENT_CHANGE_DELETED || change.Agreement == nil {
+			return b.Delete([]byte(change.AgreementId))
+		} else if serialized, err := persistence.MarshalAgreement(change.Agreement); err != nil {
+			return fmt.Errorf("Failed to serialize agreement record: %v, error: %v", change.Agreement, err)
+		} else {
+			return b.Put([]byte(change.AgreementId), serialized)
+		}
+	})
+}
+
+// Replace the agreements in the replica with the agreements in the source database.
+func (r *agreementReplicator) copyAll() error {
+	copied := 0
+	err := r.replica.Update(func(tx *bolt.Tx) error {
+		for _, protocol := range policy.AllAgreementProtocols() {
+			bucket := []byte(AGREEMENTS + "-" + protocol)
+			if tx.Bucket(bucket) != nil {
+				if err := tx.DeleteBucket(bucket); err != nil {
+					return err
+				}
+			}
+
+			b, err := tx.CreateBucket(bucket)
+			if err != nil {
+				return err
+			}
+
+			agreements, err := r.source.FindAgreements([]persistence.AFilter{}, protocol)
+			if err != nil {
+				return err
+			}
+
+			for _, ag := range agreements {
+				if serialized, err := persistence.MarshalAgreement(&ag); err != nil {
+					return fmt.Errorf("Failed to serialize agreement record: %v, error: %v", ag, err)
+				} else if err := b.Put([]byte(ag.CurrentAgreementId), serialized); err != nil {
+					return err
+				}
+				copied += 1
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		glog.V(3).Infof("Copied %v agreements to the agreement replica", copied)
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+// +build unit
+
+package bolt
+
+import (
+	"errors"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/policy"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+// A source database that is unable to read its agreements.
+type failingSource struct {
+	persistence.AgbotDatabase
+}
+
+func (f failingSource) FindAgreements(filters []persistence.AFilter, protocol string) ([]persistence.Agreement, error) {
+	return nil, errors.New("source is down")
+}
+
+func Test_replica_copy_and_watch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agbot-replica-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	source := openTestDB(t, dir, "", false)
+	defer source.Close()
+	addTestAgreement(t, source, "a1", "org1")
+
+	cfg := &config.HorizonConfig{AgreementBot: config.AGConfig{ReplicaDBPath: path.Join(dir, "replica")}}
+	reader, err := NewAgreementReplica(source, cfg)
+	if err != nil {
+		t.Fatalf("unable to create the replica, error: %v", err)
+	}
+	replica := reader.(*AgbotBoltDB)
+	if n := countTestAgreements(t, replica); n != 1 {
+		t.Errorf("the existing agreement should have been copied to the replica, found %v", n)
+	}
+
+	// the agreements saved after the replica started are applied asynchronously
+	addTestAgreement(t, source, "a2", "org1")
+	for i := 0; i < 50 && countTestAgreements(t, replica) != 2; i++ {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if n := countTestAgreements(t, replica); n != 2 {
+		t.Errorf("the new agreement should have been applied to the replica, found %v", n)
+	}
+	if ag, err := replica.FindSingleAgreementByAgreementIdAllProtocols("a2", []string{policy.BasicProtocol}, nil); err != nil || ag == nil {
+		t.Errorf("agreement a2 should be in the replica, found %v %v", ag, err)
+	}
+}
+
+func Test_replica_copy_error(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agbot-replica-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg := &config.HorizonConfig{AgreementBot: config.AGConfig{ReplicaDBPath: dir}}
+	if _, err := NewAgreementReplica(failingSource{}, cfg); err == nil {
+		t.Fatalf("the replica should fail when the agreements cannot be copied")
+	}
+
+	// the replica file must have been closed, bolt can only open it once
+	if db, err := bolt.Open(path.Join(dir, REPLICA_DATABASE_NAME), 0600, &bolt.Options{Timeout: time.Second}); err != nil {
+		t.Errorf("the replica file should have been closed, error: %v", err)
+	} else {
+		db.Close()
+	}
+
+	// the replica directory cannot be created under a file
+	file := path.Join(dir, "file")
+	if err := ioutil.WriteFile(file, []byte{}, 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg.AgreementBot.ReplicaDBPath = path.Join(file, "replica")
+	if _, err := NewAgreementReplica(failingSource{}, cfg); err == nil {
+		t.Errorf("the replica should fail when its directory cannot be created")
+	}
+}
@@ -112,7 +112,7 @@ func (db *AgbotBoltDB) findSearchSession() (*SearchSession, error) {
 	mod := new(SearchSession)
 
 	readErr := db.db.View(func(tx *bolt.Tx) error {
-		if b := tx.Bucket([]byte(ssBucketName())); b != nil {
+		if b := tx.Bucket([]byte(db.ssBucketName())); b != nil {
 			return b.ForEach(func(k, v []byte) error {
 
 				if err := json.Unmarshal(v, mod); err != nil {
@@ -135,7 +135,7 @@ func (db *AgbotBoltDB) findSearchSession() (*SearchSession, error) {
 // Saves the one and only SearchSession object to the DB.
 func (db *AgbotBoltDB) saveSearchSession(ss *SearchSession) error {
 	writeErr := db.db.Update(func(tx *bolt.Tx) error {
-		b, err := tx.CreateBucketIfNotExists([]byte(ssBucketName()))
+		b, err := tx.CreateBucketIfNotExists([]byte(db.ssBucketName()))
 		if err != nil {
 			return err
 		}
@@ -143,13 +143,13 @@ func (db *AgbotBoltDB) saveSearchSession(ss *SearchSession) error {
 		if serial, err := json.Marshal(ss); err != nil {
 			return fmt.Errorf("Failed to serialize search session: %v. Error: %v", *ss, err)
 		} else {
-			return b.Put([]byte(ssBucketName()), serial)
+			return b.Put([]byte(SEARCH_SESSION_BUCKET), serial)
 		}
 	})
 
 	return writeErr
 }
 
-func ssBucketName() string {
-	return SEARCH_SESSION_BUCKET
+func (db *AgbotBoltDB) ssBucketName() string {
+	return db.prefix + SEARCH_SESSION_BUCKET
 }
@@ -0,0 +1,197 @@
+package bolt
+
+import (
+	"errors"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/policy"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// When the agbot is configured to shard agreements by org, the agreements made for each org are kept in their own bolt DB
+// file, so that scanning the agreements of one org is not slowed down by a very large number of agreements in another org.
+// The main database file holds the workload usages, the search session and the agreements that were made before sharding
+// was enabled. The main database also holds the shard index, which maps the id of each agreement in a shard to the org of
+// the shard, so that a lookup by agreement id goes straight to the shard that holds the agreement.
+
+const SHARD_FILE_PREFIX = "agreementbot-org-"
+const SHARD_FILE_SUFFIX = ".db"
+
+const AGREEMENT_SHARDS = "agreement-shards" // The bolt DB bucket name for the index of the shard orgs by agreement id.
+
+func (db *AgbotBoltDB) shardIndexBucketName(protocol string) string {
+	return db.prefix + AGREEMENT_SHARDS + "-" + protocol
+}
+
+func shardFileName(dbPath string, org string) string {
+	return path.Join(dbPath, SHARD_FILE_PREFIX+url.PathEscape(org)+SHARD_FILE_SUFFIX)
+}
+
+// Open the shards that already exist in the database directory. Shards for other orgs are opened when the first
+// agreement for the org is made.
+func (db *AgbotBoltDB) initShards(dbPath string) error {
+	db.shards = make(map[string]*bolt.DB)
+
+	files, err := filepath.Glob(path.Join(dbPath, SHARD_FILE_PREFIX+"*"+SHARD_FILE_SUFFIX))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(path.Base(file), SHARD_FILE_PREFIX), SHARD_FILE_SUFFIX)
+		if org, err := url.PathUnescape(name); err != nil {
+			glog.Warningf("Ignoring bolt database file %v, it is not a valid agreement shard name", file)
+		} else if shard, err := acquireSharedDB(file, db.prefix); err != nil {
+			return errors.New(fmt.Sprintf("unable to open agreement shard %v, error: %v", file, err))
+		} else {
+			db.shards[org] = shard
+			glog.V(3).Infof("Opened agreement shard %v for org %v", file, org)
+		}
+	}
+	return nil
+}
+
+func (db *AgbotBoltDB) getShard(org string) *bolt.DB {
+	db.shardsLock.RLock()
+	defer db.shardsLock.RUnlock()
+	return db.shards[org]
+}
+
+// Return the database that new agreements for the org are written to, opening the org's shard if necessary.
+func (db *AgbotBoltDB) agreementDBForOrg(org string) (*bolt.DB, error) {
+	if !db.shardByOrg || org == "" {
+		return db.db, nil
+	} else if shard := db.getShard(org); shard != nil {
+		return shard, nil
+	}
+
+	db.shardsLock.Lock()
+	defer db.shardsLock.Unlock()
+
+	if shard, ok := db.shards[org]; ok {
+		return shard, nil
+	}
+
+	file := shardFileName(path.Dir(db.dbFile), org)
+	shard, err := acquireSharedDB(file, db.prefix)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to open agreement shard %v, error: %v", file, err))
+	}
+	db.shards[org] = shard
+	glog.V(3).Infof("Opened agreement shard %v for org %v", file, org)
+	return shard, nil
+}
+
+// Return all the databases that hold agreements, the main database first and then the shards in org order.
+func (db *AgbotBoltDB) agreementDBs() []*bolt.DB {
+	dbs := []*bolt.DB{db.db}
+	if !db.shardByOrg {
+		return dbs
+	}
+
+	db.shardsLock.RLock()
+	defer db.shardsLock.RUnlock()
+
+	orgs := make([]string, 0, len(db.shards))
+	for org := range db.shards {
+		orgs = append(orgs, org)
+	}
+	sort.Strings(orgs)
+	for _, org := range orgs {
+		dbs = append(dbs, db.shards[org])
+	}
+	return dbs
+}
+
+// Return the database holding the agreement, from the shard index. If the agreement is not in a shard, the main database
+// is returned so that the caller handles the missing agreement the same way it does without sharding.
+func (db *AgbotBoltDB) agreementDBForId(agreementid string, protocol string) *bolt.DB {
+	if !db.shardByOrg {
+		return db.db
+	}
+
+	org := ""
+	db.db.View(func(tx *bolt.Tx) error {
+		if ib := tx.Bucket([]byte(db.shardIndexBucketName(protocol))); ib != nil {
+			org = string(ib.Get([]byte(agreementid)))
+		}
+		return nil
+	})
+	if shard := db.getShard(org); org != "" && shard != nil {
+		return shard
+	}
+	return db.db
+}
+
+// Record the org of the shard that an agreement is written to. The shard index is in the main database, so it cannot be
+// updated in the transaction that writes the agreement to its shard. It is written first, an entry for an agreement that
+// was not written is the same as a missing agreement.
+func (db *AgbotBoltDB) addToShardIndex(protocol string, agreementid string, org string) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		if ib, err := tx.CreateBucketIfNotExists([]byte(db.shardIndexBucketName(protocol))); err != nil {
+			return err
+		} else if err := ib.Put([]byte(agreementid), []byte(org)); err != nil {
+			return fmt.Errorf("Unable to write shard index record for %v, error: %v", agreementid, err)
+		}
+		return nil
+	})
+}
+
+func (db *AgbotBoltDB) removeFromShardIndex(protocol string, agreementid string) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		if ib := tx.Bucket([]byte(db.shardIndexBucketName(protocol))); ib != nil {
+			return ib.Delete([]byte(agreementid))
+		}
+		return nil
+	})
+}
+
+// Build the shard index for the agreements that were written to the shards before the index existed. This only happens
+// once for each protocol, when its index bucket does not exist yet.
+func (db *AgbotBoltDB) initShardIndex() error {
+	db.shardsLock.RLock()
+	defer db.shardsLock.RUnlock()
+
+	return db.db.Update(func(tx *bolt.Tx) error {
+		for _, protocol := range policy.AllAgreementProtocols() {
+			if tx.Bucket([]byte(db.shardIndexBucketName(protocol))) != nil {
+				continue
+			}
+
+			glog.V(3).Infof("Building the shard index for %v agreements", protocol)
+			ib, err := tx.CreateBucket([]byte(db.shardIndexBucketName(protocol)))
+			if err != nil {
+				return err
+			}
+
+			for org, shard := range db.shards {
+				if err := shard.View(func(stx *bolt.Tx) error {
+					if b := stx.Bucket([]byte(db.bucketName(protocol))); b != nil {
+						return b.ForEach(func(k, v []byte) error {
+							return ib.Put(append([]byte{}, k...), []byte(org))
+						})
+					}
+					return nil
+				}); err != nil {
+					return fmt.Errorf("Unable to index the %v agreements of shard %v, error: %v", protocol, org, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (db *AgbotBoltDB) closeShards() {
+	db.shardsLock.Lock()
+	defer db.shardsLock.Unlock()
+
+	for org, shard := range db.shards {
+		releaseSharedDB(shard.Path(), db.prefix)
+		delete(db.shards, org)
+	}
+}
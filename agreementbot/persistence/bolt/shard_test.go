@@ -0,0 +1,90 @@
+// +build unit
+
+package bolt
+
+import (
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/policy"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func openShardedTestDB(t *testing.T, dir string) *AgbotBoltDB {
+	cfg := &config.HorizonConfig{AgreementBot: config.AGConfig{DBPath: dir, DBShardByOrg: true}}
+	db := &AgbotBoltDB{}
+	if err := db.Initialize(cfg); err != nil {
+		t.Fatalf("unable to open the sharded database, error: %v", err)
+	}
+	return db
+}
+
+func Test_shard_routing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agbot-shard-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db := openShardedTestDB(t, dir)
+	addTestAgreement(t, db, "a1", "org1")
+	addTestAgreement(t, db, "a2", "org2")
+
+	// the lookups by id go to the shard of the org of the agreement
+	for id, org := range map[string]string{"a1": "org1", "a2": "org2"} {
+		if agdb := db.agreementDBForId(id, policy.BasicProtocol); agdb != db.getShard(org) || agdb == nil {
+			t.Errorf("agreement %v should be routed to the shard of %v, found %v", id, org, agdb)
+		}
+		if ag, err := db.FindSingleAgreementByAgreementId(id, policy.BasicProtocol, nil); err != nil || ag == nil || ag.Org != org {
+			t.Errorf("agreement %v should be found, found %v %v", id, ag, err)
+		}
+	}
+	if agdb := db.agreementDBForId("unknown", policy.BasicProtocol); agdb != db.db {
+		t.Errorf("an unknown agreement should be looked up in the main database, found %v", agdb)
+	}
+
+	// an agreement in a shard is updated in its shard
+	if _, err := db.ArchiveAgreement("a1", policy.BasicProtocol, 0, "test"); err != nil {
+		t.Errorf("unable to archive agreement a1, error: %v", err)
+	} else if ag, err := db.FindSingleAgreementByAgreementId("a1", policy.BasicProtocol, nil); err != nil || ag == nil || !ag.Archived {
+		t.Errorf("agreement a1 should be archived, found %v %v", ag, err)
+	}
+
+	// the index entry is removed with the agreement
+	if err := db.DeleteAgreement("a1", policy.BasicProtocol); err != nil {
+		t.Errorf("unable to delete agreement a1, error: %v", err)
+	} else if db.agreementDBForId("a1", policy.BasicProtocol) != db.db {
+		t.Errorf("the shard index entry of a1 should have been removed")
+	} else if n := countTestAgreements(t, db); n != 1 {
+		t.Errorf("expected 1 agreement left, found %v", n)
+	}
+	db.Close()
+}
+
+func Test_shard_index_init(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agbot-shard-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the shards were written before the shard index existed
+	db := openShardedTestDB(t, dir)
+	addTestAgreement(t, db, "a1", "org1")
+	addTestAgreement(t, db, "a2", "org2")
+	if err := db.db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket([]byte(db.shardIndexBucketName(policy.BasicProtocol)))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	db = openShardedTestDB(t, dir)
+	defer db.Close()
+	for id, org := range map[string]string{"a1": "org1", "a2": "org2"} {
+		if agdb := db.agreementDBForId(id, policy.BasicProtocol); agdb != db.getShard(org) || agdb == nil {
+			t.Errorf("the shard index should have been rebuilt for agreement %v of %v, found %v", id, org, agdb)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package bolt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/policy"
+	"io"
+	"time"
+)
+
+// Write a consistent copy of the bolt DB file to w, without blocking the agbot's writes. The agreement shards are
+// separate files, so a snapshot is not possible when the agreements are sharded by org.
+func (db *AgbotBoltDB) Snapshot(w io.Writer) (int64, error) {
+	if db.shardByOrg {
+		return 0, errors.New("snapshots of the bolt database are not supported when the agreements are sharded by org")
+	}
+
+	var written int64
+	err := db.db.View(func(tx *bolt.Tx) error {
+		var err error
+		written, err = tx.WriteTo(w)
+		return err
+	})
+	return written, err
+}
+
+// ValidateSnapshot checks that the bolt DB file is a usable agbot database before it is swapped in by a restore. Every
+// agreement in the buckets with the given prefix must be readable. Returns the number of agreements in the snapshot.
+func ValidateSnapshot(dbFile string, prefix string) (int, error) {
+	snapshot, err := bolt.Open(dbFile, 0600, &bolt.Options{Timeout: 10 * time.Second, ReadOnly: true})
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("unable to open snapshot %v, error: %v", dbFile, err))
+	}
+	defer snapshot.Close()
+
+	count := 0
+	err = snapshot.View(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(prefix+SEARCH_SESSION_BUCKET)) == nil {
+			return errors.New(fmt.Sprintf("bucket %v is missing", prefix+SEARCH_SESSION_BUCKET))
+		}
+
+		for _, protocol := range policy.AllAgreementProtocols() {
+			b := tx.Bucket([]byte(prefix + AGREEMENTS + "-" + protocol))
+			if b == nil {
+				continue
+			}
+
+			if err := b.ForEach(func(k, v []byte) error {
+				var a persistence.Agreement
+				if err := json.Unmarshal(v, &a); err != nil {
+					return errors.New(fmt.Sprintf("unable to demarshal agreement %v, error: %v", string(k), err))
+				} else if a.CurrentAgreementId != string(k) {
+					return errors.New(fmt.Sprintf("agreement %v is stored with key %v", a.CurrentAgreementId, string(k)))
+				}
+				count += 1
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("snapshot %v is not a valid agbot database, error: %v", dbFile, err))
+	}
+	return count, nil
+}
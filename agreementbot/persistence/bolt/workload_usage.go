@@ -18,7 +18,7 @@ func (db *AgbotBoltDB) NewWorkloadUsage(deviceId string, hapartners []string, po
 		return err
 	} else if existing != nil {
 		return fmt.Errorf("Workload usage record for device %v and policy name %v already exists.", deviceId, policyName)
-	} else if err := db.WUPersistNew(wuBucketName(), wlUsage); err != nil {
+	} else if err := db.WUPersistNew(db.wuBucketName(), wlUsage); err != nil {
 		return err
 	} else {
 		return nil
@@ -86,7 +86,7 @@ func (db *AgbotBoltDB) SingleWorkloadUsageUpdate(deviceid string, policyName str
 // does whole-member replacements of values that are legal to change during the course of a workload usage
 func (db *AgbotBoltDB) persistUpdatedWorkloadUsage(id uint64, update *persistence.WorkloadUsage) error {
 	return db.db.Update(func(tx *bolt.Tx) error {
-		if b, err := tx.CreateBucketIfNotExists([]byte(wuBucketName())); err != nil {
+		if b, err := tx.CreateBucketIfNotExists([]byte(db.wuBucketName())); err != nil {
 			return err
 		} else {
 			pKey := strconv.FormatUint(id, 10)
@@ -130,9 +130,9 @@ func (db *AgbotBoltDB) DeleteWorkloadUsage(deviceid string, policyName string) e
 
 			pk := wlUsage.Id
 			return db.db.Update(func(tx *bolt.Tx) error {
-				b := tx.Bucket([]byte(wuBucketName()))
+				b := tx.Bucket([]byte(db.wuBucketName()))
 				if b == nil {
-					return fmt.Errorf("Unknown bucket: %v", wuBucketName())
+					return fmt.Errorf("Unknown bucket: %v", db.wuBucketName())
 				} else if existing := b.Get([]byte(strconv.FormatUint(pk, 10))); existing == nil {
 					glog.Errorf("Warning: record deletion requested, but record does not exist: %v", pk)
 					return nil // handle already-deleted workload usage as success
@@ -156,7 +156,7 @@ func (db *AgbotBoltDB) FindWorkloadUsages(filters []persistence.WUFilter) ([]per
 
 	readErr := db.db.View(func(tx *bolt.Tx) error {
 
-		if b := tx.Bucket([]byte(wuBucketName())); b != nil {
+		if b := tx.Bucket([]byte(db.wuBucketName())); b != nil {
 			b.ForEach(func(k, v []byte) error {
 
 				var a persistence.WorkloadUsage
@@ -220,6 +220,6 @@ func (db *AgbotBoltDB) WUPersistNew(bucket string, record *persistence.WorkloadU
 	}
 }
 
-func wuBucketName() string {
-	return WORKLOAD_USAGE
+func (db *AgbotBoltDB) wuBucketName() string {
+	return db.prefix + WORKLOAD_USAGE
 }
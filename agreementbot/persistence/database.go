@@ -29,8 +29,11 @@ type AgbotDatabase interface {
 	FindAgreements(filters []AFilter, protocol string) ([]Agreement, error)
 	FindSingleAgreementByAgreementId(agreementid string, protocol string, filters []AFilter) (*Agreement, error)
 	FindSingleAgreementByAgreementIdAllProtocols(agreementid string, protocols []string, filters []AFilter) (*Agreement, error)
+	FindAgreementsByDeviceId(deviceid string, protocol string, filters []AFilter) ([]Agreement, error)
+	FindAgreementsByOrg(org string, filters []AFilter, protocol string) ([]Agreement, error)
 
 	GetAgreementCount(partition string) (int64, int64, error)
+	GetAgreementStatistics(groupBy string, since uint64, until uint64) (*AgreementStatistics, error)
 
 	SingleAgreementUpdate(agreementid string, protocol string, fn func(Agreement) *Agreement) (*Agreement, error)
 
@@ -68,6 +71,12 @@ type AgbotDatabase interface {
 
 	DeleteWorkloadUsage(deviceid string, policyName string) error
 
+	// Agent upgrade rollout related functions. The rollout states are shared by all the agbot instances.
+	FindAgentUpgrades(nmpName string) ([]AgentUpgrade, error) // all the rollouts when nmpName is empty
+	SaveAgentUpgrade(au *AgentUpgrade) error
+	DeleteAgentUpgrades(nmpName string) error
+	ObtainAgentUpgradeLease(nmpName string, leaseS uint64) (bool, error) // true when this agbot instance may move the rollout forward
+
 	// Function related to persistence of search sessions with the Exchange.
 	ObtainSearchSession(policyName string) (string, uint64, error)
 	UpdateSearchSessionChangedSince(currentChangedSince uint64, newChangedSince uint64, policyName string) (bool, error)
@@ -0,0 +1,224 @@
+package persistence
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
+)
+
+// The sensitive fields of an agreement, such as the proposal which contains the user inputs of the workload, can be
+// encrypted before the agreement is written to the database, so that a copy of the database does not reveal them.
+// The database implementations serialize agreements with MarshalAgreement and UnmarshalAgreement, which encrypt and
+// decrypt the fields when a field key provider is set. An encrypted field holds the id of the key it was encrypted
+// with, so after the current key is rotated the fields encrypted with older keys can still be read, as long as the
+// provider can still return the older keys. A field is encrypted with the current key the next time the agreement is
+// written. Fields written before encryption was turned on are plain text, they are read as they are.
+
+const ENCRYPTED_FIELD_PREFIX = "hznenc:v1:"
+
+// The length of the AES-256 keys that fields are encrypted with.
+const FIELD_KEY_LENGTH = 32
+
+// The hooks to manage the keys that agreement fields are encrypted with.
+type FieldKeyProvider interface {
+	CurrentKey() (string, []byte, error) // Returns the id and the value of the key that new fields are encrypted with
+	Key(id string) ([]byte, error)       // Returns the key with the given id, used to decrypt fields
+}
+
+var fieldKeyLock sync.RWMutex
+var fieldKeyProvider FieldKeyProvider
+
+// Set the key provider used to encrypt and decrypt agreement fields. Nil turns encryption off, fields that are
+// already encrypted can no longer be read.
+func SetFieldKeyProvider(provider FieldKeyProvider) {
+	fieldKeyLock.Lock()
+	defer fieldKeyLock.Unlock()
+	fieldKeyProvider = provider
+}
+
+func getFieldKeyProvider() FieldKeyProvider {
+	fieldKeyLock.RLock()
+	defer fieldKeyLock.RUnlock()
+	return fieldKeyProvider
+}
+
+// The fields of an agreement that are encrypted.
+func sensitiveAgreementFields(a *Agreement) []*string {
+	return []*string{&a.Proposal, &a.Policy, &a.DataVerificationPW}
+}
+
+// Serialize an agreement for the database, encrypting the sensitive fields when a key provider is set.
+func MarshalAgreement(a *Agreement) ([]byte, error) {
+	provider := getFieldKeyProvider()
+	if provider == nil {
+		return json.Marshal(a)
+	}
+
+	keyId, key, err := provider.CurrentKey()
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to get the current field encryption key, error: %v", err))
+	}
+
+	encrypted := *a
+	for _, field := range sensitiveAgreementFields(&encrypted) {
+		if *field == "" || strings.HasPrefix(*field, ENCRYPTED_FIELD_PREFIX) {
+			continue
+		} else if value, err := encryptField(keyId, key, *field); err != nil {
+			return nil, errors.New(fmt.Sprintf("unable to encrypt a field of agreement %v, error: %v", a.CurrentAgreementId, err))
+		} else {
+			*field = value
+		}
+	}
+	return json.Marshal(encrypted)
+}
+
+// Deserialize an agreement from the database, decrypting the sensitive fields that are encrypted.
+func UnmarshalAgreement(data []byte, a *Agreement) error {
+	if err := json.Unmarshal(data, a); err != nil {
+		return err
+	}
+
+	for _, field := range sensitiveAgreementFields(a) {
+		if !strings.HasPrefix(*field, ENCRYPTED_FIELD_PREFIX) {
+			continue
+		} else if value, err := decryptField(*field); err != nil {
+			return errors.New(fmt.Sprintf("unable to decrypt a field of agreement %v, error: %v", a.CurrentAgreementId, err))
+		} else {
+			*field = value
+		}
+	}
+	return nil
+}
+
+// An encrypted field is the prefix, the key id, a colon and the base64 encoded nonce and AES-GCM ciphertext.
+func encryptField(keyId string, key []byte, value string) (string, error) {
+	gcm, err := newFieldCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.New(fmt.Sprintf("unable to generate nonce, error: %v", err))
+	}
+
+	// The key id is authenticated with the value, so that a field cannot be moved to another key id.
+	sealed := gcm.Seal(nonce, nonce, []byte(value), []byte(keyId))
+	return ENCRYPTED_FIELD_PREFIX + keyId + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptField(field string) (string, error) {
+	provider := getFieldKeyProvider()
+	if provider == nil {
+		return "", errors.New(fmt.Sprintf("the field is encrypted but there is no field encryption key provider"))
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(field, ENCRYPTED_FIELD_PREFIX), ":", 2)
+	if len(parts) != 2 {
+		return "", errors.New(fmt.Sprintf("the encrypted field is not in the expected format"))
+	}
+	keyId := parts[0]
+
+	key, err := provider.Key(keyId)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("unable to get field encryption key %v, error: %v", keyId, err))
+	}
+
+	gcm, err := newFieldCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("the encrypted field is not base64 encoded, error: %v", err))
+	} else if len(sealed) < gcm.NonceSize() {
+		return "", errors.New(fmt.Sprintf("the encrypted field is too short"))
+	}
+
+	if value, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(keyId)); err != nil {
+		return "", errors.New(fmt.Sprintf("unable to decrypt with key %v, error: %v", keyId, err))
+	} else {
+		return string(value), nil
+	}
+}
+
+func newFieldCipher(key []byte) (cipher.AEAD, error) {
+	if block, err := aes.NewCipher(key); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to create cipher, error: %v", err))
+	} else if gcm, err := cipher.NewGCM(block); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to create GCM cipher, error: %v", err))
+	} else {
+		return gcm, nil
+	}
+}
+
+// A key provider that reads the keys from files in a directory. The key with id <id> is in the file <id>.key, which
+// contains either the 32 raw bytes of the key or the key hex encoded. To rotate the key, add a new key file and change
+// the current key id, the older key files must be kept until every agreement encrypted with them has been rewritten
+// or archived and purged.
+type fileFieldKeyProvider struct {
+	keyDir       string
+	currentKeyId string
+	lock         sync.Mutex
+	keys         map[string][]byte
+}
+
+func NewFileFieldKeyProvider(keyDir string, currentKeyId string) (FieldKeyProvider, error) {
+	p := &fileFieldKeyProvider{
+		keyDir:       keyDir,
+		currentKeyId: currentKeyId,
+		keys:         make(map[string][]byte),
+	}
+
+	// Make sure the current key can be read when the agbot starts, rather than on the first write.
+	if _, err := p.Key(currentKeyId); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *fileFieldKeyProvider) CurrentKey() (string, []byte, error) {
+	key, err := p.Key(p.currentKeyId)
+	return p.currentKeyId, key, err
+}
+
+func (p *fileFieldKeyProvider) Key(id string) ([]byte, error) {
+	if id == "" || strings.ContainsAny(id, ":/\\") || id == "." || id == ".." {
+		return nil, errors.New(fmt.Sprintf("invalid field encryption key id %v", id))
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if key, ok := p.keys[id]; ok {
+		return key, nil
+	}
+
+	keyFile := path.Join(p.keyDir, id+".key")
+	content, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to read field encryption key file %v, error: %v", keyFile, err))
+	}
+
+	key := content
+	if len(key) != FIELD_KEY_LENGTH {
+		if decoded, err := hex.DecodeString(strings.TrimSpace(string(content))); err != nil || len(decoded) != FIELD_KEY_LENGTH {
+			return nil, errors.New(fmt.Sprintf("field encryption key file %v must contain a %v byte key, raw or hex encoded", keyFile, FIELD_KEY_LENGTH))
+		} else {
+			key = decoded
+		}
+	}
+
+	p.keys[id] = key
+	return key, nil
+}
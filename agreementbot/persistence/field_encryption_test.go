@@ -0,0 +1,144 @@
+// +build unit
+
+package persistence
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+// Write a key file for the file key provider, hex encoded or raw.
+func writeTestKey(t *testing.T, dir string, id string, fill byte, encodeHex bool) {
+	key := bytes.Repeat([]byte{fill}, FIELD_KEY_LENGTH)
+	content := key
+	if encodeHex {
+		content = []byte(hex.EncodeToString(key) + "\n")
+	}
+	if err := ioutil.WriteFile(path.Join(dir, id+".key"), content, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestKeyProvider(t *testing.T, dir string, currentKeyId string) FieldKeyProvider {
+	p, err := NewFileFieldKeyProvider(dir, currentKeyId)
+	if err != nil {
+		t.Fatalf("unable to create the key provider, error: %v", err)
+	}
+	return p
+}
+
+func Test_field_encryption(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agbot-fieldkeys-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer SetFieldKeyProvider(nil)
+
+	writeTestKey(t, dir, "k1", 1, false)
+	SetFieldKeyProvider(newTestKeyProvider(t, dir, "k1"))
+
+	ag := Agreement{CurrentAgreementId: "a1", Proposal: "the proposal", Policy: "the policy", DataVerificationPW: "thepw", Org: "org1"}
+	data, err := MarshalAgreement(&ag)
+	if err != nil {
+		t.Fatalf("unable to marshal the agreement, error: %v", err)
+	}
+	for _, plain := range []string{"the proposal", "the policy", "thepw"} {
+		if strings.Contains(string(data), plain) {
+			t.Errorf("the field %q should be encrypted: %s", plain, data)
+		}
+	}
+	if !strings.Contains(string(data), ENCRYPTED_FIELD_PREFIX+"k1:") || !strings.Contains(string(data), "org1") {
+		t.Errorf("the fields should be encrypted with k1 and the others kept: %s", data)
+	}
+	if ag.Proposal != "the proposal" {
+		t.Errorf("the agreement that is marshaled should not be changed, found %v", ag.Proposal)
+	}
+
+	var read Agreement
+	if err := UnmarshalAgreement(data, &read); err != nil {
+		t.Fatalf("unable to unmarshal the agreement, error: %v", err)
+	} else if read.Proposal != ag.Proposal || read.Policy != ag.Policy || read.DataVerificationPW != ag.DataVerificationPW {
+		t.Errorf("the fields should be decrypted, found %v", read)
+	}
+
+	// the agreements written before encryption was turned on are read as they are
+	if err := UnmarshalAgreement([]byte(`{"current_agreement_id":"a0","proposal":"plain"}`), &read); err != nil || read.Proposal != "plain" {
+		t.Errorf("a plain text field should be read as it is, found %v %v", read.Proposal, err)
+	}
+
+	// after the rotation the new fields are encrypted with k2, and the ones encrypted with k1 can still be read
+	writeTestKey(t, dir, "k2", 2, true)
+	SetFieldKeyProvider(newTestKeyProvider(t, dir, "k2"))
+	if err := UnmarshalAgreement(data, &read); err != nil || read.Proposal != "the proposal" {
+		t.Errorf("the field encrypted with k1 should be read after the rotation, found %v %v", read.Proposal, err)
+	}
+	rotated, err := MarshalAgreement(&read)
+	if err != nil {
+		t.Fatalf("unable to marshal the agreement, error: %v", err)
+	} else if !strings.Contains(string(rotated), ENCRYPTED_FIELD_PREFIX+"k2:") || strings.Contains(string(rotated), ENCRYPTED_FIELD_PREFIX+"k1:") {
+		t.Errorf("the fields should be encrypted with k2: %s", rotated)
+	}
+
+	// once k1 is removed, the fields encrypted with it cannot be read
+	os.Remove(path.Join(dir, "k1.key"))
+	SetFieldKeyProvider(newTestKeyProvider(t, dir, "k2"))
+	if err := UnmarshalAgreement(data, &read); err == nil {
+		t.Errorf("the field encrypted with a removed key should not be read")
+	}
+	if err := UnmarshalAgreement(rotated, &read); err != nil || read.Proposal != "the proposal" {
+		t.Errorf("the field encrypted with k2 should be read, found %v %v", read.Proposal, err)
+	}
+
+	// without a provider the encrypted fields cannot be read
+	SetFieldKeyProvider(nil)
+	if err := UnmarshalAgreement(rotated, &read); err == nil {
+		t.Errorf("an encrypted field should not be read without a key provider")
+	}
+}
+
+func Test_field_encryption_wrong_key(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agbot-fieldkeys-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer SetFieldKeyProvider(nil)
+
+	writeTestKey(t, dir, "k1", 1, false)
+	SetFieldKeyProvider(newTestKeyProvider(t, dir, "k1"))
+	data, err := MarshalAgreement(&Agreement{CurrentAgreementId: "a1", Proposal: "the proposal"})
+	if err != nil {
+		t.Fatalf("unable to marshal the agreement, error: %v", err)
+	}
+
+	// the key file of k1 was replaced by another key
+	writeTestKey(t, dir, "k1", 3, true)
+	SetFieldKeyProvider(newTestKeyProvider(t, dir, "k1"))
+	var read Agreement
+	if err := UnmarshalAgreement(data, &read); err == nil {
+		t.Errorf("the field should not be decrypted with the wrong key, found %v", read.Proposal)
+	}
+
+	// a field cannot be moved to the id of another key
+	writeTestKey(t, dir, "k2", 1, false)
+	moved := strings.Replace(string(data), ENCRYPTED_FIELD_PREFIX+"k1:", ENCRYPTED_FIELD_PREFIX+"k2:", 1)
+	if err := UnmarshalAgreement([]byte(moved), &read); err == nil {
+		t.Errorf("the field should not be decrypted with another key id, found %v", read.Proposal)
+	}
+
+	// the key files must hold the key, and the key ids must not be paths
+	if err := ioutil.WriteFile(path.Join(dir, "short.key"), []byte("abcd"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"short", "missing", "../k1", ""} {
+		if _, err := NewFileFieldKeyProvider(dir, id); err == nil {
+			t.Errorf("the key %q should not be usable", id)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package postgresql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+)
+
+// Constants for the SQL statements that are used to work with the agent upgrade rollouts. The rollout of an agent upgrade
+// is shared by all the agbot instances, so this table is not partitioned.
+//
+// agent_upgrades schema:
+// nmp_name:      The node management policy with the agent upgrade job, in the form org/name.
+// node_id:       The node's exchange id.
+// agent_upgrade: The agent upgrade object which is a JSON blob. The blob schema is defined by the AgentUpgrade struct in the persistence package.
+// updated:       A timestamp to record last updated time.
+const AGENT_UPGRADE_CREATE_MAIN_TABLE = `CREATE TABLE IF NOT EXISTS agent_upgrades (
+	nmp_name text NOT NULL,
+	node_id text NOT NULL,
+	agent_upgrade jsonb NOT NULL,
+	updated timestamp with time zone DEFAULT current_timestamp,
+	PRIMARY KEY (nmp_name, node_id)
+);`
+
+// Only one agbot instance moves a rollout forward at a time. The instance that does it holds a lease on the rollout,
+// which it renews every time it moves the rollout forward. Another instance takes the lease over when it has expired.
+//
+// agent_upgrade_leases schema:
+// nmp_name: The node management policy with the agent upgrade job, in the form org/name.
+// owner:    The UUID of the agbot instance that holds the lease, the same identity as in the partitions table.
+// expires:  The time the lease expires.
+const AGENT_UPGRADE_LEASE_CREATE_TABLE = `CREATE TABLE IF NOT EXISTS agent_upgrade_leases (
+	nmp_name text PRIMARY KEY,
+	owner text NOT NULL,
+	expires timestamp with time zone NOT NULL
+);`
+
+// The lease is inserted, renewed by its owner or taken over when it has expired, otherwise no row is changed.
+const AGENT_UPGRADE_LEASE_UPSERT = `INSERT INTO agent_upgrade_leases (nmp_name, owner, expires) VALUES ($1, $2, current_timestamp + $3 * interval '1 second')
+	ON CONFLICT (nmp_name) DO UPDATE SET owner = EXCLUDED.owner, expires = EXCLUDED.expires
+	WHERE agent_upgrade_leases.owner = EXCLUDED.owner OR agent_upgrade_leases.expires < current_timestamp;`
+
+const AGENT_UPGRADE_QUERY = `SELECT agent_upgrade FROM agent_upgrades WHERE nmp_name = $1;`
+const ALL_AGENT_UPGRADE_QUERY = `SELECT agent_upgrade FROM agent_upgrades;`
+
+const AGENT_UPGRADE_UPSERT = `INSERT INTO agent_upgrades (nmp_name, node_id, agent_upgrade) VALUES ($1, $2, $3)
+	ON CONFLICT (nmp_name, node_id) DO UPDATE SET agent_upgrade = EXCLUDED.agent_upgrade, updated = current_timestamp;`
+
+const AGENT_UPGRADE_DELETE = `DELETE FROM agent_upgrades WHERE nmp_name = $1;`
+
+func (db *AgbotPostgresqlDB) FindAgentUpgrades(nmpName string) ([]persistence.AgentUpgrade, error) {
+	upgrades := make([]persistence.AgentUpgrade, 0)
+
+	var rows *sql.Rows
+	var err error
+	if nmpName == "" {
+		rows, err = db.db.Query(ALL_AGENT_UPGRADE_QUERY)
+	} else {
+		rows, err = db.db.Query(AGENT_UPGRADE_QUERY, nmpName)
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("error querying for agent upgrades, error: %v", err))
+	}
+
+	// If the rows object doesnt get closed, memory and connections will grow and/or leak.
+	defer rows.Close()
+	for rows.Next() {
+		auBytes := make([]byte, 0, 512)
+		var au persistence.AgentUpgrade
+		if err := rows.Scan(&auBytes); err != nil {
+			return nil, errors.New(fmt.Sprintf("error scanning row: %v", err))
+		} else if err := json.Unmarshal(auBytes, &au); err != nil {
+			return nil, errors.New(fmt.Sprintf("error demarshalling row: %v, error: %v", string(auBytes), err))
+		} else {
+			upgrades = append(upgrades, au)
+		}
+	}
+
+	// The rows.Next() function will exit with false when done or an error occurred. Get any error encountered during iteration.
+	if err = rows.Err(); err != nil {
+		return nil, errors.New(fmt.Sprintf("error iterating: %v", err))
+	}
+	return upgrades, nil
+}
+
+func (db *AgbotPostgresqlDB) SaveAgentUpgrade(au *persistence.AgentUpgrade) error {
+	if auBytes, err := json.Marshal(au); err != nil {
+		return errors.New(fmt.Sprintf("error marshalling agent upgrade %v, error: %v", au, err))
+	} else if _, err := db.db.Exec(AGENT_UPGRADE_UPSERT, au.NMPName, au.NodeId, auBytes); err != nil {
+		return errors.New(fmt.Sprintf("error saving agent upgrade %v, error: %v", au, err))
+	}
+	return nil
+}
+
+func (db *AgbotPostgresqlDB) DeleteAgentUpgrades(nmpName string) error {
+	if _, err := db.db.Exec(AGENT_UPGRADE_DELETE, nmpName); err != nil {
+		return errors.New(fmt.Sprintf("error deleting the agent upgrades of node management policy %v, error: %v", nmpName, err))
+	}
+	return nil
+}
+
+func (db *AgbotPostgresqlDB) ObtainAgentUpgradeLease(nmpName string, leaseS uint64) (bool, error) {
+	if res, err := db.db.Exec(AGENT_UPGRADE_LEASE_UPSERT, nmpName, db.identity, leaseS); err != nil {
+		return false, errors.New(fmt.Sprintf("error obtaining the agent upgrade lease of node management policy %v, error: %v", nmpName, err))
+	} else if rows, err := res.RowsAffected(); err != nil {
+		return false, errors.New(fmt.Sprintf("error obtaining the agent upgrade lease of node management policy %v, error: %v", nmpName, err))
+	} else {
+		return rows == 1, nil
+	}
+}
@@ -92,6 +92,8 @@ const AGREEMENT_CREATE_PARTITION_TABLE = `CREATE TABLE IF NOT EXISTS "agreements
 	CHECK ( partition = 'partition_name' )
 ) INHERITS (agreements);`
 const AGREEMENT_CREATE_PARTITION_INDEX = `CREATE INDEX IF NOT EXISTS "agreement_id_index_on_agreements_ ON "agreements_ (agreement_id);`
+const AGREEMENT_CREATE_PARTITION_DEVICE_INDEX = `CREATE INDEX IF NOT EXISTS "device_id_index_on_agreements_ ON "agreements_ ((agreement->>'device_id'));`
+const AGREEMENT_CREATE_PARTITION_ORG_INDEX = `CREATE INDEX IF NOT EXISTS "org_index_on_agreements_ ON "agreements_ ((agreement->>'org'));`
 
 // Please note that the following SQL statement has a different syntax where the table name is specified. Note the use of
 // single quotes instead of double quotes that are used in all the other SQL. Don't ya just love SQL syntax consistency.
@@ -102,6 +104,8 @@ const AGREEMENT_PARTITION_FILLIN = `partition_name`
 
 const AGREEMENT_QUERY = `SELECT agreement FROM "agreements_ WHERE agreement_id = $1 AND protocol = $2;`
 const ALL_AGREEMENTS_QUERY = `SELECT agreement FROM "agreements_ WHERE protocol = $1;`
+const DEVICE_AGREEMENTS_QUERY = `SELECT agreement FROM "agreements_ WHERE agreement->>'device_id' = $1 AND protocol = $2;`
+const ORG_AGREEMENTS_QUERY = `SELECT agreement FROM "agreements_ WHERE agreement->>'org' = $1 AND protocol = $2;`
 const AGREEMENT_PARTITION_EMPTY = `SELECT agreement_id FROM "agreements_;`
 
 const AGREEMENT_COUNT = `SELECT agreement FROM "agreements_;`
@@ -156,6 +160,16 @@ func (db *AgbotPostgresqlDB) GetPrimaryAgreementPartitionTableIndexCreate() stri
 	return sql
 }
 
+func (db *AgbotPostgresqlDB) GetAgreementPartitionTableDeviceIndexCreate(partition string) string {
+	sql := strings.Replace(AGREEMENT_CREATE_PARTITION_DEVICE_INDEX, AGREEMENT_TABLE_NAME_ROOT, db.GetAgreementPartitionTableName(partition), 2)
+	return sql
+}
+
+func (db *AgbotPostgresqlDB) GetAgreementPartitionTableOrgIndexCreate(partition string) string {
+	sql := strings.Replace(AGREEMENT_CREATE_PARTITION_ORG_INDEX, AGREEMENT_TABLE_NAME_ROOT, db.GetAgreementPartitionTableName(partition), 2)
+	return sql
+}
+
 func (db *AgbotPostgresqlDB) GetAgreementPartitionTableDrop(partition string) string {
 	sql := strings.Replace(AGREEMENT_DROP_PARTITION, AGREEMENT_TABLE_NAME_ROOT, db.GetAgreementPartitionTableName(partition), 1)
 	return sql
@@ -181,6 +195,39 @@ func (db *AgbotPostgresqlDB) GetAgreementPartitionMove(fromPartition string, toP
 	return sql
 }
 
+// The indexes that were added to the agreement partition tables after the partitions were introduced. They are created on
+// every partition table, not only on the primary one, because the tables of the other partitions might have been
+// created by an older level of the agbot. The queries that use them run on all the partitions of the agbot.
+func (db *AgbotPostgresqlDB) getAgreementPartitionTableIndexesCreate(partition string) []string {
+	return []string{db.GetAgreementPartitionTableDeviceIndexCreate(partition), db.GetAgreementPartitionTableOrgIndexCreate(partition)}
+}
+
+// Create the indexes on the tables of all the agreement partitions. The primary partition must be indexed, the other
+// partitions might be moved and dropped by another agbot at the same time, so they are indexed when possible.
+func (db *AgbotPostgresqlDB) createAgreementPartitionIndexes() error {
+
+	ids, err := db.findPartitionIds()
+	if err != nil {
+		return err
+	}
+
+	partitions, err := db.VerifyPartitions(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, partition := range partitions {
+		for _, sql := range db.getAgreementPartitionTableIndexesCreate(partition) {
+			if _, err := db.db.Exec(sql); err != nil && partition == db.PrimaryPartition() {
+				return errors.New(fmt.Sprintf("unable to create agreements partition table index %v, error: %v", sql, err))
+			} else if err != nil {
+				glog.Warningf("Unable to create agreements partition table index %v, error: %v", sql, err)
+			}
+		}
+	}
+	return nil
+}
+
 func (db *AgbotPostgresqlDB) FindAgreementPartitions() ([]string, error) {
 
 	// Find all the agreement partitions.
@@ -252,6 +299,10 @@ func (db *AgbotPostgresqlDB) GetAgreementCount(partition string) (int64, int64,
 	return activeNum, archivedNum, nil
 }
 
+func (db *AgbotPostgresqlDB) GetAgreementStatistics(groupBy string, since uint64, until uint64) (*persistence.AgreementStatistics, error) {
+	return persistence.GetAgreementStatistics(db, policy.AllAgreementProtocols(), groupBy, since, until)
+}
+
 // Retrieve all agreements from the database and filter them out based on the input filters.
 func (db *AgbotPostgresqlDB) FindAgreements(filters []persistence.AFilter, protocol string) ([]persistence.Agreement, error) {
 
@@ -274,7 +325,7 @@ func (db *AgbotPostgresqlDB) FindAgreements(filters []persistence.AFilter, proto
 			ag := new(persistence.Agreement)
 			if err := rows.Scan(&agBytes); err != nil {
 				return nil, errors.New(fmt.Sprintf("error scanning row: %v", err))
-			} else if err := json.Unmarshal(agBytes, ag); err != nil {
+			} else if err := persistence.UnmarshalAgreement(agBytes, ag); err != nil {
 				return nil, errors.New(fmt.Sprintf("error demarshalling row: %v, error: %v", string(agBytes), err))
 			} else {
 				if !ag.Archived {
@@ -296,6 +347,54 @@ func (db *AgbotPostgresqlDB) FindAgreements(filters []persistence.AFilter, proto
 
 }
 
+// Retrieve the agreements with a given device from the database, using the device id index on each partition table,
+// and filter them based on the input filters.
+func (db *AgbotPostgresqlDB) FindAgreementsByDeviceId(deviceid string, protocol string, filters []persistence.AFilter) ([]persistence.Agreement, error) {
+	return db.findAgreementsByColumn(DEVICE_AGREEMENTS_QUERY, deviceid, protocol, filters)
+}
+
+// Retrieve the agreements for an org from the database, using the org index on each partition table, and filter them
+// based on the input filters.
+func (db *AgbotPostgresqlDB) FindAgreementsByOrg(org string, filters []persistence.AFilter, protocol string) ([]persistence.Agreement, error) {
+	return db.findAgreementsByColumn(ORG_AGREEMENTS_QUERY, org, protocol, filters)
+}
+
+func (db *AgbotPostgresqlDB) findAgreementsByColumn(query string, value string, protocol string, filters []persistence.AFilter) ([]persistence.Agreement, error) {
+
+	ags := make([]persistence.Agreement, 0, 10)
+
+	for _, currentPartition := range db.AllPartitions() {
+		sql := strings.Replace(query, AGREEMENT_TABLE_NAME_ROOT, db.GetAgreementPartitionTableName(currentPartition), 1)
+		glog.V(5).Infof("Find agreements for %v using SQL: %v for partition %v", value, sql, currentPartition)
+		rows, err := db.db.Query(sql, value, protocol)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("error querying for agreements for %v error: %v", value, err))
+		}
+
+		// If the rows object doesnt get closed, memory and connections will grow and/or leak.
+		defer rows.Close()
+		for rows.Next() {
+			agBytes := make([]byte, 0, 2048)
+			ag := new(persistence.Agreement)
+			if err := rows.Scan(&agBytes); err != nil {
+				return nil, errors.New(fmt.Sprintf("error scanning row: %v", err))
+			} else if err := persistence.UnmarshalAgreement(agBytes, ag); err != nil {
+				return nil, errors.New(fmt.Sprintf("error demarshalling row: %v, error: %v", string(agBytes), err))
+			} else if agPassed := persistence.RunFilters(ag, filters); agPassed != nil {
+				ags = append(ags, *ag)
+			}
+		}
+
+		// The rows.Next() function will exit with false when done or an error occurred. Get any error encountered during iteration.
+		if err = rows.Err(); err != nil {
+			return nil, errors.New(fmt.Sprintf("error iterating: %v", err))
+		}
+	}
+
+	return ags, nil
+
+}
+
 // Find a specific agreement in the database. The input filters are ignored for this query. They are needed by the bolt implementation.
 func (db *AgbotPostgresqlDB) internalFindSingleAgreementByAgreementId(tx *sql.Tx, agreementId string, protocol string, filters []persistence.AFilter) (*persistence.Agreement, string, error) {
 
@@ -320,7 +419,7 @@ func (db *AgbotPostgresqlDB) internalFindSingleAgreementByAgreementId(tx *sql.Tx
 			continue
 		}
 
-		if err := json.Unmarshal(agBytes, ag); err != nil {
+		if err := persistence.UnmarshalAgreement(agBytes, ag); err != nil {
 			return nil, "", errors.New(fmt.Sprintf("error demarshalling row: %v, error: %v", string(agBytes), err))
 		} else if agPassed := persistence.RunFilters(ag, filters); agPassed == nil {
 			return nil, "", nil // Agreement ids are unique. If we found the one we want but the filters rejected it, then we're done. No need to look at more partitions.
@@ -417,8 +516,11 @@ func (db *AgbotPostgresqlDB) DeleteAgreement(agreementid string, protocol string
 
 	if err := db.deleteAgreement(tx, agreementid, protocol); err != nil {
 		return err
+	} else if err := tx.Commit(); err != nil {
+		return err
 	} else {
-		return tx.Commit()
+		persistence.NotifyAgreementChange(persistence.AGREEMENT_CHANGE_DELETED, protocol, agreementid, nil)
+		return nil
 	}
 }
 
@@ -449,29 +551,33 @@ func (db *AgbotPostgresqlDB) wrapTransaction(agreementid string, protocol string
 
 	if tx, err := db.db.Begin(); err != nil {
 		return err
-	} else if err := db.persistUpdatedAgreement(tx, agreementid, protocol, updated); err != nil {
+	} else if mod, err := db.persistUpdatedAgreement(tx, agreementid, protocol, updated); err != nil {
 		tx.Rollback()
 		return err
+	} else if err := tx.Commit(); err != nil {
+		return err
 	} else {
-		return tx.Commit()
+		persistence.NotifyAgreementChange(persistence.AGREEMENT_CHANGE_UPDATED, protocol, agreementid, mod)
+		return nil
 	}
 
 }
 
 // This function runs inside a transaction. It will atomicly read the agreement from the DB, verify that the updated
-// agreement object contains valid state transitions, and then write the updated agreement back to the database.
-func (db *AgbotPostgresqlDB) persistUpdatedAgreement(tx *sql.Tx, agreementid string, protocol string, update *persistence.Agreement) error {
+// agreement object contains valid state transitions, and then write the updated agreement back to the database. The
+// agreement as it was written is returned.
+func (db *AgbotPostgresqlDB) persistUpdatedAgreement(tx *sql.Tx, agreementid string, protocol string, update *persistence.Agreement) (*persistence.Agreement, error) {
 
 	if mod, partition, err := db.internalFindSingleAgreementByAgreementId(tx, agreementid, protocol, []persistence.AFilter{}); err != nil {
-		return err
+		return nil, err
 	} else if mod == nil {
-		return errors.New(fmt.Sprintf("No agreement with given id available to update: %v", agreementid))
+		return nil, errors.New(fmt.Sprintf("No agreement with given id available to update: %v", agreementid))
 	} else {
 		// This code is running in a database transaction. Within the tx, the current record (mod) is
 		// read and then updated according to the updates within the input update record. It is critical
 		// to check for correct data transitions within the tx.
 		persistence.ValidateStateTransition(mod, update)
-		return db.updateAgreement(tx, mod, protocol, partition)
+		return mod, db.updateAgreement(tx, mod, protocol, partition)
 	}
 }
 
@@ -479,12 +585,13 @@ func (db *AgbotPostgresqlDB) insertAgreement(ag *persistence.Agreement, protocol
 
 	sql := strings.Replace(AGREEMENT_INSERT, AGREEMENT_TABLE_NAME_ROOT, db.GetAgreementPartitionTableName(db.PrimaryPartition()), 1)
 
-	if agm, err := json.Marshal(ag); err != nil {
+	if agm, err := persistence.MarshalAgreement(ag); err != nil {
 		return err
 	} else if _, err = db.db.Exec(sql, ag.CurrentAgreementId, protocol, db.PrimaryPartition(), agm); err != nil {
 		return err
 	} else {
 		glog.V(2).Infof("Succeeded creating agreement record %v", *ag)
+		persistence.NotifyAgreementChange(persistence.AGREEMENT_CHANGE_CREATED, protocol, ag.CurrentAgreementId, ag)
 	}
 
 	return nil
@@ -494,7 +601,7 @@ func (db *AgbotPostgresqlDB) updateAgreement(tx *sql.Tx, ag *persistence.Agreeme
 
 	sql := strings.Replace(AGREEMENT_UPDATE, AGREEMENT_TABLE_NAME_ROOT, db.GetAgreementPartitionTableName(partition), 1)
 
-	if agm, err := json.Marshal(ag); err != nil {
+	if agm, err := persistence.MarshalAgreement(ag); err != nil {
 		return err
 	} else if _, err = tx.Exec(sql, ag.CurrentAgreementId, protocol, agm); err != nil {
 		return err
@@ -0,0 +1,189 @@
+// +build unit
+
+package postgresql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// A database/sql driver that records the statements that are run, and answers the queries about the partitions, so
+// that the SQL of the partitions can be tested without a postgresql server. The data source name is the name of the
+// test, which finds its fakeDB.
+type fakeDriver struct{}
+
+type fakeDB struct {
+	lock       sync.Mutex
+	partitions []string        // the ids in the partitions table
+	tables     map[string]bool // the partitions that have an agreement table
+	failing    map[string]bool // the partitions whose statements fail
+	execs      []string
+}
+
+var fakeDBs sync.Map
+
+func init() {
+	sql.Register("fakepostgresql", fakeDriver{})
+}
+
+func newFakeDB(t *testing.T, primary string, partitions ...string) (*AgbotPostgresqlDB, *fakeDB) {
+	fake := &fakeDB{partitions: partitions, tables: map[string]bool{}, failing: map[string]bool{}}
+	for _, partition := range partitions {
+		fake.tables[partition] = true
+	}
+	fakeDBs.Store(t.Name(), fake)
+
+	handle, err := sql.Open("fakepostgresql", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &AgbotPostgresqlDB{db: handle, primaryPartition: primary, partitions: []string{primary}}, fake
+}
+
+// The statements that were run on the agreement table of the partition.
+func (f *fakeDB) execsOn(partition string) []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	execs := []string{}
+	for _, sql := range f.execs {
+		if strings.Contains(sql, `"agreements_`+partition+`"`) {
+			execs = append(execs, sql)
+		}
+	}
+	return execs
+}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	if fake, ok := fakeDBs.Load(name); ok {
+		return &fakeConn{db: fake.(*fakeDB)}, nil
+	}
+	return nil, errors.New("no fake database " + name)
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.lock.Lock()
+	defer s.db.lock.Unlock()
+	for partition := range s.db.failing {
+		if strings.Contains(s.query, `"agreements_`+partition+`"`) {
+			return nil, errors.New("relation does not exist")
+		}
+	}
+	s.db.execs = append(s.db.execs, s.query)
+	return driver.RowsAffected(0), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.lock.Lock()
+	defer s.db.lock.Unlock()
+	rows := &fakeRows{}
+	if s.query == PARTITION_IDS {
+		for _, id := range s.db.partitions {
+			rows.values = append(rows.values, []driver.Value{id})
+		}
+	} else if strings.HasPrefix(s.query, "SELECT to_regclass(") {
+		// the table name is the result when the table exists, null otherwise
+		table := strings.TrimSuffix(strings.TrimPrefix(s.query, "SELECT to_regclass('"), "');")
+		if s.db.tables[strings.TrimPrefix(table, AGREEMENT_TABLE_NAME_ROOT)] {
+			rows.values = append(rows.values, []driver.Value{[]byte(table)})
+		} else {
+			rows.values = append(rows.values, []driver.Value{nil})
+		}
+	} else {
+		return nil, errors.New("unexpected query " + s.query)
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	values [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"value"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func Test_agreement_partition_index_sql(t *testing.T) {
+	db := &AgbotPostgresqlDB{primaryPartition: "1"}
+	if sql := db.GetAgreementPartitionTableDeviceIndexCreate("3"); sql != `CREATE INDEX IF NOT EXISTS "device_id_index_on_agreements_3" ON "agreements_3" ((agreement->>'device_id'));` {
+		t.Errorf("unexpected device index SQL %v", sql)
+	}
+	if sql := db.GetAgreementPartitionTableOrgIndexCreate("3"); sql != `CREATE INDEX IF NOT EXISTS "org_index_on_agreements_3" ON "agreements_3" ((agreement->>'org'));` {
+		t.Errorf("unexpected org index SQL %v", sql)
+	}
+}
+
+func Test_createAgreementPartitionIndexes(t *testing.T) {
+	db, fake := newFakeDB(t, "2", "1", "2", "3")
+	defer db.db.Close()
+
+	// partition 3 was moved and dropped by another agbot
+	delete(fake.tables, "3")
+	if err := db.createAgreementPartitionIndexes(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, partition := range []string{"1", "2"} {
+		if execs := fake.execsOn(partition); len(execs) != len(db.getAgreementPartitionTableIndexesCreate(partition)) {
+			t.Errorf("expected the indexes to be created on partition %v, found %v", partition, execs)
+		}
+	}
+	if execs := fake.execsOn("3"); len(execs) != 0 {
+		t.Errorf("expected no index on the dropped partition, found %v", execs)
+	}
+
+	// only the indexes of the primary partition are required
+	fake.failing["1"] = true
+	if err := db.createAgreementPartitionIndexes(); err != nil {
+		t.Errorf("an index that cannot be created on another partition should not fail, found %v", err)
+	}
+	fake.failing["2"] = true
+	if err := db.createAgreementPartitionIndexes(); err == nil {
+		t.Errorf("an index that cannot be created on the primary partition should fail")
+	}
+}
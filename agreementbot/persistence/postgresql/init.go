@@ -89,6 +89,15 @@ func (db *AgbotPostgresqlDB) Initialize(cfg *config.HorizonConfig) error {
 			return errors.New(fmt.Sprintf("unable to create agreements partition table, error: %v", err))
 		} else if _, err := db.db.Exec(db.GetPrimaryAgreementPartitionTableIndexCreate()); err != nil {
 			return errors.New(fmt.Sprintf("unable to create agreements partition table index, error: %v", err))
+		} else if err := db.createAgreementPartitionIndexes(); err != nil {
+			return err
+		}
+
+		// Create the agent upgrade tables if necessary.
+		if _, err := db.db.Exec(AGENT_UPGRADE_CREATE_MAIN_TABLE); err != nil {
+			return errors.New(fmt.Sprintf("unable to create agent upgrades table, error: %v", err))
+		} else if _, err := db.db.Exec(AGENT_UPGRADE_LEASE_CREATE_TABLE); err != nil {
+			return errors.New(fmt.Sprintf("unable to create agent upgrade leases table, error: %v", err))
 		}
 
 		glog.V(3).Infof("Postgresql primary partition database tables exist.")
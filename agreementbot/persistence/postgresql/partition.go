@@ -48,6 +48,8 @@ const PARTITION_QUIESCE = `UPDATE partitions SET owner = NULL, heartbeat = NULL
 
 const PARTITION_DELETE = `DELETE FROM partitions WHERE id = $1;`
 
+const PARTITION_IDS = `SELECT id FROM partitions;`
+
 // The complexity of the WHERE clause should not be underestimated. Each row is scanned whlie the table is locked
 // so we are sure that no other agbot can even read this table until this query is complete. This query runs in a
 // transaction that is controlled by the functions in this package.
@@ -160,6 +162,35 @@ func (db *AgbotPostgresqlDB) FindPartitions() ([]string, error) {
 
 }
 
+// Locate the ids of all the partitions in the partition table, including the partitions that do not have any agreements.
+func (db *AgbotPostgresqlDB) findPartitionIds() ([]string, error) {
+
+	ids := make([]string, 0, 5)
+
+	rows, err := db.db.Query(PARTITION_IDS)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("error querying for partitions: %v", err))
+	}
+
+	// If the rows object doesnt get closed, memory and connections will grow and/or leak.
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.New(fmt.Sprintf("error scanning row: %v", err))
+		} else {
+			ids = append(ids, id)
+		}
+	}
+
+	// The rows.Next() function will exit with false when done or an error occurred. Get any error encountered during iteration.
+	if err = rows.Err(); err != nil {
+		return nil, errors.New(fmt.Sprintf("error iterating: %v", err))
+	}
+
+	return ids, nil
+}
+
 // Retrieve the partition owner for a given partition.
 func (db *AgbotPostgresqlDB) GetPartitionOwner(id string) (string, error) {
 
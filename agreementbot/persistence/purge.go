@@ -0,0 +1,53 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/cutil"
+)
+
+// Remove the agreements and workload usages that this agbot has for a node, to satisfy a request to remove the data
+// about the node. The node must not have an active agreement with this agbot, since the agreement would be made again
+// if it was removed; the agreements must be cancelled and archived first. What was removed is added to the report.
+func PurgeDeviceRecords(db AgbotDatabase, deviceId string, protocols []string, report *cutil.DeletionReport) error {
+
+	// Find all the agreements first, nothing is removed while the node has an active agreement.
+	agreements := make(map[string][]Agreement)
+	for _, protocol := range protocols {
+		if ags, err := db.FindAgreementsByDeviceId(deviceId, protocol, []AFilter{}); err != nil {
+			return errors.New(fmt.Sprintf("unable to read the agreements of node %v, error: %v", deviceId, err))
+		} else {
+			for _, ag := range ags {
+				if !ag.Archived {
+					return errors.New(fmt.Sprintf("node %v has an active agreement %v, the agreements of the node must be cancelled before its data can be purged", deviceId, ag.CurrentAgreementId))
+				}
+			}
+			agreements[protocol] = ags
+		}
+	}
+
+	for protocol, ags := range agreements {
+		for _, ag := range ags {
+			if err := db.DeleteAgreement(ag.CurrentAgreementId, protocol); err != nil {
+				return errors.New(fmt.Sprintf("unable to delete agreement %v of node %v, error: %v", ag.CurrentAgreementId, deviceId, err))
+			}
+			report.AgreementIds = append(report.AgreementIds, ag.CurrentAgreementId)
+			report.Removed[cutil.DELETED_ARCHIVED_AGREEMENTS] += 1
+		}
+	}
+
+	if wus, err := db.FindWorkloadUsages([]WUFilter{DWUFilter(deviceId)}); err != nil {
+		return errors.New(fmt.Sprintf("unable to read the workload usages of node %v, error: %v", deviceId, err))
+	} else {
+		for _, wu := range wus {
+			if err := db.DeleteWorkloadUsage(deviceId, wu.PolicyName); err != nil {
+				return errors.New(fmt.Sprintf("unable to delete the workload usage of node %v for policy %v, error: %v", deviceId, wu.PolicyName, err))
+			}
+			report.Removed[cutil.DELETED_WORKLOAD_USAGES] += 1
+		}
+	}
+
+	glog.V(3).Infof("Purged the records of node %v: %v", deviceId, report.Removed)
+	return nil
+}
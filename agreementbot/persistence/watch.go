@@ -0,0 +1,87 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"github.com/open-horizon/anax/config"
+	"sync"
+)
+
+// The watch API lets other components of the agbot be told about every change to an agreement after the change has been
+// committed to the database. The database implementations call NotifyAgreementChange, the watchers are called on the
+// goroutine that made the change so they must not block, they should queue the change and process it elsewhere.
+
+const AGREEMENT_CHANGE_CREATED = "created"
+const AGREEMENT_CHANGE_UPDATED = "updated"
+const AGREEMENT_CHANGE_DELETED = "deleted"
+
+type AgreementChange struct {
+	Type        string     // one of the AGREEMENT_CHANGE_ constants
+	Protocol    string     // the agreement protocol of the agreement
+	AgreementId string     // the id of the agreement that changed
+	Agreement   *Agreement // the agreement as it was committed, nil when it was deleted
+}
+
+func (c AgreementChange) String() string {
+	return fmt.Sprintf("Type: %v, Protocol: %v, AgreementId: %v", c.Type, c.Protocol, c.AgreementId)
+}
+
+type AgreementWatcher func(change AgreementChange)
+
+var watchersLock sync.RWMutex
+var agreementWatchers = make([]AgreementWatcher, 0)
+
+// Register a function that is called for each committed agreement change.
+func WatchAgreements(watcher AgreementWatcher) {
+	watchersLock.Lock()
+	defer watchersLock.Unlock()
+	agreementWatchers = append(agreementWatchers, watcher)
+}
+
+// Called by the database implementations after an agreement change has been committed.
+func NotifyAgreementChange(changeType string, protocol string, agreementId string, ag *Agreement) {
+	watchersLock.RLock()
+	defer watchersLock.RUnlock()
+
+	if len(agreementWatchers) == 0 {
+		return
+	}
+
+	change := AgreementChange{Type: changeType, Protocol: protocol, AgreementId: agreementId}
+	if ag != nil {
+		copied := *ag
+		change.Agreement = &copied
+	}
+	for _, watcher := range agreementWatchers {
+		watcher(change)
+	}
+}
+
+// The read only functions of the agbot database, which are all that is needed by reporting queries. A read replica
+// of the agreements implements this interface.
+type AgreementReader interface {
+	FindAgreements(filters []AFilter, protocol string) ([]Agreement, error)
+	FindSingleAgreementByAgreementIdAllProtocols(agreementid string, protocols []string, filters []AFilter) (*Agreement, error)
+	GetAgreementStatistics(groupBy string, since uint64, until uint64) (*AgreementStatistics, error)
+}
+
+// The function that creates a read replica of the agreements in the source database. It is registered by the database
+// implementation that provides the replica store, in the same way that the databases register themselves.
+type ReplicaProvider func(source AgbotDatabase, cfg *config.HorizonConfig) (AgreementReader, error)
+
+var replicaProvider ReplicaProvider
+
+func RegisterReplicaProvider(provider ReplicaProvider) {
+	replicaProvider = provider
+}
+
+// Start replicating the agreements to the read replica, if one is configured. The returned reader is the replica, or the
+// source database itself when no replica is configured.
+func InitReplica(source AgbotDatabase, cfg *config.HorizonConfig) (AgreementReader, error) {
+	if cfg.AgreementBot.ReplicaDBPath == "" {
+		return source, nil
+	} else if replicaProvider == nil {
+		return nil, errors.New(fmt.Sprintf("agreement read replica is configured in %v, but there is no replica provider", cfg.AgreementBot.ReplicaDBPath))
+	}
+	return replicaProvider(source, cfg)
+}
@@ -3,7 +3,10 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"sync"
 
 	"github.com/boltdb/bolt"
@@ -75,10 +78,12 @@ func (a *API) router(includeStaticRedirects bool) *mux.Router {
 
 	// For working with existing or archived agreements
 	router.HandleFunc("/agreement", a.agreement).Methods("GET", "OPTIONS")
+	router.HandleFunc("/agreement/statistics", a.agreementStatistics).Methods("GET", "OPTIONS")
 	router.HandleFunc("/agreement/{id}", a.agreement).Methods("GET", "DELETE", "OPTIONS")
 
 	// For obtaining microservice info or configuring a microservice (sensor) userInput variables
 	router.HandleFunc("/service", a.service).Methods("GET", "OPTIONS")
+	router.HandleFunc("/service/log", a.servicelog).Methods("GET", "OPTIONS")
 	router.HandleFunc("/service/config", a.serviceconfig).Methods("GET", "POST", "OPTIONS")
 	router.HandleFunc("/service/configstate", a.service_configstate).Methods("GET", "POST", "OPTIONS")
 	router.HandleFunc("/service/policy", a.servicepolicy).Methods("GET", "OPTIONS")
@@ -95,6 +100,10 @@ func (a *API) router(includeStaticRedirects bool) *mux.Router {
 	router.HandleFunc("/node/configstate", a.nodeconfigstate).Methods("GET", "HEAD", "PUT", "OPTIONS")
 	router.HandleFunc("/node/policy", a.nodepolicy).Methods("GET", "HEAD", "PUT", "POST", "PATCH", "DELETE", "OPTIONS")
 	router.HandleFunc("/node/userinput", a.nodeuserinput).Methods("GET", "HEAD", "PUT", "POST", "PATCH", "DELETE", "OPTIONS")
+	router.HandleFunc("/node/token", a.nodetoken).Methods("PUT", "OPTIONS")
+	router.HandleFunc("/node/settings", a.nodesettings).Methods("PATCH", "OPTIONS")
+	router.HandleFunc("/node/purge", a.nodepurge).Methods("POST", "OPTIONS")
+	router.HandleFunc("/node/resources", a.noderesources).Methods("GET", "OPTIONS")
 
 	// Used to get the event logs on this node.
 	// get the eventlogs for current registration.
@@ -104,6 +113,9 @@ func (a *API) router(includeStaticRedirects bool) *mux.Router {
 	//get the active surface errors for this node
 	router.HandleFunc("/eventlog/surface", a.surface).Methods("GET", "OPTIONS")
 
+	// Used to get the status of the node management policy jobs on this node.
+	router.HandleFunc("/nodemanagement/status", a.nodemanagementstatus).Methods("GET", "OPTIONS")
+
 	// For importing workload public signing keys (RSA-PSS key pair public key)
 	router.HandleFunc("/{p:(?:publickey|trust)}", a.publickey).Methods("GET", "OPTIONS")
 	router.HandleFunc("/{p:(?:publickey|trust)}/{filename}", a.publickey).Methods("GET", "PUT", "DELETE", "OPTIONS")
@@ -137,13 +149,49 @@ func (a *API) listen(cfg *config.HorizonConfig) {
 	// This routine does not need to be a subworker because there is no way to terminate it. It will terminate when
 	// the main anax process goes away.
 	go func() {
-		if err := http.ListenAndServe(cfg.Edge.APIListen, nocache(a.router(true))); err != nil {
+		listener, err := apiListener(cfg)
+		if err != nil {
+			glog.Fatalf(apiLogString(fmt.Sprintf("Failed to start listener on %v, error %v", cfg.Edge.APIListen, err)))
+		}
+		if err := http.Serve(listener, nocache(a.router(true))); err != nil {
 			glog.Fatalf(apiLogString(fmt.Sprintf("Failed to start listener on %v, error %v", cfg.Edge.APIListen, err)))
 		}
 	}()
 
 }
 
+// Returns the listener of the API, on the unix domain socket or the host and port of the APIListen config. A socket
+// left behind by a previous anax process is removed first, any other file at the path is left alone and is an error.
+// Only the owner and group of the socket can use the API.
+func apiListener(cfg *config.HorizonConfig) (net.Listener, error) {
+	socket := cfg.GetAPIUnixSocket()
+	if socket == "" {
+		return net.Listen("tcp", cfg.Edge.APIListen)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(socket), 0755); err != nil {
+		return nil, err
+	}
+	if info, err := os.Lstat(socket); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%v is not a unix domain socket, it is not removed", socket)
+		} else if err := os.Remove(socket); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	} else if err := os.Chmod(socket, 0660); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	glog.Info(apiLogString(fmt.Sprintf("Listening on unix domain socket %v", socket)))
+	return listener, nil
+}
+
 // Worker framework functions
 func (a *API) Messages() chan events.Message {
 	return a.Manager.Messages
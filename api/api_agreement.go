@@ -3,9 +3,13 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/golang/glog"
 	"github.com/gorilla/mux"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
 )
 
 func (a *API) agreement(w http.ResponseWriter, r *http.Request) {
@@ -63,3 +67,65 @@ func (a *API) agreement(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	}
 }
+
+// Return established agreement counts and rates grouped by state, protocol, workload or agbot. The time window is given by
+// since and until (in seconds since the epoch), or by window (the number of seconds before now). The default window is all time.
+func (a *API) agreementStatistics(w http.ResponseWriter, r *http.Request) {
+
+	resource := "agreement/statistics"
+	errorhandler := GetHTTPErrorHandler(w)
+
+	switch r.Method {
+	case "GET":
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		groupBy := r.URL.Query().Get("group_by")
+		if groupBy != "" && !persistence.ValidEAStatisticsGroupBy(groupBy) {
+			errorhandler(NewAPIUserInputError(fmt.Sprintf("must be one of %v, %v, %v or %v", persistence.EA_STATS_GROUP_BY_STATE, persistence.EA_STATS_GROUP_BY_PROTOCOL, persistence.EA_STATS_GROUP_BY_WORKLOAD, persistence.EA_STATS_GROUP_BY_AGBOT), "group_by"))
+			return
+		}
+
+		// Parse the time window parameters
+		times := make(map[string]uint64)
+		for _, param := range []string{"since", "until", "window"} {
+			if value := r.URL.Query().Get(param); value != "" {
+				if t, err := strconv.ParseUint(value, 10, 64); err != nil {
+					errorhandler(NewAPIUserInputError("must be a number of seconds", param))
+					return
+				} else {
+					times[param] = t
+				}
+			}
+		}
+
+		since, until := times["since"], times["until"]
+		if window, ok := times["window"]; ok {
+			if since != 0 {
+				errorhandler(NewAPIUserInputError("can not be used with since", "window"))
+				return
+			}
+			if until == 0 {
+				until = uint64(time.Now().Unix())
+			}
+			if window < until {
+				since = until - window
+			}
+		}
+		if until != 0 && since > until {
+			errorhandler(NewAPIUserInputError("must not be after until", "since"))
+			return
+		}
+
+		if stats, err := persistence.GetEstablishedAgreementStatistics(a.db, policy.AllAgreementProtocols(), groupBy, since, until); err != nil {
+			errorhandler(NewSystemError(fmt.Sprintf("Error getting %v for output, error %v", resource, err)))
+		} else {
+			writeResponse(w, stats, http.StatusOK)
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -537,3 +537,146 @@ func (a *API) nodeuserinput(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	}
 }
+
+func (a *API) nodetoken(w http.ResponseWriter, r *http.Request) {
+
+	resource := "node/token"
+
+	errorHandler := GetHTTPErrorHandler(w)
+
+	switch r.Method {
+	case "PUT":
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		var token NodeToken
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &token); err != nil {
+			errorHandler(NewAPIUserInputError(fmt.Sprintf("Input body couldn't be deserialized to %v object, error: %v", resource, err), "token"))
+			return
+		}
+
+		// Verify the new token with the exchange and persist it in place of the old token.
+		errHandled, exDev := UpdateHorizonDeviceToken(&token, errorHandler, exchange.GetHTTPDeviceHandler2(a.Config), a.db)
+		if errHandled {
+			return
+		}
+
+		// The workers that talk to the exchange switch to the new token.
+		a.EC = worker.NewExchangeContext(fmt.Sprintf("%v/%v", *exDev.Org, *exDev.Id), *token.Token, a.Config.Edge.ExchangeURL, a.Config.GetCSSURL(), a.Config.Collaborators.HTTPClientFactory)
+		a.Messages() <- events.NewNodeTokenUpdatedMessage(events.NODE_TOKEN_UPDATED, *token.Token)
+
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handled %v on resource %v", r.Method, resource)))
+
+		writeResponse(w, exDev, http.StatusOK)
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "PUT, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *API) nodesettings(w http.ResponseWriter, r *http.Request) {
+
+	resource := "node/settings"
+
+	errorHandler := GetHTTPErrorHandler(w)
+
+	switch r.Method {
+	case "PATCH":
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		var settings NodeSettings
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &settings); err != nil {
+			LogDeviceEvent(a.db, persistence.SEVERITY_ERROR,
+				persistence.NewMessageMeta(EL_API_ERR_PARSING_INPUT_FOR_NODE_UPDATE, string(body), err.Error()),
+				persistence.EC_API_USER_INPUT_ERROR, nil)
+			errorHandler(NewAPIUserInputError(fmt.Sprintf("Input body couldn't be deserialized to %v object: %v, error: %v", resource, string(body), err), "settings"))
+			return
+		}
+
+		update_settings_error_handler := func(device interface{}, err error) bool {
+			LogDeviceEvent(a.db, persistence.SEVERITY_ERROR, persistence.NewMessageMeta(EL_API_ERR_IN_NODE_UPDATE, exchange.GetId(a.GetExchangeId()), err.Error()),
+				persistence.EC_ERROR_NODE_UPDATE, device)
+			return errorHandler(err)
+		}
+
+		// Validate the settings and change them in the exchange and the local database.
+		errHandled, exDev, msgs := UpdateHorizonDeviceSettings(&settings, update_settings_error_handler, exchange.GetHTTPExchangePatternHandler(a),
+			exchange.GetHTTPPatchDeviceHandler(a), exchange.GetHTTPNodePolicyHandler(a), exchange.GetHTTPPutNodePolicyHandler(a), a.db)
+		if errHandled {
+			return
+		}
+
+		// Send out all messages
+		for _, msg := range msgs {
+			a.Messages() <- msg
+		}
+
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handled %v on resource %v", r.Method, resource)))
+
+		writeResponse(w, exDev, http.StatusOK)
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "PATCH, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *API) nodepurge(w http.ResponseWriter, r *http.Request) {
+
+	resource := "node/purge"
+
+	errorHandler := GetHTTPErrorHandler(w)
+
+	switch r.Method {
+	case "POST":
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		// Remove the records of the past activity of the node and return the signed deletion report.
+		errHandled, report := PurgeHorizonDevice(a.Config.GetEventLogArchivePath(), errorHandler, a.db)
+		if errHandled {
+			return
+		}
+
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handled %v on resource %v", r.Method, resource)))
+
+		writeResponse(w, report, http.StatusOK)
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "POST, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *API) noderesources(w http.ResponseWriter, r *http.Request) {
+
+	resource := "node/resources"
+
+	errorHandler := GetHTTPErrorHandler(w)
+
+	switch r.Method {
+	case "GET":
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		if out, err := FindNodeResourcesForOutput(a.db); err != nil {
+			errorHandler(NewSystemError(fmt.Sprintf("Error getting %v for output, error %v", resource, err)))
+		} else if out == nil {
+			errorHandler(NewNotFoundError("the agent has not detected the node resources yet", "resources"))
+		} else {
+			writeResponse(w, out, http.StatusOK)
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
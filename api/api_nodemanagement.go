@@ -0,0 +1,39 @@
+package api
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/i18n"
+	"net/http"
+)
+
+// get the status of the node management policy jobs on this node.
+func (a *API) nodemanagementstatus(w http.ResponseWriter, r *http.Request) {
+
+	resource := "nodemanagement/status"
+
+	errorHandler := GetHTTPErrorHandler(w)
+
+	switch r.Method {
+	case "GET":
+		lan := r.Header.Get("Accept-Language")
+		if lan == "" {
+			lan = i18n.DEFAULT_LANGUAGE
+		}
+		msgPrinter := i18n.GetMessagePrinterWithLocale(lan)
+
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v. Language: %v", r.Method, resource, lan)))
+
+		if out, err := FindNodeManagementStatusForOutput(a.db); err != nil {
+			errorHandler(NewSystemError(msgPrinter.Sprintf("Error getting %v for output, error %v", resource, err)))
+		} else {
+			writeResponse(w, out, http.StatusOK)
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/open-horizon/anax/persistence"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 )
 
 func (a *API) service(w http.ResponseWriter, r *http.Request) {
@@ -52,6 +53,131 @@ func (a *API) service(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// For reading the logs of the containers of a running service from the docker API, so that they can be read without
+// access to docker. The logs are written as plain text, and streamed until the request ends when following them.
+// Anyone who can reach the agent API can read them, so the API is only there when ServiceLogAPI is set in the config.
+func (a *API) servicelog(w http.ResponseWriter, r *http.Request) {
+
+	resource := "service/log"
+	errorhandler := GetHTTPErrorHandler(w)
+
+	if !a.Config.Edge.ServiceLogAPI {
+		errorhandler(NewNotFoundError("the service log API is not enabled, set ServiceLogAPI in the Edge section of the agent config to enable it", "service"))
+		return
+	}
+
+	_, errWritten := a.existingDeviceOrError(w)
+	if errWritten {
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v with %v", r.Method, resource, r.URL.RawQuery)))
+
+		query := r.URL.Query()
+		service := query.Get("service")
+		if service == "" {
+			errorhandler(NewAPIUserInputError("must be the <org>/<url> of a running service", "service"))
+			return
+		}
+
+		options := ServiceLogOptions{Tail: "all"}
+		for _, param := range []string{"follow", "timestamps"} {
+			if value := query.Get(param); value != "" {
+				if b, err := strconv.ParseBool(value); err != nil {
+					errorhandler(NewAPIUserInputError("must be true or false", param))
+					return
+				} else if param == "follow" {
+					options.Follow = b
+				} else {
+					options.Timestamps = b
+				}
+			}
+		}
+		if value := query.Get("tail"); value != "" && value != "all" {
+			if n, err := strconv.ParseUint(value, 10, 64); err != nil {
+				errorhandler(NewAPIUserInputError("must be a number of lines or all", "tail"))
+				return
+			} else {
+				options.Tail = strconv.FormatUint(n, 10)
+			}
+		}
+		if value := query.Get("since"); value != "" {
+			if t, err := strconv.ParseInt(value, 10, 64); err != nil || t < 0 {
+				errorhandler(NewAPIUserInputError("must be a number of seconds", "since"))
+				return
+			} else {
+				options.Since = t
+			}
+		}
+
+		out, err := FindServicesForOutput(a.pm, a.db, a.Config)
+		if err != nil {
+			errorhandler(NewSystemError(fmt.Sprintf("Error getting %v for output, error %v", resource, err)))
+			return
+		}
+		inst, err := FindServiceLogInstance(out, service, query.Get("instance"))
+		if err != nil {
+			errorhandler(NewAPIUserInputError(err.Error(), "instance"))
+			return
+		} else if inst == nil {
+			errorhandler(NewNotFoundError(fmt.Sprintf("service %v is not running on the node", service), "service"))
+			return
+		} else if inst.Containers == nil || len(*inst.Containers) == 0 {
+			errorhandler(NewNotFoundError(fmt.Sprintf("service %v has no containers on the node", service), "service"))
+			return
+		}
+
+		// the status is sent with the first line of the logs, an error after that can only be written in the logs
+		logs := &serviceLogResponse{ResponseWriter: w, instanceId: inst.InstanceId}
+		if err := StreamServiceLogs(r.Context(), a.Config.Edge.DockerEndpoint, *inst.Containers, options, logs); err != nil {
+			glog.Errorf(apiLogString(fmt.Sprintf("Error streaming the logs of service %v, error %v", service, err)))
+			if !logs.started {
+				errorhandler(NewSystemError(fmt.Sprintf("Error getting %v of service %v, error %v", resource, service, err)))
+			} else {
+				fmt.Fprintf(logs, "Error: %v\n", err)
+			}
+		} else {
+			logs.start()
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Writes the status of a service log response with its first line, so that an error from docker before the logs start
+// is still an error status.
+type serviceLogResponse struct {
+	http.ResponseWriter
+	instanceId string
+	started    bool
+}
+
+func (s *serviceLogResponse) start() {
+	if !s.started {
+		s.started = true
+		s.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		s.Header().Set("X-Horizon-Service-Instance", s.instanceId)
+		s.WriteHeader(http.StatusOK)
+	}
+}
+
+func (s *serviceLogResponse) Write(p []byte) (int, error) {
+	s.start()
+	return s.ResponseWriter.Write(p)
+}
+
+func (s *serviceLogResponse) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 // For working with a node's representation of a service, including the policy and input variables of the service.
 func (a *API) serviceconfig(w http.ResponseWriter, r *http.Request) {
 
@@ -3,8 +3,58 @@
 package api
 
 import (
+	"github.com/open-horizon/anax/config"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
 func Test_service(t *testing.T) {
 }
+
+func Test_apiListener_unix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "api-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a socket left behind by a previous process is replaced
+	socket := filepath.Join(dir, "run", "horizon.sock")
+	if err := os.MkdirAll(filepath.Dir(socket), 0755); err != nil {
+		t.Fatalf("unable to create the socket dir: %v", err)
+	} else if previous, err := net.ListenUnix("unix", &net.UnixAddr{Name: socket, Net: "unix"}); err != nil {
+		t.Fatalf("unable to listen on %v: %v", socket, err)
+	} else {
+		previous.SetUnlinkOnClose(false)
+		previous.Close()
+	}
+
+	cfg := &config.HorizonConfig{Edge: config.Config{APIListen: config.APIUnixSocketPrefix + socket}}
+	listener, err := apiListener(cfg)
+	if err != nil {
+		t.Fatalf("unable to listen on %v: %v", socket, err)
+	}
+	defer listener.Close()
+
+	if listener.Addr().Network() != "unix" || listener.Addr().String() != socket {
+		t.Errorf("expected a listener on %v, found %v %v", socket, listener.Addr().Network(), listener.Addr())
+	} else if info, err := os.Stat(socket); err != nil || info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0660 {
+		t.Errorf("expected a socket with mode 0660, found %v %v", info, err)
+	}
+
+	// a file that is not a socket is not removed
+	file := filepath.Join(dir, "horizon.conf")
+	if err := ioutil.WriteFile(file, []byte("conf"), 0644); err != nil {
+		t.Fatalf("unable to write %v: %v", file, err)
+	}
+	cfg = &config.HorizonConfig{Edge: config.Config{APIListen: config.APIUnixSocketPrefix + file}}
+	if listener, err := apiListener(cfg); err == nil {
+		listener.Close()
+		t.Errorf("expected an error for a path that is not a socket")
+	} else if content, err := ioutil.ReadFile(file); err != nil || string(content) != "conf" {
+		t.Errorf("the file should not be removed, found %q %v", content, err)
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"github.com/open-horizon/anax/externalpolicy"
 	"github.com/open-horizon/anax/microservice"
 	"github.com/open-horizon/anax/persistence"
 	"reflect"
@@ -103,6 +104,37 @@ func ConvertFromPersistentHorizonDevice(pDevice *persistence.ExchangeDevice) *Ho
 	}
 }
 
+// The settings of a registered node that can be changed in place, without registering the node again. Only the
+// settings that are given are changed, the properties are merged into the properties of the node policy.
+type NodeSettings struct {
+	Name       *string                      `json:"name,omitempty"`
+	Pattern    *string                      `json:"pattern,omitempty"` // <org>/<pattern>, or a pattern in the node's org
+	Properties *externalpolicy.PropertyList `json:"properties,omitempty"`
+}
+
+func (n NodeSettings) String() string {
+	name, pattern, properties := "not set", "not set", "not set"
+	if n.Name != nil {
+		name = *n.Name
+	}
+	if n.Pattern != nil {
+		pattern = *n.Pattern
+	}
+	if n.Properties != nil {
+		properties = fmt.Sprintf("%v", *n.Properties)
+	}
+	return fmt.Sprintf("Name: %v, Pattern: %v, Properties: %v", name, pattern, properties)
+}
+
+// The new exchange token of the node, used to rotate the token without registering the node again.
+type NodeToken struct {
+	Token *string `json:"token"`
+}
+
+func (n NodeToken) String() string {
+	return "Token: ********"
+}
+
 type Attribute struct {
 	Id           *string                   `json:"id"`
 	Type         *string                   `json:"type"`
@@ -49,10 +49,9 @@ func DeleteAgreement(errorhandler ErrorHandler, agreementId string, db *bolt.DB)
 	glog.V(3).Infof(apiLogString(fmt.Sprintf("Handling DELETE of agreement: %v", agreementId)))
 
 	var filters []persistence.EAFilter
-	filters = append(filters, persistence.UnarchivedEAFilter())
 	filters = append(filters, persistence.IdEAFilter(agreementId))
 
-	agreements, err := persistence.FindEstablishedAgreementsAllProtocols(db, policy.AllAgreementProtocols(), filters)
+	agreements, err := persistence.FindActiveEstablishedAgreementsAllProtocols(db, policy.AllAgreementProtocols(), filters)
 	if err != nil {
 		return errorhandler(NewSystemError(fmt.Sprintf("unable to read agreement objects, error %v", err))), nil
 	} else if len(agreements) == 0 {
@@ -9,6 +9,7 @@ import (
 	"github.com/open-horizon/anax/eventlog"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/exchangesync"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/version"
 	"os"
@@ -312,6 +313,134 @@ func UpdateHorizonDevice(device *HorizonDevice,
 
 }
 
+// Replace the exchange token of a registered node, after the node has been given the new token in the exchange. The new
+// token is verified by reading the node from the exchange with it before the old token is replaced, so that a node is
+// never left with a token that the exchange does not accept.
+func UpdateHorizonDeviceToken(token *NodeToken,
+	errorhandler ErrorHandler,
+	getDevice exchange.DeviceHandler,
+	db *bolt.DB) (bool, *HorizonDevice) {
+
+	pDevice, err := persistence.FindExchangeDevice(db)
+	if err != nil {
+		return errorhandler(NewSystemError(fmt.Sprintf("Unable to read node object, error %v", err))), nil
+	} else if pDevice == nil {
+		return errorhandler(NewNotFoundError("Exchange registration not recorded. Complete account and device registration with an exchange and then record device registration using this API.", "node")), nil
+	} else if !pDevice.IsState(persistence.CONFIGSTATE_CONFIGURED) && !pDevice.IsState(persistence.CONFIGSTATE_CONFIGURING) {
+		return errorhandler(NewBadRequestError(fmt.Sprintf("The node must be in configuring or configured state in order to update its token."))), nil
+	}
+
+	// The token is not checked for illegal characters, the exchange decides which tokens it accepts.
+	if token.Token == nil {
+		return errorhandler(NewAPIUserInputError("null and must not be", "token")), nil
+	} else if *token.Token == "" {
+		return errorhandler(NewAPIUserInputError("empty and must not be", "token")), nil
+	}
+
+	if _, err := getDevice(pDevice.GetId(), *token.Token); err != nil {
+		return errorhandler(NewAPIUserInputError(fmt.Sprintf("the exchange did not accept the token for node %v, error: %v", pDevice.GetId(), err), "token")), nil
+	}
+
+	updatedDev, err := pDevice.SetExchangeDeviceToken(db, pDevice.Id, *token.Token)
+	if err != nil {
+		return errorhandler(NewSystemError(fmt.Sprintf("error persisting token update on node object: %v", err))), nil
+	}
+
+	glog.V(3).Infof(apiLogString(fmt.Sprintf("Updated the exchange token of node %v", pDevice.GetId())))
+	return false, ConvertFromPersistentHorizonDevice(updatedDev)
+}
+
+// Change the name, pattern or properties of a registered node in place. The name and pattern are changed in the
+// exchange and the properties are patched into the node policy. The agent handles a new pattern like a pattern that is
+// changed in the exchange, it ends the agreements of the old pattern and registers the node again with the new one.
+func UpdateHorizonDeviceSettings(settings *NodeSettings,
+	errorhandler DeviceErrorHandler,
+	getPatterns exchange.PatternHandler,
+	patchDevice exchange.PatchDeviceHandler,
+	nodeGetPolicyHandler exchange.NodePolicyHandler,
+	nodePatchPolicyHandler exchange.PutNodePolicyHandler,
+	db *bolt.DB) (bool, *HorizonDevice, []*events.NodePolicyMessage) {
+
+	pDevice, err := persistence.FindExchangeDevice(db)
+	if err != nil {
+		return errorhandler(nil, NewSystemError(fmt.Sprintf("Unable to read node object, error %v", err))), nil, nil
+	} else if pDevice == nil {
+		return errorhandler(nil, NewNotFoundError("Exchange registration not recorded. Complete account and device registration with an exchange and then record device registration using this API.", "node")), nil, nil
+	} else if !pDevice.IsState(persistence.CONFIGSTATE_CONFIGURED) {
+		return errorhandler(pDevice, NewBadRequestError(fmt.Sprintf("The node must be in configured state in order to update its settings."))), nil, nil
+	}
+
+	LogDeviceEvent(db, persistence.SEVERITY_INFO, persistence.NewMessageMeta(EL_API_START_NODE_UPDATE, pDevice.Id), persistence.EC_START_NODE_UPDATE, pDevice)
+
+	if settings.Name == nil && settings.Pattern == nil && settings.Properties == nil {
+		return errorhandler(pDevice, NewAPIUserInputError("at least one of name, pattern or properties must be set", "settings")), nil, nil
+	}
+	if settings.Name != nil {
+		if *settings.Name == "" {
+			return errorhandler(pDevice, NewAPIUserInputError("empty and must not be", "settings.name")), nil, nil
+		} else if bail := checkInputString(func(err error) bool { return errorhandler(pDevice, err) }, "settings.name", settings.Name); bail {
+			return true, nil, nil
+		}
+	}
+
+	// Only a node that runs a pattern can change its pattern in place. The agent can not switch between a pattern and
+	// policy without registering the node again.
+	var pattern *string
+	if settings.Pattern != nil && *settings.Pattern != pDevice.Pattern {
+		if pDevice.Pattern == "" {
+			return errorhandler(pDevice, NewAPIUserInputError("the node is registered with a policy, unregister and register the node to use a pattern", "settings.pattern")), nil, nil
+		} else if *settings.Pattern == "" {
+			return errorhandler(pDevice, NewAPIUserInputError("empty and must not be, unregister and register the node to use a policy", "settings.pattern")), nil, nil
+		} else if bail := checkInputString(func(err error) bool { return errorhandler(pDevice, err) }, "settings.pattern", settings.Pattern); bail {
+			return true, nil, nil
+		}
+
+		patternOrg, patternName, formatted := persistence.GetFormatedPatternString(*settings.Pattern, pDevice.Org)
+		if patternDefs, err := getPatterns(patternOrg, patternName); err != nil {
+			return errorhandler(pDevice, NewAPIUserInputError(fmt.Sprintf("error searching for pattern %v in exchange, error: %v", formatted, err), "settings.pattern")), nil, nil
+		} else if _, ok := patternDefs[formatted]; !ok {
+			return errorhandler(pDevice, NewAPIUserInputError(fmt.Sprintf("pattern %v not found in exchange.", formatted), "settings.pattern")), nil, nil
+		}
+		if formatted != pDevice.Pattern {
+			pattern = &formatted
+		}
+	}
+
+	// PATCH the node in the exchange first, the agent finds the new pattern there.
+	var name *string
+	if settings.Name != nil && *settings.Name != pDevice.Name {
+		name = settings.Name
+	}
+	if name != nil || pattern != nil {
+		if err := patchDevice(pDevice.GetId(), pDevice.Token, &exchange.PatchDeviceRequest{Name: name, Pattern: pattern}); err != nil {
+			return errorhandler(pDevice, NewSystemError(fmt.Sprintf("Unable to update the node %v in the exchange, error %v", pDevice.GetId(), err))), nil, nil
+		}
+	}
+	if name != nil {
+		if pDevice, err = pDevice.SetName(db, pDevice.Id, *name); err != nil {
+			return errorhandler(pDevice, NewSystemError(fmt.Sprintf("error persisting name update on node object: %v", err))), nil, nil
+		}
+	}
+
+	var msgs []*events.NodePolicyMessage
+	if settings.Properties != nil {
+		if _, err := exchangesync.PatchNodePolicy(pDevice, db, *settings.Properties, nodeGetPolicyHandler, nodePatchPolicyHandler); err != nil {
+			return errorhandler(pDevice, NewSystemError(fmt.Sprintf("Unable to sync the local db with the exchange node policy. %v", err))), nil, nil
+		}
+		LogDeviceEvent(db, persistence.SEVERITY_INFO, persistence.NewMessageMeta(EL_API_NEW_NODE_POL, *settings.Properties), persistence.EC_NODE_POLICY_UPDATED, pDevice)
+		msgs = append(msgs, events.NewNodePolicyMessage(events.UPDATE_POLICY))
+	}
+
+	LogDeviceEvent(db, persistence.SEVERITY_INFO, persistence.NewMessageMeta(EL_API_COMPLETE_NODE_UPDATE, pDevice.Id), persistence.EC_NODE_UPDATE_COMPLETE, pDevice)
+
+	// The pattern of the local node is changed by the agent when it registers the node again.
+	exDev := ConvertFromPersistentHorizonDevice(pDevice)
+	if pattern != nil {
+		exDev.Pattern = pattern
+	}
+	return false, exDev, msgs
+}
+
 // Handles the DELETE verb on this resource.
 func DeleteHorizonDevice(removeNode string,
 	deepClean string,
@@ -408,3 +537,31 @@ func DeleteHorizonDevice(removeNode string,
 	return false
 
 }
+
+// Remove the records of the past activity of this node, and return a report of what was removed, signed with the
+// node's messaging key. The event logs are removed too, so the purge itself is not event logged.
+func PurgeHorizonDevice(archiveDir string, errorhandler ErrorHandler, db *bolt.DB) (bool, *cutil.DeletionReport) {
+
+	nodeId := ""
+	if pDevice, err := persistence.FindExchangeDevice(db); err != nil {
+		return errorhandler(NewSystemError(fmt.Sprintf("Unable to read node object, error %v", err))), nil
+	} else if pDevice != nil {
+		nodeId = pDevice.GetId()
+	}
+
+	// Get the signing key before anything is removed, a report that can not be signed is of no use.
+	_, privKey, err := exchange.GetKeys("")
+	if err != nil {
+		return errorhandler(NewSystemError(fmt.Sprintf("Unable to get the node messaging key to sign the deletion report, error %v", err))), nil
+	}
+
+	report := cutil.NewDeletionReport(nodeId, nodeId, uint64(time.Now().Unix()))
+	if err := persistence.PurgeNodeRecords(db, archiveDir, report); err != nil {
+		return errorhandler(NewSystemError(fmt.Sprintf("Unable to purge the node records, error %v", err))), nil
+	} else if err := report.Sign(privKey); err != nil {
+		return errorhandler(NewSystemError(fmt.Sprintf("Unable to sign the deletion report %v, error %v", report, err))), nil
+	}
+
+	glog.V(3).Infof(apiLogString(fmt.Sprintf("Purged node records: %v", report)))
+	return false, report
+}
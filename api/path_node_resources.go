@@ -0,0 +1,19 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/persistence"
+)
+
+// Return the resources of the node that the agent last detected, nil when it has not detected them yet. The API does
+// not detect them itself, the governance worker detects them when the agent starts and periodically after that.
+func FindNodeResourcesForOutput(db *bolt.DB) (*persistence.NodeResources, error) {
+
+	if resources, err := persistence.FindNodeResources(db); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to read node resources object, error %v", err))
+	} else {
+		return resources, nil
+	}
+}
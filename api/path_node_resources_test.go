@@ -0,0 +1,33 @@
+// +build unit
+
+package api
+
+import (
+	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/persistence"
+	"testing"
+)
+
+// Verify that nothing is returned, or saved, before the agent has detected the node resources, and that the saved
+// resources are returned after that.
+func Test_FindNodeResourcesForOutput(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	if res, err := FindNodeResourcesForOutput(db); err != nil || res != nil {
+		t.Errorf("no node resources should be found before they are detected, found %v, error %v", res, err)
+	} else if saved, err := persistence.FindNodeResources(db); err != nil || saved != nil {
+		t.Errorf("the node resources should not be saved by the API, found %v, error %v", saved, err)
+	}
+
+	detected := persistence.NodeResources{NodeResources: cutil.NodeResources{Arch: "arm64", CPUs: 4, GPUs: []cutil.GPU{}, DetectedTime: 1602632290}}
+	if err := persistence.SaveNodeResources(db, &detected); err != nil {
+		t.Fatalf("failed to save node resources, error %v", err)
+	} else if res, err := FindNodeResourcesForOutput(db); err != nil || res == nil || !res.IsSame(detected.NodeResources) {
+		t.Errorf("expected the saved node resources, found %v, error %v", res, err)
+	}
+}
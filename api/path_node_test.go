@@ -822,6 +822,76 @@ func Test_PatchHorizonDevice_fail1(t *testing.T) {
 	}
 }
 
+func Test_UpdateHorizonDeviceToken_success(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	device := getBasicDevice("testOrg", "testPattern")
+
+	_, err = persistence.SaveNewExchangeDevice(db, *device.Id, *device.Token, *device.Name, "", false, *device.Org, *device.Pattern, persistence.CONFIGSTATE_CONFIGURED)
+	if err != nil {
+		t.Errorf("unexpected error creating device %v", err)
+	}
+
+	newToken := "newToken"
+	var myError error
+	errorhandler := GetPassThroughErrorHandler(&myError)
+
+	errHandled, dev := UpdateHorizonDeviceToken(&NodeToken{Token: &newToken}, errorhandler, getExchangeDevice("testPattern"), db)
+
+	if errHandled {
+		t.Errorf("unexpected error %v", myError)
+	} else if dev == nil || dev.Token != nil {
+		t.Errorf("returned device should not contain the token: %v", dev)
+	} else if pDevice, err := persistence.FindExchangeDevice(db); err != nil {
+		t.Errorf("failed to find device in db, error %v", err)
+	} else if pDevice.Token != newToken {
+		t.Errorf("token was not updated, is %v", pDevice.Token)
+	}
+}
+
+func Test_UpdateHorizonDeviceToken_rejected(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	device := getBasicDevice("testOrg", "testPattern")
+
+	_, err = persistence.SaveNewExchangeDevice(db, *device.Id, *device.Token, *device.Name, "", false, *device.Org, *device.Pattern, persistence.CONFIGSTATE_CONFIGURED)
+	if err != nil {
+		t.Errorf("unexpected error creating device %v", err)
+	}
+
+	rejectDevice := func(id string, token string) (*exchange.Device, error) {
+		return nil, errors.New("invalid credentials")
+	}
+
+	newToken := "newToken"
+	var myError error
+	errorhandler := GetPassThroughErrorHandler(&myError)
+
+	errHandled, dev := UpdateHorizonDeviceToken(&NodeToken{Token: &newToken}, errorhandler, rejectDevice, db)
+
+	if !errHandled {
+		t.Errorf("expected error")
+	} else if _, ok := myError.(*APIUserInputError); !ok {
+		t.Errorf("myError has the wrong type (%T)", myError)
+	} else if dev != nil {
+		t.Errorf("returned non-nil response device object: %v", *dev)
+	} else if pDevice, err := persistence.FindExchangeDevice(db); err != nil {
+		t.Errorf("failed to find device in db, error %v", err)
+	} else if pDevice.Token != *device.Token {
+		t.Errorf("token should not have been updated, is %v", pDevice.Token)
+	}
+}
+
 func getBasicDevice(org string, pattern string) *HorizonDevice {
 	myId := "testid"
 	myName := "testName"
@@ -851,3 +921,58 @@ func getExchangeDevice(pattern string) exchange.DeviceHandler {
 		}, nil
 	}
 }
+
+// change the name and pattern of a configured node
+func Test_UpdateHorizonDeviceSettings(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	if _, err := persistence.SaveNewExchangeDevice(db, "testid", "testtoken", "testname", "device", false, "myorg", "myorg/p1", persistence.CONFIGSTATE_CONFIGURED); err != nil {
+		t.Fatalf("failed to save the device, error %v", err)
+	}
+
+	var myError error
+	errorhandler := func(device interface{}, err error) bool {
+		myError = err
+		return true
+	}
+	getPatterns := func(org string, pattern string) (map[string]exchange.Pattern, error) {
+		return map[string]exchange.Pattern{"myorg/p2": exchange.Pattern{}}, nil
+	}
+	var patched *exchange.PatchDeviceRequest
+	patchDevice := func(id string, token string, pdr *exchange.PatchDeviceRequest) error {
+		patched = pdr
+		return nil
+	}
+
+	name, pattern := "newname", "p2"
+	errHandled, dev, msgs := UpdateHorizonDeviceSettings(&NodeSettings{Name: &name, Pattern: &pattern}, errorhandler, getPatterns, patchDevice, getDummyNodePolicyHandler(nil), getDummyPutNodePolicyHandler(), db)
+	if errHandled {
+		t.Fatalf("unexpected error %v", myError)
+	} else if patched == nil || patched.Name == nil || *patched.Name != "newname" || patched.Pattern == nil || *patched.Pattern != "myorg/p2" {
+		t.Errorf("the exchange node was not patched with the name and pattern: %v", patched)
+	} else if *dev.Name != "newname" || *dev.Pattern != "myorg/p2" || len(msgs) != 0 {
+		t.Errorf("wrong response %v, messages %v", dev, msgs)
+	} else if pDevice, err := persistence.FindExchangeDevice(db); err != nil {
+		t.Errorf("failed to find device in db, error %v", err)
+	} else if pDevice.Name != "newname" || pDevice.Pattern != "myorg/p1" {
+		t.Errorf("only the name should be changed in the local node, the agent changes the pattern: %v", pDevice)
+	}
+
+	// a pattern that is not in the exchange
+	pattern = "p3"
+	if errHandled, _, _ := UpdateHorizonDeviceSettings(&NodeSettings{Pattern: &pattern}, errorhandler, getPatterns, patchDevice, getDummyNodePolicyHandler(nil), getDummyPutNodePolicyHandler(), db); !errHandled {
+		t.Errorf("expected an error for a pattern that is not in the exchange")
+	} else if _, ok := myError.(*APIUserInputError); !ok {
+		t.Errorf("myError has the wrong type (%T)", myError)
+	}
+
+	// nothing to change
+	if errHandled, _, _ := UpdateHorizonDeviceSettings(&NodeSettings{}, errorhandler, getPatterns, patchDevice, getDummyNodePolicyHandler(nil), getDummyPutNodePolicyHandler(), db); !errHandled {
+		t.Errorf("expected an error when no setting is given")
+	}
+}
@@ -0,0 +1,16 @@
+package api
+
+import (
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/persistence"
+)
+
+// This API returns the status of the node management policy jobs on this node, keyed by policy name.
+func FindNodeManagementStatusForOutput(db *bolt.DB) (map[string]persistence.NMPStatus, error) {
+
+	glog.V(5).Infof(apiLogString(fmt.Sprintf("Getting node management statuses from the db.")))
+
+	return persistence.FindNMPStatuses(db)
+}
@@ -1,6 +1,9 @@
 package api
 
 import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -37,7 +40,23 @@ func FindPublicKeyForOutput(fileName string, config *config.HorizonConfig) (stri
 type KeyPairSimpleRecord struct {
 	// embedded
 	listkeys.KeyPairSimple
-	ID string `json:"id"`
+	ID          string `json:"id"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// PublicKeyFingerprint returns the SHA-256 fingerprint of the DER encoding of a public key, as colon separated hex
+// bytes, so that a trusted key can be compared with the key that signed a service.
+func PublicKeyFingerprint(publicKey *rsa.PublicKey) (string, error) {
+	derBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(derBytes)
+	hexBytes := make([]string, 0, len(sum))
+	for _, b := range sum {
+		hexBytes = append(hexBytes, fmt.Sprintf("%02x", b))
+	}
+	return strings.Join(hexBytes, ":"), nil
 }
 
 func FindPublicKeysForOutput(config *config.HorizonConfig, verbose bool) (map[string][]interface{}, error) {
@@ -71,10 +90,17 @@ func FindPublicKeysForOutput(config *config.HorizonConfig, verbose bool) (map[st
 				continue
 			}
 
+			fingerprint, err := PublicKeyFingerprint(keyPair.PublicKey)
+			if err != nil {
+				glog.Errorf("Error reading the public key of user x509 cert from file path: %v. Error: %v", keyPath, err)
+				continue
+			}
+
 			// add the filename as an id in the returned record (so that the REST part of the HTTP interface makes sense)
 			value = KeyPairSimpleRecord{
 				ID:            pf.Name(),
 				KeyPairSimple: *kp,
+				Fingerprint:   fingerprint,
 			}
 
 		} else {
@@ -5,13 +5,18 @@ package api
 import (
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/pem"
 	"flag"
+	"fmt"
+	"github.com/open-horizon/rsapss-tool/generatekeys"
 	"io/ioutil"
 	"os"
 	"path"
+	"strings"
 	"testing"
+	"time"
 )
 
 func init() {
@@ -122,3 +127,61 @@ func Test_PKTest1(t *testing.T) {
 	}
 
 }
+
+// The verbose output of a trusted cert has the fingerprint of its public key
+func Test_PublicKeyFingerprint(t *testing.T) {
+
+	cfg := getBasicConfig()
+	dir, err := ioutil.TempDir("", "keys")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyFiles, err := generatekeys.Write(dir, 2048, "me@myorg.com", "myorg", time.Now().AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("could not create the keys, error %v", err)
+	}
+	certFile := ""
+	for _, f := range keyFiles {
+		if strings.HasSuffix(f, "-public.pem") {
+			certFile = f
+		}
+	}
+	certBytes, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		t.Fatalf("unable to read %v, error %v", certFile, err)
+	}
+
+	var myError error
+	errorhandler := GetPassThroughErrorHandler(&myError)
+	if errHandled := UploadPublicKey(path.Base(certFile), certBytes, cfg, errorhandler); errHandled {
+		t.Fatalf("unexpected error creating key %v", myError)
+	}
+	defer DeletePublicKey(path.Base(certFile), cfg, errorhandler)
+
+	block, _ := pem.Decode(certBytes)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("unable to parse the cert, error %v", err)
+	}
+	expected := fmt.Sprintf("% x", sha256.Sum256(cert.RawSubjectPublicKeyInfo))
+	expected = strings.Replace(expected, " ", ":", -1)
+
+	keys, err := FindPublicKeysForOutput(cfg, true)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	found := false
+	for _, k := range keys["pem"] {
+		if record, ok := k.(KeyPairSimpleRecord); ok && record.ID == path.Base(certFile) {
+			found = true
+			if record.Fingerprint != expected {
+				t.Errorf("wrong fingerprint %v, expected %v", record.Fingerprint, expected)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("did not find test cert in returned map: %v", keys)
+	}
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	dockerclient "github.com/fsouza/go-dockerclient"
+	"github.com/open-horizon/anax/cutil"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// The options of a request for the logs of a service.
+type ServiceLogOptions struct {
+	Follow     bool   // keep streaming the logs until the request is ended
+	Tail       string // the number of lines from the end of the logs, or all
+	Since      int64  // only the logs since this time in seconds since the epoch
+	Timestamps bool   // prefix each line with its docker timestamp
+}
+
+// FindServiceLogInstance returns the active service instance that the service identifies, with its containers. The
+// service is <org>/<url>, or a part of a service url like hzn service log has always matched it. The instance id is
+// only needed when the service has more than one instance. A nil instance is returned when the service is not running.
+func FindServiceLogInstance(services *AllServices, service string, instanceId string) (*MicroserviceInstanceOutput, error) {
+	org, url := cutil.SplitOrgSpecUrl(service)
+
+	exact := make([]*MicroserviceInstanceOutput, 0, 1)
+	partial := make([]*MicroserviceInstanceOutput, 0, 1)
+	for _, inst := range services.Instances["active"] {
+		if instanceId != "" && inst.InstanceId != instanceId {
+			continue
+		}
+		if (inst.SpecRef == url && (org == "" || inst.Org == org)) || inst.SpecRef == service {
+			exact = append(exact, inst)
+		} else if strings.Contains(inst.SpecRef, service) {
+			partial = append(partial, inst)
+		}
+	}
+
+	matches := exact
+	if len(matches) == 0 {
+		matches = partial
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	} else if len(matches) > 1 {
+		ids := make([]string, 0, len(matches))
+		for _, inst := range matches {
+			ids = append(ids, inst.Org+"/"+inst.SpecRef+" "+inst.InstanceId)
+		}
+		return nil, errors.New(fmt.Sprintf("service %v matches more than one instance, specify the instance: %v", service, strings.Join(ids, ", ")))
+	}
+	return matches[0], nil
+}
+
+// StreamServiceLogs writes the logs of the containers of a service instance from the docker API. When the instance has
+// more than one container, each line is prefixed with the name of its container. When following, the logs of the
+// containers are streamed together until the context is ended, otherwise they are written one container at a time.
+func StreamServiceLogs(ctx context.Context, dockerEndpoint string, containers []dockerclient.APIContainers, options ServiceLogOptions, w io.Writer) error {
+	client, err := dockerclient.NewClient(dockerEndpoint)
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to create docker client from %v, error %v", dockerEndpoint, err))
+	}
+
+	lock := new(sync.Mutex)
+	logs := func(c dockerclient.APIContainers) error {
+		name := containerName(c)
+		prefix := ""
+		if len(containers) > 1 {
+			prefix = "[" + name + "] "
+		}
+		out := &logLineWriter{lock: lock, prefix: prefix, w: w}
+		defer out.Close()
+
+		// the log stream of a container with a tty is not multiplexed
+		rawTerminal := false
+		if info, err := client.InspectContainerWithContext(c.ID, ctx); err != nil {
+			return errors.New(fmt.Sprintf("unable to inspect container %v, error %v", name, err))
+		} else if info.Config != nil {
+			rawTerminal = info.Config.Tty
+		}
+
+		opts := dockerclient.LogsOptions{
+			Context:      ctx,
+			Container:    c.ID,
+			OutputStream: out,
+			ErrorStream:  out,
+			Tail:         options.Tail,
+			Since:        options.Since,
+			Follow:       options.Follow,
+			Stdout:       true,
+			Stderr:       true,
+			Timestamps:   options.Timestamps,
+			RawTerminal:  rawTerminal,
+		}
+		if err := client.Logs(opts); err != nil && ctx.Err() == nil {
+			return errors.New(fmt.Sprintf("unable to get the logs of container %v, error %v", name, err))
+		}
+		return nil
+	}
+
+	if !options.Follow {
+		for _, c := range containers {
+			if err := logs(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	errs := make(chan error, len(containers))
+	for _, c := range containers {
+		go func(c dockerclient.APIContainers) { errs <- logs(c) }(c)
+	}
+	var firstErr error
+	for range containers {
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// The name of a container without the leading /, or its id if it has no name.
+func containerName(c dockerclient.APIContainers) string {
+	if len(c.Names) > 0 {
+		return strings.TrimPrefix(c.Names[0], "/")
+	}
+	return c.ID
+}
+
+// Writes whole lines with a prefix, so that the lines of the containers that are streamed together are not mixed. The
+// lines are flushed to the client as they are written.
+type logLineWriter struct {
+	lock   *sync.Mutex
+	prefix string
+	w      io.Writer
+	buf    []byte
+}
+
+func (l *logLineWriter) Write(p []byte) (int, error) {
+	l.buf = append(l.buf, p...)
+	end := bytes.LastIndexByte(l.buf, '\n')
+	if end < 0 {
+		return len(p), nil
+	}
+	lines := l.buf[:end+1]
+	if err := l.write(lines); err != nil {
+		return 0, err
+	}
+	l.buf = append(l.buf[:0], l.buf[end+1:]...)
+	return len(p), nil
+}
+
+// Write the last line, when it does not end with a line break.
+func (l *logLineWriter) Close() error {
+	if len(l.buf) == 0 {
+		return nil
+	}
+	err := l.write(append(l.buf, '\n'))
+	l.buf = nil
+	return err
+}
+
+func (l *logLineWriter) write(lines []byte) error {
+	if l.prefix != "" {
+		prefixed := make([]byte, 0, len(lines)+len(l.prefix)*bytes.Count(lines, []byte("\n")))
+		for _, line := range bytes.SplitAfter(lines, []byte("\n")) {
+			if len(line) > 0 {
+				prefixed = append(append(prefixed, l.prefix...), line...)
+			}
+		}
+		lines = prefixed
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if _, err := l.w.Write(lines); err != nil {
+		return err
+	}
+	if f, ok := l.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// +build unit
+
+package api
+
+import (
+	"bytes"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/worker"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func Test_FindServiceLogInstance(t *testing.T) {
+	instance := func(org string, url string, id string) *MicroserviceInstanceOutput {
+		return &MicroserviceInstanceOutput{MicroserviceInstance: persistence.MicroserviceInstance{Org: org, SpecRef: url, InstanceId: id}}
+	}
+	services := NewServiceOutput()
+	services.Instances["active"] = []*MicroserviceInstanceOutput{
+		instance("IBM", "netspeed", "ag1"),
+		instance("IBM", "https://bluehorizon.network/services/gps", "gps1"),
+		instance("IBM", "https://bluehorizon.network/services/gps", "gps2"),
+		instance("myorg", "netspeed-sidecar", "ag2"),
+	}
+
+	for _, tc := range []struct {
+		service, instance string
+		expected          string
+	}{
+		{"IBM/netspeed", "", "ag1"},
+		{"netspeed", "", "ag1"},
+		{"sidecar", "", "ag2"},
+		{"gps", "gps2", "gps2"},
+		{"IBM/https://bluehorizon.network/services/gps", "gps1", "gps1"},
+		{"cpu", "", ""},
+		{"netspeed", "gps1", ""},
+	} {
+		if inst, err := FindServiceLogInstance(services, tc.service, tc.instance); err != nil {
+			t.Errorf("unexpected error for %v: %v", tc.service, err)
+		} else if tc.expected == "" && inst != nil {
+			t.Errorf("%v %v should not match, found %v", tc.service, tc.instance, inst.InstanceId)
+		} else if tc.expected != "" && (inst == nil || inst.InstanceId != tc.expected) {
+			t.Errorf("%v %v should match %v, found %v", tc.service, tc.instance, tc.expected, inst)
+		}
+	}
+
+	// the instance is needed when the service has more than one
+	if _, err := FindServiceLogInstance(services, "gps", ""); err == nil || !strings.Contains(err.Error(), "gps1") {
+		t.Errorf("expected an error with the instances, found %v", err)
+	}
+}
+
+func Test_logLineWriter(t *testing.T) {
+	out := new(bytes.Buffer)
+	lock := new(sync.Mutex)
+	w1 := &logLineWriter{lock: lock, prefix: "[c1] ", w: out}
+	w2 := &logLineWriter{lock: lock, w: out}
+
+	w1.Write([]byte("one "))
+	w2.Write([]byte("two\nthree"))
+	w1.Write([]byte("line\nnext\n"))
+	w2.Close()
+	w1.Close()
+
+	if expected := "two\n[c1] one line\n[c1] next\nthree\n"; out.String() != expected {
+		t.Errorf("expected %q, found %q", expected, out.String())
+	}
+}
+
+func Test_servicelog_disabled(t *testing.T) {
+	// the logs are not returned unless the API is enabled in the config
+	a := &API{Manager: worker.Manager{Config: &config.HorizonConfig{Edge: config.Config{}}}}
+	w := httptest.NewRecorder()
+	a.servicelog(w, httptest.NewRequest(http.MethodGet, "/service/log?service=IBM/netspeed", nil))
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "ServiceLogAPI") {
+		t.Errorf("expected the API to be disabled, found %v %v", w.Code, w.Body.String())
+	}
+}
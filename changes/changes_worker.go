@@ -113,7 +113,7 @@ func (w *ChangesWorker) Initialize() bool {
 
 	// If there are already agreements, then we can allow the polling interval to grow. If not, the first agreement
 	// that gets made will allow the poller interval to grow.
-	if agreements, err := persistence.FindEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter()}); err != nil {
+	if agreements, err := persistence.FindActiveEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{}); err != nil {
 		glog.Errorf(chglog(fmt.Sprintf("error searching for agreements, error %v", err)))
 	} else if len(agreements) != 0 {
 		w.agreementReached = true
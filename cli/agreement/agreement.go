@@ -1,11 +1,11 @@
 package agreement
 
 import (
-	"encoding/json"
-	"fmt"
+	"errors"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/persistence"
+	"time"
 )
 
 type ActiveAgreement struct {
@@ -93,27 +93,131 @@ func GetAgreements(archivedAgreements bool) (apiAgreements []persistence.Establi
 	return
 }
 
-func List(archivedAgreements bool, agreementId string) {
-	apiAgreements := GetAgreements(archivedAgreements)
+// The columns of the csv output of 'hzn agreement list', when --columns is not specified.
+var ActiveAgreementColumns = []string{"current_agreement_id", "name", "consumer_id", "agreement_creation_time", "agreement_execution_start_time", "workload_to_run.url", "workload_to_run.org", "workload_to_run.version"}
+var ArchivedAgreementColumns = []string{"current_agreement_id", "name", "consumer_id", "agreement_creation_time", "agreement_terminated_time", "terminated_description", "workload_to_run.url", "workload_to_run.org", "workload_to_run.version"}
+
+// The states of the agreements, from the steps of the agreement protocol they have reached.
+const (
+	STATE_PROPOSED   = "proposed"
+	STATE_ACCEPTED   = "accepted"
+	STATE_FINALIZED  = "finalized"
+	STATE_EXECUTING  = "executing"
+	STATE_TERMINATED = "terminated"
+)
+
+var AgreementStates = []string{STATE_PROPOSED, STATE_ACCEPTED, STATE_FINALIZED, STATE_EXECUTING, STATE_TERMINATED}
+
+// State returns the last step of the agreement protocol that the agreement has reached.
+func State(ag persistence.EstablishedAgreement) string {
+	if ag.AgreementTerminatedTime != 0 || ag.Archived {
+		return STATE_TERMINATED
+	} else if ag.AgreementExecutionStartTime != 0 {
+		return STATE_EXECUTING
+	} else if ag.AgreementFinalizedTime != 0 {
+		return STATE_FINALIZED
+	} else if ag.AgreementAcceptedTime != 0 {
+		return STATE_ACCEPTED
+	}
+	return STATE_PROPOSED
+}
+
+// Parses the --since filter, an age such as 12h or 7d, or a date such as 2006-01-02 or 2006-01-02T15:04:05Z.
+func parseSince(since string) (time.Time, error) {
+	if age, err := cliutils.ParseAge(since); err == nil {
+		return time.Now().Add(-age), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, since); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New(i18n.GetMessagePrinter().Sprintf("invalid --since %v, it must be an age such as 12h or 7d, or a date such as 2006-01-02 or 2006-01-02T15:04:05Z", since))
+}
 
+// Returns true if the agreement matches all of the filters of the list that are set. The workload is the url of the
+// service, or org/url. An active agreement is since the time when it was created after it, an archived agreement when
+// it was terminated after it.
+func matchesListFilters(ag persistence.EstablishedAgreement, workload string, state string, since time.Time) bool {
+	if workload != "" && workload != ag.RunningWorkload.URL && workload != ag.RunningWorkload.Org+"/"+ag.RunningWorkload.URL {
+		return false
+	}
+	if state != "" && State(ag) != state {
+		return false
+	}
+	if !since.IsZero() {
+		agTime := ag.AgreementCreationTime
+		if ag.Archived {
+			agTime = ag.AgreementTerminatedTime
+		}
+		if time.Unix(int64(agTime), 0).Before(since) {
+			return false
+		}
+	}
+	return true
+}
+
+func List(archivedAgreements bool, agreementId string, raw bool, output string, columns string, workload string, state string, since string) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
+	var sinceTime time.Time
+	if since != "" {
+		var err error
+		if sinceTime, err = parseSince(since); err != nil {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, err.Error())
+		}
+	}
+
+	apiAgreements := GetAgreements(archivedAgreements)
+	if agreementId == "" && (workload != "" || state != "" || since != "") {
+		filtered := make([]persistence.EstablishedAgreement, 0, len(apiAgreements))
+		for _, ag := range apiAgreements {
+			if matchesListFilters(ag, workload, state, sinceTime) {
+				filtered = append(filtered, ag)
+			}
+		}
+		apiAgreements = filtered
+	}
+
 	if agreementId != "" {
 		// Look for our agreement id. This works for either active or archived
 		for i := range apiAgreements {
 			if agreementId == apiAgreements[i].CurrentAgreementId {
-				// Found it
-				jsonBytes, err := json.MarshalIndent(apiAgreements[i], "", cliutils.JSON_INDENT)
-				if err != nil {
-					cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal agreement with index %d: %v", i, err))
+				// Found it. Unless the raw output is requested, present the proposal in its decoded form.
+				var agreement interface{} = apiAgreements[i]
+				if !raw && apiAgreements[i].Proposal != "" {
+					if decoded, err := DecodeProposal(apiAgreements[i].Proposal); err != nil {
+						msgPrinter.Printf("Warning: unable to decode the proposal, displaying it as is: %v", err)
+						msgPrinter.Println()
+					} else {
+						agreement = AgreementWithDecodedProposal{EstablishedAgreement: apiAgreements[i], Proposal: decoded}
+					}
 				}
-				fmt.Printf("%s\n", jsonBytes)
+				cliutils.Output(agreement, "hzn agreement list")
 				return
 			}
 		}
 		// Did not find it
 		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("agreement id %s not found", agreementId))
+	} else if cliutils.IsCSVOutput(output) {
+		records := make([]map[string]interface{}, len(apiAgreements))
+		for i := range apiAgreements {
+			if !archivedAgreements {
+				var a ActiveAgreement
+				a.CopyAgreementInto(apiAgreements[i])
+				records[i] = cliutils.CSVRecord("", a)
+			} else {
+				var a ArchivedAgreement
+				a.CopyAgreementInto(apiAgreements[i])
+				records[i] = cliutils.CSVRecord("", a)
+			}
+		}
+		defaultColumns := ActiveAgreementColumns
+		if archivedAgreements {
+			defaultColumns = ArchivedAgreementColumns
+		}
+		cliutils.OutputCSV(records, cliutils.ParseColumns(columns, defaultColumns))
 	} else {
 		// Listing all active or archived agreements. Go thru apiAgreements and convert into our output struct and then print
 		if !archivedAgreements {
@@ -121,40 +225,65 @@ func List(archivedAgreements bool, agreementId string) {
 			for i := range apiAgreements {
 				agreements[i].CopyAgreementInto(apiAgreements[i])
 			}
-			jsonBytes, err := json.MarshalIndent(agreements, "", cliutils.JSON_INDENT)
-			if err != nil {
-				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn agreement list' output: %v", err))
-			}
-			fmt.Printf("%s\n", jsonBytes)
+			cliutils.Output(agreements, "hzn agreement list")
 		} else {
 			// Archived agreements
 			agreements := make([]ArchivedAgreement, len(apiAgreements))
 			for i := range apiAgreements {
 				agreements[i].CopyAgreementInto(apiAgreements[i])
 			}
-			jsonBytes, err := json.MarshalIndent(agreements, "", cliutils.JSON_INDENT)
-			if err != nil {
-				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn agreement list' output: %v", err))
-			}
-			fmt.Printf("%s\n", jsonBytes)
+			cliutils.Output(agreements, "hzn agreement list")
 		}
 	}
 }
 
-func Cancel(agreementId string, allAgreements bool) {
+// Returns true if the agreement matches all of the filters that are set.
+func matchesCancelFilters(ag persistence.EstablishedAgreement, pattern string, org string, olderThan time.Duration) bool {
+	if org != "" && ag.RunningWorkload.Org != org {
+		return false
+	}
+	if olderThan != 0 && time.Since(time.Unix(int64(ag.AgreementCreationTime), 0)) < olderThan {
+		return false
+	}
+	if pattern != "" {
+		if decoded, err := DecodeProposal(ag.Proposal); err != nil || decoded.TsAndCs == nil || decoded.TsAndCs.PatternId != pattern {
+			return false
+		}
+	}
+	return true
+}
+
+func Cancel(agreementId string, allAgreements bool, pattern string, org string, olderThan string, concurrency int, summaryFile string) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
+	filtered := pattern != "" || org != "" || olderThan != ""
+	if filtered && !allAgreements {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("--pattern, --org and --older-than can only be specified with -a."))
+	}
+
+	var age time.Duration
+	if olderThan != "" {
+		var err error
+		if age, err = cliutils.ParseAge(olderThan); err != nil {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, err.Error())
+		}
+	}
+
 	// Put the agreement ids in a slice
 	var agrIds []string
 	if allAgreements {
 		apiAgreements := GetAgreements(false)
 		for _, a := range apiAgreements {
-			agrIds = append(agrIds, a.CurrentAgreementId)
+			if matchesCancelFilters(a, pattern, org, age) {
+				agrIds = append(agrIds, a.CurrentAgreementId)
+			}
 		}
 		if len(agrIds) == 0 {
 			msgPrinter.Printf("No active agreements to cancel.")
 			msgPrinter.Println()
+			cliutils.FinishBulk([]cliutils.BulkResult{}, summaryFile, cliutils.HTTP_ERROR, "")
+			return
 		}
 	} else {
 		if agreementId == "" {
@@ -164,9 +293,12 @@ func Cancel(agreementId string, allAgreements bool) {
 	}
 
 	// Cancel the agreements
-	for _, id := range agrIds {
-		msgPrinter.Printf("Canceling agreement %s ...", id)
-		msgPrinter.Println()
-		cliutils.HorizonDelete("agreement/"+id, []int{200, 204}, []int{}, false)
-	}
+	msgPrinter.Printf("Canceling %d agreement(s) ...", len(agrIds))
+	msgPrinter.Println()
+	results := cliutils.RunBulk(agrIds, concurrency, func(id string) error {
+		_, err := cliutils.HorizonDelete("agreement/"+id, []int{200, 204}, []int{}, true)
+		return err
+	})
+	failed := cliutils.PrintBulkResults(results, "%s: canceled")
+	cliutils.FinishBulk(results, summaryFile, cliutils.HTTP_ERROR, msgPrinter.Sprintf("unable to cancel %d of %d agreement(s).", failed, len(agrIds)))
 }
@@ -0,0 +1,94 @@
+// +build unit
+
+package agreement
+
+import (
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_List_outputPrecedence(t *testing.T) {
+	h := clitest.New(t)
+	now := time.Now().Unix()
+	h.Agent.Handle(http.MethodGet, "agreement", http.StatusOK, map[string]interface{}{"agreements": map[string]interface{}{
+		"active": []interface{}{map[string]interface{}{"current_agreement_id": "ag1", "agreement_creation_time": now - 60,
+			"workload_to_run": map[string]interface{}{"url": "netspeed", "org": "IBM", "version": "1.0.0"}}},
+		"archived": []interface{}{},
+	}})
+
+	// the default json output of the command is written in the global format, for the list and a single agreement
+	h.Setenv(cliutils.OUTPUT_FORMAT_ENV, "go-template={{range .}}{{.current_agreement_id}}{{end}}")
+	if res := h.Run(func() { List(false, "", false, cliutils.OUTPUT_FORMAT_JSON, "", "", "", "") }); res.ExitCode != 0 || res.Stdout != "ag1" {
+		t.Errorf("expected the template output, found %q, stderr: %v", res.Stdout, res.Stderr)
+	}
+	h.Setenv(cliutils.OUTPUT_FORMAT_ENV, "yaml")
+	if res := h.Run(func() { List(false, "ag1", true, cliutils.OUTPUT_FORMAT_JSON, "", "", "", "") }); res.ExitCode != 0 || !strings.Contains(res.Stdout, "current_agreement_id: ag1\n") {
+		t.Errorf("expected the yaml output, found %q, stderr: %v", res.Stdout, res.Stderr)
+	}
+
+	// csv takes precedence over the env var, and can not be used with --output-format
+	if res := h.Run(func() { List(false, "", false, cliutils.OUTPUT_FORMAT_CSV, "current_agreement_id", "", "", "") }); res.ExitCode != 0 || !strings.HasPrefix(res.Stdout, "current_agreement_id\nag1\n") {
+		t.Errorf("expected the csv output, found %q, stderr: %v", res.Stdout, res.Stderr)
+	}
+	res := h.Run(func() {
+		format := "yaml"
+		cliutils.Opts.OutputFmt = &format
+		List(false, "", false, cliutils.OUTPUT_FORMAT_CSV, "", "", "", "")
+	})
+	if res.ExitCode != cliutils.CLI_INPUT_ERROR {
+		t.Errorf("expected exit code %v, found %v, output: %v", cliutils.CLI_INPUT_ERROR, res.ExitCode, res.Stdout)
+	}
+}
+
+func Test_List_filters(t *testing.T) {
+	h := clitest.New(t)
+	now := time.Now().Unix()
+	workload := func(url string) map[string]interface{} {
+		return map[string]interface{}{"url": url, "org": "IBM", "version": "1.0.0"}
+	}
+	h.Agent.Handle(http.MethodGet, "agreement", http.StatusOK, map[string]interface{}{"agreements": map[string]interface{}{
+		"active": []interface{}{
+			map[string]interface{}{"current_agreement_id": "old", "agreement_creation_time": now - 3*24*3600, "agreement_accepted_time": now, "agreement_execution_start_time": now, "workload_to_run": workload("netspeed")},
+			map[string]interface{}{"current_agreement_id": "new", "agreement_creation_time": now - 60, "workload_to_run": workload("netspeed")},
+			map[string]interface{}{"current_agreement_id": "gps", "agreement_creation_time": now - 60, "workload_to_run": workload("gps")},
+		},
+		"archived": []interface{}{
+			map[string]interface{}{"current_agreement_id": "gone", "archived": true, "agreement_creation_time": now - 3600, "agreement_terminated_time": now - 600,
+				"terminated_reason": 201, "terminated_description": "node policy changed", "workload_to_run": workload("netspeed")},
+		},
+	}})
+
+	ids := func(archived bool, workload string, state string, since string) []string {
+		var list []map[string]interface{}
+		h.Run(func() { List(archived, "", false, "json", "", workload, state, since) }).JSON(t, &list)
+		found := []string{}
+		for _, ag := range list {
+			found = append(found, ag["current_agreement_id"].(string))
+		}
+		return found
+	}
+	for _, tc := range []struct {
+		archived               bool
+		workload, state, since string
+		expected               string
+	}{
+		{false, "", "", "", "old,new,gps"},
+		{false, "IBM/netspeed", "", "", "old,new"},
+		{false, "netspeed", "executing", "", "old"},
+		{false, "", "proposed", "1d", "new,gps"},
+		{true, "", "terminated", "1h", "gone"},
+		{true, "", "", "5m", ""},
+	} {
+		if found := strings.Join(ids(tc.archived, tc.workload, tc.state, tc.since), ","); found != tc.expected {
+			t.Errorf("expected %v for %+v, found %v", tc.expected, tc, found)
+		}
+	}
+
+	if res := h.Run(func() { List(false, "", false, "json", "", "", "", "yesterday") }); res.ExitCode != cliutils.CLI_INPUT_ERROR {
+		t.Errorf("expected exit code %v for an invalid --since, found %v", cliutils.CLI_INPUT_ERROR, res.ExitCode)
+	}
+}
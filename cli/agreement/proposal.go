@@ -0,0 +1,69 @@
+package agreement
+
+import (
+	"errors"
+	"github.com/open-horizon/anax/abstractprotocol"
+	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
+)
+
+// DecodedProposal is the structured form of the proposal that is stored in an agreement as a JSON string.
+type DecodedProposal struct {
+	Type           string               `json:"type"`
+	Protocol       string               `json:"protocol"`
+	Version        int                  `json:"version"`
+	AgreementId    string               `json:"agreementId"`
+	ConsumerId     string               `json:"consumerId"`
+	Workload       *policy.Workload     `json:"workload,omitempty"`       // the workload from the tsandcs
+	Pricing        policy.ValueExchange `json:"pricing"`                  // the value exchange from the tsandcs
+	TsAndCs        *policy.Policy       `json:"tsandcs,omitempty"`        // the merged consumer and producer policy
+	ProducerPolicy *policy.Policy       `json:"producerPolicy,omitempty"` // the producer (node) policy
+}
+
+// AgreementWithDecodedProposal is the agreement as returned by the agent, with the proposal string replaced by its decoded form.
+type AgreementWithDecodedProposal struct {
+	persistence.EstablishedAgreement
+	Proposal *DecodedProposal `json:"proposal"`
+}
+
+// DecodeProposal parses the JSON serialized proposal and the policies embedded in it.
+func DecodeProposal(proposal string) (*DecodedProposal, error) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	prop, err := abstractprotocol.DemarshalProposal(proposal)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := DecodedProposal{
+		Type:        prop.Type(),
+		Protocol:    prop.Protocol(),
+		Version:     prop.Version(),
+		AgreementId: prop.AgreementId(),
+		ConsumerId:  prop.ConsumerId(),
+	}
+
+	if prop.TsAndCs() != "" {
+		tsandcs, err := policy.DemarshalPolicy(prop.TsAndCs())
+		if err != nil {
+			return nil, errors.New(msgPrinter.Sprintf("failed to decode the tsandcs of the proposal: %v", err))
+		}
+		decoded.TsAndCs = tsandcs
+		decoded.Pricing = tsandcs.ValueEx
+		if len(tsandcs.Workloads) > 0 {
+			decoded.Workload = &tsandcs.Workloads[0]
+		}
+	}
+
+	if prop.ProducerPolicy() != "" {
+		producerPol, err := policy.DemarshalPolicy(prop.ProducerPolicy())
+		if err != nil {
+			return nil, errors.New(msgPrinter.Sprintf("failed to decode the producer policy of the proposal: %v", err))
+		}
+		decoded.ProducerPolicy = producerPol
+	}
+
+	return &decoded, nil
+}
@@ -0,0 +1,42 @@
+package agreementbot
+
+import (
+	"fmt"
+	"github.com/open-horizon/anax/agreementbot"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/i18n"
+)
+
+// Display the progress of the agent upgrade rollouts that the agbot schedules. If a node management policy is
+// specified, display only its rollout. Show the upgrade state of each node if long.
+func AgentUpgradeList(org string, nmpName string, long bool) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	// set env to call agbot url
+	cliutils.UseAgbotUrlBase()
+
+	auUrl := "agentupgrade"
+	nmpOrg := ""
+	if nmpName != "" {
+		nmpOrg, nmpName = cliutils.TrimOrg(org, nmpName)
+		if nmpOrg == "" {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("org must be specified with -o or as part of the node management policy name."))
+		}
+		auUrl = fmt.Sprintf("%v/%v/%v", auUrl, nmpOrg, nmpName)
+	}
+	if long {
+		auUrl = fmt.Sprintf("%v?long=1", auUrl)
+	}
+
+	if nmpName == "" {
+		progress := map[string]*agreementbot.AgentUpgradeProgress{}
+		cliutils.HorizonGet(auUrl, []int{200}, &progress, false)
+		cliutils.Output(progress, "agbot agentupgrade list")
+	} else {
+		var progress agreementbot.AgentUpgradeProgress
+		if httpCode, _ := cliutils.HorizonGet(auUrl, []int{200, 404}, &progress, false); httpCode == 404 {
+			cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("No agent upgrade rollout found for node management policy %v/%v.", nmpOrg, nmpName))
+		}
+		cliutils.Output(progress, "agbot agentupgrade list")
+	}
+}
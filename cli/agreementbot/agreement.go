@@ -2,11 +2,12 @@ package agreementbot
 
 import (
 	"encoding/json"
-	"fmt"
 	agbot "github.com/open-horizon/anax/agreementbot/persistence"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/i18n"
-	"os"
+	"github.com/open-horizon/anax/policy"
+	"strings"
+	"time"
 )
 
 type ActiveAgreement struct {
@@ -69,9 +70,7 @@ func getAgreements(archivedAgreements bool) (apiAgreements []agbot.Agreement) {
 	msgPrinter := i18n.GetMessagePrinter()
 
 	// set env to call agbot url
-	if err := os.Setenv("HORIZON_URL", cliutils.GetAgbotUrlBase()); err != nil {
-		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("unable to set env var 'HORIZON_URL', error %v", err))
-	}
+	cliutils.UseAgbotUrlBase()
 
 	// Get horizon api agreement output and drill down to the category we want
 	apiOutput := make(map[string]map[string][]agbot.Agreement, 0)
@@ -91,11 +90,21 @@ func getAgreements(archivedAgreements bool) (apiAgreements []agbot.Agreement) {
 	return
 }
 
-func AgreementList(archivedAgreements bool, agreement string) {
+// AgreementList displays the active or archived agreements of the agbot, or just one agreement. The node and service
+// only keep the agreements made with that node and for that service, see matchesAgreementFilters.
+func AgreementList(archivedAgreements bool, agreement string, node string, service string) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	apiAgreements := getAgreements(archivedAgreements)
+	apiAgreements := []agbot.Agreement{}
+	for _, ag := range getAgreements(archivedAgreements) {
+		if (agreement == "" || ag.CurrentAgreementId == agreement) && matchesAgreementFilters(ag, node, service) {
+			apiAgreements = append(apiAgreements, ag)
+		}
+	}
+	if agreement != "" && len(apiAgreements) == 0 {
+		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("agreement %v not found.", agreement))
+	}
 
 	// Go thru the apiAgreements and convert into our output struct and then print
 	if !archivedAgreements {
@@ -103,38 +112,83 @@ func AgreementList(archivedAgreements bool, agreement string) {
 		for i := range apiAgreements {
 			agreements[i] = *NewActiveAgreement(apiAgreements[i])
 		}
-		jsonBytes, err := json.MarshalIndent(agreements, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'agreement list' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(agreements, "agreement list")
 	} else {
 		agreements := make([]ArchivedAgreement, len(apiAgreements))
 		for i := range apiAgreements {
 			agreements[i] = *NewArchivedAgreement(apiAgreements[i])
 		}
-		jsonBytes, err := json.MarshalIndent(agreements, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'agreement list' output: %v", err))
+		cliutils.Output(agreements, "agreement list")
+	}
+}
+
+// Returns true if the agreement was made with the node and for the service, when they are set. The node is <org>/<id>
+// or only the id. The service is the url of a service of the policy of the agreement, optionally prefixed with <org>/.
+func matchesAgreementFilters(ag agbot.Agreement, node string, service string) bool {
+	if node != "" && ag.DeviceId != node && !strings.HasSuffix(ag.DeviceId, "/"+node) {
+		return false
+	}
+	if service == "" {
+		return true
+	}
+	pol := policy.Policy{}
+	if err := json.Unmarshal([]byte(ag.Policy), &pol); err != nil {
+		cliutils.Verbose(i18n.GetMessagePrinter().Sprintf("Unable to read the policy of agreement %v: %v", ag.CurrentAgreementId, err))
+		return false
+	}
+	for _, wl := range pol.Workloads {
+		if wl.WorkloadURL == service || wl.Org+"/"+wl.WorkloadURL == service {
+			return true
 		}
-		fmt.Printf("%s\n", jsonBytes)
 	}
+	return false
+}
+
+// Returns true if the agreement matches all of the filters that are set.
+func matchesCancelFilters(ag agbot.Agreement, pattern string, org string, olderThan time.Duration) bool {
+	if org != "" && ag.Org != org {
+		return false
+	}
+	if pattern != "" && ag.Pattern != pattern {
+		return false
+	}
+	if olderThan != 0 && time.Since(time.Unix(int64(ag.AgreementInceptionTime), 0)) < olderThan {
+		return false
+	}
+	return true
 }
 
-func AgreementCancel(agreementId string, allAgreements bool) {
+func AgreementCancel(agreementId string, allAgreements bool, pattern string, org string, olderThan string, node string, service string, concurrency int, summaryFile string) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
+	filtered := pattern != "" || org != "" || olderThan != "" || node != "" || service != ""
+	if filtered && !allAgreements {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("--pattern, --org, --older-than, --node and --service can only be specified with -a."))
+	}
+
+	var age time.Duration
+	if olderThan != "" {
+		var err error
+		if age, err = cliutils.ParseAge(olderThan); err != nil {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, err.Error())
+		}
+	}
+
 	// Put the agreement ids in a slice
 	var agrIds []string
 	if allAgreements {
 		apiAgreements := getAgreements(false)
 		for _, a := range apiAgreements {
-			agrIds = append(agrIds, a.CurrentAgreementId)
+			if matchesCancelFilters(a, pattern, org, age) && matchesAgreementFilters(a, node, service) {
+				agrIds = append(agrIds, a.CurrentAgreementId)
+			}
 		}
 		if len(agrIds) == 0 {
 			msgPrinter.Printf("No active agreements to cancel.")
 			msgPrinter.Println()
+			cliutils.FinishBulk([]cliutils.BulkResult{}, summaryFile, cliutils.HTTP_ERROR, "")
+			return
 		}
 	} else {
 		if agreementId == "" {
@@ -144,13 +198,14 @@ func AgreementCancel(agreementId string, allAgreements bool) {
 	}
 
 	// Cancel the agreements
-	if err := os.Setenv("HORIZON_URL", cliutils.GetAgbotUrlBase()); err != nil {
-		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("unable to set env var 'HORIZON_URL', error %v", err))
-	}
-
-	for _, id := range agrIds {
-		msgPrinter.Printf("Canceling agreement %s ...", id)
-		msgPrinter.Println()
-		cliutils.HorizonDelete("agreement/"+id, []int{200, 204}, []int{}, false)
-	}
+	cliutils.UseAgbotUrlBase()
+
+	msgPrinter.Printf("Canceling %d agreement(s) ...", len(agrIds))
+	msgPrinter.Println()
+	results := cliutils.RunBulk(agrIds, concurrency, func(id string) error {
+		_, err := cliutils.HorizonDelete("agreement/"+id, []int{200, 204}, []int{}, true)
+		return err
+	})
+	failed := cliutils.PrintBulkResults(results, "%s: canceled")
+	cliutils.FinishBulk(results, summaryFile, cliutils.HTTP_ERROR, msgPrinter.Sprintf("unable to cancel %d of %d agreement(s).", failed, len(agrIds)))
 }
@@ -0,0 +1,71 @@
+// +build unit
+
+package agreementbot
+
+import (
+	"fmt"
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Agreements_filters(t *testing.T) {
+	h := clitest.New(t)
+
+	agreement := func(id string, device string, workloadUrl string) map[string]interface{} {
+		pol := fmt.Sprintf(`{"header":{"name":"p"},"workloads":[{"workloadUrl":"%v","organization":"myorg"}]}`, workloadUrl)
+		return map[string]interface{}{"current_agreement_id": id, "org": "myorg", "device_id": device, "policy": pol, "agreement_creation_time": time.Now().Unix()}
+	}
+	h.Agbot.Handle(http.MethodGet, "agreement", http.StatusOK, map[string]interface{}{"agreements": map[string]interface{}{
+		"active": []interface{}{
+			agreement("a1", "myorg/node1", "web"),
+			agreement("a2", "myorg/node2", "web"),
+			agreement("a3", "myorg/node1", "db"),
+		},
+		"archived": []interface{}{},
+	}})
+	h.Agbot.Handle(http.MethodDelete, "agreement/a1", http.StatusOK, nil)
+
+	ids := func(res clitest.Result) []string {
+		var output []ActiveAgreement
+		res.JSON(t, &output)
+		found := []string{}
+		for _, ag := range output {
+			found = append(found, ag.CurrentAgreementId)
+		}
+		return found
+	}
+	for _, tc := range []struct {
+		agreement, node, service string
+		expected                 string
+	}{
+		{"", "node1", "", "a1,a3"},
+		{"", "myorg/node1", "myorg/web", "a1"},
+		{"", "", "web", "a1,a2"},
+		{"a2", "", "", "a2"},
+	} {
+		res := h.Run(func() { AgreementList(false, tc.agreement, tc.node, tc.service) })
+		if res.ExitCode != 0 {
+			t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+		} else if found := strings.Join(ids(res), ","); found != tc.expected {
+			t.Errorf("%+v: expected agreements %v, found %v", tc, tc.expected, found)
+		}
+	}
+	if res := h.Run(func() { AgreementList(false, "other", "", "") }); res.ExitCode != cliutils.NOT_FOUND {
+		t.Errorf("expected exit code %v, found %v", cliutils.NOT_FOUND, res.ExitCode)
+	}
+
+	// only the agreement with the node for the service is canceled
+	res := h.Run(func() { AgreementCancel("", true, "", "", "", "node1", "web", 1, "") })
+	if res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if !strings.Contains(res.Stdout, "a1: canceled") || strings.Contains(res.Stdout, "a3") {
+		t.Errorf("wrong output: %v", res.Stdout)
+	}
+	if res := h.Run(func() { AgreementCancel("a1", false, "", "", "", "node1", "", 1, "") }); res.ExitCode != cliutils.CLI_INPUT_ERROR {
+		t.Errorf("expected exit code %v, found %v", cliutils.CLI_INPUT_ERROR, res.ExitCode)
+	}
+}
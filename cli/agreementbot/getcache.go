@@ -1,32 +1,23 @@
 package agreementbot
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/open-horizon/anax/agreementbot"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/i18n"
-	"os"
 )
 
 // Display served pattern orgs and deployment policy orgs cached by aggrement bot.
 func GetServedOrgs() {
-	msgPrinter := i18n.GetMessagePrinter()
 	// set env to call agbot url
-	if err := os.Setenv("HORIZON_URL", cliutils.GetAgbotUrlBase()); err != nil {
-		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("unable to set env var 'HORIZON_URL', error %v", err))
-	}
+	cliutils.UseAgbotUrlBase()
 
 	// Get the agbot servedorgs info
 	servedOrgsInfo := agreementbot.ServedOrgs{} // the structure we will output
 	cliutils.HorizonGet("cache/servedorg", []int{200}, &servedOrgsInfo, false)
 
 	// Output the combined info
-	jsonBytes, err := json.MarshalIndent(servedOrgsInfo, "", cliutils.JSON_INDENT)
-	if err != nil {
-		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn node list' output: %v", err))
-	}
-	fmt.Printf("%s\n", jsonBytes)
+	cliutils.Output(servedOrgsInfo, "hzn agbot cache servedorg list")
 
 }
 
@@ -35,9 +26,7 @@ func GetPatterns(org string, name string, long bool) {
 	msgPrinter := i18n.GetMessagePrinter()
 
 	// set env to call agbot url
-	if err := os.Setenv("HORIZON_URL", cliutils.GetAgbotUrlBase()); err != nil {
-		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("unable to set env var 'HORIZON_URL', error %v", err))
-	}
+	cliutils.UseAgbotUrlBase()
 
 	if name != "" && org == "" {
 		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("org must be specified with -o when pattern name is specified."))
@@ -65,11 +54,7 @@ func GetPatterns(org string, name string, long bool) {
 			cliutils.HorizonGet(patUrl, []int{200}, &patInfo, false)
 
 			// Output the combined info
-			jsonBytes, err := json.MarshalIndent(patInfo, "", cliutils.JSON_INDENT)
-			if err != nil {
-				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal output: %v", err))
-			}
-			fmt.Printf("%s\n", jsonBytes)
+			cliutils.Output(patInfo, "hzn agbot cache pattern list")
 
 		} else if name == "" {
 			// Get the agbot servedorgs info
@@ -80,11 +65,7 @@ func GetPatterns(org string, name string, long bool) {
 				msgPrinter.Println()
 			} else {
 				// Output the combined info
-				jsonBytes, err := json.MarshalIndent(patInfo, "", cliutils.JSON_INDENT)
-				if err != nil {
-					cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal output: %v", err))
-				}
-				fmt.Printf("%s\n", jsonBytes)
+				cliutils.Output(patInfo, "hzn agbot cache pattern list")
 			}
 		}
 
@@ -97,11 +78,7 @@ func GetPatterns(org string, name string, long bool) {
 				msgPrinter.Println()
 			} else {
 				// Output the combined info
-				jsonBytes, err := json.MarshalIndent(patInfo, "", cliutils.JSON_INDENT)
-				if err != nil {
-					cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal output: %v", err))
-				}
-				fmt.Printf("%s\n", jsonBytes)
+				cliutils.Output(patInfo, "hzn agbot cache pattern list")
 			}
 		} else {
 
@@ -111,11 +88,7 @@ func GetPatterns(org string, name string, long bool) {
 				msgPrinter.Println()
 			} else {
 				// Output the combined info
-				jsonBytes, err := json.MarshalIndent(patInfo, "", cliutils.JSON_INDENT)
-				if err != nil {
-					cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal output: %v", err))
-				}
-				fmt.Printf("%s\n", jsonBytes)
+				cliutils.Output(patInfo, "hzn agbot cache pattern list")
 			}
 		}
 	} else {
@@ -124,11 +97,7 @@ func GetPatterns(org string, name string, long bool) {
 		cliutils.HorizonGet(patUrl, []int{200}, &patInfo, false)
 
 		// Output the combined info
-		jsonBytes, err := json.MarshalIndent(patInfo, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(patInfo, "hzn agbot cache pattern list")
 	}
 
 }
@@ -137,9 +106,7 @@ func GetPatterns(org string, name string, long bool) {
 func GetPolicies(org string, name string, long bool) {
 	msgPrinter := i18n.GetMessagePrinter()
 	// set env to call agbot url
-	if err := os.Setenv("HORIZON_URL", cliutils.GetAgbotUrlBase()); err != nil {
-		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("unable to set env var 'HORIZON_URL', error %v", err))
-	}
+	cliutils.UseAgbotUrlBase()
 
 	if name != "" && org == "" {
 		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("org must be specified with -o when deployment policy name is specified."))
@@ -168,11 +135,7 @@ func GetPolicies(org string, name string, long bool) {
 			cliutils.HorizonGet(polUrl, []int{200}, &polInfo, false)
 
 			// Output the combined info
-			jsonBytes, err := json.MarshalIndent(polInfo, "", cliutils.JSON_INDENT)
-			if err != nil {
-				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal output: %v", err))
-			}
-			fmt.Printf("%s\n", jsonBytes)
+			cliutils.Output(polInfo, "hzn agbot cache deploymentpol list")
 
 		} else if name == "" {
 			// Get the agbot servedorgs info
@@ -183,11 +146,7 @@ func GetPolicies(org string, name string, long bool) {
 				msgPrinter.Println()
 			} else {
 				// Output the combined info
-				jsonBytes, err := json.MarshalIndent(polInfo, "", cliutils.JSON_INDENT)
-				if err != nil {
-					cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal output: %v", err))
-				}
-				fmt.Printf("%s\n", jsonBytes)
+				cliutils.Output(polInfo, "hzn agbot cache deploymentpol list")
 			}
 		}
 
@@ -200,11 +159,7 @@ func GetPolicies(org string, name string, long bool) {
 				msgPrinter.Println()
 			} else {
 				// Output the combined info
-				jsonBytes, err := json.MarshalIndent(polInfo, "", cliutils.JSON_INDENT)
-				if err != nil {
-					cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal output: %v", err))
-				}
-				fmt.Printf("%s\n", jsonBytes)
+				cliutils.Output(polInfo, "hzn agbot cache deploymentpol list")
 			}
 		} else {
 			polInfo := []string{} //the structure we will output
@@ -213,11 +168,7 @@ func GetPolicies(org string, name string, long bool) {
 				msgPrinter.Println()
 			} else {
 				// Output the combined info
-				jsonBytes, err := json.MarshalIndent(polInfo, "", cliutils.JSON_INDENT)
-				if err != nil {
-					cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal output: %v", err))
-				}
-				fmt.Printf("%s\n", jsonBytes)
+				cliutils.Output(polInfo, "hzn agbot cache deploymentpol list")
 			}
 		}
 	} else {
@@ -226,10 +177,6 @@ func GetPolicies(org string, name string, long bool) {
 		cliutils.HorizonGet(polUrl, []int{200}, &polInfo, false)
 
 		// Output the combined info
-		jsonBytes, err := json.MarshalIndent(polInfo, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(polInfo, "hzn agbot cache deploymentpol list")
 	}
 }
@@ -1,13 +1,10 @@
 package agreementbot
 
 import (
-	"encoding/json"
-	"fmt"
 	"github.com/open-horizon/anax/agreementbot"
 	"github.com/open-horizon/anax/apicommon"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/i18n"
-	"os"
 )
 
 // This is a combo of anax's HorizonDevice and Info (status) structs
@@ -35,9 +32,7 @@ func (n *AgbotAndStatus) CopyStatusInto(status *apicommon.Info) {
 
 func List() {
 	// set env to call agbot url
-	if err := os.Setenv("HORIZON_URL", cliutils.GetAgbotUrlBase()); err != nil {
-		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, i18n.GetMessagePrinter().Sprintf("unable to set env var 'HORIZON_URL', error %v", err))
-	}
+	cliutils.UseAgbotUrlBase()
 
 	// Get the agbot info
 	horDevice := agreementbot.HorizonAgbot{}
@@ -51,9 +46,26 @@ func List() {
 	nodeInfo.CopyStatusInto(&status)
 
 	// Output the combined info
-	jsonBytes, err := json.MarshalIndent(nodeInfo, "", cliutils.JSON_INDENT)
-	if err != nil {
-		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn node list' output: %v", err))
+	cliutils.Output(nodeInfo, "hzn node list")
+}
+
+// Purge removes the archived agreements and the workload usages the agbot has for a node, and prints the signed report
+// of what was removed. The node must not have an active agreement with the agbot.
+func Purge(node string, force bool, reportFile string) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	// set env to call agbot url
+	cliutils.UseAgbotUrlBase()
+
+	org, id := cliutils.TrimOrg("", node)
+	if org == "" || id == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the node must be in the form org/id: %v", node))
+	}
+
+	if !force {
+		cliutils.ConfirmRemove(msgPrinter.Sprintf("Are you sure you want to remove the archived agreements and the workload usages this agbot has for node %v/%v?", org, id))
 	}
-	fmt.Printf("%s\n", jsonBytes) //todo: is there a way to output with json syntax highlighting like jq does?
+
+	_, respBody, _ := cliutils.HorizonPutPost("POST", "node/"+org+"/"+id+"/purge", []int{200}, "", true)
+	cliutils.OutputDeletionReport(respBody, reportFile)
 }
@@ -1,20 +1,16 @@
 package agreementbot
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/policy"
-	"os"
 )
 
 // get the policy names that the agbot hosts
 func getPolicyNames(org string) (map[string][]string, int) {
 	// set env to call agbot url
-	if err := os.Setenv("HORIZON_URL", cliutils.GetAgbotUrlBase()); err != nil {
-		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, i18n.GetMessagePrinter().Sprintf("unable to set env var 'HORIZON_URL', error %v", err))
-	}
+	cliutils.UseAgbotUrlBase()
 
 	// Get horizon api policy output
 	apiOutput := make(map[string][]string, 0)
@@ -34,9 +30,7 @@ func getPolicyNames(org string) (map[string][]string, int) {
 // get the policy with the given name for the given org
 func getPolicy(org string, name string) (*policy.Policy, int) {
 	// set env to call agbot url
-	if err := os.Setenv("HORIZON_URL", cliutils.GetAgbotUrlBase()); err != nil {
-		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, i18n.GetMessagePrinter().Sprintf("unable to set env var 'HORIZON_URL', error %v", err))
-	}
+	cliutils.UseAgbotUrlBase()
 
 	// Get horizon api policy output
 	var apiOutput policy.Policy
@@ -52,11 +46,7 @@ func PolicyList(org string, name string) {
 	if name == "" {
 		policies, httpCode := getPolicyNames(org)
 		if httpCode == 200 {
-			jsonBytes, err := json.MarshalIndent(policies, "", cliutils.JSON_INDENT)
-			if err != nil {
-				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'policy list' output: %v", err))
-			}
-			fmt.Printf("%s\n", jsonBytes)
+			cliutils.Output(policies, "policy list")
 		} else if httpCode == 400 {
 			msgPrinter.Printf("Error: The organization '%v' does not exist.", org)
 			msgPrinter.Println()
@@ -64,11 +54,7 @@ func PolicyList(org string, name string) {
 	} else {
 		pol, httpCode := getPolicy(org, name)
 		if httpCode == 200 {
-			jsonBytes, err := json.MarshalIndent(pol, "", cliutils.JSON_INDENT)
-			if err != nil {
-				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'policy list' output: %v", err))
-			}
-			fmt.Printf("%s\n", jsonBytes)
+			cliutils.Output(pol, "policy list")
 		} else if httpCode == 400 {
 			msgPrinter.Printf("Error: Either the organization '%v' does not exist or the policy '%v' is not hosted by this agbot.", org, name)
 			msgPrinter.Println()
@@ -1,18 +1,24 @@
 package attribute
 
 import (
+	"bytes"
 	"encoding/json"
-	"fmt"
 	"github.com/open-horizon/anax/api"
+	"github.com/open-horizon/anax/cli/cliconfig"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/persistence"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
 )
 
 const HTTPSBasicAuthAttributes = "HTTPSBasicAuthAttributes"
 
 // Our form of the attributes output
 type OurAttributes struct {
+	Id           string                   `json:"id,omitempty"`
 	Type         string                   `json:"type"`
 	Label        string                   `json:"label"`
 	ServiceSpecs persistence.ServiceSpecs `json:"service_specs,omitempty"`
@@ -35,17 +41,141 @@ func List() {
 	// Only include interesting fields in our output
 	attrs := []OurAttributes{}
 	for _, a := range apiAttrs {
+		id := ""
+		if a.Id != nil {
+			id = *a.Id
+		}
 		if a.ServiceSpecs == nil {
-			attrs = append(attrs, OurAttributes{Type: *a.Type, Label: *a.Label, Variables: *a.Mappings})
+			attrs = append(attrs, OurAttributes{Id: id, Type: *a.Type, Label: *a.Label, Variables: *a.Mappings})
 		} else {
-			attrs = append(attrs, OurAttributes{Type: *a.Type, Label: *a.Label, ServiceSpecs: *a.ServiceSpecs, Variables: *a.Mappings})
+			attrs = append(attrs, OurAttributes{Id: id, Type: *a.Type, Label: *a.Label, ServiceSpecs: *a.ServiceSpecs, Variables: *a.Mappings})
 		}
 	}
 
 	// Convert to json and output
-	jsonBytes, err := json.MarshalIndent(attrs, "", cliutils.JSON_INDENT)
-	if err != nil {
-		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn attribute list' output: %v", err))
+	cliutils.Output(attrs, "hzn attribute list")
+}
+
+// The input file of hzn attribute add and update, in the format of the output of hzn attribute list. The label defaults
+// to the type, publishable and host_only to false.
+type AttributeFile struct {
+	Type         string                    `json:"type"`
+	Label        *string                   `json:"label,omitempty"`
+	Publishable  *bool                     `json:"publishable,omitempty"`
+	HostOnly     *bool                     `json:"host_only,omitempty"`
+	ServiceSpecs *persistence.ServiceSpecs `json:"service_specs,omitempty"`
+	Variables    *map[string]interface{}   `json:"variables,omitempty"`
+}
+
+// The types of the attributes that the agent accepts.
+func attributeTypes() []string {
+	return []string{
+		reflect.TypeOf(persistence.UserInputAttributes{}).Name(),
+		reflect.TypeOf(persistence.HAAttributes{}).Name(),
+		reflect.TypeOf(persistence.MeteringAttributes{}).Name(),
+		reflect.TypeOf(persistence.AgreementProtocolAttributes{}).Name(),
+		HTTPSBasicAuthAttributes,
+		reflect.TypeOf(persistence.DockerRegistryAuthAttributes{}).Name(),
+	}
+}
+
+// Read the attribute file and validate it with the same checks as the agent API, so that an attribute with the wrong
+// type or variables is reported before it is sent. The numbers are kept as json numbers, like the agent reads them.
+func readAttributeFile(filePath string) *api.Attribute {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	attrFile := AttributeFile{}
+	decoder := json.NewDecoder(bytes.NewReader(cliconfig.ReadJsonFileWithLocalConfig(filePath)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&attrFile); err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal json input file %s: %v", filePath, err))
+	}
+	types := attributeTypes()
+	found := false
+	for _, t := range types {
+		found = found || t == attrFile.Type
+	}
+	if !found {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the attribute type '%v' in %v is not supported, it must be one of: %v", attrFile.Type, filePath, strings.Join(types, ", ")))
+	}
+
+	attr := &api.Attribute{Type: &attrFile.Type, Label: attrFile.Label, Publishable: attrFile.Publishable, HostOnly: attrFile.HostOnly, ServiceSpecs: attrFile.ServiceSpecs, Mappings: attrFile.Variables}
+	if attr.Label == nil {
+		label := attrFile.Type
+		attr.Label = &label
+	}
+	if attr.Publishable == nil {
+		attr.Publishable = new(bool)
+	}
+	if attr.HostOnly == nil {
+		attr.HostOnly = new(bool)
+	}
+	if attr.Mappings == nil {
+		attr.Mappings = &map[string]interface{}{}
+	}
+
+	var passthruError error
+	if _, errorHandled, err := api.ValidateAndConvertAPIAttribute(api.GetPassThroughErrorHandler(&passthruError), false, *attr); errorHandled && passthruError != nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the attribute in %v is not valid: %v", filePath, passthruError))
+	} else if err != nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the attribute in %v is not valid: %v", filePath, err))
+	}
+	return attr
+}
+
+// Add adds the attribute of the file to the node.
+func Add(filePath string) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	attr := readAttributeFile(filePath)
+	httpCode, respBody, _ := cliutils.HorizonPutPost(http.MethodPost, "attribute", []int{201, 200, http.StatusConflict, cliutils.ANAX_NOT_CONFIGURED_YET}, attr, true)
+	if httpCode == cliutils.ANAX_NOT_CONFIGURED_YET {
+		cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf(cliutils.MUST_REGISTER_FIRST))
+	} else if httpCode == http.StatusConflict {
+		cliutils.Fatal(cliutils.CONFLICT_ERROR, msgPrinter.Sprintf("the node already has an attribute of type %v for the same services, use 'hzn attribute update' to change it.", *attr.Type))
+	}
+
+	added := api.Attribute{}
+	if err := json.Unmarshal([]byte(respBody), &added); err == nil && added.Id != nil {
+		msgPrinter.Printf("Attribute %v added.", *added.Id)
+	} else {
+		msgPrinter.Printf("Attribute added.")
+	}
+	msgPrinter.Println()
+}
+
+// Update replaces the attribute with the attribute of the file. The agent does not support partial updates of most of
+// the attribute types, so the whole attribute is replaced.
+func Update(id string, filePath string) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	attr := readAttributeFile(filePath)
+	httpCode, _, _ := cliutils.HorizonPutPost(http.MethodPut, "attribute/"+url.PathEscape(id), []int{200, http.StatusNotFound, cliutils.ANAX_NOT_CONFIGURED_YET}, attr, true)
+	if httpCode == cliutils.ANAX_NOT_CONFIGURED_YET {
+		cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf(cliutils.MUST_REGISTER_FIRST))
+	} else if httpCode == http.StatusNotFound {
+		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("attribute %v of type %v not found on this node.", id, *attr.Type))
+	}
+	msgPrinter.Printf("Attribute %v updated.", id)
+	msgPrinter.Println()
+}
+
+// Remove removes an attribute from the node. The agent does not report a missing attribute, so it is looked up first.
+func Remove(id string, force bool) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	apiOutput := map[string][]api.Attribute{}
+	httpCode, _ := cliutils.HorizonGet("attribute/"+url.PathEscape(id), []int{200, cliutils.ANAX_NOT_CONFIGURED_YET}, &apiOutput, false)
+	if httpCode == cliutils.ANAX_NOT_CONFIGURED_YET {
+		cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf(cliutils.MUST_REGISTER_FIRST))
+	} else if len(apiOutput["attributes"]) == 0 {
+		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("attribute %v not found on this node.", id))
+	}
+
+	if !force {
+		cliutils.ConfirmRemove(msgPrinter.Sprintf("Are you sure you want to remove attribute %v from this node?", id))
 	}
-	fmt.Printf("%s\n", jsonBytes)
+	cliutils.HorizonDelete("attribute/"+url.PathEscape(id), []int{200, 204}, []int{}, false)
+	msgPrinter.Printf("Attribute %v removed.", id)
+	msgPrinter.Println()
 }
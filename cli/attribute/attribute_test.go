@@ -0,0 +1,75 @@
+// +build unit
+
+package attribute
+
+import (
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Attribute(t *testing.T) {
+	h := clitest.New(t)
+	h.Agent.SetNode(clitest.FAKE_ORG, "mynode", "", "configured")
+	h.Agent.Handle(http.MethodPost, "attribute", http.StatusCreated, map[string]interface{}{"id": "a1", "type": "MeteringAttributes"})
+	dir, err := ioutil.TempDir("", "clitest")
+	if err != nil {
+		t.Fatalf("unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name string, content string) string {
+		file := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("unable to write %v: %v", file, err)
+		}
+		return file
+	}
+
+	metering := write("metering.json", `{"type": "MeteringAttributes", "variables": {"tokens": 2, "perTimeUnit": "min", "notificationInterval": 30}}`)
+	if res := h.Run(func() { Add(metering) }); res.ExitCode != 0 || !strings.Contains(res.Stdout, "Attribute a1 added.") {
+		t.Fatalf("unexpected exit code %v, output %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+	if reqs := h.Agent.RequestsTo(http.MethodPost, "attribute"); len(reqs) != 1 || !strings.Contains(string(reqs[0].Body), `"mappings":{"notificationInterval":30,"perTimeUnit":"min","tokens":2}`) || !strings.Contains(string(reqs[0].Body), `"publishable":false`) {
+		t.Errorf("unexpected attribute posted %v", reqs)
+	}
+
+	// the attributes that the agent would reject are not sent
+	for _, content := range []string{`{"type": "LocationAttributes", "variables": {}}`, `{"type": "HAAttributes", "variables": {"partnerID": "nodeB"}}`, `{"type": "MeteringAttributes", "variables": {"tokens": 2}}`} {
+		file := write("invalid.json", content)
+		if res := h.Run(func() { Add(file) }); res.ExitCode != cliutils.CLI_INPUT_ERROR {
+			t.Errorf("expected %v to be rejected, exit code %v, stderr: %v", content, res.ExitCode, res.Stderr)
+		}
+	}
+	if reqs := h.Agent.RequestsTo(http.MethodPost, "attribute"); len(reqs) != 1 {
+		t.Errorf("expected the invalid attributes not to be posted, found %v requests", len(reqs))
+	}
+
+	h.Agent.Handle(http.MethodPost, "attribute", http.StatusConflict, "")
+	if res := h.Run(func() { Add(metering) }); res.ExitCode != cliutils.CONFLICT_ERROR {
+		t.Errorf("expected a conflict, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+
+	h.Agent.Handle(http.MethodPut, "attribute/a1", http.StatusOK, map[string]interface{}{"id": "a1"})
+	if res := h.Run(func() { Update("a1", metering) }); res.ExitCode != 0 || len(h.Agent.RequestsTo(http.MethodPut, "attribute/a1")) != 1 {
+		t.Errorf("unexpected exit code %v for the update, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	h.Agent.Handle(http.MethodPut, "attribute/a2", http.StatusNotFound, "")
+	if res := h.Run(func() { Update("a2", metering) }); res.ExitCode != cliutils.NOT_FOUND {
+		t.Errorf("expected the attribute not to be found, exit code %v", res.ExitCode)
+	}
+
+	h.Agent.Handle(http.MethodGet, "attribute/a1", http.StatusOK, map[string]interface{}{"attributes": []interface{}{map[string]interface{}{"id": "a1", "type": "MeteringAttributes"}}})
+	h.Agent.Handle(http.MethodGet, "attribute/a2", http.StatusOK, map[string]interface{}{"attributes": []interface{}{}})
+	h.Agent.Handle(http.MethodDelete, "attribute/a1", http.StatusOK, map[string]interface{}{"id": "a1"})
+	if res := h.Run(func() { Remove("a1", true) }); res.ExitCode != 0 || len(h.Agent.RequestsTo(http.MethodDelete, "attribute/a1")) != 1 {
+		t.Errorf("unexpected exit code %v for the removal, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	if res := h.Run(func() { Remove("a2", true) }); res.ExitCode != cliutils.NOT_FOUND {
+		t.Errorf("expected the attribute not to be found, exit code %v", res.ExitCode)
+	}
+}
@@ -25,6 +25,9 @@ var PROJECT_CONFIG_FILE string
 var PACKAGE_CONFIG_FILE string
 var USER_CONFIG_FILE string
 
+// the config file that each env var was set from by SetEnvVarsFromConfigFiles
+var envVarSources = map[string]string{}
+
 // configuration for hzn command
 type HorizonCliConfig struct {
 
@@ -33,6 +36,9 @@ type HorizonCliConfig struct {
 	HZN_EXCHANGE_NODE_AUTH string `json:"HZN_EXCHANGE_NODE_AUTH,omitempty"`
 	HZN_ORG_ID             string `json:"HZN_ORG_ID,omitempty"`
 
+	// the program that hzn runs to get the exchange user credentials, e.g. from the OS keyring, when they are not set
+	HZN_CREDENTIAL_HELPER string `json:"HZN_CREDENTIAL_HELPER,omitempty"`
+
 	// the locale that the hzn cli will run under, for example pt-BR, es, fr, de, it, ja, ko, zh-CN, zh-TW.
 	HZN_LANG string `json:"HZN_LANG,omitempty"`
 
@@ -42,10 +48,40 @@ type HorizonCliConfig struct {
 	// exchange url, the default is shipped with the horizon-cli package
 	HZN_EXCHANGE_URL string `json:"HZN_EXCHANGE_URL,omitempty"`
 
-	// http max retries and retry interval (in second) when transport error occurs.
+	// http max retries and retry interval (in second) when transport error occurs. The interval doubles with each retry.
 	HZN_HTTP_RETRIES        string `json:"HZN_HTTP_RETRIES,omitempty"`
 	HZN_HTTP_RETRY_INTERVAL string `json:"HZN_HTTP_RETRY_INTERVAL,omitempty"`
 
+	// the number of times that a request is retried when the exchange rate limits it (429), waiting for its Retry-After
+	HZN_HTTP_RATE_LIMIT_RETRIES string `json:"HZN_HTTP_RATE_LIMIT_RETRIES,omitempty"`
+
+	// http request timeout (in seconds)
+	HZN_HTTP_TIMEOUT string `json:"HZN_HTTP_TIMEOUT,omitempty"`
+
+	// the number of idle http connections that are kept open to be reused
+	HZN_HTTP_MAX_IDLE_CONNS string `json:"HZN_HTTP_MAX_IDLE_CONNS,omitempty"`
+
+	// trace the http requests to stderr or to a file
+	HZN_HTTP_TRACE string `json:"HZN_HTTP_TRACE,omitempty"`
+
+	// cache the exchange patterns, services and deployment policies by their lastUpdated time, true for the directory of the profile in ~/.hzn/cache or a directory
+	HZN_EXCHANGE_CACHE string `json:"HZN_EXCHANGE_CACHE,omitempty"`
+
+	// how the exchange credentials without a bearer: or apikey: prefix are sent, basic (the default), bearer or apikey
+	HZN_EXCHANGE_AUTH_SCHEME string `json:"HZN_EXCHANGE_AUTH_SCHEME,omitempty"`
+
+	// the IAM token service that the api keys are exchanged at for the tokens sent to the exchange
+	HZN_IAM_TOKEN_URL string `json:"HZN_IAM_TOKEN_URL,omitempty"`
+
+	// skip the confirmation prompts of the commands, true or false
+	HZN_NONINTERACTIVE string `json:"HZN_NONINTERACTIVE,omitempty"`
+
+	// the profile of ~/.hzn/config to use when --profile is not given, it also names the saved cookies, tokens and cache
+	HZN_PROFILE string `json:"HZN_PROFILE,omitempty"`
+
+	// the format of the output of the list and get commands: json (the default), yaml, table or go-template=<template>
+	HZN_OUTPUT_FORMAT string `json:"HZN_OUTPUT_FORMAT,omitempty"`
+
 	// the CSS url, the default is shipped with the horizon-cli package
 	HZN_FSS_CSSURL string `json:"HZN_FSS_CSSURL,omitempty"`
 
@@ -56,9 +92,6 @@ type HorizonCliConfig struct {
 	// if the env variables in a file will be substituted or not
 	//HZN_DONT_SUBST_ENV_VARS string `json:"HZN_DONT_SUBST_ENV_VARS,omitempty"`
 
-	// if the user auth or the node auth is an api key or not
-	//USING_API_KEY string `json:"USING_API_KEY,omitempty"`
-
 	// the following are only used by 'hzn dev' commands
 	HZN_DEVICE_ID           string `json:"HZN_DEVICE_ID,omitempty"`
 	HZN_PATTERN             string `json:"HZN_PATTERN,omitempty"`
@@ -400,17 +433,21 @@ func SetEnvVarsFromConfigFiles(project_dir string) error {
 	if configFile_pkg, err = filepath.Abs(configFile_pkg); err != nil {
 		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("Failed to get the absolute path for file %v. %v", configFile_pkg, err))
 	}
-	_, _, err = SetEnvVarsFromConfigFile(configFile_pkg, orig_env_vars, false)
+	hzn_vars, metadata_vars, err := SetEnvVarsFromConfigFile(configFile_pkg, orig_env_vars, false)
 	if err != nil {
 		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("Error reading environment variables from file %v. %v", configFile_pkg, err))
 	} else {
 		PACKAGE_CONFIG_FILE = filepath.Clean(configFile_pkg)
+		recordEnvVarSources(metadata_vars, orig_env_vars, PACKAGE_CONFIG_FILE)
+		recordEnvVarSources(hzn_vars, orig_env_vars, PACKAGE_CONFIG_FILE)
 	}
 
 	// check /etc/default/horizon file that ships with horizon package
-	_, err = SetEnvVarsFromNonJsonFile("/etc/default/horizon", orig_env_vars, false)
+	hzn_vars, err = SetEnvVarsFromNonJsonFile("/etc/default/horizon", orig_env_vars, false)
 	if err != nil {
 		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("Error reading environment variables from file /etc/default/horizon. %v", err))
+	} else {
+		recordEnvVarSources(hzn_vars, orig_env_vars, "/etc/default/horizon")
 	}
 
 	// check the user's configuration file  ~/.hzn/hzn.json
@@ -419,11 +456,13 @@ func SetEnvVarsFromConfigFiles(project_dir string) error {
 		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("Failed to get the absolute path for file ~/.hzn/hzn.json. %v", err))
 	}
 	if configFile_user != configFile_pkg {
-		_, _, err = SetEnvVarsFromConfigFile(configFile_user, orig_env_vars, false)
+		hzn_vars, metadata_vars, err = SetEnvVarsFromConfigFile(configFile_user, orig_env_vars, false)
 		if err != nil {
 			cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("Error reading environment variables from file %v. %v", configFile_user, err))
 		} else {
 			USER_CONFIG_FILE = filepath.Clean(configFile_user)
+			recordEnvVarSources(metadata_vars, orig_env_vars, USER_CONFIG_FILE)
+			recordEnvVarSources(hzn_vars, orig_env_vars, USER_CONFIG_FILE)
 		}
 	}
 
@@ -431,6 +470,26 @@ func SetEnvVarsFromConfigFiles(project_dir string) error {
 
 }
 
+// remember the config file the given env vars were set from. The ones that were in the original environment were not set.
+func recordEnvVarSources(vars map[string]string, orig_env_vars map[string]string, source string) {
+	for k := range vars {
+		if _, found := orig_env_vars[k]; !found {
+			envVarSources[k] = source
+		}
+	}
+}
+
+// GetEnvVarSource returns where the value of the env var came from: the config file it was set from by
+// SetEnvVarsFromConfigFiles, "environment" if it was set before hzn started, or an empty string if it is not set.
+func GetEnvVarSource(name string) string {
+	if _, ok := os.LookupEnv(name); !ok {
+		return ""
+	} else if source, ok := envVarSources[name]; ok {
+		return source
+	}
+	return "environment"
+}
+
 // set up the environment variables from the project config files.
 // the precedence order is: environmental variables, project config file
 func SetEnvVarsFromProjectConfigFile(project_dir string) error {
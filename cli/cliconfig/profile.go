@@ -0,0 +1,296 @@
+package cliconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/i18n"
+	yaml "gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The name of the file with the named profiles in ~/.hzn. It can be JSON or YAML.
+const PROFILES_FILE_NAME = "config"
+
+// The env var with the name of the profile to use, when --profile is not given. It also names the state of the
+// profile that hzn saves, the cookies, IAM tokens and exchange cache.
+const PROFILE_ENV = "HZN_PROFILE"
+
+// The settings of a profile, and the env vars that they set. Like the hzn.json files, a profile only sets the env vars
+// that were not set before hzn started, so the precedence is: flags, env vars, the profile, the hzn.json files.
+var profileSettings = []struct {
+	key    string
+	envVar string
+	secret bool // the value is read by hzn config set rather than given on the command line
+}{
+	{"exchangeUrl", "HZN_EXCHANGE_URL", false},
+	{"org", "HZN_ORG_ID", false},
+	{"credentialHelper", cliutils.CREDENTIAL_HELPER_ENV, false},
+	{"userAuth", "HZN_EXCHANGE_USER_AUTH", true},
+	{"nodeAuth", "HZN_EXCHANGE_NODE_AUTH", true},
+	{"arch", "ARCH", false},
+	{"horizonUrl", "HORIZON_URL", false},
+	{"cssUrl", "HZN_FSS_CSSURL", false},
+}
+
+// ProfilesConfig is the content of ~/.hzn/config.
+type ProfilesConfig struct {
+	CurrentProfile string                       `json:"currentProfile,omitempty" yaml:"currentProfile,omitempty"`
+	Profiles       map[string]map[string]string `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+}
+
+// The profile that was applied by UseProfile, and where its name came from.
+var activeProfile, activeProfileSource string
+
+// GetProfilesFile returns the path of the file with the named profiles.
+func GetProfilesFile() string {
+	return filepath.Join(os.Getenv("HOME"), ".hzn", PROFILES_FILE_NAME)
+}
+
+// GetActiveProfile returns the name of the profile that was applied, and whether it came from --profile, HZN_PROFILE
+// or the current profile of the file. The name is empty if no profile was applied.
+func GetActiveProfile() (string, string) {
+	return activeProfile, activeProfileSource
+}
+
+// Read the profiles file. A file that does not exist has no profiles. The file is YAML unless it starts with {.
+func readProfiles(file string) (*ProfilesConfig, bool, error) {
+	config := &ProfilesConfig{Profiles: map[string]map[string]string{}}
+	content, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return config, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	isYaml := !bytes.HasPrefix(bytes.TrimSpace(content), []byte("{"))
+	if isYaml {
+		err = yaml.Unmarshal(content, config)
+	} else {
+		err = json.Unmarshal(content, config)
+	}
+	if err != nil {
+		return nil, isYaml, fmt.Errorf(i18n.GetMessagePrinter().Sprintf("Unable to decode content of config file %v. %v", file, err))
+	}
+	if config.Profiles == nil {
+		config.Profiles = map[string]map[string]string{}
+	}
+	for name, profile := range config.Profiles {
+		for key := range profile {
+			if profileEnvVar(key) == "" {
+				return nil, isYaml, fmt.Errorf(i18n.GetMessagePrinter().Sprintf("The profile %v in config file %v has the unknown setting %v, the settings are: %v", name, file, key, strings.Join(profileKeys(), ", ")))
+			}
+		}
+	}
+	return config, isYaml, nil
+}
+
+// Write the profiles file, in the format it was read in. The file can have credentials, so only the user can read it.
+func writeProfiles(file string, config *ProfilesConfig, isYaml bool) error {
+	var content []byte
+	var err error
+	if isYaml {
+		content, err = yaml.Marshal(config)
+	} else if content, err = json.MarshalIndent(config, "", cliutils.JSON_INDENT); err == nil {
+		content = append(content, '\n')
+	}
+	if err != nil {
+		return err
+	} else if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, content, 0600)
+}
+
+// Returns the env var of a profile setting, or the empty string if it is not one.
+func profileEnvVar(key string) string {
+	for _, setting := range profileSettings {
+		if strings.EqualFold(setting.key, key) {
+			return setting.envVar
+		}
+	}
+	return ""
+}
+
+func profileKeys() []string {
+	keys := make([]string, 0, len(profileSettings))
+	for _, setting := range profileSettings {
+		keys = append(keys, setting.key)
+	}
+	return keys
+}
+
+// UseProfile applies the settings of the profile from the --profile flag, HZN_PROFILE or the current profile of
+// ~/.hzn/config, in that order, by setting their env vars. It must be called after the flags are parsed and before the
+// env vars are used. A profile given with --profile must exist, unless it is being created with 'hzn config set'.
+func UseProfile(flagProfile string, mustExist bool) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	file := GetProfilesFile()
+	config, _, err := readProfiles(file)
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, err.Error())
+	}
+
+	name, source := flagProfile, "--profile"
+	if name == "" {
+		if name, source = os.Getenv(PROFILE_ENV), PROFILE_ENV; name == "" {
+			name, source = config.CurrentProfile, file
+		}
+	}
+	if name == "" {
+		return
+	}
+
+	// the name of the profile also names its saved state
+	if err := os.Setenv(PROFILE_ENV, name); err != nil {
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("unable to set env var '%v', error %v", PROFILE_ENV, err))
+	}
+
+	profile, ok := config.Profiles[name]
+	if !ok {
+		// HZN_PROFILE can also just name the saved state
+		if source == "--profile" && mustExist {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("profile %v not found in %v", name, file))
+		}
+		return
+	}
+	activeProfile, activeProfileSource = name, source
+
+	profileSource := msgPrinter.Sprintf("profile %v in %v", name, file)
+	for key, value := range profile {
+		envVar := profileEnvVar(key)
+		if GetEnvVarSource(envVar) == "environment" || value == "" {
+			continue
+		}
+		if err := os.Setenv(envVar, value); err != nil {
+			cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("unable to set env var '%v', error %v", envVar, err))
+		}
+		envVarSources[envVar] = profileSource
+	}
+	cliutils.Verbose(msgPrinter.Sprintf("Using the profile %v from %v", name, source))
+}
+
+// ConfigSet sets a setting of a profile in ~/.hzn/config, or removes it when the value is empty. The profile is the one
+// given with --profile, or the current profile. The profile is created if it does not exist, and becomes the current
+// profile if there is none. The credentials are not given on the command line, a value of - reads them with
+// cliutils.ReadSecret.
+func ConfigSet(profileName string, key string, value string) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	file := GetProfilesFile()
+	config, isYaml, err := readProfiles(file)
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, err.Error())
+	}
+
+	if profileEnvVar(key) == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("unknown setting %v, the settings are: %v", key, strings.Join(profileKeys(), ", ")))
+	}
+	for _, setting := range profileSettings {
+		if strings.EqualFold(setting.key, key) {
+			key = setting.key
+			if setting.secret && value == "-" {
+				value = cliutils.ReadSecret(key, value)
+			} else if setting.secret && value != "" {
+				cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the %v must not be given on the command line, where other users can see it. Use - to be prompted for it, or to read it from stdin.", key))
+			}
+		}
+	}
+
+	if profileName == "" {
+		if profileName = config.CurrentProfile; profileName == "" {
+			profileName = cliutils.DEFAULT_PROFILE
+		}
+	}
+	if config.CurrentProfile == "" {
+		config.CurrentProfile = profileName
+	}
+
+	profile, ok := config.Profiles[profileName]
+	if !ok {
+		profile = map[string]string{}
+		config.Profiles[profileName] = profile
+	}
+	if value == "" {
+		delete(profile, key)
+	} else {
+		profile[key] = value
+	}
+
+	if cliutils.IsDryRun() {
+		return
+	} else if err := writeProfiles(file, config, isYaml); err != nil {
+		cliutils.Fatal(cliutils.FILE_IO_ERROR, msgPrinter.Sprintf("unable to write %v: %v", file, err))
+	}
+	if value == "" {
+		msgPrinter.Printf("Removed %v from the profile %v.", key, profileName)
+	} else {
+		msgPrinter.Printf("Set %v of the profile %v.", key, profileName)
+	}
+	msgPrinter.Println()
+}
+
+// ConfigUse makes a profile of ~/.hzn/config the current profile, which is used when --profile and HZN_PROFILE are not
+// given.
+func ConfigUse(profileName string) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	file := GetProfilesFile()
+	config, isYaml, err := readProfiles(file)
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, err.Error())
+	}
+	if _, ok := config.Profiles[profileName]; !ok {
+		names := make([]string, 0, len(config.Profiles))
+		for name := range config.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("profile %v not found in %v, the profiles are: %v", profileName, file, strings.Join(names, ", ")))
+	}
+
+	config.CurrentProfile = profileName
+	if cliutils.IsDryRun() {
+		return
+	} else if err := writeProfiles(file, config, isYaml); err != nil {
+		cliutils.Fatal(cliutils.FILE_IO_ERROR, msgPrinter.Sprintf("unable to write %v: %v", file, err))
+	}
+	msgPrinter.Printf("Using the profile %v.", profileName)
+	msgPrinter.Println()
+}
+
+// ConfigView displays the profiles of ~/.hzn/config, or only the one given with --profile, with the passwords and
+// tokens of the credentials masked.
+func ConfigView(profileName string) {
+	file := GetProfilesFile()
+	config, _, err := readProfiles(file)
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, err.Error())
+	}
+
+	for name, profile := range config.Profiles {
+		if profileName != "" && name != profileName {
+			delete(config.Profiles, name)
+			continue
+		}
+		for _, key := range []string{"userAuth", "nodeAuth"} {
+			if creds, ok := profile[key]; ok {
+				if id, token := cliutils.SplitIdToken(creds); token != "" {
+					profile[key] = id + ":********"
+				} else {
+					profile[key] = "********"
+				}
+			}
+		}
+	}
+	if profileName != "" && len(config.Profiles) == 0 {
+		cliutils.Fatal(cliutils.NOT_FOUND, i18n.GetMessagePrinter().Sprintf("profile %v not found in %v", profileName, file))
+	}
+	cliutils.Output(config, "hzn config view")
+}
@@ -0,0 +1,80 @@
+// +build unit
+
+package cliconfig
+
+import (
+	"fmt"
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_Profiles(t *testing.T) {
+	h := clitest.New(t)
+	dir, err := ioutil.TempDir("", "hzn-profiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	h.Setenv("HOME", dir)
+	for _, name := range []string{"HZN_ORG_ID", "HZN_EXCHANGE_URL", "HZN_PROFILE"} {
+		h.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	h.Setenv("ARCH", "amd64")
+
+	// the first profile that is set becomes the current profile
+	for _, setting := range [][]string{{"prod", "org", "prodorg"}, {"prod", "exchangeUrl", "http://other.example.com"}, {"", "ARCH", "arm64"}} {
+		if res := h.Run(func() { ConfigSet(setting[0], setting[1], setting[2]) }); res.ExitCode != 0 {
+			t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+		}
+	}
+
+	// the credentials are read from stdin, not from the command line
+	if res := h.Run(func() { ConfigSet("", "userAuth", "me:secret") }); res.ExitCode != cliutils.CLI_INPUT_ERROR {
+		t.Errorf("expected exit code %v for credentials on the command line, found %v", cliutils.CLI_INPUT_ERROR, res.ExitCode)
+	}
+	if res := h.RunWithInput("me:secret\n", func() { ConfigSet("", "userAuth", "-") }); res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	if res := h.Run(func() { ConfigSet("", "color", "blue") }); res.ExitCode != cliutils.CLI_INPUT_ERROR {
+		t.Errorf("expected exit code %v for an unknown setting, found %v", cliutils.CLI_INPUT_ERROR, res.ExitCode)
+	}
+	if res := h.Run(func() { ConfigView("") }); res.ExitCode != 0 || !strings.Contains(res.Stdout, `"currentProfile": "prod"`) || !strings.Contains(res.Stdout, `"arch": "arm64"`) || strings.Contains(res.Stdout, "secret") {
+		t.Errorf("expected the prod profile with the password masked, found %v, stderr: %v", res.Stdout, res.Stderr)
+	}
+
+	// the profile sets what the environment does not, the environment keeps ARCH
+	if res := h.Run(func() { UseProfile("", true) }); res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	if org := os.Getenv("HZN_ORG_ID"); org != "prodorg" || !strings.Contains(GetEnvVarSource("HZN_ORG_ID"), "profile prod") {
+		t.Errorf("expected the org of the profile, found %v from %v", org, GetEnvVarSource("HZN_ORG_ID"))
+	} else if arch := os.Getenv("ARCH"); arch != "amd64" {
+		t.Errorf("the env var should take precedence over the profile, found %v", arch)
+	} else if name, _ := GetActiveProfile(); name != "prod" || os.Getenv("HZN_PROFILE") != "prod" {
+		t.Errorf("expected the prod profile, found %v and HZN_PROFILE %v", name, os.Getenv("HZN_PROFILE"))
+	}
+
+	// the flag takes precedence over the profile
+	exchUrl := "http://flag.example.com"
+	res := h.Run(func() {
+		cliutils.Opts.ExchangeUrl = &exchUrl
+		fmt.Print(cliutils.GetExchangeUrl())
+	})
+	if res.Stdout != exchUrl {
+		t.Errorf("expected the exchange url of the flag, found %v", res.Stdout)
+	} else if res := h.Run(func() { fmt.Print(cliutils.GetExchangeUrl()) }); res.Stdout != "http://other.example.com" {
+		t.Errorf("expected the exchange url of the profile, found %v", res.Stdout)
+	}
+
+	if res := h.Run(func() { UseProfile("test", true) }); res.ExitCode != cliutils.CLI_INPUT_ERROR {
+		t.Errorf("expected exit code %v for an unknown profile, found %v", cliutils.CLI_INPUT_ERROR, res.ExitCode)
+	}
+	if res := h.Run(func() { ConfigUse("test") }); res.ExitCode != cliutils.NOT_FOUND {
+		t.Errorf("expected exit code %v for an unknown profile, found %v", cliutils.NOT_FOUND, res.ExitCode)
+	}
+}
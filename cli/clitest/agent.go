@@ -0,0 +1,58 @@
+package clitest
+
+import (
+	"github.com/open-horizon/anax/apicommon"
+	"github.com/open-horizon/anax/version"
+	"net/http"
+	"testing"
+)
+
+// NewFakeAgent starts a fake Horizon agent API that is closed when the test ends. It is an unregistered node with no
+// agreements, talking to the exchange at exchangeUrl. Change its responses with Handle, or register it with
+// SetNode.
+func NewFakeAgent(t testing.TB, exchangeUrl string) *FakeServer {
+	a := NewFakeServer(t)
+	a.Handle(http.MethodGet, "status", http.StatusOK, fakeStatus(exchangeUrl))
+	a.SetNode("", "", "", "unconfigured")
+	a.Handle(http.MethodGet, "agreement", http.StatusOK, map[string]interface{}{"agreements": map[string]interface{}{"active": []interface{}{}, "archived": []interface{}{}}})
+	a.Handle(http.MethodGet, "service", http.StatusOK, map[string]interface{}{"instances": map[string]interface{}{"active": []interface{}{}, "archived": []interface{}{}}, "definitions": map[string]interface{}{"active": []interface{}{}, "archived": []interface{}{}}})
+	a.Handle(http.MethodGet, "eventlog", http.StatusOK, []interface{}{})
+	return a
+}
+
+// NewFakeAgbot starts a fake agbot API that is closed when the test ends. It serves no orgs and has no agreements.
+func NewFakeAgbot(t testing.TB, exchangeUrl string) *FakeServer {
+	a := NewFakeServer(t)
+	a.Handle(http.MethodGet, "status", http.StatusOK, fakeStatus(exchangeUrl))
+	a.Handle(http.MethodGet, "node", http.StatusOK, map[string]interface{}{"agbot_id": "myorg/agbot", "organization": "myorg"})
+	a.Handle(http.MethodGet, "agreement", http.StatusOK, map[string]interface{}{"agreements": map[string]interface{}{"active": []interface{}{}, "archived": []interface{}{}}})
+	a.Handle(http.MethodGet, "policy", http.StatusOK, map[string]interface{}{})
+	a.Handle(http.MethodGet, "agentupgrade", http.StatusOK, map[string]interface{}{})
+	a.Handle(http.MethodGet, "cache/servedorg", http.StatusOK, map[string]interface{}{"servedPatterns": map[string]interface{}{}, "servedPolicies": map[string]interface{}{}})
+	return a
+}
+
+// SetNode sets the node that the GET /node API of a fake agent returns.
+func (f *FakeServer) SetNode(org string, id string, pattern string, configState string) {
+	f.Handle(http.MethodGet, "node", http.StatusOK, map[string]interface{}{
+		"id":           id,
+		"organization": org,
+		"pattern":      pattern,
+		"configstate":  map[string]interface{}{"state": configState, "last_update_time": 0},
+	})
+}
+
+func fakeStatus(exchangeUrl string) apicommon.Info {
+	return apicommon.Info{
+		Configuration: &apicommon.Configuration{
+			ExchangeAPI:     exchangeUrl + "/",
+			ExchangeVersion: FAKE_EXCHANGE_VERSION,
+			MinExchVersion:  version.MINIMUM_EXCHANGE_VERSION,
+			PrefExchVersion: version.PREFERRED_EXCHANGE_VERSION,
+			Arch:            "amd64",
+			HorizonVersion:  version.HORIZON_VERSION,
+		},
+		Connectivity: map[string]bool{},
+		LiveHealth:   &apicommon.HealthTimestamps{},
+	}
+}
@@ -0,0 +1,206 @@
+// +build unit
+
+package clitest
+
+import (
+	"encoding/json"
+	"github.com/open-horizon/anax/cli/agreementbot"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/cli/exchange"
+	"github.com/open-horizon/anax/cli/node"
+	exchangeapi "github.com/open-horizon/anax/exchange"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Harness_agent(t *testing.T) {
+	h := New(t)
+	h.Agent.SetNode(FAKE_ORG, "mynode", "netspeed", "configured")
+
+	res := h.Run(func() { node.List() })
+	if res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+
+	var out node.NodeAndStatus
+	res.JSON(t, &out)
+	if out.Id == nil || *out.Id != "mynode" || out.Pattern == nil || *out.Pattern != "netspeed" || out.Configuration == nil || out.Configuration.ExchangeAPI != h.Exchange.URL+"/" {
+		t.Errorf("wrong node output: %v", res.Stdout)
+	}
+
+	// an agent error ends the command
+	h.Agent.FailNext(http.MethodGet, "node", 1, http.StatusInternalServerError, "the database is down")
+	if res := h.Run(func() { node.List() }); res.ExitCode != cliutils.HTTP_ERROR {
+		t.Errorf("expected exit code %v, found %v, stderr: %v", cliutils.HTTP_ERROR, res.ExitCode, res.Stderr)
+	} else if !strings.Contains(res.Stderr, "the database is down") {
+		t.Errorf("the error should be reported, found %v", res.Stderr)
+	} else if res := h.Run(func() { node.List() }); res.ExitCode != 0 {
+		t.Errorf("only the next request should fail, stderr: %v", res.Stderr)
+	}
+}
+
+func Test_Harness_exchange(t *testing.T) {
+	h := New(t)
+
+	dir, err := ioutil.TempDir("", "clitest-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	nmpFile := filepath.Join(dir, "nmp.json")
+	nmp := `{"patterns": ["netspeed"], "enabled": true, "start": "now", "job": {"type": "agentUpgrade", "config": {"version": "2.30.0"}}}`
+	if err := ioutil.WriteFile(nmpFile, []byte(nmp), 0644); err != nil {
+		t.Fatalf("unable to write %v: %v", nmpFile, err)
+	}
+
+	if res := h.Run(func() { exchange.NMPAdd(FAKE_ORG, FAKE_USER_AUTH, "upgrade", nmpFile, false) }); res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if !strings.Contains(res.Stdout, "added") {
+		t.Errorf("the policy should be added, found %v", res.Stdout)
+	}
+
+	var stored exchangeapi.ExchangeNodeManagementPolicy
+	if !h.Exchange.GetResource("orgs/myorg/managementpolicies/upgrade", &stored) {
+		t.Fatalf("the policy should be stored, found %v", h.Exchange.Paths())
+	} else if stored.Job.Type != exchangeapi.NMP_JOB_AGENT_UPGRADE {
+		t.Errorf("wrong policy stored: %v", stored)
+	}
+
+	// adding it again replaces it
+	if res := h.Run(func() { exchange.NMPAdd(FAKE_ORG, FAKE_USER_AUTH, "upgrade", nmpFile, false) }); !strings.Contains(res.Stdout, "updated") {
+		t.Errorf("the policy should be updated, found %v, stderr: %v", res.Stdout, res.Stderr)
+	}
+
+	var list map[string]exchangeapi.ExchangeNodeManagementPolicy
+	h.Run(func() { exchange.NMPList(FAKE_ORG, FAKE_USER_AUTH, "", false) }).JSON(t, &list)
+	if _, ok := list["myorg/upgrade"]; !ok || len(list) != 1 {
+		t.Errorf("wrong policy list: %v", list)
+	}
+
+	if res := h.Run(func() { exchange.NMPRemove(FAKE_ORG, FAKE_USER_AUTH, "upgrade", true) }); res.ExitCode != 0 {
+		t.Errorf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if res := h.Run(func() { exchange.NMPList(FAKE_ORG, FAKE_USER_AUTH, "upgrade", false) }); res.ExitCode != cliutils.NOT_FOUND {
+		t.Errorf("the policy should be removed, exit code %v", res.ExitCode)
+	}
+
+	// the confirmation is read from stdin
+	h.Exchange.AddResource("orgs/myorg/managementpolicies/upgrade", stored)
+	if res := h.RunWithInput("n\n", func() { exchange.NMPRemove(FAKE_ORG, FAKE_USER_AUTH, "upgrade", false) }); res.ExitCode != 0 {
+		t.Errorf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if !h.Exchange.GetResource("orgs/myorg/managementpolicies/upgrade", &stored) {
+		t.Errorf("the policy should not be removed without a confirmation")
+	}
+
+	if reqs := h.Exchange.RequestsTo(http.MethodPost, "orgs/myorg/managementpolicies/upgrade"); len(reqs) != 2 {
+		t.Errorf("expected 2 POSTs, found %v", reqs)
+	} else if reqs[0].Header.Get("Authorization") == "" {
+		t.Errorf("the request should have credentials")
+	}
+}
+
+func Test_Harness_agbot(t *testing.T) {
+	h := New(t)
+
+	progress := map[string]interface{}{"nmpName": "myorg/upgrade", "version": "2.30.0", "nodes": 3, "currentWave": 0}
+	h.Agbot.Handle(http.MethodGet, "agentupgrade/myorg/upgrade", http.StatusOK, progress)
+
+	res := h.Run(func() { agreementbot.AgentUpgradeList("", "myorg/upgrade", false) })
+	if res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if !strings.Contains(res.Stdout, `"nodes": 3`) {
+		t.Errorf("wrong output: %v", res.Stdout)
+	}
+
+	if res := h.Run(func() { agreementbot.AgentUpgradeList("myorg", "other", false) }); res.ExitCode != cliutils.NOT_FOUND {
+		t.Errorf("expected exit code %v, found %v", cliutils.NOT_FOUND, res.ExitCode)
+	}
+
+	// the agbot commands do not change the agent url of the next command
+	if res := h.Run(func() { node.List() }); res.ExitCode != 0 || len(h.Agent.RequestsTo(http.MethodGet, "node")) != 1 {
+		t.Errorf("the agent should be called, stderr: %v", res.Stderr)
+	}
+}
+
+func Test_FakeServer_behaviors(t *testing.T) {
+	f := NewFakeServer(t)
+	f.HandleSequence(http.MethodGet, "orgs/{org}/items",
+		Response{Code: http.StatusOK, Body: map[string]int{"page": 1}},
+		Response{Code: http.StatusOK, Body: map[string]int{"page": 2}})
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(f.URL + "/" + path)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, strings.TrimSpace(string(body))
+	}
+
+	if code, body := get("orgs/myorg/items"); code != 200 || body != `{"page":1}` {
+		t.Errorf("wrong first response %v %v", code, body)
+	} else if code, body := get("orgs/other/items"); code != 200 || body != `{"page":2}` {
+		t.Errorf("wrong second response %v %v", code, body)
+	} else if code, body := get("orgs/myorg/items"); code != 200 || body != `{"page":2}` {
+		t.Errorf("the last response should be repeated, found %v %v", code, body)
+	} else if code, _ := get("orgs/myorg/items/x"); code != 404 {
+		t.Errorf("a request without a route should get a 404, found %v", code)
+	}
+
+	f.SetLatency(50 * time.Millisecond)
+	start := time.Now()
+	get("orgs/myorg/items")
+	if time.Since(start) < 50*time.Millisecond {
+		t.Errorf("the response should be delayed")
+	}
+
+	if reqs := f.RequestsTo(http.MethodGet, "orgs/myorg/items"); len(reqs) != 3 {
+		t.Errorf("expected 3 requests, found %v", reqs)
+	}
+}
+
+func Test_FakeExchange_paging(t *testing.T) {
+	e := NewFakeExchange(t)
+	for _, id := range []string{"n1", "n2", "n3"} {
+		e.AddResource("orgs/myorg/nodes/"+id, map[string]string{"name": id})
+		e.AddResource("orgs/myorg/nodes/"+id+"/policy", map[string]string{})
+	}
+	e.SetPageSize(2)
+
+	var page exchangeapi.GetDevicesResponse
+	for _, tc := range []struct {
+		offset    string
+		nodes     int
+		lastIndex int
+	}{{"", 2, 2}, {"?offset=2", 1, 0}} {
+		resp, err := http.Get(e.URL + "/orgs/myorg/nodes" + tc.offset)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		page = exchangeapi.GetDevicesResponse{}
+		decodeBody(t, resp, &page)
+		if len(page.Devices) != tc.nodes || page.LastIndex != tc.lastIndex {
+			t.Errorf("wrong page at offset %v: %v", tc.offset, page)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, e.URL+"/orgs/myorg/nodes/n1", nil)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Errorf("the node should be removed: %v %v", resp, err)
+	} else if len(e.Paths()) != 4 {
+		t.Errorf("the node policy should be removed with the node, found %v", e.Paths())
+	}
+}
+
+func decodeBody(t *testing.T, resp *http.Response, v interface{}) {
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("unable to decode the response: %v", err)
+	}
+}
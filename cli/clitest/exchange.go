@@ -0,0 +1,213 @@
+package clitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// The exchange version that the fake exchange reports.
+const FAKE_EXCHANGE_VERSION = "2.100.0"
+
+// The resource collections of the fake exchange, relative to orgs/{org}, and the key of the map of the resources in
+// the responses.
+var exchangeCollections = map[string]string{
+	"nodes":              "nodes",
+	"agbots":             "agbots",
+	"users":              "users",
+	"services":           "services",
+	"patterns":           "patterns",
+	"business/policies":  "businessPolicy",
+	"managementpolicies": "managementPolicy",
+}
+
+// FakeExchange is a fake exchange that keeps the resources PUT and POSTed to it in memory. The resources in the
+// collections of an org are returned the way the exchange returns them, in a map keyed by org/id, while any other
+// resource, such as the policy of a node, is returned as it was stored. Routes set with Handle take precedence over
+// the stored resources.
+type FakeExchange struct {
+	*FakeServer
+	storeLock sync.Mutex
+	resources map[string]json.RawMessage
+	pageSize  int
+}
+
+// NewFakeExchange starts a fake exchange that is closed when the test ends.
+func NewFakeExchange(t testing.TB) *FakeExchange {
+	e := &FakeExchange{FakeServer: NewFakeServer(t), resources: map[string]json.RawMessage{}}
+	e.fallback = e.serveResource
+	e.Handle(http.MethodGet, "admin/version", http.StatusOK, FAKE_EXCHANGE_VERSION)
+	return e
+}
+
+// AddResource stores a resource at the path, e.g. orgs/myorg/nodes/mynode.
+func (e *FakeExchange) AddResource(path string, resource interface{}) {
+	if b, err := json.Marshal(resource); err != nil {
+		panic(fmt.Sprintf("unable to marshal the fake resource %v: %v", path, err))
+	} else {
+		e.storeLock.Lock()
+		defer e.storeLock.Unlock()
+		e.resources[strings.Trim(path, "/")] = b
+	}
+}
+
+// GetResource unmarshals the resource stored at the path into resource, and returns false if there is none.
+func (e *FakeExchange) GetResource(path string, resource interface{}) bool {
+	e.storeLock.Lock()
+	defer e.storeLock.Unlock()
+	if b, ok := e.resources[strings.Trim(path, "/")]; !ok {
+		return false
+	} else if err := json.Unmarshal(b, resource); err != nil {
+		panic(fmt.Sprintf("unable to unmarshal the fake resource %v: %v", path, err))
+	}
+	return true
+}
+
+// SetPageSize splits the resources of a collection into pages of size resources, sorted by id. The page that starts
+// at the index in the offset query parameter is returned, and lastIndex is the index of the next page, or 0 when it is
+// the last page. A size of 0 returns the whole collection.
+func (e *FakeExchange) SetPageSize(size int) {
+	e.storeLock.Lock()
+	defer e.storeLock.Unlock()
+	e.pageSize = size
+}
+
+// Returns the org, the collection and the id of a path in a collection. The id is empty for the collection itself.
+func parseCollectionPath(path string) (string, string, string, bool) {
+	segments := splitPath(path)
+	if len(segments) < 3 || segments[0] != "orgs" {
+		return "", "", "", false
+	}
+	rest := strings.Join(segments[2:], "/")
+	for coll := range exchangeCollections {
+		if rest == coll {
+			return segments[1], coll, "", true
+		} else if strings.HasPrefix(rest, coll+"/") && !strings.Contains(strings.TrimPrefix(rest, coll+"/"), "/") {
+			return segments[1], coll, strings.TrimPrefix(rest, coll+"/"), true
+		}
+	}
+	return "", "", "", false
+}
+
+func (e *FakeExchange) serveResource(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	body, _ := ioutil.ReadAll(r.Body)
+	org, coll, id, inColl := parseCollectionPath(path)
+
+	e.storeLock.Lock()
+	defer e.storeLock.Unlock()
+
+	stored, exists := e.resources[path]
+	switch r.Method {
+	case http.MethodGet:
+		if inColl {
+			items := map[string]json.RawMessage{}
+			for p, res := range e.resources {
+				if o, c, i, ok := parseCollectionPath(p); ok && o == org && c == coll && i != "" && (id == "" || id == i) {
+					items[org+"/"+i] = res
+				}
+			}
+			items, lastIndex := e.page(items, r.URL.Query().Get("offset"))
+			code := http.StatusOK
+			if len(items) == 0 {
+				code = http.StatusNotFound
+			}
+			WriteResponse(w, Response{Code: code, Body: map[string]interface{}{exchangeCollections[coll]: items, "lastIndex": lastIndex}})
+		} else if exists {
+			WriteResponse(w, Response{Code: http.StatusOK, Body: []byte(stored)})
+		} else {
+			WriteResponse(w, exchangeMsg(http.StatusNotFound, "not found"))
+		}
+
+	case http.MethodPost, http.MethodPut:
+		if !json.Valid(body) {
+			WriteResponse(w, exchangeMsg(http.StatusBadRequest, "invalid JSON input"))
+		} else if r.Method == http.MethodPost && exists {
+			WriteResponse(w, exchangeMsg(http.StatusForbidden, "already exists"))
+		} else if r.Method == http.MethodPut && inColl && id != "" && !exists && coll != "nodes" && coll != "agbots" {
+			// only nodes and agbots are created with a PUT
+			WriteResponse(w, exchangeMsg(http.StatusNotFound, "not found"))
+		} else {
+			e.resources[path] = body
+			WriteResponse(w, exchangeMsg(http.StatusCreated, "created/updated"))
+		}
+
+	case http.MethodPatch:
+		var current, patch map[string]json.RawMessage
+		if !exists {
+			WriteResponse(w, exchangeMsg(http.StatusNotFound, "not found"))
+		} else if err := json.Unmarshal(body, &patch); err != nil {
+			WriteResponse(w, exchangeMsg(http.StatusBadRequest, "invalid JSON input"))
+		} else if err := json.Unmarshal(stored, &current); err != nil {
+			WriteResponse(w, exchangeMsg(http.StatusBadRequest, "the stored resource is not an object"))
+		} else {
+			for k, v := range patch {
+				current[k] = v
+			}
+			e.resources[path], _ = json.Marshal(current)
+			WriteResponse(w, exchangeMsg(http.StatusCreated, "updated"))
+		}
+
+	case http.MethodDelete:
+		if !exists {
+			WriteResponse(w, exchangeMsg(http.StatusNotFound, "not found"))
+		} else {
+			// the resources under the path, such as the policy of a node, are removed with it
+			for p := range e.resources {
+				if p == path || strings.HasPrefix(p, path+"/") {
+					delete(e.resources, p)
+				}
+			}
+			WriteResponse(w, Response{Code: http.StatusNoContent})
+		}
+
+	default:
+		WriteResponse(w, exchangeMsg(http.StatusMethodNotAllowed, "method not allowed"))
+	}
+}
+
+// Returns the page of the items that starts at the offset, and the offset of the next page.
+func (e *FakeExchange) page(items map[string]json.RawMessage, offset string) (map[string]json.RawMessage, int) {
+	if e.pageSize <= 0 {
+		return items, 0
+	}
+
+	ids := make([]string, 0, len(items))
+	for id := range items {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	start, _ := strconv.Atoi(offset)
+	page := map[string]json.RawMessage{}
+	for ix := start; ix >= 0 && ix < len(ids) && ix < start+e.pageSize; ix++ {
+		page[ids[ix]] = items[ids[ix]]
+	}
+	if start+e.pageSize < len(ids) {
+		return page, start + e.pageSize
+	}
+	return page, 0
+}
+
+// Paths returns the paths of the stored resources, sorted.
+func (e *FakeExchange) Paths() []string {
+	e.storeLock.Lock()
+	defer e.storeLock.Unlock()
+	paths := make([]string, 0, len(e.resources))
+	for p := range e.resources {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// The body of the exchange responses that have no resource.
+func exchangeMsg(code int, msg string) Response {
+	return Response{Code: code, Body: map[string]string{"code": http.StatusText(code), "msg": msg}}
+}
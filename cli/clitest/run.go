@@ -0,0 +1,157 @@
+package clitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"io"
+	"os"
+	"sync"
+	"testing"
+)
+
+// The org and exchange user credentials that the commands are run with.
+const (
+	FAKE_ORG       = "myorg"
+	FAKE_USER_AUTH = "myuser:mypw"
+)
+
+// Harness is a fake Horizon agent, agbot and exchange for the hzn commands to talk to. The HORIZON_URL, HZN_AGBOT_API,
+// HZN_EXCHANGE_URL, HZN_ORG_ID and HZN_EXCHANGE_USER_AUTH env vars point the commands at them, and they are restored
+// when the test ends. The commands use global state, so tests that use a harness must not run in parallel.
+type Harness struct {
+	t        testing.TB
+	Agent    *FakeServer
+	Agbot    *FakeServer
+	Exchange *FakeExchange
+}
+
+// Result is the outcome of a command. The exit code is the one passed to cliutils.Fatal, or 0 when the command
+// returned.
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// Unmarshal the JSON output of the command into v.
+func (r Result) JSON(t testing.TB, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(r.Stdout), v); err != nil {
+		t.Fatalf("the output is not JSON: %v, output: %v", err, r.Stdout)
+	}
+}
+
+// New starts the fakes that the hzn commands of the test talk to.
+func New(t testing.TB) *Harness {
+	h := &Harness{t: t}
+	h.Exchange = NewFakeExchange(t)
+	h.Agent = NewFakeAgent(t, h.Exchange.URL)
+	h.Agbot = NewFakeAgbot(t, h.Exchange.URL)
+
+	h.Setenv("HZN_AGBOT_API", h.Agbot.URL)
+	h.Setenv("HZN_EXCHANGE_URL", h.Exchange.URL)
+	h.Setenv("HZN_ORG_ID", FAKE_ORG)
+	h.Setenv("HZN_EXCHANGE_USER_AUTH", FAKE_USER_AUTH)
+	h.Setenv("HORIZON_URL", h.Agent.URL)
+
+	opts := cliutils.Opts
+	t.Cleanup(func() {
+		cliutils.Opts = opts
+		cliutils.ResetExchangeUrl()
+		cliutils.CloseIdleConnections()
+	})
+	return h
+}
+
+// Setenv sets an env var for the test, restoring it when the test ends.
+func (h *Harness) Setenv(name string, value string) {
+	prev, wasSet := os.LookupEnv(name)
+	os.Setenv(name, value)
+	h.t.Cleanup(func() {
+		if wasSet {
+			os.Setenv(name, prev)
+		} else {
+			os.Unsetenv(name)
+		}
+	})
+}
+
+// Run runs a command function, e.g. func() { node.List() }, and returns its output and exit code.
+func (h *Harness) Run(command func()) Result {
+	return h.RunWithInput("", command)
+}
+
+// The panic that ends a command when it calls cliutils.Fatal.
+type exitPanic struct {
+	code int
+}
+
+// RunWithInput runs a command function with input as its stdin, for commands that ask for a confirmation. A command
+// that calls cliutils.Fatal from a goroutine it started can not be run in-process.
+func (h *Harness) RunWithInput(input string, command func()) (result Result) {
+	h.t.Helper()
+
+	// every command starts from the same global state, in particular UseAgbotUrlBase points HORIZON_URL at the agbot
+	verbose, dryRun, insecure := false, false, false
+	exchUrl, horizonUrl, userAuth, nodeAuth := "", "", "", ""
+	cliutils.Opts = cliutils.GlobalOptions{Verbose: &verbose, IsDryRun: &dryRun, ExchangeUrl: &exchUrl, HorizonUrl: &horizonUrl,
+		UserAuth: &userAuth, NodeAuth: &nodeAuth, Insecure: &insecure}
+	cliutils.ResetExchangeUrl()
+	cliutils.ResetHelperCredentials()
+	os.Setenv("HORIZON_URL", h.Agent.URL)
+
+	stdin, stdout, stderr := os.Stdin, os.Stdout, os.Stderr
+	inR, inW := h.pipe()
+	outR, outW := h.pipe()
+	errR, errW := h.pipe()
+	os.Stdin, os.Stdout, os.Stderr = inR, outW, errW
+
+	go func() {
+		io.WriteString(inW, input)
+		inW.Close()
+	}()
+
+	var wg sync.WaitGroup
+	var outBuf, errBuf bytes.Buffer
+	wg.Add(2)
+	go func() { io.Copy(&outBuf, outR); wg.Done() }()
+	go func() { io.Copy(&errBuf, errR); wg.Done() }()
+
+	prevExit := cliutils.SetExitFunc(func(code int) { panic(exitPanic{code: code}) })
+	// the input stands for what the user would type in the terminal
+	prevTerminal := cliutils.SetStdinIsTerminal(func() bool { return true })
+
+	defer func() {
+		r := recover()
+
+		cliutils.SetExitFunc(prevExit)
+		cliutils.SetStdinIsTerminal(prevTerminal)
+		os.Stdin, os.Stdout, os.Stderr = stdin, stdout, stderr
+		outW.Close()
+		errW.Close()
+		wg.Wait()
+		inR.Close()
+		outR.Close()
+		errR.Close()
+
+		result.Stdout = outBuf.String()
+		result.Stderr = errBuf.String()
+		if exit, ok := r.(exitPanic); ok {
+			result.ExitCode = exit.code
+		} else if r != nil {
+			panic(r)
+		}
+	}()
+
+	command()
+	return
+}
+
+func (h *Harness) pipe() (*os.File, *os.File) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		h.t.Fatalf("unable to create a pipe: %v", err)
+	}
+	return r, w
+}
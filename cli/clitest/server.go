@@ -0,0 +1,245 @@
+// Package clitest is a harness for running the hzn command functions in-process against fake Horizon agent, agbot and
+// exchange APIs, so that the CLI can be tested end to end without docker or a management hub.
+package clitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Response is a scripted response of a fake server. Body is marshaled to JSON, unless it is a string or []byte, which
+// is sent as is.
+type Response struct {
+	Code   int
+	Body   interface{}
+	Header map[string]string
+}
+
+// Request is a request received by a fake server.
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   []byte
+}
+
+func (r Request) String() string {
+	return fmt.Sprintf("%v /%v?%v", r.Method, r.Path, r.Query)
+}
+
+// A route of a fake server. The responses are returned in order and the last one is repeated, which scripts
+// pagination and recovery from errors. A route with a handler calls it instead.
+type route struct {
+	method    string
+	path      []string
+	responses []Response
+	calls     int
+	handler   http.HandlerFunc
+}
+
+// Returns true if the route matches the method and path. A {name} segment of the route matches any segment
+// and a trailing * matches the rest of the path.
+func (r *route) matches(method string, path []string) bool {
+	if r.method != method {
+		return false
+	}
+	for ix, seg := range r.path {
+		if seg == "*" && ix == len(r.path)-1 {
+			return true
+		} else if ix >= len(path) {
+			return false
+		} else if !(strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}")) && seg != path[ix] {
+			return false
+		}
+	}
+	return len(path) == len(r.path)
+}
+
+// A failure injected into the next requests of a route.
+type failure struct {
+	method string
+	path   string
+	count  int
+	resp   Response
+}
+
+// FakeServer is an http server with scriptable routes. Requests that do not match a route get a 404. Every request
+// is recorded, and the latency and failures of the server can be set at any time.
+type FakeServer struct {
+	*httptest.Server
+	lock     sync.Mutex
+	routes   []*route
+	requests []Request
+	latency  time.Duration
+	failures []*failure
+	fallback http.HandlerFunc
+}
+
+// NewFakeServer starts a fake server that is closed when the test ends.
+func NewFakeServer(t testing.TB) *FakeServer {
+	f := &FakeServer{}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// Handle sets the response to a request. Setting the response of a route again replaces it.
+func (f *FakeServer) Handle(method string, path string, code int, body interface{}) {
+	f.HandleSequence(method, path, Response{Code: code, Body: body})
+}
+
+// HandleSequence sets the successive responses to a request. Once they are used up, the last one is repeated.
+func (f *FakeServer) HandleSequence(method string, path string, responses ...Response) {
+	f.addRoute(&route{method: method, path: splitPath(path), responses: responses})
+}
+
+// HandleFunc sets the handler of a request, for behaviors that responses can not script.
+func (f *FakeServer) HandleFunc(method string, path string, handler http.HandlerFunc) {
+	f.addRoute(&route{method: method, path: splitPath(path), handler: handler})
+}
+
+func (f *FakeServer) addRoute(r *route) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for ix, existing := range f.routes {
+		if existing.method == r.method && strings.Join(existing.path, "/") == strings.Join(r.path, "/") {
+			f.routes[ix] = r
+			return
+		}
+	}
+	f.routes = append(f.routes, r)
+}
+
+// SetLatency delays every response by d.
+func (f *FakeServer) SetLatency(d time.Duration) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.latency = d
+}
+
+// FailNext makes the next count requests to the path fail with the code and body, before the route is used again.
+// An empty method fails every method.
+func (f *FakeServer) FailNext(method string, path string, count int, code int, body interface{}) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.failures = append(f.failures, &failure{method: method, path: strings.Trim(path, "/"), count: count, resp: Response{Code: code, Body: body}})
+}
+
+// Requests returns the requests received so far.
+func (f *FakeServer) Requests() []Request {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]Request{}, f.requests...)
+}
+
+// RequestsTo returns the requests received so far for the method and path.
+func (f *FakeServer) RequestsTo(method string, path string) []Request {
+	reqs := make([]Request, 0)
+	for _, r := range f.Requests() {
+		if r.Method == method && r.Path == strings.Trim(path, "/") {
+			reqs = append(reqs, r)
+		}
+	}
+	return reqs
+}
+
+func (f *FakeServer) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	path := strings.Trim(r.URL.Path, "/")
+
+	f.lock.Lock()
+	f.requests = append(f.requests, Request{Method: r.Method, Path: path, Query: r.URL.RawQuery, Header: r.Header.Clone(), Body: body})
+	latency := f.latency
+
+	var resp *Response
+	for _, fail := range f.failures {
+		if fail.count > 0 && fail.path == path && (fail.method == "" || fail.method == r.Method) {
+			fail.count--
+			resp = &fail.resp
+			break
+		}
+	}
+
+	var handler http.HandlerFunc
+	if resp == nil {
+		segments := splitPath(path)
+		for _, rt := range f.routes {
+			if !rt.matches(r.Method, segments) {
+				continue
+			} else if rt.handler != nil {
+				handler = rt.handler
+			} else if len(rt.responses) != 0 {
+				ix := rt.calls
+				if ix >= len(rt.responses) {
+					ix = len(rt.responses) - 1
+				}
+				rt.calls++
+				resp = &rt.responses[ix]
+			}
+			break
+		}
+	}
+	if resp == nil && handler == nil {
+		handler = f.fallback
+	}
+	f.lock.Unlock()
+
+	if latency != 0 {
+		time.Sleep(latency)
+	}
+
+	if resp != nil {
+		WriteResponse(w, *resp)
+	} else if handler != nil {
+		r.Body = ioutil.NopCloser(strings.NewReader(string(body)))
+		handler(w, r)
+	} else {
+		http.Error(w, fmt.Sprintf("no fake response for %v /%v", r.Method, path), http.StatusNotFound)
+	}
+}
+
+// WriteResponse writes a scripted response, for the handlers of HandleFunc.
+func WriteResponse(w http.ResponseWriter, resp Response) {
+	var body []byte
+	switch b := resp.Body.(type) {
+	case nil:
+	case []byte:
+		body = b
+	case string:
+		body = []byte(b)
+	default:
+		var err error
+		if body, err = json.Marshal(b); err != nil {
+			http.Error(w, fmt.Sprintf("unable to marshal the fake response: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+	}
+
+	for k, v := range resp.Header {
+		w.Header().Set(k, v)
+	}
+
+	code := resp.Code
+	if code == 0 {
+		code = http.StatusOK
+	}
+	w.WriteHeader(code)
+	w.Write(body)
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return []string{}
+	}
+	return strings.Split(path, "/")
+}
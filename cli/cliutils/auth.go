@@ -0,0 +1,103 @@
+package cliutils
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/open-horizon/anax/i18n"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// The env var that selects how the credentials without a scheme prefix are sent to the exchange.
+const EXCHANGE_AUTH_SCHEME_ENV = "HZN_EXCHANGE_AUTH_SCHEME"
+
+// The schemes of the credentials. Basic sends id:password in a Basic Authorization header. Bearer sends the token in a
+// Bearer Authorization header, for an exchange behind an IAM gateway. Apikey sends the api key as the iamapikey user
+// that the exchange authenticates with IAM, or exchanges it for a token at the IAM token service in HZN_IAM_TOKEN_URL.
+// Wiotp sends the api key and authentication token of Watson IoT Platform in a Basic Authorization header, without an
+// org, see wiotp.go.
+const (
+	AUTH_SCHEME_BASIC  = "basic"
+	AUTH_SCHEME_BEARER = "bearer"
+	AUTH_SCHEME_APIKEY = "apikey"
+	AUTH_SCHEME_WIOTP  = "wiotp"
+)
+
+// GetAuthScheme returns the scheme of the credentials, and the org and the token or password in them. The scheme is
+// the prefix of the credentials, as in [org/]bearer:<token>, [org/]apikey:<key> or wiotp:<key>:<auth token>, or the one
+// in HZN_EXCHANGE_AUTH_SCHEME for credentials without a prefix, which is basic by default. The token of the wiotp
+// scheme is the api key and the authentication token.
+func GetAuthScheme(credentials string) (scheme string, org string, token string) {
+	scheme, org, token, err := getAuthScheme(credentials)
+	exitOnError(err)
+	return
+}
+
+// GetAuthScheme returning the error of an invalid HZN_EXCHANGE_AUTH_SCHEME instead of exiting.
+func getAuthScheme(credentials string) (scheme string, org string, token string, err error) {
+	id, token := SplitIdToken(credentials)
+	if parts := strings.SplitN(id, "/", 2); len(parts) == 2 {
+		org, id = parts[0], parts[1]
+	}
+
+	switch strings.ToLower(id) {
+	case AUTH_SCHEME_BEARER, AUTH_SCHEME_APIKEY, AUTH_SCHEME_WIOTP:
+		return strings.ToLower(id), org, token, nil
+	}
+
+	scheme = strings.ToLower(os.Getenv(EXCHANGE_AUTH_SCHEME_ENV))
+	switch scheme {
+	case "", AUTH_SCHEME_BASIC:
+		scheme = AUTH_SCHEME_BASIC
+	case AUTH_SCHEME_BEARER, AUTH_SCHEME_APIKEY:
+		// credentials that are only the token
+		if token == "" {
+			token = id
+		}
+	case AUTH_SCHEME_WIOTP:
+		if token != "" {
+			token = id + ":" + token
+		} else {
+			token = id
+		}
+	default:
+		return "", "", "", newCLIError(CLI_INPUT_ERROR, i18n.GetMessagePrinter().Sprintf("%v must be %v, %v, %v or %v, not %v", EXCHANGE_AUTH_SCHEME_ENV, AUTH_SCHEME_BASIC, AUTH_SCHEME_BEARER, AUTH_SCHEME_APIKEY, AUTH_SCHEME_WIOTP, scheme))
+	}
+	return scheme, org, token, nil
+}
+
+// Add the Authorization header of the credentials to the request. Requests without credentials are anonymous.
+func setAuthHeader(req *http.Request, credentials string) error {
+	if credentials == "" {
+		return nil
+	}
+
+	scheme, org, token, err := getAuthScheme(credentials)
+	if err != nil {
+		return err
+	}
+	switch scheme {
+	case AUTH_SCHEME_BEARER:
+		req.Header.Set("Authorization", "Bearer "+token)
+	case AUTH_SCHEME_APIKEY:
+		if GetIamTokenUrl() != "" {
+			iamToken, err := getIamToken(token)
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Authorization", "Bearer "+iamToken)
+			return nil
+		}
+		user := "iamapikey:" + token
+		if org != "" {
+			user = org + "/" + user
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Basic %v", base64.StdEncoding.EncodeToString([]byte(user))))
+	case AUTH_SCHEME_WIOTP:
+		req.Header.Set("Authorization", fmt.Sprintf("Basic %v", base64.StdEncoding.EncodeToString([]byte(token))))
+	default:
+		req.Header.Set("Authorization", fmt.Sprintf("Basic %v", base64.StdEncoding.EncodeToString([]byte(credentials))))
+	}
+	return nil
+}
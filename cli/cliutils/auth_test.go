@@ -0,0 +1,47 @@
+// +build unit
+
+package cliutils
+
+import (
+	"encoding/base64"
+	"net/http"
+	"os"
+	"testing"
+)
+
+func Test_setAuthHeader(t *testing.T) {
+	basic := func(creds string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(creds))
+	}
+	defer os.Unsetenv(EXCHANGE_AUTH_SCHEME_ENV)
+
+	for _, tc := range []struct {
+		scheme      string
+		credentials string
+		header      string
+	}{
+		{"", "myorg/me:pw", basic("myorg/me:pw")},
+		{"", "myorg/bearer:abc.def", "Bearer abc.def"},
+		{"", "myorg/apikey:k1", basic("myorg/iamapikey:k1")},
+		{"", "apikey:k1", basic("iamapikey:k1")},
+		{"basic", "myorg/me:pw", basic("myorg/me:pw")},
+		{"bearer", "myorg/me:abc.def", "Bearer abc.def"},
+		{"bearer", "myorg/abc.def", "Bearer abc.def"},
+		{"apikey", "myorg/k1", basic("myorg/iamapikey:k1")},
+		{"", "wiotp:a-abc123-key:authtok", basic("a-abc123-key:authtok")},
+		{"wiotp", "a-abc123-key:authtok", basic("a-abc123-key:authtok")},
+		{"bearer", "", ""},
+	} {
+		os.Setenv(EXCHANGE_AUTH_SCHEME_ENV, tc.scheme)
+		req, _ := http.NewRequest(http.MethodGet, "http://exchange/v1/orgs", nil)
+		setAuthHeader(req, tc.credentials)
+		if header := req.Header.Get("Authorization"); header != tc.header {
+			t.Errorf("wrong header for %v with scheme %v: expected %v, found %v", tc.credentials, tc.scheme, tc.header, header)
+		}
+	}
+
+	os.Setenv(EXCHANGE_AUTH_SCHEME_ENV, "digest")
+	if err := Try(func() { GetAuthScheme("myorg/me:pw") }); ExitCode(err) != CLI_INPUT_ERROR {
+		t.Errorf("an unknown scheme should be an input error, found %v", err)
+	}
+}
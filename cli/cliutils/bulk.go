@@ -0,0 +1,214 @@
+package cliutils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/open-horizon/anax/i18n"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The default number of operations that a bulk command runs at the same time.
+const DEFAULT_BULK_CONCURRENCY = 5
+
+// The outcome of one operation of a bulk command.
+type BulkResult struct {
+	Id    string
+	Error error
+}
+
+// RunBulk calls fn for each id, with at most concurrency calls running at the same time. The results are returned
+// in the same order as the ids. A concurrency less than 1 means the default. fn returns the failure of its operation,
+// with the E variants of the helpers, because a call to Fatal ends the command.
+func RunBulk(ids []string, concurrency int, fn func(id string) error) []BulkResult {
+	if concurrency < 1 {
+		concurrency = DEFAULT_BULK_CONCURRENCY
+	}
+
+	results := make([]BulkResult, len(ids))
+	sem := make(chan bool, concurrency)
+	var wg sync.WaitGroup
+
+	for i, id := range ids {
+		wg.Add(1)
+		sem <- true
+		go func(i int, id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = BulkResult{Id: id, Error: fn(id)}
+		}(i, id)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// PrintBulkResults prints the outcome of each operation and a summary line. The doneMsg is printed for each successful
+// operation with the id as its argument. Returns the number of operations that failed.
+func PrintBulkResults(results []BulkResult, doneMsg string) int {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	failed := 0
+	for _, r := range results {
+		if r.Error != nil {
+			failed += 1
+			msgPrinter.Printf("%s: failed: %v", r.Id, r.Error)
+		} else {
+			msgPrinter.Printf(doneMsg, r.Id)
+		}
+		msgPrinter.Println()
+	}
+
+	msgPrinter.Printf("%d succeeded, %d failed.", len(results)-failed, failed)
+	msgPrinter.Println()
+	return failed
+}
+
+// The machine readable outcome of a bulk command, written to the file given with --summary-file.
+type BulkSummary struct {
+	Total     int           `json:"total"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Failures  []BulkFailure `json:"failures"`
+}
+
+type BulkFailure struct {
+	Id    string `json:"id"`
+	Error string `json:"error"`
+}
+
+func NewBulkSummary(results []BulkResult) BulkSummary {
+	summary := BulkSummary{Total: len(results), Failures: make([]BulkFailure, 0)}
+	for _, r := range results {
+		if r.Error != nil {
+			summary.Failures = append(summary.Failures, BulkFailure{Id: r.Id, Error: r.Error.Error()})
+		}
+	}
+	summary.Failed = len(summary.Failures)
+	summary.Succeeded = summary.Total - summary.Failed
+	return summary
+}
+
+// BulkExitCode returns the exit code of a bulk command: 0 if all the operations succeeded, PARTIAL_SUCCESS if only
+// some of them failed, and failCode if all of them failed.
+func BulkExitCode(results []BulkResult, failCode int) int {
+	summary := NewBulkSummary(results)
+	if summary.Failed == 0 {
+		return 0
+	} else if summary.Succeeded == 0 {
+		return failCode
+	}
+	return PARTIAL_SUCCESS
+}
+
+// FinishBulk ends a bulk command. The summary of the results is written to the summaryFile, if one is given, and the
+// command exits with the error message and the code from BulkExitCode if any of the operations failed.
+func FinishBulk(results []BulkResult, summaryFile string, failCode int, failMsg string) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if summaryFile != "" {
+		if jsonBytes, err := json.MarshalIndent(NewBulkSummary(results), "", JSON_INDENT); err != nil {
+			Fatal(JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal the summary: %v", err))
+		} else if err := ioutil.WriteFile(summaryFile, append(jsonBytes, '\n'), 0644); err != nil {
+			Fatal(FILE_IO_ERROR, msgPrinter.Sprintf("failed to write the summary to %v: %v", summaryFile, err))
+		}
+	}
+
+	if code := BulkExitCode(results, failCode); code != 0 {
+		Fatal(code, failMsg)
+	}
+}
+
+// BulkErrors combines the errors of the failed operations into one error, with one line per failed operation, so
+// that a command that prints its own output can report all the failures at the end. Returns nil if none failed.
+func BulkErrors(results []BulkResult) error {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	failures := make([]string, 0)
+	for _, r := range results {
+		if r.Error != nil {
+			failures = append(failures, msgPrinter.Sprintf("%s: %v", r.Id, r.Error))
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return errors.New(msgPrinter.Sprintf("%d of %d operations failed:\n%s", len(failures), len(results), strings.Join(failures, "\n")))
+}
+
+// ParseAge parses an age such as 30m, 12h or 7d. The units supported by time.ParseDuration can be used, plus d for days.
+func ParseAge(age string) (time.Duration, error) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if strings.HasSuffix(age, "d") {
+		if days, err := strconv.ParseUint(strings.TrimSuffix(age, "d"), 10, 32); err != nil {
+			return 0, errors.New(msgPrinter.Sprintf("invalid age %v, it must be a number of days such as 7d or a duration such as 12h", age))
+		} else {
+			return time.Duration(days) * 24 * time.Hour, nil
+		}
+	} else if d, err := time.ParseDuration(age); err != nil || d < 0 {
+		return 0, errors.New(msgPrinter.Sprintf("invalid age %v, it must be a number of days such as 7d or a duration such as 12h", age))
+	} else {
+		return d, nil
+	}
+}
+
+// IsGlob returns true if the id of a resource is a wildcard pattern, such as myorg/weather_*, rather than a single id.
+func IsGlob(id string) bool {
+	return strings.ContainsAny(id, "*?[")
+}
+
+// MatchGlob returns the ids that match the wildcard pattern, sorted. The pattern syntax is the one of path.Match, so a
+// * does not match the / between the org and the id.
+func MatchGlob(pattern string, ids []string) ([]string, error) {
+	matches := make([]string, 0)
+	for _, id := range ids {
+		if matched, err := path.Match(pattern, id); err != nil {
+			return nil, errors.New(i18n.GetMessagePrinter().Sprintf("invalid wildcard pattern %v: %v", pattern, err))
+		} else if matched {
+			matches = append(matches, id)
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// RemoveMatching removes the resources whose ids match the wildcard pattern, for the remove commands. Everything that
+// matches is listed first, and the user must confirm the removal unless force is true. The resources are removed by
+// calling remove with each id, the command exits with an error if any of them could not be removed.
+func RemoveMatching(kind string, pattern string, ids []string, force bool, remove func(id string) error) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	matches, err := MatchGlob(pattern, ids)
+	if err != nil {
+		Fatal(CLI_INPUT_ERROR, err.Error())
+	} else if len(matches) == 0 {
+		Fatal(NOT_FOUND, msgPrinter.Sprintf("no %s match %s", kind, pattern))
+	}
+
+	msgPrinter.Printf("The following %d %s match %s:", len(matches), kind, pattern)
+	msgPrinter.Println()
+	for _, id := range matches {
+		fmt.Printf("  %s\n", id)
+	}
+	if !force {
+		ConfirmRemove(msgPrinter.Sprintf("Are you sure you want to remove these %d %s from the Horizon Exchange?", len(matches), kind))
+	}
+
+	results := RunBulk(matches, DEFAULT_BULK_CONCURRENCY, remove)
+	summary := NewBulkSummary(results)
+	msgPrinter.Printf("Removed %d of %d %s.", summary.Succeeded, summary.Total, kind)
+	msgPrinter.Println()
+
+	failMsg := ""
+	if err := BulkErrors(results); err != nil {
+		failMsg = err.Error()
+	}
+	FinishBulk(results, "", HTTP_ERROR, failMsg)
+}
@@ -0,0 +1,135 @@
+// +build unit
+
+package cliutils
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_RunBulk(t *testing.T) {
+	var lock sync.Mutex
+	running, maxRunning := 0, 0
+
+	ids := []string{"a", "b", "c", "d", "e", "f", "g"}
+	results := RunBulk(ids, 3, func(id string) error {
+		lock.Lock()
+		running += 1
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		lock.Lock()
+		running -= 1
+		lock.Unlock()
+
+		if id == "c" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	if maxRunning > 3 {
+		t.Errorf("expected at most 3 concurrent operations, got %v", maxRunning)
+	}
+	if len(results) != len(ids) {
+		t.Fatalf("expected %v results, got %v", len(ids), len(results))
+	}
+	for i, r := range results {
+		if r.Id != ids[i] {
+			t.Errorf("result %v is for %v, expected %v", i, r.Id, ids[i])
+		} else if (r.Error != nil) != (r.Id == "c") {
+			t.Errorf("unexpected error %v for %v", r.Error, r.Id)
+		}
+	}
+}
+
+func Test_BulkErrors(t *testing.T) {
+	if err := BulkErrors([]BulkResult{{Id: "a"}, {Id: "b"}}); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	err := BulkErrors([]BulkResult{{Id: "a"}, {Id: "b", Error: errors.New("b failed")}, {Id: "c", Error: errors.New("c failed")}})
+	if err == nil {
+		t.Fatalf("expected an error")
+	} else if err.Error() != "2 of 3 operations failed:\nb: b failed\nc: c failed" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func Test_BulkExitCode(t *testing.T) {
+	ok := BulkResult{Id: "a"}
+	failed := BulkResult{Id: "b", Error: errors.New("b failed")}
+
+	if code := BulkExitCode([]BulkResult{ok, ok}, HTTP_ERROR); code != 0 {
+		t.Errorf("expected 0 when all succeeded, got %v", code)
+	} else if code := BulkExitCode([]BulkResult{ok, failed}, HTTP_ERROR); code != PARTIAL_SUCCESS {
+		t.Errorf("expected %v when some failed, got %v", PARTIAL_SUCCESS, code)
+	} else if code := BulkExitCode([]BulkResult{failed, failed}, HTTP_ERROR); code != HTTP_ERROR {
+		t.Errorf("expected %v when all failed, got %v", HTTP_ERROR, code)
+	} else if code := BulkExitCode([]BulkResult{}, HTTP_ERROR); code != 0 {
+		t.Errorf("expected 0 when there is nothing to do, got %v", code)
+	}
+
+	summary := NewBulkSummary([]BulkResult{ok, failed, ok})
+	if summary.Total != 3 || summary.Succeeded != 2 || summary.Failed != 1 {
+		t.Errorf("unexpected summary %v", summary)
+	} else if len(summary.Failures) != 1 || summary.Failures[0].Id != "b" || summary.Failures[0].Error != "b failed" {
+		t.Errorf("unexpected failures %v", summary.Failures)
+	}
+}
+
+func Test_ParseAge(t *testing.T) {
+	tests := []struct {
+		age      string
+		expected time.Duration
+		valid    bool
+	}{
+		{"7d", 7 * 24 * time.Hour, true},
+		{"12h", 12 * time.Hour, true},
+		{"90m", 90 * time.Minute, true},
+		{"d", 0, false},
+		{"-1h", 0, false},
+		{"soon", 0, false},
+	}
+
+	for _, test := range tests {
+		if d, err := ParseAge(test.age); test.valid && err != nil {
+			t.Errorf("unexpected error parsing %v: %v", test.age, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("expected an error parsing %v", test.age)
+		} else if d != test.expected {
+			t.Errorf("parsed %v as %v, expected %v", test.age, d, test.expected)
+		}
+	}
+}
+
+func Test_MatchGlob(t *testing.T) {
+	ids := []string{"myorg/weather_2", "myorg/weather_1", "myorg/gps", "myorg/weather/nested"}
+
+	if matches, err := MatchGlob("myorg/weather_*", ids); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if !reflect.DeepEqual(matches, []string{"myorg/weather_1", "myorg/weather_2"}) {
+		t.Errorf("unexpected matches %v", matches)
+	}
+
+	if matches, err := MatchGlob("myorg/*", ids); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if len(matches) != 3 {
+		t.Errorf("expected * to not match the /, got %v", matches)
+	}
+
+	if _, err := MatchGlob("myorg/[", ids); err == nil {
+		t.Errorf("expected an error for an invalid pattern")
+	}
+
+	if !IsGlob("myorg/weather_*") || IsGlob("myorg/weather_1") {
+		t.Errorf("IsGlob did not recognize the wildcard patterns")
+	}
+}
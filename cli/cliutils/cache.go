@@ -0,0 +1,125 @@
+package cliutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/open-horizon/anax/i18n"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// The env var that enables the cache of the exchange GET responses.
+const EXCHANGE_CACHE_ENV = "HZN_EXCHANGE_CACHE"
+
+// The exchange resources whose responses are cached. Each of them has a lastUpdated time that the exchange returns on
+// its own with the attribute query parameter, and changes on every update of the resource.
+var cachedResourceRE = regexp.MustCompile(`^orgs/[^/]+/(patterns|services|business/policies)/[^/?]+$`)
+
+// GetExchangeCacheDir returns the directory the exchange responses are cached in, or an empty string if they are not
+// cached. The cache is enabled with HZN_EXCHANGE_CACHE, which is either true, to use the directory of the profile in
+// ~/.hzn/cache, or the name of the directory to use.
+func GetExchangeCacheDir() string {
+	cacheDir := os.Getenv(EXCHANGE_CACHE_ENV)
+	switch strings.ToLower(cacheDir) {
+	case "", "false", "0":
+		return ""
+	case "true", "1":
+		return filepath.Join(os.Getenv("HOME"), ".hzn", "cache", GetProfileName())
+	}
+	return cacheDir
+}
+
+// A response that is cached with the lastUpdated time of its resource. The next GET of the same url with the same
+// credentials first gets only the lastUpdated time of the resource, and the cached body is used when it has not
+// changed. The cache never serves a response without asking the exchange, so it is never stale.
+type cachedResponse struct {
+	URL         string `json:"url"`
+	LastUpdated string `json:"lastUpdated"`
+	Body        []byte `json:"body"`
+}
+
+// The file of the cached response of a url. The credentials are part of the key, because the exchange returns
+// different resources to different users, but only the hash of them is kept.
+func cacheFile(cacheDir string, url string, credentials string) string {
+	hash := sha256.Sum256([]byte(url + "\n" + credentials))
+	return filepath.Join(cacheDir, hex.EncodeToString(hash[:])+".json")
+}
+
+// Returns the cached response of a url, or nil.
+func getCachedResponse(cacheDir string, url string, credentials string) *cachedResponse {
+	content, err := ioutil.ReadFile(cacheFile(cacheDir, url, credentials))
+	if err != nil {
+		return nil
+	}
+	cached := new(cachedResponse)
+	if err := json.Unmarshal(content, cached); err != nil || cached.URL != url || cached.LastUpdated == "" {
+		return nil
+	}
+	return cached
+}
+
+// Cache a response. A failure only means the next GET downloads the body again.
+func putCachedResponse(cacheDir string, url string, credentials string, cached *cachedResponse) {
+	file := cacheFile(cacheDir, url, credentials)
+	content, err := json.Marshal(cached)
+	if err == nil {
+		if err = os.MkdirAll(cacheDir, 0700); err == nil {
+			err = ioutil.WriteFile(file, content, 0600)
+		}
+	}
+	if err != nil {
+		Verbose(i18n.GetMessagePrinter().Sprintf("unable to cache the response of %v in %v: %v", url, file, err))
+	}
+}
+
+// Returns the lastUpdated time of the only resource in the body of an exchange GET, e.g.
+// {"patterns": {"myorg/p1": {"lastUpdated": "..."}}}, or an empty string.
+func bodyLastUpdated(bodyBytes []byte) string {
+	var resources map[string]map[string]struct {
+		LastUpdated string `json:"lastUpdated"`
+	}
+	if err := json.Unmarshal(bodyBytes, &resources); err != nil || len(resources) != 1 {
+		return ""
+	}
+	for _, byId := range resources {
+		if len(byId) != 1 {
+			return ""
+		}
+		for _, r := range byId {
+			return r.LastUpdated
+		}
+	}
+	return ""
+}
+
+// Returns the current lastUpdated time of a resource in the exchange, or an empty string if it cannot be read, in
+// which case the whole resource is read again.
+func getLastUpdated(httpClient *http.Client, service string, url string, credentials string) string {
+	apiMsg := http.MethodGet + " " + url + "?attribute=lastUpdated"
+	Verbose(apiMsg)
+	resp, err := invokeRestApi(httpClient, http.MethodGet, url+"?attribute=lastUpdated", credentials, nil, service, apiMsg, nil)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	bodyBytes, err := readRespBody(resp.Body, apiMsg)
+	if err != nil {
+		return ""
+	}
+	var attr struct {
+		Attribute string `json:"attribute"`
+		Value     string `json:"value"`
+	}
+	if err := json.Unmarshal(bodyBytes, &attr); err != nil || attr.Attribute != "lastUpdated" {
+		return ""
+	}
+	return attr.Value
+}
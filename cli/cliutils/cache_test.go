@@ -0,0 +1,80 @@
+// +build unit
+
+package cliutils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func Test_ExchangeGet_cache(t *testing.T) {
+	lastUpdated, description, fullResponses := "2020-10-01T12:00:00Z", "v1", 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("attribute") == "lastUpdated" {
+			fmt.Fprintf(w, `{"attribute":"lastUpdated","value":"%v"}`, lastUpdated)
+			return
+		}
+		fullResponses++
+		fmt.Fprintf(w, `{"patterns":{"myorg/p1":{"description":"%v","lastUpdated":"%v"}}}`, description, lastUpdated)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "cache-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	verbose, dryRun := false, false
+	Opts.Verbose, Opts.IsDryRun = &verbose, &dryRun
+	defer func() { Opts.Verbose, Opts.IsDryRun = nil, nil }()
+	os.Setenv(EXCHANGE_CACHE_ENV, dir)
+	defer os.Unsetenv(EXCHANGE_CACHE_ENV)
+
+	get := func(urlSuffix string, credentials string) string {
+		var out map[string]map[string]map[string]string
+		if code, err := ExchangeGetE("Exchange", ts.URL, urlSuffix, credentials, []int{200, 404}, &out); err != nil || code != 200 {
+			t.Fatalf("unexpected result %v %v", code, err)
+		}
+		return out["patterns"]["myorg/p1"]["description"]
+	}
+
+	if d := get("orgs/myorg/patterns/p1", "myorg/u:p"); d != "v1" || fullResponses != 1 {
+		t.Errorf("wrong first response %v, %v full responses", d, fullResponses)
+	} else if d := get("orgs/myorg/patterns/p1", "myorg/u:p"); d != "v1" || fullResponses != 1 {
+		t.Errorf("the cached body should be used, found %v, %v full responses", d, fullResponses)
+	} else if get("orgs/myorg/patterns/p1", "myorg/other:p"); fullResponses != 2 {
+		t.Errorf("the responses of other credentials should not be used, %v full responses", fullResponses)
+	}
+	get("orgs/myorg/patterns", "myorg/u:p")
+	if get("orgs/myorg/patterns", "myorg/u:p"); fullResponses != 4 {
+		t.Errorf("the lists of resources should not be cached, %v full responses", fullResponses)
+	}
+
+	lastUpdated, description = "2020-10-02T12:00:00Z", "v2"
+	if d := get("orgs/myorg/patterns/p1", "myorg/u:p"); d != "v2" || fullResponses != 5 {
+		t.Errorf("a changed resource should be downloaded again, found %v, %v full responses", d, fullResponses)
+	}
+
+	os.Unsetenv(EXCHANGE_CACHE_ENV)
+	if get("orgs/myorg/patterns/p1", "myorg/u:p"); fullResponses != 6 {
+		t.Errorf("the cache should be off without %v, %v full responses", EXCHANGE_CACHE_ENV, fullResponses)
+	}
+}
+
+func Test_bodyLastUpdated(t *testing.T) {
+	for body, expected := range map[string]string{
+		`{"services":{"myorg/s1":{"lastUpdated":"2020-10-01T12:00:00Z"}}}`:                                "2020-10-01T12:00:00Z",
+		`{"services":{"myorg/s1":{"lastUpdated":"2020-10-01T12:00:00Z"},"myorg/s2":{"lastUpdated":"x"}}}`: "",
+		`{"services":{"myorg/s1":{}}}`: "",
+		`not json`:                     "",
+	} {
+		if lastUpdated := bodyLastUpdated([]byte(body)); lastUpdated != expected {
+			t.Errorf("expected %q for %v, found %q", expected, body, lastUpdated)
+		}
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"bytes"
 	"crypto/tls"
 	"crypto/x509"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -19,7 +18,6 @@ import (
 	"golang.org/x/text/language"
 	"io"
 	"io/ioutil"
-	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -27,6 +25,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -48,6 +47,12 @@ const (
 	NOT_FOUND         = 8
 	SIGNATURE_INVALID = 9
 	EXEC_CMD_ERROR    = 10
+	CONFLICT_ERROR    = 11
+	PARTIAL_SUCCESS   = 12 // some of the operations of a bulk command failed, but not all of them
+	INVALID_CREDS     = 13 // the exchange did not accept the credentials
+	HEARTBEAT_STALE   = 14 // the node has not heartbeated to the exchange recently enough
+	DEGRADED          = 15 // a health check of the node or of the exchange failed
+	INTERRUPTED       = 130 // the command was interrupted with Ctrl-C or SIGTERM, the same code as a shell uses
 	INTERNAL_ERROR    = 99
 
 	// Anax API HTTP Codes
@@ -62,17 +67,48 @@ const (
 	DEFAULT_PRIVATE_KEY_FILE = ".hzn/keys/service.private.key"
 	DEFAULT_PUBLIC_KEY_FILE  = ".hzn/keys/service.public.pem"
 
+	// limits on the size of http response bodies that are read into memory. The first can be overridden with HZN_HTTP_MAX_BODY_SIZE.
+	DEFAULT_MAX_RESPONSE_BODY_SIZE = 100 * 1024 * 1024
+	MAX_ERROR_RESPONSE_BODY_SIZE   = 64 * 1024
+
 	// http request body types
 	HTTP_REQ_BODYTYPE_DEFAULT = 0
 	HTTP_REQ_BODYTYPE_BYTES   = 1
 	HTTP_REQ_BODYTYPE_FILE    = 2
 )
 
+// The env var with the file of the CA certificates that sign the TLS certificates of the management hub.
+const EXCHANGE_CA_CERT_ENV = "HORIZON_EXCHANGE_CA_CERT"
+
+// The env vars with the files of the client certificate and key, for a management hub that authenticates the clients
+// with mutual TLS.
+const EXCHANGE_CLIENT_CERT_ENV = "HORIZON_EXCHANGE_CLIENT_CERT"
+const EXCHANGE_CLIENT_KEY_ENV = "HORIZON_EXCHANGE_CLIENT_KEY"
+
+// The env var with the exchange url, or the comma separated list of mirrored exchanges, when HZN_EXCHANGE_URL is not set.
+const EXCHANGE_URL_BASE_ENV = "HORIZON_EXCHANGE_URL_BASE"
+
 // Holds the cmd line flags that were set so other pkgs can access
 type GlobalOptions struct {
 	Verbose     *bool
 	IsDryRun    *bool
-	UsingApiKey bool // should go away soon
+	ExchangeUrl *string // overrides HZN_EXCHANGE_URL and the agent configuration
+	HorizonUrl  *string // overrides HORIZON_URL
+	UserAuth    *string // the exchange user credentials when the command is not given -u, overrides HZN_EXCHANGE_USER_AUTH
+	NodeAuth    *string // the exchange node credentials when the command is not given -n, overrides HZN_EXCHANGE_NODE_AUTH
+	Insecure    *bool   // skip the verification of TLS certificates, same as HZN_SSL_SKIP_VERIFY
+	Timeout     *int    // the http request timeout in seconds, overrides HZN_HTTP_TIMEOUT
+	ClientCert  *string // the client certificate file for mutual TLS, overrides HORIZON_EXCHANGE_CLIENT_CERT
+	ClientKey   *string // the client key file for mutual TLS, overrides HORIZON_EXCHANGE_CLIENT_KEY
+	Proxy       *string // the proxy for every request, overrides HTTP_PROXY and HTTPS_PROXY
+	DumpHttp    *bool   // write the trace of the http requests to stderr, same as HZN_HTTP_TRACE=stderr
+	JsonErrors  *bool   // write the error that ends the command to stderr as a JSON object
+	AssumeYes   *bool   // skip the confirmation prompts, same as HZN_NONINTERACTIVE
+	OutputFmt   *string // the format of the output of the list and get commands, same as HZN_OUTPUT_FORMAT
+	YamlInput   *bool   // read the input files that do not have a .yaml or .yml extension, and stdin, as YAML
+	UsingWiotp  bool    // the credentials of the command are the ones of Watson IoT Platform, see SetWhetherUsingWiotp
+
+	warnedUsingApiKey bool // the deprecation of USING_API_KEY was displayed
 }
 
 var Opts GlobalOptions
@@ -122,12 +158,36 @@ func Verbose(msg string, args ...interface{}) {
 	}
 }
 
+// Fatal displays the error and exits with the exit code. Inside Try, it ends the function Try runs and Try returns
+// the error instead.
 func Fatal(exitCode int, msg string, args ...interface{}) {
-	if !strings.HasSuffix(msg, "\n") {
-		msg += "\n"
+	// the failures of an interrupted command are caused by the cancelled requests
+	if Interrupted() {
+		exitCode, msg, args = INTERRUPTED, i18n.GetMessagePrinter().Sprintf("The command was interrupted."), nil
+	}
+	if isTrying() {
+		panic(newCLIError(exitCode, msg, args...))
 	}
-	fmt.Fprintf(os.Stderr, i18n.GetMessagePrinter().Sprintf("Error: %s", msg), args...)
-	os.Exit(exitCode)
+	if len(args) != 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+	printError(exitCode, msg)
+	exitFunc(exitCode)
+}
+
+// The function that ends the command. Tests that run commands in-process replace it with SetExitFunc.
+var exitFunc = os.Exit
+
+// SetExitFunc sets the function that Fatal calls to end the command and returns the previous one.
+func SetExitFunc(f func(int)) func(int) {
+	prev := exitFunc
+	exitFunc = f
+	return prev
+}
+
+// Exit ends the command with the exit code, without an error message, for the commands whose output is the verdict.
+func Exit(exitCode int) {
+	exitFunc(exitCode)
 }
 
 func Warning(msg string, args ...interface{}) {
@@ -138,7 +198,7 @@ func Warning(msg string, args ...interface{}) {
 }
 
 func IsDryRun() bool {
-	return *Opts.IsDryRun
+	return Opts.IsDryRun != nil && *Opts.IsDryRun
 }
 
 /*
@@ -175,23 +235,6 @@ func MarshalIndent(v interface{}, errMsg string) string {
 	return string(jsonBytes)
 }
 
-//todo: this function should be removed because it was for WIoTP keys that shouldn't have the org prepended.
-//		The name is also very misleading because it doesn't apply to Cloud IAM api keys.
-// SetWhetherUsingApiKey is a hack because some api keys are global and shouldn't be prepended by the org
-// an api key or device id/token.
-func SetWhetherUsingApiKey(creds string) {
-	if os.Getenv("USING_API_KEY") == "0" {
-		return // this is their way of telling us that even though the creds look like an api key it isn't
-	}
-	// Some API keys start with: a-<6charorgid>-
-	if matched, err := regexp.MatchString(`^a-[A-Za-z0-9]{6}-`, creds); err != nil {
-		Fatal(INTERNAL_ERROR, i18n.GetMessagePrinter().Sprintf("problem testing api key match: %v", err))
-	} else if matched {
-		Opts.UsingApiKey = true
-		Verbose(i18n.GetMessagePrinter().Sprintf("Using API key"))
-	}
-}
-
 func NewDockerClient() (client *dockerclient.Client) {
 	var err error
 	dockerEndpoint := "unix:///var/run/docker.sock" // if we need this to be user configurable someday, we can get it from an env var
@@ -309,8 +352,8 @@ func PullDockerImage(client *dockerclient.Client, domain, path, tag string) (dig
 // OrgAndCreds prepends the org to creds (separated by /) unless creds already has an org prepended
 func OrgAndCreds(org, creds string) string {
 	// org is the org of the resource being accessed, so if they want to use creds from a different org, the prepend that org to creds before calling this
-	if Opts.UsingApiKey || os.Getenv("USING_API_KEY") == "1" { //todo: remove because this was for WIoTP keys that shouldn't have the org prepended
-		return creds
+	if UsingWiotp() || isWiotpCredentials(creds) {
+		return creds // the wiotp credentials have no org
 	}
 	id, _ := SplitIdToken(creds) // only look for the / in the id, because the token is more likely to have special chars
 	if strings.Contains(id, "/") {
@@ -393,7 +436,8 @@ func ExpandEnv(s string) string {
 	return os.Expand(s, ExpandMapping)
 }
 
-// ReadJsonFile reads json from a file or stdin, eliminates comments, substitutes env vars, and returns it.
+// ReadJsonFile reads json from a file or stdin, eliminates comments and trailing commas, substitutes env vars, and
+// returns it. A YAML file, by its extension or with --yaml, is converted to json after the env vars are substituted.
 func ReadJsonFile(filePath string) []byte {
 	var fileBytes []byte
 	var err error
@@ -406,9 +450,21 @@ func ReadJsonFile(filePath string) []byte {
 		Fatal(FILE_IO_ERROR, i18n.GetMessagePrinter().Sprintf("reading %s failed: %v", filePath, err))
 	}
 
-	// Remove /* */ comments
-	re := regexp.MustCompile(`(?s)/\*.*?\*/`)
-	newBytes := re.ReplaceAll(fileBytes, nil)
+	if IsYamlFile(filePath) {
+		if os.Getenv("HZN_DONT_SUBST_ENV_VARS") != "1" {
+			fileBytes = []byte(ExpandEnv(string(fileBytes)))
+		}
+		jsonBytes, err := yamlToJson(fileBytes)
+		if err != nil {
+			Fatal(JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to convert the YAML in %s to JSON: %v", filePath, err))
+		}
+		setYamlLines(filePath, fileBytes)
+		Verbose(i18n.GetMessagePrinter().Sprintf("converted the YAML in %s to JSON", filePath))
+		return jsonBytes
+	}
+
+	// Remove the /* */ and // comments and the trailing commas, keeping the lines of the file for the parse errors
+	newBytes := stripJsonComments(fileBytes)
 
 	// Replace env vars
 	if os.Getenv("HZN_DONT_SUBST_ENV_VARS") == "1" {
@@ -418,24 +474,39 @@ func ReadJsonFile(filePath string) []byte {
 	return []byte(str)
 }
 
-// ConfirmRemove prompts the user to confirm they want to run the destructive cmd
+// ConfirmRemove prompts the user to confirm they want to run the destructive cmd. It returns right away with --yes
+// or HZN_NONINTERACTIVE. When stdin is not a terminal the answer is read from it, and the command fails when there is
+// no answer or an empty one.
 func ConfirmRemove(question string) {
-	// Prompt the user to make sure he/she wants to do this
+	if IsNonInteractive() {
+		Verbose(i18n.GetMessagePrinter().Sprintf("Skipping the confirmation: %v", question))
+		return
+	}
+
+	// Prompt the user to make sure he/she wants to do this. The answer can also be piped in, e.g. echo y | hzn unregister.
 	fmt.Print(question + " [y/N]: ")
 	var response string
 
 	reader := bufio.NewReader(os.Stdin)
 	response, err := reader.ReadString('\n')
-	if err != nil {
+	if err != nil && (err != io.EOF || strings.TrimSpace(response) == "") {
+		if !stdinIsTerminal() {
+			Fatal(CLI_INPUT_ERROR, i18n.GetMessagePrinter().Sprintf("%v The command needs a confirmation, but stdin is not a terminal and has no answer. Specify --yes or set %v=true to skip the confirmation, or the --force flag of the command.", question, NONINTERACTIVE_ENV))
+		}
 		Fatal(CLI_INPUT_ERROR, i18n.GetMessagePrinter().Sprintf("Error reading input, error %v", err))
 	}
 	response = strings.TrimSuffix(response, "\n")
 	response = strings.ToLower(response)
 
-	if strings.TrimSpace(response) != "y" {
+	if strings.TrimSpace(response) == "" && !stdinIsTerminal() {
+		Fatal(CLI_INPUT_ERROR, i18n.GetMessagePrinter().Sprintf("%v The command needs a confirmation, but the answer read from stdin is empty. Specify --yes or set %v=true to skip the confirmation, or the --force flag of the command.", question, NONINTERACTIVE_ENV))
+	} else if strings.TrimSpace(response) != "y" {
+		if isTrying() {
+			panic(newCLIError(0, i18n.GetMessagePrinter().Sprintf("The removal was not confirmed.")))
+		}
 		i18n.GetMessagePrinter().Printf("Exiting.")
 		i18n.GetMessagePrinter().Println()
-		os.Exit(0)
+		exitFunc(0)
 	}
 }
 
@@ -464,8 +535,12 @@ func RequiredWithDefaultEnvVar(flag *string, envVarName, errMsg string) *string
 	return flag // won't ever happen, here just to make intellij happy
 }
 
-// GetHorizonUrlBase returns the base part of the horizon api url (which can be overridden by env var HORIZON_URL)
+// GetHorizonUrlBase returns the base part of the horizon api url (which can be overridden by the --horizon-url flag or env var HORIZON_URL)
+// The url can also be the unix domain socket of the api, e.g. unix:///var/run/horizon/horizon.sock
 func GetHorizonUrlBase() string {
+	if Opts.HorizonUrl != nil && *Opts.HorizonUrl != "" {
+		return strings.TrimSuffix(*Opts.HorizonUrl, "/")
+	}
 	envVar := os.Getenv("HORIZON_URL")
 	if envVar != "" {
 		return envVar
@@ -487,17 +562,82 @@ func GetAgbotUrlBase() string {
 	return GetHorizonUrlBase()
 }
 
-// GetRespBodyAsString converts an http response body to a string
+// UseAgbotUrlBase sends the horizon api calls of the rest of this invocation to the agbot api, for the agbot commands.
+func UseAgbotUrlBase() {
+	agbotUrl := GetAgbotUrlBase()
+	if err := os.Setenv("HORIZON_URL", agbotUrl); err != nil {
+		Fatal(CLI_GENERAL_ERROR, i18n.GetMessagePrinter().Sprintf("unable to set env var 'HORIZON_URL', error %v", err))
+	}
+	if Opts.HorizonUrl != nil {
+		*Opts.HorizonUrl = agbotUrl
+	}
+}
+
+// GetMaxResponseBodySize returns the maximum number of bytes of an http response body that will be read into memory.
+// It can be set with HZN_HTTP_MAX_BODY_SIZE, to protect edge devices with little memory.
+func GetMaxResponseBodySize() int64 {
+	if maxSize := os.Getenv("HZN_HTTP_MAX_BODY_SIZE"); maxSize != "" {
+		if size, err := strconv.ParseInt(maxSize, 10, 64); err == nil && size > 0 {
+			return size
+		} else {
+			Warning(i18n.GetMessagePrinter().Sprintf("Unable to use HZN_HTTP_MAX_BODY_SIZE to set the maximum response body size, the value is not a valid positive number: %v", maxSize))
+		}
+	}
+	return DEFAULT_MAX_RESPONSE_BODY_SIZE
+}
+
+// Read at most limit bytes from the body. The returned bool is true if the body is larger than limit and was truncated.
+func readLimitedBody(body io.Reader, limit int64) ([]byte, bool, error) {
+	bodyBytes, err := ioutil.ReadAll(io.LimitReader(body, limit+1))
+	if int64(len(bodyBytes)) > limit {
+		return bodyBytes[:limit], true, err
+	}
+	return bodyBytes, false, err
+}
+
+// ReadRespBody reads the body of a successful http response. It exits with an error if the body can not be read or if
+// it is larger than GetMaxResponseBodySize, rather than buffering an arbitrarily large response.
+func ReadRespBody(body io.Reader, apiMsg string) []byte {
+	bodyBytes, err := readRespBody(body, apiMsg)
+	exitOnError(err)
+	return bodyBytes
+}
+
+// ReadRespBody returning the error instead of exiting.
+func readRespBody(body io.Reader, apiMsg string) ([]byte, error) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	maxSize := GetMaxResponseBodySize()
+	bodyBytes, truncated, err := readLimitedBody(body, maxSize)
+	if err != nil {
+		return nil, newCLIError(HTTP_ERROR, msgPrinter.Sprintf("failed to read body response from %s: %v", apiMsg, err))
+	} else if truncated {
+		return nil, newCLIError(HTTP_ERROR, msgPrinter.Sprintf("the body response from %s is larger than the maximum of %d bytes. Set HZN_HTTP_MAX_BODY_SIZE to allow a larger response.", apiMsg, maxSize))
+	}
+	return bodyBytes, nil
+}
+
+// GetRespBodyAsString converts an http response body to a string. The body can be at most GetMaxResponseBodySize bytes.
 func GetRespBodyAsString(responseBody io.ReadCloser) string {
+	body, err := getRespBodyAsString(responseBody)
+	exitOnError(err)
+	return body
+}
+
+// GetRespBodyAsString returning the error instead of exiting.
+func getRespBodyAsString(responseBody io.ReadCloser) (string, error) {
 	if responseBody == nil {
-		return ""
+		return "", nil
 	}
 
-	buf := new(bytes.Buffer)
-	if _, err := buf.ReadFrom(responseBody); err != nil {
-		Fatal(HTTP_ERROR, i18n.GetMessagePrinter().Sprintf("Error reading HTTP response, error %v", err))
+	maxSize := GetMaxResponseBodySize()
+	bodyBytes, truncated, err := readLimitedBody(responseBody, maxSize)
+	if err != nil {
+		return "", newCLIError(HTTP_ERROR, i18n.GetMessagePrinter().Sprintf("Error reading HTTP response, error %v", err))
+	} else if truncated {
+		return "", newCLIError(HTTP_ERROR, i18n.GetMessagePrinter().Sprintf("the HTTP response is larger than the maximum of %d bytes. Set HZN_HTTP_MAX_BODY_SIZE to allow a larger response.", maxSize))
 	}
-	return buf.String()
+	return string(bodyBytes), nil
 }
 
 func isGoodCode(actualHttpCode int, goodHttpCodes []int) bool {
@@ -512,7 +652,8 @@ func isGoodCode(actualHttpCode int, goodHttpCodes []int) bool {
 	return false
 }
 
-func printHorizonRestError(apiMethod string, err error) {
+// The error of a request to the anax api that could not be sent.
+func horizonRestError(apiMethod string, err error) *CLIError {
 	msg := ""
 	if os.Getenv("HORIZON_URL") == "" {
 		statusCommand := "systemctl status horizon"
@@ -524,77 +665,118 @@ func printHorizonRestError(apiMethod string, err error) {
 	} else {
 		msg = i18n.GetMessagePrinter().Sprintf("Can't connect to the Horizon REST API to run %s. Maybe the ssh tunnel associated with that port is down? Or maybe the remote Horizon agent at the other end of that tunnel is down. Specific error is: %v", apiMethod, err)
 	}
-	Fatal(HTTP_ERROR, msg)
+	return newCLIError(HTTP_ERROR, msg)
 }
 
-// HorizonGet runs a GET on the anax api and fills in the specified structure with the json.
+// HorizonGet runs a GET on the anax api and fills in the specified structure with the json. An io.Writer gets the
+// body streamed to it.
 // If the list of goodHttpCodes is not empty and none match the actual http code, it will exit with an error. Otherwise the actual code is returned.
 // Only if the actual code matches the 1st element in goodHttpCodes, will it parse the body into the specified structure.
 // If quiet is true, then the error will be returned, the function returns back to the caller instead of exiting out.
 func HorizonGet(urlSuffix string, goodHttpCodes []int, structure interface{}, quiet bool) (httpCode int, retError error) {
-	retError = nil
+	httpCode, retError = horizonGet(urlSuffix, goodHttpCodes, structure, quiet)
+	if !quiet {
+		exitOnError(retError)
+	}
+	return
+}
 
+// HorizonGetE is HorizonGet returning an error instead of exiting.
+func HorizonGetE(urlSuffix string, goodHttpCodes []int, structure interface{}) (httpCode int, err error) {
+	return horizonGet(urlSuffix, goodHttpCodes, structure, false)
+}
+
+// HorizonGet returning the error instead of exiting. The messages of the errors are the ones of the quiet mode if
+// quiet is true.
+func horizonGet(urlSuffix string, goodHttpCodes []int, structure interface{}, quiet bool) (httpCode int, retError error) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	httpClient := GetHTTPClient(0)
+	httpClient, err := newHTTPClient(config.HTTPRequestTimeoutS)
+	if err != nil {
+		return 0, newCLIError(CLI_INPUT_ERROR, err.Error())
+	}
+	var idleTimeout time.Duration
+	if _, ok := structure.(io.Writer); ok {
+		idleTimeout = downloadClient(httpClient)
+	}
 
-	url := GetHorizonUrlBase() + "/" + urlSuffix
+	url := horizonApiUrl(urlSuffix)
 	apiMsg := http.MethodGet + " " + url
 	Verbose(apiMsg)
-	// Create the request and run it
-	req, err := http.NewRequest(http.MethodGet, url, nil)
-	if err != nil {
-		Fatal(HTTP_ERROR, msgPrinter.Sprintf("%s new request failed: %v", apiMsg, err))
-	}
-	req.Close = true
-	req.Header.Add("Accept", "application/json")
 
-	// add the language request to the http header
-	localeTag, err := i18n.GetLocale()
+	// get retry count and retry interval from env
+	maxRetries, retryInterval, err := GetHttpRetryParameters(5, 2)
 	if err != nil {
-		localeTag = language.English
+		return 0, newCLIError(CLI_GENERAL_ERROR, err.Error())
 	}
-	req.Header.Add("Accept-Language", localeTag.String())
 
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		if quiet {
-			if os.Getenv("HORIZON_URL") == "" {
-				statusCommand := "systemctl status horizon"
-				statusURL := "curl http://localhost:8081/status"
-				if runtime.GOOS == "darwin" {
-					statusCommand = "docker ps | grep horizon"
-				}
-				retError = fmt.Errorf(msgPrinter.Sprintf("Can't connect to the Horizon REST API to run %s. Run '%s' to check if the Horizon agent is running. Or run '%s' to check the Horizon agent status. Or set HORIZON_URL to connect to another local port that is connected to a remote Horizon agent via a ssh tunnel. Specific error is: %v", apiMsg, statusCommand, statusURL, err))
-			} else {
-				retError = fmt.Errorf(msgPrinter.Sprintf("Can't connect to the Horizon REST API to run %s. Maybe the ssh tunnel associated with that port is down? Or maybe the remote Horizon agent at the other end of that tunnel is down. Specific error is: %v", apiMsg, err))
+	var resp *http.Response
+	for retryCount := 1; ; retryCount++ {
+		// Create the request and run it
+		req, reqErr := newRequest(http.MethodGet, url, nil)
+		if reqErr != nil {
+			return 0, newCLIError(HTTP_ERROR, msgPrinter.Sprintf("%s new request failed: %v", apiMsg, reqErr))
+		}
+		req.Header.Add("Accept", "application/json")
+
+		// add the language request to the http header
+		localeTag, localeErr := i18n.GetLocale()
+		if localeErr != nil {
+			localeTag = language.English
+		}
+		req.Header.Add("Accept-Language", localeTag.String())
+
+		resp, err = httpClient.Do(req)
+		if isRetryableHorizonError(resp, err) && retryCount <= maxRetries && !RetryBudgetExceeded(retryInterval, retryCount) {
+			httpStatus := ""
+			if resp != nil {
+				httpStatus = resp.Status
+				resp.Body.Close()
 			}
-			return
-		} else {
-			printHorizonRestError(apiMsg, err)
+			delay := RetryDelay(retryInterval, retryCount)
+			Verbose(msgPrinter.Sprintf("Encountered HTTP error: %v calling Horizon REST API %v. HTTP status: %v. Will retry in %v.", err, apiMsg, httpStatus, delay))
+			sleepUnlessInterrupted(delay)
+			continue
 		}
+		break
+	}
+	if err != nil {
+		return 0, horizonRestError(apiMsg, err)
 	}
 	defer resp.Body.Close()
 	httpCode = resp.StatusCode
 	Verbose(msgPrinter.Sprintf("HTTP code: %d", httpCode))
 	if !isGoodCode(httpCode, goodHttpCodes) {
+		errBody := GetErrorRespBody(resp)
 		if quiet {
-			retError = fmt.Errorf(msgPrinter.Sprintf("Bad HTTP code from %s: %d", apiMsg, httpCode))
-			return
-		} else {
-			Fatal(HTTP_ERROR, msgPrinter.Sprintf("bad HTTP code from %s: %d", apiMsg, httpCode))
+			return httpCode, newCLIError(HTTP_ERROR, msgPrinter.Sprintf("Bad HTTP code from %s: %d, %s", apiMsg, httpCode, errBody))
 		}
+		return httpCode, newCLIError(HTTP_ERROR, msgPrinter.Sprintf("bad HTTP code from %s: %d, %s", apiMsg, httpCode, errBody))
 	}
 	if httpCode == goodHttpCodes[0] {
-		bodyBytes, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
+		readErr := func(err error) *CLIError {
 			if quiet {
-				retError = fmt.Errorf(msgPrinter.Sprintf("Failed to read body response from %s: %v", apiMsg, err))
-				return
-			} else {
-				Fatal(HTTP_ERROR, msgPrinter.Sprintf("failed to read body response from %s: %v", apiMsg, err))
+				return newCLIError(HTTP_ERROR, msgPrinter.Sprintf("Failed to read body response from %s: %v", apiMsg, err))
+			}
+			return newCLIError(HTTP_ERROR, msgPrinter.Sprintf("failed to read body response from %s: %v", apiMsg, err))
+		}
+
+		// A writer is the signal that they want the body streamed to it, so the size of the body is not limited
+		if w, ok := structure.(io.Writer); ok {
+			startDownload(w, resp.ContentLength)
+			if _, err := newIdleTimeoutReader(resp.Body, resp.Body, idleTimeout).copyTo(w); err != nil {
+				return httpCode, readErr(err)
 			}
+			return
+		}
+
+		bodyBytes, truncated, err := readLimitedBody(resp.Body, GetMaxResponseBodySize())
+		if err == nil && truncated {
+			err = errors.New(msgPrinter.Sprintf("the body is larger than the maximum of %d bytes, set HZN_HTTP_MAX_BODY_SIZE to allow a larger response", GetMaxResponseBodySize()))
+		}
+		if err != nil {
+			return httpCode, readErr(err)
 		}
 		switch s := structure.(type) {
 		case *string:
@@ -602,24 +784,78 @@ func HorizonGet(urlSuffix string, goodHttpCodes []int, structure interface{}, qu
 			*s = string(bodyBytes)
 		default:
 			// Put the response body in the specified struct
-			err = json.Unmarshal(bodyBytes, structure)
-			if err != nil {
+			if err := json.Unmarshal(bodyBytes, structure); err != nil {
 				if quiet {
-					retError = fmt.Errorf(msgPrinter.Sprintf("Failed to unmarshal body response from %s: %v", apiMsg, err))
-					return
-				} else {
-					Fatal(JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal body response from %s: %v", apiMsg, err))
+					return httpCode, newCLIError(JSON_PARSING_ERROR, msgPrinter.Sprintf("Failed to unmarshal body response from %s: %v", apiMsg, err))
 				}
+				return httpCode, newCLIError(JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal body response from %s: %v", apiMsg, err))
 			}
 		}
 	}
 	return
 }
 
+// HorizonStream runs a GET on the anax api and copies the body to w as it is received. Unlike HorizonGet the request
+// has no timeout and is not retried, so that a body that does not end, like followed logs, is streamed until the
+// command is interrupted, which is not an error. The error is returned when the http code is not 200.
+func HorizonStream(urlSuffix string, w io.Writer) (httpCode int, retError error) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	httpClient := GetHTTPClient(config.HTTPRequestTimeoutS)
+	httpClient.Timeout = 0
+
+	url := horizonApiUrl(urlSuffix)
+	apiMsg := http.MethodGet + " " + url
+	Verbose(apiMsg)
+
+	req, err := newRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf(msgPrinter.Sprintf("%s new request failed: %v", apiMsg, err))
+	}
+	if localeTag, err := i18n.GetLocale(); err == nil {
+		req.Header.Add("Accept-Language", localeTag.String())
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf(msgPrinter.Sprintf("Can't connect to the Horizon REST API to run %s. Specific error is: %v", apiMsg, err))
+	}
+	defer resp.Body.Close()
+	httpCode = resp.StatusCode
+	Verbose(msgPrinter.Sprintf("HTTP code: %d", httpCode))
+	if httpCode != http.StatusOK {
+		return httpCode, errors.New(msgPrinter.Sprintf("Bad HTTP code from %s: %d, %s", apiMsg, httpCode, GetErrorRespBody(resp)))
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil && Context().Err() == nil {
+		return httpCode, fmt.Errorf(msgPrinter.Sprintf("Failed to read body response from %s: %v", apiMsg, err))
+	}
+	return httpCode, nil
+}
+
 // HorizonDelete runs a DELETE on the anax api.
 // If the list of goodHttpCodes is not empty and none match the actual http code, it will exit with an error. Otherwise the actual code is returned.
 func HorizonDelete(urlSuffix string, goodHttpCodes []int, expectedHttpErrorCodes []int, quiet bool) (httpCode int, retError error) {
-	url := GetHorizonUrlBase() + "/" + urlSuffix
+	httpCode, retError = horizonDelete(urlSuffix, goodHttpCodes, expectedHttpErrorCodes)
+	if _, failed := retError.(*CLIError); failed && !quiet {
+		exitOnError(retError)
+	}
+	return
+}
+
+// HorizonDeleteE is HorizonDelete returning an error instead of exiting. An http code in expectedHttpErrorCodes is not
+// an error.
+func HorizonDeleteE(urlSuffix string, goodHttpCodes []int, expectedHttpErrorCodes []int) (httpCode int, err error) {
+	httpCode, err = horizonDelete(urlSuffix, goodHttpCodes, expectedHttpErrorCodes)
+	if _, failed := err.(*CLIError); !failed {
+		err = nil
+	}
+	return
+}
+
+// HorizonDelete returning the error instead of exiting. A failure is a CLIError, an http code in
+// expectedHttpErrorCodes is returned as an error with the body of the response.
+func horizonDelete(urlSuffix string, goodHttpCodes []int, expectedHttpErrorCodes []int) (httpCode int, retError error) {
+	url := horizonApiUrl(urlSuffix)
 	apiMsg := http.MethodDelete + " " + url
 
 	// get message printer
@@ -629,35 +865,18 @@ func HorizonDelete(urlSuffix string, goodHttpCodes []int, expectedHttpErrorCodes
 	if IsDryRun() {
 		return 204, nil
 	}
-	httpClient := GetHTTPClient(0)
-	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	httpClient, err := newHTTPClient(config.HTTPRequestTimeoutS)
 	if err != nil {
-		if quiet {
-			retError = fmt.Errorf(msgPrinter.Sprintf("%s new request failed: %v", apiMsg, err))
-			return
-		} else {
-			Fatal(HTTP_ERROR, msgPrinter.Sprintf("%s new request failed: %v", apiMsg, err))
-		}
+		return 0, newCLIError(CLI_INPUT_ERROR, err.Error())
+	}
+	req, err := newRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		return 0, newCLIError(HTTP_ERROR, msgPrinter.Sprintf("%s new request failed: %v", apiMsg, err))
 	}
-	req.Close = true
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		if quiet {
-			if os.Getenv("HORIZON_URL") == "" {
-				statusCommand := "systemctl status horizon"
-				statusURL := "curl http://localhost:8081/status"
-				if runtime.GOOS == "darwin" {
-					statusCommand = "docker ps | grep horizon"
-				}
-				retError = fmt.Errorf(msgPrinter.Sprintf("Can't connect to the Horizon REST API to run %s. Run '%s' to check if the Horizon agent is running. Or run '%s' to check the Horizon agent status. Or set HORIZON_URL to connect to another local port that is connected to a remote Horizon agent via a ssh tunnel. Specific error is: %v", apiMsg, statusCommand, statusURL, err))
-			} else {
-				retError = fmt.Errorf(msgPrinter.Sprintf("Can't connect to the Horizon REST API to run %s. Maybe the ssh tunnel associated with that port is down? Or maybe the remote Horizon agent at the other end of that tunnel is down. Specific error is: %v", apiMsg, err))
-			}
-			return
-		} else {
-			printHorizonRestError(apiMsg, err)
-		}
+		return 0, horizonRestError(apiMsg, err)
 	}
 	defer resp.Body.Close()
 	httpCode = resp.StatusCode
@@ -665,77 +884,100 @@ func HorizonDelete(urlSuffix string, goodHttpCodes []int, expectedHttpErrorCodes
 	if isGoodCode(httpCode, goodHttpCodes) {
 		return
 	} else if isGoodCode(httpCode, expectedHttpErrorCodes) {
-		err_msg := GetRespBodyAsString(resp.Body)
-		retError = fmt.Errorf(err_msg)
-		return
-	} else {
-		err_msg := msgPrinter.Sprintf("bad HTTP code %d from %s: %s", httpCode, apiMsg, GetRespBodyAsString(resp.Body))
-		if quiet {
-			retError = fmt.Errorf(err_msg)
-			return
-		} else {
-			Fatal(HTTP_ERROR, err_msg)
-		}
+		return httpCode, errors.New(GetErrorRespBody(resp))
 	}
-	return
+	return httpCode, newCLIError(HTTP_ERROR, msgPrinter.Sprintf("bad HTTP code %d from %s: %s", httpCode, apiMsg, GetErrorRespBody(resp)))
 }
 
-// HorizonPutPost runs a PUT or POST to the anax api to create or update a resource.
+// HorizonPutPost runs a PUT or POST to the anax api to create or update a resource. The body is a []byte or string that
+// is sent as is, an io.Reader or *MultipartBody that is streamed, or a struct that is sent as json.
 // If the list of goodHttpCodes is not empty and none match the actual http code, it will exit with an error. Otherwise the actual code is returned.
 func HorizonPutPost(method string, urlSuffix string, goodHttpCodes []int, body interface{}, exitOnErr bool) (httpCode int, resp_body string, err error) {
-	url := GetHorizonUrlBase() + "/" + urlSuffix
+	httpCode, resp_body, err = horizonPutPost(method, urlSuffix, goodHttpCodes, body)
+	if err != nil {
+		if exitOnErr {
+			exitOnError(err)
+		}
+		return 0, "", err
+	}
+	return
+}
+
+// HorizonPutPostE is HorizonPutPost returning an error instead of exiting.
+func HorizonPutPostE(method string, urlSuffix string, goodHttpCodes []int, body interface{}) (httpCode int, respBody string, err error) {
+	return horizonPutPost(method, urlSuffix, goodHttpCodes, body)
+}
+
+// HorizonPutPost returning the error instead of exiting.
+func horizonPutPost(method string, urlSuffix string, goodHttpCodes []int, body interface{}) (httpCode int, resp_body string, err error) {
+	url := horizonApiUrl(urlSuffix)
 	apiMsg := method + " " + url
 	Verbose(apiMsg)
 	if IsDryRun() {
 		return 201, "", nil
 	}
-	httpClient := GetHTTPClient(0)
+	httpClient, err := newHTTPClient(config.HTTPRequestTimeoutS)
+	if err != nil {
+		return 0, "", newCLIError(CLI_INPUT_ERROR, err.Error())
+	}
 
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
 	// Prepare body
-	var jsonBytes []byte
-	bodyIsBytes := false
+	var requestBody io.Reader
+	contentLength := int64(-1)
+	contentType := ""
 	switch b := body.(type) {
 	// If the body is a byte array or string, we treat it like a file being uploaded (not multi-part)
 	case []byte:
-		jsonBytes = b
-		bodyIsBytes = true
+		requestBody = bytes.NewReader(b)
+		contentLength = int64(len(b))
 	case string:
-		jsonBytes = []byte(b)
-		bodyIsBytes = true
+		requestBody = strings.NewReader(b)
+		contentLength = int64(len(b))
+	// A multipart body and a reader are streamed, so that a large file is not loaded in memory. A file is sent with
+	// its size, any other reader is sent in chunks.
+	case *MultipartBody:
+		if requestBody, contentType, err = b.reader(); err != nil {
+			return 0, "", newCLIError(FILE_IO_ERROR, msgPrinter.Sprintf("failed to prepare the body for %s: %v", apiMsg, err))
+		}
+	case *os.File:
+		requestBody = b
+		if info, err := b.Stat(); err == nil && info.Mode().IsRegular() {
+			contentLength = info.Size()
+		}
+	case io.Reader:
+		requestBody = b
 	// Else it is a struct so assume it should be sent as json
 	default:
-		var err error
-		jsonBytes, err = json.Marshal(body)
-		if exitOnErr && err != nil {
-			Fatal(JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal body for %s: %v", apiMsg, err))
-		} else if err != nil {
-			return 0, "", err
+		jsonBytes, err := json.Marshal(body)
+		if err != nil {
+			return 0, "", newCLIError(JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal body for %s: %v", apiMsg, err))
 		}
+		requestBody = bytes.NewReader(jsonBytes)
+		contentLength = int64(len(jsonBytes))
+		contentType = "application/json"
 	}
-	requestBody := bytes.NewBuffer(jsonBytes)
 
 	// Create the request and run it
-	req, err := http.NewRequest(method, url, requestBody)
-	if exitOnErr && err != nil {
-		Fatal(HTTP_ERROR, msgPrinter.Sprintf("%s new request failed: %v", apiMsg, err))
-	} else if err != nil {
-		return 0, "", err
+	req, err := newRequest(method, url, requestBody)
+	if err != nil {
+		return 0, "", newCLIError(HTTP_ERROR, msgPrinter.Sprintf("%s new request failed: %v", apiMsg, err))
 	}
-	req.Close = true
 	req.Header.Add("Accept", "application/json")
-	if bodyIsBytes {
-		req.Header.Add("Content-Length", strconv.Itoa(len(jsonBytes)))
+	if contentType != "" {
+		req.Header.Add("Content-Type", contentType)
+	}
+	if contentLength >= 0 {
+		req.ContentLength = contentLength
+		req.Header.Add("Content-Length", strconv.FormatInt(contentLength, 10))
 	} else {
-		req.Header.Add("Content-Type", "application/json")
+		req.ContentLength = -1
 	}
 	resp, err := httpClient.Do(req)
-	if err != nil && exitOnErr {
-		printHorizonRestError(apiMsg, err)
-	} else if err != nil {
-		return 0, "", err
+	if err != nil {
+		return 0, "", horizonRestError(apiMsg, err)
 	}
 
 	// Process the response
@@ -743,17 +985,25 @@ func HorizonPutPost(method string, urlSuffix string, goodHttpCodes []int, body i
 	httpCode = resp.StatusCode
 	Verbose(msgPrinter.Sprintf("HTTP code: %d", httpCode))
 
-	resp_body = GetRespBodyAsString(resp.Body)
 	if !isGoodCode(httpCode, goodHttpCodes) {
-		if exitOnErr {
-			Fatal(HTTP_ERROR, msgPrinter.Sprintf("bad HTTP code %d from %s: %s", httpCode, apiMsg, resp_body))
-		} else {
-			return 0, "", fmt.Errorf(msgPrinter.Sprintf("bad HTTP code %d from %s: %s", httpCode, apiMsg, resp_body))
-		}
+		resp_body = GetErrorRespBody(resp)
+		return httpCode, resp_body, newCLIError(HTTP_ERROR, msgPrinter.Sprintf("bad HTTP code %d from %s: %s", httpCode, apiMsg, resp_body))
 	}
+	resp_body, err = getRespBodyAsString(resp.Body)
 	return
 }
 
+// HorizonPatch runs a PATCH on the anax api to change some of the fields of a resource, with the same goodHttpCodes and
+// exitOnErr semantics as HorizonPutPost.
+func HorizonPatch(urlSuffix string, goodHttpCodes []int, patch interface{}, exitOnErr bool) (httpCode int, resp_body string, err error) {
+	return HorizonPutPost(http.MethodPatch, urlSuffix, goodHttpCodes, patch, exitOnErr)
+}
+
+// HorizonPatchE is HorizonPatch returning an error instead of exiting.
+func HorizonPatchE(urlSuffix string, goodHttpCodes []int, patch interface{}) (httpCode int, respBody string, err error) {
+	return horizonPutPost(http.MethodPatch, urlSuffix, goodHttpCodes, patch)
+}
+
 // get a value keyed by key in a file. The file contains key=value for each line.
 func GetEnvVarFromFile(filename string, key string) (string, error) {
 	fHandle, err := os.Open(filename)
@@ -812,10 +1062,12 @@ func GetAnaxConfig(configFile string) (*config.HorizonConfig, error) {
 }
 
 // GetIcpCertPath gets the path of the certificate for the exchange and CSS (collectively referred to as the
-// management hub). The variable name changed, so it could be the old one or the new one from the '/etc/default/horizon' file.
-// If the env var is not set or the field is not found an empty string is returned.
+// management hub). HORIZON_EXCHANGE_CA_CERT takes precedence. The variable name changed, so it could be the old one or the
+// new one from the '/etc/default/horizon' file. If the env var is not set or the field is not found an empty string is returned.
 func GetIcpCertPath() string {
-	if value := os.Getenv(config.OldMgmtHubCertPath); value != "" {
+	if value := os.Getenv(EXCHANGE_CA_CERT_ENV); value != "" {
+		return value
+	} else if value := os.Getenv(config.OldMgmtHubCertPath); value != "" {
 		return value
 	} else if value := os.Getenv(config.ManagementHubCertPath); value != "" {
 		return value
@@ -831,24 +1083,76 @@ func GetIcpCertPath() string {
 	return ""
 }
 
-//TrustIcpCert adds the icp cert file to be trusted in calls made by the given http client
+//TrustIcpCert adds the certs in the icp cert file to the system CAs trusted in calls made by the given http client
 func TrustIcpCert(httpClient *http.Client) error {
+	if st := findSharedTransport(httpClient); st != nil {
+		return st.icpCertErr
+	}
+	return trustIcpCert(unwrapTransport(httpClient.Transport).(*http.Transport).TLSClientConfig)
+}
+
+func trustIcpCert(tlsConfig *tls.Config) error {
 	icpCertPath := GetIcpCertPath()
 	if icpCertPath != "" {
 		icpCert, err := ioutil.ReadFile(icpCertPath)
 		if err != nil {
 			return fmt.Errorf(i18n.GetMessagePrinter().Sprintf("Encountered error reading ICP cert file %v: %v", icpCertPath, err))
 		}
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(icpCert)
 
-		transport := httpClient.Transport.(*http.Transport)
-		transport.TLSClientConfig.RootCAs = caCertPool
+		// the bundle is added to the system CAs, so that a hub with a public certificate is still trusted
+		caCertPool, err := x509.SystemCertPool()
+		if err != nil || caCertPool == nil {
+			caCertPool = x509.NewCertPool()
+		}
+		if !caCertPool.AppendCertsFromPEM(icpCert) {
+			return fmt.Errorf(i18n.GetMessagePrinter().Sprintf("The CA cert file %v does not contain any PEM encoded certificate", icpCertPath))
+		}
+
+		tlsConfig.RootCAs = caCertPool
 
 	}
 	return nil
 }
 
+// GetClientCertPaths returns the files of the client certificate and key that are presented to the management hub
+// during the TLS handshake. The --client-cert and --client-key flags take precedence over the env vars.
+func GetClientCertPaths() (string, string) {
+	certPath := os.Getenv(EXCHANGE_CLIENT_CERT_ENV)
+	if Opts.ClientCert != nil && *Opts.ClientCert != "" {
+		certPath = *Opts.ClientCert
+	}
+	keyPath := os.Getenv(EXCHANGE_CLIENT_KEY_ENV)
+	if Opts.ClientKey != nil && *Opts.ClientKey != "" {
+		keyPath = *Opts.ClientKey
+	}
+	return certPath, keyPath
+}
+
+// UseClientCert makes the http client present the client certificate, if there is one, to the servers that ask for it.
+func UseClientCert(httpClient *http.Client) error {
+	if st := findSharedTransport(httpClient); st != nil {
+		return st.clientCertErr
+	}
+	return useClientCert(unwrapTransport(httpClient.Transport).(*http.Transport).TLSClientConfig)
+}
+
+func useClientCert(tlsConfig *tls.Config) error {
+	certPath, keyPath := GetClientCertPaths()
+	if certPath == "" && keyPath == "" {
+		return nil
+	} else if certPath == "" || keyPath == "" {
+		return fmt.Errorf(i18n.GetMessagePrinter().Sprintf("Both the client certificate and key must be specified, with --client-cert and --client-key or %v and %v", EXCHANGE_CLIENT_CERT_ENV, EXCHANGE_CLIENT_KEY_ENV))
+	}
+
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return fmt.Errorf(i18n.GetMessagePrinter().Sprintf("Unable to load the client certificate %v and key %v: %v", certPath, keyPath, err))
+	}
+
+	tlsConfig.Certificates = []tls.Certificate{cert}
+	return nil
+}
+
 // Get exchange url from /etc/default/horizon file. if not set, check /etc/horizon/anax.json file
 func GetExchangeUrlFromAnax() string {
 	if value, err := GetEnvVarFromFile(ANAX_OVERWRITE_FILE, "HZN_EXCHANGE_URL"); err != nil {
@@ -883,58 +1187,60 @@ func GetExchangeUrlLocationFromAnax() string {
 	return ""
 }
 
-// GetExchangeUrl returns the exchange url from the env var or anax api
-func GetExchangeUrl() string {
-	exchUrl := os.Getenv("HZN_EXCHANGE_URL")
+// The exchange url and where it came from. They are resolved once per invocation of the command by resolveExchangeUrl.
+var cachedExchUrl, cachedExchUrlLoc string
+
+// ResetExchangeUrl clears the resolved exchange url, so that the next command resolves it again.
+func ResetExchangeUrl() {
+	cachedExchUrl, cachedExchUrlLoc = "", ""
+	setExchangeMirrors("")
+}
+
+// Resolve the exchange url from the --exchange-url flag, the HZN_EXCHANGE_URL or HORIZON_EXCHANGE_URL_BASE env var or the horizon agent configuration files, in that order.
+// The url can be a comma separated list of mirrored exchanges, the one returned is the mirror that the requests are sent to.
+func resolveExchangeUrl() (string, string) {
+	if cachedExchUrl != "" {
+		return currentExchangeMirror(), cachedExchUrlLoc
+	}
 
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	if exchUrl == "" {
-		Verbose(msgPrinter.Sprintf("HZN_EXCHANGE_URL is not set, get it from horizon agent configuration on the node."))
-		value := GetExchangeUrlFromAnax()
-		if value != "" {
-			exchUrl = value
-		} else {
-			Fatal(CLI_GENERAL_ERROR, msgPrinter.Sprintf("Could not get the Exchange url from environment variable HZN_EXCHANGE_URL or the horizon agent"))
+	exchUrl := ""
+	exchUrlLoc := ""
+	if Opts.ExchangeUrl != nil && *Opts.ExchangeUrl != "" {
+		exchUrl = *Opts.ExchangeUrl
+		exchUrlLoc = "--exchange-url"
+	} else if exchUrl = os.Getenv("HZN_EXCHANGE_URL"); exchUrl != "" {
+		exchUrlLoc = "HZN_EXCHANGE_URL"
+	} else if exchUrl = os.Getenv(EXCHANGE_URL_BASE_ENV); exchUrl != "" {
+		exchUrlLoc = EXCHANGE_URL_BASE_ENV
+	} else {
+		Verbose(msgPrinter.Sprintf("HZN_EXCHANGE_URL and %v are not set, get it from horizon agent configuration on the node.", EXCHANGE_URL_BASE_ENV))
+		exchUrl = GetExchangeUrlFromAnax()
+		if exchUrl == "" {
+			Fatal(CLI_GENERAL_ERROR, msgPrinter.Sprintf("Could not get the Exchange url from environment variable HZN_EXCHANGE_URL or %v, or the horizon agent", EXCHANGE_URL_BASE_ENV))
 		}
+		exchUrlLoc = GetExchangeUrlLocationFromAnax()
 	}
 
-	exchUrl = strings.TrimSuffix(exchUrl, "/")                 // anax puts a trailing slash on it
-	if Opts.UsingApiKey || os.Getenv("USING_API_KEY") == "1" { //todo: remove because this was for WIoTP keys that shouldn't have the org prepended
-		re := regexp.MustCompile(`edgenode$`)
-		exchUrl = re.ReplaceAllLiteralString(exchUrl, "edge")
-	}
+	cachedExchUrl = setExchangeMirrors(exchUrl) // anax puts a trailing slash on it, which is removed
+	cachedExchUrlLoc = exchUrlLoc
+	return cachedExchUrl, cachedExchUrlLoc
+}
 
-	Verbose(msgPrinter.Sprintf("The exchange url: %v", exchUrl))
+// GetExchangeUrl returns the exchange url from the --exchange-url flag, the env var or the anax configuration
+func GetExchangeUrl() string {
+	exchUrl, _ := resolveExchangeUrl()
+
+	exchUrl = wiotpUrl(exchUrl)
+	Verbose(i18n.GetMessagePrinter().Sprintf("The exchange url: %v", exchUrl))
 	return exchUrl
 }
 
-// GetExchangeUrlLocation returns a string with the filename or envvar that GetExchangeUrl is getting the exchange url from
+// GetExchangeUrlLocation returns a string with the flag, envvar or filename that GetExchangeUrl is getting the exchange url from
 func GetExchangeUrlLocation() string {
-
-	// get message printer
-	msgPrinter := i18n.GetMessagePrinter()
-
-	exchUrlLoc := "HZN_EXCHANGE_URL"
-	exchUrl := os.Getenv("HZN_EXCHANGE_URL")
-	if exchUrl == "" {
-		Verbose(msgPrinter.Sprintf("HZN_EXCHANGE_URL is not set, get it from horizon agent configuration on the node."))
-		location := GetExchangeUrlLocationFromAnax()
-		if location != "" {
-			exchUrlLoc = location
-		} else {
-			Fatal(CLI_GENERAL_ERROR, msgPrinter.Sprintf("Could not get the Exchange url from environment variable HZN_EXCHANGE_URL or the horizon agent"))
-		}
-	}
-
-	exchUrl = strings.TrimSuffix(exchUrl, "/")                 // anax puts a trailing slash on it
-	if Opts.UsingApiKey || os.Getenv("USING_API_KEY") == "1" { // todo: remove because this was for WIoTP keys that shouldn't have the org prepended
-		re := regexp.MustCompile(`edgenode$`)
-		exchUrl = re.ReplaceAllLiteralString(exchUrl, "edge")
-	}
-
-	Verbose(msgPrinter.Sprintf("The exchange url: %v", exchUrl))
+	_, exchUrlLoc := resolveExchangeUrl()
 	return exchUrlLoc
 }
 
@@ -971,11 +1277,8 @@ func GetMMSUrl() string {
 		}
 	}
 
-	mmsUrl = strings.TrimSuffix(mmsUrl, "/")                   // anax puts a trailing slash on it
-	if Opts.UsingApiKey || os.Getenv("USING_API_KEY") == "1" { // todo: remove because this was for WIoTP keys that shouldn't have the org prepended
-		re := regexp.MustCompile(`edgenode$`)
-		mmsUrl = re.ReplaceAllLiteralString(mmsUrl, "edge")
-	}
+	mmsUrl = strings.TrimSuffix(mmsUrl, "/") // anax puts a trailing slash on it
+	mmsUrl = wiotpUrl(mmsUrl)
 
 	Verbose(msgPrinter.Sprintf("The model management service url: %v", mmsUrl))
 	return mmsUrl
@@ -1001,7 +1304,12 @@ func GetSdoSvcUrl() string {
 	return sdoUrl
 }
 
-func printHorizonServiceRestError(horizonService string, apiMethod string, err error) {
+// The error of a request to a Horizon service that could not be sent.
+func horizonServiceRestError(horizonService string, apiMethod string, err error) *CLIError {
+	if IsCertificateError(err) {
+		return newCLIError(HTTP_ERROR, i18n.GetMessagePrinter().Sprintf("Can't verify the TLS certificate of the Horizon %v to run %s: %v. If the management hub uses a self-signed certificate or a certificate from a private CA, set %v to the file of the CA certificates, or use --insecure to skip the verification.", horizonService, apiMethod, err, EXCHANGE_CA_CERT_ENV))
+	}
+
 	serviceEnvVarName := "HZN_EXCHANGE_URL"
 	article := "an"
 	if horizonService == "Model Management Service" {
@@ -1013,20 +1321,29 @@ func printHorizonServiceRestError(horizonService string, apiMethod string, err e
 	}
 
 	if os.Getenv(serviceEnvVarName) == "" {
-		Fatal(HTTP_ERROR, i18n.GetMessagePrinter().Sprintf("Can't connect to the Horizon %v REST API to run %s. Set %v to use %v %v other than the one the Horizon Agent is currently configured for. Specific error is: %v", horizonService, apiMethod, serviceEnvVarName, article, horizonService, err))
-	} else {
-		Fatal(HTTP_ERROR, i18n.GetMessagePrinter().Sprintf("Can't connect to the Horizon %v REST API to run %s. Maybe %v is set incorrectly? Or unset %v to use the %v that the Horizon Agent is configured for. Specific error is: %v", horizonService, apiMethod, serviceEnvVarName, serviceEnvVarName, horizonService, err))
+		return newCLIError(HTTP_ERROR, i18n.GetMessagePrinter().Sprintf("Can't connect to the Horizon %v REST API to run %s. Set %v to use %v %v other than the one the Horizon Agent is currently configured for. Specific error is: %v", horizonService, apiMethod, serviceEnvVarName, article, horizonService, err))
 	}
+	return newCLIError(HTTP_ERROR, i18n.GetMessagePrinter().Sprintf("Can't connect to the Horizon %v REST API to run %s. Maybe %v is set incorrectly? Or unset %v to use the %v that the Horizon Agent is configured for. Specific error is: %v", horizonService, apiMethod, serviceEnvVarName, serviceEnvVarName, horizonService, err))
+}
 
+// IsCertificateError returns true if the error is the failure to verify the TLS certificate of a server.
+func IsCertificateError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var unknownAuth x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	return errors.As(err, &unknownAuth) || errors.As(err, &invalid) || errors.As(err, &hostname) || strings.Contains(err.Error(), "x509: ")
 }
 
 // creates an request body for http calls. Only PUT/PATCH/POST calls has request body.
-func createRequestBody(body interface{}, apiMsg string) (io.Reader, int, int) {
+func createRequestBody(body interface{}, apiMsg string) (io.Reader, int, int, error) {
 
 	bodyType := HTTP_REQ_BODYTYPE_DEFAULT
 
 	if body == nil {
-		return nil, 0, bodyType
+		return nil, 0, bodyType, nil
 	}
 
 	// get message printer
@@ -1049,7 +1366,7 @@ func createRequestBody(body interface{}, apiMsg string) (io.Reader, int, int) {
 		var err error
 		jsonBytes, err = json.Marshal(body)
 		if err != nil {
-			Fatal(JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal exchange body for %s: %v", apiMsg, err))
+			return nil, 0, bodyType, newCLIError(JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal exchange body for %s: %v", apiMsg, err))
 		}
 	}
 
@@ -1068,21 +1385,36 @@ func createRequestBody(body interface{}, apiMsg string) (io.Reader, int, int) {
 		bodyLen = len(jsonBytes)
 	}
 
-	return requestBody, bodyLen, bodyType
+	return requestBody, bodyLen, bodyType, nil
 }
 
 // invoke rest api call with retry
 func InvokeRestApi(httpClient *http.Client, method string, urlPath string, credentials string, body interface{}, service string, apiMsg string) *http.Response {
+	return InvokeRestApiWithHeaders(httpClient, method, urlPath, credentials, body, service, apiMsg, nil)
+}
+
+// InvokeRestApiWithHeaders is the same as InvokeRestApi, but also adds the given headers to the request.
+func InvokeRestApiWithHeaders(httpClient *http.Client, method string, urlPath string, credentials string, body interface{}, service string, apiMsg string, headers map[string]string) *http.Response {
+	resp, err := invokeRestApi(httpClient, method, urlPath, credentials, body, service, apiMsg, headers)
+	exitOnError(err)
+	return resp
+}
+
+// InvokeRestApiWithHeaders returning the error instead of exiting.
+func invokeRestApi(httpClient *http.Client, method string, urlPath string, credentials string, body interface{}, service string, apiMsg string, headers map[string]string) (*http.Response, error) {
 
 	// encode the url so that it can accept unicode
 	urlObj, errUrl := url.Parse(urlPath)
 	if errUrl != nil {
-		Fatal(CLI_INPUT_ERROR, fmt.Sprintf("Malformed URL: %v. %v", urlPath, errUrl))
+		return nil, newCLIError(CLI_INPUT_ERROR, fmt.Sprintf("Malformed URL: %v. %v", urlPath, errUrl))
 	}
 	urlObj.RawQuery = urlObj.Query().Encode()
 
 	if err := TrustIcpCert(httpClient); err != nil {
-		Fatal(FILE_IO_ERROR, err.Error())
+		return nil, newCLIError(FILE_IO_ERROR, err.Error())
+	}
+	if err := UseClientCert(httpClient); err != nil {
+		return nil, newCLIError(CLI_INPUT_ERROR, err.Error())
 	}
 
 	// get message printer
@@ -1091,15 +1423,25 @@ func InvokeRestApi(httpClient *http.Client, method string, urlPath string, crede
 	// get retry count and retry interval from env
 	maxRetries, retryInterval, err := GetHttpRetryParameters(5, 2)
 	if err != nil {
-		Fatal(CLI_GENERAL_ERROR, err.Error())
+		return nil, newCLIError(CLI_GENERAL_ERROR, err.Error())
 	}
 
+	maxRateLimitRetries := GetRateLimitRetries()
+
 	retryCount := 0
-	for {
+	rateLimitCount := 0
+	rateLimitWaited := time.Duration(0)
+	tokenRefreshed := false
+	reqUrl := exchangeMirrorUrl(urlObj.String())
+	mirrorsTried := 0
+	for attempt := 1; ; attempt++ {
 		retryCount++
 
 		// requestBody is nil if body is nil.
-		requestBody, bodyLen, bodyType := createRequestBody(body, apiMsg)
+		requestBody, bodyLen, bodyType, err := createRequestBody(body, apiMsg)
+		if err != nil {
+			return nil, err
+		}
 
 		if requestBody != nil && bodyType == HTTP_REQ_BODYTYPE_FILE && bodyLen != 0 {
 			// Calculate and show progress of file uploading
@@ -1116,12 +1458,12 @@ func InvokeRestApi(httpClient *http.Client, method string, urlPath string, crede
 			}}
 		}
 		// If we're retrying with an os.File body, then re-open it.
-		if retryCount > 1 && body != nil {
+		if attempt > 1 && body != nil {
 			switch body.(type) {
 			case *os.File:
 				file := body.(*os.File)
 				if rb, err := os.Open(file.Name()); err != nil {
-					Fatal(CLI_INPUT_ERROR, msgPrinter.Sprintf("unable to open object file %v: %v", file.Name(), err))
+					return nil, newCLIError(CLI_INPUT_ERROR, msgPrinter.Sprintf("unable to open object file %v: %v", file.Name(), err))
 				} else {
 					requestBody = rb
 				}
@@ -1129,12 +1471,11 @@ func InvokeRestApi(httpClient *http.Client, method string, urlPath string, crede
 		}
 
 		// Create the request and run it
-		req, err := http.NewRequest(method, urlObj.String(), requestBody)
+		req, err := newRequest(method, reqUrl, requestBody)
 		if err != nil {
-			Fatal(HTTP_ERROR, msgPrinter.Sprintf("%s new request failed: %v", apiMsg, err))
+			return nil, newCLIError(HTTP_ERROR, msgPrinter.Sprintf("%s new request failed: %v", apiMsg, err))
 		}
 
-		req.Close = true
 		req.Header.Add("Accept", "application/json")
 
 		// for PUT/PATCH/POST
@@ -1155,28 +1496,66 @@ func InvokeRestApi(httpClient *http.Client, method string, urlPath string, crede
 		}
 		req.Header.Add("Accept-Language", localeTag.String())
 
-		if credentials != "" {
-			req.Header.Add("Authorization", fmt.Sprintf("Basic %v", base64.StdEncoding.EncodeToString([]byte(credentials))))
-		} // else it is an anonymous call
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+
+		if err := setAuthHeader(req, credentials); err != nil {
+			return nil, err
+		}
 
 		resp, err := httpClient.Do(req)
+		if resp == nil && isConnectionError(err) {
+			// the next mirrored exchange is tried right away, it does not use up a retry
+			if mirrorUrl, ok := failoverExchangeUrl(reqUrl, mirrorsTried+1); ok {
+				reqUrl = mirrorUrl
+				mirrorsTried++
+				retryCount--
+				continue
+			}
+		}
 		if exchange.IsTransportError(resp, err) {
 			http_status := ""
 			if resp != nil {
 				http_status = resp.Status
+				resp.Body.Close()
 			}
-			if retryCount <= maxRetries {
-				Verbose(msgPrinter.Sprintf("Encountered HTTP error: %v calling %v REST API %v. HTTP status: %v. Will retry.", err, service, apiMsg, http_status))
+			if retryCount <= maxRetries && !RetryBudgetExceeded(retryInterval, retryCount) {
+				delay := RetryDelay(retryInterval, retryCount)
+				if resp != nil {
+					delay = retryAfter(resp, delay, HTTP_RETRY_MAX_INTERVAL_S*time.Second)
+				}
+				Verbose(msgPrinter.Sprintf("Encountered HTTP error: %v calling %v REST API %v. HTTP status: %v. Will retry in %v.", err, service, apiMsg, http_status, delay))
 				// retry for network tranport errors
-				time.Sleep(time.Duration(retryInterval) * time.Second)
+				sleepUnlessInterrupted(delay)
 				continue
 			} else {
-				Fatal(HTTP_ERROR, msgPrinter.Sprintf("Encountered HTTP error: %v calling %v REST API %v. HTTP status: %v.", err, service, apiMsg, http_status))
+				return nil, newCLIError(HTTP_ERROR, msgPrinter.Sprintf("Encountered HTTP error: %v calling %v REST API %v. HTTP status: %v.", err, service, apiMsg, http_status))
 			}
 		} else if err != nil {
-			printHorizonServiceRestError(service, apiMsg, err)
+			return nil, horizonServiceRestError(service, apiMsg, err)
+		} else if resp.StatusCode == http.StatusTooManyRequests && rateLimitCount < maxRateLimitRetries {
+			// the rate limited requests are retried separately from the transport errors, waiting as long as the
+			// server asks for, up to the request timeout for all the retries of the request
+			rateLimitCount++
+			budget := rateLimitBudget(httpClient)
+			delay := retryAfter(resp, RetryDelay(retryInterval, rateLimitCount), budget-rateLimitWaited)
+			resp.Body.Close()
+			if delay <= 0 && rateLimitWaited >= budget {
+				return nil, newCLIError(HTTP_ERROR, msgPrinter.Sprintf("The %v is still rate limiting the requests after waiting %v, HTTP status: %v calling REST API %v. Use --http-timeout or %v to wait longer.", service, rateLimitWaited, resp.Status, apiMsg, config.HTTPRequestTimeoutOverride))
+			}
+			rateLimitWaited += delay
+			Verbose(msgPrinter.Sprintf("The %v is rate limiting the requests, HTTP status: %v calling REST API %v. Will retry in %v (retry %v of %v).", service, resp.Status, apiMsg, delay, rateLimitCount, maxRateLimitRetries))
+			sleepUnlessInterrupted(delay)
+			retryCount--
+			continue
+		} else if resp.StatusCode == http.StatusUnauthorized && !tokenRefreshed && refreshIamToken(credentials) {
+			// the IAM token expired, the request is sent again with a new one
+			resp.Body.Close()
+			tokenRefreshed = true
+			continue
 		} else {
-			return resp
+			return resp, nil
 		}
 	}
 }
@@ -1184,6 +1563,13 @@ func InvokeRestApi(httpClient *http.Client, method string, urlPath string, crede
 // ExchangeGet runs a GET to the specified service api and fills in the specified json structure. If the structure is just a string, fill in the raw json.
 // If the list of goodHttpCodes is not empty and none match the actual http code, it will exit with an error. Otherwise the actual code is returned.
 func ExchangeGet(service string, urlBase string, urlSuffix string, credentials string, goodHttpCodes []int, structure interface{}) (httpCode int) {
+	httpCode, err := ExchangeGetE(service, urlBase, urlSuffix, credentials, goodHttpCodes, structure)
+	exitOnError(err)
+	return
+}
+
+// ExchangeGetE is ExchangeGet returning an error instead of exiting.
+func ExchangeGetE(service string, urlBase string, urlSuffix string, credentials string, goodHttpCodes []int, structure interface{}) (httpCode int, err error) {
 	url := urlBase + "/" + urlSuffix
 	apiMsg := http.MethodGet + " " + url
 
@@ -1192,13 +1578,44 @@ func ExchangeGet(service string, urlBase string, urlSuffix string, credentials s
 
 	Verbose(apiMsg)
 
-	httpClient := GetHTTPClient(config.HTTPRequestTimeoutS)
+	httpClient, err := newHTTPClient(config.HTTPRequestTimeoutS)
+	if err != nil {
+		return 0, newCLIError(CLI_INPUT_ERROR, err.Error())
+	}
 
-	resp := InvokeRestApi(httpClient, http.MethodGet, url, credentials, nil, service, apiMsg)
+	var idleTimeout time.Duration
+	if _, ok := structure.(io.Writer); ok {
+		idleTimeout = downloadClient(httpClient)
+	}
+
+	// the cached body is used if the lastUpdated time of the resource has not changed, except when the body is streamed
+	cacheDir := GetExchangeCacheDir()
+	if _, ok := structure.(io.Writer); ok || !cachedResourceRE.MatchString(urlSuffix) || !isGoodCode(http.StatusOK, goodHttpCodes) {
+		cacheDir = ""
+	}
+	if cacheDir != "" {
+		if cached := getCachedResponse(cacheDir, url, credentials); cached != nil && getLastUpdated(httpClient, service, url, credentials) == cached.LastUpdated {
+			Verbose(msgPrinter.Sprintf("Using the cached response of %s, last updated %s", apiMsg, cached.LastUpdated))
+			return http.StatusOK, decodeExchangeBody(cached.Body, structure, apiMsg)
+		}
+	}
+
+	resp, err := invokeRestApi(httpClient, http.MethodGet, url, credentials, nil, service, apiMsg, nil)
+	if err != nil {
+		return 0, err
+	}
 	defer resp.Body.Close()
 
+	httpCode = resp.StatusCode
+	Verbose(msgPrinter.Sprintf("HTTP code: %d", httpCode))
+	if !isGoodCode(httpCode, goodHttpCodes) {
+		return httpCode, newCLIError(HTTP_ERROR, msgPrinter.Sprintf("bad HTTP code %d from %s, output: %s", httpCode, apiMsg, GetErrorRespBody(resp)))
+	}
+
 	respBody := io.Reader(resp.Body)
-	if resp.Header.Get("Content-type") == "application/octet-stream" {
+	if _, ok := structure.(*downloadWriter); ok {
+		// the download reports its own progress
+	} else if resp.Header.Get("Content-type") == "application/octet-stream" {
 		// Show progress of binary files downloading
 		msgPrinter.Print("Downloading object")
 		chunkNumber := 0
@@ -1216,15 +1633,32 @@ func ExchangeGet(service string, urlBase string, urlSuffix string, credentials s
 		}}
 	}
 
-	bodyBytes, err := ioutil.ReadAll(respBody)
+	// A writer is the signal that they want the body streamed to it, so the size of the body is not limited
+	if w, ok := structure.(io.Writer); ok {
+		startDownload(w, resp.ContentLength)
+		if _, err := newIdleTimeoutReader(resp.Body, respBody, idleTimeout).copyTo(w); err != nil {
+			return httpCode, newCLIError(HTTP_ERROR, msgPrinter.Sprintf("failed to read body response from %s: %v", apiMsg, err))
+		}
+		return httpCode, nil
+	}
+
+	bodyBytes, err := readRespBody(respBody, apiMsg)
 	if err != nil {
-		Fatal(HTTP_ERROR, msgPrinter.Sprintf("failed to read body response from %s: %v", apiMsg, err))
+		return httpCode, err
 	}
-	httpCode = resp.StatusCode
-	Verbose(msgPrinter.Sprintf("HTTP code: %d", httpCode))
-	if !isGoodCode(httpCode, goodHttpCodes) {
-		Fatal(HTTP_ERROR, msgPrinter.Sprintf("bad HTTP code %d from %s, output: %s", httpCode, apiMsg, string(bodyBytes)))
+	if cacheDir != "" && httpCode == http.StatusOK {
+		if lastUpdated := bodyLastUpdated(bodyBytes); lastUpdated != "" {
+			putCachedResponse(cacheDir, url, credentials, &cachedResponse{URL: url, LastUpdated: lastUpdated, Body: bodyBytes})
+		}
 	}
+	return httpCode, decodeExchangeBody(bodyBytes, structure, apiMsg)
+}
+
+// Fill in the structure with the body of an exchange response. A *[]byte gets the raw body and a *string gets the
+// indented json.
+func decodeExchangeBody(bodyBytes []byte, structure interface{}, apiMsg string) error {
+	msgPrinter := i18n.GetMessagePrinter()
+	var err error
 
 	if len(bodyBytes) > 0 && structure != nil { // the DP front-end of exchange will return nothing when auth problem
 		switch s := structure.(type) {
@@ -1237,27 +1671,70 @@ func ExchangeGet(service string, urlBase string, urlSuffix string, credentials s
 			var jsonStruct interface{}
 			err = json.Unmarshal(bodyBytes, &jsonStruct)
 			if err != nil {
-				Fatal(JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal exchange body response from %s: %v", apiMsg, err))
+				return newCLIError(JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal exchange body response from %s: %v", apiMsg, err))
 			}
 			jsonBytes, err := json.MarshalIndent(jsonStruct, "", JSON_INDENT)
 			if err != nil {
-				Fatal(JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal exchange output from %s: %v", apiMsg, err))
+				return newCLIError(JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal exchange output from %s: %v", apiMsg, err))
 			}
 			*s = string(jsonBytes)
 		default:
-			err = json.Unmarshal(bodyBytes, structure)
+			err = UnmarshalResponse(bodyBytes, structure, apiMsg)
 			if err != nil {
-				Fatal(JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal exchange body response from %s: %v", apiMsg, err))
+				return newCLIError(JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal exchange body response from %s: %v", apiMsg, err))
 			}
 		}
 	}
-	return
+	return nil
 }
 
 // ExchangePutPost runs a PUT, POST or PATCH to the exchange api to create of update a resource. If body is a string, it will be given to the exchange
 // as json. Otherwise the struct will be marshaled to json.
 // If the list of goodHttpCodes is not empty and none match the actual http code, it will exit with an error. Otherwise the actual code is returned.
 func ExchangePutPost(service string, method string, urlBase string, urlSuffix string, credentials string, goodHttpCodes []int, body interface{}, structure interface{}) (httpCode int) {
+	return exchangePutPostWithHeaders(service, method, urlBase, urlSuffix, credentials, goodHttpCodes, body, structure, nil)
+}
+
+// ExchangePutPostE is ExchangePutPost returning an error instead of exiting.
+func ExchangePutPostE(service string, method string, urlBase string, urlSuffix string, credentials string, goodHttpCodes []int, body interface{}, structure interface{}) (httpCode int, err error) {
+	return exchangePutPost(service, method, urlBase, urlSuffix, credentials, goodHttpCodes, body, structure, nil)
+}
+
+// ExchangePatch runs a PATCH to the specified service api to change some of the fields of a resource, with the same
+// goodHttpCodes and structure semantics as ExchangePutPost.
+func ExchangePatch(service string, urlBase string, urlSuffix string, credentials string, goodHttpCodes []int, patch interface{}, structure interface{}) (httpCode int) {
+	return exchangePutPostWithHeaders(service, http.MethodPatch, urlBase, urlSuffix, credentials, goodHttpCodes, patch, structure, nil)
+}
+
+// ExchangePatchE is ExchangePatch returning an error instead of exiting.
+func ExchangePatchE(service string, urlBase string, urlSuffix string, credentials string, goodHttpCodes []int, patch interface{}, structure interface{}) (httpCode int, err error) {
+	return exchangePutPost(service, http.MethodPatch, urlBase, urlSuffix, credentials, goodHttpCodes, patch, structure, nil)
+}
+
+// ExchangePatchFields changes the fields of a resource with one PATCH for each field, in the order of the field names,
+// because the exchange only accepts one field in the PATCH of most resources. The http code of the last PATCH is returned.
+func ExchangePatchFields(service string, urlBase string, urlSuffix string, credentials string, goodHttpCodes []int, fields map[string]interface{}) (httpCode int) {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		httpCode = ExchangePatch(service, urlBase, urlSuffix, credentials, goodHttpCodes, map[string]interface{}{name: fields[name]}, nil)
+	}
+	return
+}
+
+// The same as ExchangePutPost, with the headers added to the request.
+func exchangePutPostWithHeaders(service string, method string, urlBase string, urlSuffix string, credentials string, goodHttpCodes []int, body interface{}, structure interface{}, headers map[string]string) (httpCode int) {
+	httpCode, err := exchangePutPost(service, method, urlBase, urlSuffix, credentials, goodHttpCodes, body, structure, headers)
+	exitOnError(err)
+	return
+}
+
+// exchangePutPostWithHeaders returning the error instead of exiting.
+func exchangePutPost(service string, method string, urlBase string, urlSuffix string, credentials string, goodHttpCodes []int, body interface{}, structure interface{}, headers map[string]string) (httpCode int, err error) {
 	url := urlBase + "/" + urlSuffix
 	apiMsg := method + " " + url
 
@@ -1266,57 +1743,41 @@ func ExchangePutPost(service string, method string, urlBase string, urlSuffix st
 
 	Verbose(apiMsg)
 	if IsDryRun() {
-		return 201
+		return 201, nil
 	}
 
-	httpClient := GetHTTPClient(config.HTTPRequestTimeoutS)
-	resp := InvokeRestApi(httpClient, method, url, credentials, body, service, apiMsg)
+	httpClient, err := newHTTPClient(config.HTTPRequestTimeoutS)
+	if err != nil {
+		return 0, newCLIError(CLI_INPUT_ERROR, err.Error())
+	}
+	resp, err := invokeRestApi(httpClient, method, url, credentials, body, service, apiMsg, headers)
+	if err != nil {
+		return 0, err
+	}
 	defer resp.Body.Close()
 	httpCode = resp.StatusCode
 	Verbose(msgPrinter.Sprintf("HTTP code: %d", httpCode))
-	bodyBytes, err := ioutil.ReadAll(resp.Body)
 	if !isGoodCode(httpCode, goodHttpCodes) {
-		if err != nil {
-			Fatal(HTTP_ERROR, msgPrinter.Sprintf("failed to read exchange body response from %s: %v", apiMsg, err))
-		}
-		respMsg := exchange.PostDeviceResponse{}
-		err = json.Unmarshal(bodyBytes, &respMsg)
-		if err != nil {
-			Fatal(HTTP_ERROR, msgPrinter.Sprintf("bad HTTP code %d from %s: %s", httpCode, apiMsg, string(bodyBytes)))
-		}
-		Fatal(HTTP_ERROR, msgPrinter.Sprintf("bad HTTP code %d from %s: %s, %s", httpCode, apiMsg, respMsg.Code, respMsg.Msg))
-	} else if len(bodyBytes) > 0 && structure != nil { // the DP front-end of exchange will return nothing when auth problem
-		switch s := structure.(type) {
-		case *[]byte:
-			// This is the signal that they want the raw body back
-			*s = bodyBytes
-		case *string:
-			// If the structure to fill in is just a string, unmarshal/remarshal it to get it in json indented form, and then return as a string
-			//todo: this gets it in json indented form, but also returns the fields in random order (because they were interpreted as a map)
-			var jsonStruct interface{}
-			err = json.Unmarshal(bodyBytes, &jsonStruct)
-			if err != nil {
-				Fatal(JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal exchange body response from %s: %v", apiMsg, err))
-			}
-			jsonBytes, err := json.MarshalIndent(jsonStruct, "", JSON_INDENT)
-			if err != nil {
-				Fatal(JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal exchange output from %s: %v", apiMsg, err))
-			}
-			*s = string(jsonBytes)
-		default:
-			err = json.Unmarshal(bodyBytes, structure)
-			if err != nil {
-				Fatal(JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal exchange body response from %s: %v", apiMsg, err))
-			}
-		}
+		return httpCode, newCLIError(HTTP_ERROR, msgPrinter.Sprintf("bad HTTP code %d from %s: %s", httpCode, apiMsg, GetErrorRespBody(resp)))
 	}
 
-	return
+	bodyBytes, err := readRespBody(resp.Body, apiMsg)
+	if err != nil {
+		return httpCode, err
+	}
+	return httpCode, decodeExchangeBody(bodyBytes, structure, apiMsg)
 }
 
 // ExchangeDelete deletes a resource via the exchange api.
 // If the list of goodHttpCodes is not empty and none match the actual http code, it will exit with an error. Otherwise the actual code is returned.
 func ExchangeDelete(service string, urlBase string, urlSuffix string, credentials string, goodHttpCodes []int) (httpCode int) {
+	httpCode, err := ExchangeDeleteE(service, urlBase, urlSuffix, credentials, goodHttpCodes)
+	exitOnError(err)
+	return
+}
+
+// ExchangeDeleteE is ExchangeDelete returning an error instead of exiting.
+func ExchangeDeleteE(service string, urlBase string, urlSuffix string, credentials string, goodHttpCodes []int) (httpCode int, err error) {
 	url := urlBase + "/" + urlSuffix
 	apiMsg := http.MethodDelete + " " + url
 
@@ -1325,21 +1786,37 @@ func ExchangeDelete(service string, urlBase string, urlSuffix string, credential
 
 	Verbose(apiMsg)
 	if IsDryRun() {
-		return 204
+		return 204, nil
 	}
 
-	httpClient := GetHTTPClient(config.HTTPRequestTimeoutS)
+	httpClient, err := newHTTPClient(config.HTTPRequestTimeoutS)
+	if err != nil {
+		return 0, newCLIError(CLI_INPUT_ERROR, err.Error())
+	}
 
-	resp := InvokeRestApi(httpClient, http.MethodDelete, url, credentials, nil, service, apiMsg)
+	resp, err := invokeRestApi(httpClient, http.MethodDelete, url, credentials, nil, service, apiMsg, nil)
+	if err != nil {
+		return 0, err
+	}
 	defer resp.Body.Close()
 
-	// delete never returns a body
+	// delete only returns a body when it fails
 	httpCode = resp.StatusCode
 	Verbose(msgPrinter.Sprintf("HTTP code: %d", httpCode))
 	if !isGoodCode(httpCode, goodHttpCodes) {
-		Fatal(HTTP_ERROR, msgPrinter.Sprintf("bad HTTP code %d from %s", httpCode, apiMsg))
+		return httpCode, newCLIError(HTTP_ERROR, msgPrinter.Sprintf("bad HTTP code %d from %s: %s", httpCode, apiMsg, GetErrorRespBody(resp)))
+	}
+	return httpCode, nil
+}
+
+// CheckResourceUnchanged exits with a conflict error if the lastUpdated time of a resource is no longer the one that the
+// user prepared the update from, given with --if-last-updated, which means that someone else has changed the resource
+// in between. The exchange has no conditional updates, so a change that is made between this check and the update is
+// still overwritten.
+func CheckResourceUnchanged(resourceDesc string, expectedLastUpdated string, currentLastUpdated string) {
+	if expectedLastUpdated != currentLastUpdated {
+		Fatal(CONFLICT_ERROR, i18n.GetMessagePrinter().Sprintf("%v was changed by someone else (last updated %v) after the version the update was prepared from (last updated %v), it has not been updated. Check the current content of the resource and run the command again.", resourceDesc, currentLastUpdated, expectedLastUpdated))
 	}
-	return
 }
 
 func ConvertTime(unixSeconds uint64) string {
@@ -1349,45 +1826,75 @@ func ConvertTime(unixSeconds uint64) string {
 	return time.Unix(int64(unixSeconds), 0).String()
 }
 
-// find correct credentials to use. Use -u or -n if one of them is not empty.
-// If both are empty, use HZN_EXCHANGE_USER_AUTH first, if it is not set use HZN_EXCHANGE_NODE_AUTH.
+// GetUserAuth returns the exchange user credentials to use. The -u flag of the command is used first, then the global
+// --user-auth flag, then HZN_EXCHANGE_USER_AUTH, which can also be set in the hzn configuration files, then the
+// credential helper in HZN_CREDENTIAL_HELPER. Returns the empty string if none of them is set.
+func GetUserAuth(userPw string) string {
+	if userPw != "" {
+		return userPw
+	} else if Opts.UserAuth != nil && *Opts.UserAuth != "" {
+		return *Opts.UserAuth
+	} else if envU := os.Getenv("HZN_EXCHANGE_USER_AUTH"); envU != "" {
+		return envU
+	}
+	return getHelperCredentials()
+}
+
+// GetNodeAuth returns the exchange node credentials to use, in the same way as GetUserAuth: the -n flag of the command,
+// then the global --node-auth flag, then HZN_EXCHANGE_NODE_AUTH.
+func GetNodeAuth(nodeIdTok string) string {
+	if nodeIdTok != "" {
+		return nodeIdTok
+	} else if Opts.NodeAuth != nil && *Opts.NodeAuth != "" {
+		return *Opts.NodeAuth
+	}
+	return os.Getenv("HZN_EXCHANGE_NODE_AUTH")
+}
+
+// RequiredUserAuth returns the exchange user credentials from GetUserAuth, for the commands that can only be run with
+// user credentials. Exits with an error if the credentials are not set.
+func RequiredUserAuth(userPw string) string {
+	credToUse := GetUserAuth(userPw)
+	if credToUse == "" {
+		Fatal(CLI_INPUT_ERROR, i18n.GetMessagePrinter().Sprintf("exchange user authentication must be specified with one of the following: the -u flag, the --user-auth flag, HZN_EXCHANGE_USER_AUTH or HZN_CREDENTIAL_HELPER"))
+	}
+	return credToUse
+}
+
+// find correct credentials to use, for the commands that can be run with user or node credentials. The credentials
+// given to the command with -u or -n are used first, then the global --user-auth and --node-auth flags, then
+// HZN_EXCHANGE_USER_AUTH and HZN_EXCHANGE_NODE_AUTH, then the credential helper. User credentials are used before node
+// credentials at each level.
 func GetExchangeAuth(userPw string, nodeIdTok string) string {
 	credToUse := ""
 
 	if userPw != "" {
 		credToUse = userPw
+	} else if nodeIdTok != "" {
+		credToUse = nodeIdTok
+	} else if Opts.UserAuth != nil && *Opts.UserAuth != "" {
+		credToUse = *Opts.UserAuth
+	} else if Opts.NodeAuth != nil && *Opts.NodeAuth != "" {
+		credToUse = *Opts.NodeAuth
+	} else if envU := os.Getenv("HZN_EXCHANGE_USER_AUTH"); envU != "" {
+		credToUse = envU
+	} else if envN := os.Getenv("HZN_EXCHANGE_NODE_AUTH"); envN != "" {
+		credToUse = envN
 	} else {
-		if nodeIdTok != "" {
-			credToUse = nodeIdTok
-		} else {
-			if tmpU := WithDefaultEnvVar(&userPw, "HZN_EXCHANGE_USER_AUTH"); *tmpU != "" {
-				credToUse = *tmpU
-			} else if tmpN := WithDefaultEnvVar(&nodeIdTok, "HZN_EXCHANGE_NODE_AUTH"); *tmpN != "" {
-				credToUse = *tmpN
-			}
-		}
+		credToUse = getHelperCredentials()
 	}
 
 	if credToUse == "" {
-		Fatal(CLI_INPUT_ERROR, i18n.GetMessagePrinter().Sprintf("exchange authentication must be specified with one of the following: the -u flag, the -n flag, HZN_EXCHANGE_USER_AUTH or HZN_EXCHANGE_NODE_AUTH"))
+		Fatal(CLI_INPUT_ERROR, i18n.GetMessagePrinter().Sprintf("exchange authentication must be specified with one of the following: the -u flag, the -n flag, the --user-auth flag, the --node-auth flag, HZN_EXCHANGE_USER_AUTH, HZN_EXCHANGE_NODE_AUTH or HZN_CREDENTIAL_HELPER"))
 	}
 
 	return credToUse
 }
 
-// Find correct credentials to use. Use -u first.
-// If empty, use HZN_EXCHANGE_USER_AUTH
+// Find correct credentials to use, the same way as GetUserAuth,
 // but do not show an error if credentials are empty
 func GetExchangeAuthVersion(userPw string) string {
-	credToUse := ""
-	if userPw != "" {
-		credToUse = userPw
-	} else {
-		if tmpU := WithDefaultEnvVar(&userPw, "HZN_EXCHANGE_USER_AUTH"); *tmpU != "" {
-			credToUse = *tmpU
-		}
-	}
-	return credToUse
+	return GetUserAuth(userPw)
 }
 
 // set env variable ARCH if it is not set
@@ -1589,17 +2096,38 @@ func DisplayAsJson(data interface{}) (string, error) {
 	}
 }
 
-// Common function for getting an HTTP client connection object.
-func GetHTTPClient(timeout int) *http.Client {
+// set once the warning about skipping TLS verification has been displayed, so it is only shown once per invocation
+var insecureWarningShown bool
+
+// IsInsecure returns true if the verification of TLS certificates should be skipped, either because --insecure
+// was specified or HZN_SSL_SKIP_VERIFY is set. A warning is displayed the first time it returns true.
+func IsInsecure() bool {
+	insecure := (Opts.Insecure != nil && *Opts.Insecure) || os.Getenv("HZN_SSL_SKIP_VERIFY") != ""
+	if insecure && !insecureWarningShown {
+		insecureWarningShown = true
+		Warning(i18n.GetMessagePrinter().Sprintf("TLS certificate verification is disabled. The identity of the Horizon management hub is not verified and the connection can be intercepted. Only use --insecure or HZN_SSL_SKIP_VERIFY with test hubs that have self-signed certificates."))
+	}
+	return insecure
+}
 
-	// This env var should only be used in our test environments or in an emergency when there is a problem with the SSL certificate of a horizon service.
-	skipSSL := false
-	if os.Getenv("HZN_SSL_SKIP_VERIFY") != "" {
-		skipSSL = true
+// Common function for getting an HTTP client connection object. The clients with the same settings share their
+// transport, so the connections are reused by the next requests.
+func GetHTTPClient(timeout int) *http.Client {
+	httpClient, err := newHTTPClient(timeout)
+	if err != nil {
+		Fatal(CLI_INPUT_ERROR, err.Error())
 	}
+	return httpClient
+}
 
-	// Set request timeout based on environment variables and input values. The environment variable always overrides the
-	// input parameter. The other timeouts are subject to the timeout setting also.
+// GetHTTPClient returning the error of an invalid proxy instead of exiting.
+func newHTTPClient(timeout int) (*http.Client, error) {
+
+	// This should only be used in our test environments or in an emergency when there is a problem with the SSL certificate of a horizon service.
+	skipSSL := IsInsecure()
+
+	// Set request timeout based on the --http-timeout flag, environment variables and input values. The flag and the environment
+	// variable always override the input parameter. The other timeouts are subject to the timeout setting also.
 	requestTimeout := timeout
 
 	if envTimeout := os.Getenv(config.HTTPRequestTimeoutOverride); envTimeout != "" {
@@ -1609,35 +2137,21 @@ func GetHTTPClient(timeout int) *http.Client {
 			Warning(i18n.GetMessagePrinter().Sprintf("Unable to use %v to set the request timeout, the value is not a valid number: %v", config.HTTPRequestTimeoutOverride, envTimeout))
 		}
 	}
-
-	responseTimeout := int(float64(requestTimeout) * 0.8)
-	dialTimeout := int(float64(requestTimeout) * 0.5)
-	keepAlive := requestTimeout * 2
-	TLSHandshake := dialTimeout
-	expectContinue := int(float64(requestTimeout) * 0.5)
+	if Opts.Timeout != nil && *Opts.Timeout > 0 {
+		requestTimeout = *Opts.Timeout
+	}
 
 	Verbose(i18n.GetMessagePrinter().Sprintf("HTTP request timeout set to %v seconds", requestTimeout))
 
+	st := getSharedTransport(requestTimeout, skipSSL)
 	return &http.Client{
 		// remember that this timeout is for the whole request, including
 		// body reading. This means that you must set the timeout according
 		// to the total payload size you expect
-		Timeout: time.Second * time.Duration(requestTimeout),
-		Transport: &http.Transport{
-			Dial: (&net.Dialer{
-				Timeout:   time.Duration(dialTimeout) * time.Second,
-				KeepAlive: time.Duration(keepAlive) * time.Second,
-			}).Dial,
-			TLSHandshakeTimeout:   time.Duration(TLSHandshake) * time.Second,
-			ResponseHeaderTimeout: time.Duration(responseTimeout) * time.Second,
-			ExpectContinueTimeout: time.Duration(expectContinue) * time.Second,
-			MaxIdleConns:          config.MaxHTTPIdleConnections,
-			IdleConnTimeout:       config.HTTPIdleConnectionTimeoutS * time.Second,
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: skipSSL,
-			},
-		},
-	}
+		Timeout:   time.Second * time.Duration(requestTimeout),
+		Jar:       getCookieJar(),
+		Transport: withHttpTrace(withRequestRecord(st.transport)),
+	}, st.proxyErr
 
 }
 
@@ -1678,6 +2192,9 @@ func NewHTTPClientFactory() *config.HTTPClientFactory {
 		if err := TrustIcpCert(httpClient); err != nil {
 			Fatal(FILE_IO_ERROR, err.Error())
 		}
+		if err := UseClientCert(httpClient); err != nil {
+			Fatal(CLI_INPUT_ERROR, err.Error())
+		}
 
 		return httpClient
 	}
@@ -1695,6 +2212,46 @@ func NewHTTPClientFactory() *config.HTTPClientFactory {
 	}
 }
 
+// The longest wait between two retries of a request, and the longest total wait of all the retries of a request, in
+// seconds.
+const HTTP_RETRY_MAX_INTERVAL_S = 10
+const HTTP_RETRY_MAX_TOTAL_S = 30
+
+// RetryDelay returns the wait before the retry of a request that failed retryCount times. The wait starts at the retry
+// interval and doubles with each retry, up to HTTP_RETRY_MAX_INTERVAL_S, so that a hub that is down or overloaded is not
+// hammered by the retries.
+func RetryDelay(retryInterval int, retryCount int) time.Duration {
+	delay := time.Duration(retryInterval) * time.Second
+	for i := 1; i < retryCount && delay < HTTP_RETRY_MAX_INTERVAL_S*time.Second; i++ {
+		delay *= 2
+	}
+	if delay > HTTP_RETRY_MAX_INTERVAL_S*time.Second {
+		delay = HTTP_RETRY_MAX_INTERVAL_S * time.Second
+	}
+	return delay
+}
+
+// RetryBudgetExceeded returns true if waiting for the retry retryCount of a request would make the total wait of its
+// retries longer than HTTP_RETRY_MAX_TOTAL_S, in which case the request is not retried again.
+func RetryBudgetExceeded(retryInterval int, retryCount int) bool {
+	var total time.Duration
+	for i := 1; i <= retryCount; i++ {
+		total += RetryDelay(retryInterval, i)
+	}
+	return total > HTTP_RETRY_MAX_TOTAL_S*time.Second
+}
+
+// Returns true if a request to the Horizon agent should be retried. Unlike the exchange, a refused connection is not
+// retried because it almost always means that the agent is not running.
+func isRetryableHorizonError(resp *http.Response, err error) bool {
+	if Interrupted() {
+		return false
+	} else if err != nil && strings.Contains(strings.ToLower(err.Error()), "connection refused") {
+		return false
+	}
+	return exchange.IsTransportError(resp, err)
+}
+
 // get the http retry count and interval from the env variables.
 func GetHttpRetryParameters(default_count int, default_interval int) (int, int, error) {
 	maxRetries := default_count
@@ -1730,8 +2287,9 @@ func getString(v interface{}) string {
 */
 
 // This function is used in the service publish command to pull the docker image.
-// It  the image name with the digest.
-func GetNewDockerImageName(image string, dontTouchImage bool, pullImage bool) string {
+// It  the image name with the digest. With resolveDigest, the digest of the tag is resolved from the docker registry
+// instead, without pushing or pulling the image.
+func GetNewDockerImageName(image string, dontTouchImage bool, pullImage bool, resolveDigest bool) string {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
@@ -1747,7 +2305,12 @@ func GetNewDockerImageName(image string, dontTouchImage bool, pullImage bool) st
 			// Push it, get the repo digest, and modify the imagePath to use the digest.
 			client := NewDockerClient()
 			digest := ""
-			if pullImage {
+			if resolveDigest {
+				var err error
+				if digest, err = ResolveDockerImageDigest(domain, path, tag); err != nil {
+					Fatal(CLI_GENERAL_ERROR, msgPrinter.Sprintf("unable to resolve the digest of image %v: %v", image, err))
+				}
+			} else if pullImage {
 				digest = PullDockerImage(client, domain, path, tag) // this will error out if pull fails
 			} else {
 				digest = PushDockerImage(client, domain, path, tag) // this will error out if the push fails or can't get the digest
@@ -1800,3 +2363,25 @@ func (pr *progressReader) Read(p []byte) (n int, err error) {
 	pr.Reporter(n)
 	return n, err
 }
+
+// OutputDeletionReport prints the signed deletion report returned by a purge API, and also writes it to reportFile
+// when it is set, so that it can be kept as evidence of the removal.
+func OutputDeletionReport(respBody string, reportFile string) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	var report cutil.DeletionReport
+	if err := json.Unmarshal([]byte(respBody), &report); err != nil {
+		Fatal(JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal the deletion report: %v", err))
+	}
+	jsonBytes, err := json.MarshalIndent(report, "", JSON_INDENT)
+	if err != nil {
+		Fatal(JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal the deletion report: %v", err))
+	}
+
+	if reportFile != "" {
+		if err := ioutil.WriteFile(reportFile, append(jsonBytes, '\n'), 0600); err != nil {
+			Fatal(FILE_IO_ERROR, msgPrinter.Sprintf("failed to write the deletion report to %v: %v", reportFile, err))
+		}
+	}
+	fmt.Printf("%s\n", jsonBytes)
+}
@@ -0,0 +1,71 @@
+package cliutils
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/open-horizon/anax/i18n"
+	"golang.org/x/crypto/ssh/terminal"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Set to true to skip every confirmation prompt, the same as the --yes flag.
+const NONINTERACTIVE_ENV = "HZN_NONINTERACTIVE"
+
+// Returns true if the confirmation prompts are skipped, with --yes or HZN_NONINTERACTIVE.
+func IsNonInteractive() bool {
+	if Opts.AssumeYes != nil && *Opts.AssumeYes {
+		return true
+	}
+	nonInteractive, _ := strconv.ParseBool(os.Getenv(NONINTERACTIVE_ENV))
+	return nonInteractive
+}
+
+// Returns true if stdin is a terminal, that a user can answer a confirmation prompt in.
+var stdinIsTerminal = func() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// SetStdinIsTerminal sets the function that tells if stdin is a terminal and returns the previous one. Tests that
+// answer the confirmation prompts through a pipe replace it.
+func SetStdinIsTerminal(f func() bool) func() bool {
+	prev := stdinIsTerminal
+	stdinIsTerminal = f
+	return prev
+}
+
+// ReadSecret returns the value of a password or a token that is not given on the command line, where other users can
+// see it. When stdin is a terminal the user is prompted for it without echo, otherwise it is the first line of stdin,
+// e.g. echo "$TOKEN" | hzn exchange agbot create agbot1. A value of - is the same as no value. The command fails when
+// the secret is empty.
+func ReadSecret(what string, value string) string {
+	msgPrinter := i18n.GetMessagePrinter()
+	if value != "" && value != "-" {
+		return value
+	}
+
+	var secret string
+	if fd := int(os.Stdin.Fd()); terminal.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, msgPrinter.Sprintf("Enter the %v: ", what))
+		b, err := terminal.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			Fatal(CLI_INPUT_ERROR, msgPrinter.Sprintf("unable to read the %v, error %v", what, err))
+		}
+		secret = string(b)
+	} else {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			Fatal(CLI_INPUT_ERROR, msgPrinter.Sprintf("unable to read the %v from stdin, error %v", what, err))
+		}
+		secret = strings.TrimRight(line, "\r\n")
+	}
+
+	if secret == "" {
+		Fatal(CLI_INPUT_ERROR, msgPrinter.Sprintf("the %v must be specified.", what))
+	}
+	return secret
+}
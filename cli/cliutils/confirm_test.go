@@ -0,0 +1,71 @@
+// +build unit
+
+package cliutils
+
+import (
+	"os"
+	"testing"
+)
+
+// Run the function with stdin piped from the input.
+func withStdin(t *testing.T, input string, f func() error) error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := w.WriteString(input); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	// the prompt is not displayed
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+
+	stdin, stdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = r, devNull
+	defer func() { os.Stdin, os.Stdout = stdin, stdout }()
+	return f()
+}
+
+func Test_ConfirmRemove_nonInteractive(t *testing.T) {
+	verbose, dryRun, yes := false, false, false
+	Opts.Verbose, Opts.IsDryRun, Opts.AssumeYes = &verbose, &dryRun, &yes
+	defer func() { Opts.Verbose, Opts.IsDryRun, Opts.AssumeYes = nil, nil, nil }()
+
+	prev := SetStdinIsTerminal(func() bool { return false })
+	defer SetStdinIsTerminal(prev)
+
+	// without a terminal the answer is read from stdin, and the command fails when there is none
+	for input, code := range map[string]int{"": CLI_INPUT_ERROR, "\n": CLI_INPUT_ERROR, "y\n": -1, "y": -1, "n\n": 0} {
+		err := withStdin(t, input, func() error { return Try(func() { ConfirmRemove("Are you sure?") }) })
+		if code == -1 && err != nil {
+			t.Errorf("the answer %q should confirm the removal, found %v", input, err)
+		} else if code != -1 && (err == nil || ExitCode(err) != code) {
+			t.Errorf("expected exit code %v for the answer %q, found %v", code, input, err)
+		}
+	}
+
+	yes = true
+	if !IsNonInteractive() {
+		t.Errorf("--yes should skip the prompts")
+	} else if err := Try(func() { ConfirmRemove("Are you sure?") }); err != nil {
+		t.Errorf("the confirmation should be skipped, found %v", err)
+	}
+
+	yes = false
+	os.Setenv(NONINTERACTIVE_ENV, "true")
+	defer os.Unsetenv(NONINTERACTIVE_ENV)
+	if err := Try(func() { ConfirmRemove("Are you sure?") }); err != nil {
+		t.Errorf("%v should skip the confirmation, found %v", NONINTERACTIVE_ENV, err)
+	}
+
+	os.Setenv(NONINTERACTIVE_ENV, "no")
+	if IsNonInteractive() {
+		t.Errorf("%v=no should not skip the prompts", NONINTERACTIVE_ENV)
+	}
+}
@@ -0,0 +1,77 @@
+package cliutils
+
+import (
+	"context"
+	"github.com/open-horizon/anax/i18n"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// How long a command has to end after it is interrupted, before the CLI exits without waiting for it.
+const INTERRUPT_GRACE_PERIOD_S = 2
+
+var cliContextLock sync.Mutex
+var cliContext, cliCancel = context.WithCancel(context.Background())
+
+// Context returns the context of the command. It is cancelled when the command is interrupted, which cancels the
+// requests to the agent, the agbot and the exchange that are in flight.
+func Context() context.Context {
+	cliContextLock.Lock()
+	defer cliContextLock.Unlock()
+	return cliContext
+}
+
+// SetContext replaces the context of the command, so that programs and tests that embed this package can cancel the
+// requests themselves, and returns the previous one.
+func SetContext(ctx context.Context) context.Context {
+	cliContextLock.Lock()
+	defer cliContextLock.Unlock()
+	prev := cliContext
+	cliContext, cliCancel = context.WithCancel(ctx)
+	return prev
+}
+
+// Interrupted returns true if the context of the command has been cancelled.
+func Interrupted() bool {
+	return Context().Err() != nil
+}
+
+// CancelOnInterrupt cancels the context of the command when it gets SIGINT (Ctrl-C) or SIGTERM. The request in flight
+// fails right away and the command exits with the INTERRUPTED exit code. A command that is not waiting for a request
+// is ended after INTERRUPT_GRACE_PERIOD_S seconds, or right away at the second signal.
+func CancelOnInterrupt() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-signals
+		cliContextLock.Lock()
+		cliCancel()
+		cliContextLock.Unlock()
+
+		select {
+		case <-signals:
+		case <-time.After(INTERRUPT_GRACE_PERIOD_S * time.Second):
+		}
+		printError(INTERRUPTED, i18n.GetMessagePrinter().Sprintf("The command was interrupted."))
+		exitFunc(INTERRUPTED)
+	}()
+}
+
+// Create a request that is cancelled when the command is interrupted.
+func newRequest(method string, url string, body io.Reader) (*http.Request, error) {
+	return http.NewRequestWithContext(Context(), method, url, body)
+}
+
+// Wait before retrying a request. The wait ends early when the command is interrupted, and the next request fails.
+func sleepUnlessInterrupted(delay time.Duration) {
+	select {
+	case <-Context().Done():
+	case <-time.After(delay):
+	}
+}
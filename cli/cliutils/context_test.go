@@ -0,0 +1,50 @@
+// +build unit
+
+package cliutils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_ExchangeGet_interrupted(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	verbose, dryRun := false, false
+	Opts.Verbose, Opts.IsDryRun = &verbose, &dryRun
+	defer func() { Opts.Verbose, Opts.IsDryRun = nil, nil }()
+	os.Setenv("HZN_HTTP_RETRIES", "3")
+	defer os.Unsetenv("HZN_HTTP_RETRIES")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	prev := SetContext(ctx)
+	defer SetContext(prev)
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	var out map[string]interface{}
+	if _, err := ExchangeGetE("Exchange", ts.URL, "orgs", "", []int{200}, &out); ExitCode(err) != INTERRUPTED {
+		t.Errorf("expected exit code %v, found %v", INTERRUPTED, err)
+	} else if time.Since(start) > 5*time.Second {
+		t.Errorf("the request should be cancelled right away, it took %v", time.Since(start))
+	} else if !Interrupted() {
+		t.Errorf("the command should be interrupted")
+	}
+
+	SetContext(context.Background())
+	if Interrupted() {
+		t.Errorf("a new context should not be interrupted")
+	}
+}
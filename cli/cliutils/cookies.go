@@ -0,0 +1,153 @@
+package cliutils
+
+import (
+	"encoding/json"
+	"github.com/open-horizon/anax/i18n"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The name of the profile whose cookie jar is used when HZN_PROFILE is not set.
+const DEFAULT_PROFILE = "default"
+
+// GetProfileName returns the name of the profile of this invocation of hzn, which keeps the state that is saved
+// between invocations, such as the session cookies, apart for each exchange that is used.
+func GetProfileName() string {
+	if profile := os.Getenv("HZN_PROFILE"); profile != "" {
+		return profile
+	}
+	return DEFAULT_PROFILE
+}
+
+// GetCookieJarFile returns the file the session cookies are saved in, or an empty string if they are not saved. The
+// cookie jar is enabled with HZN_COOKIE_JAR, which is either true, to use the file of the profile in ~/.hzn/cookies,
+// or the name of the file to use.
+func GetCookieJarFile() string {
+	jarFile := os.Getenv("HZN_COOKIE_JAR")
+	switch strings.ToLower(jarFile) {
+	case "", "false", "0":
+		return ""
+	case "true", "1":
+		return filepath.Join(os.Getenv("HOME"), ".hzn", "cookies", GetProfileName()+".json")
+	}
+	return jarFile
+}
+
+// A cookie that is saved in the cookie jar file, with the url of the response that set it.
+type savedCookie struct {
+	URL    string       `json:"url"`
+	Cookie *http.Cookie `json:"cookie"`
+}
+
+// persistentJar is a cookie jar that saves the cookies in a file, so that the session cookies that an SSO gateway in
+// front of the exchange sets are used again by the next invocation of hzn.
+type persistentJar struct {
+	lock    sync.Mutex
+	jar     *cookiejar.Jar
+	file    string
+	cookies []savedCookie
+}
+
+var cookieJar http.CookieJar
+var cookieJarOnce sync.Once
+
+// getCookieJar returns the cookie jar that the http clients share, or nil if the cookie jar is not enabled.
+func getCookieJar() http.CookieJar {
+	cookieJarOnce.Do(func() {
+		if jarFile := GetCookieJarFile(); jarFile != "" {
+			if jar, err := newPersistentJar(jarFile); err != nil {
+				Warning(i18n.GetMessagePrinter().Sprintf("the session cookies can not be used: %v", err))
+			} else {
+				cookieJar = jar
+			}
+		}
+	})
+	return cookieJar
+}
+
+// newPersistentJar creates a cookie jar with the cookies saved in the file that have not expired.
+func newPersistentJar(file string) (*persistentJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	pj := &persistentJar{jar: jar, file: file, cookies: make([]savedCookie, 0)}
+
+	content, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return pj, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	saved := make([]savedCookie, 0)
+	if err := json.Unmarshal(content, &saved); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, sc := range saved {
+		if sc.Cookie == nil || (!sc.Cookie.Expires.IsZero() && sc.Cookie.Expires.Before(now)) {
+			continue
+		} else if u, err := url.Parse(sc.URL); err == nil {
+			jar.SetCookies(u, []*http.Cookie{sc.Cookie})
+			pj.cookies = append(pj.cookies, sc)
+		}
+	}
+	Verbose(i18n.GetMessagePrinter().Sprintf("Using %d session cookies from %v", len(pj.cookies), file))
+	return pj, nil
+}
+
+func (pj *persistentJar) Cookies(u *url.URL) []*http.Cookie {
+	return pj.jar.Cookies(u)
+}
+
+// SetCookies adds the cookies to the jar and saves the jar in its file. A cookie replaces the saved cookie with the
+// same name, domain and path.
+func (pj *persistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	pj.lock.Lock()
+	defer pj.lock.Unlock()
+
+	pj.jar.SetCookies(u, cookies)
+
+	cookieUrl := url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}
+	for _, c := range cookies {
+		saved := *c
+		if saved.MaxAge > 0 {
+			saved.Expires = time.Now().Add(time.Duration(saved.MaxAge) * time.Second)
+			saved.MaxAge = 0
+		}
+		kept := make([]savedCookie, 0, len(pj.cookies)+1)
+		for _, sc := range pj.cookies {
+			if sc.Cookie.Name != saved.Name || sc.Cookie.Domain != saved.Domain || sc.Cookie.Path != saved.Path {
+				kept = append(kept, sc)
+			}
+		}
+		// a cookie with a negative max age, or an expiry in the past, deletes the cookie
+		if c.MaxAge >= 0 && (saved.Expires.IsZero() || saved.Expires.After(time.Now())) {
+			kept = append(kept, savedCookie{URL: cookieUrl.String(), Cookie: &saved})
+		}
+		pj.cookies = kept
+	}
+
+	if err := pj.save(); err != nil {
+		Warning(i18n.GetMessagePrinter().Sprintf("unable to save the session cookies in %v: %v", pj.file, err))
+	}
+}
+
+func (pj *persistentJar) save() error {
+	if err := os.MkdirAll(filepath.Dir(pj.file), 0700); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(pj.cookies, "", JSON_INDENT)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(pj.file, content, 0600)
+}
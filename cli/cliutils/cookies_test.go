@@ -0,0 +1,63 @@
+// +build unit
+
+package cliutils
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_persistentJar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cookies")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "profile", "cookies.json")
+
+	u, _ := url.Parse("https://exchange.example.com/edge-exchange/v1/orgs")
+	jar, err := newPersistentJar(file)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: "abc", Path: "/", MaxAge: 3600}, {Name: "gone", Value: "x", Path: "/", MaxAge: -1}})
+	jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: "def", Path: "/", MaxAge: 3600}})
+
+	// a new jar, as in the next invocation of hzn, has the saved session cookie
+	jar2, err := newPersistentJar(file)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	cookies := jar2.Cookies(u)
+	if len(cookies) != 1 || cookies[0].Name != "session" || cookies[0].Value != "def" {
+		t.Errorf("unexpected cookies %v", cookies)
+	}
+
+	other, _ := url.Parse("https://other.example.com/")
+	if cookies := jar2.Cookies(other); len(cookies) != 0 {
+		t.Errorf("the cookies were sent to another host: %v", cookies)
+	}
+}
+
+func Test_GetCookieJarFile(t *testing.T) {
+	defer os.Unsetenv("HZN_COOKIE_JAR")
+	defer os.Unsetenv("HZN_PROFILE")
+
+	os.Unsetenv("HZN_COOKIE_JAR")
+	if file := GetCookieJarFile(); file != "" {
+		t.Errorf("expected the cookie jar to be disabled, got %v", file)
+	}
+	os.Setenv("HZN_COOKIE_JAR", "true")
+	os.Setenv("HZN_PROFILE", "prod")
+	if file := GetCookieJarFile(); filepath.Base(file) != "prod.json" {
+		t.Errorf("expected the cookie jar of the profile, got %v", file)
+	}
+	os.Setenv("HZN_COOKIE_JAR", "/tmp/jar.json")
+	if file := GetCookieJarFile(); file != "/tmp/jar.json" {
+		t.Errorf("expected the given cookie jar file, got %v", file)
+	}
+}
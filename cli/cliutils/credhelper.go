@@ -0,0 +1,87 @@
+package cliutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/open-horizon/anax/i18n"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// The env var with the program that hzn runs to get the exchange user credentials when they are not given with -u,
+// --user-auth or HZN_EXCHANGE_USER_AUTH, so that they are not on the command line or in the environment.
+const CREDENTIAL_HELPER_ENV = "HZN_CREDENTIAL_HELPER"
+
+// The credential helper is run as '<helper> get' with the exchange url on stdin, and writes the credentials to stdout
+// as {"Username": "[org/]user", "Secret": "pw"}. This is the protocol of the docker credential helpers, so that the
+// ones for the OS keyrings work as they are, e.g. HZN_CREDENTIAL_HELPER=docker-credential-secretservice.
+type helperCredentials struct {
+	ServerURL string `json:"ServerURL,omitempty"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+// The credentials from the helper, it is run once per command.
+var helperCredsLock sync.Mutex
+var helperCreds *string
+
+// GetCredentialHelper returns the credential helper program in HZN_CREDENTIAL_HELPER, or the empty string if none is
+// configured.
+func GetCredentialHelper() string {
+	return strings.TrimSpace(os.Getenv(CREDENTIAL_HELPER_ENV))
+}
+
+// ResetHelperCredentials forgets the credentials from the credential helper, so that the next command runs it again.
+func ResetHelperCredentials() {
+	helperCredsLock.Lock()
+	defer helperCredsLock.Unlock()
+	helperCreds = nil
+}
+
+// Returns the exchange user credentials from the credential helper, or the empty string if no helper is configured or
+// it has no credentials for the exchange.
+func getHelperCredentials() string {
+	helper := GetCredentialHelper()
+	if helper == "" {
+		return ""
+	}
+
+	helperCredsLock.Lock()
+	defer helperCredsLock.Unlock()
+	if helperCreds != nil {
+		return *helperCreds
+	}
+
+	msgPrinter := i18n.GetMessagePrinter()
+	exchUrl := GetExchangeUrl()
+	Verbose(msgPrinter.Sprintf("getting the exchange credentials from the credential helper %v", helper))
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(helper, "get")
+	cmd.Stdin = strings.NewReader(exchUrl)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	creds := ""
+	if err := cmd.Run(); err != nil {
+		// the docker helpers exit with an error when they do not have the credentials
+		errMsg := strings.TrimSpace(stderr.String() + " " + stdout.String())
+		if !strings.Contains(strings.ToLower(errMsg), "credentials not found") {
+			Fatal(EXEC_CMD_ERROR, msgPrinter.Sprintf("the credential helper %v failed: %v %v", helper, err, errMsg))
+		}
+		Verbose(msgPrinter.Sprintf("the credential helper %v has no credentials for %v", helper, exchUrl))
+	} else {
+		var hc helperCredentials
+		if err := json.Unmarshal(stdout.Bytes(), &hc); err != nil {
+			Fatal(EXEC_CMD_ERROR, msgPrinter.Sprintf("the output of the credential helper %v is not valid: %v", helper, err))
+		} else if hc.Username != "" && hc.Secret != "" {
+			creds = hc.Username + ":" + hc.Secret
+		} else if hc.Secret != "" {
+			// only a token, which is sent with the scheme of HZN_EXCHANGE_AUTH_SCHEME
+			creds = hc.Secret
+		}
+	}
+
+	helperCreds = &creds
+	return creds
+}
@@ -0,0 +1,65 @@
+// +build unit
+
+package cliutils
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_GetUserAuth_credentialHelper(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cliutils-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	verbose, dryRun, userAuth := false, false, ""
+	Opts.Verbose, Opts.IsDryRun, Opts.UserAuth = &verbose, &dryRun, &userAuth
+	defer func() { Opts.Verbose, Opts.IsDryRun, Opts.UserAuth = nil, nil, nil }()
+
+	os.Setenv("HZN_EXCHANGE_URL", "https://exchange.example.com/v1")
+	os.Unsetenv("HZN_EXCHANGE_USER_AUTH")
+	os.Unsetenv("HZN_EXCHANGE_NODE_AUTH")
+	defer os.Unsetenv("HZN_EXCHANGE_URL")
+	defer ResetExchangeUrl()
+	defer ResetHelperCredentials()
+
+	// the helper records its args and stdin, and gives the credentials for the exchange
+	calls := filepath.Join(dir, "calls")
+	writeHelper := func(script string) string {
+		helper := filepath.Join(dir, "credential-helper")
+		if err := ioutil.WriteFile(helper, []byte("#!/bin/sh\necho \"$@ $(cat)\" >> "+calls+"\n"+script), 0755); err != nil {
+			t.Fatalf("unable to write %v: %v", helper, err)
+		}
+		ResetHelperCredentials()
+		return helper
+	}
+	os.Setenv(CREDENTIAL_HELPER_ENV, writeHelper(`echo '{"ServerURL": "https://exchange.example.com/v1", "Username": "myorg/myuser", "Secret": "mypw"}'`))
+	defer os.Unsetenv(CREDENTIAL_HELPER_ENV)
+
+	if creds := GetUserAuth(""); creds != "myorg/myuser:mypw" {
+		t.Errorf("expected the credentials of the helper, found %v", creds)
+	} else if creds := GetExchangeAuth("", ""); creds != "myorg/myuser:mypw" {
+		t.Errorf("expected the credentials of the helper, found %v", creds)
+	} else if creds := GetUserAuth("other/user:pw"); creds != "other/user:pw" {
+		t.Errorf("the -u flag should be used first, found %v", creds)
+	}
+	if out, err := ioutil.ReadFile(calls); err != nil || strings.TrimSpace(string(out)) != "get https://exchange.example.com/v1" {
+		t.Errorf("the helper should be run once with the exchange url, found %q %v", out, err)
+	}
+
+	// a helper without the credentials is the same as no credentials
+	writeHelper(`echo "credentials not found in native keychain"; exit 1`)
+	if err := Try(func() { RequiredUserAuth("") }); ExitCode(err) != CLI_INPUT_ERROR {
+		t.Errorf("expected an input error, found %v", err)
+	}
+
+	writeHelper(`echo "the keyring is locked" >&2; exit 3`)
+	if err := Try(func() { GetUserAuth("") }); ExitCode(err) != EXEC_CMD_ERROR || !strings.Contains(err.Error(), "the keyring is locked") {
+		t.Errorf("expected the error of the helper, found %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package cliutils
+
+import (
+	"encoding/json"
+	"github.com/open-horizon/anax/i18n"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UnmarshalResponse decodes the json body of a response into the structure. Fields of the body that the structure
+// does not have are ignored, as json.Unmarshal does, but they are listed in the verbose output so that it is visible
+// when a newer exchange returns data that this version of the CLI drops.
+func UnmarshalResponse(body []byte, structure interface{}, apiMsg string) error {
+	if err := json.Unmarshal(body, structure); err != nil {
+		return err
+	}
+	if Opts.Verbose != nil && *Opts.Verbose {
+		if unknown := UnknownFields(body, structure); len(unknown) > 0 {
+			Verbose(i18n.GetMessagePrinter().Sprintf("the response from %s has fields that this version of hzn does not know, they are ignored: %s", apiMsg, strings.Join(unknown, ", ")))
+		}
+	}
+	return nil
+}
+
+// UnknownFields returns the fields of the json body that were dropped when it was decoded into the structure, sorted.
+// A field of a nested object is named with dots and an element of a list with its index, for example
+// services.myorg/svc.deployment. The structure is encoded again and compared with the body, so a field is unknown
+// when it has a value in the body but not in the structure.
+func UnknownFields(body []byte, structure interface{}) []string {
+	var original interface{}
+	if err := json.Unmarshal(body, &original); err != nil {
+		return nil
+	}
+	var decoded interface{}
+	if decodedBytes, err := json.Marshal(structure); err != nil {
+		return nil
+	} else if err := json.Unmarshal(decodedBytes, &decoded); err != nil {
+		return nil
+	}
+
+	unknown := make([]string, 0)
+	compareFields("", original, decoded, &unknown)
+	sort.Strings(unknown)
+	return unknown
+}
+
+func compareFields(prefix string, original interface{}, decoded interface{}, unknown *[]string) {
+	switch o := original.(type) {
+	case map[string]interface{}:
+		d, ok := decoded.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for name, value := range o {
+			if decodedValue, found := lookupField(d, name); found {
+				compareFields(joinField(prefix, name), value, decodedValue, unknown)
+			} else if !isEmptyValue(value) {
+				*unknown = append(*unknown, joinField(prefix, name))
+			}
+		}
+	case []interface{}:
+		if d, ok := decoded.([]interface{}); ok && len(d) == len(o) {
+			for i := range o {
+				compareFields(joinField(prefix, strconv.Itoa(i)), o[i], d[i], unknown)
+			}
+		}
+	}
+}
+
+// json.Unmarshal matches the names of fields without regard to case, so the lookup does the same.
+func lookupField(fields map[string]interface{}, name string) (interface{}, bool) {
+	if value, ok := fields[name]; ok {
+		return value, true
+	}
+	for n, value := range fields {
+		if strings.EqualFold(n, name) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// An empty value can be left out of the encoded structure by omitempty, so it is not reported.
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func joinField(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,36 @@
+// +build unit
+
+package cliutils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_UnknownFields(t *testing.T) {
+	type service struct {
+		URL     string `json:"url"`
+		Version string `json:"version,omitempty"`
+	}
+	type services struct {
+		Services  map[string]service `json:"services"`
+		List      []service          `json:"list"`
+		LastIndex int                `json:"lastIndex"`
+	}
+
+	body := []byte(`{"services": {"myorg/svc": {"url": "svc", "Version": "1.0", "clusterDeployment": "yaml", "owner": ""}},
+		"list": [{"url": "a", "arch": "amd64"}], "lastIndex": 0, "newField": {"a": 1}}`)
+
+	var s services
+	if err := UnmarshalResponse(body, &s, "GET test"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if s.Services["myorg/svc"].Version != "1.0" {
+		t.Errorf("the known fields were not decoded: %v", s)
+	}
+
+	expected := []string{"list.0.arch", "newField", "services.myorg/svc.clusterDeployment"}
+	if unknown := UnknownFields(body, &s); !reflect.DeepEqual(unknown, expected) {
+		t.Errorf("expected unknown fields %v, got %v", expected, unknown)
+	}
+}
@@ -0,0 +1,62 @@
+package cliutils
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// DiffLines returns a line diff of the lines of before and after, from their longest common subsequence. The lines
+// only in before are prefixed with "- ", the lines only in after with "+ " and the common lines with "  ". Nil is
+// returned when the lines are the same.
+func DiffLines(before []string, after []string) []string {
+	// lcs[i][j] is the length of the longest common subsequence of before[i:] and after[j:]
+	lcs := make([][]int, len(before)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	if lcs[0][0] == len(before) && len(before) == len(after) {
+		return nil
+	}
+
+	diff := make([]string, 0, len(before)+len(after)-lcs[0][0])
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		if i < len(before) && j < len(after) && before[i] == after[j] {
+			diff = append(diff, "  "+before[i])
+			i++
+			j++
+		} else if i < len(before) && (j == len(after) || lcs[i+1][j] >= lcs[i][j+1]) {
+			diff = append(diff, "- "+before[i])
+			i++
+		} else {
+			diff = append(diff, "+ "+after[j])
+			j++
+		}
+	}
+	return diff
+}
+
+// DiffJson returns a line diff, like DiffLines, of the indented json of before and after. Nil is returned when their
+// json is the same.
+func DiffJson(before interface{}, after interface{}) ([]string, error) {
+	beforeBytes, err := json.MarshalIndent(before, "", JSON_INDENT)
+	if err != nil {
+		return nil, err
+	}
+	afterBytes, err := json.MarshalIndent(after, "", JSON_INDENT)
+	if err != nil {
+		return nil, err
+	}
+	return DiffLines(strings.Split(string(beforeBytes), "\n"), strings.Split(string(afterBytes), "\n")), nil
+}
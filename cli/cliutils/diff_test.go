@@ -0,0 +1,44 @@
+// +build unit
+
+package cliutils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_DiffLines(t *testing.T) {
+	tests := []struct {
+		before []string
+		after  []string
+		diff   []string
+	}{
+		{[]string{"a", "b"}, []string{"a", "b"}, nil},
+		{[]string{}, []string{}, nil},
+		{[]string{"a", "b", "c"}, []string{"a", "c"}, []string{"  a", "- b", "  c"}},
+		{[]string{"a", "c"}, []string{"a", "b", "c"}, []string{"  a", "+ b", "  c"}},
+		{[]string{"a", "b", "c"}, []string{"a", "x", "c"}, []string{"  a", "- b", "+ x", "  c"}},
+		{[]string{}, []string{"a"}, []string{"+ a"}},
+		{[]string{"a"}, []string{}, []string{"- a"}},
+	}
+	for _, test := range tests {
+		if diff := DiffLines(test.before, test.after); !reflect.DeepEqual(diff, test.diff) {
+			t.Errorf("DiffLines(%v, %v) = %q, expected %q", test.before, test.after, diff, test.diff)
+		}
+	}
+}
+
+func Test_DiffJson(t *testing.T) {
+	type pattern struct {
+		Label  string `json:"label"`
+		Public bool   `json:"public"`
+	}
+	if diff, err := DiffJson(pattern{"p", false}, pattern{"p", false}); err != nil || diff != nil {
+		t.Errorf("expected no diff, got %q %v", diff, err)
+	}
+	diff, err := DiffJson(pattern{"p", false}, pattern{"p", true})
+	expected := []string{"  {", `    "label": "p",`, `-   "public": false`, `+   "public": true`, "  }"}
+	if err != nil || !reflect.DeepEqual(diff, expected) {
+		t.Errorf("DiffJson = %q %v, expected %q", diff, err, expected)
+	}
+}
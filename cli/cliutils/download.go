@@ -0,0 +1,193 @@
+package cliutils
+
+import (
+	"fmt"
+	"github.com/open-horizon/anax/i18n"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// How often the progress of a download is reported.
+const DOWNLOAD_PROGRESS_INTERVAL = 500 * time.Millisecond
+
+// A writer that the GET helpers stream a response body to. It reports the progress of the download to progress,
+// when it is set, at most every DOWNLOAD_PROGRESS_INTERVAL.
+type downloadWriter struct {
+	w        io.Writer
+	progress io.Writer
+	name     string
+	size     int64 // the size of the body, -1 when the response has no Content-Length
+	written  int64
+	reported time.Time
+}
+
+func (d *downloadWriter) Write(p []byte) (int, error) {
+	n, err := d.w.Write(p)
+	d.written += int64(n)
+	if d.progress != nil && time.Since(d.reported) >= DOWNLOAD_PROGRESS_INTERVAL {
+		d.report("\r")
+	}
+	return n, err
+}
+
+func (d *downloadWriter) report(prefix string) {
+	d.reported = time.Now()
+	msgPrinter := i18n.GetMessagePrinter()
+	if d.size > 0 {
+		fmt.Fprint(d.progress, prefix+msgPrinter.Sprintf("Downloading %v: %v of %v (%d%%)", d.name, formatBytes(d.written), formatBytes(d.size), d.written*100/d.size))
+	} else {
+		fmt.Fprint(d.progress, prefix+msgPrinter.Sprintf("Downloading %v: %v", d.name, formatBytes(d.written)))
+	}
+}
+
+// Called by the GET helpers before they stream the body to a writer.
+func startDownload(w io.Writer, size int64) {
+	if d, ok := w.(*downloadWriter); ok {
+		d.size = size
+		if d.progress != nil {
+			d.report("")
+		}
+	}
+}
+
+// Report the end of a download.
+func (d *downloadWriter) finish() {
+	if d.progress != nil {
+		d.report("\r")
+		fmt.Fprintln(d.progress)
+	}
+	Verbose(i18n.GetMessagePrinter().Sprintf("Downloaded %v bytes to %v", d.written, d.name))
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// A download can take longer than the timeout of a request, so the client that streams a body to a writer has no
+// overall timeout. The transport still times out the connection and the response headers, and the body fails when no
+// data is received for the request timeout.
+func downloadClient(httpClient *http.Client) (idleTimeout time.Duration) {
+	idleTimeout, httpClient.Timeout = httpClient.Timeout, 0
+	return
+}
+
+// A reader of a response body that closes the body, which ends a blocked read, when no data is received for the
+// timeout.
+type idleTimeoutReader struct {
+	body     io.ReadCloser
+	r        io.Reader // the body, or a reader that wraps it
+	timeout  time.Duration
+	timer    *time.Timer
+	timedOut int32
+}
+
+func newIdleTimeoutReader(body io.ReadCloser, r io.Reader, timeout time.Duration) *idleTimeoutReader {
+	i := &idleTimeoutReader{body: body, r: r, timeout: timeout}
+	if timeout > 0 {
+		i.timer = time.AfterFunc(timeout, func() {
+			atomic.StoreInt32(&i.timedOut, 1)
+			body.Close()
+		})
+	}
+	return i
+}
+
+func (i *idleTimeoutReader) Read(p []byte) (int, error) {
+	n, err := i.r.Read(p)
+	if atomic.LoadInt32(&i.timedOut) == 1 {
+		return n, fmt.Errorf(i18n.GetMessagePrinter().Sprintf("no data was received for %v", i.timeout))
+	}
+	if i.timer != nil {
+		i.timer.Reset(i.timeout)
+	}
+	return n, err
+}
+
+// Copies the body to w, and stops the timer.
+func (i *idleTimeoutReader) copyTo(w io.Writer) (int64, error) {
+	if i.timer != nil {
+		defer i.timer.Stop()
+	}
+	return io.Copy(w, i)
+}
+
+// Returns true if the progress of the downloads is shown, when stderr is a terminal. It is not written to logs.
+var showDownloadProgress = func() bool {
+	info, err := os.Stderr.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Runs get with a writer to the file at filePath, or to stdout when it is -. The body is written to a .part file
+// that is renamed when the download is done, so that a failed download does not leave a partial file behind it.
+func downloadToFile(filePath string, get func(w io.Writer) (int, error)) (httpCode int) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	d := &downloadWriter{name: filePath, size: -1}
+	if showDownloadProgress() {
+		d.progress = os.Stderr
+	}
+
+	if filePath == "-" {
+		d.w, d.name = os.Stdout, "stdout"
+		var err error
+		httpCode, err = get(d)
+		exitOnError(err)
+		d.finish()
+		return
+	}
+
+	partFile := filePath + ".part"
+	file, err := os.OpenFile(partFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		Fatal(FILE_IO_ERROR, msgPrinter.Sprintf("unable to create %v: %v", partFile, err))
+	}
+	d.w = file
+
+	if httpCode, err = get(d); err != nil {
+		file.Close()
+		os.Remove(partFile)
+		exitOnError(err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(partFile)
+		Fatal(FILE_IO_ERROR, msgPrinter.Sprintf("unable to write %v: %v", partFile, err))
+	}
+	if isEmptyDownload(httpCode) {
+		// the body is not the content of the file, e.g. the body of a 404 that the caller handles
+		os.Remove(partFile)
+		return
+	}
+	if err := os.Rename(partFile, filepath.Clean(filePath)); err != nil {
+		os.Remove(partFile)
+		Fatal(FILE_IO_ERROR, msgPrinter.Sprintf("unable to rename %v to %v: %v", partFile, filePath, err))
+	}
+	d.finish()
+	return
+}
+
+// Returns true for the HTTP codes whose body is not written to the file.
+func isEmptyDownload(httpCode int) bool {
+	return httpCode < 200 || httpCode >= 300
+}
+
+// ExchangeGetToFile runs a GET on the exchange api, like ExchangeGet, and streams the response body to the file at
+// filePath, or to stdout when it is -, instead of reading it into memory. The progress is shown on stderr when it is
+// a terminal. The file is not written when the http code is not a 2xx one.
+func ExchangeGetToFile(service string, urlBase string, urlSuffix string, credentials string, goodHttpCodes []int, filePath string) (httpCode int) {
+	return downloadToFile(filePath, func(w io.Writer) (int, error) {
+		return ExchangeGetE(service, urlBase, urlSuffix, credentials, goodHttpCodes, w)
+	})
+}
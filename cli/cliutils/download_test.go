@@ -0,0 +1,115 @@
+// +build unit
+
+package cliutils
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ExchangeGetToFile(t *testing.T) {
+	body := strings.Repeat("0123456789", 100000)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orgs/myorg/big", "/eventlog":
+			w.Write([]byte(body))
+		case "/orgs/myorg/bad":
+			http.Error(w, `{"code":"bad-input","msg":"bad input"}`, http.StatusBadRequest)
+		default:
+			http.Error(w, `{"code":"not-found","msg":"not found"}`, http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	verbose, dryRun := false, false
+	Opts.Verbose, Opts.IsDryRun = &verbose, &dryRun
+	defer func() { Opts.Verbose, Opts.IsDryRun = nil, nil }()
+
+	dir, err := ioutil.TempDir("", "cliutils-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "big.json")
+
+	if code := ExchangeGetToFile("Exchange", ts.URL, "orgs/myorg/big", "myorg/myuser:mypw", []int{200}, file); code != 200 {
+		t.Errorf("expected 200, found %v", code)
+	} else if content, err := ioutil.ReadFile(file); err != nil || string(content) != body {
+		t.Errorf("the body should be written to the file, found %v bytes, %v", len(content), err)
+	}
+
+	// a code that the caller handles does not write the file, and a failure does not leave a partial file
+	missing := filepath.Join(dir, "missing.json")
+	if code := ExchangeGetToFile("Exchange", ts.URL, "orgs/myorg/missing", "myorg/myuser:mypw", []int{200, 404}, missing); code != 404 {
+		t.Errorf("expected 404, found %v", code)
+	} else if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Errorf("the file should not be written for a 404")
+	}
+	err = Try(func() { ExchangeGetToFile("Exchange", ts.URL, "orgs/myorg/bad", "myorg/myuser:mypw", []int{200}, missing) })
+	if ExitCode(err) != HTTP_ERROR {
+		t.Errorf("expected exit code %v, found %v", HTTP_ERROR, err)
+	} else if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("only the downloaded file should be left, found %v", files)
+	}
+
+}
+
+func Test_downloadWriter_progress(t *testing.T) {
+	var out, progress bytes.Buffer
+	d := &downloadWriter{w: &out, progress: &progress, name: "dump.json", size: -1}
+
+	startDownload(d, 4096)
+	d.Write(make([]byte, 1024))
+	d.finish()
+
+	if out.Len() != 1024 {
+		t.Errorf("the data should be written, found %v bytes", out.Len())
+	} else if !strings.Contains(progress.String(), "Downloading dump.json: 1.0 KiB of 4.0 KiB (25%)") {
+		t.Errorf("wrong progress %q", progress.String())
+	}
+
+	for n, expected := range map[int64]string{10: "10 B", 1536: "1.5 KiB", 5 * 1024 * 1024: "5.0 MiB"} {
+		if formatBytes(n) != expected {
+			t.Errorf("%v bytes should be %v, found %v", n, expected, formatBytes(n))
+		}
+	}
+}
+
+func Test_idleTimeoutReader(t *testing.T) {
+	httpClient := &http.Client{Timeout: time.Minute}
+	if idle := downloadClient(httpClient); idle != time.Minute || httpClient.Timeout != 0 {
+		t.Errorf("the download should have no overall timeout and the request timeout as idle timeout, found %v %v", httpClient.Timeout, idle)
+	}
+
+	// the body sends some data and then stalls
+	pr, pw := io.Pipe()
+	go pw.Write([]byte("some data"))
+	var out bytes.Buffer
+	if _, err := newIdleTimeoutReader(pr, pr, 50*time.Millisecond).copyTo(&out); err == nil {
+		t.Errorf("a stalled body should fail")
+	} else if out.String() != "some data" {
+		t.Errorf("the data received before the body stalled should be written, found %q", out.String())
+	}
+
+	// a body that keeps sending data is not ended by the idle timeout
+	pr, pw = io.Pipe()
+	go func() {
+		for i := 0; i < 5; i++ {
+			pw.Write([]byte("x"))
+			time.Sleep(20 * time.Millisecond)
+		}
+		pw.Close()
+	}()
+	out.Reset()
+	if _, err := newIdleTimeoutReader(pr, pr, 50*time.Millisecond).copyTo(&out); err != nil || out.String() != "xxxxx" {
+		t.Errorf("a body that sends data should be read to the end, found %q %v", out.String(), err)
+	}
+}
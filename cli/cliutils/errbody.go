@@ -0,0 +1,89 @@
+package cliutils
+
+import (
+	"encoding/json"
+	"github.com/open-horizon/anax/i18n"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var htmlTagRegex = regexp.MustCompile(`<[^>]*>`)
+
+// GetErrorRespBody reads the body of a failed http response to be displayed in an error message. At most
+// MAX_ERROR_RESPONSE_BODY_SIZE bytes are read, the rest of the body is left unread. The body is shown according to its
+// content type: the error message is taken out of the json error responses of the exchange and the anax api, the tags
+// are dropped from html, and a binary body is only described.
+func GetErrorRespBody(resp *http.Response) string {
+	if resp == nil || resp.Body == nil {
+		return ""
+	}
+
+	bodyBytes, truncated, err := readLimitedBody(resp.Body, MAX_ERROR_RESPONSE_BODY_SIZE)
+	if err != nil {
+		Verbose(i18n.GetMessagePrinter().Sprintf("Error reading HTTP response, error %v", err))
+	}
+	if truncated {
+		Warning(i18n.GetMessagePrinter().Sprintf("the HTTP error response is larger than %d bytes, it has been truncated.", MAX_ERROR_RESPONSE_BODY_SIZE))
+	}
+	return formatErrorBody(resp.Header.Get("Content-Type"), bodyBytes, truncated)
+}
+
+// formatErrorBody returns the text of an error response body with the given content type. The content type is detected
+// from the body when it is not given.
+func formatErrorBody(contentType string, body []byte, truncated bool) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	text := ""
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		text = formatJsonErrorBody(body, truncated)
+	case mediaType == "text/html":
+		text = strings.Join(strings.Fields(htmlTagRegex.ReplaceAllString(string(body), " ")), " ")
+	case strings.HasPrefix(mediaType, "text/") || mediaType == "application/xml":
+		text = strings.TrimSpace(string(body))
+	default:
+		return i18n.GetMessagePrinter().Sprintf("%d bytes of %s", len(body), mediaType)
+	}
+
+	if truncated {
+		text += "..."
+	}
+	return text
+}
+
+// The exchange returns errors as {"code": ..., "msg": ...} and the anax api as {"error": ..., "input": ...}. For those
+// only the error is returned, any other json is returned as it is.
+func formatJsonErrorBody(body []byte, truncated bool) string {
+	if truncated {
+		return strings.TrimSpace(string(body))
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return strings.TrimSpace(string(body))
+	}
+
+	if msg, ok := fields["msg"].(string); ok && msg != "" {
+		if code, ok := fields["code"].(string); ok && code != "" {
+			return code + ", " + msg
+		}
+		return msg
+	} else if errMsg, ok := fields["error"].(string); ok && errMsg != "" {
+		if input, ok := fields["input"].(string); ok && input != "" {
+			return i18n.GetMessagePrinter().Sprintf("%s (input: %s)", errMsg, input)
+		}
+		return errMsg
+	}
+	return strings.TrimSpace(string(body))
+}
@@ -0,0 +1,87 @@
+// +build unit
+
+package cliutils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_formatErrorBody(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		truncated   bool
+		expected    string
+	}{
+		{"application/json", `{"code":"access-denied","msg":"Access denied"}`, false, "access-denied, Access denied"},
+		{"application/json; charset=UTF-8", `{"error":"node is not configured","input":"token"}`, false, "node is not configured (input: token)"},
+		{"application/json", `{"other":"field"}`, false, `{"other":"field"}`},
+		{"application/json", `{"msg":"cut`, true, `{"msg":"cut...`},
+		{"text/html", "<html><body><h1>502 Bad Gateway</h1>\n<p>nginx</p></body></html>", false, "502 Bad Gateway nginx"},
+		{"text/plain", "  not found\n", false, "not found"},
+		{"", "plain text without a content type", false, "plain text without a content type"},
+		{"application/octet-stream", "\x00\x01\x02", false, "3 bytes of application/octet-stream"},
+		{"application/json", "", false, ""},
+	}
+
+	for _, test := range tests {
+		if text := formatErrorBody(test.contentType, []byte(test.body), test.truncated); text != test.expected {
+			t.Errorf("content type %v body %v: expected %v, got %v", test.contentType, test.body, test.expected, text)
+		}
+	}
+}
+
+func Test_GetErrorRespBody_limit(t *testing.T) {
+	body := strings.Repeat("x", MAX_ERROR_RESPONSE_BODY_SIZE+10)
+	resp := &http.Response{Header: http.Header{"Content-Type": []string{"text/plain"}}, Body: ioutil.NopCloser(bytes.NewBufferString(body))}
+
+	text := GetErrorRespBody(resp)
+	if len(text) != MAX_ERROR_RESPONSE_BODY_SIZE+3 || !strings.HasSuffix(text, "...") {
+		t.Errorf("expected the body to be truncated to %v bytes, got %v bytes", MAX_ERROR_RESPONSE_BODY_SIZE, len(text))
+	}
+
+	if text := GetErrorRespBody(nil); text != "" {
+		t.Errorf("expected no text for a nil response, got %v", text)
+	}
+}
+
+func Test_errorBody_notFormatted(t *testing.T) {
+	body := "100% of the %d nodes failed: %s"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		if r.URL.Path == "/expected" {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	verbose, dryRun := false, false
+	Opts.Verbose, Opts.IsDryRun = &verbose, &dryRun
+	defer func() { Opts.Verbose, Opts.IsDryRun = nil, nil }()
+	os.Setenv("HORIZON_URL", ts.URL)
+	defer os.Unsetenv("HORIZON_URL")
+
+	// the body of the response is in the error as it was received
+	if _, err := HorizonDelete("expected", []int{204}, []int{400}, true); err == nil || err.Error() != body {
+		t.Errorf("expected the error %q, found %v", body, err)
+	}
+	if _, err := HorizonDelete("other", []int{204}, []int{400}, true); err == nil || !strings.HasSuffix(err.Error(), ": "+body) {
+		t.Errorf("expected the error to end with %q, found %v", body, err)
+	}
+	if _, _, err := HorizonPutPost(http.MethodPost, "other", []int{201}, nil, false); err == nil || !strings.HasSuffix(err.Error(), ": "+body) {
+		t.Errorf("expected the error to end with %q, found %v", body, err)
+	}
+	var out map[string]interface{}
+	if _, err := HorizonGet("other", []int{200}, &out, true); err == nil || !strings.HasSuffix(err.Error(), ", "+body) {
+		t.Errorf("expected the error to end with %q, found %v", body, err)
+	}
+}
@@ -0,0 +1,78 @@
+package cliutils
+
+import (
+	"fmt"
+	"github.com/open-horizon/anax/i18n"
+	"strings"
+	"sync/atomic"
+)
+
+// CLIError is an error that would have ended the command, with the exit code the command would have exited with.
+// It is returned by Try and by the helpers with an E suffix, so that programs and tests that embed this package can
+// handle the failures themselves.
+type CLIError struct {
+	ExitCode int
+	Msg      string
+}
+
+func (e *CLIError) Error() string {
+	return e.Msg
+}
+
+// The failures of an interrupted command are caused by the cancelled requests, so they are all errors with the
+// INTERRUPTED exit code.
+func newCLIError(exitCode int, msg string, args ...interface{}) *CLIError {
+	if Interrupted() {
+		return &CLIError{ExitCode: INTERRUPTED, Msg: i18n.GetMessagePrinter().Sprintf("The command was interrupted.")}
+	} else if len(args) != 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+	return &CLIError{ExitCode: exitCode, Msg: strings.TrimSuffix(msg, "\n")}
+}
+
+// The number of calls to Try in progress.
+var tryDepth int32
+
+// Returns true if a function is running in Try.
+func isTrying() bool {
+	return atomic.LoadInt32(&tryDepth) > 0
+}
+
+// Try runs f, returning the error of the first call to Fatal instead of exiting. A declined ConfirmRemove is
+// returned as a CLIError with exit code 0. Try is for the code that has no E variant to call, and f must not start
+// goroutines that call Fatal, because the panic of a call to Fatal is only recovered in the goroutine that called
+// Try. The concurrent operations, like the ones of RunBulk, use the E variants of the helpers instead.
+func Try(f func()) (err error) {
+	atomic.AddInt32(&tryDepth, 1)
+	defer func() {
+		atomic.AddInt32(&tryDepth, -1)
+		if r := recover(); r != nil {
+			if cliErr, ok := r.(*CLIError); ok {
+				err = cliErr
+			} else {
+				panic(r)
+			}
+		}
+	}()
+
+	f()
+	return nil
+}
+
+// ExitCode returns the exit code of a CLIError, or CLI_GENERAL_ERROR for any other error.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	} else if cliErr, ok := err.(*CLIError); ok {
+		return cliErr.ExitCode
+	}
+	return CLI_GENERAL_ERROR
+}
+
+// Ends the command with the exit code and message of an error returned by the E variant of a helper. The error is
+// returned to Try when it runs the helper.
+func exitOnError(err error) {
+	if err != nil {
+		Fatal(ExitCode(err), err.Error())
+	}
+}
@@ -0,0 +1,145 @@
+// +build unit
+
+package cliutils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_Try(t *testing.T) {
+
+	if err := Try(func() {}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	err := Try(func() {
+		Fatal(CLI_INPUT_ERROR, "bad input %v", "x")
+		t.Errorf("Fatal should end the function")
+	})
+	if cliErr, ok := err.(*CLIError); !ok {
+		t.Errorf("expected a CLIError, found %v", err)
+	} else if cliErr.ExitCode != CLI_INPUT_ERROR || cliErr.Msg != "bad input x" {
+		t.Errorf("wrong error %v", cliErr)
+	} else if ExitCode(err) != CLI_INPUT_ERROR {
+		t.Errorf("wrong exit code %v", ExitCode(err))
+	} else if isTrying() {
+		t.Errorf("Try should be done")
+	}
+
+	// nested calls return to the innermost Try
+	outer := Try(func() {
+		if inner := Try(func() { Fatal(NOT_FOUND, "not found") }); ExitCode(inner) != NOT_FOUND {
+			t.Errorf("wrong inner error %v", inner)
+		}
+	})
+	if outer != nil {
+		t.Errorf("unexpected outer error %v", outer)
+	}
+}
+
+func Test_RunBulk_E(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/orgs/b" {
+			http.Error(w, `{"code":"not-found","msg":"not found"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"orgs":{}}`))
+	}))
+	defer ts.Close()
+
+	verbose, dryRun := false, false
+	Opts.Verbose, Opts.IsDryRun = &verbose, &dryRun
+	defer func() { Opts.Verbose, Opts.IsDryRun = nil, nil }()
+
+	// the E variants return the failures of the concurrent operations without Try
+	results := RunBulk([]string{"a", "b", "c"}, 2, func(id string) error {
+		var out map[string]interface{}
+		_, err := ExchangeGetE("Exchange", ts.URL, "orgs/"+id, "myorg/u:p", []int{200}, &out)
+		return err
+	})
+	if results[0].Error != nil || results[2].Error != nil {
+		t.Errorf("only b should fail, found %v", results)
+	} else if ExitCode(results[1].Error) != HTTP_ERROR || !strings.Contains(results[1].Error.Error(), "bad HTTP code 404") {
+		t.Errorf("the http error of b should be its error, found %v", results[1].Error)
+	} else if isTrying() {
+		t.Errorf("the E variants should not run in Try")
+	}
+}
+
+func Test_ExchangeGetE(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orgs/myorg":
+			w.Write([]byte(`{"orgs":{"myorg":{}}}`))
+		case "/orgs/bad":
+			w.Write([]byte(`not json`))
+		default:
+			http.Error(w, `{"code":"not-found","msg":"not found"}`, http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	verbose, dryRun := false, false
+	Opts.Verbose, Opts.IsDryRun = &verbose, &dryRun
+	defer func() { Opts.Verbose, Opts.IsDryRun = nil, nil }()
+
+	var out map[string]interface{}
+	if code, err := ExchangeGetE("Exchange", ts.URL, "orgs/myorg", "myorg/u:p", []int{200}, &out); err != nil || code != 200 {
+		t.Errorf("unexpected result %v %v", code, err)
+	} else if code, err := ExchangeGetE("Exchange", ts.URL, "orgs/other", "myorg/u:p", []int{200}, &out); ExitCode(err) != HTTP_ERROR {
+		t.Errorf("expected an http error, found %v %v", code, err)
+	} else if code, err := ExchangeGetE("Exchange", ts.URL, "orgs/other", "myorg/u:p", []int{200, 404}, &out); err != nil || code != 404 {
+		t.Errorf("a good code should not be an error, found %v %v", code, err)
+	} else if _, err := ExchangeGetE("Exchange", ts.URL, "orgs/bad", "myorg/u:p", []int{200}, &out); ExitCode(err) != JSON_PARSING_ERROR {
+		t.Errorf("expected a parsing error, found %v", err)
+	}
+}
+
+func Test_ExchangePatch(t *testing.T) {
+	patches := []map[string]interface{}{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			http.Error(w, `{"code":"bad-input","msg":"wrong method"}`, http.StatusBadRequest)
+			return
+		}
+		var patch map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil || len(patch) != 1 {
+			http.Error(w, `{"code":"bad-input","msg":"one attribute is allowed"}`, http.StatusBadRequest)
+			return
+		}
+		patches = append(patches, patch)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"code":"ok","msg":"node attribute updated"}`))
+	}))
+	defer ts.Close()
+
+	verbose, dryRun := false, false
+	Opts.Verbose, Opts.IsDryRun = &verbose, &dryRun
+	defer func() { Opts.Verbose, Opts.IsDryRun = nil, nil }()
+
+	if code, err := ExchangePatchE("Exchange", ts.URL, "orgs/myorg/nodes/n1", "myorg/u:p", []int{201}, map[string]string{"name": "n1"}, nil); err != nil || code != 201 {
+		t.Errorf("unexpected result %v %v", code, err)
+	} else if code, err := ExchangePatchE("Exchange", ts.URL, "orgs/myorg/nodes/n1", "myorg/u:p", []int{201}, map[string]string{"name": "n1", "arch": "arm"}, nil); ExitCode(err) != HTTP_ERROR {
+		t.Errorf("expected an http error, found %v %v", code, err)
+	}
+
+	patches = patches[:0]
+	if err := Try(func() {
+		ExchangePatchFields("Exchange", ts.URL, "orgs/myorg/nodes/n1", "myorg/u:p", []int{201}, map[string]interface{}{"name": "n1", "arch": "arm"})
+	}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if len(patches) != 2 || patches[0]["arch"] != "arm" || patches[1]["name"] != "n1" {
+		t.Errorf("expected one patch per field in order, found %v", patches)
+	}
+
+	// a dry run does not change the resource
+	dryRun = true
+	patches = patches[:0]
+	if code, err := ExchangePatchE("Exchange", ts.URL, "orgs/myorg/nodes/n1", "myorg/u:p", []int{201}, map[string]string{"name": "n1"}, nil); err != nil || code != 201 || len(patches) != 0 {
+		t.Errorf("unexpected dry run result %v %v %v", code, err, patches)
+	}
+}
@@ -0,0 +1,90 @@
+package cliutils
+
+import (
+	"errors"
+	"github.com/open-horizon/anax/i18n"
+	"net"
+	"strings"
+	"sync"
+)
+
+// The mirrored exchanges of a comma separated exchange url, and the one that the requests are sent to. It is the first
+// one until it can't be reached, then the next one that can be reached is used for the rest of the command.
+var exchangeMirrorsLock sync.Mutex
+var exchangeMirrors []string
+var currentMirror int
+
+// Set the mirrored exchanges of an exchange url, and return the one to use first.
+func setExchangeMirrors(exchUrl string) string {
+	exchangeMirrorsLock.Lock()
+	defer exchangeMirrorsLock.Unlock()
+
+	exchangeMirrors, currentMirror = nil, 0
+	for _, u := range strings.Split(exchUrl, ",") {
+		if u = strings.TrimSuffix(strings.TrimSpace(u), "/"); u != "" {
+			exchangeMirrors = append(exchangeMirrors, u)
+		}
+	}
+	if len(exchangeMirrors) == 0 {
+		return ""
+	}
+	return exchangeMirrors[0]
+}
+
+// Returns the mirrored exchange that the requests are sent to.
+func currentExchangeMirror() string {
+	exchangeMirrorsLock.Lock()
+	defer exchangeMirrorsLock.Unlock()
+	if len(exchangeMirrors) == 0 {
+		return ""
+	}
+	return exchangeMirrors[currentMirror]
+}
+
+// Returns the index of the mirrored exchange that a request url is for, or -1 if it is not for one of them.
+func mirrorOf(reqUrl string) int {
+	for i, mirror := range exchangeMirrors {
+		if reqUrl == mirror || strings.HasPrefix(reqUrl, mirror+"/") || strings.HasPrefix(reqUrl, mirror+"?") {
+			return i
+		}
+	}
+	return -1
+}
+
+// Returns the url of a request to one of the mirrored exchanges, sent to the mirror that is used now. The callers
+// may have got the exchange url before a failover.
+func exchangeMirrorUrl(reqUrl string) string {
+	exchangeMirrorsLock.Lock()
+	defer exchangeMirrorsLock.Unlock()
+	if i := mirrorOf(reqUrl); i >= 0 && i != currentMirror {
+		return exchangeMirrors[currentMirror] + strings.TrimPrefix(reqUrl, exchangeMirrors[i])
+	}
+	return reqUrl
+}
+
+// Move on to the next mirrored exchange after a request to one of them could not connect. Returns the url of the
+// request on the next mirror, and false if the request url is not for a mirror or there is no other mirror to try.
+// The mirrors tried counts the ones that the request already failed on.
+func failoverExchangeUrl(reqUrl string, mirrorsTried int) (string, bool) {
+	exchangeMirrorsLock.Lock()
+	defer exchangeMirrorsLock.Unlock()
+
+	i := mirrorOf(reqUrl)
+	if i < 0 || mirrorsTried >= len(exchangeMirrors) {
+		return reqUrl, false
+	}
+	next := (i + 1) % len(exchangeMirrors)
+	if i == currentMirror {
+		currentMirror = next
+	}
+	Verbose(i18n.GetMessagePrinter().Sprintf("Unable to connect to the exchange %v, trying the exchange %v.", exchangeMirrors[i], exchangeMirrors[next]))
+	return exchangeMirrors[next] + strings.TrimPrefix(reqUrl, exchangeMirrors[i]), true
+}
+
+// Returns true if a request failed because it could not connect to the server: the host name was not found, the
+// connection was refused or it timed out. The server got no part of the request, so it can be sent to a mirror.
+func isConnectionError(err error) bool {
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	return (errors.As(err, &opErr) && opErr.Op == "dial") || errors.As(err, &dnsErr)
+}
@@ -0,0 +1,85 @@
+// +build unit
+
+package cliutils
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func Test_ExchangeGet_failover(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"myorg"}`))
+	}))
+	defer ts.Close()
+
+	// the addresses that refuse the connections
+	downUrl := func() string {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("unable to listen: %v", err)
+		}
+		defer l.Close()
+		return "http://" + l.Addr().String()
+	}
+	down := downUrl()
+
+	verbose, dryRun := false, false
+	Opts.Verbose, Opts.IsDryRun = &verbose, &dryRun
+	defer func() { Opts.Verbose, Opts.IsDryRun = nil, nil }()
+
+	os.Setenv("HZN_EXCHANGE_URL", down+"/, "+ts.URL)
+	defer os.Unsetenv("HZN_EXCHANGE_URL")
+	defer ResetExchangeUrl()
+
+	exchUrl := GetExchangeUrl()
+	if exchUrl != down {
+		t.Fatalf("the first exchange should be used first, found %v", exchUrl)
+	}
+
+	var org map[string]interface{}
+	if httpCode := ExchangeGet("Exchange", exchUrl, "orgs/myorg", "myorg/myuser:mypw", []int{200}, &org); httpCode != 200 || org["id"] != "myorg" {
+		t.Fatalf("the request should be sent to the next exchange, found %v %v", httpCode, org)
+	}
+
+	// the exchange that works is used for the rest of the command, also for the urls got before the failover
+	if exchUrl := GetExchangeUrl(); exchUrl != ts.URL {
+		t.Errorf("expected the exchange %v, found %v", ts.URL, exchUrl)
+	} else if reqUrl := exchangeMirrorUrl(down + "/orgs/myorg?a=b"); reqUrl != ts.URL+"/orgs/myorg?a=b" {
+		t.Errorf("expected the url on %v, found %v", ts.URL, reqUrl)
+	} else if loc := GetExchangeUrlLocation(); loc != "HZN_EXCHANGE_URL" {
+		t.Errorf("wrong location %v", loc)
+	}
+
+	// when all of them are down, the request fails
+	os.Setenv("HZN_EXCHANGE_URL", down+","+downUrl())
+	os.Setenv("HZN_HTTP_RETRIES", "0")
+	defer os.Unsetenv("HZN_HTTP_RETRIES")
+	ResetExchangeUrl()
+	if _, err := ExchangeGetE("Exchange", GetExchangeUrl(), "orgs/myorg", "myorg/myuser:mypw", []int{200}, &org); ExitCode(err) != HTTP_ERROR {
+		t.Errorf("expected an http error, found %v", err)
+	}
+
+	// the mirrors can be listed in HORIZON_EXCHANGE_URL_BASE instead
+	os.Unsetenv("HZN_EXCHANGE_URL")
+	os.Setenv(EXCHANGE_URL_BASE_ENV, down+","+ts.URL)
+	defer os.Unsetenv(EXCHANGE_URL_BASE_ENV)
+	ResetExchangeUrl()
+	if httpCode := ExchangeGet("Exchange", GetExchangeUrl(), "orgs/myorg", "myorg/myuser:mypw", []int{200}, &org); httpCode != 200 {
+		t.Errorf("the request should be sent to the next exchange, found %v", httpCode)
+	} else if exchUrl := GetExchangeUrl(); exchUrl != ts.URL {
+		t.Errorf("expected the exchange %v, found %v", ts.URL, exchUrl)
+	} else if loc := GetExchangeUrlLocation(); loc != EXCHANGE_URL_BASE_ENV {
+		t.Errorf("wrong location %v", loc)
+	}
+
+	// HZN_EXCHANGE_URL takes precedence
+	os.Setenv("HZN_EXCHANGE_URL", ts.URL)
+	ResetExchangeUrl()
+	if loc := GetExchangeUrlLocation(); loc != "HZN_EXCHANGE_URL" {
+		t.Errorf("wrong location %v", loc)
+	}
+}
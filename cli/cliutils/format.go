@@ -0,0 +1,243 @@
+package cliutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/open-horizon/anax/i18n"
+	yaml "gopkg.in/yaml.v2"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+// The env var with the format of the output of the list and get commands, when --output-format is not given.
+const OUTPUT_FORMAT_ENV = "HZN_OUTPUT_FORMAT"
+
+// The formats that Output can write, in addition to OUTPUT_FORMAT_JSON. The go-template format is given as
+// go-template=<template>, the template is run on the json form of the output, e.g. go-template={{.id}}.
+const (
+	OUTPUT_FORMAT_YAML        = "yaml"
+	OUTPUT_FORMAT_TABLE       = "table"
+	OUTPUT_FORMAT_GO_TEMPLATE = "go-template"
+)
+
+// GetOutputFormat returns the format of the output from --output-format or HZN_OUTPUT_FORMAT, and the template of the
+// go-template format. The format is json when neither is set. Exits with an error if the format is not valid.
+func GetOutputFormat() (format string, tmpl string) {
+	value := ""
+	if Opts.OutputFmt != nil && *Opts.OutputFmt != "" {
+		value = *Opts.OutputFmt
+	} else {
+		value = os.Getenv(OUTPUT_FORMAT_ENV)
+	}
+
+	format = strings.ToLower(strings.TrimSpace(value))
+	if parts := strings.SplitN(value, "=", 2); len(parts) == 2 {
+		format, tmpl = strings.ToLower(strings.TrimSpace(parts[0])), parts[1]
+	}
+	switch format {
+	case "":
+		return OUTPUT_FORMAT_JSON, ""
+	case OUTPUT_FORMAT_JSON, OUTPUT_FORMAT_YAML, OUTPUT_FORMAT_TABLE:
+		if tmpl == "" {
+			return format, ""
+		}
+	case OUTPUT_FORMAT_GO_TEMPLATE:
+		if tmpl != "" {
+			return format, tmpl
+		}
+	}
+	Fatal(CLI_INPUT_ERROR, i18n.GetMessagePrinter().Sprintf("the output format must be %v, %v, %v or %v=<template>, not %v", OUTPUT_FORMAT_JSON, OUTPUT_FORMAT_YAML, OUTPUT_FORMAT_TABLE, OUTPUT_FORMAT_GO_TEMPLATE, value))
+	return "", "" // won't ever happen
+}
+
+// Output writes the output of a list or get command to stdout, in the format from GetOutputFormat. The json format is
+// the indented json that the commands have always written, without escaping <, > and & like DisplayAsJson. The what is
+// the command, for the error message.
+func Output(obj interface{}, what string) {
+	format, tmpl := GetOutputFormat()
+	if err := WriteOutput(os.Stdout, obj, format, tmpl); err != nil {
+		Fatal(JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal '%s' output: %v", what, err))
+	}
+}
+
+// WriteOutput writes an object in a format. The yaml, table and go-template formats use the json form of the object,
+// so that the field names are the same in every format.
+func WriteOutput(w io.Writer, obj interface{}, format string, tmpl string) error {
+	if format == OUTPUT_FORMAT_JSON {
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", JSON_INDENT)
+		return enc.Encode(obj)
+	}
+
+	value, err := outputValue(obj)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case OUTPUT_FORMAT_YAML:
+		yamlBytes, err := yaml.Marshal(value)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(yamlBytes)
+		return err
+	case OUTPUT_FORMAT_TABLE:
+		return writeTable(w, value)
+	case OUTPUT_FORMAT_GO_TEMPLATE:
+		t, err := template.New("output").Option("missingkey=zero").Parse(tmpl)
+		if err != nil {
+			return err
+		}
+		return t.Execute(w, value)
+	}
+	return fmt.Errorf(i18n.GetMessagePrinter().Sprintf("unknown output format %v", format))
+}
+
+// Returns the json form of an object as maps, lists and scalars. The numbers that are integers stay integers, so that
+// yaml does not write them as floats.
+func outputValue(obj interface{}) (interface{}, error) {
+	jsonBytes, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(jsonBytes))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return withNumbers(value), nil
+}
+
+func withNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			v[key] = withNumbers(val)
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = withNumbers(val)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		} else if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	}
+	return value
+}
+
+// Write a table with a header row and one row per resource, with the columns tab aligned. A list has a row per item
+// and a map of objects, which is how most listings are keyed by the resource id, has a row per id. Each top level
+// field is a column, the objects and lists in the fields are written in their json form. Any other object is written
+// as a row per field.
+func writeTable(w io.Writer, value interface{}) error {
+	var ids []string
+	var rows []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		rows = v
+	case map[string]interface{}:
+		for id, val := range v {
+			if _, ok := val.(map[string]interface{}); !ok {
+				ids = nil
+				break
+			}
+			ids = append(ids, id)
+		}
+		if len(ids) != len(v) || len(v) == 0 {
+			return writeFieldTable(w, v)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			rows = append(rows, v[id])
+		}
+	default:
+		_, err := fmt.Fprintln(w, tableValue(v))
+		return err
+	}
+
+	// the columns are the fields of all the rows, sorted
+	columnSet := map[string]bool{}
+	for _, row := range rows {
+		if m, ok := row.(map[string]interface{}); ok {
+			for field := range m {
+				columnSet[field] = true
+			}
+		}
+	}
+	columns := make([]string, 0, len(columnSet))
+	for field := range columnSet {
+		columns = append(columns, field)
+	}
+	sort.Strings(columns)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	header := columns
+	if ids != nil {
+		header = append([]string{"ID"}, header...)
+	} else if len(columns) == 0 {
+		header = []string{"VALUE"}
+	}
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(header, "\t")))
+	for i, row := range rows {
+		cells := make([]string, 0, len(header))
+		if ids != nil {
+			cells = append(cells, ids[i])
+		}
+		if m, ok := row.(map[string]interface{}); ok {
+			for _, column := range columns {
+				cells = append(cells, tableValue(m[column]))
+			}
+		} else {
+			cells = append(cells, tableValue(row))
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// Write an object as a row per field, sorted by the field name.
+func writeFieldTable(w io.Writer, obj map[string]interface{}) error {
+	fields := make([]string, 0, len(obj))
+	for field := range obj {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tVALUE")
+	for _, field := range fields {
+		fmt.Fprintf(tw, "%v\t%v\n", field, tableValue(obj[field]))
+	}
+	return tw.Flush()
+}
+
+// The value of a table cell, on one line. The tabs and line breaks of the strings would break the columns.
+func tableValue(value interface{}) string {
+	cell := ""
+	switch v := value.(type) {
+	case nil:
+	case string:
+		cell = v
+	case map[string]interface{}, []interface{}:
+		buf := new(bytes.Buffer)
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err == nil {
+			cell = strings.TrimSpace(buf.String())
+		}
+	default:
+		cell = fmt.Sprint(v)
+	}
+	return strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(cell)
+}
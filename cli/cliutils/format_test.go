@@ -0,0 +1,82 @@
+// +build unit
+
+package cliutils
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_GetOutputFormat(t *testing.T) {
+	outputFmt := ""
+	Opts.OutputFmt = &outputFmt
+	defer func() { Opts.OutputFmt = nil }()
+	defer os.Unsetenv(OUTPUT_FORMAT_ENV)
+
+	for _, tc := range []struct {
+		flag     string
+		env      string
+		format   string
+		template string
+	}{
+		{"", "", OUTPUT_FORMAT_JSON, ""},
+		{"", "YAML", OUTPUT_FORMAT_YAML, ""},
+		{"table", "yaml", OUTPUT_FORMAT_TABLE, ""},
+		{"go-template={{.id}} = {{.name}}", "", OUTPUT_FORMAT_GO_TEMPLATE, "{{.id}} = {{.name}}"},
+	} {
+		outputFmt = tc.flag
+		os.Setenv(OUTPUT_FORMAT_ENV, tc.env)
+		if format, tmpl := GetOutputFormat(); format != tc.format || tmpl != tc.template {
+			t.Errorf("%q and %q should be %v %q, found %v %q", tc.flag, tc.env, tc.format, tc.template, format, tmpl)
+		}
+	}
+
+	for _, bad := range []string{"xml", "go-template", "go-template=", "json={{.id}}"} {
+		outputFmt = bad
+		if err := Try(func() { GetOutputFormat() }); ExitCode(err) != CLI_INPUT_ERROR {
+			t.Errorf("%v should be an input error, found %v", bad, err)
+		}
+	}
+}
+
+func Test_WriteOutput(t *testing.T) {
+	type node struct {
+		Name  string            `json:"name"`
+		Arch  string            `json:"arch,omitempty"`
+		Port  int               `json:"port"`
+		Props map[string]string `json:"props,omitempty"`
+	}
+	nodes := map[string]node{
+		"myorg/b": {Name: "b", Port: 8080, Props: map[string]string{"a": "b & c"}},
+		"myorg/a": {Name: "a", Arch: "amd64", Port: 8510},
+	}
+
+	for _, tc := range []struct {
+		format   string
+		tmpl     string
+		obj      interface{}
+		expected string
+	}{
+		{OUTPUT_FORMAT_JSON, "", nodes["myorg/b"], "{\n  \"name\": \"b\",\n  \"port\": 8080,\n  \"props\": {\n    \"a\": \"b & c\"\n  }\n}\n"},
+		{OUTPUT_FORMAT_YAML, "", nodes["myorg/b"], "name: b\nport: 8080\nprops:\n  a: b & c\n"},
+		{OUTPUT_FORMAT_TABLE, "", nodes, "ID       ARCH   NAME  PORT  PROPS\nmyorg/a  amd64  a     8510  \nmyorg/b         b     8080  {\"a\":\"b & c\"}\n"},
+		{OUTPUT_FORMAT_TABLE, "", []node{nodes["myorg/a"]}, "ARCH   NAME  PORT\namd64  a     8510\n"},
+		{OUTPUT_FORMAT_TABLE, "", []string{"a", "b\tc"}, "VALUE\na\nb c\n"},
+		{OUTPUT_FORMAT_TABLE, "", map[string]interface{}{"state": "configured", "count": 2}, "FIELD  VALUE\ncount  2\nstate  configured\n"},
+		{OUTPUT_FORMAT_GO_TEMPLATE, `{{range $id, $n := .}}{{$id}}={{$n.port}} {{end}}`, nodes, "myorg/a=8510 myorg/b=8080 "},
+	} {
+		var out bytes.Buffer
+		if err := WriteOutput(&out, tc.obj, tc.format, tc.tmpl); err != nil {
+			t.Errorf("unexpected %v error %v", tc.format, err)
+		} else if out.String() != tc.expected {
+			t.Errorf("the %v output should be %q, found %q", tc.format, tc.expected, out.String())
+		}
+	}
+
+	var out bytes.Buffer
+	if err := WriteOutput(&out, nodes, OUTPUT_FORMAT_GO_TEMPLATE, "{{.name"); err == nil || !strings.Contains(err.Error(), "template") {
+		t.Errorf("expected a template error, found %v", err)
+	}
+}
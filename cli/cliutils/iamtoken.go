@@ -0,0 +1,186 @@
+package cliutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/i18n"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The env var of the url of the IAM token service. When it is set, the api keys of the apikey scheme are exchanged
+// for short lived tokens that are sent as Bearer tokens, for an exchange behind an OAuth or IAM gateway.
+const IAM_TOKEN_URL_ENV = "HZN_IAM_TOKEN_URL"
+
+// A token is refreshed when it expires in less than this, so that it does not expire during a request.
+const IAM_TOKEN_EXPIRY_MARGIN_S = 60
+
+// The response of the token service, the OAuth fields and the expiration time of IBM Cloud IAM.
+type iamTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Expiration  int64  `json:"expiration"`
+}
+
+// A token that is saved in the token file, so that the next invocations of hzn use it until it expires. Only the hash of
+// the api key and the token service url is kept.
+type savedIamToken struct {
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
+
+var iamTokenLock sync.Mutex
+
+// GetIamTokenUrl returns the url of the IAM token service, or an empty string if the api keys are sent without being
+// exchanged for a token.
+func GetIamTokenUrl() string {
+	return os.Getenv(IAM_TOKEN_URL_ENV)
+}
+
+// GetIamTokenFile returns the file that the IAM tokens of the profile are saved in.
+func GetIamTokenFile() string {
+	return filepath.Join(os.Getenv("HOME"), ".hzn", "tokens", GetProfileName()+".json")
+}
+
+func iamTokenKey(tokenUrl string, apiKey string) string {
+	hash := sha256.Sum256([]byte(tokenUrl + "\n" + apiKey))
+	return hex.EncodeToString(hash[:])
+}
+
+func readIamTokens(file string) map[string]savedIamToken {
+	tokens := map[string]savedIamToken{}
+	if content, err := ioutil.ReadFile(file); err == nil {
+		if err := json.Unmarshal(content, &tokens); err != nil {
+			Verbose(i18n.GetMessagePrinter().Sprintf("ignoring the IAM tokens in %v: %v", file, err))
+			return map[string]savedIamToken{}
+		}
+	}
+	return tokens
+}
+
+// Save the tokens, without the expired ones. A failure only means the next invocation gets a new token.
+func writeIamTokens(file string, tokens map[string]savedIamToken) {
+	for key, t := range tokens {
+		if t.Expires.Before(time.Now()) {
+			delete(tokens, key)
+		}
+	}
+	content, err := json.MarshalIndent(tokens, "", JSON_INDENT)
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(file), 0700); err == nil {
+			err = ioutil.WriteFile(file, content, 0600)
+		}
+	}
+	if err != nil {
+		Warning(i18n.GetMessagePrinter().Sprintf("unable to save the IAM token in %v: %v", file, err))
+	}
+}
+
+// GetIamToken returns a token for the api key from the token service, using the saved token until it is about to
+// expire.
+func GetIamToken(apiKey string) string {
+	token, err := getIamToken(apiKey)
+	exitOnError(err)
+	return token
+}
+
+// GetIamToken returning the error of the token service instead of exiting.
+func getIamToken(apiKey string) (string, error) {
+	iamTokenLock.Lock()
+	defer iamTokenLock.Unlock()
+
+	tokenUrl := GetIamTokenUrl()
+	file := GetIamTokenFile()
+	key := iamTokenKey(tokenUrl, apiKey)
+	tokens := readIamTokens(file)
+	if t, ok := tokens[key]; ok && time.Until(t.Expires) > IAM_TOKEN_EXPIRY_MARGIN_S*time.Second {
+		return t.Token, nil
+	}
+
+	t, err := requestIamToken(tokenUrl, apiKey)
+	if err != nil {
+		return "", err
+	}
+	tokens[key] = t
+	writeIamTokens(file, tokens)
+	return t.Token, nil
+}
+
+// Forget the saved token of the credentials, so that the next request gets a new one. Returns false if the credentials
+// do not use a token from the token service.
+func refreshIamToken(credentials string) bool {
+	tokenUrl := GetIamTokenUrl()
+	if credentials == "" || tokenUrl == "" {
+		return false
+	} else if scheme, _, apiKey, _ := getAuthScheme(credentials); scheme != AUTH_SCHEME_APIKEY {
+		return false
+	} else {
+		iamTokenLock.Lock()
+		defer iamTokenLock.Unlock()
+
+		Verbose(i18n.GetMessagePrinter().Sprintf("The exchange did not accept the IAM token, getting a new one from %v", tokenUrl))
+		file := GetIamTokenFile()
+		tokens := readIamTokens(file)
+		delete(tokens, iamTokenKey(tokenUrl, apiKey))
+		writeIamTokens(file, tokens)
+		return true
+	}
+}
+
+// Exchange the api key for a token with the OAuth api key grant of the token service.
+func requestIamToken(tokenUrl string, apiKey string) (savedIamToken, error) {
+	msgPrinter := i18n.GetMessagePrinter()
+	apiMsg := http.MethodPost + " " + tokenUrl
+	Verbose(apiMsg)
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ibm:params:oauth:grant-type:apikey")
+	form.Set("apikey", apiKey)
+	req, err := newRequest(http.MethodPost, tokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return savedIamToken{}, newCLIError(CLI_INPUT_ERROR, msgPrinter.Sprintf("%s new request failed: %v", apiMsg, err))
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	httpClient, err := newHTTPClient(config.HTTPRequestTimeoutS)
+	if err != nil {
+		return savedIamToken{}, newCLIError(CLI_INPUT_ERROR, err.Error())
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return savedIamToken{}, horizonServiceRestError("IAM token service", apiMsg, err)
+	}
+	defer resp.Body.Close()
+	Verbose(msgPrinter.Sprintf("HTTP code: %d", resp.StatusCode))
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized {
+		return savedIamToken{}, newCLIError(INVALID_CREDS, msgPrinter.Sprintf("the IAM token service did not accept the api key (HTTP code %d): %s", resp.StatusCode, GetErrorRespBody(resp)))
+	} else if resp.StatusCode != http.StatusOK {
+		return savedIamToken{}, newCLIError(HTTP_ERROR, msgPrinter.Sprintf("bad HTTP code %d from %s: %s", resp.StatusCode, apiMsg, GetErrorRespBody(resp)))
+	}
+
+	bodyBytes, err := readRespBody(resp.Body, apiMsg)
+	if err != nil {
+		return savedIamToken{}, err
+	}
+	var tokenResp iamTokenResponse
+	if err := json.Unmarshal(bodyBytes, &tokenResp); err != nil || tokenResp.AccessToken == "" {
+		return savedIamToken{}, newCLIError(JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to get the token from the response of %s: %v", apiMsg, err))
+	}
+
+	t := savedIamToken{Token: tokenResp.AccessToken, Expires: time.Now().Add(time.Hour)}
+	if tokenResp.Expiration > 0 {
+		t.Expires = time.Unix(tokenResp.Expiration, 0)
+	} else if tokenResp.ExpiresIn > 0 {
+		t.Expires = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	}
+	return t, nil
+}
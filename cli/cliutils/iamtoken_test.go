@@ -0,0 +1,79 @@
+// +build unit
+
+package cliutils
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func Test_IamToken_refresh(t *testing.T) {
+	issued := 0
+	iam := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("apikey") != "k1" || r.FormValue("grant_type") != "urn:ibm:params:oauth:grant-type:apikey" {
+			http.Error(w, `{"errorMessage":"bad api key"}`, http.StatusBadRequest)
+			return
+		}
+		issued++
+		w.Write([]byte(`{"access_token":"t` + strconv.Itoa(issued) + `","expires_in":3600}`))
+	}))
+	defer iam.Close()
+
+	validToken := "t1"
+	exch := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+validToken {
+			http.Error(w, `{"code":"access-denied","msg":"invalid token"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"orgs":{}}`))
+	}))
+	defer exch.Close()
+
+	dir, err := ioutil.TempDir("", "iamtoken-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	home := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	defer os.Setenv("HOME", home)
+	os.Setenv(IAM_TOKEN_URL_ENV, iam.URL)
+	defer os.Unsetenv(IAM_TOKEN_URL_ENV)
+
+	verbose, dryRun := false, false
+	Opts.Verbose, Opts.IsDryRun = &verbose, &dryRun
+	defer func() { Opts.Verbose, Opts.IsDryRun = nil, nil }()
+
+	var out map[string]interface{}
+	get := func(creds string) (int, error) {
+		return ExchangeGetE("Exchange", exch.URL, "orgs", creds, []int{200}, &out)
+	}
+
+	if code, err := get("myorg/apikey:k1"); err != nil || code != 200 || issued != 1 {
+		t.Fatalf("unexpected result %v %v, %v tokens", code, err, issued)
+	} else if code, err := get("myorg/apikey:k1"); err != nil || code != 200 || issued != 1 {
+		t.Errorf("the saved token should be used, found %v %v, %v tokens", code, err, issued)
+	} else if _, err := os.Stat(filepath.Join(dir, ".hzn", "tokens", DEFAULT_PROFILE+".json")); err != nil {
+		t.Errorf("the token should be saved: %v", err)
+	}
+
+	// the exchange no longer accepts the token
+	validToken = "t2"
+	if code, err := get("myorg/apikey:k1"); err != nil || code != 200 || issued != 2 {
+		t.Errorf("the token should be refreshed, found %v %v, %v tokens", code, err, issued)
+	}
+
+	validToken = "none"
+	if code, err := get("myorg/apikey:k1"); ExitCode(err) != HTTP_ERROR || issued != 3 {
+		t.Errorf("the token should only be refreshed once, found %v %v, %v tokens", code, err, issued)
+	}
+
+	if _, err := get("myorg/apikey:wrong"); ExitCode(err) != INVALID_CREDS {
+		t.Errorf("a wrong api key should be an invalid credentials error, found %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+// +build unit
+
+package cliutils
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_stripJsonComments(t *testing.T) {
+	input := `{
+  /* the service
+     definition */
+  "url": "https://example.com/svc", // not a comment inside the string
+  "label": "a /* b */ c // d",
+  "escaped": "a \" // b",
+  "arch": ["amd64", "arm64", ],
+  "deployment": {"services": {},},
+}
+`
+	out := stripJsonComments([]byte(input))
+	if len(out) != len(input) || strings.Count(string(out), "\n") != strings.Count(input, "\n") {
+		t.Errorf("the offsets and lines should be kept, found %q", out)
+	}
+
+	var v map[string]interface{}
+	if err := json.Unmarshal(out, &v); err != nil {
+		t.Fatalf("the output should be json: %v, output %v", err, string(out))
+	}
+	expected := map[string]interface{}{
+		"url":        "https://example.com/svc",
+		"label":      "a /* b */ c // d",
+		"escaped":    `a " // b`,
+		"arch":       []interface{}{"amd64", "arm64"},
+		"deployment": map[string]interface{}{"services": map[string]interface{}{}},
+	}
+	if !reflect.DeepEqual(v, expected) {
+		t.Errorf("expected %v, found %v", expected, v)
+	}
+
+	// a parse error points at the line of the original file
+	bad := "{\n  // a comment\n  \"a\": 1,\n  \"b\": oops\n}"
+	err := json.Unmarshal(stripJsonComments([]byte(bad)), &v)
+	if syntaxErr, ok := err.(*json.SyntaxError); !ok {
+		t.Errorf("expected a syntax error, found %v", err)
+	} else if line := strings.Count(bad[:syntaxErr.Offset], "\n") + 1; line != 4 {
+		t.Errorf("expected the error on line 4, found line %v", line)
+	}
+
+	// only the commas before a closing bracket are removed
+	if out := string(stripJsonComments([]byte(`[1, , 2]`))); out != `[1, , 2]` {
+		t.Errorf("a missing value should still be an error, found %v", out)
+	}
+}
@@ -0,0 +1,145 @@
+package cliutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/open-horizon/anax/i18n"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// The error classes of the exit codes, in the JSON errors.
+var errorClasses = map[int]string{
+	CLI_INPUT_ERROR:    "input",
+	JSON_PARSING_ERROR: "json_parsing",
+	FILE_IO_ERROR:      "file_io",
+	HTTP_ERROR:         "http",
+	CLI_GENERAL_ERROR:  "general",
+	NOT_FOUND:          "not_found",
+	SIGNATURE_INVALID:  "signature_invalid",
+	EXEC_CMD_ERROR:     "exec_cmd",
+	CONFLICT_ERROR:     "conflict",
+	PARTIAL_SUCCESS:    "partial_success",
+	INVALID_CREDS:      "invalid_credentials",
+	HEARTBEAT_STALE:    "heartbeat_stale",
+	DEGRADED:           "degraded",
+	INTERRUPTED:        "interrupted",
+	INTERNAL_ERROR:     "internal",
+}
+
+// JsonError is the error that Fatal writes to stderr with --json-errors. The HTTP code and url are the ones of the
+// last request when it failed and the error is one that a failed request ends the command with, the HTTP code is 0
+// when the request got no response.
+type JsonError struct {
+	ExitCode   int    `json:"exitCode"`
+	ErrorClass string `json:"errorClass"`
+	Message    string `json:"message"`
+	HttpCode   *int   `json:"httpCode,omitempty"`
+	Method     string `json:"method,omitempty"`
+	Url        string `json:"url,omitempty"`
+}
+
+// Returns true if --json-errors is set.
+func IsJsonErrors() bool {
+	return Opts.JsonErrors != nil && *Opts.JsonErrors
+}
+
+// The last request that the CLI sent, when it failed.
+type requestRecord struct {
+	method   string
+	url      string
+	httpCode int
+}
+
+var lastRequestLock sync.Mutex
+var lastRequest *requestRecord
+
+// A transport that records the outcome of every request, for the JSON errors.
+type recordTransport struct {
+	next http.RoundTripper
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+
+	// only a failed request is kept, a request that works is not the cause of the error that ends the command
+	var record *requestRecord
+	if err != nil || resp.StatusCode >= 400 {
+		record = &requestRecord{method: req.Method, url: redactUrl(req.URL)}
+		if resp != nil {
+			record.httpCode = resp.StatusCode
+		}
+	}
+	lastRequestLock.Lock()
+	lastRequest = record
+	lastRequestLock.Unlock()
+	return resp, err
+}
+
+// Returns the transport that records its requests when --json-errors is set.
+func withRequestRecord(transport http.RoundTripper) http.RoundTripper {
+	if IsJsonErrors() {
+		return &recordTransport{next: transport}
+	}
+	return transport
+}
+
+// The exit codes of the errors that a failed request ends the command with. The commands handle some failed requests,
+// such as a 404, and can end with an unrelated error afterwards, which must not be attributed to the request.
+var requestExitCodes = map[int]bool{
+	HTTP_ERROR:     true,
+	NOT_FOUND:      true,
+	CONFLICT_ERROR: true,
+	INVALID_CREDS:  true,
+}
+
+// Returns the JSON error of an exit code and message.
+func newJsonError(exitCode int, msg string) JsonError {
+	class, ok := errorClasses[exitCode]
+	if !ok {
+		class = errorClasses[CLI_GENERAL_ERROR]
+	}
+	jsonErr := JsonError{ExitCode: exitCode, ErrorClass: class, Message: strings.TrimSpace(msg)}
+
+	lastRequestLock.Lock()
+	defer lastRequestLock.Unlock()
+	if lastRequest != nil && requestExitCodes[exitCode] {
+		httpCode := lastRequest.httpCode
+		jsonErr.HttpCode, jsonErr.Method, jsonErr.Url = &httpCode, lastRequest.method, lastRequest.url
+	}
+	return jsonErr
+}
+
+// Write the error that ends the command to stderr, as a JSON object with --json-errors.
+func printError(exitCode int, msg string) {
+	if !IsJsonErrors() {
+		fmt.Fprintln(os.Stderr, i18n.GetMessagePrinter().Sprintf("Error: %s", strings.TrimSuffix(msg, "\n")))
+		return
+	}
+	out, err := json.Marshal(newJsonError(exitCode, msg))
+	if err != nil {
+		// the error has only strings and numbers
+		out = []byte(fmt.Sprintf(`{"exitCode": %v, "message": %q}`, exitCode, msg))
+	}
+	fmt.Fprintln(os.Stderr, string(out))
+}
+
+// ParseError ends the command with an input error when its arguments can not be parsed. The error is a JSON object
+// when --json-errors is in the arguments, which kingpin may not have parsed. It returns otherwise, so that kingpin
+// displays the error with the usage of the command.
+func ParseError(args []string, err error) {
+	if err == nil {
+		return
+	}
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		} else if arg == "--json-errors" || arg == "--json-errors=true" {
+			jsonErrors := true
+			Opts.JsonErrors = &jsonErrors
+			Fatal(CLI_INPUT_ERROR, "%v", err)
+		}
+	}
+}
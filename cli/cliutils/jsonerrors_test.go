@@ -0,0 +1,76 @@
+// +build unit
+
+package cliutils
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_newJsonError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/orgs/myorg" {
+			w.Write([]byte(`{"orgs":{"myorg":{}}}`))
+			return
+		}
+		http.Error(w, `{"code":"bad-input","msg":"bad input"}`, http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	verbose, dryRun, jsonErrors := false, false, true
+	Opts.Verbose, Opts.IsDryRun, Opts.JsonErrors = &verbose, &dryRun, &jsonErrors
+	defer func() { Opts.Verbose, Opts.IsDryRun, Opts.JsonErrors = nil, nil, nil }()
+
+	var out map[string]interface{}
+	_, err := ExchangeGetE("Exchange", ts.URL, "orgs/other?token=secret", "myorg/myuser:mypw", []int{200}, &out)
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	jsonErr := newJsonError(ExitCode(err), err.Error())
+	if jsonErr.ExitCode != ExitCode(err) || jsonErr.ErrorClass != errorClasses[ExitCode(err)] || jsonErr.Message == "" {
+		t.Errorf("wrong error %v", jsonErr)
+	} else if jsonErr.HttpCode == nil || *jsonErr.HttpCode != http.StatusBadRequest || jsonErr.Method != http.MethodGet {
+		t.Errorf("the failed request should be in the error: %v", jsonErr)
+	} else if !strings.Contains(jsonErr.Url, "/orgs/other") || strings.Contains(jsonErr.Url, "secret") {
+		t.Errorf("the url should be in the error, redacted: %v", jsonErr.Url)
+	}
+
+	// an error after a request that worked has no http code
+	if _, err := ExchangeGetE("Exchange", ts.URL, "orgs/myorg", "myorg/myuser:mypw", []int{200}, &out); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if jsonErr := newJsonError(CLI_INPUT_ERROR, "bad flag\n"); jsonErr.HttpCode != nil || jsonErr.Url != "" || jsonErr.ErrorClass != "input" || jsonErr.Message != "bad flag" {
+		t.Errorf("wrong error %v", jsonErr)
+	}
+
+	// a 404 that the command handles is not the cause of an unrelated error
+	if _, err := ExchangeGetE("Exchange", ts.URL, "orgs/missing", "myorg/myuser:mypw", []int{200, 400}, &out); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if jsonErr := newJsonError(CLI_INPUT_ERROR, "bad flag"); jsonErr.HttpCode != nil {
+		t.Errorf("the handled request should not be in an input error: %v", jsonErr)
+	} else if jsonErr := newJsonError(NOT_FOUND, "not found"); jsonErr.HttpCode == nil || *jsonErr.HttpCode != http.StatusBadRequest {
+		t.Errorf("the failed request should be in a not found error: %v", jsonErr)
+	}
+	if jsonErr := newJsonError(42, "?"); jsonErr.ErrorClass != "general" {
+		t.Errorf("an unknown exit code should be a general error, found %v", jsonErr.ErrorClass)
+	}
+}
+
+func Test_ParseError(t *testing.T) {
+	defer func() { Opts.JsonErrors = nil }()
+
+	// without --json-errors kingpin displays the error
+	ParseError([]string{"exchange", "node", "list", "--bad"}, nil)
+	ParseError([]string{"exchange", "node", "list", "--bad"}, errors.New("unknown long flag '--bad'"))
+	ParseError([]string{"exchange", "--", "--json-errors"}, errors.New("unknown long flag '--bad'"))
+
+	err := Try(func() { ParseError([]string{"--json-errors", "exchange", "node", "list", "--bad"}, errors.New("unknown long flag '--bad'")) })
+	if ExitCode(err) != CLI_INPUT_ERROR || !IsJsonErrors() {
+		t.Errorf("expected a JSON input error, found %v", err)
+	}
+}
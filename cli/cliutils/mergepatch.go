@@ -0,0 +1,78 @@
+package cliutils
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/open-horizon/anax/i18n"
+)
+
+// MergePatch applies an RFC 7386 JSON merge patch to the target document and returns the result.
+// Members of the patch object replace the members of the target, nested objects are merged recursively,
+// null removes a member and anything that is not an object (including arrays) replaces the target value.
+// A nil or empty target is treated as null.
+func MergePatch(target []byte, patch []byte) ([]byte, error) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	var targetDoc interface{}
+	if len(target) != 0 {
+		if err := json.Unmarshal(target, &targetDoc); err != nil {
+			return nil, errors.New(msgPrinter.Sprintf("failed to unmarshal the merge patch target: %v", err))
+		}
+	}
+
+	var patchDoc interface{}
+	if err := json.Unmarshal(patch, &patchDoc); err != nil {
+		return nil, errors.New(msgPrinter.Sprintf("failed to unmarshal the merge patch: %v", err))
+	}
+
+	merged, err := json.Marshal(mergePatchValue(targetDoc, patchDoc))
+	if err != nil {
+		return nil, errors.New(msgPrinter.Sprintf("failed to marshal the merge patch result: %v", err))
+	}
+	return merged, nil
+}
+
+// MergePatchValue is like MergePatch but works on values that are marshaled to and from json, so that
+// callers can use their own types for the target, the patch and the result.
+func MergePatchValue(target interface{}, patch interface{}, result interface{}) error {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	targetBytes, err := json.Marshal(target)
+	if err != nil {
+		return errors.New(msgPrinter.Sprintf("failed to marshal the merge patch target: %v", err))
+	}
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		return errors.New(msgPrinter.Sprintf("failed to marshal the merge patch: %v", err))
+	}
+	merged, err := MergePatch(targetBytes, patchBytes)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(merged, result); err != nil {
+		return errors.New(msgPrinter.Sprintf("failed to unmarshal the merge patch result: %v", err))
+	}
+	return nil
+}
+
+// The MergePatch algorithm from section 2 of RFC 7386, applied to generic json values.
+func mergePatchValue(target interface{}, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{})
+	}
+
+	for name, value := range patchObj {
+		if value == nil {
+			delete(targetObj, name)
+		} else {
+			targetObj[name] = mergePatchValue(targetObj[name], value)
+		}
+	}
+	return targetObj
+}
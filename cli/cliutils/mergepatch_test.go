@@ -0,0 +1,70 @@
+// +build unit
+
+package cliutils
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// The test cases from appendix A of RFC 7386.
+func Test_MergePatch(t *testing.T) {
+	tests := []struct {
+		target string
+		patch  string
+		result string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{``, `{"a":"b"}`, `{"a":"b"}`},
+	}
+
+	for _, test := range tests {
+		merged, err := MergePatch([]byte(test.target), []byte(test.patch))
+		if err != nil {
+			t.Errorf("target %v patch %v: unexpected error %v", test.target, test.patch, err)
+			continue
+		}
+
+		var got, expected interface{}
+		if err := json.Unmarshal(merged, &got); err != nil {
+			t.Errorf("target %v patch %v: result %s is not valid json: %v", test.target, test.patch, merged, err)
+		} else if err := json.Unmarshal([]byte(test.result), &expected); err != nil {
+			t.Errorf("bad expected result %v: %v", test.result, err)
+		} else if !reflect.DeepEqual(got, expected) {
+			t.Errorf("target %v patch %v: expected %v but got %s", test.target, test.patch, test.result, merged)
+		}
+	}
+
+	if _, err := MergePatch([]byte(`{"a":"b"}`), []byte(`{"a":`)); err == nil {
+		t.Errorf("expected an error for an invalid patch")
+	}
+}
+
+func Test_MergePatchValue(t *testing.T) {
+	type hb struct {
+		MinInterval int `json:"minInterval"`
+		MaxInterval int `json:"maxInterval"`
+	}
+
+	var result hb
+	if err := MergePatchValue(hb{MinInterval: 10, MaxInterval: 120}, map[string]interface{}{"maxInterval": 60}, &result); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if result.MinInterval != 10 || result.MaxInterval != 60 {
+		t.Errorf("expected the min interval to be preserved, got %v", result)
+	}
+}
@@ -0,0 +1,131 @@
+package cliutils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/open-horizon/anax/i18n"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The formats that the list commands can display their output in.
+const (
+	OUTPUT_FORMAT_JSON = "json"
+	OUTPUT_FORMAT_CSV  = "csv"
+)
+
+// IsCSVOutput returns true if the --output flag of a list command is csv. The csv output is a table of its own, so
+// --output csv takes precedence over HZN_OUTPUT_FORMAT, and it exits with an error when --output-format is also given.
+// The default, --output json, writes the output in the format of --output-format or HZN_OUTPUT_FORMAT.
+func IsCSVOutput(output string) bool {
+	if output != OUTPUT_FORMAT_CSV {
+		return false
+	}
+	if Opts.OutputFmt != nil && *Opts.OutputFmt != "" {
+		Fatal(CLI_INPUT_ERROR, i18n.GetMessagePrinter().Sprintf("--output %v can not be used with --output-format %v", OUTPUT_FORMAT_CSV, *Opts.OutputFmt))
+	}
+	return true
+}
+
+// ParseColumns splits the comma separated list of columns given with --columns. The default columns are returned
+// when none are given.
+func ParseColumns(columns string, defaultColumns []string) []string {
+	cols := make([]string, 0)
+	for _, c := range strings.Split(columns, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			cols = append(cols, c)
+		}
+	}
+	if len(cols) == 0 {
+		return defaultColumns
+	}
+	return cols
+}
+
+// CSVRecord returns the json form of the object as a map, which is how WriteCSV finds the value of each column. If the
+// id is not empty it is added as the id field, for the listings that are keyed by the id of each resource.
+func CSVRecord(id string, obj interface{}) map[string]interface{} {
+	record := make(map[string]interface{})
+	if jsonBytes, err := json.Marshal(obj); err != nil {
+		Fatal(JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal %v: %v", obj, err))
+	} else if err := json.Unmarshal(jsonBytes, &record); err != nil {
+		Fatal(JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to unmarshal %v: %v", string(jsonBytes), err))
+	}
+	if id != "" {
+		record["id"] = id
+	}
+	return record
+}
+
+// CSVRecords returns the records of a listing that is keyed by the id of each resource, sorted by the id.
+func CSVRecords(objects map[string]interface{}) []map[string]interface{} {
+	ids := make([]string, 0, len(objects))
+	for id := range objects {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	records := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		records = append(records, CSVRecord(id, objects[id]))
+	}
+	return records
+}
+
+// WriteCSV writes a header row with the column names and then one row per record. A column is the name of a json field
+// of the record, a field of a nested object is named with dots, for example workload_to_run.url. The value of a field
+// that does not exist is empty, and a field that is an object or a list is written in its json form.
+func WriteCSV(w io.Writer, records []map[string]interface{}, columns []string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	for _, record := range records {
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = csvValue(record, column)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// OutputCSV writes the records to stdout with WriteCSV, and exits with an error if they can not be written.
+func OutputCSV(records []map[string]interface{}, columns []string) {
+	if err := WriteCSV(os.Stdout, records, columns); err != nil {
+		Fatal(CLI_GENERAL_ERROR, i18n.GetMessagePrinter().Sprintf("failed to write the csv output: %v", err))
+	}
+}
+
+func csvValue(record map[string]interface{}, column string) string {
+	var value interface{} = record
+	for _, name := range strings.Split(column, ".") {
+		if m, ok := value.(map[string]interface{}); !ok {
+			return ""
+		} else if value, ok = m[name]; !ok {
+			return ""
+		}
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		if jsonBytes, err := json.Marshal(v); err == nil {
+			return string(jsonBytes)
+		}
+		return ""
+	}
+}
@@ -0,0 +1,50 @@
+// +build unit
+
+package cliutils
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func Test_ParseColumns(t *testing.T) {
+	defaults := []string{"id", "name"}
+	if cols := ParseColumns("", defaults); !reflect.DeepEqual(cols, defaults) {
+		t.Errorf("expected the default columns, got %v", cols)
+	}
+	if cols := ParseColumns(" url, version ,,arch", defaults); !reflect.DeepEqual(cols, []string{"url", "version", "arch"}) {
+		t.Errorf("unexpected columns %v", cols)
+	}
+}
+
+func Test_WriteCSV(t *testing.T) {
+	type workload struct {
+		URL     string `json:"url"`
+		Version string `json:"version"`
+	}
+	type resource struct {
+		Name     string   `json:"name"`
+		Public   bool     `json:"public"`
+		Count    int      `json:"count"`
+		Tags     []string `json:"tags"`
+		Workload workload `json:"workload"`
+	}
+
+	records := CSVRecords(map[string]interface{}{
+		"org/b": resource{Name: "b, with a comma", Count: 2, Workload: workload{URL: "u2", Version: "2.0"}},
+		"org/a": resource{Name: "a", Public: true, Count: 1, Tags: []string{"x", "y"}, Workload: workload{URL: "u1", Version: "1.0"}},
+	})
+
+	var out bytes.Buffer
+	if err := WriteCSV(&out, records, []string{"id", "name", "public", "count", "tags", "workload.url", "missing", "name.nested"}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	expected := "id,name,public,count,tags,workload.url,missing,name.nested\n" +
+		"org/a,a,true,1,\"[\"\"x\"\",\"\"y\"\"]\",u1,,\n" +
+		"org/b,\"b, with a comma\",false,2,,u2,,\n"
+	if out.String() != expected {
+		t.Errorf("unexpected csv output:\n%v\nexpected:\n%v", out.String(), expected)
+	}
+}
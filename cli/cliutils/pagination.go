@@ -0,0 +1,136 @@
+package cliutils
+
+import (
+	"encoding/json"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/i18n"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+)
+
+// ExchangeGetAll runs a GET of a list of resources, like ExchangeGet, and follows the pages of the list until the last
+// one. The resources of all the pages are returned in the structure as if they were in one page. The next page is the
+// url in the rel="next" Link header, or the one at the offset in the lastIndex field of the body. The http code is the
+// one of the first page.
+func ExchangeGetAll(service string, urlBase string, urlSuffix string, credentials string, goodHttpCodes []int, structure interface{}) (httpCode int) {
+	msgPrinter := i18n.GetMessagePrinter()
+	httpClient := GetHTTPClient(config.HTTPRequestTimeoutS)
+
+	var pages [][]byte
+	pageUrl := urlBase + "/" + urlSuffix
+	apiMsg := http.MethodGet + " " + pageUrl
+	visited := map[string]bool{}
+	for pageUrl != "" && !visited[pageUrl] {
+		visited[pageUrl] = true
+		pageMsg := http.MethodGet + " " + pageUrl
+		Verbose(pageMsg)
+
+		resp := InvokeRestApi(httpClient, http.MethodGet, pageUrl, credentials, nil, service, pageMsg)
+		code := resp.StatusCode
+		Verbose(msgPrinter.Sprintf("HTTP code: %d", code))
+		if len(pages) == 0 {
+			httpCode = code
+			if !isGoodCode(httpCode, goodHttpCodes) {
+				errBody := GetErrorRespBody(resp)
+				resp.Body.Close()
+				Fatal(HTTP_ERROR, msgPrinter.Sprintf("bad HTTP code %d from %s, output: %s", httpCode, pageMsg, errBody))
+			}
+		} else if code != http.StatusOK {
+			// the list ends with an empty page
+			resp.Body.Close()
+			break
+		}
+
+		bodyBytes := ReadRespBody(resp.Body, pageMsg)
+		resp.Body.Close()
+		pages = append(pages, bodyBytes)
+		if code != http.StatusOK {
+			break
+		}
+		pageUrl = nextPageUrl(pageUrl, resp.Header, bodyBytes)
+	}
+
+	if len(pages) == 1 {
+		exitOnError(decodeExchangeBody(pages[0], structure, apiMsg))
+		return
+	}
+	Verbose(msgPrinter.Sprintf("Merged %d pages of %s", len(pages), apiMsg))
+	merged, err := mergePages(pages)
+	if err != nil {
+		Fatal(JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal exchange body response from %s: %v", apiMsg, err))
+	}
+	exitOnError(decodeExchangeBody(merged, structure, apiMsg))
+	return
+}
+
+var linkNextRegex = regexp.MustCompile(`<([^>]*)>\s*;[^,]*rel="?next"?`)
+
+// Returns the url of the page after this one, or an empty string if this is the last page.
+func nextPageUrl(pageUrl string, header http.Header, body []byte) string {
+	current, err := url.Parse(pageUrl)
+	if err != nil {
+		return ""
+	}
+
+	for _, link := range header["Link"] {
+		if m := linkNextRegex.FindStringSubmatch(link); m != nil {
+			if next, err := current.Parse(m[1]); err == nil {
+				return next.String()
+			}
+		}
+	}
+
+	var page struct {
+		LastIndex int `json:"lastIndex"`
+	}
+	if json.Unmarshal(body, &page) != nil || page.LastIndex <= 0 {
+		return ""
+	}
+	query := current.Query()
+	if offset, _ := strconv.Atoi(query.Get("offset")); page.LastIndex <= offset {
+		return ""
+	}
+	query.Set("offset", strconv.Itoa(page.LastIndex))
+	current.RawQuery = query.Encode()
+	return current.String()
+}
+
+// Merge the pages of a list into one. The objects and arrays of the resources in the pages are combined, the other
+// fields are the ones of the last page, and lastIndex is 0 because the list is complete.
+func mergePages(pages [][]byte) ([]byte, error) {
+	merged := map[string]interface{}{}
+	for _, page := range pages {
+		fields := map[string]json.RawMessage{}
+		if err := json.Unmarshal(page, &fields); err != nil {
+			return nil, err
+		}
+
+		for name, value := range fields {
+			var object map[string]json.RawMessage
+			var array []json.RawMessage
+			if json.Unmarshal(value, &object) == nil && object != nil {
+				if prev, ok := merged[name].(map[string]json.RawMessage); ok {
+					for id, resource := range object {
+						prev[id] = resource
+					}
+				} else {
+					merged[name] = object
+				}
+			} else if json.Unmarshal(value, &array) == nil && array != nil {
+				if prev, ok := merged[name].([]json.RawMessage); ok {
+					merged[name] = append(prev, array...)
+				} else {
+					merged[name] = array
+				}
+			} else {
+				merged[name] = value
+			}
+		}
+	}
+	if _, ok := merged["lastIndex"]; ok {
+		merged["lastIndex"] = 0
+	}
+	return json.Marshal(merged)
+}
@@ -0,0 +1,60 @@
+// +build unit
+
+package cliutils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_nextPageUrl(t *testing.T) {
+	link := http.Header{"Link": []string{`<https://exch/v1/orgs/myorg/nodes?page=1>; rel="prev", </v1/orgs/myorg/nodes?page=3>; rel="next"`}}
+	for _, tc := range []struct {
+		pageUrl string
+		header  http.Header
+		body    string
+		next    string
+	}{
+		{"https://exch/v1/orgs/myorg/nodes?page=2", link, `{}`, "https://exch/v1/orgs/myorg/nodes?page=3"},
+		{"https://exch/v1/orgs/myorg/nodes", http.Header{}, `{"nodes":{},"lastIndex":100}`, "https://exch/v1/orgs/myorg/nodes?offset=100"},
+		{"https://exch/v1/orgs/myorg/nodes?offset=100", http.Header{}, `{"nodes":{},"lastIndex":200}`, "https://exch/v1/orgs/myorg/nodes?offset=200"},
+		{"https://exch/v1/orgs/myorg/nodes?offset=100", http.Header{}, `{"nodes":{},"lastIndex":100}`, ""},
+		{"https://exch/v1/orgs/myorg/nodes", http.Header{}, `{"nodes":{},"lastIndex":0}`, ""},
+		{"https://exch/v1/orgs/myorg/nodes", http.Header{}, `not json`, ""},
+	} {
+		if next := nextPageUrl(tc.pageUrl, tc.header, []byte(tc.body)); next != tc.next {
+			t.Errorf("wrong next page of %v %v: expected %v, found %v", tc.pageUrl, tc.body, tc.next, next)
+		}
+	}
+}
+
+func Test_ExchangeGetAll(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", `</orgs/myorg/services?page=2>; rel="next"`)
+			w.Write([]byte(`{"services":{"myorg/s1":{"url":"s1"}},"keys":["k1"],"lastIndex":0}`))
+		case "2":
+			w.Write([]byte(`{"services":{"myorg/s2":{"url":"s2"}},"keys":["k2"],"lastIndex":0}`))
+		}
+	}))
+	defer ts.Close()
+
+	verbose, dryRun := false, false
+	Opts.Verbose, Opts.IsDryRun = &verbose, &dryRun
+	defer func() { Opts.Verbose, Opts.IsDryRun = nil, nil }()
+
+	var out struct {
+		Services  map[string]map[string]string `json:"services"`
+		Keys      []string                     `json:"keys"`
+		LastIndex int                          `json:"lastIndex"`
+	}
+	if err := Try(func() {
+		ExchangeGetAll("Exchange", ts.URL, "orgs/myorg/services", "myorg/u:p", []int{200, 404}, &out)
+	}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	} else if len(out.Services) != 2 || out.Services["myorg/s2"]["url"] != "s2" || len(out.Keys) != 2 {
+		t.Errorf("the pages should be merged, found %v", out)
+	}
+}
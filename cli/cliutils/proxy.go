@@ -0,0 +1,143 @@
+package cliutils
+
+import (
+	"errors"
+	"fmt"
+	"github.com/open-horizon/anax/i18n"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// GetProxy returns the proxy that the --proxy flag sets for every request, or an empty string when the proxy comes
+// from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY env vars.
+func GetProxy() string {
+	if Opts.Proxy != nil {
+		return *Opts.Proxy
+	}
+	return ""
+}
+
+// Returns the function that chooses the proxy of each request. A proxy with credentials in its url, for example
+// http://user:pw@proxy.example.com:3128, gets them in the Proxy-Authorization header, including for https requests
+// that are tunneled through it. NO_PROXY applies to the --proxy flag as well. An invalid proxy is returned as the
+// error, with a function that fails every request.
+func getProxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	proxy := GetProxy()
+	if proxy == "" {
+		return func(req *http.Request) (*url.URL, error) {
+			if req.URL.Hostname() == UNIX_SOCKET_HOST {
+				return nil, nil
+			}
+			return http.ProxyFromEnvironment(req)
+		}, nil
+	}
+
+	proxyUrl, err := parseProxyUrl(proxy)
+	if err != nil {
+		err = errors.New(i18n.GetMessagePrinter().Sprintf("Invalid proxy %v: %v", MaskProxyCredentials(proxy), err))
+		return func(req *http.Request) (*url.URL, error) { return nil, err }, err
+	}
+	noProxy := getNoProxy()
+
+	return func(req *http.Request) (*url.URL, error) {
+		if isNoProxyHost(req.URL.Host, noProxy) {
+			return nil, nil
+		}
+		return proxyUrl, nil
+	}, nil
+}
+
+// Parse a proxy url. A proxy without a scheme is an http proxy, the same as in HTTP_PROXY.
+func parseProxyUrl(proxy string) (*url.URL, error) {
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	proxyUrl, err := url.Parse(proxy)
+	if err != nil {
+		return nil, err
+	} else if proxyUrl.Host == "" {
+		return nil, fmt.Errorf(i18n.GetMessagePrinter().Sprintf("the proxy has no host"))
+	} else if proxyUrl.Scheme != "http" && proxyUrl.Scheme != "https" && proxyUrl.Scheme != "socks5" {
+		return nil, fmt.Errorf(i18n.GetMessagePrinter().Sprintf("unsupported proxy scheme %v", proxyUrl.Scheme))
+	}
+	return proxyUrl, nil
+}
+
+func getNoProxy() []string {
+	noProxy := os.Getenv("NO_PROXY")
+	if noProxy == "" {
+		noProxy = os.Getenv("no_proxy")
+	}
+
+	entries := []string{}
+	for _, e := range strings.Split(noProxy, ",") {
+		if e = strings.ToLower(strings.TrimSpace(e)); e != "" {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Returns true if a host, with an optional port, matches one of the NO_PROXY entries. An entry is *, an IP address,
+// a CIDR, or a domain name that also matches its sub domains, with an optional port. Requests to localhost and to the
+// Horizon API unix socket are never proxied.
+func isNoProxyHost(hostPort string, noProxy []string) bool {
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		host = hostPort
+	}
+	host = strings.ToLower(strings.Trim(host, "[]"))
+	if host == "localhost" || host == UNIX_SOCKET_HOST {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsLoopback() {
+		return true
+	}
+
+	for _, entry := range noProxy {
+		if entry == "*" {
+			return true
+		}
+
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		entryHost, entryPort, err := net.SplitHostPort(entry)
+		if err != nil {
+			entryHost, entryPort = entry, ""
+		}
+		if entryPort != "" && entryPort != port {
+			continue
+		}
+
+		entryHost = strings.TrimPrefix(strings.Trim(entryHost, "[]"), "*")
+		if entryIp := net.ParseIP(entryHost); entryIp != nil {
+			if ip != nil && entryIp.Equal(ip) {
+				return true
+			}
+		} else if host == strings.TrimPrefix(entryHost, ".") || strings.HasSuffix(host, "."+strings.TrimPrefix(entryHost, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskProxyCredentials hides the password of a proxy url, so that it can be displayed.
+func MaskProxyCredentials(proxy string) string {
+	if proxyUrl, err := parseProxyUrl(proxy); err == nil && proxyUrl.User != nil {
+		if _, hasPw := proxyUrl.User.Password(); hasPw {
+			// the url would escape the mask
+			proxyUrl.User = url.UserPassword(proxyUrl.User.Username(), "MASKED")
+			return strings.Replace(proxyUrl.String(), ":MASKED@", ":******@", 1)
+		}
+	}
+	return proxy
+}
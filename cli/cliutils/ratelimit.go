@@ -0,0 +1,57 @@
+package cliutils
+
+import (
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/i18n"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The env var with the number of times that a request that is rate limited by the exchange (429) is sent again.
+const HTTP_RATE_LIMIT_RETRIES_ENV = "HZN_HTTP_RATE_LIMIT_RETRIES"
+
+const DEFAULT_HTTP_RATE_LIMIT_RETRIES = 10
+
+// GetRateLimitRetries returns the number of times that a rate limited request is retried, from HZN_HTTP_RATE_LIMIT_RETRIES
+// or the default.
+func GetRateLimitRetries() int {
+	if envRetries := os.Getenv(HTTP_RATE_LIMIT_RETRIES_ENV); envRetries != "" {
+		if retries, err := strconv.Atoi(envRetries); err == nil && retries >= 0 {
+			return retries
+		}
+		Warning(i18n.GetMessagePrinter().Sprintf("Unable to use %v to set the number of retries of the rate limited requests, the value is not a valid number: %v", HTTP_RATE_LIMIT_RETRIES_ENV, envRetries))
+	}
+	return DEFAULT_HTTP_RATE_LIMIT_RETRIES
+}
+
+// Returns how long to wait before sending a request again, from the Retry-After header of the response, which is a
+// number of seconds or a date. The default delay is used when the response has no valid Retry-After. The delay is at
+// most maxDelay, so that a server that asks for hours does not hang the command.
+func retryAfter(resp *http.Response, defaultDelay time.Duration, maxDelay time.Duration) time.Duration {
+	delay := defaultDelay
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		delay = 0
+		if until := time.Until(date); until > 0 {
+			delay = until.Round(time.Second)
+		}
+	}
+	if delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+// Returns how long a request may wait in total for the retries of its rate limited responses: the request timeout of
+// the client, set by --http-timeout or HZN_HTTP_TIMEOUT, or the default request timeout when the client has none.
+func rateLimitBudget(httpClient *http.Client) time.Duration {
+	if httpClient != nil && httpClient.Timeout > 0 {
+		return httpClient.Timeout
+	}
+	return config.HTTPRequestTimeoutS * time.Second
+}
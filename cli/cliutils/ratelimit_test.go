@@ -0,0 +1,104 @@
+// +build unit
+
+package cliutils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_retryAfter(t *testing.T) {
+	for _, tc := range []struct {
+		header   string
+		expected time.Duration
+	}{
+		{"", 3 * time.Second},
+		{"5", 5 * time.Second},
+		{" 0 ", 0},
+		{"soon", 3 * time.Second},
+		{"-1", 3 * time.Second},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0},
+		{"3600", 10 * time.Second},
+		{time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), 10 * time.Second},
+	} {
+		resp := &http.Response{Header: http.Header{}}
+		if tc.header != "" {
+			resp.Header.Set("Retry-After", tc.header)
+		}
+		if delay := retryAfter(resp, 3*time.Second, 10*time.Second); delay != tc.expected {
+			t.Errorf("Retry-After %q should be %v, found %v", tc.header, tc.expected, delay)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)}}}
+	if delay := retryAfter(resp, 0, time.Hour); delay < 55*time.Second || delay > time.Minute {
+		t.Errorf("a date a minute from now should be about a minute, found %v", delay)
+	}
+}
+
+func Test_ExchangeGet_rateLimited(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= 2 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"id":"myorg"}`))
+	}))
+	defer ts.Close()
+
+	verbose, dryRun := false, false
+	Opts.Verbose, Opts.IsDryRun = &verbose, &dryRun
+	defer func() { Opts.Verbose, Opts.IsDryRun = nil, nil }()
+
+	// the rate limited requests do not use up the retries of the transport errors
+	os.Setenv("HZN_HTTP_RETRIES", "0")
+	defer os.Unsetenv("HZN_HTTP_RETRIES")
+
+	var org map[string]interface{}
+	if httpCode := ExchangeGet("Exchange", ts.URL, "orgs/myorg", "myorg/myuser:mypw", []int{200}, &org); httpCode != 200 || calls != 3 || org["id"] != "myorg" {
+		t.Errorf("the request should be retried until it is not rate limited, found %v after %v calls", httpCode, calls)
+	}
+
+	// when the retries are used up, the 429 is the response
+	calls = 0
+	os.Setenv(HTTP_RATE_LIMIT_RETRIES_ENV, "1")
+	defer os.Unsetenv(HTTP_RATE_LIMIT_RETRIES_ENV)
+	if httpCode := ExchangeGet("Exchange", ts.URL, "orgs/myorg", "myorg/myuser:mypw", nil, nil); httpCode != http.StatusTooManyRequests || calls != 2 {
+		t.Errorf("expected a 429 after 2 calls, found %v after %v calls", httpCode, calls)
+	}
+}
+
+func Test_ExchangeGet_rateLimitBudget(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "3600")
+		http.Error(w, "too many requests", http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	verbose, dryRun := false, false
+	Opts.Verbose, Opts.IsDryRun = &verbose, &dryRun
+	defer func() { Opts.Verbose, Opts.IsDryRun = nil, nil }()
+
+	// the Retry-After of an hour is cut to the request timeout, and the request fails once it is used up
+	os.Setenv("HZN_HTTP_TIMEOUT", "1")
+	defer os.Unsetenv("HZN_HTTP_TIMEOUT")
+	start := time.Now()
+	err := Try(func() { ExchangeGet("Exchange", ts.URL, "orgs/myorg", "myorg/myuser:mypw", nil, nil) })
+	if ExitCode(err) != HTTP_ERROR {
+		t.Errorf("expected exit code %v, found %v", HTTP_ERROR, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the rate limited retries should wait about the request timeout, found %v", elapsed)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls within the budget, found %v", calls)
+	}
+}
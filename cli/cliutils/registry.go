@@ -0,0 +1,194 @@
+package cliutils
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/i18n"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// The registry of the images in docker hub, which have no domain in their path.
+const DOCKER_HUB_REGISTRY = "registry-1.docker.io"
+
+// The media types of the manifests that the digest of an image can be resolved to. A manifest list is preferred, so
+// that the digest is the same for all the arches of a multi-arch image.
+var registryManifestTypes = []string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+// ResolveDockerImageDigest returns the digest of the manifest that the tag of the image points to in its docker
+// registry, with the Docker Registry HTTP API V2, without pulling or pushing the image. The domain is empty for the
+// images in docker hub and the tag defaults to latest. The credentials of the registry in ~/.docker/config.json are
+// used when the registry requires them.
+func ResolveDockerImageDigest(domain, path, tag string) (string, error) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	registry := domain
+	if registry == "" || registry == "docker.io" || registry == "index.docker.io" {
+		registry = DOCKER_HUB_REGISTRY
+		if !strings.Contains(path, "/") {
+			path = "library/" + path
+		}
+	}
+	if tag == "" {
+		tag = "latest"
+	}
+	manifestUrl := registryScheme(registry) + "://" + registry + "/v2/" + path + "/manifests/" + url.PathEscape(tag)
+	Verbose(msgPrinter.Sprintf("Resolving the digest of %v:%v with %v", path, tag, manifestUrl))
+
+	username, password := "", ""
+	if auth, err := GetDockerAuth(domain); err == nil {
+		username, password = auth.Username, auth.Password
+	}
+
+	httpClient := GetHTTPClient(config.HTTPRequestTimeoutS)
+	authorization := ""
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(http.MethodGet, manifestUrl, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Accept", strings.Join(registryManifestTypes, ", "))
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return "", errors.New(msgPrinter.Sprintf("unable to get the manifest of %v:%v from %v: %v", path, tag, registry, err))
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return "", errors.New(msgPrinter.Sprintf("unable to read the manifest of %v:%v from %v: %v", path, tag, registry, err))
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized && attempt == 0:
+			if authorization, err = registryAuthorization(httpClient, resp.Header.Get("Www-Authenticate"), username, password); err != nil {
+				return "", err
+			}
+		case resp.StatusCode == http.StatusOK:
+			// the registries send the digest of the manifest, otherwise it is the digest of its bytes
+			if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+				return digest, nil
+			}
+			return fmt.Sprintf("sha256:%x", sha256.Sum256(body)), nil
+		case resp.StatusCode == http.StatusNotFound:
+			return "", errors.New(msgPrinter.Sprintf("the tag %v of %v was not found in the docker registry %v", tag, path, registry))
+		default:
+			return "", errors.New(msgPrinter.Sprintf("unable to get the manifest of %v:%v from %v, HTTP code %v: %v", path, tag, registry, resp.StatusCode, strings.TrimSpace(string(body))))
+		}
+	}
+}
+
+// The registries on the loopback interface, like the docker daemon trusts by default, are called with http.
+func registryScheme(registry string) string {
+	host := registry
+	if h, _, err := net.SplitHostPort(registry); err == nil {
+		host = h
+	}
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return "http"
+	}
+	return "https"
+}
+
+// The authorization header for the challenge of a registry. A Bearer challenge gets a token from the token server of
+// the registry, with the credentials if there are some, a Basic challenge uses the credentials.
+func registryAuthorization(httpClient *http.Client, challenge string, username string, password string) (string, error) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	scheme, params := parseAuthChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if username == "" {
+			return "", errors.New(msgPrinter.Sprintf("the docker registry requires credentials, run 'docker login' first"))
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(username, password)
+		return req.Header.Get("Authorization"), nil
+	case "bearer":
+		if params["realm"] == "" {
+			return "", errors.New(msgPrinter.Sprintf("the authentication challenge of the docker registry has no realm: %v", challenge))
+		}
+		query := url.Values{}
+		for _, p := range []string{"service", "scope"} {
+			if params[p] != "" {
+				query.Set(p, params[p])
+			}
+		}
+		req, err := http.NewRequest(http.MethodGet, params["realm"]+"?"+query.Encode(), nil)
+		if err != nil {
+			return "", err
+		}
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return "", errors.New(msgPrinter.Sprintf("unable to get a token from %v: %v", params["realm"], err))
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", errors.New(msgPrinter.Sprintf("unable to get a token from %v, HTTP code %v", params["realm"], resp.StatusCode))
+		}
+		token := struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}{}
+		if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+			return "", errors.New(msgPrinter.Sprintf("unable to read the token from %v: %v", params["realm"], err))
+		}
+		if token.Token == "" {
+			token.Token = token.AccessToken
+		}
+		return "Bearer " + token.Token, nil
+	default:
+		return "", errors.New(msgPrinter.Sprintf("the docker registry requires an unsupported authentication: %v", challenge))
+	}
+}
+
+// Parse a WWW-Authenticate header, for example Bearer realm="https://auth.docker.io/token",service="registry.docker.io",
+// into its scheme and parameters.
+func parseAuthChallenge(challenge string) (string, map[string]string) {
+	params := map[string]string{}
+	parts := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	if len(parts) < 2 {
+		return parts[0], params
+	}
+	rest := parts[1]
+	for rest != "" {
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		name := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = strings.TrimSpace(rest[eq+1:])
+		value := ""
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if comma := strings.Index(rest, ","); comma >= 0 {
+			value, rest = rest[:comma], rest[comma:]
+		} else {
+			value, rest = rest, ""
+		}
+		params[name] = value
+		rest = strings.TrimPrefix(strings.TrimSpace(rest), ",")
+	}
+	return parts[0], params
+}
@@ -0,0 +1,60 @@
+// +build unit
+
+package cliutils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_parseAuthChallenge(t *testing.T) {
+	scheme, params := parseAuthChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/ubuntu:pull"`)
+	if scheme != "Bearer" || params["realm"] != "https://auth.docker.io/token" || params["service"] != "registry.docker.io" || params["scope"] != "repository:library/ubuntu:pull" {
+		t.Errorf("wrong challenge: %v %v", scheme, params)
+	}
+	if scheme, params := parseAuthChallenge(`Basic realm=registry`); scheme != "Basic" || params["realm"] != "registry" {
+		t.Errorf("wrong challenge: %v %v", scheme, params)
+	}
+}
+
+func Test_ResolveDockerImageDigest(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			if r.URL.Query().Get("scope") != "repository:myorg/netspeed:pull" {
+				http.Error(w, "wrong scope", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"token":"mytoken"}`))
+		case r.Header.Get("Authorization") != "Bearer mytoken":
+			w.Header().Set("Www-Authenticate", `Bearer realm="`+ts.URL+`/token",service="registry",scope="repository:myorg/netspeed:pull"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		case r.URL.Path == "/v2/myorg/netspeed/manifests/1.0.0":
+			if !strings.Contains(r.Header.Get("Accept"), "manifest.list.v2+json") {
+				http.Error(w, "wrong media type", http.StatusNotAcceptable)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", digest)
+			w.Write([]byte(`{}`))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	verbose, dryRun := false, false
+	Opts.Verbose, Opts.IsDryRun = &verbose, &dryRun
+	defer func() { Opts.Verbose, Opts.IsDryRun = nil, nil }()
+
+	registry := strings.TrimPrefix(ts.URL, "http://")
+	if found, err := ResolveDockerImageDigest(registry, "myorg/netspeed", "1.0.0"); err != nil || found != digest {
+		t.Errorf("expected digest %v, found %v, error %v", digest, found, err)
+	}
+	if _, err := ResolveDockerImageDigest(registry, "myorg/netspeed", "2.0.0"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected the tag not to be found, error %v", err)
+	}
+}
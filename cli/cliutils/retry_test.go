@@ -0,0 +1,83 @@
+// +build unit
+
+package cliutils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_RetryDelay(t *testing.T) {
+	tests := []struct {
+		interval int
+		count    int
+		expected time.Duration
+	}{
+		{2, 1, 2 * time.Second},
+		{2, 2, 4 * time.Second},
+		{2, 3, 8 * time.Second},
+		{2, 4, HTTP_RETRY_MAX_INTERVAL_S * time.Second},
+		{2, 10, HTTP_RETRY_MAX_INTERVAL_S * time.Second},
+		{90, 1, HTTP_RETRY_MAX_INTERVAL_S * time.Second},
+		{0, 5, 0},
+	}
+	for _, test := range tests {
+		if delay := RetryDelay(test.interval, test.count); delay != test.expected {
+			t.Errorf("RetryDelay(%v, %v) should be %v, found %v", test.interval, test.count, test.expected, delay)
+		}
+	}
+}
+
+func Test_RetryBudgetExceeded(t *testing.T) {
+	// 2 + 4 + 8 + 10 seconds is within the budget, the fifth retry is not
+	if RetryBudgetExceeded(2, 4) {
+		t.Errorf("the fourth retry should be within the budget")
+	}
+	if !RetryBudgetExceeded(2, 5) {
+		t.Errorf("the fifth retry should exceed the budget")
+	}
+	if RetryBudgetExceeded(0, 100) {
+		t.Errorf("retries without a wait should not exceed the budget")
+	}
+}
+
+func Test_HorizonGet_retry(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= 2 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		} else {
+			w.Write([]byte(`{"id":"mynode"}`))
+		}
+	}))
+	defer ts.Close()
+
+	os.Setenv("HORIZON_URL", ts.URL)
+	os.Setenv("HZN_HTTP_RETRY_INTERVAL", "0")
+	defer os.Unsetenv("HORIZON_URL")
+	defer os.Unsetenv("HZN_HTTP_RETRY_INTERVAL")
+	verbose := false
+	Opts.Verbose = &verbose
+	defer func() { Opts.Verbose = nil }()
+
+	var node map[string]string
+	if code, err := HorizonGet("node", []int{200}, &node, true); err != nil || code != 200 || node["id"] != "mynode" {
+		t.Errorf("the request should succeed after the retries, found %v %v %v", code, err, node)
+	} else if calls != 3 {
+		t.Errorf("expected 3 calls, found %v", calls)
+	}
+
+	// a refused connection means the agent is down and is not retried
+	ts.Close()
+	start := time.Now()
+	os.Setenv("HZN_HTTP_RETRY_INTERVAL", "1")
+	if _, err := HorizonGet("node", []int{200}, &node, true); err == nil {
+		t.Errorf("the request should fail")
+	} else if time.Since(start) > time.Second {
+		t.Errorf("a refused connection should not be retried")
+	}
+}
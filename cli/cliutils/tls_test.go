@@ -0,0 +1,140 @@
+// +build unit
+
+package cliutils
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ExchangeGet_caCert(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"orgs":{}}`))
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "cacert-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0644); err != nil {
+		t.Fatalf("unable to write %v: %v", caFile, err)
+	}
+	badFile := filepath.Join(dir, "bad.pem")
+	if err := ioutil.WriteFile(badFile, []byte("not a cert"), 0644); err != nil {
+		t.Fatalf("unable to write %v: %v", badFile, err)
+	}
+
+	verbose, dryRun := false, false
+	Opts.Verbose, Opts.IsDryRun = &verbose, &dryRun
+	defer func() { Opts.Verbose, Opts.IsDryRun = nil, nil }()
+	defer os.Unsetenv(EXCHANGE_CA_CERT_ENV)
+	os.Setenv("HZN_HTTP_RETRIES", "0")
+	defer os.Unsetenv("HZN_HTTP_RETRIES")
+
+	var out map[string]interface{}
+
+	// the certificate of the test server is not signed by a system CA
+	os.Unsetenv(EXCHANGE_CA_CERT_ENV)
+	if _, err := ExchangeGetE("Exchange", ts.URL, "orgs", "", []int{200}, &out); err == nil {
+		t.Errorf("the certificate should not be trusted")
+	} else if !strings.Contains(err.Error(), EXCHANGE_CA_CERT_ENV) {
+		t.Errorf("the error should tell how to trust the certificate, found %v", err)
+	}
+
+	os.Setenv(EXCHANGE_CA_CERT_ENV, caFile)
+	if GetIcpCertPath() != caFile {
+		t.Errorf("%v should take precedence, found %v", EXCHANGE_CA_CERT_ENV, GetIcpCertPath())
+	} else if code, err := ExchangeGetE("Exchange", ts.URL, "orgs", "", []int{200}, &out); err != nil || code != 200 {
+		t.Errorf("the certificate should be trusted, found %v %v", code, err)
+	}
+
+	os.Setenv(EXCHANGE_CA_CERT_ENV, badFile)
+	if err := TrustIcpCert(GetHTTPClient(0)); err == nil {
+		t.Errorf("a file without certificates should be an error")
+	}
+}
+
+func Test_ExchangeGet_clientCert(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clientcert-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a self signed client certificate that the server trusts
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to create a key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "edge-client"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unable to create a cert: %v", err)
+	}
+	clientCert, _ := x509.ParseCertificate(der)
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"orgs":{}}`))
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	ts.StartTLS()
+	defer ts.Close()
+
+	verbose, dryRun, insecure := false, false, true
+	clientCertFlag, clientKeyFlag := "", ""
+	Opts.Verbose, Opts.IsDryRun, Opts.Insecure, Opts.ClientCert, Opts.ClientKey = &verbose, &dryRun, &insecure, &clientCertFlag, &clientKeyFlag
+	defer func() { Opts.Verbose, Opts.IsDryRun, Opts.Insecure, Opts.ClientCert, Opts.ClientKey = nil, nil, nil, nil, nil }()
+	os.Setenv("HZN_HTTP_RETRIES", "0")
+	defer os.Unsetenv("HZN_HTTP_RETRIES")
+	defer os.Unsetenv(EXCHANGE_CLIENT_CERT_ENV)
+	defer os.Unsetenv(EXCHANGE_CLIENT_KEY_ENV)
+
+	var out map[string]interface{}
+	if _, err := ExchangeGetE("Exchange", ts.URL, "orgs", "", []int{200}, &out); err == nil {
+		t.Errorf("the server should require a client certificate")
+	}
+
+	os.Setenv(EXCHANGE_CLIENT_CERT_ENV, certFile)
+	if _, err := ExchangeGetE("Exchange", ts.URL, "orgs", "", []int{200}, &out); ExitCode(err) != CLI_INPUT_ERROR {
+		t.Errorf("a certificate without a key should be an input error, found %v", err)
+	}
+
+	clientKeyFlag = keyFile
+	if code, err := ExchangeGetE("Exchange", ts.URL, "orgs", "", []int{200}, &out); err != nil || code != 200 {
+		t.Errorf("the client certificate should be accepted, found %v %v", code, err)
+	}
+
+	clientKeyFlag = certFile
+	if _, err := ExchangeGetE("Exchange", ts.URL, "orgs", "", []int{200}, &out); ExitCode(err) != CLI_INPUT_ERROR {
+		t.Errorf("a bad key should be an input error, found %v", err)
+	}
+}
@@ -0,0 +1,274 @@
+package cliutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/open-horizon/anax/i18n"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// The env var that turns on the trace of the http requests. It is stderr (or true) to write the trace to stderr, or the
+// file that the trace is appended to.
+const HTTP_TRACE_ENV = "HZN_HTTP_TRACE"
+
+// The largest part of a request or response body that is written to the trace.
+const HTTP_TRACE_MAX_BODY_SIZE = 64 * 1024
+
+// The env var that writes the bodies that cannot be redacted to the trace: the bodies that are not json and the bodies
+// larger than HTTP_TRACE_MAX_BODY_SIZE, which are truncated. They are left out of the trace unless it is set to true.
+const HTTP_TRACE_RAW_BODIES_ENV = "HZN_HTTP_TRACE_RAW_BODIES"
+
+// The value that replaces the credentials in the trace and in the support bundle.
+const REDACTED = "REDACTED"
+
+// The headers, and the json fields and query parameters that contain these words, that are never written to the trace.
+var traceRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+var traceRedactedWords = []string{"password", "token", "secret", "apikey", "privatekey"}
+
+// GetHttpTrace returns where the http requests are traced: "" when they are not, stderr, or the name of a file. The
+// --dump-http flag writes the trace to stderr, unless HZN_HTTP_TRACE names a file.
+func GetHttpTrace() string {
+	target := os.Getenv(HTTP_TRACE_ENV)
+	switch strings.ToLower(target) {
+	case "", "0", "false":
+		target = ""
+	case "1", "true", "stderr":
+		target = "stderr"
+	}
+	if target == "" && Opts.DumpHttp != nil && *Opts.DumpHttp {
+		target = "stderr"
+	}
+	return target
+}
+
+// A transport that writes the headers and bodies of the requests and responses to the trace, with the credentials
+// redacted, so that a trace can be attached to a bug report.
+type traceTransport struct {
+	next   http.RoundTripper
+	target string
+}
+
+// Returns the transport, wrapped with the trace when it is turned on.
+func withHttpTrace(transport http.RoundTripper) http.RoundTripper {
+	if target := GetHttpTrace(); target != "" {
+		return &traceTransport{next: transport, target: target}
+	}
+	return transport
+}
+
+// Returns the transport that the trace and record transports wrap.
+func unwrapTransport(transport http.RoundTripper) http.RoundTripper {
+	for {
+		switch t := transport.(type) {
+		case *traceTransport:
+			transport = t.next
+		case *recordTransport:
+			transport = t.next
+		default:
+			return transport
+		}
+	}
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var trace bytes.Buffer
+	fmt.Fprintf(&trace, "> %v %v %v\n", req.Method, redactUrl(req.URL), time.Now().Format(time.RFC3339))
+	writeTraceHeaders(&trace, "> ", req.Header)
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			fmt.Fprintf(&trace, ">\n> (streamed body of %v bytes not shown)\n", req.ContentLength)
+		} else if body, err := req.GetBody(); err == nil {
+			data, _ := ioutil.ReadAll(io.LimitReader(body, HTTP_TRACE_MAX_BODY_SIZE+1))
+			body.Close()
+			writeTraceBody(&trace, "> ", data)
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		fmt.Fprintf(&trace, "< error after %v: %v\n\n", time.Since(start), err)
+		t.write(trace.Bytes())
+		return resp, err
+	}
+
+	fmt.Fprintf(&trace, "< %v %v (%v)\n", resp.Proto, resp.Status, time.Since(start))
+	writeTraceHeaders(&trace, "< ", resp.Header)
+
+	// the start of the body is read for the trace, and read again by the caller
+	data, readErr := ioutil.ReadAll(io.LimitReader(resp.Body, HTTP_TRACE_MAX_BODY_SIZE+1))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), resp.Body), resp.Body}
+	if len(data) != 0 {
+		writeTraceBody(&trace, "< ", data)
+	}
+	if readErr != nil {
+		fmt.Fprintf(&trace, "< error reading the body: %v\n", readErr)
+	}
+	trace.WriteString("\n")
+	t.write(trace.Bytes())
+	return resp, nil
+}
+
+var traceLock sync.Mutex
+var traceFiles = map[string]*os.File{}
+
+// Write one request and response to the trace. The trace files stay open until the command ends.
+func (t *traceTransport) write(trace []byte) {
+	traceLock.Lock()
+	defer traceLock.Unlock()
+
+	if t.target == "stderr" {
+		os.Stderr.Write(trace)
+		return
+	}
+	f, ok := traceFiles[t.target]
+	if !ok {
+		var err error
+		if f, err = os.OpenFile(t.target, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600); err != nil {
+			Warning(i18n.GetMessagePrinter().Sprintf("Unable to open the http trace file %v: %v", t.target, err))
+			f = os.Stderr
+		}
+		traceFiles[t.target] = f
+	}
+	f.Write(trace)
+}
+
+func writeTraceHeaders(trace *bytes.Buffer, prefix string, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, value := range header[name] {
+			for _, redacted := range traceRedactedHeaders {
+				if strings.EqualFold(name, redacted) {
+					value = REDACTED
+				}
+			}
+			fmt.Fprintf(trace, "%v%v: %v\n", prefix, name, value)
+		}
+	}
+}
+
+func writeTraceBody(trace *bytes.Buffer, prefix string, data []byte) {
+	truncated := len(data) > HTTP_TRACE_MAX_BODY_SIZE
+	if truncated {
+		data = data[:HTTP_TRACE_MAX_BODY_SIZE]
+	}
+
+	// only the complete json bodies can be redacted, the others are left out unless the raw bodies are asked for
+	body := ""
+	if !truncated && json.Valid(data) {
+		body = RedactJson(data)
+	} else if !traceRawBodies() {
+		truncated = false
+		body = i18n.GetMessagePrinter().Sprintf("(body not shown, it is not json or is larger than %v bytes, set %v to true to show it)", HTTP_TRACE_MAX_BODY_SIZE, HTTP_TRACE_RAW_BODIES_ENV)
+	} else if utf8.Valid(data) {
+		body = string(data)
+	} else {
+		body = i18n.GetMessagePrinter().Sprintf("(%v bytes of binary data)", len(data))
+	}
+	trace.WriteString(prefix + "\n")
+	for _, line := range strings.Split(strings.TrimSuffix(body, "\n"), "\n") {
+		trace.WriteString(prefix + line + "\n")
+	}
+	if truncated {
+		fmt.Fprintf(trace, "%v(the body is truncated at %v bytes)\n", prefix, HTTP_TRACE_MAX_BODY_SIZE)
+	}
+}
+
+// Returns true if the bodies that cannot be redacted are written to the trace.
+func traceRawBodies() bool {
+	raw, err := strconv.ParseBool(os.Getenv(HTTP_TRACE_RAW_BODIES_ENV))
+	return err == nil && raw
+}
+
+// IsRedactedName returns true if a json field, a query parameter or a setting with the name can contain credentials.
+func IsRedactedName(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range traceRedactedWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactJson returns the indented json with the values of the fields that can contain credentials redacted.
+func RedactJson(data []byte) string {
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return string(data)
+	}
+	if out, err := json.MarshalIndent(redactJsonValue(v), "", JSON_INDENT); err == nil {
+		return string(out)
+	}
+	return string(data)
+}
+
+func redactJsonValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		// the settings that are name/value pairs, like the user inputs {"name":"PASSWORD","value":"..."}
+		if name, ok := value["name"].(string); ok && isRedactedPairName(name) {
+			if _, hasValue := value["value"]; hasValue {
+				value["value"] = REDACTED
+			}
+		}
+		for name, field := range value {
+			if name == "value" && field == REDACTED {
+				continue
+			}
+			if _, isString := field.(string); isString && IsRedactedName(name) {
+				value[name] = REDACTED
+			} else {
+				value[name] = redactJsonValue(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = redactJsonValue(item)
+		}
+	}
+	return v
+}
+
+// Returns true if the value of a name/value pair with the name can contain credentials. The names of these pairs are
+// the names of settings, like HZN_EXCHANGE_USER_AUTH.
+func isRedactedPairName(name string) bool {
+	return IsRedactedName(name) || strings.HasSuffix(strings.ToUpper(name), "_AUTH")
+}
+
+func redactUrl(u *url.URL) string {
+	redacted := *u
+	if redacted.User != nil {
+		redacted.User = url.User(redacted.User.Username())
+	}
+	if query := redacted.Query(); len(query) != 0 {
+		for name := range query {
+			if IsRedactedName(name) {
+				query.Set(name, REDACTED)
+			}
+		}
+		redacted.RawQuery = query.Encode()
+	}
+	return redacted.String()
+}
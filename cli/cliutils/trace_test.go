@@ -0,0 +1,112 @@
+// +build unit
+
+package cliutils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_HttpTrace(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=xyz")
+		if r.Method == http.MethodPut {
+			w.WriteHeader(http.StatusCreated)
+		}
+		w.Write([]byte(`{"nodes":{"myorg/n1":{"name":"n1","token":"exchange-token"}}}`))
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "trace-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	traceFile := filepath.Join(dir, "trace.log")
+
+	verbose, dryRun, dumpHttp := false, false, false
+	Opts.Verbose, Opts.IsDryRun, Opts.DumpHttp = &verbose, &dryRun, &dumpHttp
+	defer func() { Opts.Verbose, Opts.IsDryRun, Opts.DumpHttp = nil, nil, nil }()
+
+	if GetHttpTrace() != "" {
+		t.Errorf("the trace should be off by default")
+	}
+	dumpHttp = true
+	if GetHttpTrace() != "stderr" {
+		t.Errorf("--dump-http should trace to stderr, found %v", GetHttpTrace())
+	}
+
+	os.Setenv(HTTP_TRACE_ENV, traceFile)
+	defer os.Unsetenv(HTTP_TRACE_ENV)
+
+	var out map[string]interface{}
+	body := map[string]string{"name": "n1", "token": "node-token"}
+	if _, err := ExchangePutPostE("Exchange", http.MethodPut, ts.URL, "orgs/myorg/nodes/n1?password=pw1", "myorg/me:userpw", []int{201}, body, nil); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	} else if _, err := ExchangeGetE("Exchange", ts.URL, "orgs/myorg/nodes", "myorg/me:userpw", []int{200}, &out); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	} else if _, ok := out["nodes"]; !ok {
+		t.Errorf("the traced response should still be returned, found %v", out)
+	}
+
+	data, err := ioutil.ReadFile(traceFile)
+	if err != nil {
+		t.Fatalf("unable to read the trace: %v", err)
+	}
+	trace := string(data)
+	for _, expected := range []string{"> PUT " + ts.URL, "> GET " + ts.URL, `"name": "n1"`, "< HTTP/1.1 201 Created", "< Set-Cookie: REDACTED", "> Authorization: REDACTED", "password=REDACTED"} {
+		if !strings.Contains(trace, expected) {
+			t.Errorf("the trace should contain %v, found %v", expected, trace)
+		}
+	}
+	for _, secret := range []string{"node-token", "exchange-token", "userpw", "pw1", "session=xyz", base64.StdEncoding.EncodeToString([]byte("myorg/me:userpw"))} {
+		if strings.Contains(trace, secret) {
+			t.Errorf("the trace should not contain %v, found %v", secret, trace)
+		}
+	}
+}
+
+func Test_RedactJson(t *testing.T) {
+	data := []byte(`{"token":"tok1","userInput":[{"serviceUrl":"svc1","inputs":[{"name":"PASSWORD","value":"pw1"},{"name":"HZN_EXCHANGE_USER_AUTH","value":"me:pw2"},{"name":"VERBOSE","value":"true"}]}],"nested":{"apiKey":{"id":"k1"}}}`)
+	redacted := RedactJson(data)
+	for _, secret := range []string{"tok1", "pw1", "me:pw2"} {
+		if strings.Contains(redacted, secret) {
+			t.Errorf("the json should not contain %v, found %v", secret, redacted)
+		}
+	}
+	for _, expected := range []string{`"name": "PASSWORD"`, `"value": "true"`, `"id": "k1"`} {
+		if !strings.Contains(redacted, expected) {
+			t.Errorf("the json should contain %v, found %v", expected, redacted)
+		}
+	}
+}
+
+func Test_writeTraceBody(t *testing.T) {
+	os.Unsetenv(HTTP_TRACE_RAW_BODIES_ENV)
+	defer os.Unsetenv(HTTP_TRACE_RAW_BODIES_ENV)
+
+	large := []byte(`{"token":"tok1","data":"` + strings.Repeat("x", HTTP_TRACE_MAX_BODY_SIZE) + `"}`)
+	for _, body := range [][]byte{[]byte("password=pw1&user=me"), large} {
+		var trace bytes.Buffer
+		writeTraceBody(&trace, "> ", body)
+		if strings.Contains(trace.String(), "pw1") || strings.Contains(trace.String(), "tok1") {
+			t.Errorf("a body that cannot be redacted should not be traced by default, found %v", trace.String())
+		} else if !strings.Contains(trace.String(), HTTP_TRACE_RAW_BODIES_ENV) {
+			t.Errorf("the trace should say how to show the body, found %v", trace.String())
+		}
+	}
+
+	os.Setenv(HTTP_TRACE_RAW_BODIES_ENV, "true")
+	var trace bytes.Buffer
+	writeTraceBody(&trace, "> ", large)
+	if !strings.Contains(trace.String(), "tok1") || !strings.Contains(trace.String(), "truncated") {
+		t.Errorf("the raw body should be traced truncated when asked for, found %v", trace.String()[:200])
+	}
+}
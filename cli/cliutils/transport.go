@@ -0,0 +1,112 @@
+package cliutils
+
+import (
+	"crypto/tls"
+	"fmt"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/i18n"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The env var that sets how many idle connections are kept open to be reused by the next requests.
+const HTTP_MAX_IDLE_CONNS_ENV = "HZN_HTTP_MAX_IDLE_CONNS"
+
+// A transport that is shared by the http clients created with the same settings, so that the connections to the agent,
+// the agbot and the exchange are kept alive and reused by all the requests of a command. The TLS settings are loaded
+// once, when the transport is created, and the errors are returned by TrustIcpCert and UseClientCert. An invalid
+// proxy is returned by newHTTPClient.
+type sharedTransport struct {
+	transport     *http.Transport
+	icpCertErr    error
+	clientCertErr error
+	proxyErr      error
+}
+
+var sharedTransportsLock sync.Mutex
+var sharedTransports = map[string]*sharedTransport{}
+
+// GetMaxIdleConns returns the number of idle connections that are kept open, from HZN_HTTP_MAX_IDLE_CONNS or the default.
+func GetMaxIdleConns() int {
+	if envMax := os.Getenv(HTTP_MAX_IDLE_CONNS_ENV); envMax != "" {
+		if max, err := strconv.Atoi(envMax); err == nil && max >= 0 {
+			return max
+		}
+		Warning(i18n.GetMessagePrinter().Sprintf("Unable to use %v to set the maximum number of idle connections, the value is not a valid number: %v", HTTP_MAX_IDLE_CONNS_ENV, envMax))
+	}
+	return config.MaxHTTPIdleConnections
+}
+
+// Returns the transport shared by the clients with this request timeout and the current TLS and proxy settings,
+// creating it the first time.
+func getSharedTransport(requestTimeout int, skipSSL bool) *sharedTransport {
+	certPath, keyPath := GetClientCertPaths()
+	maxIdleConns := GetMaxIdleConns()
+	key := fmt.Sprintf("%v|%v|%v|%v|%v|%v|%v|%v", requestTimeout, skipSSL, GetIcpCertPath(), certPath, keyPath, GetProxy(), strings.Join(getNoProxy(), ","), maxIdleConns)
+
+	sharedTransportsLock.Lock()
+	defer sharedTransportsLock.Unlock()
+	if st, ok := sharedTransports[key]; ok {
+		return st
+	}
+
+	responseTimeout := int(float64(requestTimeout) * 0.8)
+	dialTimeout := int(float64(requestTimeout) * 0.5)
+	keepAlive := requestTimeout * 2
+	TLSHandshake := dialTimeout
+	expectContinue := int(float64(requestTimeout) * 0.5)
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: skipSSL,
+	}
+	proxyFunc, proxyErr := getProxyFunc()
+	st := &sharedTransport{
+		transport: &http.Transport{
+			Proxy: proxyFunc,
+			DialContext: dialWithUnixSocket(&net.Dialer{
+				Timeout:   time.Duration(dialTimeout) * time.Second,
+				KeepAlive: time.Duration(keepAlive) * time.Second,
+			}),
+			TLSHandshakeTimeout:   time.Duration(TLSHandshake) * time.Second,
+			ResponseHeaderTimeout: time.Duration(responseTimeout) * time.Second,
+			ExpectContinueTimeout: time.Duration(expectContinue) * time.Second,
+			MaxIdleConns:          maxIdleConns,
+			MaxIdleConnsPerHost:   maxIdleConns,
+			IdleConnTimeout:       config.HTTPIdleConnectionTimeoutS * time.Second,
+			TLSClientConfig:       tlsConfig,
+		},
+		icpCertErr:    trustIcpCert(tlsConfig),
+		clientCertErr: useClientCert(tlsConfig),
+		proxyErr:      proxyErr,
+	}
+	sharedTransports[key] = st
+	return st
+}
+
+// Returns the shared transport of an http client, or nil if the client has a transport of its own.
+func findSharedTransport(httpClient *http.Client) *sharedTransport {
+	sharedTransportsLock.Lock()
+	defer sharedTransportsLock.Unlock()
+	for _, st := range sharedTransports {
+		if unwrapTransport(httpClient.Transport) == st.transport {
+			return st
+		}
+	}
+	return nil
+}
+
+// CloseIdleConnections closes the idle connections of the shared transports, and forgets them so that the next clients
+// get new ones.
+func CloseIdleConnections() {
+	sharedTransportsLock.Lock()
+	defer sharedTransportsLock.Unlock()
+	for key, st := range sharedTransports {
+		st.transport.CloseIdleConnections()
+		delete(sharedTransports, key)
+	}
+}
@@ -0,0 +1,53 @@
+// +build unit
+
+package cliutils
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_GetHTTPClient_reuse(t *testing.T) {
+	var conns int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"orgs":{}}`))
+	}))
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+	defer CloseIdleConnections()
+
+	verbose, dryRun := false, false
+	Opts.Verbose, Opts.IsDryRun = &verbose, &dryRun
+	defer func() { Opts.Verbose, Opts.IsDryRun = nil, nil }()
+
+	var out map[string]interface{}
+	for i := 0; i < 5; i++ {
+		if code, err := ExchangeGetE("Exchange", ts.URL, "orgs", "", []int{200}, &out); err != nil || code != 200 {
+			t.Fatalf("unexpected result %v %v", code, err)
+		}
+	}
+	if conns != 1 {
+		t.Errorf("the connection should be reused, found %v connections", conns)
+	}
+
+	if GetHTTPClient(0).Transport != GetHTTPClient(0).Transport {
+		t.Errorf("the clients with the same settings should share their transport")
+	} else if GetHTTPClient(0).Transport == GetHTTPClient(10).Transport {
+		t.Errorf("the clients with different timeouts should not share their transport")
+	}
+
+	os.Setenv(HTTP_MAX_IDLE_CONNS_ENV, "3")
+	defer os.Unsetenv(HTTP_MAX_IDLE_CONNS_ENV)
+	if transport := GetHTTPClient(0).Transport.(*http.Transport); transport.MaxIdleConns != 3 || transport.MaxIdleConnsPerHost != 3 {
+		t.Errorf("%v should set the idle connections, found %v", HTTP_MAX_IDLE_CONNS_ENV, transport.MaxIdleConns)
+	}
+}
@@ -0,0 +1,47 @@
+package cliutils
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+)
+
+// A Horizon agent or agbot url like unix:///var/run/horizon/horizon.sock sends the requests to the API on that unix
+// domain socket.
+const UNIX_SOCKET_URL_PREFIX = "unix://"
+
+// The host of the urls of the requests that are sent to the unix domain socket.
+const UNIX_SOCKET_HOST = "horizon.unix.socket"
+
+// The socket of the last unix socket url that a request was sent to. The agent and agbot commands use one API at a
+// time.
+var horizonSocketLock sync.Mutex
+var horizonSocket string
+
+// Returns the url of a request to the Horizon API at urlSuffix. The requests to a unix socket url are sent to
+// UNIX_SOCKET_HOST, which the shared transports dial the socket for.
+func horizonApiUrl(urlSuffix string) string {
+	urlBase := GetHorizonUrlBase()
+	if !strings.HasPrefix(urlBase, UNIX_SOCKET_URL_PREFIX) {
+		return urlBase + "/" + urlSuffix
+	}
+
+	horizonSocketLock.Lock()
+	horizonSocket = strings.TrimPrefix(urlBase, UNIX_SOCKET_URL_PREFIX)
+	horizonSocketLock.Unlock()
+	return "http://" + UNIX_SOCKET_HOST + "/" + urlSuffix
+}
+
+// Returns the dial function of the shared transports, which dials the Horizon API socket for UNIX_SOCKET_HOST.
+func dialWithUnixSocket(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if host, _, err := net.SplitHostPort(addr); err == nil && host == UNIX_SOCKET_HOST {
+			horizonSocketLock.Lock()
+			socket := horizonSocket
+			horizonSocketLock.Unlock()
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
@@ -0,0 +1,50 @@
+// +build unit
+
+package cliutils
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_HorizonGet_unixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cliutils-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "horizon.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("unable to listen on %v: %v", socket, err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/node" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"id":"mynode"}`))
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	verbose, dryRun, horizonUrl := false, false, UNIX_SOCKET_URL_PREFIX+socket
+	Opts.Verbose, Opts.IsDryRun, Opts.HorizonUrl = &verbose, &dryRun, &horizonUrl
+	defer func() { Opts.Verbose, Opts.IsDryRun, Opts.HorizonUrl = nil, nil, nil }()
+
+	// a proxy is not used for the socket
+	os.Setenv("HTTP_PROXY", "http://127.0.0.1:1")
+	defer os.Unsetenv("HTTP_PROXY")
+
+	var node map[string]interface{}
+	if httpCode, err := HorizonGet("node", []int{200}, &node, true); err != nil || httpCode != 200 {
+		t.Fatalf("unexpected response code %v, error %v", httpCode, err)
+	} else if node["id"] != "mynode" {
+		t.Errorf("expected the node, found %v", node)
+	}
+}
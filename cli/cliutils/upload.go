@@ -0,0 +1,75 @@
+package cliutils
+
+import (
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// MultipartBody is a multipart/form-data body for HorizonPutPost. The files are streamed from disk as the request is
+// sent, so a large file does not have to fit in memory.
+type MultipartBody struct {
+	Fields map[string]string // the form fields
+	Files  map[string]string // the files to upload, by the name of their form field
+}
+
+// Returns the reader of the body and its content type. The files are opened first, so that a missing file is an error
+// before the request is sent, and the body is written by a goroutine as the request reads it.
+func (m *MultipartBody) reader() (io.Reader, string, error) {
+	fileFields := make([]string, 0, len(m.Files))
+	for field := range m.Files {
+		fileFields = append(fileFields, field)
+	}
+	sort.Strings(fileFields)
+
+	files := make([]*os.File, 0, len(fileFields))
+	for _, field := range fileFields {
+		f, err := os.Open(m.Files[field])
+		if err != nil {
+			for _, opened := range files {
+				opened.Close()
+			}
+			return nil, "", err
+		}
+		files = append(files, f)
+	}
+
+	fieldNames := make([]string, 0, len(m.Fields))
+	for name := range m.Fields {
+		fieldNames = append(fieldNames, name)
+	}
+	sort.Strings(fieldNames)
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		var err error
+		defer func() {
+			for _, f := range files {
+				f.Close()
+			}
+			if err == nil {
+				err = mw.Close()
+			}
+			pw.CloseWithError(err)
+		}()
+
+		for _, name := range fieldNames {
+			if err = mw.WriteField(name, m.Fields[name]); err != nil {
+				return
+			}
+		}
+		for i, field := range fileFields {
+			var part io.Writer
+			if part, err = mw.CreateFormFile(field, filepath.Base(files[i].Name())); err != nil {
+				return
+			}
+			if _, err = io.Copy(part, files[i]); err != nil {
+				return
+			}
+		}
+	}()
+	return pr, mw.FormDataContentType(), nil
+}
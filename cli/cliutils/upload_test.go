@@ -0,0 +1,96 @@
+// +build unit
+
+package cliutils
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_HorizonPutPost_upload(t *testing.T) {
+	type received struct {
+		contentType      string
+		contentLength    int64
+		transferEncoding []string
+		body             string
+		form             map[string]string
+	}
+	var last received
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last = received{contentType: r.Header.Get("Content-Type"), contentLength: r.ContentLength, transferEncoding: r.TransferEncoding, form: map[string]string{}}
+		if strings.HasPrefix(last.contentType, "multipart/form-data") {
+			if err := r.ParseMultipartForm(1024); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			for name, values := range r.MultipartForm.Value {
+				last.form[name] = values[0]
+			}
+			for name, headers := range r.MultipartForm.File {
+				f, _ := headers[0].Open()
+				data, _ := ioutil.ReadAll(f)
+				f.Close()
+				last.form[name] = headers[0].Filename + ":" + string(data)
+			}
+		} else {
+			data, _ := ioutil.ReadAll(r.Body)
+			last.body = string(data)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "upload-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "service.public.pem")
+	ioutil.WriteFile(keyFile, []byte("-----BEGIN PUBLIC KEY-----"), 0644)
+
+	verbose, dryRun := false, false
+	Opts.Verbose, Opts.IsDryRun = &verbose, &dryRun
+	defer func() { Opts.Verbose, Opts.IsDryRun = nil, nil }()
+	os.Setenv("HORIZON_URL", ts.URL)
+	defer os.Unsetenv("HORIZON_URL")
+
+	// a file is streamed with its size
+	f, _ := os.Open(keyFile)
+	if _, _, err := HorizonPutPostE(http.MethodPut, "trust/service.public.pem", []int{201}, f); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	} else if last.body != "-----BEGIN PUBLIC KEY-----" || last.contentLength != 26 {
+		t.Errorf("wrong file upload %v", last)
+	}
+
+	// any other reader is sent in chunks
+	if _, _, err := HorizonPutPostE(http.MethodPut, "trust/k", []int{201}, ioutil.NopCloser(strings.NewReader("chunked body"))); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	} else if last.body != "chunked body" || len(last.transferEncoding) == 0 || last.transferEncoding[0] != "chunked" {
+		t.Errorf("wrong chunked upload %v", last)
+	}
+
+	// a multipart body streams its files
+	body := &MultipartBody{Fields: map[string]string{"name": "k1"}, Files: map[string]string{"key": keyFile}}
+	if _, _, err := HorizonPutPostE(http.MethodPost, "trust", []int{201}, body); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	} else if last.form["name"] != "k1" || last.form["key"] != "service.public.pem:-----BEGIN PUBLIC KEY-----" || len(last.transferEncoding) == 0 || last.transferEncoding[0] != "chunked" {
+		t.Errorf("wrong multipart upload %v", last)
+	}
+
+	missing := &MultipartBody{Files: map[string]string{"key": filepath.Join(dir, "missing.pem")}}
+	if _, _, err := HorizonPutPostE(http.MethodPost, "trust", []int{201}, missing); ExitCode(err) != FILE_IO_ERROR {
+		t.Errorf("a missing file should be a file error, found %v", err)
+	}
+
+	// the json bodies are unchanged
+	if _, _, err := HorizonPutPostE(http.MethodPost, "node/policy", []int{201}, map[string]string{"a": "b"}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	} else if last.body != `{"a":"b"}` || last.contentType != "application/json" {
+		t.Errorf("wrong json upload %v", last)
+	}
+}
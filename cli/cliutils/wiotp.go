@@ -0,0 +1,88 @@
+package cliutils
+
+import (
+	"github.com/open-horizon/anax/i18n"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// The credentials of Watson IoT Platform are an api key and its authentication token, which are global rather than in
+// an org of the exchange. They are used as wiotp:<api key>:<auth token>, or without the prefix when
+// HZN_EXCHANGE_AUTH_SCHEME is wiotp. With them the org is not prepended to the credentials, and the edgenode api of
+// the WIoTP exchange, which the agents use, is replaced by its edge api for hzn.
+
+// The api of the WIoTP exchange that the agents use, at the end of the exchange url, and the one of hzn.
+var reWiotpAgentApi = regexp.MustCompile(`/edgenode/?$`)
+
+const WIOTP_HZN_API = "/edge"
+
+// Before the wiotp scheme, USING_API_KEY=1 told hzn that the credentials were the ones of WIoTP, and the api keys of
+// WIoTP, which start with a-<6 char org id>-, were detected unless USING_API_KEY was 0. Both still work, with a
+// deprecation warning, until they are removed in a later release.
+const USING_API_KEY_ENV = "USING_API_KEY"
+
+var reWiotpApiKey = regexp.MustCompile(`^a-[A-Za-z0-9]{6}-`)
+
+func warnUsingApiKey(msg string, args ...interface{}) {
+	if !Opts.warnedUsingApiKey {
+		Opts.warnedUsingApiKey = true
+		Warning("%v", i18n.GetMessagePrinter().Sprintf(msg, args...))
+	}
+}
+
+// SetWhetherUsingWiotp records whether the credentials of the command are the ones of Watson IoT Platform, so that the
+// urls of the WIoTP exchange are the ones of hzn. The commands call it with their credentials before they get the
+// exchange url.
+func SetWhetherUsingWiotp(creds string) {
+	if isWiotpCredentials(creds) {
+		Opts.UsingWiotp = true
+		Verbose(i18n.GetMessagePrinter().Sprintf("Using Watson IoT Platform credentials"))
+	} else if reWiotpApiKey.MatchString(creds) && os.Getenv(USING_API_KEY_ENV) != "0" {
+		Opts.UsingWiotp = true
+		warnUsingApiKey("the credentials look like a Watson IoT Platform api key and are used as one. This detection is deprecated, use wiotp:KEY:TOKEN or set %v to %v instead, or set %v to 0 if they are not a Watson IoT Platform api key.", EXCHANGE_AUTH_SCHEME_ENV, AUTH_SCHEME_WIOTP, USING_API_KEY_ENV)
+	}
+}
+
+// UsingWiotp returns true if the exchange is the one of Watson IoT Platform: the credentials of the command have the
+// wiotp: prefix, or HZN_EXCHANGE_AUTH_SCHEME is wiotp, or the deprecated USING_API_KEY is 1.
+func UsingWiotp() bool {
+	if os.Getenv(USING_API_KEY_ENV) == "1" {
+		warnUsingApiKey("%v is deprecated, use wiotp:KEY:TOKEN credentials or set %v to %v instead.", USING_API_KEY_ENV, EXCHANGE_AUTH_SCHEME_ENV, AUTH_SCHEME_WIOTP)
+		return true
+	}
+	return Opts.UsingWiotp || strings.ToLower(os.Getenv(EXCHANGE_AUTH_SCHEME_ENV)) == AUTH_SCHEME_WIOTP
+}
+
+// WiotpCredentials returns the credentials with the wiotp: prefix, unless they already have it.
+func WiotpCredentials(creds string) string {
+	if isWiotpCredentials(creds) {
+		return creds
+	}
+	return AUTH_SCHEME_WIOTP + ":" + creds
+}
+
+// WiotpApiUrl returns the url of the api of Watson IoT Platform, such as https://myorg.internetofthings.ibmcloud.com/api/v0002,
+// of the url of its exchange.
+func WiotpApiUrl(exchUrl string) string {
+	exchUrl = strings.TrimSuffix(exchUrl, "/")
+	exchUrl = reWiotpAgentApi.ReplaceAllLiteralString(exchUrl, "")
+	return strings.TrimSuffix(exchUrl, WIOTP_HZN_API)
+}
+
+// WiotpAgentExchangeUrl returns the url of the WIoTP exchange that the agents use, of its url for hzn.
+func WiotpAgentExchangeUrl(exchUrl string) string {
+	return WiotpApiUrl(exchUrl) + "/edgenode"
+}
+
+func isWiotpCredentials(creds string) bool {
+	return strings.HasPrefix(strings.ToLower(creds), AUTH_SCHEME_WIOTP+":")
+}
+
+// The url of a service of the WIoTP exchange for hzn, the url is not changed with other exchanges.
+func wiotpUrl(url string) string {
+	if !UsingWiotp() {
+		return url
+	}
+	return reWiotpAgentApi.ReplaceAllLiteralString(url, WIOTP_HZN_API)
+}
@@ -0,0 +1,66 @@
+// +build unit
+
+package cliutils
+
+import (
+	"os"
+	"testing"
+)
+
+func Test_wiotp(t *testing.T) {
+	opts := Opts
+	defer func() { Opts = opts }()
+	defer os.Unsetenv(EXCHANGE_AUTH_SCHEME_ENV)
+	os.Unsetenv(EXCHANGE_AUTH_SCHEME_ENV)
+
+	exchUrl := "https://abc123.internetofthings.ibmcloud.com/api/v0002/edgenode"
+	Opts = GlobalOptions{}
+
+	// the other credentials do not change the urls or the credentials
+	SetWhetherUsingWiotp("myuser:mypw")
+	if UsingWiotp() || wiotpUrl(exchUrl) != exchUrl || OrgAndCreds("myorg", "myuser:mypw") != "myorg/myuser:mypw" {
+		t.Errorf("the credentials without the wiotp: prefix should not be wiotp credentials")
+	}
+
+	// the deprecated detection of the wiotp api keys can be turned off
+	os.Setenv(USING_API_KEY_ENV, "0")
+	defer os.Unsetenv(USING_API_KEY_ENV)
+	SetWhetherUsingWiotp("a-abc123-key:authtok")
+	if UsingWiotp() || OrgAndCreds("myorg", "a-abc123-key:authtok") != "myorg/a-abc123-key:authtok" {
+		t.Errorf("USING_API_KEY=0 should turn off the detection of the wiotp api keys")
+	}
+	os.Unsetenv(USING_API_KEY_ENV)
+
+	SetWhetherUsingWiotp("wiotp:a-abc123-key:authtok")
+	if !UsingWiotp() {
+		t.Fatalf("the wiotp: credentials should be wiotp credentials")
+	}
+	if url := wiotpUrl(exchUrl); url != "https://abc123.internetofthings.ibmcloud.com/api/v0002/edge" {
+		t.Errorf("wrong url of hzn: %v", url)
+	}
+	if url := wiotpUrl("https://exchange/v1/nodes/edgenode1"); url != "https://exchange/v1/nodes/edgenode1" {
+		t.Errorf("only the edgenode api should be replaced: %v", url)
+	}
+	if creds := OrgAndCreds("myorg", "a-abc123-key:authtok"); creds != "a-abc123-key:authtok" {
+		t.Errorf("the org should not be prepended to the wiotp credentials: %v", creds)
+	}
+
+	// the scheme can also be set for all the credentials
+	Opts = GlobalOptions{}
+	os.Setenv(EXCHANGE_AUTH_SCHEME_ENV, AUTH_SCHEME_WIOTP)
+	if !UsingWiotp() || OrgAndCreds("myorg", "a-abc123-key:authtok") != "a-abc123-key:authtok" {
+		t.Errorf("HZN_EXCHANGE_AUTH_SCHEME=wiotp should use the wiotp credentials")
+	}
+
+	for _, url := range []string{exchUrl, exchUrl + "/", "https://abc123.internetofthings.ibmcloud.com/api/v0002/edge"} {
+		if apiUrl := WiotpApiUrl(url); apiUrl != "https://abc123.internetofthings.ibmcloud.com/api/v0002" {
+			t.Errorf("wrong api url of %v: %v", url, apiUrl)
+		}
+	}
+	if url := WiotpAgentExchangeUrl("https://abc123.internetofthings.ibmcloud.com/api/v0002/edge"); url != exchUrl {
+		t.Errorf("wrong exchange url of the agents: %v", url)
+	}
+	if creds := WiotpCredentials("a-abc123-key:authtok"); creds != "wiotp:a-abc123-key:authtok" || WiotpCredentials(creds) != creds {
+		t.Errorf("wrong wiotp credentials: %v", creds)
+	}
+}
@@ -0,0 +1,176 @@
+package cliutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/open-horizon/anax/i18n"
+	yaml "gopkg.in/yaml.v2"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// IsYamlFile returns true if an input file is YAML: it has a .yaml or .yml extension, or --yaml is given and it does
+// not have a .json extension.
+func IsYamlFile(filePath string) bool {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		return true
+	case ".json":
+		return false
+	}
+	return Opts.YamlInput != nil && *Opts.YamlInput
+}
+
+// Convert a YAML document to indented JSON. The YAML 1.1 rules apply, so a version like 1.0 or a value like yes must
+// be quoted to stay a string.
+func yamlToJson(content []byte) ([]byte, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	doc, err := jsonCompatible(doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// The YAML decoder returns maps with interface{} keys, which json can not marshal.
+func jsonCompatible(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			switch key.(type) {
+			case map[interface{}]interface{}, []interface{}:
+				return nil, fmt.Errorf(i18n.GetMessagePrinter().Sprintf("the key %v is not a scalar", key))
+			}
+			converted, err := jsonCompatible(val)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(key)] = converted
+		}
+		return m, nil
+	case []interface{}:
+		for i, val := range v {
+			converted, err := jsonCompatible(val)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = converted
+		}
+		return v, nil
+	}
+	return value, nil
+}
+
+// The lines of the values of the YAML files that ReadJsonFile converted, by file path, so that an error about a value
+// of the json can be reported at its line in the YAML.
+var yamlLinesLock sync.Mutex
+var yamlLines = map[string]map[string]int{}
+
+func setYamlLines(filePath string, content []byte) {
+	yamlLinesLock.Lock()
+	defer yamlLinesLock.Unlock()
+	yamlLines[filePath] = yamlValueLines(content)
+}
+
+// YamlLines returns the line of every value of a YAML file that ReadJsonFile converted to json, by its path, e.g.
+// services[0].serviceUrl, or nil if the file was not YAML.
+func YamlLines(filePath string) map[string]int {
+	yamlLinesLock.Lock()
+	defer yamlLinesLock.Unlock()
+	return yamlLines[filePath]
+}
+
+// A key of a block mapping, plain or quoted, and the value after it.
+var yamlKeyRE = regexp.MustCompile(`^("(?:[^"\\]|\\.)*"|'(?:[^']|'')*'|[^\s#"'\[\]{},&*!|>%@` + "`" + `][^#]*?)\s*:(?:\s+(.*))?$`)
+
+// Returns the line of every value of a YAML document, by its path, the same paths as the json it converts to has.
+// The line of a mapping key is the line of the key, and the line of a sequence entry the line of its dash. Only the
+// block style has lines, the values inside a flow mapping or sequence have the line of the flow collection.
+func yamlValueLines(content []byte) map[string]int {
+	type collection struct {
+		indent int
+		path   string
+		isSeq  bool
+		index  int
+	}
+	lines := map[string]int{"": 1}
+	stack := []*collection{}
+	parent := ""      // the path of the last key whose value is on the next lines
+	blockScalar := -1 // the indent of the key of a literal or folded scalar that is being skipped
+	for i, line := range strings.Split(string(content), "\n") {
+		text := strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimLeft(text, " ")
+		indent := len(text) - len(trimmed)
+		if trimmed == "" || (blockScalar >= 0 && indent > blockScalar) {
+			continue
+		}
+		blockScalar = -1
+		if strings.HasPrefix(trimmed, "#") || trimmed == "---" || trimmed == "..." {
+			continue
+		}
+
+		for trimmed != "" {
+			isEntry := trimmed == "-" || strings.HasPrefix(trimmed, "- ")
+			for len(stack) > 0 {
+				if top := stack[len(stack)-1]; top.indent > indent || (top.indent == indent && top.isSeq && !isEntry) {
+					stack = stack[:len(stack)-1]
+				} else {
+					break
+				}
+			}
+			var top *collection
+			if len(stack) > 0 {
+				top = stack[len(stack)-1]
+			}
+
+			if isEntry {
+				if top == nil || top.indent != indent || !top.isSeq {
+					top = &collection{indent: indent, path: parent, isSeq: true, index: -1}
+					stack = append(stack, top)
+				}
+				top.index++
+				parent = fmt.Sprintf("%v[%v]", top.path, top.index)
+				lines[parent] = i + 1
+
+				// the value of the entry can start on the line of the dash
+				rest := strings.TrimLeft(strings.TrimPrefix(trimmed, "-"), " ")
+				indent += len(trimmed) - len(rest)
+				trimmed = rest
+			} else if match := yamlKeyRE.FindStringSubmatch(trimmed); match != nil {
+				if top == nil || top.indent != indent || top.isSeq {
+					top = &collection{indent: indent, path: parent}
+					stack = append(stack, top)
+				}
+				key := match[1]
+				if strings.HasPrefix(key, `"`) {
+					key = strings.Trim(key, `"`)
+				} else if strings.HasPrefix(key, "'") {
+					key = strings.Replace(strings.Trim(key, "'"), "''", "'", -1)
+				}
+				path := key
+				if top.path != "" {
+					path = top.path + "." + key
+				}
+				lines[path] = i + 1
+
+				value := strings.TrimSpace(match[2])
+				if value == "" || strings.HasPrefix(value, "#") {
+					parent = path
+				} else if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+					blockScalar = indent
+				}
+				trimmed = ""
+			} else {
+				// a scalar or flow collection, or the continuation of one
+				trimmed = ""
+			}
+		}
+	}
+	return lines
+}
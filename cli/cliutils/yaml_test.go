@@ -0,0 +1,157 @@
+// +build unit
+
+package cliutils
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_IsYamlFile(t *testing.T) {
+	yamlInput := false
+	Opts.YamlInput = &yamlInput
+	defer func() { Opts.YamlInput = nil }()
+
+	for _, tc := range []struct {
+		path      string
+		yamlInput bool
+		expected  bool
+	}{
+		{"pattern.yaml", false, true},
+		{"pattern.YML", false, true},
+		{"pattern.json", false, false},
+		{"pattern.json", true, false},
+		{"-", false, false},
+		{"-", true, true},
+		{"input", false, false},
+		{"input", true, true},
+	} {
+		yamlInput = tc.yamlInput
+		if IsYamlFile(tc.path) != tc.expected {
+			t.Errorf("%v with --yaml %v should be yaml: %v", tc.path, tc.yamlInput, tc.expected)
+		}
+	}
+}
+
+func Test_yamlValueLines(t *testing.T) {
+	content := `# the pattern
+label: netspeed
+"public": true
+description: |
+  label: not a key
+services:
+- serviceUrl: netspeed
+  serviceVersions:
+    - version: 1.0.0
+
+      priority: {retries: 2}
+    - version: 2.0.0
+  nodeHealth:
+    missing_heartbeat_interval: 60
+-
+  serviceUrl: cpu
+userInput: []
+`
+	expected := map[string]int{
+		"":                                       1,
+		"label":                                  2,
+		"public":                                 3,
+		"description":                            4,
+		"services":                               6,
+		"services[0]":                            7,
+		"services[0].serviceUrl":                 7,
+		"services[0].serviceVersions":            8,
+		"services[0].serviceVersions[0]":         9,
+		"services[0].serviceVersions[0].version": 9,
+		"services[0].serviceVersions[0].priority":           11,
+		"services[0].serviceVersions[1]":                    12,
+		"services[0].serviceVersions[1].version":            12,
+		"services[0].nodeHealth":                            13,
+		"services[0].nodeHealth.missing_heartbeat_interval": 14,
+		"services[1]":            15,
+		"services[1].serviceUrl": 16,
+		"userInput":              17,
+	}
+	if lines := yamlValueLines([]byte(content)); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %v, found %v", expected, lines)
+	}
+}
+
+func Test_ReadJsonFile_yaml(t *testing.T) {
+	verbose, dryRun := false, false
+	Opts.Verbose, Opts.IsDryRun = &verbose, &dryRun
+	defer func() { Opts.Verbose, Opts.IsDryRun = nil, nil }()
+
+	dir, err := ioutil.TempDir("", "cliutils-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Setenv("TEST_SVC_VERSION", "1.2.3")
+	defer os.Unsetenv("TEST_SVC_VERSION")
+
+	file := filepath.Join(dir, "pattern.yml")
+	content := `label: netspeed
+public: true
+services:
+  - serviceUrl: netspeed
+    serviceVersions:
+      - version: "$TEST_SVC_VERSION"
+        priority:
+          retries: 2
+`
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("unable to write %v: %v", file, err)
+	}
+
+	var pattern map[string]interface{}
+	if err := json.Unmarshal(ReadJsonFile(file), &pattern); err != nil {
+		t.Fatalf("the file should be converted to json: %v", err)
+	}
+	expected := map[string]interface{}{
+		"label":  "netspeed",
+		"public": true,
+		"services": []interface{}{map[string]interface{}{
+			"serviceUrl": "netspeed",
+			"serviceVersions": []interface{}{map[string]interface{}{
+				"version":  "1.2.3",
+				"priority": map[string]interface{}{"retries": float64(2)},
+			}},
+		}},
+	}
+	if !reflect.DeepEqual(pattern, expected) {
+		t.Errorf("expected %v, found %v", expected, pattern)
+	}
+	if lines := YamlLines(file); lines["services[0].serviceVersions[0].priority.retries"] != 8 {
+		t.Errorf("expected the lines of the yaml, found %v", lines)
+	}
+
+	// a file with another extension is yaml with --yaml
+	stdinFile := filepath.Join(dir, "pattern")
+	if err := ioutil.WriteFile(stdinFile, []byte("label: netspeed"), 0644); err != nil {
+		t.Fatalf("unable to write %v: %v", stdinFile, err)
+	}
+	if err := Try(func() { ReadJsonFile(stdinFile) }); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if YamlLines(stdinFile) != nil {
+		t.Errorf("the file should be json without --yaml")
+	}
+	yamlInput := true
+	Opts.YamlInput = &yamlInput
+	defer func() { Opts.YamlInput = nil }()
+	if err := json.Unmarshal(ReadJsonFile(stdinFile), &pattern); err != nil || pattern["label"] != "netspeed" {
+		t.Errorf("the file should be converted to json with --yaml, found %v %v", pattern, err)
+	}
+
+	if err := ioutil.WriteFile(file, []byte("label: [netspeed"), 0644); err != nil {
+		t.Fatalf("unable to write %v: %v", file, err)
+	}
+	if err := Try(func() { ReadJsonFile(file) }); ExitCode(err) != JSON_PARSING_ERROR {
+		t.Errorf("invalid yaml should be a parsing error, found %v", err)
+	}
+}
@@ -3,7 +3,6 @@ package deploycheck
 import (
 	"encoding/json"
 	"flag"
-	"fmt"
 	"github.com/open-horizon/anax/api"
 	"github.com/open-horizon/anax/businesspolicy"
 	"github.com/open-horizon/anax/cli/cliconfig"
@@ -16,7 +15,6 @@ import (
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
-	"os"
 )
 
 // check if the policies are compatible
@@ -132,12 +130,7 @@ func AllCompatible(org string, userPw string, nodeId string, nodeArch string, no
 		}
 
 		// display the output
-		output, err := cliutils.DisplayAsJson(compOutput)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn policy compatible' output: %v", err))
-		}
-
-		fmt.Println(output)
+		cliutils.Output(compOutput, "hzn policy compatible")
 	}
 }
 
@@ -183,8 +176,8 @@ func verifyCompCheckParameters(org string, userPw string, nodeId string, nodeTyp
 		}
 	} else {
 		if (useBPol && nodePolFile == "") || (!useBPol && nodeUIFile == "") {
-			// get node id from HZN_EXCHANGE_NODE_AUTH
-			if nodeIdTok := os.Getenv("HZN_EXCHANGE_NODE_AUTH"); nodeIdTok != "" {
+			// get node id from --node-auth or HZN_EXCHANGE_NODE_AUTH
+			if nodeIdTok := cliutils.GetNodeAuth(""); nodeIdTok != "" {
 				nodeIdToUse, _ = cliutils.SplitIdToken(nodeIdTok)
 				if nodeIdToUse != "" {
 					// true means will use exchange call
@@ -224,8 +217,11 @@ func verifyCompCheckParameters(org string, userPw string, nodeId string, nodeTyp
 
 	useSId, serviceDefs := useExchangeForServiceDef(svcDefFiles)
 
-	// if user credential is not given, then use the node auth env HZN_EXCHANGE_NODE_AUTH if it is defined.
-	credToUse := cliutils.WithDefaultEnvVar(&userPw, "HZN_EXCHANGE_NODE_AUTH")
+	// if user credential is not given, then use the node auth from --node-auth or HZN_EXCHANGE_NODE_AUTH if it is defined.
+	if userPw == "" {
+		userPw = cliutils.GetNodeAuth("")
+	}
+	credToUse := &userPw
 	orgToUse := org
 	if useNodeId || useBPolId || useSPolId || usePatternId || useSId {
 		if *credToUse == "" {
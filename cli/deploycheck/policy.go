@@ -3,14 +3,12 @@ package deploycheck
 import (
 	"encoding/json"
 	"flag"
-	"fmt"
 	"github.com/open-horizon/anax/cli/cliconfig"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/common"
 	"github.com/open-horizon/anax/compcheck"
 	"github.com/open-horizon/anax/externalpolicy"
 	"github.com/open-horizon/anax/i18n"
-	"os"
 )
 
 func readExternalPolicyFile(filePath string, inputFileStruct *externalpolicy.ExternalPolicy) {
@@ -106,12 +104,7 @@ func PolicyCompatible(org string, userPw string, nodeId string, nodeArch string,
 		}
 
 		// display the output
-		output, err := cliutils.DisplayAsJson(compOutput)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn policy compatible' output: %v", err))
-		}
-
-		fmt.Println(output)
+		cliutils.Output(compOutput, "hzn policy compatible")
 	}
 }
 
@@ -136,8 +129,8 @@ func verifyPolicyCompatibleParameters(org string, userPw string, nodeId string,
 		}
 	} else {
 		if nodePolFile == "" {
-			// get node id from HZN_EXCHANGE_NODE_AUTH
-			if nodeIdTok := os.Getenv("HZN_EXCHANGE_NODE_AUTH"); nodeIdTok != "" {
+			// get node id from --node-auth or HZN_EXCHANGE_NODE_AUTH
+			if nodeIdTok := cliutils.GetNodeAuth(""); nodeIdTok != "" {
 				nodeIdToUse, _ = cliutils.SplitIdToken(nodeIdTok)
 				if nodeIdToUse != "" {
 					// true means will use exchange call
@@ -173,8 +166,11 @@ func verifyPolicyCompatibleParameters(org string, userPw string, nodeId string,
 
 	useSId, serviceDefs := useExchangeForServiceDef(svcDefFiles)
 
-	// if user credential is not given, then use the node auth env HZN_EXCHANGE_NODE_AUTH if it is defined.
-	credToUse := cliutils.WithDefaultEnvVar(&userPw, "HZN_EXCHANGE_NODE_AUTH")
+	// if user credential is not given, then use the node auth from --node-auth or HZN_EXCHANGE_NODE_AUTH if it is defined.
+	if userPw == "" {
+		userPw = cliutils.GetNodeAuth("")
+	}
+	credToUse := &userPw
 	orgToUse := org
 	if useNodeId || useBPolId || useSPolId || useSId {
 		if *credToUse == "" {
@@ -13,7 +13,6 @@ import (
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/semanticversion"
-	"os"
 )
 
 func readServiceFile(filePath string, inputFileStruct *common.ServiceFile) {
@@ -112,12 +111,7 @@ func UserInputCompatible(org string, userPw string, nodeId string, nodeArch stri
 		}
 
 		// display the output
-		output, err := cliutils.DisplayAsJson(compOutput)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn policy compatible' output: %v", err))
-		}
-
-		fmt.Println(output)
+		cliutils.Output(compOutput, "hzn policy compatible")
 	}
 }
 
@@ -165,8 +159,8 @@ func verifyUserInputCompatibleParameters(org string, userPw string, nodeId strin
 		}
 	} else {
 		if nodeUIFile == "" {
-			// get node id from HZN_EXCHANGE_NODE_AUTH
-			if nodeIdTok := os.Getenv("HZN_EXCHANGE_NODE_AUTH"); nodeIdTok != "" {
+			// get node id from --node-auth or HZN_EXCHANGE_NODE_AUTH
+			if nodeIdTok := cliutils.GetNodeAuth(""); nodeIdTok != "" {
 				nodeIdToUse, _ = cliutils.SplitIdToken(nodeIdTok)
 				if nodeIdToUse != "" {
 					// true means will use exchange call
@@ -213,8 +207,11 @@ func verifyUserInputCompatibleParameters(org string, userPw string, nodeId strin
 
 	useSId, serviceDefs := useExchangeForServiceDef(svcDefFiles)
 
-	// if user credential is not given, then use the node auth env HZN_EXCHANGE_NODE_AUTH if it is defined.
-	credToUse := cliutils.WithDefaultEnvVar(&userPw, "HZN_EXCHANGE_NODE_AUTH")
+	// if user credential is not given, then use the node auth from --node-auth or HZN_EXCHANGE_NODE_AUTH if it is defined.
+	if userPw == "" {
+		userPw = cliutils.GetNodeAuth("")
+	}
+	credToUse := &userPw
 	orgToUse := org
 	if useNodeId || useBPolId || usePatternId || useSId {
 		if *credToUse == "" {
@@ -565,7 +565,7 @@ func fetchExchangeProjectDependency(homeDirectory string, specRef string, url st
 	msgPrinter := i18n.GetMessagePrinter()
 
 	// Pull the metadata from the exchange, including any of this dependency's dependencies.
-	sDef, err := getExchangeDefinition(homeDirectory, specRef, url, org, "", arch, userCreds, userInputFile)
+	sDef, err := getExchangeDefinition(homeDirectory, specRef, url, org, version, arch, userCreds, userInputFile)
 	if err != nil {
 		return err
 	}
@@ -663,7 +663,7 @@ func getExchangeDefinition(homeDirectory string, specRef string, surl string, or
 	} else if ex, err := DependenciesExists(homeDirectory, true); !ex || err != nil {
 		return nil, errors.New(i18n.GetMessagePrinter().Sprintf("no dependency directory found in project"))
 	} else {
-		return getServiceDefinition(homeDirectory, surl, org, version, arch, userCreds)
+		return getServiceDefinition(homeDirectory, surl, org, version, "", arch, userCreds)
 	}
 }
 
@@ -730,8 +730,13 @@ func UpdateDependentDependencies(homeDirectory string, depProject string) error
 func getServiceDefinitionDependencies(homeDirectory string, serviceDef *common.ServiceFile, userCreds string) error {
 	for _, rs := range serviceDef.RequiredServices {
 		// Get the service definition for each required service. Dependencies refer to each other by version range, so the
-		// service we're looking for might not be at the exact version specified in the required service element.
-		if sDef, err := getServiceDefinition(homeDirectory, rs.URL, rs.Org, "", rs.Arch, userCreds); err != nil {
+		// service we're looking for might not be at the exact version specified in the required service element. Like
+		// the agent, use the highest version within the range.
+		versionRange := rs.VersionRange
+		if versionRange == "" {
+			versionRange = rs.Version
+		}
+		if sDef, err := getServiceDefinition(homeDirectory, rs.URL, rs.Org, "", versionRange, rs.Arch, userCreds); err != nil {
 			return err
 		} else if err := UpdateDependencyFile(homeDirectory, sDef); err != nil {
 			return err
@@ -742,7 +747,9 @@ func getServiceDefinitionDependencies(homeDirectory string, serviceDef *common.S
 	return nil
 }
 
-func getServiceDefinition(homeDirectory, surl string, org string, version string, arch string, userCreds string) (*common.ServiceFile, error) {
+// Get the service definition of a dependency from the exchange, with its images and its own dependencies. The version is
+// an exact version, or the highest version within the version range is used. Without either, the highest version is used.
+func getServiceDefinition(homeDirectory, surl string, org string, version string, versionRange string, arch string, userCreds string) (*common.ServiceFile, error) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
@@ -760,19 +767,26 @@ func getServiceDefinition(homeDirectory, surl string, org string, version string
 	resp := new(exchange.GetServicesResponse)
 
 	// Call the exchange to get the service definition.
-	if userCreds == "" {
-		userCreds = os.Getenv(DEVTOOL_HZN_USER)
-	}
-	cliutils.SetWhetherUsingApiKey(userCreds)
+	userCreds = cliutils.GetUserAuth(userCreds)
+	cliutils.SetWhetherUsingWiotp(userCreds)
 	cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), resSuffix, cliutils.OrgAndCreds(os.Getenv(DEVTOOL_HZN_ORG), userCreds), []int{200}, resp)
 
 	// Parse the response and extract the highest version service definition or return an error.
 	var serviceDef exchange.ServiceDefinition
 	var serviceId string
-	if len(resp.Services) > 1 {
-		highest, sDef, sId, err := exchange.GetHighestVersion(resp.Services, nil)
+	var vRange *semanticversion.Version_Expression
+	if versionRange != "" {
+		var err error
+		if vRange, err = semanticversion.Version_Expression_Factory(versionRange); err != nil {
+			return nil, errors.New(msgPrinter.Sprintf("the version range %v of %v %v is not valid: %v", versionRange, surl, org, err))
+		}
+	}
+	if len(resp.Services) > 1 || (len(resp.Services) == 1 && vRange != nil) {
+		highest, sDef, sId, err := exchange.GetHighestVersion(resp.Services, vRange)
 		if err != nil {
 			return nil, err
+		} else if highest == "" && vRange != nil {
+			return nil, errors.New(msgPrinter.Sprintf("no version of %v %v within the version range %v found in the Exchange", surl, org, versionRange))
 		} else if highest == "" {
 			return nil, errors.New(msgPrinter.Sprintf("unable to find highest version of %v %v in the Exchange: %v", surl, org, resp.Services))
 		} else {
@@ -811,7 +825,7 @@ func getServiceDefinition(homeDirectory, surl string, org string, version string
 		// Get docker auth for the service
 		auth_url := fmt.Sprintf("orgs/%v/services/%v/dockauths", org, exchange.GetId(serviceId))
 		docker_auths := make([]exchange.ImageDockerAuth, 0)
-		cliutils.SetWhetherUsingApiKey(userCreds)
+		cliutils.SetWhetherUsingWiotp(userCreds)
 		cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), auth_url, cliutils.OrgAndCreds(os.Getenv(DEVTOOL_HZN_ORG), userCreds), []int{200, 404}, &docker_auths)
 
 		img_auths := make([]events.ImageDockerAuth, 0)
@@ -0,0 +1,70 @@
+// +build unit
+
+package dev
+
+import (
+	"encoding/json"
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/exchange"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_getServiceDefinition(t *testing.T) {
+	h := clitest.New(t)
+
+	// the exchange filters the services by the version in the query, like the real one
+	versions := []string{"1.0.0", "1.2.0", "2.0.0"}
+	h.Exchange.HandleFunc(http.MethodGet, "orgs/IBM/services", func(w http.ResponseWriter, r *http.Request) {
+		resp := exchange.GetServicesResponse{Services: map[string]exchange.ServiceDefinition{}}
+		for _, v := range versions {
+			if version := r.URL.Query().Get("version"); version == "" || version == v {
+				resp.Services["IBM/cpu_"+v+"_amd64"] = exchange.ServiceDefinition{URL: "cpu", Version: v, Arch: "amd64"}
+			}
+		}
+		if len(resp.Services) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	get := func(version string, versionRange string) (sDef string, exitCode int, err error) {
+		exitCode = h.Run(func() {
+			if def, e := getServiceDefinition("", "cpu", "IBM", version, versionRange, "amd64", ""); e != nil {
+				err = e
+			} else {
+				sDef = def.Version
+			}
+		}).ExitCode
+		return
+	}
+
+	for _, tc := range []struct {
+		version, versionRange, expected string
+	}{
+		{"1.2.0", "", "1.2.0"},
+		{"", "[1.0.0,2.0.0)", "1.2.0"},
+		{"", "1.0.0", "2.0.0"},
+		{"", "", "2.0.0"},
+	} {
+		if version, exitCode, err := get(tc.version, tc.versionRange); err != nil || exitCode != 0 || version != tc.expected {
+			t.Errorf("expected version %v for %q and range %q, found %v %v %v", tc.expected, tc.version, tc.versionRange, version, err, exitCode)
+		}
+	}
+	if reqs := h.Exchange.RequestsTo(http.MethodGet, "orgs/IBM/services"); len(reqs) == 0 || !strings.Contains(reqs[0].Query, "version=1.2.0") {
+		t.Errorf("the exact version should be in the query, found %v", reqs)
+	}
+
+	// there is no version of the service within the range, or at the version
+	if _, _, err := get("", "[3.0.0,4.0.0)"); err == nil || !strings.Contains(err.Error(), "within the version range") {
+		t.Errorf("expected no version within the range, found %v", err)
+	}
+	if _, _, err := get("", "not a range"); err == nil {
+		t.Errorf("expected an invalid version range")
+	}
+	if _, exitCode, _ := get("3.0.0", ""); exitCode != cliutils.HTTP_ERROR {
+		t.Errorf("expected the version not to be found, exit code %v", exitCode)
+	}
+}
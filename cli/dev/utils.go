@@ -36,6 +36,7 @@ const DEVTOOL_HZN_USER = "HZN_EXCHANGE_USER_AUTH"
 const DEVTOOL_HZN_EXCHANGE_URL = "HZN_EXCHANGE_URL"
 const DEVTOOL_HZN_DEVICE_ID = "HZN_DEVICE_ID"
 const DEVTOOL_HZN_PATTERN = "HZN_PATTERN"
+const DEVTOOL_HZN_NODE_POLICY_FILE = "HZN_DEV_NODE_POLICY_FILE"
 
 const DEVTOOL_HZN_FSS_IMAGE_TAG = "HZN_DEV_FSS_IMAGE_TAG"
 const DEVTOOL_HZN_FSS_IMAGE_REPO = "HZN_DEV_FSS_IMAGE_REPO"
@@ -155,13 +156,13 @@ func VerifyEnvironment(homeDirectory string, mustExist bool, needExchange bool,
 			return "", errors.New(msgPrinter.Sprintf("Must set environment variable %v or specify the user as 'org/user' on the --user-pw flag", DEVTOOL_HZN_ORG))
 		}
 	} else if needExchange && userCreds == "" {
-		id, _ := cliutils.SplitIdToken(os.Getenv(DEVTOOL_HZN_USER)) // only look for the / in the id, because the token is more likely to have special chars
+		id, _ := cliutils.SplitIdToken(cliutils.GetUserAuth("")) // only look for the / in the id, because the token is more likely to have special chars
 		if !strings.Contains(id, "/") && os.Getenv(DEVTOOL_HZN_ORG) == "" {
 			return "", errors.New(msgPrinter.Sprintf("Must set environment variable %v or specify the user as 'org/user' on the --user-pw flag", DEVTOOL_HZN_ORG))
 		}
 	}
 
-	if needExchange && os.Getenv(DEVTOOL_HZN_USER) == "" && userCreds == "" {
+	if needExchange && cliutils.GetUserAuth(userCreds) == "" {
 		return "", errors.New(msgPrinter.Sprintf("Must set environment variable %v or specify user exchange credentials with --user-pw", DEVTOOL_HZN_USER))
 	} else if os.Getenv(DEVTOOL_HZN_EXCHANGE_URL) == "" {
 		exchangeUrl := cliutils.GetExchangeUrl()
@@ -373,11 +374,13 @@ func createEnvVarMap(agreementId string,
 	byValueAttrs := makeByValueAttributes(attrs)
 
 	// Get the node policy info
-	nodePolicy := externalpolicy.ExternalPolicy{}
-	cliutils.HorizonGet("node/policy", []int{200}, &nodePolicy, true)
+	nodePolicy, perr := getMockNodePolicy()
+	if perr != nil {
+		return nil, perr
+	}
 	// Fourth, convert all attributes to system env vars.
 	var cerr error
-	envvars, cerr = attrConverter(byValueAttrs, envvars, config.ENVVAR_PREFIX, cw.Config.Edge.DefaultServiceRegistrationRAM, &nodePolicy, false)
+	envvars, cerr = attrConverter(byValueAttrs, envvars, config.ENVVAR_PREFIX, cw.Config.Edge.DefaultServiceRegistrationRAM, nodePolicy, false)
 	if cerr != nil {
 		return nil, errors.New(msgPrinter.Sprintf("global attribute conversion error: %v", cerr))
 	}
@@ -395,6 +398,31 @@ func createEnvVarMap(agreementId string,
 	return envvars, nil
 }
 
+// Get the node policy of the mocked Horizon Agent environment, so that a service can be tested without registering a
+// node. It is read from the file in HZN_DEV_NODE_POLICY_FILE, otherwise it is the node policy of the local Horizon
+// Agent, if there is one.
+func getMockNodePolicy() (*externalpolicy.ExternalPolicy, error) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	nodePolicy := new(externalpolicy.ExternalPolicy)
+	policyFile := os.Getenv(DEVTOOL_HZN_NODE_POLICY_FILE)
+	if policyFile == "" {
+		cliutils.HorizonGet("node/policy", []int{200}, nodePolicy, true)
+		return nodePolicy, nil
+	}
+
+	if policyBytes, err := ioutil.ReadFile(policyFile); err != nil {
+		return nil, errors.New(msgPrinter.Sprintf("unable to read the node policy file %v from %v: %v", policyFile, DEVTOOL_HZN_NODE_POLICY_FILE, err))
+	} else if err := json.Unmarshal(policyBytes, nodePolicy); err != nil {
+		return nil, errors.New(msgPrinter.Sprintf("unable to unmarshal the node policy file %v: %v", policyFile, err))
+	} else if err := nodePolicy.ValidateAndNormalize(); err != nil {
+		return nil, errors.New(msgPrinter.Sprintf("the node policy file %v is not valid: %v", policyFile, err))
+	}
+	cliutils.Verbose(msgPrinter.Sprintf("Using the node policy in %v: %v", policyFile, nodePolicy))
+	return nodePolicy, nil
+}
+
 func createContainerWorker() (*container.ContainerWorker, error) {
 
 	workloadStorageDir := "/tmp/hzn"
@@ -633,7 +661,7 @@ func StartContainers(deployment *containermessage.DeploymentDescription,
 	// Now that we have the configured variables, turn everything into environment variables for the container.
 	environmentAdditions, enverr := createEnvVarMap(agId, wlpw, globals, specRef, configVars, defUserInputs, org, cw, persistence.AttributesToEnvvarMap)
 	if enverr != nil {
-		return nil, errors.New(msgPrinter.Sprintf("unable to create environment variables"))
+		return nil, errors.New(msgPrinter.Sprintf("unable to create environment variables, error: %v", enverr))
 	}
 
 	cliutils.Verbose(msgPrinter.Sprintf("Passing environment variables: %v", environmentAdditions))
@@ -67,7 +67,7 @@ func setupUT(t *testing.T, debug bool) (string, *container.ContainerWorker) {
 	// Setup CLI environment.
 	cliutils.Opts.Verbose = &debug
 	cliutils.Opts.IsDryRun = &debug
-	cliutils.Opts.UsingApiKey = false
+	cliutils.Opts.UsingWiotp = false
 
 	// Create the containerWorker.
 	cw, cerr := createContainerWorker()
@@ -3,6 +3,7 @@
 package dev
 
 import (
+	"io/ioutil"
 	"os"
 	"path"
 	"strings"
@@ -238,3 +239,35 @@ func Test_GetImageInfoFromImageList(t *testing.T) {
 		t.Errorf("image_base should be an empty string but got %v", image_base)
 	}
 }
+
+func Test_getMockNodePolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hzndev-")
+	if err != nil {
+		t.Fatalf("error creating temp dir %v", err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv(DEVTOOL_HZN_NODE_POLICY_FILE)
+
+	policyFile := path.Join(dir, "node.policy.json")
+	if err := ioutil.WriteFile(policyFile, []byte(`{"properties": [{"name": "purpose", "value": "location"}], "constraints": ["iame2edev == true"]}`), 0644); err != nil {
+		t.Fatalf("error writing %v: %v", policyFile, err)
+	}
+	os.Setenv(DEVTOOL_HZN_NODE_POLICY_FILE, policyFile)
+	if pol, err := getMockNodePolicy(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if len(pol.Properties) != 1 || pol.Properties[0].Name != "purpose" || len(pol.Constraints) != 1 {
+		t.Errorf("unexpected node policy %v", pol)
+	}
+
+	if err := ioutil.WriteFile(policyFile, []byte(`{"properties": [{"name": "purpose"}]}`), 0644); err != nil {
+		t.Fatalf("error writing %v: %v", policyFile, err)
+	}
+	if _, err := getMockNodePolicy(); err == nil || !strings.Contains(err.Error(), "is not valid") {
+		t.Errorf("expected an invalid policy error, got %v", err)
+	}
+
+	os.Setenv(DEVTOOL_HZN_NODE_POLICY_FILE, path.Join(dir, "missing.json"))
+	if _, err := getMockNodePolicy(); err == nil || !strings.Contains(err.Error(), "unable to read") {
+		t.Errorf("expected a read error, got %v", err)
+	}
+}
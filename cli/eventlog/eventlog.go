@@ -1,11 +1,14 @@
 package eventlog
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/persistence"
+	"io"
+	"os"
 	"regexp"
 	"strings"
 	"time"
@@ -63,90 +66,239 @@ func getSelectionString(selections []string) (string, error) {
 	return strings.Join(sels, "&"), nil
 }
 
-func List(all bool, detail bool, selections []string, tailing bool) {
+// Convert the event log returned by the anax api into the output format.
+func newEventLog(v persistence.EventLogRaw) EventLog {
+	return EventLog{
+		Id:         v.Id,
+		Timestamp:  cliutils.ConvertTime(v.Timestamp),
+		Severity:   v.Severity,
+		Message:    v.Message,
+		EventCode:  v.EventCode,
+		SourceType: v.SourceType,
+		Source:     v.Source,
+	}
+}
+
+// The severities of the event logs, from the least to the most severe.
+var Severities = []string{persistence.SEVERITY_INFO, persistence.SEVERITY_WARN, persistence.SEVERITY_ERROR, persistence.SEVERITY_FATAL}
+
+// Returns true if the severity is the minimum severity or a more severe one. An unknown severity is always shown.
+func severityAtLeast(severity string, minSeverity string) bool {
+	level, minLevel := -1, -1
+	for i, s := range Severities {
+		if s == severity {
+			level = i
+		}
+		if s == minSeverity {
+			minLevel = i
+		}
+	}
+	return level == -1 || level >= minLevel
+}
+
+// List displays the event logs of the current registration, or of all of them, that match the selections, were
+// logged since the time given and are at least as severe as the severity given. With tailing, it keeps polling the
+// event log for the new records.
+func List(all bool, detail bool, selections []string, tailing bool, since string, severity string) {
+	listEventLogs(all, detail, selections, since, severity, tailing, -1)
+}
+
+// Tail displays the last records of the event log of the current registration, then keeps polling it for the new
+// records, like tail -f.
+func Tail(detail bool, selections []string, severity string, lines int) {
+	if lines < 0 {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, i18n.GetMessagePrinter().Sprintf("the number of lines must not be negative, not %v", lines))
+	}
+	listEventLogs(false, detail, selections, "", severity, true, lines)
+}
+
+// Display the event logs, only the last lines records of the first poll when lines is not -1.
+func listEventLogs(all bool, detail bool, selections []string, since string, severity string, tailing bool, lines int) {
+	sinceTime, err := parseSince(since)
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "%v", err)
+	}
 
 	// format the eventlog api string
-	url_s := "eventlog"
+	base_url := "eventlog"
 	if all {
-		url_s = fmt.Sprintf("%v/all", url_s)
+		base_url = fmt.Sprintf("%v/all", base_url)
 	}
 
-	if len(selections) > 0 {
-		if s, err := getSelectionString(selections); err != nil {
-			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "%v", err)
-		} else {
-			url_s = fmt.Sprintf("%v?%v", url_s, s)
-		}
+	sels := append([]string{}, selections...)
+	if sinceTime > 0 {
+		sels = append(sels, fmt.Sprintf("timestamp>%v", sinceTime-1))
 	}
-
+	lastId := ""
 	for {
+		// select for most recent records if any
+		newselect := sels
+		if lastId != "" {
+			newselect = append(append([]string{}, sels...), fmt.Sprintf("record_id>%v", lastId))
+		}
+		url_s := base_url
+		if len(newselect) > 0 {
+			if s, err := getSelectionString(newselect); err != nil {
+				cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "%v", err)
+			} else {
+				url_s = fmt.Sprintf("%v?%v", url_s, s)
+			}
+		}
+
 		// get the eventlog from anax
 		apiOutput := make([]persistence.EventLogRaw, 0)
 		cliutils.HorizonGet(url_s, []int{200}, &apiOutput, false)
+		if len(apiOutput) > 0 {
+			lastId = apiOutput[len(apiOutput)-1].Id
+		}
 
-		//output
-
-		if detail {
-			long_output := make([]EventLog, len(apiOutput))
-			for i, v := range apiOutput {
-				long_output[i].Id = v.Id
-				long_output[i].Timestamp = cliutils.ConvertTime(v.Timestamp)
-				long_output[i].Severity = v.Severity
-				long_output[i].Message = v.Message
-				long_output[i].EventCode = v.EventCode
-				long_output[i].SourceType = v.SourceType
-				long_output[i].Source = v.Source
+		records := apiOutput
+		if severity != "" {
+			records = make([]persistence.EventLogRaw, 0, len(apiOutput))
+			for _, v := range apiOutput {
+				if severityAtLeast(v.Severity, severity) {
+					records = append(records, v)
+				}
 			}
+		}
+		if lines >= 0 && len(records) > lines {
+			records = records[len(records)-lines:]
+		}
+		lines = -1
+		printEventLogs(records, detail)
 
-			jsonBytes, err := cliutils.DisplayAsJson(long_output)
-			if err != nil {
-				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn eventlog list' output: %v", err))
-			}
-			if len(jsonBytes) > 3 {
-				fmt.Printf("%s", jsonBytes[2:len(jsonBytes)-2])
-			}
-		} else {
-			short_output := make([]string, len(apiOutput))
-			for i, v := range apiOutput {
-				t := time.Unix(int64(v.Timestamp), 0)
-				short_output[i] = fmt.Sprintf("%v:   %v", t.Format("2006-01-02 15:04:05"), v.Message)
-			}
-			jsonBytes, err := cliutils.DisplayAsJson(short_output)
-			if err != nil {
-				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn eventlog list' output: %v", err))
-			}
+		if !tailing {
+			break
+		}
+		select {
+		case <-cliutils.Context().Done():
+			return
+		case <-time.After(1 * time.Second):
+		}
+	}
+}
 
-			if len(jsonBytes) > 3 {
-				fmt.Printf("%s", jsonBytes[2:len(jsonBytes)-2])
-			}
+// Print the event logs, with all their details or as their time and message. The json output is the records without
+// the brackets of the list, as it has always been, so that the records of the polls of a tail follow each other. The
+// other formats of --output-format write the records of each poll that has new records.
+func printEventLogs(apiOutput []persistence.EventLogRaw, detail bool) {
+	var output interface{}
+	if detail {
+		long_output := make([]EventLog, len(apiOutput))
+		for i, v := range apiOutput {
+			long_output[i] = newEventLog(v)
+		}
+		output = long_output
+	} else {
+		short_output := make([]string, len(apiOutput))
+		for i, v := range apiOutput {
+			t := time.Unix(int64(v.Timestamp), 0)
+			short_output[i] = fmt.Sprintf("%v:   %v", t.Format("2006-01-02 15:04:05"), v.Message)
 		}
+		output = short_output
+	}
 
-		if tailing {
-			// selection contraints for most recent records
-			var newselect []string
+	if format, _ := cliutils.GetOutputFormat(); format != cliutils.OUTPUT_FORMAT_JSON {
+		if len(apiOutput) > 0 {
+			cliutils.Output(output, "hzn eventlog list")
+		}
+		return
+	}
+	jsonBytes, err := cliutils.DisplayAsJson(output)
+	if err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn eventlog list' output: %v", err))
+	}
+	if len(jsonBytes) > 3 {
+		fmt.Printf("%s", jsonBytes[2:len(jsonBytes)-2])
+	}
+}
 
-			// update with inputted selection contraints
-			if len(selections) > 0 {
-				newselect = make([]string, len(selections))
-				copy(newselect, selections)
-			} else {
-				newselect = []string{}
-			}
+// Parse the start of the export window, either an age such as 7d or 12h, or a time such as 2020-10-20 or 2020-10-20T15:04:05Z.
+func parseSince(since string) (uint64, error) {
+	if since == "" {
+		return 0, nil
+	} else if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return uint64(t.Unix()), nil
+	} else if t, err := time.ParseInLocation("2006-01-02", since, time.Local); err == nil {
+		return uint64(t.Unix()), nil
+	} else if age, err := cliutils.ParseAge(since); err == nil {
+		return uint64(time.Now().Add(-age).Unix()), nil
+	}
+	return 0, fmt.Errorf(i18n.GetMessagePrinter().Sprintf("invalid --since value %v, it must be an age such as 7d or 12h, or a time such as 2020-10-20 or 2020-10-20T15:04:05Z", since))
+}
 
-			// select for most recent records if any
-			if len(apiOutput) > 0 {
-				newselect = append(newselect, fmt.Sprintf("record_id>%v", apiOutput[len(apiOutput)-1].Id))
-				if s, err := getSelectionString(newselect); err != nil {
-					cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "%v", err)
-				} else {
-					url_s = fmt.Sprintf("eventlog?%v", s)
-				}
-			}
-			time.Sleep(1 * time.Second)
+// Export writes the event logs, with all their details, to a file in json or csv format.
+func Export(all bool, selections []string, since string, format string, file string) {
+	msgPrinter := i18n.GetMessagePrinter()
 
+	if format != "json" && format != "csv" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("invalid --format value %v, it must be json or csv", format))
+	}
+
+	sinceTime, err := parseSince(since)
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "%v", err)
+	}
+
+	// format the eventlog api string
+	url_s := "eventlog"
+	if all {
+		url_s = fmt.Sprintf("%v/all", url_s)
+	}
+
+	sels := append([]string{}, selections...)
+	if sinceTime > 0 {
+		sels = append(sels, fmt.Sprintf("timestamp>%v", sinceTime-1))
+	}
+	if len(sels) > 0 {
+		if s, err := getSelectionString(sels); err != nil {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "%v", err)
 		} else {
-			break
+			url_s = fmt.Sprintf("%v?%v", url_s, s)
+		}
+	}
+
+	apiOutput := make([]persistence.EventLogRaw, 0)
+	cliutils.HorizonGet(url_s, []int{200}, &apiOutput, false)
+
+	var out io.Writer = os.Stdout
+	if file != "-" {
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			cliutils.Fatal(cliutils.FILE_IO_ERROR, msgPrinter.Sprintf("unable to create %v: %v", file, err))
 		}
+		defer f.Close()
+		out = f
+	}
+
+	if format == "json" {
+		output := make([]EventLog, len(apiOutput))
+		for i, v := range apiOutput {
+			output[i] = newEventLog(v)
+		}
+		// the format of the file is the one of --format, not of --output-format
+		if err := cliutils.WriteOutput(out, output, cliutils.OUTPUT_FORMAT_JSON, ""); err != nil {
+			cliutils.Fatal(cliutils.FILE_IO_ERROR, msgPrinter.Sprintf("unable to write %v: %v", file, err))
+		}
+	} else {
+		w := csv.NewWriter(out)
+		w.Write([]string{"record_id", "timestamp", "severity", "event_code", "source_type", "message", "event_source"})
+		for _, v := range apiOutput {
+			source := ""
+			if v.Source != nil {
+				source = string(*v.Source)
+			}
+			w.Write([]string{v.Id, cliutils.ConvertTime(v.Timestamp), v.Severity, v.EventCode, v.SourceType, v.Message, source})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			cliutils.Fatal(cliutils.FILE_IO_ERROR, msgPrinter.Sprintf("unable to write %v: %v", file, err))
+		}
+	}
+
+	if file != "-" {
+		msgPrinter.Printf("Exported %d event logs to %v.", len(apiOutput), file)
+		msgPrinter.Println()
 	}
 }
 
@@ -171,19 +323,11 @@ func ListSurfaced(long bool) {
 			long_output[i].SourceType = fullV.SourceType
 			long_output[i].Source = fullV.Source
 		}
-		jsonBytes, err := cliutils.DisplayAsJson(long_output)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn eventlog surface' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(long_output, "hzn eventlog surface")
 	} else {
 		if len(apiOutput) == 0 {
 			apiOutput = []persistence.SurfaceError{}
 		}
-		jsonBytes, err := cliutils.DisplayAsJson(apiOutput)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn eventlog surface' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(apiOutput, "hzn eventlog surface")
 	}
 }
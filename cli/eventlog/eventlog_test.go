@@ -0,0 +1,46 @@
+// +build unit
+
+package eventlog
+
+import (
+	"context"
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Tail(t *testing.T) {
+	h := clitest.New(t)
+	now := time.Now().Unix()
+	record := func(id string, severity string, message string) map[string]interface{} {
+		return map[string]interface{}{"record_id": id, "timestamp": now, "severity": severity, "message": message, "event_code": "code", "source_type": "node"}
+	}
+	records := []interface{}{record("1", "info", "registered"), record("2", "error", "image pull failed"), record("3", "fatal", "agent stopped")}
+	h.Agent.Handle(http.MethodGet, "eventlog", http.StatusOK, records)
+
+	// only the errors of the first poll are listed, since an hour ago
+	res := h.Run(func() { List(false, false, nil, false, "1h", "error") })
+	if res.ExitCode != 0 || strings.Contains(res.Stdout, "registered") || !strings.Contains(res.Stdout, "image pull failed") || !strings.Contains(res.Stdout, "agent stopped") {
+		t.Errorf("expected only the error records, found %v, stderr: %v", res.Stdout, res.Stderr)
+	} else if reqs := h.Agent.RequestsTo(http.MethodGet, "eventlog"); len(reqs) != 1 || !strings.Contains(reqs[0].Query, "timestamp=>") {
+		t.Errorf("expected a request with the timestamp selection, found %v", reqs)
+	}
+
+	// the tail shows the last record, then the new ones until it is interrupted
+	h.Agent.HandleSequence(http.MethodGet, "eventlog", clitest.Response{Code: http.StatusOK, Body: records},
+		clitest.Response{Code: http.StatusOK, Body: []interface{}{record("4", "warning", "retrying")}}, clitest.Response{Code: http.StatusOK, Body: []interface{}{}})
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+	prevCtx := cliutils.SetContext(ctx)
+	defer cliutils.SetContext(prevCtx)
+	res = h.Run(func() { Tail(false, nil, "warning", 1) })
+	if res.ExitCode != 0 || strings.Contains(res.Stdout, "image pull failed") || !strings.Contains(res.Stdout, "agent stopped") || !strings.Contains(res.Stdout, "retrying") {
+		t.Errorf("expected the last record and the new one, found %v, stderr: %v", res.Stdout, res.Stderr)
+	}
+	if reqs := h.Agent.RequestsTo(http.MethodGet, "eventlog"); len(reqs) < 3 || !strings.Contains(reqs[2].Query, "record_id=>3") {
+		t.Errorf("expected the new records to be polled after the last one, found %v", reqs)
+	}
+}
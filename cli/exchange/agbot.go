@@ -1,8 +1,6 @@
 package exchange
 
 import (
-	"encoding/json"
-	"fmt"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/i18n"
@@ -17,7 +15,7 @@ type ExchangeAgbots struct {
 }
 
 func AgbotList(org string, userPw string, agbot string, namesOnly bool) {
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	var agbotOrg string
 	agbotOrg, agbot = cliutils.TrimOrg(org, agbot)
 	if agbot == "*" {
@@ -26,25 +24,79 @@ func AgbotList(org string, userPw string, agbot string, namesOnly bool) {
 	if namesOnly && agbot == "" {
 		// Only display the names
 		var resp ExchangeAgbots
-		cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+agbotOrg+"/agbots"+cliutils.AddSlash(agbot), cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &resp)
+		cliutils.ExchangeGetAll("Exchange", cliutils.GetExchangeUrl(), "orgs/"+agbotOrg+"/agbots"+cliutils.AddSlash(agbot), cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &resp)
 		agbots := []string{}
 		for a := range resp.Agbots {
 			agbots = append(agbots, a)
 		}
-		jsonBytes, err := json.MarshalIndent(agbots, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'exchange agbot list' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(agbots, "exchange agbot list")
 	} else {
 		// Display the full resources
 		var agbots ExchangeAgbots
-		httpCode := cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+agbotOrg+"/agbots"+cliutils.AddSlash(agbot), cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &agbots)
+		httpCode := cliutils.ExchangeGetAll("Exchange", cliutils.GetExchangeUrl(), "orgs/"+agbotOrg+"/agbots"+cliutils.AddSlash(agbot), cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &agbots)
 		if httpCode == 404 && agbot != "" {
 			cliutils.Fatal(cliutils.NOT_FOUND, i18n.GetMessagePrinter().Sprintf("agbot '%s' not found in org %s", agbot, agbotOrg))
 		}
-		output := cliutils.MarshalIndent(agbots.Agbots, "exchange agbots list")
-		fmt.Println(output)
+		cliutils.Output(agbots.Agbots, "exchange agbots list")
+	}
+}
+
+type AgbotExchangePut struct {
+	Token       string `json:"token"`
+	Name        string `json:"name"`
+	MsgEndPoint string `json:"msgEndPoint"`
+	PublicKey   string `json:"publicKey"`
+}
+
+// Create the agbot resource, or change its name and token if it exists, so that an agbot can be deployed with its
+// credentials. The name defaults to the id. The token is read from stdin, or prompted for, when it is empty or -. The
+// message endpoint and the public key of an existing agbot are kept.
+func AgbotCreate(org, userPw, agbot, token, name string) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	cliutils.SetWhetherUsingWiotp(userPw)
+	var agbotOrg string
+	agbotOrg, agbot = cliutils.TrimOrg(org, agbot)
+	if agbot == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the agbot id must be specified."))
+	}
+	token = cliutils.ReadSecret(msgPrinter.Sprintf("agbot token"), token)
+	if name == "" {
+		name = agbot
+	}
+
+	putAgbotReq := AgbotExchangePut{Token: token, Name: name}
+	var existing struct {
+		Agbots map[string]AgbotExchangePut `json:"agbots"`
+	}
+	if httpCode := cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+agbotOrg+"/agbots/"+agbot, cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &existing); httpCode == 200 {
+		if a, ok := existing.Agbots[agbotOrg+"/"+agbot]; ok {
+			putAgbotReq.MsgEndPoint = a.MsgEndPoint
+			putAgbotReq.PublicKey = a.PublicKey
+		}
+	}
+
+	cliutils.ExchangePutPost("Exchange", http.MethodPut, cliutils.GetExchangeUrl(), "orgs/"+agbotOrg+"/agbots/"+agbot, cliutils.OrgAndCreds(org, userPw), []int{201}, putAgbotReq, nil)
+	msgPrinter.Printf("Agbot %v/%v created in the Horizon Exchange.", agbotOrg, agbot)
+	msgPrinter.Println()
+}
+
+// Remove the agbot resource, with the patterns and deployment policies that it serves.
+func AgbotRemove(org, userPw, agbot string, force bool) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	cliutils.SetWhetherUsingWiotp(userPw)
+	var agbotOrg string
+	agbotOrg, agbot = cliutils.TrimOrg(org, agbot)
+	if !force {
+		cliutils.ConfirmRemove(msgPrinter.Sprintf("Are you sure you want to remove agbot %v/%v from the Horizon Exchange? The patterns and deployment policies that it serves will no longer be deployed by it.", agbotOrg, agbot))
+	}
+
+	httpCode := cliutils.ExchangeDelete("Exchange", cliutils.GetExchangeUrl(), "orgs/"+agbotOrg+"/agbots/"+agbot, cliutils.OrgAndCreds(org, userPw), []int{204, 404})
+	if httpCode == 404 {
+		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("agbot '%s' not found in org %s", agbot, agbotOrg))
 	}
 }
 
@@ -57,7 +109,7 @@ type ExchangeAgbotPatterns struct {
 }
 
 func AgbotListPatterns(org, userPw, agbot, patternOrg, pattern, nodeOrg string) {
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	var agbotOrg string
 	agbotOrg, agbot = cliutils.TrimOrg(org, agbot)
 	var patternId string
@@ -76,8 +128,7 @@ func AgbotListPatterns(org, userPw, agbot, patternOrg, pattern, nodeOrg string)
 	if httpCode == 404 && patternOrg != "" && pattern != "" {
 		cliutils.Fatal(cliutils.NOT_FOUND, i18n.GetMessagePrinter().Sprintf("pattern '%s' with org '%s' and node org '%s' not found in agbot '%s'", pattern, patternOrg, nodeOrg, agbot))
 	}
-	output := cliutils.MarshalIndent(patterns.Patterns, "exchange agbot listpattern")
-	fmt.Println(output)
+	cliutils.Output(patterns.Patterns, "exchange agbot listpattern")
 }
 
 type ServedPattern struct {
@@ -87,7 +138,7 @@ type ServedPattern struct {
 }
 
 func AgbotAddPattern(org, userPw, agbot, patternOrg, pattern, nodeOrg string) {
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	var agbotOrg string
 	agbotOrg, agbot = cliutils.TrimOrg(org, agbot)
 	if nodeOrg == "" {
@@ -103,7 +154,7 @@ func AgbotAddPattern(org, userPw, agbot, patternOrg, pattern, nodeOrg string) {
 }
 
 func AgbotRemovePattern(org, userPw, agbot, patternOrg, pattern, nodeOrg string) {
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	var agbotOrg string
 	agbotOrg, agbot = cliutils.TrimOrg(org, agbot)
 	if nodeOrg == "" {
@@ -121,20 +172,19 @@ type ServedBusinessPolicy struct {
 }
 
 func AgbotListBusinessPolicy(org, userPw, agbot string) {
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	var agbotOrg string
 	agbotOrg, agbot = cliutils.TrimOrg(org, agbot)
 	// Display the full resources
 	resp := new(exchange.GetAgbotsBusinessPolsResponse)
 	cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+agbotOrg+"/agbots/"+agbot+"/businesspols", cliutils.OrgAndCreds(org, userPw), []int{200, 404}, resp)
-	output := cliutils.MarshalIndent(resp.BusinessPols, "exchange agbot listbusinesspol")
-	fmt.Println(output)
+	cliutils.Output(resp.BusinessPols, "exchange agbot listbusinesspol")
 }
 
 // Add the business policy to the agot supporting list. Currently
 // all the patterns are open to all the nodes within the same organization.
 func AgbotAddBusinessPolicy(org, userPw, agbot, polOrg string) {
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	var agbotOrg string
 	agbotOrg, agbot = cliutils.TrimOrg(org, agbot)
 
@@ -152,7 +202,7 @@ func AgbotAddBusinessPolicy(org, userPw, agbot, polOrg string) {
 // Remove the business policy from the agot supporting list. Currently
 // only supporting removing all the policies from a organization.
 func AgbotRemoveBusinessPolicy(org, userPw, agbot, PolOrg string) {
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	var agbotOrg string
 	agbotOrg, agbot = cliutils.TrimOrg(org, agbot)
 	polId := formServicedObjectId(PolOrg, "*", PolOrg)
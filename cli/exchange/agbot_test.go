@@ -0,0 +1,44 @@
+// +build unit
+
+package exchange
+
+import (
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/cli/clitest"
+	"testing"
+)
+
+func Test_AgbotCreateRemove(t *testing.T) {
+	h := clitest.New(t)
+
+	if res := h.Run(func() { AgbotCreate(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "agbot1", "agbottok", "") }); res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	agbot := AgbotExchangePut{}
+	if !h.Exchange.GetResource("orgs/myorg/agbots/agbot1", &agbot) || agbot.Token != "agbottok" || agbot.Name != "agbot1" {
+		t.Errorf("expected the agbot to be created, got %v", agbot)
+	}
+
+	// the token is read from stdin, and the agbot that is running keeps its message endpoint and key
+	h.Exchange.AddResource("orgs/myorg/agbots/agbot1", AgbotExchangePut{Token: "agbottok", Name: "agbot1", MsgEndPoint: "http://agbot1", PublicKey: "agbot1key"})
+	if res := h.RunWithInput("newtok\n", func() { AgbotCreate(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "agbot1", "-", "bot one") }); res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	agbot = AgbotExchangePut{}
+	if !h.Exchange.GetResource("orgs/myorg/agbots/agbot1", &agbot) || agbot.Token != "newtok" || agbot.Name != "bot one" || agbot.MsgEndPoint != "http://agbot1" || agbot.PublicKey != "agbot1key" {
+		t.Errorf("expected the token and name to be changed and the rest to be kept, got %v", agbot)
+	}
+
+	if res := h.Run(func() { AgbotCreate(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "agbot1", "", "") }); res.ExitCode != cliutils.CLI_INPUT_ERROR {
+		t.Errorf("expected an input error without a token, exit code %v", res.ExitCode)
+	}
+
+	if res := h.Run(func() { AgbotRemove(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "agbot1", true) }); res.ExitCode != 0 {
+		t.Errorf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if h.Exchange.GetResource("orgs/myorg/agbots/agbot1", &agbot) {
+		t.Errorf("expected the agbot to be removed")
+	}
+	if res := h.Run(func() { AgbotRemove(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "agbot1", true) }); res.ExitCode != cliutils.NOT_FOUND {
+		t.Errorf("expected the agbot not to be found, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+}
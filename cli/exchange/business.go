@@ -7,6 +7,7 @@ import (
 	"github.com/open-horizon/anax/businesspolicy"
 	"github.com/open-horizon/anax/cli/cliconfig"
 	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/cli/schema"
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/externalpolicy"
 	"github.com/open-horizon/anax/i18n"
@@ -16,7 +17,7 @@ import (
 
 //BusinessListPolicy lists all the policies in the org or only the specified policy if one is given
 func BusinessListPolicy(org string, credToUse string, policy string, namesOnly bool) {
-	cliutils.SetWhetherUsingApiKey(credToUse)
+	cliutils.SetWhetherUsingWiotp(credToUse)
 
 	var polOrg string
 	polOrg, policy = cliutils.TrimOrg(org, policy)
@@ -30,7 +31,7 @@ func BusinessListPolicy(org string, credToUse string, policy string, namesOnly b
 
 	//get policy list from Horizon Exchange
 	var policyList exchange.GetBusinessPolicyResponse
-	httpCode := cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+polOrg+"/business/policies"+cliutils.AddSlash(policy), cliutils.OrgAndCreds(org, credToUse), []int{200, 404}, &policyList)
+	httpCode := cliutils.ExchangeGetAll("Exchange", cliutils.GetExchangeUrl(), "orgs/"+polOrg+"/business/policies"+cliutils.AddSlash(policy), cliutils.OrgAndCreds(org, credToUse), []int{200, 404}, &policyList)
 	if httpCode == 404 && policy != "" {
 		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("Policy %s not found in org %s", policy, polOrg))
 	} else if httpCode == 404 {
@@ -41,11 +42,7 @@ func BusinessListPolicy(org string, credToUse string, policy string, namesOnly b
 		for bPolicy := range policyList.BusinessPolicy {
 			policyNameList = append(policyNameList, bPolicy)
 		}
-		jsonBytes, err := json.MarshalIndent(policyNameList, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn exchange deployment listpolicy' output: %v", err))
-		}
-		fmt.Println(string(jsonBytes))
+		cliutils.Output(policyNameList, "hzn exchange deployment listpolicy")
 	} else {
 		buf := new(bytes.Buffer)
 		enc := json.NewEncoder(buf)
@@ -65,7 +62,7 @@ func BusinessAddPolicy(org string, credToUse string, policy string, jsonFilePath
 	//check for ExchangeUrl early on
 	var exchUrl = cliutils.GetExchangeUrl()
 
-	cliutils.SetWhetherUsingApiKey(credToUse)
+	cliutils.SetWhetherUsingWiotp(credToUse)
 	var polOrg string
 	polOrg, policy = cliutils.TrimOrg(org, policy)
 
@@ -74,6 +71,7 @@ func BusinessAddPolicy(org string, credToUse string, policy string, jsonFilePath
 
 	//read in the new business policy from file
 	newBytes := cliconfig.ReadJsonFileWithLocalConfig(jsonFilePath)
+	schema.Check(schema.KIND_DEPLOYMENT, jsonFilePath, newBytes)
 	var policyFile businesspolicy.BusinessPolicy
 	err := json.Unmarshal(newBytes, &policyFile)
 	if err != nil {
@@ -109,7 +107,7 @@ func BusinessUpdatePolicy(org string, credToUse string, policyName string, fileP
 	//check for ExchangeUrl early on
 	var exchUrl = cliutils.GetExchangeUrl()
 
-	cliutils.SetWhetherUsingApiKey(credToUse)
+	cliutils.SetWhetherUsingWiotp(credToUse)
 	var polOrg string
 	polOrg, policyName = cliutils.TrimOrg(org, policyName)
 
@@ -138,7 +136,7 @@ func BusinessUpdatePolicy(org string, credToUse string, policyName string, fileP
 		}
 		msgPrinter.Printf("Updating Policy %v/%v in the Horizon Exchange and re-evaluating all agreements based on this deployment policy. Existing agreements might be cancelled and re-negotiated.", polOrg, policyName)
 		msgPrinter.Println()
-		cliutils.ExchangePutPost("Exchange", http.MethodPatch, exchUrl, "orgs/"+polOrg+"/business/policies"+cliutils.AddSlash(policyName), cliutils.OrgAndCreds(org, credToUse), []int{201}, patch, nil)
+		cliutils.ExchangePatch("Exchange", exchUrl, "orgs/"+polOrg+"/business/policies"+cliutils.AddSlash(policyName), cliutils.OrgAndCreds(org, credToUse), []int{201}, patch, nil)
 		msgPrinter.Printf("Policy %v/%v updated in the Horizon Exchange", polOrg, policyName)
 		msgPrinter.Println()
 	} else if _, ok := findPatchType["properties"]; ok {
@@ -156,7 +154,7 @@ func BusinessUpdatePolicy(org string, credToUse string, policyName string, fileP
 		patch["properties"] = newValue
 		msgPrinter.Printf("Updating Policy %v/%v in the Horizon Exchange and re-evaluating all agreements based on this deployment policy. Existing agreements might be cancelled and re-negotiated.", polOrg, policyName)
 		msgPrinter.Println()
-		cliutils.ExchangePutPost("Exchange", http.MethodPatch, exchUrl, "orgs/"+polOrg+"/business/policies"+cliutils.AddSlash(policyName), cliutils.OrgAndCreds(org, credToUse), []int{201}, patch, nil)
+		cliutils.ExchangePatch("Exchange", exchUrl, "orgs/"+polOrg+"/business/policies"+cliutils.AddSlash(policyName), cliutils.OrgAndCreds(org, credToUse), []int{201}, patch, nil)
 		msgPrinter.Printf("Policy %v/%v updated in the Horizon Exchange", polOrg, policyName)
 		msgPrinter.Println()
 	} else if _, ok := findPatchType["constraints"]; ok {
@@ -173,7 +171,7 @@ func BusinessUpdatePolicy(org string, credToUse string, policyName string, fileP
 		newValue = patch["constraints"]
 		msgPrinter.Printf("Updating Policy %v/%v in the Horizon Exchange and re-evaluating all agreements based on this deployment policy. Existing agreements might be cancelled and re-negotiated.", polOrg, policyName)
 		msgPrinter.Println()
-		cliutils.ExchangePutPost("Exchange", http.MethodPatch, exchUrl, "orgs/"+polOrg+"/business/policies"+cliutils.AddSlash(policyName), cliutils.OrgAndCreds(org, credToUse), []int{201}, patch, nil)
+		cliutils.ExchangePatch("Exchange", exchUrl, "orgs/"+polOrg+"/business/policies"+cliutils.AddSlash(policyName), cliutils.OrgAndCreds(org, credToUse), []int{201}, patch, nil)
 		msgPrinter.Printf("Policy %v/%v updated in the Horizon Exchange", polOrg, policyName)
 		msgPrinter.Println()
 	} else if _, ok := findPatchType["userInput"]; ok {
@@ -182,7 +180,7 @@ func BusinessUpdatePolicy(org string, credToUse string, policyName string, fileP
 		if err != nil {
 			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal attribute input %s: %v", attribute, err))
 		}
-		cliutils.ExchangePutPost("Exchange", http.MethodPatch, exchUrl, "orgs/"+polOrg+"/business/policies"+cliutils.AddSlash(policyName), cliutils.OrgAndCreds(org, credToUse), []int{201}, patch, nil)
+		cliutils.ExchangePatch("Exchange", exchUrl, "orgs/"+polOrg+"/business/policies"+cliutils.AddSlash(policyName), cliutils.OrgAndCreds(org, credToUse), []int{201}, patch, nil)
 		msgPrinter.Printf("Updating Policy %v/%v in the Horizon Exchange and re-evaluating all agreements based on this deployment policy. Existing agreements might be cancelled and re-negotiated.", polOrg, policyName)
 		msgPrinter.Println()
 		msgPrinter.Printf("Policy %v/%v updated in the Horizon Exchange", polOrg, policyName)
@@ -198,7 +196,7 @@ func BusinessUpdatePolicy(org string, credToUse string, policyName string, fileP
 			}
 			msgPrinter.Printf("Updating Policy %v/%v in the Horizon Exchange and re-evaluating all agreements based on this deployment policy. Existing agreements might be cancelled and re-negotiated.", polOrg, policyName)
 			msgPrinter.Println()
-			cliutils.ExchangePutPost("Exchange", http.MethodPatch, exchUrl, "orgs/"+polOrg+"/business/policies"+cliutils.AddSlash(policyName), cliutils.OrgAndCreds(org, credToUse), []int{201}, patch, nil)
+			cliutils.ExchangePatch("Exchange", exchUrl, "orgs/"+polOrg+"/business/policies"+cliutils.AddSlash(policyName), cliutils.OrgAndCreds(org, credToUse), []int{201}, patch, nil)
 			msgPrinter.Printf("Policy %v/%v updated in the Horizon Exchange", polOrg, policyName)
 			msgPrinter.Println()
 		} else {
@@ -209,7 +207,7 @@ func BusinessUpdatePolicy(org string, credToUse string, policyName string, fileP
 
 //BusinessRemovePolicy will remove an existing business policy in the Horizon Exchange
 func BusinessRemovePolicy(org string, credToUse string, policy string, force bool) {
-	cliutils.SetWhetherUsingApiKey(credToUse)
+	cliutils.SetWhetherUsingWiotp(credToUse)
 	var polOrg string
 	polOrg, policy = cliutils.TrimOrg(org, policy)
 
@@ -1,8 +1,6 @@
 package exchange
 
 import (
-	"encoding/json"
-	"fmt"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/i18n"
@@ -25,30 +23,22 @@ func CatalogServiceList(credOrg string, userPw string, displayShort bool, displa
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	if displayShort && displayLong {
 		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Flags -s and -l are mutually exclusive."))
 	}
 
 	var resp exchange.GetServicesResponse
-	cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "catalog/services?orgtype="+orgType, cliutils.OrgAndCreds(credOrg, userPw), []int{200}, &resp)
+	cliutils.ExchangeGetAll("Exchange", cliutils.GetExchangeUrl(), "catalog/services?orgtype="+orgType, cliutils.OrgAndCreds(credOrg, userPw), []int{200}, &resp)
 
 	if displayLong {
-		jsonBytes, err := json.MarshalIndent(resp.Services, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn exchange catalog servicelist -l' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(resp.Services, "hzn exchange catalog servicelist -l")
 	} else if displayShort {
 		serviceNames := []string{}
 		for k := range resp.Services {
 			serviceNames = append(serviceNames, k)
 		}
-		jsonBytes, err := json.MarshalIndent(serviceNames, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn exchange catalog servicelist -s' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(serviceNames, "hzn exchange catalog servicelist -s")
 	} else {
 		// display medium information about public services
 		var servicesMedium = make(map[string]CatalogServiceWithMediumInfo)
@@ -59,11 +49,7 @@ func CatalogServiceList(credOrg string, userPw string, displayShort bool, displa
 			}
 			servicesMedium[k] = catalogServiceMedium
 		}
-		jsonBytes, err := json.MarshalIndent(servicesMedium, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn exchange catalog servicelist' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(servicesMedium, "hzn exchange catalog servicelist")
 
 	}
 
@@ -74,30 +60,22 @@ func CatalogPatternList(credOrg string, userPw string, displayShort bool, displa
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	if displayShort && displayLong {
 		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Flags -s and -l are mutually exclusive."))
 	}
 
 	var resp ExchangePatterns
-	cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "catalog/patterns?orgtype="+orgType, cliutils.OrgAndCreds(credOrg, userPw), []int{200}, &resp)
+	cliutils.ExchangeGetAll("Exchange", cliutils.GetExchangeUrl(), "catalog/patterns?orgtype="+orgType, cliutils.OrgAndCreds(credOrg, userPw), []int{200}, &resp)
 
 	if displayLong {
-		jsonBytes, err := json.MarshalIndent(resp.Patterns, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn exchange catalog patternlist -l' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(resp.Patterns, "hzn exchange catalog patternlist -l")
 	} else if displayShort {
 		patternNames := []string{}
 		for k := range resp.Patterns {
 			patternNames = append(patternNames, k)
 		}
-		jsonBytes, err := json.MarshalIndent(patternNames, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn exchange catalog patternlist -s' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(patternNames, "hzn exchange catalog patternlist -s")
 	} else {
 		// display medium information about public patterns
 		var patternsMedium = make(map[string]CatalogPatternWithMediumInfo)
@@ -107,11 +85,7 @@ func CatalogPatternList(credOrg string, userPw string, displayShort bool, displa
 			}
 			patternsMedium[k] = catalogPatternMedium
 		}
-		jsonBytes, err := json.MarshalIndent(patternsMedium, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn exchange catalog patternlist' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(patternsMedium, "hzn exchange catalog patternlist")
 
 	}
 }
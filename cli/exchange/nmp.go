@@ -0,0 +1,141 @@
+package exchange
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/open-horizon/anax/cli/cliconfig"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/cli/schema"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/i18n"
+	"net/http"
+)
+
+// NMPList lists all the node management policies in the org or only the specified policy if one is given
+func NMPList(org string, credToUse string, nmpName string, namesOnly bool) {
+	cliutils.SetWhetherUsingWiotp(credToUse)
+
+	var nmpOrg string
+	nmpOrg, nmpName = cliutils.TrimOrg(org, nmpName)
+
+	if nmpName == "*" {
+		nmpName = ""
+	}
+
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	var nmpList exchange.GetNodeManagementPolicyResponse
+	httpCode := cliutils.ExchangeGetAll("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nmpOrg+"/managementpolicies"+cliutils.AddSlash(nmpName), cliutils.OrgAndCreds(org, credToUse), []int{200, 404}, &nmpList)
+	if httpCode == 404 && nmpName != "" {
+		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("Node management policy %s not found in org %s", nmpName, nmpOrg))
+	} else if httpCode == 404 {
+		fmt.Println("[]")
+	} else if namesOnly && nmpName == "" {
+		nmpNameList := []string{}
+		for name := range nmpList.Policies {
+			nmpNameList = append(nmpNameList, name)
+		}
+		cliutils.Output(nmpNameList, "hzn exchange nmp list")
+	} else {
+		cliutils.Output(nmpList.Policies, "exchange nmp list")
+	}
+}
+
+// NMPAdd adds or replaces a node management policy in the org. The job of the policy is validated first, so that
+// the agents do not get a job they can not run.
+func NMPAdd(org string, credToUse string, nmpName string, jsonFilePath string, noConstraints bool) {
+
+	//check for ExchangeUrl early on
+	var exchUrl = cliutils.GetExchangeUrl()
+
+	cliutils.SetWhetherUsingWiotp(credToUse)
+	var nmpOrg string
+	nmpOrg, nmpName = cliutils.TrimOrg(org, nmpName)
+
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	newBytes := cliconfig.ReadJsonFileWithLocalConfig(jsonFilePath)
+	schema.Check(schema.KIND_NMP, jsonFilePath, newBytes)
+	var nmpFile exchange.ExchangeNodeManagementPolicy
+	if err := json.Unmarshal(newBytes, &nmpFile); err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal json input file %s: %v", jsonFilePath, err))
+	}
+
+	if err := nmpFile.Validate(); err != nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Incorrect node management policy format in file %s: %v", jsonFilePath, err))
+	}
+
+	// a policy without patterns and constraints applies to all the policy nodes of the org
+	if !noConstraints && len(nmpFile.Patterns) == 0 && len(nmpFile.Constraints) == 0 {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("The node management policy has no patterns and no constraints which might result in the job being run on all nodes. Please specify --no-constraints to confirm that this is acceptable."))
+	}
+
+	httpCode := cliutils.ExchangePutPost("Exchange", http.MethodPost, exchUrl, "orgs/"+nmpOrg+"/managementpolicies"+cliutils.AddSlash(nmpName), cliutils.OrgAndCreds(org, credToUse), []int{201, 403}, nmpFile, nil)
+	if httpCode == 403 {
+		cliutils.ExchangePutPost("Exchange", http.MethodPut, exchUrl, "orgs/"+nmpOrg+"/managementpolicies"+cliutils.AddSlash(nmpName), cliutils.OrgAndCreds(org, credToUse), []int{201, 404}, nmpFile, nil)
+		msgPrinter.Printf("Node management policy: %v/%v updated in the Horizon Exchange", nmpOrg, nmpName)
+		msgPrinter.Println()
+	} else {
+		msgPrinter.Printf("Node management policy: %v/%v added in the Horizon Exchange", nmpOrg, nmpName)
+		msgPrinter.Println()
+	}
+}
+
+// NMPRemove removes a node management policy from the org. The statuses of the jobs the policy ran remain on the nodes.
+func NMPRemove(org string, credToUse string, nmpName string, force bool) {
+	cliutils.SetWhetherUsingWiotp(credToUse)
+	var nmpOrg string
+	nmpOrg, nmpName = cliutils.TrimOrg(org, nmpName)
+
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if !force {
+		cliutils.ConfirmRemove(msgPrinter.Sprintf("Are you sure you want to remove node management policy %v for org %v from the Horizon Exchange?", nmpName, nmpOrg))
+	}
+
+	httpCode := cliutils.ExchangeDelete("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nmpOrg+"/managementpolicies"+cliutils.AddSlash(nmpName), cliutils.OrgAndCreds(org, credToUse), []int{204, 404})
+	if httpCode == 404 {
+		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("Node management policy %v/%v not found in the Horizon Exchange", nmpOrg, nmpName))
+	} else {
+		msgPrinter.Printf("Node management policy %v/%v removed", nmpOrg, nmpName)
+		msgPrinter.Println()
+	}
+}
+
+// NMPNew displays an empty node management policy template that can be filled in.
+func NMPNew() {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	var nmp_template = []string{
+		`{`,
+		`  "label": "",         /* ` + msgPrinter.Sprintf("Node management policy label.") + ` */`,
+		`  "description": "",   /* ` + msgPrinter.Sprintf("Node management policy description.") + ` */`,
+		`  "properties": [      /* ` + msgPrinter.Sprintf("A list of policy properties that the constraints of the node policies can refer to.") + ` */`,
+		`    {`,
+		`       "name": "",`,
+		`       "value": null`,
+		`    }`,
+		`  ],`,
+		`  "constraints": [     /* ` + msgPrinter.Sprintf("A list of constraint expressions that the node policies must satisfy.") + ` */`,
+		`       "myproperty == myvalue"`,
+		`  ],`,
+		`  "patterns": [],      /* ` + msgPrinter.Sprintf("The patterns of the nodes to manage. Mutually exclusive with properties and constraints.") + ` */`,
+		`  "enabled": true,`,
+		`  "start": "now",      /* ` + msgPrinter.Sprintf("'now' or an RFC3339 time when the job starts on the nodes.") + ` */`,
+		`  "job": {`,
+		`    "type": "` + exchange.NMP_JOB_CERT_UPDATE + `",  /* ` + msgPrinter.Sprintf("The type of the job. The supported types are %v and %v. An %v job is configured with the version to upgrade to, the waveSize, waveIntervalS, failureThreshold and nodeTimeoutS of the rollout.", exchange.NMP_JOB_CERT_UPDATE, exchange.NMP_JOB_AGENT_UPGRADE, exchange.NMP_JOB_AGENT_UPGRADE) + ` */`,
+		`    "config": {`,
+		`      "cert": ""       /* ` + msgPrinter.Sprintf("The PEM encoded CA certs for the agent to trust.") + ` */`,
+		`    }`,
+		`  }`,
+		`}`,
+	}
+
+	for _, s := range nmp_template {
+		fmt.Println(s)
+	}
+}
@@ -0,0 +1,31 @@
+// +build unit
+
+package exchange
+
+import (
+	"encoding/json"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/cli/clitest"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_NMPList_jsonErrors(t *testing.T) {
+	h := clitest.New(t)
+
+	res := h.Run(func() {
+		jsonErrors := true
+		cliutils.Opts.JsonErrors = &jsonErrors
+		NMPList(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "missing", false)
+	})
+
+	var jsonErr cliutils.JsonError
+	if err := json.Unmarshal([]byte(res.Stderr), &jsonErr); err != nil {
+		t.Fatalf("the error should be JSON: %v, stderr: %v", err, res.Stderr)
+	} else if jsonErr.ExitCode != res.ExitCode || jsonErr.ExitCode != cliutils.NOT_FOUND || jsonErr.ErrorClass != "not_found" {
+		t.Errorf("wrong error %v", jsonErr)
+	} else if jsonErr.HttpCode == nil || *jsonErr.HttpCode != http.StatusNotFound || !strings.Contains(jsonErr.Url, "managementpolicies/missing") {
+		t.Errorf("the failed request should be in the error: %v", res.Stderr)
+	}
+}
@@ -2,12 +2,15 @@ package exchange
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"github.com/open-horizon/anax/api"
 	"github.com/open-horizon/anax/cli/cliconfig"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/common"
 	"github.com/open-horizon/anax/compcheck"
+	"github.com/open-horizon/anax/cutil"
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/externalpolicy"
 	_ "github.com/open-horizon/anax/externalpolicy/text_language"
@@ -16,7 +19,9 @@ import (
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/semanticversion"
 	"net/http"
-	"os"
+	"sort"
+	"sync"
+	"time"
 )
 
 type ExchangeNodes struct {
@@ -48,35 +53,45 @@ type ExchangeNodeStatus struct {
 	LastUpdated     string                `json:"lastUpdated,omitempty"`
 }
 
-func NodeList(org string, credToUse string, node string, namesOnly bool) {
-	cliutils.SetWhetherUsingApiKey(credToUse)
+// The columns of the csv output of 'hzn exchange node list', when --columns is not specified.
+var NodeListColumns = []string{"id", "name", "nodeType", "pattern", "arch", "lastHeartbeat"}
+
+func NodeList(org string, credToUse string, node string, namesOnly bool, output string, columns string) {
+	cliutils.SetWhetherUsingWiotp(credToUse)
 	var nodeOrg string
 	nodeOrg, node = cliutils.TrimOrg(org, node)
 	if node == "*" {
 		node = ""
 	}
-	if namesOnly && node == "" {
+	if cliutils.IsCSVOutput(output) {
+		var nodes ExchangeNodes
+		httpCode := cliutils.ExchangeGetAll("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes"+cliutils.AddSlash(node), cliutils.OrgAndCreds(org, credToUse), []int{200, 404}, &nodes)
+		if httpCode == 404 && node != "" {
+			cliutils.Fatal(cliutils.NOT_FOUND, i18n.GetMessagePrinter().Sprintf("node '%s' not found in org %s", node, nodeOrg))
+		}
+		objects := make(map[string]interface{})
+		for id, n := range nodes.Nodes {
+			n.Token = ""
+			objects[id] = n
+		}
+		cliutils.OutputCSV(cliutils.CSVRecords(objects), cliutils.ParseColumns(columns, NodeListColumns))
+	} else if namesOnly && node == "" {
 		// Only display the names
 		var resp ExchangeNodes
-		cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes"+cliutils.AddSlash(node), cliutils.OrgAndCreds(org, credToUse), []int{200, 404}, &resp)
+		cliutils.ExchangeGetAll("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes"+cliutils.AddSlash(node), cliutils.OrgAndCreds(org, credToUse), []int{200, 404}, &resp)
 		nodes := []string{}
 		for n := range resp.Nodes {
 			nodes = append(nodes, n)
 		}
-		jsonBytes, err := json.MarshalIndent(nodes, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'exchange node list' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(nodes, "exchange node list")
 	} else {
 		// Display the full resources
 		var nodes ExchangeNodes
-		httpCode := cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes"+cliutils.AddSlash(node), cliutils.OrgAndCreds(org, credToUse), []int{200, 404}, &nodes)
+		httpCode := cliutils.ExchangeGetAll("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes"+cliutils.AddSlash(node), cliutils.OrgAndCreds(org, credToUse), []int{200, 404}, &nodes)
 		if httpCode == 404 && node != "" {
 			cliutils.Fatal(cliutils.NOT_FOUND, i18n.GetMessagePrinter().Sprintf("node '%s' not found in org %s", node, nodeOrg))
 		}
-		output := cliutils.MarshalIndent(nodes.Nodes, "exchange node list")
-		fmt.Println(output)
+		cliutils.Output(nodes.Nodes, "exchange node list")
 	}
 }
 
@@ -112,7 +127,7 @@ func NodeCreate(org, nodeIdTok, node, token, userPw, arch string, nodeName strin
 		nodeName = nodeId
 	}
 
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	exchUrlBase := cliutils.GetExchangeUrl()
 
 	// validate the node type
@@ -147,7 +162,7 @@ func NodeCreate(org, nodeIdTok, node, token, userPw, arch string, nodeName strin
 			msgPrinter.Println()
 		}
 		patchNodeReq := NodeExchangePatchToken{Token: nodeToken}
-		httpCode = cliutils.ExchangePutPost("Exchange", http.MethodPatch, cliutils.GetExchangeUrl(), "orgs/"+org+"/nodes/"+nodeId, cliutils.OrgAndCreds(org, userPw), []int{201, 401, 403}, patchNodeReq, &resp)
+		httpCode = cliutils.ExchangePatch("Exchange", cliutils.GetExchangeUrl(), "orgs/"+org+"/nodes/"+nodeId, cliutils.OrgAndCreds(org, userPw), []int{201, 401, 403}, patchNodeReq, &resp)
 	} else {
 		// create the node with given node type
 		if nodeType == "" {
@@ -193,20 +208,25 @@ func NodeCreate(org, nodeIdTok, node, token, userPw, arch string, nodeName strin
 	}
 }
 
-func NodeUpdate(org string, credToUse string, node string, filePath string) {
+// NodeUpdate updates the attributes of the node that are in the file. If ifLastUpdated is set, the node is only
+// updated if its lastUpdated time in the exchange is still that one.
+func NodeUpdate(org string, credToUse string, node string, filePath string, mergePatch bool, ifLastUpdated string) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey(credToUse)
+	cliutils.SetWhetherUsingWiotp(credToUse)
 	var nodeOrg string
 	nodeOrg, node = cliutils.TrimOrg(org, node)
 
-	//check that the node exists
+	//check that the node exists, and that it was not changed since the version the update was prepared from
 	var nodeReq ExchangeNodes
 	httpCode := cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes/"+node, cliutils.OrgAndCreds(org, credToUse), []int{200, 404}, &nodeReq)
 	if httpCode == 404 {
 		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("Node %s/%s not found in the Horizon Exchange.", nodeOrg, node))
 	}
+	if ifLastUpdated != "" {
+		cliutils.CheckResourceUnchanged(msgPrinter.Sprintf("Node %v/%v", nodeOrg, node), ifLastUpdated, getNodeLastUpdated(nodeReq))
+	}
 
 	attribute := cliconfig.ReadJsonFileWithLocalConfig(filePath)
 
@@ -222,8 +242,27 @@ func NodeUpdate(org string, credToUse string, node string, filePath string) {
 		}
 	}
 
+	// For a merge patch, get the current value of each attribute so that the fields the user didn't specify are preserved.
+	currentAttrs := make(map[string]interface{})
+	if mergePatch {
+		for _, n := range nodeReq.Nodes {
+			if bytes, err := json.Marshal(n); err != nil {
+				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal exchange node %s: %v", node, err))
+			} else if err := json.Unmarshal(bytes, &currentAttrs); err != nil {
+				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal exchange node %s: %v", node, err))
+			}
+			break
+		}
+	}
+
 	updated := false
 	for k, v := range findPatchType {
+		if mergePatch {
+			if err := cliutils.MergePatchValue(currentAttrs[k], v, &v); err != nil {
+				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to merge the %v attribute with its current value: %v", k, err))
+			}
+			cliutils.Verbose(msgPrinter.Sprintf("merged value of %v: %v", k, v))
+		}
 		bytes, err := json.Marshal(v)
 		if err != nil {
 			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal attribute input %s: %v", v, err))
@@ -308,23 +347,106 @@ func NodeUpdate(org string, credToUse string, node string, filePath string) {
 	}
 }
 
+// Returns the lastUpdated time of the (only) node in the exchange output.
+func getNodeLastUpdated(nodes ExchangeNodes) string {
+	for _, n := range nodes.Nodes {
+		return n.LastUpdated
+	}
+	return ""
+}
+
 type NodeExchangePatchToken struct {
 	Token string `json:"token"`
 }
 
-func NodeSetToken(org, credToUse, node, token string) {
-	cliutils.SetWhetherUsingApiKey(credToUse)
+// Change the token of the node in the exchange. With rotate, a strong token is generated and verified with the
+// exchange, and if the local agent is registered as this node it is switched to the new token before the old token
+// is forgotten.
+func NodeSetToken(org, credToUse, node, token string, rotate bool) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if rotate && token != "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Specify either a token or --rotate, not both."))
+	} else if !rotate && token == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Specify either a token or --rotate."))
+	}
+
+	cliutils.SetWhetherUsingWiotp(credToUse)
 	var nodeOrg string
 	nodeOrg, node = cliutils.TrimOrg(org, node)
+
+	if rotate {
+		var err error
+		if token, err = cutil.SecureRandomString(); err != nil {
+			cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("failed to generate a random token: %v", err))
+		}
+	}
+
 	patchNodeReq := NodeExchangePatchToken{Token: token}
-	cliutils.ExchangePutPost("Exchange", http.MethodPatch, cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes/"+node, cliutils.OrgAndCreds(org, credToUse), []int{201}, patchNodeReq, nil)
+	cliutils.ExchangePatch("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes/"+node, cliutils.OrgAndCreds(org, credToUse), []int{201}, patchNodeReq, nil)
+
+	if !rotate {
+		return
+	}
+
+	// From here on the old token no longer works in the exchange, so every failure reports the new token to keep the
+	// node recoverable.
+	var nodes ExchangeNodes
+	if httpCode := cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes/"+node, nodeOrg+"/"+node+":"+token, []int{200, 401, 403}, &nodes); httpCode != 200 {
+		cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf("the token of node %v/%v was changed in the exchange, but the exchange did not accept the new token (HTTP code %d). The new token is: %v", nodeOrg, node, httpCode, token))
+	}
+
+	// Switch the local agent to the new token if it is registered as this node.
+	horDevice := api.HorizonDevice{}
+	if _, err := cliutils.HorizonGet("node", []int{200}, &horDevice, true); err == nil && horDevice.Org != nil && horDevice.Id != nil && *horDevice.Org == nodeOrg && *horDevice.Id == node {
+		if _, _, err := cliutils.HorizonPutPost(http.MethodPut, "node/token", []int{200}, api.NodeToken{Token: &token}, false); err != nil {
+			cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf("the token of node %v/%v was changed in the exchange, but the local agent could not be updated: %v. The new token is: %v", nodeOrg, node, err, token))
+		}
+		msgPrinter.Printf("The token of node %v/%v was rotated and the local agent is using the new token.", nodeOrg, node)
+		msgPrinter.Println()
+		return
+	}
+
+	msgPrinter.Printf("The token of node %v/%v was rotated. The local agent is not registered as this node, store the new token where the node can use it: %v", nodeOrg, node, token)
+	msgPrinter.Println()
+}
+
+// Send a heartbeat for the node to the exchange. If the node is not specified, the node id is taken
+// from the credentials, which must then be node credentials.
+func NodeHeartbeat(org, credToUse, node string) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	cliutils.SetWhetherUsingWiotp(credToUse)
+	if node == "" {
+		if id, _ := cliutils.SplitIdToken(credToUse); id != "" {
+			node = id
+		} else {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Please specify the node either as an argument or with the -n flag."))
+		}
+	}
+	var nodeOrg string
+	nodeOrg, node = cliutils.TrimOrg(org, node)
+
+	httpCode := cliutils.ExchangePutPost("Exchange", http.MethodPost, cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes/"+node+"/heartbeat", cliutils.OrgAndCreds(org, credToUse), []int{200, 201, 404}, nil, nil)
+	if httpCode == 404 {
+		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("node '%s' not found in org %s", node, nodeOrg))
+	}
+	msgPrinter.Printf("Heartbeat sent for node %v/%v.", nodeOrg, node)
+	msgPrinter.Println()
 }
 
-func NodeConfirm(org, node, token string, nodeIdTok string) {
+// Confirm that the node exists in the exchange, that its token is valid and, when heartbeatWithin is not empty, that
+// it has heartbeated within that age. Each failure exits with a different code, so that a provisioning pipeline can
+// tell them apart: NOT_FOUND when the node does not exist, INVALID_CREDS when the token is not valid and
+// HEARTBEAT_STALE when the node has not heartbeated recently enough. Without user credentials the exchange can not
+// tell a node that does not exist from a token that is not valid, both exit with INVALID_CREDS.
+func NodeConfirm(org, node, token string, nodeIdTok string, userPw string, heartbeatWithin string) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey("")
+	cliutils.SetWhetherUsingWiotp("")
 
 	// check the input
 	if nodeIdTok != "" {
@@ -333,7 +455,7 @@ func NodeConfirm(org, node, token string, nodeIdTok string) {
 		}
 	} else {
 		if node == "" && token == "" {
-			nodeIdTok = os.Getenv("HZN_EXCHANGE_NODE_AUTH")
+			nodeIdTok = cliutils.GetNodeAuth("")
 		}
 	}
 
@@ -349,22 +471,143 @@ func NodeConfirm(org, node, token string, nodeIdTok string) {
 		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Please specify both node and token."))
 	}
 
-	httpCode := cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+org+"/nodes/"+node, cliutils.OrgAndCreds(org, node+":"+token), []int{200}, nil)
-	if httpCode == 200 {
-		msgPrinter.Printf("Node id and token are valid.")
-		msgPrinter.Println()
+	var maxAge time.Duration
+	if heartbeatWithin != "" {
+		var err error
+		if maxAge, err = cliutils.ParseAge(heartbeatWithin); err != nil {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, err.Error())
+		}
+	}
+
+	// with user credentials, check that the node exists before checking its token
+	userPw = cliutils.GetUserAuth(userPw)
+	if userPw != "" {
+		cliutils.SetWhetherUsingWiotp(userPw)
+		httpCode := cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+org+"/nodes/"+node, cliutils.OrgAndCreds(org, userPw), []int{200, 401, 403, 404}, nil)
+		if httpCode == 404 {
+			cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("node '%s' not found in org %s", node, org))
+		} else if httpCode != 200 {
+			cliutils.Fatal(cliutils.INVALID_CREDS, msgPrinter.Sprintf("the user credentials are not valid to read node %v/%v in the Horizon Exchange (HTTP code %v).", org, node, httpCode))
+		}
+		cliutils.SetWhetherUsingWiotp("")
+	}
+
+	// the body is only parsed when the token is valid, the body of a 401 is not always json
+	var output []byte
+	var nodes ExchangeNodes
+	httpCode := cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+org+"/nodes/"+node, cliutils.OrgAndCreds(org, node+":"+token), []int{200, 401, 403, 404}, &output)
+	if httpCode == 404 {
+		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("node '%s' not found in org %s", node, org))
+	} else if httpCode != 200 {
+		cliutils.Fatal(cliutils.INVALID_CREDS, msgPrinter.Sprintf("the node id and token are not valid in the Horizon Exchange (HTTP code %v).", httpCode))
+	} else if err := json.Unmarshal(output, &nodes); err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal REST API response: %v", err))
+	}
+	msgPrinter.Printf("Node id and token are valid.")
+	msgPrinter.Println()
+
+	if heartbeatWithin == "" {
+		return
+	}
+	lastHeartbeat := ""
+	if n, ok := nodes.Nodes[org+"/"+node]; ok {
+		lastHeartbeat = n.LastHeartbeat
+	}
+	if lastHeartbeat == "" {
+		cliutils.Fatal(cliutils.HEARTBEAT_STALE, msgPrinter.Sprintf("node %v/%v has never heartbeated to the Horizon Exchange.", org, node))
+	}
+	age := time.Since(time.Unix(cutil.TimeInSeconds(lastHeartbeat, cutil.ExchangeTimeFormat), 0))
+	if age > maxAge {
+		cliutils.Fatal(cliutils.HEARTBEAT_STALE, msgPrinter.Sprintf("node %v/%v last heartbeated at %v, which is more than %v ago.", org, node, lastHeartbeat, heartbeatWithin))
+	}
+	msgPrinter.Printf("Node last heartbeated at %v.", lastHeartbeat)
+	msgPrinter.Println()
+}
+
+type NodeAuthVerifyOutput struct {
+	Valid    bool   `json:"valid"`
+	Node     string `json:"node"`
+	Org      string `json:"org"`
+	NodeType string `json:"nodeType"`
+	Pattern  string `json:"pattern"`
+}
+
+// Verify that the node credentials are valid by reading the node's own resource. Exits with an error if they are not.
+func NodeAuthVerify(org, nodeIdTok string) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	cliutils.SetWhetherUsingWiotp("")
+
+	nodeIdTok = cliutils.GetNodeAuth(nodeIdTok)
+	node, token := cliutils.SplitIdToken(nodeIdTok)
+	if node == "" || token == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the node id and token must be specified with one of the following: the -n flag, the --node-auth flag or HZN_EXCHANGE_NODE_AUTH"))
+	}
+	nodeOrg, node := cliutils.TrimOrg(org, node)
+
+	var nodes ExchangeNodes
+	httpCode := cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes/"+node, nodeOrg+"/"+node+":"+token, []int{200, 401, 403, 404}, &nodes)
+	if httpCode != 200 {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the credentials of node %v/%v are not valid in the Horizon Exchange (HTTP code %v).", nodeOrg, node, httpCode))
+	}
+
+	output := NodeAuthVerifyOutput{Valid: true, Node: node, Org: nodeOrg}
+	if n, ok := nodes.Nodes[nodeOrg+"/"+node]; ok {
+		output.NodeType = n.NodeType
+		output.Pattern = n.Pattern
 	}
-	// else cliutils.ExchangeGet() already gave the error msg
+
+	cliutils.Output(output, "exchange node auth verify")
 }
 
-func NodeRemove(org, credToUse, node string, force bool) {
+// Remove the node, or the nodes that match the wildcard pattern. With an age, such as 30d, only the nodes that have not
+// heartbeated within it are removed, so that the stale nodes can be cleaned up without removing the nodes in use.
+func NodeRemove(org, credToUse, node string, force bool, staleAge string, includeNeverHeartbeated bool) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey(credToUse)
+	cliutils.SetWhetherUsingWiotp(credToUse)
 	var nodeOrg string
 	nodeOrg, node = cliutils.TrimOrg(org, node)
 
+	var maxAge time.Duration
+	if includeNeverHeartbeated && staleAge == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("--include-never-heartbeated can only be specified with --stale"))
+	} else if staleAge != "" {
+		var err error
+		if maxAge, err = cliutils.ParseAge(staleAge); err != nil {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, err.Error())
+		}
+	}
+
+	if cliutils.IsGlob(nodeOrg) {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the organization of the node can not be a wildcard pattern: %v", nodeOrg))
+	} else if cliutils.IsGlob(node) || staleAge != "" {
+		var nodes ExchangeNodes
+		cliutils.ExchangeGetAll("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes", cliutils.OrgAndCreds(org, credToUse), []int{200, 404}, &nodes)
+		ids := make([]string, 0, len(nodes.Nodes))
+		kind := msgPrinter.Sprintf("nodes")
+		for id, n := range nodes.Nodes {
+			if staleAge == "" || isNodeStale(n, maxAge, includeNeverHeartbeated) {
+				ids = append(ids, id)
+			}
+		}
+		if staleAge != "" {
+			kind = msgPrinter.Sprintf("nodes that have not heartbeated within %v", staleAge)
+		}
+		cliutils.RemoveMatching(kind, nodeOrg+"/"+node, ids, force, func(id string) error {
+			_, nodeId := cliutils.TrimOrg(nodeOrg, id)
+			if httpCode, err := cliutils.ExchangeDeleteE("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes/"+nodeId, cliutils.OrgAndCreds(org, credToUse), []int{204, 404}); err != nil {
+				return err
+			} else if httpCode == 404 {
+				return errors.New(msgPrinter.Sprintf("node not found"))
+			}
+			return nil
+		})
+		return
+	}
+
 	if !force {
 		cliutils.ConfirmRemove(msgPrinter.Sprintf("Are you sure you want to remove node %v/%v from the Horizon Exchange (should not be done while an edge node is registered with this node id)?", nodeOrg, node))
 	}
@@ -375,11 +618,25 @@ func NodeRemove(org, credToUse, node string, force bool) {
 	}
 }
 
+// Returns true if the node has not heartbeated to the exchange within the age. A node that has never heartbeated is
+// only stale when includeNever is set, and a node whose last heartbeat can not be read is never stale.
+func isNodeStale(n exchange.Device, maxAge time.Duration, includeNever bool) bool {
+	if n.LastHeartbeat == "" {
+		return includeNever
+	}
+	lastHeartbeat, err := time.Parse(cutil.ExchangeTimeFormat, n.LastHeartbeat)
+	if err != nil {
+		cliutils.Verbose(i18n.GetMessagePrinter().Sprintf("the last heartbeat %v of the node can not be read, it is not removed: %v", n.LastHeartbeat, err))
+		return false
+	}
+	return time.Since(lastHeartbeat) > maxAge
+}
+
 func NodeListPolicy(org string, credToUse string, node string) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey(credToUse)
+	cliutils.SetWhetherUsingWiotp(credToUse)
 	var nodeOrg string
 	nodeOrg, node = cliutils.TrimOrg(org, node)
 
@@ -395,19 +652,14 @@ func NodeListPolicy(org string, credToUse string, node string) {
 	cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes"+cliutils.AddSlash(node)+"/policy", cliutils.OrgAndCreds(org, credToUse), []int{200, 404}, &policy)
 
 	// display
-	output, err := cliutils.DisplayAsJson(policy)
-	if err != nil {
-		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal node policy output: %v", err))
-	}
-
-	fmt.Println(output)
+	cliutils.Output(policy, "exchange node listpolicy")
 }
 
 func NodeAddPolicy(org string, credToUse string, node string, jsonFilePath string) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey(credToUse)
+	cliutils.SetWhetherUsingWiotp(credToUse)
 	var nodeOrg string
 	nodeOrg, node = cliutils.TrimOrg(org, node)
 
@@ -448,7 +700,7 @@ func NodeUpdatePolicy(org, credToUse, node string, jsonfile string) {
 	msgPrinter.Printf("Warning: This command is deprecated. It will continue to be supported until the next major release. Please use 'hzn exchange node addpolicy' to update the node policy.")
 	msgPrinter.Println()
 
-	cliutils.SetWhetherUsingApiKey(credToUse)
+	cliutils.SetWhetherUsingWiotp(credToUse)
 	var nodeOrg string
 	nodeOrg, node = cliutils.TrimOrg(org, node)
 
@@ -510,7 +762,7 @@ func NodeRemovePolicy(org, credToUse, node string, force bool) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey(credToUse)
+	cliutils.SetWhetherUsingWiotp(credToUse)
 	var nodeOrg string
 	nodeOrg, node = cliutils.TrimOrg(org, node)
 	if !force {
@@ -548,7 +800,7 @@ type EventLog struct {
 func NodeListErrors(org string, credToUse string, node string, long bool) {
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey(credToUse)
+	cliutils.SetWhetherUsingWiotp(credToUse)
 	var nodeOrg string
 	nodeOrg, node = cliutils.TrimOrg(org, node)
 
@@ -568,11 +820,7 @@ func NodeListErrors(org string, credToUse string, node string, long bool) {
 	}
 
 	if !long {
-		jsonBytes, err := json.MarshalIndent(errorList, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn exchange node listerrors' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(errorList, "hzn exchange node listerrors")
 	} else {
 		long_output := make([]EventLog, len(errorList))
 		for i, v := range errorList {
@@ -590,31 +838,70 @@ func NodeListErrors(org string, credToUse string, node string, long bool) {
 			long_output[i].SourceType = fullV.SourceType
 			long_output[i].Source = fullV.Source
 		}
-		jsonBytes, err := json.MarshalIndent(long_output, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn exchange node listerrors' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(long_output, "hzn exchange node listerrors")
 	}
 }
 
 // NodeListStatus list the node run time status, for example service container status.
-func NodeListStatus(org string, credToUse string, node string) {
+func NodeListStatus(org string, credToUse string, node string, concurrency int, summaryFile string) {
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey(credToUse)
+	cliutils.SetWhetherUsingWiotp(credToUse)
 	var nodeOrg string
 	nodeOrg, node = cliutils.TrimOrg(org, node)
 
+	if node == "" || node == "*" {
+		nodeListStatusAll(org, credToUse, nodeOrg, concurrency, summaryFile)
+		return
+	}
+
 	var nodeStatus ExchangeNodeStatus
 	httpCode := cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes"+cliutils.AddSlash(node)+"/status", cliutils.OrgAndCreds(org, credToUse), []int{200, 404}, &nodeStatus)
 	if httpCode == 404 {
 		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("node status not found for node '%v/%v'.", nodeOrg, node))
 	}
 
-	output := cliutils.MarshalIndent(nodeStatus, "exchange node liststatus")
-	fmt.Println(output)
+	cliutils.Output(nodeStatus, "exchange node liststatus")
+
+}
+
+// Display the run-time status of every node in the org, reading at most concurrency statuses at the same time. The
+// statuses that could be read are displayed even if some could not, the failures are reported at the end.
+func nodeListStatusAll(org string, credToUse string, nodeOrg string, concurrency int, summaryFile string) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	var nodes ExchangeNodes
+	cliutils.ExchangeGetAll("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes", cliutils.OrgAndCreds(org, credToUse), []int{200, 404}, &nodes)
+
+	ids := make([]string, 0, len(nodes.Nodes))
+	for id := range nodes.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var lock sync.Mutex
+	statuses := make(map[string]ExchangeNodeStatus)
+	results := cliutils.RunBulk(ids, concurrency, func(id string) error {
+		var nodeStatus ExchangeNodeStatus
+		_, nodeId := cliutils.TrimOrg(nodeOrg, id)
+		if httpCode, err := cliutils.ExchangeGetE("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes/"+nodeId+"/status", cliutils.OrgAndCreds(org, credToUse), []int{200, 404}, &nodeStatus); err != nil {
+			return err
+		} else if httpCode == 404 {
+			return errors.New(msgPrinter.Sprintf("node status not found"))
+		}
+		lock.Lock()
+		statuses[id] = nodeStatus
+		lock.Unlock()
+		return nil
+	})
 
+	cliutils.Output(statuses, "exchange node liststatus")
+
+	failMsg := ""
+	if err := cliutils.BulkErrors(results); err != nil {
+		failMsg = err.Error()
+	}
+	cliutils.FinishBulk(results, summaryFile, cliutils.HTTP_ERROR, failMsg)
 }
 
 // Verify the node user input for the pattern case. Make sure that the given
@@ -0,0 +1,161 @@
+// +build unit
+
+package exchange
+
+import (
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/cutil"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_NodeRemoveStale(t *testing.T) {
+	h := clitest.New(t)
+	heartbeat := func(age time.Duration) string {
+		return time.Now().Add(-age).UTC().Format(cutil.ExchangeTimeFormat)
+	}
+	h.Exchange.AddResource("orgs/myorg/nodes/edge1", map[string]interface{}{"name": "edge1", "lastHeartbeat": heartbeat(time.Hour)})
+	h.Exchange.AddResource("orgs/myorg/nodes/edge2", map[string]interface{}{"name": "edge2", "lastHeartbeat": heartbeat(40 * 24 * time.Hour)})
+	h.Exchange.AddResource("orgs/myorg/nodes/edge3", map[string]interface{}{"name": "edge3"})
+	h.Exchange.AddResource("orgs/myorg/nodes/edge4", map[string]interface{}{"name": "edge4", "lastHeartbeat": "last tuesday"})
+	h.Exchange.AddResource("orgs/myorg/nodes/other", map[string]interface{}{"name": "other", "lastHeartbeat": heartbeat(40 * 24 * time.Hour)})
+
+	// the nodes that never heartbeated, or whose heartbeat can not be read, are kept
+	res := h.Run(func() { NodeRemove(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "edge*", true, "30d", false) })
+	if res.ExitCode != 0 || !strings.Contains(res.Stdout, "Removed 1 of 1") {
+		t.Fatalf("unexpected exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+	for node, kept := range map[string]bool{"edge1": true, "edge2": false, "edge3": true, "edge4": true, "other": true} {
+		if h.Exchange.GetResource("orgs/myorg/nodes/"+node, &map[string]interface{}{}) != kept {
+			t.Errorf("expected node %v to be kept: %v", node, kept)
+		}
+	}
+
+	res = h.Run(func() { NodeRemove(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "edge*", true, "30d", true) })
+	if res.ExitCode != 0 || !strings.Contains(res.Stdout, "Removed 1 of 1") {
+		t.Fatalf("unexpected exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+	for node, kept := range map[string]bool{"edge1": true, "edge3": false, "edge4": true} {
+		if h.Exchange.GetResource("orgs/myorg/nodes/"+node, &map[string]interface{}{}) != kept {
+			t.Errorf("expected node %v to be kept: %v", node, kept)
+		}
+	}
+
+	// a node that heartbeated recently is not removed
+	if res := h.Run(func() { NodeRemove(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "edge1", true, "30d", false) }); res.ExitCode != cliutils.NOT_FOUND {
+		t.Errorf("expected no stale node, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	if res := h.Run(func() { NodeRemove(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "edge1", true, "soon", false) }); res.ExitCode != cliutils.CLI_INPUT_ERROR {
+		t.Errorf("expected an invalid age, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	if res := h.Run(func() { NodeRemove(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "edge1", true, "", true) }); res.ExitCode != cliutils.CLI_INPUT_ERROR {
+		t.Errorf("expected an input error without --stale, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+}
+
+func Test_NodeList_pagination(t *testing.T) {
+	h := clitest.New(t)
+	for _, id := range []string{"n1", "n2", "n3", "n4", "n5"} {
+		h.Exchange.AddResource("orgs/myorg/nodes/"+id, map[string]string{"name": id})
+	}
+	h.Exchange.SetPageSize(2)
+
+	var names []string
+	res := h.Run(func() { NodeList(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "", true, "", "") })
+	res.JSON(t, &names)
+	if len(names) != 5 {
+		t.Errorf("the nodes of every page should be listed, found %v, stderr: %v", res.Stdout, res.Stderr)
+	} else if reqs := h.Exchange.RequestsTo(http.MethodGet, "orgs/myorg/nodes"); len(reqs) != 3 {
+		t.Errorf("expected 3 pages, found %v", reqs)
+	}
+}
+
+func Test_NodeConfirm(t *testing.T) {
+	h := clitest.New(t)
+	heartbeat := time.Now().Add(-time.Hour).UTC().Format(cutil.ExchangeTimeFormat)
+	h.Exchange.AddResource("orgs/myorg/nodes/edge1", map[string]interface{}{"name": "edge1", "lastHeartbeat": heartbeat})
+	h.Exchange.AddResource("orgs/myorg/nodes/edge2", map[string]interface{}{"name": "edge2"})
+
+	if res := h.Run(func() { NodeConfirm(clitest.FAKE_ORG, "edge1", "tok", "", "", "") }); res.ExitCode != 0 || !strings.Contains(res.Stdout, "Node id and token are valid") {
+		t.Errorf("expected the token to be valid, exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+	if res := h.Run(func() { NodeConfirm(clitest.FAKE_ORG, "", "", "edge1:tok", "", "2h") }); res.ExitCode != 0 || !strings.Contains(res.Stdout, "Node last heartbeated at "+heartbeat) {
+		t.Errorf("expected a recent heartbeat, exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+
+	// the heartbeat is too old, or the node never heartbeated
+	if res := h.Run(func() { NodeConfirm(clitest.FAKE_ORG, "edge1", "tok", "", "", "30m") }); res.ExitCode != cliutils.HEARTBEAT_STALE {
+		t.Errorf("expected a stale heartbeat, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	if res := h.Run(func() { NodeConfirm(clitest.FAKE_ORG, "edge2", "tok", "", "", "30m") }); res.ExitCode != cliutils.HEARTBEAT_STALE {
+		t.Errorf("expected a node that never heartbeated, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+
+	if res := h.Run(func() { NodeConfirm(clitest.FAKE_ORG, "edge3", "tok", "", "", "") }); res.ExitCode != cliutils.NOT_FOUND {
+		t.Errorf("expected the node to be not found, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+
+	// the body of a 401 is not json
+	h.Exchange.Handle(http.MethodGet, "orgs/myorg/nodes/edge1", http.StatusUnauthorized, "invalid credentials")
+	if res := h.Run(func() { NodeConfirm(clitest.FAKE_ORG, "edge1", "badtok", "", "", "") }); res.ExitCode != cliutils.INVALID_CREDS {
+		t.Errorf("expected the token to be not valid, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+
+	if res := h.Run(func() { NodeConfirm(clitest.FAKE_ORG, "edge1", "tok", "edge1:tok", "", "") }); res.ExitCode != cliutils.CLI_INPUT_ERROR {
+		t.Errorf("expected -n to be mutually exclusive with the arguments, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	if res := h.Run(func() { NodeConfirm(clitest.FAKE_ORG, "edge1", "tok", "", "", "soon") }); res.ExitCode != cliutils.CLI_INPUT_ERROR {
+		t.Errorf("expected an invalid age, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+}
+
+func Test_NodeUpdate_mergePatch(t *testing.T) {
+	h := clitest.New(t)
+	h.Exchange.AddResource("orgs/myorg/nodes/edge1", map[string]interface{}{"name": "edge1", "lastUpdated": "2020-01-01T00:00:00.000Z[UTC]",
+		"heartbeatIntervals": map[string]interface{}{"minInterval": 10, "maxInterval": 120, "intervalAdjustment": 5}})
+	h.Exchange.Handle(http.MethodPatch, "orgs/myorg/nodes/edge1", http.StatusCreated, map[string]interface{}{})
+
+	dir, err := ioutil.TempDir("", "clitest-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "node.json")
+	if err := ioutil.WriteFile(file, []byte(`{"heartbeatIntervals": {"maxInterval": 60}}`), 0644); err != nil {
+		t.Fatalf("unable to write %v: %v", file, err)
+	}
+	if res := h.Run(func() { NodeUpdate(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "edge1", file, true, "") }); res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+	// the merged attribute is sent as a normal json patch, the exchange does not take merge patches
+	reqs := h.Exchange.RequestsTo(http.MethodPatch, "orgs/myorg/nodes/edge1")
+	if len(reqs) != 1 || reqs[0].Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("expected a json patch, found %v", reqs)
+	} else if body := string(reqs[0].Body); body != `{"heartbeatIntervals":{"minInterval":10,"maxInterval":60,"intervalAdjustment":5}}` {
+		t.Errorf("the fields that are not in the file should be kept, found %v", body)
+	}
+
+	// without --merge-patch the attribute is patched as it is in the file
+	if res := h.Run(func() { NodeUpdate(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "edge1", file, false, "") }); res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+	if reqs := h.Exchange.RequestsTo(http.MethodPatch, "orgs/myorg/nodes/edge1"); len(reqs) != 2 || reqs[1].Header.Get("Content-Type") != "application/json" {
+		t.Errorf("expected a json patch, found %v", reqs)
+	}
+
+	// the node is not updated when it was changed since the version the update was prepared from
+	if res := h.Run(func() { NodeUpdate(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "edge1", file, false, "2019-01-01T00:00:00.000Z[UTC]") }); res.ExitCode != cliutils.CONFLICT_ERROR {
+		t.Errorf("expected exit code %v, found %v, stderr: %v", cliutils.CONFLICT_ERROR, res.ExitCode, res.Stderr)
+	} else if reqs := h.Exchange.RequestsTo(http.MethodPatch, "orgs/myorg/nodes/edge1"); len(reqs) != 2 {
+		t.Errorf("the changed node should not be updated, found %v", reqs)
+	}
+	if res := h.Run(func() { NodeUpdate(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "edge1", file, false, "2020-01-01T00:00:00.000Z[UTC]") }); res.ExitCode != 0 {
+		t.Errorf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+}
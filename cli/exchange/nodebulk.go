@@ -0,0 +1,289 @@
+package exchange
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/persistence"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The columns of the csv manifest of 'hzn exchange node create-bulk'. Only the id is required.
+var NodeBulkColumns = []string{"id", "token", "name", "nodeType", "arch", "pattern"}
+
+// A node of the manifest of 'hzn exchange node create-bulk'.
+type NodeBulkEntry struct {
+	Id       string `json:"id"`
+	Token    string `json:"token,omitempty"`
+	Name     string `json:"name,omitempty"`
+	NodeType string `json:"nodeType,omitempty"`
+	Arch     string `json:"arch,omitempty"`
+	Pattern  string `json:"pattern,omitempty"`
+}
+
+// The outcome of the creation of a node by 'hzn exchange node create-bulk'. The token is the one the node was created
+// with, it is generated when the manifest does not have one.
+type NodeBulkResult struct {
+	Org      string `json:"org"`
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	NodeType string `json:"nodeType"`
+	Arch     string `json:"arch,omitempty"`
+	Pattern  string `json:"pattern,omitempty"`
+	Token    string `json:"token,omitempty"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Script   string `json:"script,omitempty"`
+}
+
+const (
+	NODE_BULK_CREATED      = "created"
+	NODE_BULK_WOULD_CREATE = "would create"
+	NODE_BULK_FAILED       = "failed"
+)
+
+// NodeCreateBulk creates the nodes of a csv or json manifest in the exchange, at most concurrency at the same time.
+// The csv manifest has a header with the columns of NodeBulkColumns, the json manifest is an array of NodeBulkEntry.
+// The whole manifest is checked, and the patterns that it refers to must exist, before any node is created. A node
+// that already exists is not changed, it fails. The report of the results has the tokens of the nodes that were
+// created. When scriptDir is set, a script that registers the node with its credentials is written there for each
+// node that was created, for the provisioning tools to run on the node. With --dry-run the report has the nodes that
+// would be created, without their tokens.
+func NodeCreateBulk(org, userPw, manifestFile string, concurrency int, scriptDir string, summaryFile string) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	cliutils.SetWhetherUsingWiotp(userPw)
+	exchUrl := cliutils.GetExchangeUrl()
+
+	entries, err := parseNodeBulkManifest(manifestFile, cliutils.ReadFile(manifestFile))
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("invalid node manifest %v: %v", manifestFile, err))
+	}
+	results, err := nodeBulkResults(org, entries)
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("invalid node manifest %v: %v", manifestFile, err))
+	}
+
+	// check the patterns once, rather than creating nodes that cannot register
+	patterns := map[string]bool{}
+	for _, r := range results {
+		if r.Pattern != "" && !patterns[r.Pattern] {
+			patterns[r.Pattern] = true
+			patOrg, pat := cliutils.TrimOrg(r.Org, r.Pattern)
+			var output ExchangePatterns
+			if httpCode := cliutils.ExchangeGet("Exchange", exchUrl, "orgs/"+patOrg+"/patterns/"+pat, cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &output); httpCode == 404 {
+				cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("pattern '%v' not found in the Exchange, no nodes were created.", r.Pattern))
+			}
+		}
+	}
+
+	if scriptDir != "" && !cliutils.IsDryRun() {
+		if err := os.MkdirAll(scriptDir, 0700); err != nil {
+			cliutils.Fatal(cliutils.FILE_IO_ERROR, msgPrinter.Sprintf("unable to create the directory %v: %v", scriptDir, err))
+		}
+	}
+
+	index := map[string]int{}
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.Org + "/" + r.Id
+		index[ids[i]] = i
+	}
+	// every operation only changes the result of its own node
+	bulkResults := cliutils.RunBulk(ids, concurrency, func(id string) error {
+		result := &results[index[id]]
+
+		err := createBulkNode(exchUrl, org, userPw, result)
+		if err == nil && scriptDir != "" && !cliutils.IsDryRun() {
+			result.Script = filepath.Join(scriptDir, result.Id+".sh")
+			if writeErr := ioutil.WriteFile(result.Script, []byte(nodeRegistrationScript(exchUrl, *result)), 0700); writeErr != nil {
+				result.Script = ""
+				err = errors.New(msgPrinter.Sprintf("the node was created, but its registration script could not be written: %v", writeErr))
+			}
+		}
+		if err != nil {
+			result.Error = err.Error()
+			if result.Status == NODE_BULK_FAILED {
+				result.Token = ""
+			}
+		}
+		return err
+	})
+
+	cliutils.Output(results, "exchange node create-bulk")
+
+	failMsg := ""
+	if err := cliutils.BulkErrors(bulkResults); err != nil {
+		failMsg = err.Error()
+	}
+	cliutils.FinishBulk(bulkResults, summaryFile, cliutils.HTTP_ERROR, failMsg)
+}
+
+// Create a node of the manifest, unless it already exists. The status of the result is set. The PUT of the exchange
+// creates or replaces a node and it has no create-only option, so a node that another user creates between the GET
+// and the PUT is replaced.
+func createBulkNode(exchUrl, org, userPw string, result *NodeBulkResult) error {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	result.Status = NODE_BULK_FAILED
+	var nodes ExchangeNodes
+	httpCode, err := cliutils.ExchangeGetE("Exchange", exchUrl, "orgs/"+result.Org+"/nodes/"+result.Id, cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &nodes)
+	if err != nil {
+		return err
+	} else if httpCode == 200 {
+		return errors.New(msgPrinter.Sprintf("the node already exists in the Exchange, it was not changed"))
+	} else if cliutils.IsDryRun() {
+		result.Status = NODE_BULK_WOULD_CREATE
+		result.Token = ""
+		return nil
+	}
+
+	if result.Token == "" {
+		if result.Token, err = cutil.SecureRandomString(); err != nil {
+			return errors.New(msgPrinter.Sprintf("failed to generate a random token: %v", err))
+		}
+	}
+	putNodeReq := exchange.PutDeviceRequest{Token: result.Token, Name: result.Name, NodeType: result.NodeType, Pattern: result.Pattern, SoftwareVersions: make(map[string]string), PublicKey: []byte(""), Arch: result.Arch}
+	if _, err := cliutils.ExchangePutPostE("Exchange", http.MethodPut, exchUrl, "orgs/"+result.Org+"/nodes/"+result.Id, cliutils.OrgAndCreds(org, userPw), []int{201}, putNodeReq, nil); err != nil {
+		return err
+	}
+	result.Status = NODE_BULK_CREATED
+	return nil
+}
+
+// Parse a csv or json manifest. It is json if the file name ends with .json or its content starts with [.
+func parseNodeBulkManifest(manifestFile string, content []byte) ([]NodeBulkEntry, error) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	entries := []NodeBulkEntry{}
+	if strings.HasSuffix(strings.ToLower(manifestFile), ".json") || bytes.HasPrefix(bytes.TrimSpace(content), []byte("[")) {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&entries); err != nil {
+			return nil, err
+		}
+		return entries, nil
+	}
+
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return entries, nil
+	} else if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{}
+	for i, h := range header {
+		name := ""
+		for _, c := range NodeBulkColumns {
+			if strings.EqualFold(strings.TrimSpace(h), c) {
+				name = c
+			}
+		}
+		if name == "" {
+			return nil, errors.New(msgPrinter.Sprintf("unknown column '%v', the columns are: %v", h, strings.Join(NodeBulkColumns, ", ")))
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["id"]; !ok {
+		return nil, errors.New(msgPrinter.Sprintf("the header must have the id column"))
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		value := func(column string) string {
+			if i, ok := columns[column]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		entries = append(entries, NodeBulkEntry{Id: value("id"), Token: value("token"), Name: value("name"), NodeType: value("nodeType"), Arch: value("arch"), Pattern: value("pattern")})
+	}
+	return entries, nil
+}
+
+// Check the entries of the manifest, and return the results of the nodes that they would create, in the order of
+// the manifest.
+func nodeBulkResults(org string, entries []NodeBulkEntry) ([]NodeBulkResult, error) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if len(entries) == 0 {
+		return nil, errors.New(msgPrinter.Sprintf("it has no nodes"))
+	}
+	results := make([]NodeBulkResult, 0, len(entries))
+	seen := map[string]bool{}
+	for i, e := range entries {
+		if e.Id == "" {
+			return nil, errors.New(msgPrinter.Sprintf("node %d has no id", i+1))
+		} else if strings.Contains(e.Id, ":") || strings.Count(e.Id, "/") > 1 {
+			return nil, errors.New(msgPrinter.Sprintf("the id of node %d is not valid: %v", i+1, e.Id))
+		}
+		nodeOrg, nodeId := cliutils.TrimOrg(org, e.Id)
+		if seen[nodeOrg+"/"+nodeId] {
+			return nil, errors.New(msgPrinter.Sprintf("node %v/%v is in it more than once", nodeOrg, nodeId))
+		}
+		seen[nodeOrg+"/"+nodeId] = true
+
+		nodeType := e.NodeType
+		if nodeType == "" {
+			nodeType = persistence.DEVICE_TYPE_DEVICE
+		} else if nodeType != persistence.DEVICE_TYPE_DEVICE && nodeType != persistence.DEVICE_TYPE_CLUSTER {
+			return nil, errors.New(msgPrinter.Sprintf("wrong node type of node %v: %v. It must be 'device' or 'cluster'.", nodeId, nodeType))
+		}
+		name := e.Name
+		if name == "" {
+			name = nodeId
+		}
+		pattern := ""
+		if e.Pattern != "" {
+			if strings.Count(e.Pattern, "/") > 1 {
+				return nil, errors.New(msgPrinter.Sprintf("the pattern of node %v is not valid: %v", nodeId, e.Pattern))
+			}
+			pattern = cliutils.AddOrg(nodeOrg, e.Pattern)
+		}
+
+		results = append(results, NodeBulkResult{Org: nodeOrg, Id: nodeId, Name: name, NodeType: nodeType, Arch: e.Arch, Pattern: pattern, Token: e.Token})
+	}
+	return results, nil
+}
+
+// The script that registers a node that create-bulk created, with its credentials.
+func nodeRegistrationScript(exchUrl string, r NodeBulkResult) string {
+	script := &bytes.Buffer{}
+	fmt.Fprintf(script, "#!/bin/sh\n")
+	fmt.Fprintf(script, "# Register this edge node as node %v/%v of the Horizon Exchange.\n", r.Org, r.Id)
+	fmt.Fprintf(script, "# Written by 'hzn exchange node create-bulk', it has the token of the node.\n")
+	fmt.Fprintf(script, "set -e\n")
+	fmt.Fprintf(script, "export HZN_EXCHANGE_URL=%v\n", shellQuote(exchUrl))
+	fmt.Fprintf(script, "export HZN_ORG_ID=%v\n", shellQuote(r.Org))
+	fmt.Fprintf(script, "export HZN_EXCHANGE_NODE_AUTH=%v\n", shellQuote(r.Id+":"+r.Token))
+	register := []string{"hzn", "register", "--name", shellQuote(r.Name)}
+	if r.Pattern != "" {
+		register = append(register, "--pattern", shellQuote(r.Pattern))
+	}
+	fmt.Fprintf(script, "%v \"$@\"\n", strings.Join(register, " "))
+	return script.String()
+}
+
+// Quote a value for a shell, in single quotes.
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,111 @@
+// +build unit
+
+package exchange
+
+import (
+	"encoding/json"
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	exchangeapi "github.com/open-horizon/anax/exchange"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_NodeCreateBulk(t *testing.T) {
+	h := clitest.New(t)
+
+	dir, err := ioutil.TempDir("", "clitest-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	h.Exchange.AddResource("orgs/myorg/patterns/netspeed", map[string]interface{}{"label": "netspeed"})
+	h.Exchange.AddResource("orgs/myorg/nodes/edge3", map[string]interface{}{"name": "edge3", "token": "oldtoken"})
+	manifest := filepath.Join(dir, "nodes.csv")
+	if err := ioutil.WriteFile(manifest, []byte("# the nodes of the store\nid,token,name,pattern,arch\nedge1,tok1,Store 1,netspeed,amd64\nedge2,,,,arm64\nedge3,tok3,,,\n"), 0644); err != nil {
+		t.Fatalf("unable to write %v: %v", manifest, err)
+	}
+	scriptDir := filepath.Join(dir, "scripts")
+	summaryFile := filepath.Join(dir, "summary.json")
+
+	res := h.Run(func() { NodeCreateBulk(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, manifest, 2, scriptDir, summaryFile) })
+	if res.ExitCode != cliutils.PARTIAL_SUCCESS || !strings.Contains(res.Stderr, "edge3: the node already exists") {
+		t.Fatalf("expected edge3 to fail, exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+	results := []NodeBulkResult{}
+	res.JSON(t, &results)
+	if len(results) != 3 || results[0].Status != NODE_BULK_CREATED || results[1].Status != NODE_BULK_CREATED || results[2].Status != NODE_BULK_FAILED {
+		t.Fatalf("unexpected results: %v", results)
+	}
+	if results[1].Token == "" || results[2].Token != "" {
+		t.Errorf("expected a generated token for edge2 and no token for edge3: %v", results)
+	}
+
+	node := exchangeapi.PutDeviceRequest{}
+	if !h.Exchange.GetResource("orgs/myorg/nodes/edge1", &node) || node.Token != "tok1" || node.Name != "Store 1" || node.Pattern != "myorg/netspeed" || node.Arch != "amd64" || node.NodeType != "device" {
+		t.Errorf("unexpected node edge1: %v", node)
+	}
+	if !h.Exchange.GetResource("orgs/myorg/nodes/edge2", &node) || node.Token != results[1].Token || node.Name != "edge2" || node.Pattern != "" {
+		t.Errorf("unexpected node edge2: %v", node)
+	}
+	if !h.Exchange.GetResource("orgs/myorg/nodes/edge3", &node) || node.Token != "oldtoken" {
+		t.Errorf("an existing node should not be changed: %v", node)
+	}
+
+	script, err := ioutil.ReadFile(filepath.Join(scriptDir, "edge1.sh"))
+	if err != nil || results[0].Script != filepath.Join(scriptDir, "edge1.sh") {
+		t.Fatalf("expected the registration script of edge1: %v", err)
+	}
+	for _, expected := range []string{"export HZN_ORG_ID='myorg'", "export HZN_EXCHANGE_NODE_AUTH='edge1:tok1'", "hzn register --name 'Store 1' --pattern 'myorg/netspeed'"} {
+		if !strings.Contains(string(script), expected) {
+			t.Errorf("the script should contain %q: %s", expected, script)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(scriptDir, "edge3.sh")); !os.IsNotExist(err) {
+		t.Errorf("no script should be written for a node that was not created: %v", err)
+	}
+	summary := cliutils.BulkSummary{}
+	if b, err := ioutil.ReadFile(summaryFile); err != nil || json.Unmarshal(b, &summary) != nil || summary.Succeeded != 2 || summary.Failed != 1 {
+		t.Errorf("unexpected summary %v: %v", summary, err)
+	}
+
+	// under dry run the nodes are only reported, without their tokens
+	dryRunManifest := filepath.Join(dir, "dryrun.csv")
+	if err := ioutil.WriteFile(dryRunManifest, []byte("id,token\nedge6,tok6\nedge3,\n"), 0644); err != nil {
+		t.Fatalf("unable to write %v: %v", dryRunManifest, err)
+	}
+	res = h.Run(func() {
+		*cliutils.Opts.IsDryRun = true
+		NodeCreateBulk(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, dryRunManifest, 0, scriptDir, "")
+	})
+	results = []NodeBulkResult{}
+	res.JSON(t, &results)
+	if len(results) != 2 || results[0].Status != NODE_BULK_WOULD_CREATE || results[0].Token != "" || results[1].Status != NODE_BULK_FAILED {
+		t.Errorf("unexpected dry run results: %v", results)
+	} else if h.Exchange.GetResource("orgs/myorg/nodes/edge6", &node) {
+		t.Errorf("no node should be created under dry run")
+	} else if _, err := os.Stat(filepath.Join(scriptDir, "edge6.sh")); !os.IsNotExist(err) {
+		t.Errorf("no script should be written under dry run: %v", err)
+	}
+
+	// the manifest is checked before any node is created
+	jsonManifest := filepath.Join(dir, "nodes.json")
+	if err := ioutil.WriteFile(jsonManifest, []byte(`[{"id":"edge4"},{"id":"edge5","pattern":"unknown"}]`), 0644); err != nil {
+		t.Fatalf("unable to write %v: %v", jsonManifest, err)
+	}
+	if res := h.Run(func() { NodeCreateBulk(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, jsonManifest, 0, "", "") }); res.ExitCode != cliutils.NOT_FOUND {
+		t.Errorf("expected the pattern not to be found, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if h.Exchange.GetResource("orgs/myorg/nodes/edge4", &node) {
+		t.Errorf("no node should be created when the manifest refers to a pattern that does not exist")
+	}
+	if err := ioutil.WriteFile(jsonManifest, []byte(`[{"id":"edge4"},{"id":"edge4","nodeType":"cluster"}]`), 0644); err != nil {
+		t.Fatalf("unable to write %v: %v", jsonManifest, err)
+	}
+	if res := h.Run(func() { NodeCreateBulk(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, jsonManifest, 0, "", "") }); res.ExitCode != cliutils.CLI_INPUT_ERROR || !strings.Contains(res.Stderr, "more than once") {
+		t.Errorf("expected a duplicate node, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+}
@@ -1,8 +1,6 @@
 package exchange
 
 import (
-	"encoding/json"
-	"fmt"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/i18n"
@@ -39,7 +37,7 @@ func OrgList(org, userPwCreds, theOrg string, long bool) {
 
 	// get orgs
 	var orgs ExchangeOrgs
-	httpCode := cliutils.ExchangeGet("Exchange", exchUrlBase, orgurl, cliutils.OrgAndCreds(org, userPwCreds), []int{200, 404}, &orgs)
+	httpCode := cliutils.ExchangeGetAll("Exchange", exchUrlBase, orgurl, cliutils.OrgAndCreds(org, userPwCreds), []int{200, 404}, &orgs)
 	if httpCode == 404 {
 		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("org '%s' not found.", theOrg))
 	}
@@ -52,14 +50,9 @@ func OrgList(org, userPwCreds, theOrg string, long bool) {
 			organizations = append(organizations, o)
 		}
 
-		jsonBytes, err := json.MarshalIndent(organizations, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'exchange org list' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(organizations, "exchange org list")
 	} else {
-		output := cliutils.MarshalIndent(orgs.Orgs, "exchange orgs list")
-		fmt.Println(output)
+		cliutils.Output(orgs.Orgs, "exchange orgs list")
 	}
 }
 
@@ -68,7 +61,7 @@ func OrgCreate(org, userPwCreds, theOrg string, label string, desc string, tags
 	msgPrinter := i18n.GetMessagePrinter()
 
 	// get credentials
-	cliutils.SetWhetherUsingApiKey(userPwCreds)
+	cliutils.SetWhetherUsingWiotp(userPwCreds)
 
 	// check if the agbot specified by -a exist or not
 	CheckAgbot(org, userPwCreds, agbot)
@@ -156,20 +149,20 @@ func OrgUpdate(org, userPwCreds, theOrg string, label string, desc string, tags
 	// if --label is specified, update it
 	if label != "" {
 		newOrgLabel := exchange.Organization{Label: label}
-		cliutils.ExchangePutPost("Exchange", http.MethodPatch, cliutils.GetExchangeUrl(), "orgs/"+theOrg, cliutils.OrgAndCreds(org, userPwCreds), []int{201}, newOrgLabel, nil)
+		cliutils.ExchangePatch("Exchange", cliutils.GetExchangeUrl(), "orgs/"+theOrg, cliutils.OrgAndCreds(org, userPwCreds), []int{201}, newOrgLabel, nil)
 	}
 
 	// if --description is specified, update it
 	if desc != "" {
 		newOrgDesc := exchange.Organization{Description: desc}
-		cliutils.ExchangePutPost("Exchange", http.MethodPatch, cliutils.GetExchangeUrl(), "orgs/"+theOrg, cliutils.OrgAndCreds(org, userPwCreds), []int{201}, newOrgDesc, nil)
+		cliutils.ExchangePatch("Exchange", cliutils.GetExchangeUrl(), "orgs/"+theOrg, cliutils.OrgAndCreds(org, userPwCreds), []int{201}, newOrgDesc, nil)
 	}
 
 	// convert the input tags into map[string]string
 	orgTags := convertTags(tags, true)
 	if orgTags != nil {
 		newTags := PatchOrgTags{Tags: orgTags}
-		cliutils.ExchangePutPost("Exchange", http.MethodPatch, cliutils.GetExchangeUrl(), "orgs/"+theOrg, cliutils.OrgAndCreds(org, userPwCreds), []int{201}, newTags, nil)
+		cliutils.ExchangePatch("Exchange", cliutils.GetExchangeUrl(), "orgs/"+theOrg, cliutils.OrgAndCreds(org, userPwCreds), []int{201}, newTags, nil)
 	}
 
 	// do nothing if they are -1
@@ -177,7 +170,7 @@ func OrgUpdate(org, userPwCreds, theOrg string, label string, desc string, tags
 		newMin, newMax, newAdjust := getNewHeartbeatAttributes(min, max, adjust, orgs.Orgs[theOrg].HeartbeatIntv)
 		orgHb := exchange.HeartbeatIntervals{MinInterval: newMin, MaxInterval: newMax, IntervalAdjustment: newAdjust}
 		newOrgHeartbeaat := exchange.Organization{HeartbeatIntv: &orgHb}
-		cliutils.ExchangePutPost("Exchange", http.MethodPatch, cliutils.GetExchangeUrl(), "orgs/"+theOrg, cliutils.OrgAndCreds(org, userPwCreds), []int{201}, newOrgHeartbeaat, nil)
+		cliutils.ExchangePatch("Exchange", cliutils.GetExchangeUrl(), "orgs/"+theOrg, cliutils.OrgAndCreds(org, userPwCreds), []int{201}, newOrgHeartbeaat, nil)
 	}
 
 	// do nothing if maxNodes is -1
@@ -186,7 +179,7 @@ func OrgUpdate(org, userPwCreds, theOrg string, label string, desc string, tags
 	} else if maxNodes > -1 {
 		limits := exchange.OrgLimits{MaxNodes: maxNodes}
 		newOrgLimits := exchange.Organization{Limits: &limits}
-		cliutils.ExchangePutPost("Exchange", http.MethodPatch, cliutils.GetExchangeUrl(), "orgs/"+theOrg, cliutils.OrgAndCreds(org, userPwCreds), []int{201}, newOrgLimits, nil)
+		cliutils.ExchangePatch("Exchange", cliutils.GetExchangeUrl(), "orgs/"+theOrg, cliutils.OrgAndCreds(org, userPwCreds), []int{201}, newOrgLimits, nil)
 	}
 
 	msgPrinter.Printf("Organization %v is successfully updated.", theOrg)
@@ -207,7 +200,7 @@ func OrgDel(org, userPwCreds, theOrg, agbot string, force bool) {
 	CheckAgbot(org, userPwCreds, agbot)
 
 	// "Are you sure?" prompt
-	cliutils.SetWhetherUsingApiKey(userPwCreds)
+	cliutils.SetWhetherUsingWiotp(userPwCreds)
 	if !force {
 		cliutils.ConfirmRemove(msgPrinter.Sprintf("Warning: this will also delete all Exchange resources owned by this org (nodes, services, patterns, etc). Are you sure you want to remove user %v from the Horizon Exchange and the MMS?", theOrg))
 	}
@@ -373,12 +366,12 @@ func GetDefaultAgbot(org, userPwCreds string) string {
 
 	// get all the orgs
 	var orgs ExchangeOrgs
-	cliutils.ExchangeGet("Exchange", exchUrlBase, "orgs", cliutils.OrgAndCreds(org, userPwCreds), []int{200}, &orgs)
+	cliutils.ExchangeGetAll("Exchange", exchUrlBase, "orgs", cliutils.OrgAndCreds(org, userPwCreds), []int{200}, &orgs)
 
 	// for each org find agbots
 	for o := range orgs.Orgs {
 		var resp ExchangeAgbots
-		httpCode := cliutils.ExchangeGet("Exchange", exchUrlBase, "orgs/"+o+"/agbots", cliutils.OrgAndCreds(org, userPwCreds), []int{200, 404}, &resp)
+		httpCode := cliutils.ExchangeGetAll("Exchange", exchUrlBase, "orgs/"+o+"/agbots", cliutils.OrgAndCreds(org, userPwCreds), []int{200, 404}, &resp)
 		if httpCode == 200 {
 			for a := range resp.Agbots {
 				return a
@@ -2,9 +2,11 @@ package exchange
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/open-horizon/anax/cli/cliconfig"
 	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/cli/schema"
 	"github.com/open-horizon/anax/common"
 	"github.com/open-horizon/anax/cutil"
 	"github.com/open-horizon/anax/exchange"
@@ -13,6 +15,7 @@ import (
 	"github.com/open-horizon/rsapss-tool/sign"
 	"github.com/open-horizon/rsapss-tool/verify"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -69,56 +72,63 @@ type PatternInput struct {
 	UserInput          []policy.UserInput           `json:"userInput,omitempty"`
 }
 
+// The columns of the csv output of 'hzn exchange pattern list', when --columns is not specified.
+var PatternListColumns = []string{"id", "label", "owner", "public", "description", "lastUpdated"}
+
 // List the pattern resources for the given org.
 // The userPw can be the userId:password auth or the nodeId:token auth.
-func PatternList(org string, userPw string, pattern string, namesOnly bool) {
+func PatternList(org string, userPw string, pattern string, namesOnly bool, output string, columns string) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
 	//check for ExchangeUrl early on
 	var exchUrl = cliutils.GetExchangeUrl()
 
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	var patOrg string
 	patOrg, pattern = cliutils.TrimOrg(org, pattern)
 	if pattern == "*" {
 		pattern = ""
 	}
-	if namesOnly && pattern == "" {
+	if cliutils.IsCSVOutput(output) {
+		var patterns ExchangePatterns
+		httpCode := cliutils.ExchangeGetAll("Exchange", exchUrl, "orgs/"+patOrg+"/patterns"+cliutils.AddSlash(pattern), cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &patterns)
+		if httpCode == 404 && pattern != "" {
+			cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("pattern '%s' not found in org %s", pattern, patOrg))
+		}
+		objects := make(map[string]interface{})
+		for id, p := range patterns.Patterns {
+			objects[id] = p
+		}
+		cliutils.OutputCSV(cliutils.CSVRecords(objects), cliutils.ParseColumns(columns, PatternListColumns))
+	} else if namesOnly && pattern == "" {
 		// Only display the names
 		var resp ExchangePatterns
-		cliutils.ExchangeGet("Exchange", exchUrl, "orgs/"+patOrg+"/patterns"+cliutils.AddSlash(pattern), cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &resp)
+		cliutils.ExchangeGetAll("Exchange", exchUrl, "orgs/"+patOrg+"/patterns"+cliutils.AddSlash(pattern), cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &resp)
 		patterns := []string{} // this is important (instead of leaving it nil) so json marshaling displays it as [] instead of null
 		for p := range resp.Patterns {
 			patterns = append(patterns, p)
 		}
-		jsonBytes, err := json.MarshalIndent(patterns, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'exchange pattern list' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(patterns, "exchange pattern list")
 	} else {
 		// Display the full resources
 		var patterns ExchangePatterns
-		httpCode := cliutils.ExchangeGet("Exchange", exchUrl, "orgs/"+patOrg+"/patterns"+cliutils.AddSlash(pattern), cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &patterns)
+		httpCode := cliutils.ExchangeGetAll("Exchange", exchUrl, "orgs/"+patOrg+"/patterns"+cliutils.AddSlash(pattern), cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &patterns)
 		if httpCode == 404 && pattern != "" {
 			cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("pattern '%s' not found in org %s", pattern, patOrg))
 		}
-		jsonBytes, err := json.MarshalIndent(patterns.Patterns, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn exchange pattern list' output: %v", err))
-		}
-		fmt.Println(string(jsonBytes))
+		cliutils.Output(patterns.Patterns, "hzn exchange pattern list")
 	}
 }
 
-// This function updates an attribute for the given pattern
-func PatternUpdate(org string, credToUse string, pattern string, filePath string) {
+// This function updates an attribute for the given pattern. If ifLastUpdated is set, the pattern is only updated if
+// its lastUpdated time in the exchange is still that one.
+func PatternUpdate(org string, credToUse string, pattern string, filePath string, ifLastUpdated string) {
 
 	//check for ExchangeUrl early on
 	var exchUrl = cliutils.GetExchangeUrl()
 
-	cliutils.SetWhetherUsingApiKey(credToUse)
+	cliutils.SetWhetherUsingWiotp(credToUse)
 	var patOrg string
 	patOrg, pattern = cliutils.TrimOrg(org, pattern)
 
@@ -128,12 +138,15 @@ func PatternUpdate(org string, credToUse string, pattern string, filePath string
 	//Read in the file
 	attribute := cliconfig.ReadJsonFileWithLocalConfig(filePath)
 
-	//verify that the pattern exists
+	//verify that the pattern exists, and that it was not changed since the version the update was prepared from
 	var exchPatterns ExchangePatterns
 	httpCode := cliutils.ExchangeGet("Exchange", exchUrl, "orgs/"+patOrg+"/patterns"+cliutils.AddSlash(pattern), cliutils.OrgAndCreds(org, credToUse), []int{200, 404}, &exchPatterns)
 	if httpCode == 404 {
 		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("Pattern %s not found in org %s", pattern, patOrg))
 	}
+	if ifLastUpdated != "" {
+		cliutils.CheckResourceUnchanged(msgPrinter.Sprintf("Pattern %v/%v", patOrg, pattern), ifLastUpdated, getPatternLastUpdated(exchPatterns))
+	}
 
 	findPatchType := make(map[string]interface{})
 
@@ -161,11 +174,20 @@ func PatternUpdate(org string, credToUse string, pattern string, filePath string
 	if err != nil {
 		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal attribute input %s: %v", attribute, err))
 	}
+
 	cliutils.ExchangePutPost("Exchange", http.MethodPatch, exchUrl, "orgs/"+patOrg+"/patterns"+cliutils.AddSlash(pattern), cliutils.OrgAndCreds(org, credToUse), []int{201}, patch, nil)
 	msgPrinter.Printf("Pattern %v/%v updated in the Horizon Exchange", patOrg, pattern)
 	msgPrinter.Println()
 }
 
+// Returns the lastUpdated time of the (only) pattern in the exchange output.
+func getPatternLastUpdated(patterns ExchangePatterns) string {
+	for _, p := range patterns.Patterns {
+		return p.LastUpdated
+	}
+	return ""
+}
+
 // Take the deployment overrides field, which we have told the json unmarshaller was unknown type (so we can handle both escaped string and struct)
 // and turn it into the DeploymentOverrides struct we really want.
 func ConvertToDeploymentOverrides(deployment interface{}) *DeploymentOverrides {
@@ -211,9 +233,10 @@ func PatternPublish(org, userPw, jsonFilePath, keyFilePath, pubKeyFilePath, patN
 	//Get ExchangeUrl value early on
 	var exchUrl = cliutils.GetExchangeUrl()
 
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	// Read in the pattern metadata
 	newBytes := cliconfig.ReadJsonFileWithLocalConfig(jsonFilePath)
+	schema.Check(schema.KIND_PATTERN, jsonFilePath, newBytes)
 	var patFile common.PatternFile
 	err := json.Unmarshal(newBytes, &patFile)
 	if err != nil {
@@ -233,6 +256,8 @@ func PatternPublish(org, userPw, jsonFilePath, keyFilePath, pubKeyFilePath, patN
 	}
 
 	keyVerified := false
+	var signedDeployment []byte
+	var signature string
 	// Loop thru the services array and the servicesVersions array and sign the deployment_overrides fields
 	if patFile.Services != nil && len(patFile.Services) > 0 {
 		patInput.Services = make([]ServiceReference, len(patFile.Services))
@@ -283,11 +308,20 @@ func PatternPublish(org, userPw, jsonFilePath, keyFilePath, pubKeyFilePath, patN
 					if err != nil {
 						cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("problem signing the deployment_overrides string with %s: %v", keyFilePath, err))
 					}
+					signedDeployment, signature = deployment, patInput.Services[i].ServiceVersions[j].DeploymentOverridesSignature
 				}
 			}
 		}
 	}
 
+	// The agent verifies the overrides with the public key that is stored with the pattern
+	if signedDeployment != nil && pubKeyFilePath != "" {
+		VerifySigningKeyPair(pubKeyFilePath, keyFilePath, signature, signedDeployment)
+	}
+
+	// The agent can only make agreements for the services that are in the exchange
+	checkPatternServices(org, userPw, exchUrl, patInput.Services)
+
 	// Create or update resource in the exchange
 	var exchId string
 	if patName != "" {
@@ -302,10 +336,13 @@ func PatternPublish(org, userPw, jsonFilePath, keyFilePath, pubKeyFilePath, patN
 	// replace the unwanted charactors from the id with '-'
 	exchId = cutil.FormExchangeId(exchId)
 
-	var output string
-	httpCode := cliutils.ExchangeGet("Exchange", exchUrl, "orgs/"+patFile.Org+"/patterns/"+exchId, cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &output)
+	var exchPatterns ExchangePatterns
+	httpCode := cliutils.ExchangeGet("Exchange", exchUrl, "orgs/"+patFile.Org+"/patterns/"+exchId, cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &exchPatterns)
 	if httpCode == 200 {
-		// Pattern exists, update it
+		// Pattern exists, show what changes and update it
+		if current, ok := exchPatterns.Patterns[patFile.Org+"/"+exchId]; ok {
+			printPatternDiff(patFile.Org+"/"+exchId, current, patInput)
+		}
 		msgPrinter.Printf("Updating %s in the Exchange...", exchId)
 		msgPrinter.Println()
 		cliutils.ExchangePutPost("Exchange", http.MethodPut, exchUrl, "orgs/"+patFile.Org+"/patterns/"+exchId, cliutils.OrgAndCreds(org, userPw), []int{201}, patInput, nil)
@@ -330,6 +367,87 @@ func PatternPublish(org, userPw, jsonFilePath, keyFilePath, pubKeyFilePath, patN
 	}
 }
 
+// Exits with an error if a version of a service of the pattern is not in the exchange for the architecture of the
+// service. A pattern service with the * architecture, or none, needs the version for one architecture.
+func checkPatternServices(org string, userPw string, exchUrl string, services []ServiceReference) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	missing := []string{}
+	for _, svc := range services {
+		svcOrg := svc.ServiceOrg
+		if svcOrg == "" {
+			svcOrg = org
+		}
+		anyArch := svc.ServiceArch == "" || svc.ServiceArch == "*"
+
+		// only get the services of the url, and of the arch, from the exchange
+		route := "orgs/" + svcOrg + "/services?url=" + url.QueryEscape(svc.ServiceURL)
+		if !anyArch {
+			route += "&arch=" + url.QueryEscape(svc.ServiceArch)
+		}
+		var resp exchange.GetServicesResponse
+		cliutils.ExchangeGet("Exchange", exchUrl, route, cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &resp)
+
+		for _, choice := range svc.ServiceVersions {
+			found := false
+			for _, sd := range resp.Services {
+				if sd.URL == svc.ServiceURL && sd.Version == choice.Version && (anyArch || sd.Arch == svc.ServiceArch) {
+					found = true
+					break
+				}
+			}
+			if !found && anyArch {
+				missing = append(missing, msgPrinter.Sprintf("%v/%v version %v", svcOrg, svc.ServiceURL, choice.Version))
+			} else if !found {
+				missing = append(missing, msgPrinter.Sprintf("%v/%v version %v for arch %v", svcOrg, svc.ServiceURL, choice.Version, svc.ServiceArch))
+			}
+		}
+	}
+	if len(missing) > 0 {
+		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("the pattern references services that are not in the Exchange, publish them first: %v", strings.Join(missing, ", ")))
+	}
+}
+
+// Print the changes that publishing the pattern makes to the pattern in the exchange. The signatures of the deployment
+// overrides are not compared, because the overrides are signed again each time the pattern is published.
+func printPatternDiff(patId string, current PatternOutput, patInput PatternInput) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	// compare the same fields, in the same format
+	before, after := PatternInput{}, patInput
+	if currentBytes, err := json.Marshal(current); err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal pattern %v: %v", patId, err))
+	} else if err := json.Unmarshal(currentBytes, &before); err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal pattern %v: %v", patId, err))
+	}
+	after.Services = make([]ServiceReference, len(patInput.Services))
+	for i := range patInput.Services {
+		after.Services[i] = patInput.Services[i]
+		after.Services[i].ServiceVersions = append([]ServiceChoice{}, patInput.Services[i].ServiceVersions...)
+	}
+	for _, pat := range []*PatternInput{&before, &after} {
+		for i := range pat.Services {
+			for j := range pat.Services[i].ServiceVersions {
+				pat.Services[i].ServiceVersions[j].DeploymentOverridesSignature = ""
+			}
+		}
+	}
+
+	diff, err := cliutils.DiffJson(before, after)
+	if err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to compare pattern %v: %v", patId, err))
+	} else if diff == nil {
+		msgPrinter.Printf("Pattern %v in the Exchange is unchanged, except for the signatures.", patId)
+		msgPrinter.Println()
+		return
+	}
+	msgPrinter.Printf("Changes to pattern %v in the Exchange:", patId)
+	msgPrinter.Println()
+	fmt.Println(strings.Join(diff, "\n"))
+}
+
 // Verify that the deployment_overrides_signature is valid for the given key.
 // The userPw can be the userId:password auth or the nodeId:token auth.
 func PatternVerify(org, userPw, pattern, keyFilePath string) {
@@ -339,7 +457,7 @@ func PatternVerify(org, userPw, pattern, keyFilePath string) {
 	//check for ExchangeUrl early on
 	var exchUrl = cliutils.GetExchangeUrl()
 
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	var patorg string
 	patorg, pattern = cliutils.TrimOrg(org, pattern)
 	// Get pattern resource from exchange
@@ -394,9 +512,30 @@ func PatternRemove(org, userPw, pattern string, force bool) {
 	//check for ExchangeUrl early on
 	var exchUrl = cliutils.GetExchangeUrl()
 
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	var patorg string
 	patorg, pattern = cliutils.TrimOrg(org, pattern)
+	if cliutils.IsGlob(patorg) {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the organization of the pattern can not be a wildcard pattern: %v", patorg))
+	} else if cliutils.IsGlob(pattern) {
+		var patterns exchange.GetPatternResponse
+		cliutils.ExchangeGetAll("Exchange", exchUrl, "orgs/"+patorg+"/patterns", cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &patterns)
+		ids := make([]string, 0, len(patterns.Patterns))
+		for id := range patterns.Patterns {
+			ids = append(ids, id)
+		}
+		cliutils.RemoveMatching(msgPrinter.Sprintf("patterns"), patorg+"/"+pattern, ids, force, func(id string) error {
+			_, patId := cliutils.TrimOrg(patorg, id)
+			if httpCode, err := cliutils.ExchangeDeleteE("Exchange", exchUrl, "orgs/"+patorg+"/patterns/"+patId, cliutils.OrgAndCreds(org, userPw), []int{204, 404}); err != nil {
+				return err
+			} else if httpCode == 404 {
+				return errors.New(msgPrinter.Sprintf("pattern not found"))
+			}
+			return nil
+		})
+		return
+	}
+
 	if !force {
 		cliutils.ConfirmRemove(msgPrinter.Sprintf("Are you sure you want to remove pattern %v/%v from the Horizon Exchange?", org, pattern))
 	}
@@ -413,7 +552,7 @@ func PatternListKey(org, userPw, pattern, keyName string) {
 	//check for ExchangeUrl early on
 	var exchUrl = cliutils.GetExchangeUrl()
 
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	var patorg string
 	patorg, pattern = cliutils.TrimOrg(org, pattern)
 	if keyName == "" {
@@ -436,7 +575,7 @@ func PatternRemoveKey(org, userPw, pattern, keyName string) {
 	//check for ExchangeUrl early on
 	var exchUrl = cliutils.GetExchangeUrl()
 
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	var patorg string
 	patorg, pattern = cliutils.TrimOrg(org, pattern)
 	httpCode := cliutils.ExchangeDelete("Exchange", exchUrl, "orgs/"+patorg+"/patterns/"+pattern+"/keys/"+keyName, cliutils.OrgAndCreds(org, userPw), []int{204, 404})
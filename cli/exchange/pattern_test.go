@@ -0,0 +1,121 @@
+// +build unit
+
+package exchange
+
+import (
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_PatternUpdate_changed(t *testing.T) {
+	h := clitest.New(t)
+	dir, err := ioutil.TempDir("", "pattern-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "label.json")
+	if err := ioutil.WriteFile(file, []byte(`{"label": "new label"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	pattern := func(lastUpdated string) clitest.Response {
+		return clitest.Response{Code: http.StatusOK, Body: map[string]interface{}{"patterns": map[string]interface{}{
+			"myorg/p1": map[string]interface{}{"label": "old label", "lastUpdated": lastUpdated}}}}
+	}
+	h.Exchange.Handle(http.MethodPatch, "orgs/myorg/patterns/p1", http.StatusCreated, map[string]interface{}{"code": "ok"})
+
+	h.Exchange.Handle(http.MethodGet, "orgs/myorg/patterns/p1", pattern("t1").Code, pattern("t1").Body)
+	if res := h.Run(func() { PatternUpdate(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "p1", file, "") }); res.ExitCode != 0 {
+		t.Errorf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if n := len(h.Exchange.RequestsTo(http.MethodPatch, "orgs/myorg/patterns/p1")); n != 1 {
+		t.Errorf("expected the pattern to be updated, found %v updates", n)
+	}
+
+	// the pattern is updated when it is still the version the update was prepared from
+	if res := h.Run(func() { PatternUpdate(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "p1", file, "t1") }); res.ExitCode != 0 {
+		t.Errorf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if n := len(h.Exchange.RequestsTo(http.MethodPatch, "orgs/myorg/patterns/p1")); n != 2 {
+		t.Errorf("expected the pattern to be updated, found %v updates", n)
+	}
+
+	// the pattern was changed by someone else since
+	h.Exchange.Handle(http.MethodGet, "orgs/myorg/patterns/p1", pattern("t2").Code, pattern("t2").Body)
+	if res := h.Run(func() { PatternUpdate(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "p1", file, "t1") }); res.ExitCode != cliutils.CONFLICT_ERROR {
+		t.Errorf("expected exit code %v, found %v, stderr: %v", cliutils.CONFLICT_ERROR, res.ExitCode, res.Stderr)
+	} else if n := len(h.Exchange.RequestsTo(http.MethodPatch, "orgs/myorg/patterns/p1")); n != 2 {
+		t.Errorf("the changed pattern should not be updated, found %v updates", n)
+	}
+	if n := len(h.Exchange.RequestsTo(http.MethodGet, "orgs/myorg/patterns/p1")); n != 3 {
+		t.Errorf("expected the pattern to be read once per update, found %v reads", n)
+	}
+}
+
+func Test_PatternPublish(t *testing.T) {
+	h := clitest.New(t)
+	dir, err := ioutil.TempDir("", "clitest-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	patFile := filepath.Join(dir, "pattern.json")
+	writePattern := func(label string) {
+		pat := `{"label": "` + label + `", "services": [{"serviceUrl": "gps", "serviceOrgid": "myorg", "serviceArch": "amd64", "serviceVersions": [{"version": "1.0.0"}]}]}`
+		if err := ioutil.WriteFile(patFile, []byte(pat), 0644); err != nil {
+			t.Fatalf("unable to write %v: %v", patFile, err)
+		}
+	}
+	publish := func() clitest.Result {
+		return h.Run(func() { PatternPublish(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, patFile, "", "", "mypat") })
+	}
+
+	// the service of the pattern must be in the exchange for its arch
+	writePattern("gps")
+	h.Exchange.AddResource("orgs/myorg/services/gps_1.0.0_arm", map[string]interface{}{"url": "gps", "version": "1.0.0", "arch": "arm"})
+	if res := publish(); res.ExitCode != cliutils.NOT_FOUND || !strings.Contains(res.Stderr, "myorg/gps version 1.0.0 for arch amd64") {
+		t.Errorf("expected the missing service, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if h.Exchange.GetResource("orgs/myorg/patterns/mypat", &map[string]interface{}{}) {
+		t.Errorf("the pattern should not be published without its service")
+	}
+	if reqs := h.Exchange.RequestsTo(http.MethodGet, "orgs/myorg/services"); len(reqs) != 1 || !strings.Contains(reqs[0].Query, "url=gps") || !strings.Contains(reqs[0].Query, "arch=amd64") {
+		t.Errorf("expected the services of the url and arch to be queried, found %v", reqs)
+	}
+
+	h.Exchange.AddResource("orgs/myorg/services/gps_1.0.0_amd64", map[string]interface{}{"url": "gps", "version": "1.0.0", "arch": "amd64"})
+	if res := publish(); res.ExitCode != 0 || !strings.Contains(res.Stdout, "Creating mypat") {
+		t.Fatalf("unexpected exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+
+	// an update shows the changes
+	if res := publish(); res.ExitCode != 0 || !strings.Contains(res.Stdout, "is unchanged") {
+		t.Errorf("expected no changes, exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+	writePattern("new gps")
+	res := publish()
+	if res.ExitCode != 0 || !strings.Contains(res.Stdout, "Changes to pattern myorg/mypat") || !strings.Contains(res.Stdout, `-   "label": "gps",`) || !strings.Contains(res.Stdout, `+   "label": "new gps",`) {
+		t.Errorf("expected the label change, exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+	pat := PatternInput{}
+	if !h.Exchange.GetResource("orgs/myorg/patterns/mypat", &pat) || pat.Label != "new gps" {
+		t.Errorf("expected the pattern to be updated, got %v", pat)
+	}
+
+	// a service of any arch needs the version for one arch
+	pattern := `{"label": "gps", "services": [{"serviceUrl": "gps", "serviceOrgid": "myorg", "serviceArch": "*", "serviceVersions": [{"version": "2.0.0"}]}]}`
+	if err := ioutil.WriteFile(patFile, []byte(pattern), 0644); err != nil {
+		t.Fatalf("unable to write %v: %v", patFile, err)
+	}
+	if res := publish(); res.ExitCode != cliutils.NOT_FOUND || !strings.HasSuffix(strings.TrimSpace(res.Stderr), "publish them first: myorg/gps version 2.0.0") {
+		t.Errorf("expected the missing service version, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	if reqs := h.Exchange.RequestsTo(http.MethodGet, "orgs/myorg/services"); reqs[len(reqs)-1].Query != "url=gps" {
+		t.Errorf("expected the services of the url to be queried, found %v", reqs[len(reqs)-1])
+	}
+}
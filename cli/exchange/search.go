@@ -0,0 +1,116 @@
+package exchange
+
+import (
+	"fmt"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/i18n"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// A node that is running a service, from 'hzn exchange search nodes'.
+type SearchNodeOutput struct {
+	Id            string   `json:"id"`
+	Name          string   `json:"name"`
+	LastHeartbeat string   `json:"lastHeartbeat"`
+	Services      []string `json:"services"`
+}
+
+// The nodes in the response of the exchange service search API.
+type ServiceSearchResponse struct {
+	Nodes []struct {
+		Id string `json:"id"`
+	} `json:"nodes"`
+}
+
+// SearchNodes displays the nodes of the node org that are running a service, with their last heartbeat. The exchange
+// search API is called for each version and arch of the service, the version and arch are optional. The properties,
+// name=value, only keep the nodes whose node policy has all of them, so that the rollout of a service to a part of the
+// fleet can be checked.
+func SearchNodes(org string, userPw string, svcUrl string, version string, arch string, nodeOrg string, properties []string) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	cliutils.SetWhetherUsingWiotp(userPw)
+	var svcOrg string
+	svcOrg, svcUrl = cliutils.TrimOrg(org, svcUrl)
+	if nodeOrg == "" {
+		nodeOrg = org
+	}
+
+	props := map[string]string{}
+	for _, p := range properties {
+		if parts := strings.SplitN(p, "=", 2); len(parts) != 2 || parts[0] == "" {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the property %v must be name=value", p))
+		} else {
+			props[parts[0]] = parts[1]
+		}
+	}
+
+	// the versions and arches of the service
+	var services exchange.GetServicesResponse
+	cliutils.ExchangeGetAll("Exchange", cliutils.GetExchangeUrl(), "orgs/"+svcOrg+"/services", cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &services)
+	svcIds := []string{}
+	for id, sd := range services.Services {
+		if sd.URL == svcUrl && (version == "" || sd.Version == version) && (arch == "" || sd.Arch == arch) {
+			svcIds = append(svcIds, id)
+		}
+	}
+	if len(svcIds) == 0 {
+		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("service %v/%v with version '%v' and arch '%v' not found in the Horizon Exchange", svcOrg, svcUrl, version, arch))
+	}
+	sort.Strings(svcIds)
+
+	// the nodes that are running each of them
+	nodeServices := map[string][]string{}
+	for _, id := range svcIds {
+		sd := services.Services[id]
+		var resp ServiceSearchResponse
+		search := ServiceNode{OrgId: svcOrg, ServiceUrl: sd.URL, ServiceVersion: sd.Version, ServiceArch: sd.Arch}
+		cliutils.ExchangePutPost("Exchange", http.MethodPost, cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/search/nodes/service", cliutils.OrgAndCreds(org, userPw), []int{201, 404}, search, &resp)
+		for _, n := range resp.Nodes {
+			nodeServices[n.Id] = append(nodeServices[n.Id], id)
+		}
+	}
+
+	// the name and heartbeat of the nodes come from the nodes of the org
+	var nodes ExchangeNodes
+	if len(nodeServices) > 0 {
+		cliutils.ExchangeGetAll("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes", cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &nodes)
+	}
+
+	output := []SearchNodeOutput{}
+	for id, svcs := range nodeServices {
+		if len(props) > 0 && !nodeHasProperties(org, userPw, id, props) {
+			continue
+		}
+		n := nodes.Nodes[id]
+		output = append(output, SearchNodeOutput{Id: id, Name: n.Name, LastHeartbeat: n.LastHeartbeat, Services: svcs})
+	}
+	sort.Slice(output, func(i, j int) bool { return output[i].Id < output[j].Id })
+	cliutils.Output(output, "exchange search nodes")
+}
+
+// Returns true if the node policy of the node has all the properties, with the same values.
+func nodeHasProperties(org string, userPw string, nodeId string, props map[string]string) bool {
+	nodeOrg, node := cliutils.TrimOrg(org, nodeId)
+	var policy exchange.ExchangePolicy
+	if httpCode := cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+nodeOrg+"/nodes/"+node+"/policy", cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &policy); httpCode == 404 {
+		return false
+	}
+	for name, value := range props {
+		found := false
+		for _, prop := range policy.Properties {
+			if prop.Name == name && fmt.Sprintf("%v", prop.Value) == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+// +build unit
+
+package exchange
+
+import (
+	"encoding/json"
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"net/http"
+	"testing"
+)
+
+func Test_SearchNodes(t *testing.T) {
+	h := clitest.New(t)
+	h.Exchange.AddResource("orgs/myorg/services/gps_1.0.0_amd64", map[string]interface{}{"url": "gps", "version": "1.0.0", "arch": "amd64"})
+	h.Exchange.AddResource("orgs/myorg/services/gps_1.0.0_arm", map[string]interface{}{"url": "gps", "version": "1.0.0", "arch": "arm"})
+	h.Exchange.AddResource("orgs/myorg/services/netspeed_1.0.0_arm", map[string]interface{}{"url": "netspeed", "version": "1.0.0", "arch": "arm"})
+	h.Exchange.AddResource("orgs/myorg/nodes/n1", map[string]interface{}{"name": "node1", "lastHeartbeat": "2020-01-01T00:00:00.000Z[UTC]"})
+	h.Exchange.AddResource("orgs/myorg/nodes/n2", map[string]interface{}{"name": "node2"})
+	h.Exchange.AddResource("orgs/myorg/nodes/n2/policy", map[string]interface{}{"properties": []interface{}{map[string]interface{}{"name": "type", "value": "camera"}}})
+	h.Exchange.HandleFunc(http.MethodPost, "orgs/myorg/search/nodes/service", func(w http.ResponseWriter, r *http.Request) {
+		search := ServiceNode{}
+		json.NewDecoder(r.Body).Decode(&search)
+		nodes := []interface{}{map[string]interface{}{"id": "myorg/n2"}}
+		if search.ServiceArch == "amd64" {
+			nodes = []interface{}{map[string]interface{}{"id": "myorg/n1"}}
+		}
+		clitest.WriteResponse(w, clitest.Response{Code: http.StatusCreated, Body: map[string]interface{}{"nodes": nodes}})
+	})
+
+	search := func(arch string, props []string) []SearchNodeOutput {
+		res := h.Run(func() { SearchNodes(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "gps", "", arch, "", props) })
+		if res.ExitCode != 0 {
+			t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+		}
+		output := []SearchNodeOutput{}
+		res.JSON(t, &output)
+		return output
+	}
+	if output := search("", nil); len(output) != 2 || output[0].Id != "myorg/n1" || output[0].LastHeartbeat != "2020-01-01T00:00:00.000Z[UTC]" || output[1].Services[0] != "myorg/gps_1.0.0_arm" {
+		t.Errorf("unexpected nodes %v", output)
+	}
+	if output := search("arm", nil); len(output) != 1 || output[0].Id != "myorg/n2" || output[0].Name != "node2" {
+		t.Errorf("unexpected nodes for arm %v", output)
+	}
+	if output := search("", []string{"type=camera"}); len(output) != 1 || output[0].Id != "myorg/n2" {
+		t.Errorf("unexpected nodes with the property %v", output)
+	}
+	if res := h.Run(func() { SearchNodes(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "gps", "2.0.0", "", "", nil) }); res.ExitCode != cliutils.NOT_FOUND {
+		t.Errorf("expected the service version not to be found, exit code %v", res.ExitCode)
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/open-horizon/anax/cli/cliconfig"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/cli/plugin_registry"
 	"github.com/open-horizon/anax/cli/policy"
+	"github.com/open-horizon/anax/cli/schema"
 	"github.com/open-horizon/anax/common"
 	"github.com/open-horizon/anax/cutil"
 	"github.com/open-horizon/anax/exchange"
@@ -35,13 +37,16 @@ type ServicePolicyFile struct {
 	Constraints externalpolicy.ConstraintExpression `json:"constraints"`
 }
 
+// The columns of the csv output of 'hzn exchange service list', when --columns is not specified.
+var ServiceListColumns = []string{"id", "url", "version", "arch", "owner", "public", "sharable"}
+
 // List the the service resources for the given org.
 // The userPw can be the userId:password auth or the nodeId:token auth.
-func ServiceList(credOrg, userPw, service string, namesOnly bool, filePath string, exSvcOpYamlForce bool) {
+func ServiceList(credOrg, userPw, service string, namesOnly bool, filePath string, exSvcOpYamlForce bool, output string, columns string) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	var svcOrg string
 	svcOrg, service = cliutils.TrimOrg(credOrg, service)
 	if service == "*" {
@@ -56,25 +61,37 @@ func ServiceList(credOrg, userPw, service string, namesOnly bool, filePath strin
 		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("-F can only be used when -f is specified."))
 	}
 
-	if namesOnly && service == "" {
+	csvOutput := cliutils.IsCSVOutput(output)
+	if csvOutput && filePath != "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("-f can not be used with the csv output."))
+	}
+
+	if csvOutput {
+		var services exchange.GetServicesResponse
+		httpCode := cliutils.ExchangeGetAll("Exchange", cliutils.GetExchangeUrl(), "orgs/"+svcOrg+"/services"+cliutils.AddSlash(service), cliutils.OrgAndCreds(credOrg, userPw), []int{200, 404}, &services)
+		if httpCode == 404 && service != "" {
+			cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("service '%s' not found in org %s", service, svcOrg))
+		}
+		objects := make(map[string]interface{})
+		for id, s := range services.Services {
+			objects[id] = s
+		}
+		cliutils.OutputCSV(cliutils.CSVRecords(objects), cliutils.ParseColumns(columns, ServiceListColumns))
+	} else if namesOnly && service == "" {
 		// Only display the names
 		var resp exchange.GetServicesResponse
-		cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+svcOrg+"/services"+cliutils.AddSlash(service), cliutils.OrgAndCreds(credOrg, userPw), []int{200, 404}, &resp)
+		cliutils.ExchangeGetAll("Exchange", cliutils.GetExchangeUrl(), "orgs/"+svcOrg+"/services"+cliutils.AddSlash(service), cliutils.OrgAndCreds(credOrg, userPw), []int{200, 404}, &resp)
 		services := []string{}
 
 		for k := range resp.Services {
 			services = append(services, k)
 		}
-		jsonBytes, err := json.MarshalIndent(services, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn exchange service list' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(services, "hzn exchange service list")
 	} else {
 		// Display the full resources
 		var services exchange.GetServicesResponse
 
-		httpCode := cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+svcOrg+"/services"+cliutils.AddSlash(service), cliutils.OrgAndCreds(credOrg, userPw), []int{200, 404}, &services)
+		httpCode := cliutils.ExchangeGetAll("Exchange", cliutils.GetExchangeUrl(), "orgs/"+svcOrg+"/services"+cliutils.AddSlash(service), cliutils.OrgAndCreds(credOrg, userPw), []int{200, 404}, &services)
 		if httpCode == 404 && service != "" {
 			cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("service '%s' not found in org %s", service, svcOrg))
 		}
@@ -101,11 +118,7 @@ func ServiceList(credOrg, userPw, service string, namesOnly bool, filePath strin
 				exchServices[sId] = s_copy
 			}
 		}
-		jsonBytes, err := json.MarshalIndent(exchServices, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn exchange service list' output: %v", err))
-		}
-		fmt.Println(string(jsonBytes))
+		cliutils.Output(exchServices, "hzn exchange service list")
 
 		// save the kube operator yaml archive to file if filePath is specified and one service is specified
 		if filePath != "" {
@@ -120,17 +133,20 @@ func ServiceList(credOrg, userPw, service string, namesOnly bool, filePath strin
 }
 
 // ServicePublish signs the MS def and puts it in the exchange
-func ServicePublish(org, userPw, jsonFilePath, keyFilePath, pubKeyFilePath string, dontTouchImage bool, pullImage bool, registryTokens []string, overwrite bool, servicePolicyFilePath string, public string) {
+func ServicePublish(org, userPw, jsonFilePath, keyFilePath, pubKeyFilePath string, dontTouchImage bool, pullImage bool, resolveDigest bool, registryTokens []string, overwrite bool, servicePolicyFilePath string, public string) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
 	if dontTouchImage && pullImage {
 		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Flags -I and -P are mutually exclusive."))
+	} else if resolveDigest && (dontTouchImage || pullImage) {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Flag --resolve-digest is mutually exclusive with -I and -P."))
 	}
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 
 	// Read in the service metadata
 	newBytes := cliconfig.ReadJsonFileWithLocalConfig(jsonFilePath)
+	schema.Check(schema.KIND_SERVICE, jsonFilePath, newBytes)
 	var svcFile common.ServiceFile
 	err := json.Unmarshal(newBytes, &svcFile)
 	if err != nil {
@@ -162,7 +178,7 @@ func ServicePublish(org, userPw, jsonFilePath, keyFilePath, pubKeyFilePath strin
 		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Error validating the input service: %v", err))
 	}
 
-	SignAndPublish(&svcFile, org, userPw, jsonFilePath, keyFilePath, pubKeyFilePath, dontTouchImage, pullImage, registryTokens, !overwrite)
+	SignAndPublish(&svcFile, org, userPw, jsonFilePath, keyFilePath, pubKeyFilePath, dontTouchImage, pullImage, resolveDigest, registryTokens, !overwrite)
 
 	// create service policy if servicePolicyFilePath is defined
 	if servicePolicyFilePath != "" {
@@ -176,7 +192,7 @@ func ServicePublish(org, userPw, jsonFilePath, keyFilePath, pubKeyFilePath strin
 }
 
 // Sign and publish the service definition. This is a function that is reusable across different hzn commands.
-func SignAndPublish(sf *common.ServiceFile, org, userPw, jsonFilePath, keyFilePath, pubKeyFilePath string, dontTouchImage bool, pullImage bool, registryTokens []string, promptForOverwrite bool) {
+func SignAndPublish(sf *common.ServiceFile, org, userPw, jsonFilePath, keyFilePath, pubKeyFilePath string, dontTouchImage bool, pullImage bool, resolveDigest bool, registryTokens []string, promptForOverwrite bool) {
 
 	//check for ExchangeUrl early on
 	var exchUrl = cliutils.GetExchangeUrl()
@@ -189,8 +205,8 @@ func SignAndPublish(sf *common.ServiceFile, org, userPw, jsonFilePath, keyFilePa
 	baseDir := filepath.Dir(jsonFilePath)
 	usedPubKey := ""
 	usedPubKey_cluster := ""
-	svcInput.Deployment, svcInput.DeploymentSignature, usedPubKey = SignDeployment(sf.Deployment, sf.DeploymentSignature, baseDir, false, keyFilePath, pubKeyFilePath, dontTouchImage, pullImage)
-	svcInput.ClusterDeployment, svcInput.ClusterDeploymentSignature, usedPubKey_cluster = SignDeployment(sf.ClusterDeployment, sf.ClusterDeploymentSignature, baseDir, true, keyFilePath, pubKeyFilePath, dontTouchImage, pullImage)
+	svcInput.Deployment, svcInput.DeploymentSignature, usedPubKey = SignDeployment(sf.Deployment, sf.DeploymentSignature, baseDir, false, keyFilePath, pubKeyFilePath, dontTouchImage, pullImage, resolveDigest)
+	svcInput.ClusterDeployment, svcInput.ClusterDeploymentSignature, usedPubKey_cluster = SignDeployment(sf.ClusterDeployment, sf.ClusterDeploymentSignature, baseDir, true, keyFilePath, pubKeyFilePath, dontTouchImage, pullImage, resolveDigest)
 
 	// Create or update resource in the exchange
 	exchId := cutil.FormExchangeIdForService(svcInput.URL, svcInput.Version, svcInput.Arch)
@@ -282,7 +298,7 @@ func SignAndPublish(sf *common.ServiceFile, org, userPw, jsonFilePath, keyFilePa
 
 // The function signs the given deployment if it is not empty abd not already signed. It returns the deployment, its signature
 // and the public key whose matching private was used for signing the deployment.
-func SignDeployment(deployment interface{}, deploymentSignature string, baseDir string, isCluster bool, keyFilePath string, pubKeyFilePath string, dontTouchImage bool, pullImage bool) (string, string, string) {
+func SignDeployment(deployment interface{}, deploymentSignature string, baseDir string, isCluster bool, keyFilePath string, pubKeyFilePath string, dontTouchImage bool, pullImage bool, resolveDigest bool) (string, string, string) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
@@ -317,6 +333,7 @@ func SignDeployment(deployment interface{}, deploymentSignature string, baseDir
 		ctx.Add("currentDir", baseDir)
 		ctx.Add("dontTouchImage", dontTouchImage)
 		ctx.Add("pullImage", pullImage)
+		ctx.Add("resolveDigest", resolveDigest)
 
 		// Allow the right plugin to sign the deployment configuration.
 		depStr, sig, err := plugin_registry.DeploymentConfigPlugins.SignByOne(dep, keyFilePath, ctx)
@@ -324,6 +341,12 @@ func SignDeployment(deployment interface{}, deploymentSignature string, baseDir
 			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("unable to sign deployment config: %v", err))
 		}
 
+		// The agent verifies the signature with the public key that is stored with the service, so make sure that it
+		// is the key of the private key before the service is published.
+		if newPubKeyToStore != "" {
+			VerifySigningKeyPair(newPubKeyToStore, keyFilePath, sig, []byte(depStr))
+		}
+
 		newDeployment = depStr
 		newDeploymentSignature = sig
 
@@ -342,13 +365,27 @@ func SignDeployment(deployment interface{}, deploymentSignature string, baseDir
 	return newDeployment, newDeploymentSignature, newPubKeyToStore
 }
 
+// VerifySigningKeyPair exits with an error if the signature that was made with the private key cannot be verified
+// with the public key, which means that they are not a key pair.
+func VerifySigningKeyPair(pubKeyFilePath string, privKeyFilePath string, signature string, data []byte) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	verified, err := verify.Input(pubKeyFilePath, signature, data)
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("error verifying the signature with %s: %v", pubKeyFilePath, err))
+	} else if !verified {
+		cliutils.Fatal(cliutils.SIGNATURE_INVALID, msgPrinter.Sprintf("the public key %v is not the public key of the private key %v, the Horizon agent would not be able to verify the signature. Specify the public key of the private key with -K.", pubKeyFilePath, privKeyFilePath))
+	}
+}
+
 // ServiceVerify verifies the deployment strings of the specified service resource in the exchange.
 // The userPw can be the userId:password auth or the nodeId:token auth.
 func ServiceVerify(org, userPw, service, keyFilePath string) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	var svcorg string
 	svcorg, service = cliutils.TrimOrg(org, service)
 	// Get service resource from exchange
@@ -404,9 +441,30 @@ func ServiceRemove(org, userPw, service string, force bool) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	var svcorg string
 	svcorg, service = cliutils.TrimOrg(org, service)
+	if cliutils.IsGlob(svcorg) {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the organization of the service can not be a wildcard pattern: %v", svcorg))
+	} else if cliutils.IsGlob(service) {
+		var services exchange.GetServicesResponse
+		cliutils.ExchangeGetAll("Exchange", cliutils.GetExchangeUrl(), "orgs/"+svcorg+"/services", cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &services)
+		ids := make([]string, 0, len(services.Services))
+		for id := range services.Services {
+			ids = append(ids, id)
+		}
+		cliutils.RemoveMatching(msgPrinter.Sprintf("services"), svcorg+"/"+service, ids, force, func(id string) error {
+			_, svcId := cliutils.TrimOrg(svcorg, id)
+			if httpCode, err := cliutils.ExchangeDeleteE("Exchange", cliutils.GetExchangeUrl(), "orgs/"+svcorg+"/services/"+svcId, cliutils.OrgAndCreds(org, userPw), []int{204, 404}); err != nil {
+				return err
+			} else if httpCode == 404 {
+				return errors.New(msgPrinter.Sprintf("service not found"))
+			}
+			return nil
+		})
+		return
+	}
+
 	if !force {
 		cliutils.ConfirmRemove(msgPrinter.Sprintf("Are you sure you want to remove service %v/%v from the Horizon Exchange?", svcorg, service))
 	}
@@ -423,7 +481,7 @@ func ServiceListKey(org, userPw, service, keyName string) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	var svcorg string
 	svcorg, service = cliutils.TrimOrg(org, service)
 	if keyName == "" {
@@ -449,7 +507,7 @@ func ServiceRemoveKey(org, userPw, service, keyName string) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	var svcorg string
 	svcorg, service = cliutils.TrimOrg(org, service)
 	httpCode := cliutils.ExchangeDelete("Exchange", cliutils.GetExchangeUrl(), "orgs/"+svcorg+"/services/"+service+"/keys/"+keyName, cliutils.OrgAndCreds(org, userPw), []int{204, 404})
@@ -464,7 +522,7 @@ func ServiceListAuth(org, userPw, service string, authId uint) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	var svcorg string
 	svcorg, service = cliutils.TrimOrg(org, service)
 	var authIdStr string
@@ -487,7 +545,7 @@ func ServiceRemoveAuth(org, userPw, service string, authId uint) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 	var svcorg string
 	svcorg, service = cliutils.TrimOrg(org, service)
 	authIdStr := strconv.Itoa(int(authId))
@@ -502,7 +560,7 @@ func ServiceListPolicy(org string, credToUse string, service string) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey(credToUse)
+	cliutils.SetWhetherUsingWiotp(credToUse)
 	var svcorg string
 	svcorg, service = cliutils.TrimOrg(org, service)
 
@@ -535,7 +593,7 @@ func ServiceAddPolicy(org string, credToUse string, service string, jsonFilePath
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey(credToUse)
+	cliutils.SetWhetherUsingWiotp(credToUse)
 	var svcorg string
 	svcorg, service = cliutils.TrimOrg(org, service)
 	fullServiceName := fmt.Sprintf(svcorg + "/" + service)
@@ -601,7 +659,7 @@ func ServiceRemovePolicy(org string, credToUse string, service string, force boo
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey(credToUse)
+	cliutils.SetWhetherUsingWiotp(credToUse)
 	var svcorg string
 	svcorg, service = cliutils.TrimOrg(org, service)
 
@@ -729,11 +787,7 @@ func ListServiceNodes(org, userPw, svcId, nodeOrg string) {
 		if nodes, ok := listNodes["nodes"]; !ok {
 			fmt.Println("[]")
 		} else {
-			jsonBytes, err := json.MarshalIndent(nodes, "", cliutils.JSON_INDENT)
-			if err != nil {
-				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, fmt.Sprintf("failed to marshal 'hzn exchange service listnode' output: %v", err))
-			}
-			fmt.Printf("%s\n", jsonBytes)
+			cliutils.Output(nodes, "hzn exchange service listnode")
 		}
 	}
 }
@@ -1,12 +1,53 @@
 package exchange
 
 import (
-	"fmt"
+	"encoding/json"
 	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/i18n"
 )
 
+// The message of the admin status of an exchange whose database is reachable.
+const EXCHANGE_STATUS_NORMAL = "Exchange server operating normally"
+
+// ExchangeAdminStatus is the output of the admin/status API of the exchange.
+type ExchangeAdminStatus struct {
+	Msg                     string `json:"msg"`
+	DbSchemaVersion         int    `json:"dbSchemaVersion"`
+	NumberOfUsers           int    `json:"numberOfUsers"`
+	NumberOfNodes           int    `json:"numberOfNodes"`
+	NumberOfNodeAgreements  int    `json:"numberOfNodeAgreements"`
+	NumberOfNodeMsgs        int    `json:"numberOfNodeMsgs"`
+	NumberOfAgbots          int    `json:"numberOfAgbots"`
+	NumberOfAgbotAgreements int    `json:"numberOfAgbotAgreements"`
+	NumberOfAgbotMsgs       int    `json:"numberOfAgbotMsgs"`
+}
+
+// ExchangeStatus is the status of the exchange that hzn resolves to, with where its url comes from and its API version.
+type ExchangeStatus struct {
+	Url       string `json:"url"`
+	UrlSource string `json:"urlSource"`
+	Version   string `json:"apiVersion"`
+	ExchangeAdminStatus
+}
+
+// Status displays the url of the exchange that hzn resolves to, the version of its API and its admin status: the
+// version of the schema of its database and the number of its resources and messages. It exits with an error when the
+// exchange does not report that it operates normally.
 func Status(org, userPw string) {
-	var output string
-	cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "admin/status", cliutils.OrgAndCreds(org, userPw), []int{200}, &output)
-	fmt.Println(output)
+	msgPrinter := i18n.GetMessagePrinter()
+
+	exchUrl := cliutils.GetExchangeUrl()
+	status := ExchangeStatus{Url: exchUrl, UrlSource: cliutils.GetExchangeUrlLocation()}
+	status.Version = LoadExchangeVersion(true, org, userPw)
+
+	var output []byte
+	cliutils.ExchangeGet("Exchange", exchUrl, "admin/status", cliutils.OrgAndCreds(org, userPw), []int{200}, &output)
+	if err := json.Unmarshal(output, &status.ExchangeAdminStatus); err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal the exchange admin status %s: %v", output, err))
+	}
+
+	cliutils.Output(status, "hzn exchange status")
+	if status.Msg != EXCHANGE_STATUS_NORMAL {
+		cliutils.Fatal(cliutils.DEGRADED, msgPrinter.Sprintf("the exchange at %v is not operating normally: %v", exchUrl, status.Msg))
+	}
 }
@@ -0,0 +1,32 @@
+// +build unit
+
+package exchange
+
+import (
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_Status(t *testing.T) {
+	h := clitest.New(t)
+	h.Setenv("HZN_HTTP_RETRIES", "0")
+	h.Exchange.Handle(http.MethodGet, "admin/status", http.StatusOK, `{"msg":"Exchange server operating normally","numberOfUsers":3,"numberOfNodes":12,"numberOfNodeAgreements":10,"numberOfNodeMsgs":0,"numberOfAgbots":1,"numberOfAgbotAgreements":10,"numberOfAgbotMsgs":2,"dbSchemaVersion":45}`)
+
+	res := h.Run(func() { Status(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH) })
+	var status ExchangeStatus
+	if res.ExitCode != 0 {
+		t.Fatalf("hzn exchange status failed with exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if res.JSON(t, &status); status.Url != h.Exchange.URL || status.Version != clitest.FAKE_EXCHANGE_VERSION || status.DbSchemaVersion != 45 || status.NumberOfNodes != 12 || status.NumberOfAgbotMsgs != 2 {
+		t.Errorf("unexpected exchange status %v", res.Stdout)
+	} else if len(h.Exchange.RequestsTo(http.MethodGet, "admin/status")) != 1 {
+		t.Errorf("the admin status should be requested once")
+	}
+
+	h.Exchange.Handle(http.MethodGet, "admin/status", http.StatusOK, `{"msg":"Exchange server not operating normally: the database is unreachable","dbSchemaVersion":0}`)
+	if res := h.Run(func() { Status(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH) }); res.ExitCode != cliutils.DEGRADED || !strings.Contains(res.Stderr, "the database is unreachable") {
+		t.Errorf("expected the exchange to be degraded, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+}
@@ -1,8 +1,6 @@
 package exchange
 
 import (
-	"encoding/json"
-	"fmt"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/i18n"
 	"net/http"
@@ -27,7 +25,7 @@ func UserList(org, userPwCreds, theUser string, allUsers, namesOnly bool) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	cliutils.SetWhetherUsingApiKey(userPwCreds)
+	cliutils.SetWhetherUsingWiotp(userPwCreds)
 
 	// Decide which users should be shown
 	exchUrlBase := cliutils.GetExchangeUrl()
@@ -40,7 +38,7 @@ func UserList(org, userPwCreds, theUser string, allUsers, namesOnly bool) {
 
 	// Get users
 	var users ExchangeUsers
-	httpCode := cliutils.ExchangeGet("Exchange", exchUrlBase, "orgs/"+org+"/users"+cliutils.AddSlash(theUser), cliutils.OrgAndCreds(org, userPwCreds), []int{200, 404}, &users)
+	httpCode := cliutils.ExchangeGetAll("Exchange", exchUrlBase, "orgs/"+org+"/users"+cliutils.AddSlash(theUser), cliutils.OrgAndCreds(org, userPwCreds), []int{200, 404}, &users)
 	if httpCode == 404 {
 		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("user '%s' not found in org %s", strings.TrimPrefix(theUser, "/"), org))
 	}
@@ -51,21 +49,65 @@ func UserList(org, userPwCreds, theUser string, allUsers, namesOnly bool) {
 		for u := range users.Users {
 			usernames = append(usernames, u)
 		}
-		jsonBytes, err := json.MarshalIndent(usernames, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'exchange user list' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(usernames, "exchange user list")
 	} else { // show full resources
-		output := cliutils.MarshalIndent(users.Users, "exchange users list")
-		fmt.Println(output)
+		cliutils.Output(users.Users, "exchange users list")
+	}
+}
+
+type UserAuthVerifyOutput struct {
+	Valid    bool   `json:"valid"`
+	User     string `json:"user"`
+	Org      string `json:"org"`
+	Admin    bool   `json:"admin"`
+	HubAdmin bool   `json:"hubAdmin"`
+}
+
+// Verify that the user credentials are valid by reading the user's own resource. Exits with an error if they are not.
+func UserAuthVerify(org, userPwCreds string) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	cliutils.SetWhetherUsingWiotp(userPwCreds)
+
+	id, _ := cliutils.SplitIdToken(userPwCreds)
+	userOrg, user := cliutils.TrimOrg(org, id)
+	if user == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("exchange user authentication must be specified with one of the following: the -u flag, the --user-auth flag or HZN_EXCHANGE_USER_AUTH"))
+	}
+
+	var users ExchangeUsers
+	httpCode := cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+userOrg+"/users/"+user, cliutils.OrgAndCreds(org, userPwCreds), []int{200, 401, 403, 404}, &users)
+	if httpCode != 200 {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the credentials of user %v/%v are not valid in the Horizon Exchange (HTTP code %v).", userOrg, user, httpCode))
+	}
+
+	output := UserAuthVerifyOutput{Valid: true, User: user, Org: userOrg}
+	if u, ok := users.Users[userOrg+"/"+user]; ok {
+		output.Admin = u.Admin
+		output.HubAdmin = u.HubAdmin
+	} else {
+		// with an api key the exchange returns the user the key belongs to
+		for id, u := range users.Users {
+			output.Org, output.User = cliutils.TrimOrg(userOrg, id)
+			output.Admin = u.Admin
+			output.HubAdmin = u.HubAdmin
+			break
+		}
 	}
+
+	cliutils.Output(output, "exchange user auth verify")
 }
 
+// Create the user. The user can be in another org, e.g. otherorg/user, when the credentials are exchange root or hub
+// admin credentials.
 func UserCreate(org, userPwCreds, user, pw, email string, isAdmin bool, isHubAdmin bool) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
+	var userOrg string
+	userOrg, user = cliutils.TrimOrg(org, user)
+
 	if email == "" {
 		if strings.Contains(user, "@") {
 			email = user
@@ -74,14 +116,14 @@ func UserCreate(org, userPwCreds, user, pw, email string, isAdmin bool, isHubAdm
 		}
 	}
 
-	if isHubAdmin && org != "root" {
+	if isHubAdmin && userOrg != "root" {
 		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("Only exchange users in the root org can be hubadmins."))
 	}
 
-	cliutils.SetWhetherUsingApiKey(userPwCreds)
+	cliutils.SetWhetherUsingWiotp(userPwCreds)
 
 	postUserReq := cliutils.UserExchangeReq{Password: pw, Admin: isAdmin, HubAdmin: isHubAdmin, Email: email}
-	cliutils.ExchangePutPost("Exchange", http.MethodPost, cliutils.GetExchangeUrl(), "orgs/"+org+"/users/"+user, cliutils.OrgAndCreds(org, userPwCreds), []int{201}, postUserReq, nil)
+	cliutils.ExchangePutPost("Exchange", http.MethodPost, cliutils.GetExchangeUrl(), "orgs/"+userOrg+"/users/"+user, cliutils.OrgAndCreds(org, userPwCreds), []int{201}, postUserReq, nil)
 }
 
 type UserExchangePatchAdmin struct {
@@ -89,9 +131,34 @@ type UserExchangePatchAdmin struct {
 }
 
 func UserSetAdmin(org, userPwCreds, user string, isAdmin bool) {
-	cliutils.SetWhetherUsingApiKey(userPwCreds)
+	cliutils.SetWhetherUsingWiotp(userPwCreds)
+	var userOrg string
+	userOrg, user = cliutils.TrimOrg(org, user)
 	patchUserReq := UserExchangePatchAdmin{Admin: isAdmin}
-	cliutils.ExchangePutPost("Exchange", http.MethodPatch, cliutils.GetExchangeUrl(), "orgs/"+org+"/users/"+user, cliutils.OrgAndCreds(org, userPwCreds), []int{201}, patchUserReq, nil)
+	cliutils.ExchangePatch("Exchange", cliutils.GetExchangeUrl(), "orgs/"+userOrg+"/users/"+user, cliutils.OrgAndCreds(org, userPwCreds), []int{201}, patchUserReq, nil)
+}
+
+type UserExchangeChangePw struct {
+	NewPassword string `json:"newPassword"`
+}
+
+// Change the password of the user. Users can change their own password, admin users can change the password of the
+// other users of their org.
+func UserChangePassword(org, userPwCreds, user, newPw string) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	cliutils.SetWhetherUsingWiotp(userPwCreds)
+	var userOrg string
+	userOrg, user = cliutils.TrimOrg(org, user)
+	newPw = cliutils.ReadSecret(msgPrinter.Sprintf("new password"), newPw)
+
+	httpCode := cliutils.ExchangePutPost("Exchange", http.MethodPost, cliutils.GetExchangeUrl(), "orgs/"+userOrg+"/users/"+user+"/changepw", cliutils.OrgAndCreds(org, userPwCreds), []int{201, 404}, UserExchangeChangePw{NewPassword: newPw}, nil)
+	if httpCode == 404 {
+		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("user '%s' not found in org %s", user, userOrg))
+	}
+	msgPrinter.Printf("Password of user %v/%v changed in the Horizon Exchange.", userOrg, user)
+	msgPrinter.Println()
 }
 
 func UserSetHubAdmin(org, userPwCreds, user string, isHubAdmin bool) {
@@ -128,13 +195,15 @@ func UserSetHubAdmin(org, userPwCreds, user string, isHubAdmin bool) {
 }
 
 func UserRemove(org, userPwCreds, user string, force bool) {
-	cliutils.SetWhetherUsingApiKey(userPwCreds)
+	cliutils.SetWhetherUsingWiotp(userPwCreds)
+	var userOrg string
+	userOrg, user = cliutils.TrimOrg(org, user)
 	if !force {
-		cliutils.ConfirmRemove(i18n.GetMessagePrinter().Sprintf("Warning: this will also delete all Exchange resources owned by this user (nodes, services, patterns, etc). Are you sure you want to remove user %v/%v from the Horizon Exchange?", org, user))
+		cliutils.ConfirmRemove(i18n.GetMessagePrinter().Sprintf("Warning: this will also delete all Exchange resources owned by this user (nodes, services, patterns, etc). Are you sure you want to remove user %v/%v from the Horizon Exchange?", userOrg, user))
 	}
 
-	httpCode := cliutils.ExchangeDelete("Exchange", cliutils.GetExchangeUrl(), "orgs/"+org+"/users/"+user, cliutils.OrgAndCreds(org, userPwCreds), []int{204, 404})
+	httpCode := cliutils.ExchangeDelete("Exchange", cliutils.GetExchangeUrl(), "orgs/"+userOrg+"/users/"+user, cliutils.OrgAndCreds(org, userPwCreds), []int{204, 404})
 	if httpCode == 404 {
-		cliutils.Fatal(cliutils.NOT_FOUND, i18n.GetMessagePrinter().Sprintf("user '%s' not found in org %s", user, org))
+		cliutils.Fatal(cliutils.NOT_FOUND, i18n.GetMessagePrinter().Sprintf("user '%s' not found in org %s", user, userOrg))
 	}
 }
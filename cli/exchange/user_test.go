@@ -0,0 +1,48 @@
+// +build unit
+
+package exchange
+
+import (
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"strings"
+	"testing"
+)
+
+func Test_UserAdmin(t *testing.T) {
+	h := clitest.New(t)
+
+	// a user can be created in another org with the root credentials
+	if res := h.Run(func() { UserCreate("root", "root/root:rootpw", "otherorg/bob", "bobpw", "bob@example.com", true, false) }); res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	user := cliutils.UserExchangeReq{}
+	if !h.Exchange.GetResource("orgs/otherorg/users/bob", &user) || user.Password != "bobpw" || !user.Admin {
+		t.Errorf("expected the user in otherorg, got %v", user)
+	}
+
+	res := h.Run(func() { UserChangePassword("root", "root/root:rootpw", "otherorg/bob", "newpw") })
+	if res.ExitCode != 0 || !strings.Contains(res.Stdout, "Password of user otherorg/bob changed") {
+		t.Errorf("unexpected exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+	change := UserExchangeChangePw{}
+	if !h.Exchange.GetResource("orgs/otherorg/users/bob/changepw", &change) || change.NewPassword != "newpw" {
+		t.Errorf("expected the new password to be posted, got %v", change)
+	}
+
+	// the password that is not on the command line is read from stdin
+	if res := h.RunWithInput("stdinpw\n", func() { UserChangePassword("root", "root/root:rootpw", "otherorg/alice", "-") }); res.ExitCode != 0 {
+		t.Errorf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if !h.Exchange.GetResource("orgs/otherorg/users/alice/changepw", &change) || change.NewPassword != "stdinpw" {
+		t.Errorf("expected the password from stdin to be posted, got %v", change)
+	}
+	if res := h.RunWithInput("", func() { UserChangePassword("root", "root/root:rootpw", "otherorg/bob", "") }); res.ExitCode != cliutils.CLI_INPUT_ERROR {
+		t.Errorf("expected an input error for an empty password, exit code %v", res.ExitCode)
+	}
+
+	if res := h.Run(func() { UserRemove("root", "root/root:rootpw", "otherorg/bob", true) }); res.ExitCode != 0 {
+		t.Errorf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if h.Exchange.GetResource("orgs/otherorg/users/bob", &user) {
+		t.Errorf("expected the user to be removed")
+	}
+}
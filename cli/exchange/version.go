@@ -24,7 +24,7 @@ func LoadExchangeVersion(loadWithoutCredentials bool, org string, userPws ...str
 	var output []byte
 	// Note: the base exchange does not need creds for this call (although is tolerant of it), but some front-ends to the exchange might
 	if credToUse != "" {
-		cliutils.SetWhetherUsingApiKey(credToUse)
+		cliutils.SetWhetherUsingWiotp(credToUse)
 		cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "admin/version", cliutils.OrgAndCreds(org, credToUse), []int{200}, &output)
 	} else if loadWithoutCredentials {
 		cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "admin/version", credToUse, []int{200}, &output)
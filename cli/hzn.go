@@ -22,15 +22,20 @@ import (
 	"github.com/open-horizon/anax/cli/kube_deployment"
 	"github.com/open-horizon/anax/cli/metering"
 	_ "github.com/open-horizon/anax/cli/native_deployment"
+	"github.com/open-horizon/anax/cli/nmp"
 	"github.com/open-horizon/anax/cli/node"
 	"github.com/open-horizon/anax/cli/policy"
 	"github.com/open-horizon/anax/cli/register"
+	"github.com/open-horizon/anax/cli/schema"
 	"github.com/open-horizon/anax/cli/service"
 	"github.com/open-horizon/anax/cli/status"
+	"github.com/open-horizon/anax/cli/support"
 	"github.com/open-horizon/anax/cli/sync_service"
 	"github.com/open-horizon/anax/cli/unregister"
 	"github.com/open-horizon/anax/cli/userinput"
 	"github.com/open-horizon/anax/cli/utilcmds"
+	"github.com/open-horizon/anax/cli/watch"
+	"github.com/open-horizon/anax/cli/wiotp"
 	"github.com/open-horizon/anax/cutil"
 	"github.com/open-horizon/anax/i18n"
 	"gopkg.in/alecthomas/kingpin.v2"
@@ -76,33 +81,78 @@ Environment Variables:
   HZN_EXCHANGE_URL:  Override the URL that the 'hzn exchange' sub-commands use
       to communicate with the Horizon Exchange, for example
       https://exchange.bluehorizon.network/api/v1. (By default hzn will ask the
-      Horizon Agent for the URL.)
+      Horizon Agent for the URL.) It can be a comma separated list of mirrored
+      exchanges, which are tried in order when one can not be reached.
+  HORIZON_EXCHANGE_URL_BASE:  The URL, or comma separated list of mirrored
+      URLs, of the Horizon Exchange when HZN_EXCHANGE_URL is not set.
   HZN_ORG_ID:  Default value for the 'hzn exchange -o' flag,
       to specify the organization ID'.
   HZN_EXCHANGE_USER_AUTH:  Default value for the 'hzn exchange -u' or 'hzn
 	  register -u' flag, in the form '[org/]user:pw'. Notice that HZN_ORG_ID can be set
 	  if org is omitted when HZN_EXCHANGE_USER_AUTH is set.
+  HZN_CREDENTIAL_HELPER:  A program that hzn runs as '<program> get' to get
+      the exchange user credentials when they are not given with -u or
+      HZN_EXCHANGE_USER_AUTH, e.g. docker-credential-secretservice to get them
+      from the OS keyring. The exchange URL is written to its stdin and it
+      writes {"Username": "org/user", "Secret": "password"} to stdout.
   HZN_FSS_CSSURL:  Override the URL that the 'hzn mms' sub-commands use
       to communicate with the Horizon Model Management Service, for example
       https://exchange.bluehorizon.network/css/. (By default hzn will ask the
       Horizon Agent for the URL.)
+  HZN_PROFILE:  The profile of ~/.hzn/config to use when --profile is not
+      given. The profiles are named sets of these settings that are managed
+      with 'hzn config'. The environment variables take precedence over the
+      profile, and the profile over ~/.hzn/hzn.json.
 
   All these environment variables and ones mentioned in the command help can be
   specified in user's configuration file: ~/.hzn/hzn.json with JSON format.
   For example:
   %s
+Exit Codes:
+  %d:  The command line or its input is not valid.
+  %d:  A Horizon API request failed.
+  %d:  The resource was not found.
+  %d:  Some but not all of the operations of a bulk command failed.
+  %d:  The Horizon Exchange did not accept the credentials.
+  %d:  The command was interrupted with Ctrl-C or SIGTERM. The requests in
+      flight are cancelled.
   `, `{
     "HZN_ORG_ID": "me@mycomp.com"
   }
-`))
+`, cliutils.CLI_INPUT_ERROR, cliutils.HTTP_ERROR, cliutils.NOT_FOUND, cliutils.PARTIAL_SUCCESS, cliutils.INVALID_CREDS, cliutils.INTERRUPTED))
 	app.HelpFlag.Short('h')
 	app.UsageTemplate(kingpin.CompactUsageTemplate)
 	cliutils.Opts.Verbose = app.Flag("verbose", msgPrinter.Sprintf("Verbose output.")).Short('v').Bool()
 	cliutils.Opts.IsDryRun = app.Flag("dry-run", msgPrinter.Sprintf("When calling the Horizon or Exchange API, do GETs, but don't do PUTs, POSTs, or DELETEs.")).Bool()
+	cliutils.Opts.ExchangeUrl = app.Flag("exchange-url", msgPrinter.Sprintf("The URL of the Horizon Exchange. It takes precedence over HZN_EXCHANGE_URL and the exchange URL in the Horizon Agent configuration.")).PlaceHolder("URL").String()
+	cliutils.Opts.HorizonUrl = app.Flag("horizon-url", msgPrinter.Sprintf("The URL of the Horizon Agent API. It takes precedence over HORIZON_URL.")).PlaceHolder("URL").String()
+	cliutils.Opts.UserAuth = app.Flag("user-auth", msgPrinter.Sprintf("Horizon Exchange user credentials for every command that uses the Horizon Exchange, when the command is not given -u. It takes precedence over HZN_EXCHANGE_USER_AUTH. Use bearer:TOKEN to send an IAM token as a Bearer token, apikey:KEY to send an api key, or wiotp:KEY:TOKEN to send a Watson IoT Platform api key and authentication token, or set HZN_EXCHANGE_AUTH_SCHEME to bearer, apikey or wiotp.")).PlaceHolder("USER:PW").String()
+	cliutils.Opts.NodeAuth = app.Flag("node-auth", msgPrinter.Sprintf("Horizon Exchange node id and token for every command that can use node credentials, when the command is not given -n. It takes precedence over HZN_EXCHANGE_NODE_AUTH.")).PlaceHolder("ID:TOK").String()
+	cliutils.Opts.Insecure = app.Flag("insecure", msgPrinter.Sprintf("Skip the verification of the TLS certificates of the Horizon management hub. This is not secure and should only be used with test hubs that have self-signed certificates. Same as setting HZN_SSL_SKIP_VERIFY.")).Bool()
+	cliutils.Opts.Timeout = app.Flag("http-timeout", msgPrinter.Sprintf("The timeout in seconds of each HTTP request to the Horizon Agent, agbot and Exchange APIs. It takes precedence over HZN_HTTP_TIMEOUT. Failed requests are retried HZN_HTTP_RETRIES times, waiting HZN_HTTP_RETRY_INTERVAL seconds before the first retry and twice as long before each next one, up to 10 seconds per retry and 30 seconds for all the retries of a request. The requests that the Exchange rate limits are retried HZN_HTTP_RATE_LIMIT_RETRIES times, waiting as long as its Retry-After asks, up to the request timeout for all the retries of a request.")).PlaceHolder("SECONDS").Int()
+	cliutils.Opts.ClientCert = app.Flag("client-cert", msgPrinter.Sprintf("The file of the PEM encoded client certificate to present to a Horizon management hub that requires mutual TLS. It takes precedence over HORIZON_EXCHANGE_CLIENT_CERT.")).PlaceHolder("FILE").String()
+	cliutils.Opts.ClientKey = app.Flag("client-key", msgPrinter.Sprintf("The file of the PEM encoded private key of the client certificate. It takes precedence over HORIZON_EXCHANGE_CLIENT_KEY.")).PlaceHolder("FILE").String()
+	cliutils.Opts.Proxy = app.Flag("proxy", msgPrinter.Sprintf("The URL of the proxy for every request to the Horizon Agent, agbot and Exchange APIs, for example http://user:pw@proxy.example.com:3128. It takes precedence over HTTP_PROXY and HTTPS_PROXY, the hosts in NO_PROXY are still reached directly.")).PlaceHolder("URL").String()
+	cliutils.Opts.DumpHttp = app.Flag("dump-http", msgPrinter.Sprintf("Write the headers and bodies of every HTTP request and response to stderr, with the credentials redacted, to attach to a bug report. Same as setting HZN_HTTP_TRACE to stderr, or set HZN_HTTP_TRACE to a file to append the trace to it. The bodies that are not json, or are larger than 64KB, cannot be redacted and are not shown unless HZN_HTTP_TRACE_RAW_BODIES is true.")).Bool()
+	cliutils.Opts.JsonErrors = app.Flag("json-errors", msgPrinter.Sprintf("Write the error that ends the command, including an invalid command line, to stderr as a JSON object with the exitCode, errorClass and message, and the httpCode, method and url of the request when the error is caused by a failed HTTP request.")).Bool()
+	cliutils.Opts.AssumeYes = app.Flag("yes", msgPrinter.Sprintf("Skip every 'are you sure?' prompt, for automation. Same as setting HZN_NONINTERACTIVE to true. Without it, the commands that ask for a confirmation read the answer from stdin, and fail when stdin is not a terminal and has no answer.")).Short('y').Bool()
+	profileName := app.Flag("profile", msgPrinter.Sprintf("The profile of ~/.hzn/config to use, which sets the exchange URL, org, credentials and default architecture that are not set with flags or environment variables. It takes precedence over HZN_PROFILE and the current profile of ~/.hzn/config.")).PlaceHolder("NAME").String()
+	cliutils.Opts.OutputFmt = app.Flag("output-format", msgPrinter.Sprintf("The format of the output of the list and get commands: json, yaml, table or go-template=TEMPLATE, where the Go template is run on the JSON output, e.g. go-template='{{.configstate.state}}'. It takes precedence over HZN_OUTPUT_FORMAT. The default is json. The --output csv flag of the list commands can not be used with it, the default --output json of those commands writes this format.")).PlaceHolder("FORMAT").String()
+	cliutils.Opts.YamlInput = app.Flag("yaml", msgPrinter.Sprintf("Read the JSON input files as YAML, for stdin and the files that do not have a .yaml or .yml extension. The files with a .yaml or .yml extension are always read as YAML, and the ones with a .json extension as JSON.")).Bool()
 
 	envCmd := app.Command("env", msgPrinter.Sprintf("Show the Horizon Environment Variables."))
-
-	versionCmd := app.Command("version", msgPrinter.Sprintf("Show the Horizon version.")) // using a cmd for this instead of --version flag, because kingpin takes over the latter and can't get version only when it is needed
+	envLong := envCmd.Flag("long", msgPrinter.Sprintf("Show every configuration input that hzn uses (agent and exchange URLs, org, credentials, certificate, timeouts and retries), with the source of each value.")).Short('l').Bool()
+	envCheck := envCmd.Flag("check", msgPrinter.Sprintf("Show the configuration like --long, then check that hzn can reach the Horizon Agent API, the Exchange with the credentials, the Model Management Service and the agbot API, and show the agent and exchange versions. Each check is tried once. Exits with an error if a check fails.")).Bool()
+
+	configCmd := app.Command("config", msgPrinter.Sprintf("View and manage the named profiles in ~/.hzn/config."))
+	configSetCmd := configCmd.Command("set", msgPrinter.Sprintf("Set a setting of the profile given with --profile, or of the current profile. The profile is created if it does not exist. The settings are: exchangeUrl, org, credentialHelper, userAuth, nodeAuth, arch, horizonUrl and cssUrl."))
+	configSetKey := configSetCmd.Arg("setting", msgPrinter.Sprintf("The name of the setting.")).Required().String()
+	configSetValue := configSetCmd.Arg("value", msgPrinter.Sprintf("The value of the setting. An empty value removes the setting from the profile. The value of userAuth and nodeAuth must be -, they are then prompted for without echo, or read from stdin.")).Required().String()
+	configUseCmd := configCmd.Command("use", msgPrinter.Sprintf("Make a profile the current profile, which is used when --profile and HZN_PROFILE are not given."))
+	configUseProfile := configUseCmd.Arg("profile", msgPrinter.Sprintf("The name of the profile.")).Required().String()
+	configViewCmd := configCmd.Command("view", msgPrinter.Sprintf("Display the profiles, or only the one given with --profile, with the credentials masked."))
+
+	versionCmd := app.Command("version", msgPrinter.Sprintf("Show the versions of the Horizon CLI, the Horizon Agent and the Horizon Exchange, with a warning for the combinations of them that are known not to work.")) // using a cmd for this instead of --version flag, because kingpin takes over the latter and can't get version only when it is needed
 	archCmd := app.Command("architecture", msgPrinter.Sprintf("Show the architecture of this machine (as defined by Horizon and golang)."))
 
 	exchangeCmd := app.Command("exchange", msgPrinter.Sprintf("List and manage Horizon Exchange resources."))
@@ -110,7 +160,7 @@ Environment Variables:
 	exUserPw := exchangeCmd.Flag("user-pw", msgPrinter.Sprintf("Horizon Exchange user credentials to query and create exchange resources. If not specified, HZN_EXCHANGE_USER_AUTH will be used as a default. If you don't prepend it with the user's org, it will automatically be prepended with the -o value. As an alternative to using -o, you can set HZN_ORG_ID with the Horizon exchange organization ID")).Short('u').PlaceHolder("USER:PW").String()
 
 	exVersionCmd := exchangeCmd.Command("version", msgPrinter.Sprintf("Display the version of the Horizon Exchange."))
-	exStatusCmd := exchangeCmd.Command("status", msgPrinter.Sprintf("Display the status of the Horizon Exchange."))
+	exStatusCmd := exchangeCmd.Command("status", msgPrinter.Sprintf("Display the URL of the Horizon Exchange that hzn resolves to and where it comes from, the version of its API, and its status with the credentials: the version of its database schema and the number of its users, nodes, agbots, agreements and messages. Exits with code 15 when the Exchange does not operate normally."))
 
 	exOrgCmd := exchangeCmd.Command("org", msgPrinter.Sprintf("List and manage organizations in the Horizon Exchange."))
 	exOrgListCmd := exOrgCmd.Command("list", msgPrinter.Sprintf("Display the organization resource from the Horizon Exchange. (Normally you can only display your own organiztion. If the org does not exist, you will get an invalid credentials error.)"))
@@ -146,19 +196,24 @@ Environment Variables:
 	exUserListAll := exUserListCmd.Flag("all", msgPrinter.Sprintf("List all users in the org. Will only do this if you are a user with admin privilege.")).Short('a').Bool()
 	exUserListNamesOnly := exUserListCmd.Flag("names", msgPrinter.Sprintf("When listing all of the users, show only the usernames, instead of each entire resource.")).Short('N').Bool()
 	exUserCreateCmd := exUserCmd.Command("create", msgPrinter.Sprintf("Create the user resource in the Horizon Exchange."))
-	exUserCreateUser := exUserCreateCmd.Arg("user", msgPrinter.Sprintf("Your username for this user account when creating it in the Horizon exchange.")).Required().String()
+	exUserCreateUser := exUserCreateCmd.Arg("user", msgPrinter.Sprintf("Your username for this user account when creating it in the Horizon exchange. With the exchange root or hub admin credentials, the user can be created in another org with <org>/<user>.")).Required().String()
 	exUserCreatePw := exUserCreateCmd.Arg("pw", msgPrinter.Sprintf("Your password for this user account when creating it in the Horizon exchange.")).Required().String()
 	exUserCreateEmail := exUserCreateCmd.Arg("email", msgPrinter.Sprintf("Your email address that should be associated with this user account when creating it in the Horizon exchange. If your username is an email address, this argument can be omitted.")).String()
 	exUserCreateIsAdmin := exUserCreateCmd.Flag("admin", msgPrinter.Sprintf("This user should be an administrator, capable of managing all resources in this org of the Exchange.")).Short('A').Bool()
 	exUserCreateIsHubAdmin := exUserCreateCmd.Flag("hubadmin", msgPrinter.Sprintf("This user should be a hub administrator, capable of managing orgs in this administration hub.")).Short('H').Bool()
 	exUserSetAdminCmd := exUserCmd.Command("setadmin", msgPrinter.Sprintf("Change the existing user to be an admin user (like root in his/her org) or to no longer be an admin user. Can only be run by exchange root or another admin user."))
-	exUserSetAdminUser := exUserSetAdminCmd.Arg("user", msgPrinter.Sprintf("The user to be modified.")).Required().String()
+	exUserSetAdminUser := exUserSetAdminCmd.Arg("user", msgPrinter.Sprintf("The user to be modified. A user in another org is specified with <org>/<user>.")).Required().String()
 	exUserSetAdminBool := exUserSetAdminCmd.Arg("isadmin", msgPrinter.Sprintf("True if they should be an admin user, otherwise false.")).Required().Bool()
 	exUserSetHubAdminCmd := exUserCmd.Command("sethubadmin", msgPrinter.Sprintf("Change an existing user to be a hub admin or make a user no longer a hub admin. A hub admin can create, modify, and delete orgs in the management hub."))
 	exUserSetHubAminUser := exUserSetHubAdminCmd.Arg("user", msgPrinter.Sprintf("The user to be modified.")).Required().String()
 	exUserSetHubAdminBool := exUserSetHubAdminCmd.Arg("ishubadmin", msgPrinter.Sprintf("True if this user should be a hub admin user, otherwise false.")).Required().Bool()
+	exUserChangePwCmd := exUserCmd.Command("changepassword", msgPrinter.Sprintf("Change the password of a user in the Horizon Exchange. Users can change their own password, admin users can change the password of the users in their org."))
+	exUserChangePwUser := exUserChangePwCmd.Arg("user", msgPrinter.Sprintf("The user whose password is changed. A user in another org is specified with <org>/<user>.")).Required().String()
+	exUserChangePwPw := exUserChangePwCmd.Arg("newpw", msgPrinter.Sprintf("The new password of the user. If omitted or -, it is prompted for without echo, or read from stdin, so that it is not seen in the process list or the shell history.")).String()
+	exUserAuthCmd := exUserCmd.Command("auth", msgPrinter.Sprintf("Check user credentials in the Horizon Exchange."))
+	exUserAuthVerifyCmd := exUserAuthCmd.Command("verify", msgPrinter.Sprintf("Verify that the user credentials given with -u (or --user-auth or HZN_EXCHANGE_USER_AUTH) are valid in the Horizon Exchange, and display the org they resolve to and the admin status of the user. Exits with a non-zero code if the credentials are not valid."))
 	exUserDelCmd := exUserCmd.Command("remove", msgPrinter.Sprintf("Remove a user resource from the Horizon Exchange. Warning: this will cause all exchange resources owned by this user to also be deleted (nodes, services, patterns, etc)."))
-	exDelUser := exUserDelCmd.Arg("user", msgPrinter.Sprintf("The user to remove.")).Required().String()
+	exDelUser := exUserDelCmd.Arg("user", msgPrinter.Sprintf("The user to remove. A user in another org is specified with <org>/<user>.")).Required().String()
 	exUserDelForce := exUserDelCmd.Flag("force", msgPrinter.Sprintf("Skip the 'are you sure?' prompt.")).Short('f').Bool()
 
 	exNodeCmd := exchangeCmd.Command("node", msgPrinter.Sprintf("List and manage nodes in the Horizon Exchange"))
@@ -166,6 +221,8 @@ Environment Variables:
 	exNode := exNodeListCmd.Arg("node", msgPrinter.Sprintf("List just this one node.")).String()
 	exNodeListNodeIdTok := exNodeListCmd.Flag("node-id-tok", msgPrinter.Sprintf("The Horizon Exchange node ID and token to be used as credentials to query and modify the node resources if -u flag is not specified. HZN_EXCHANGE_NODE_AUTH will be used as a default for -n. If you don't prepend it with the node's org, it will automatically be prepended with the -o value.")).Short('n').PlaceHolder("ID:TOK").String()
 	exNodeLong := exNodeListCmd.Flag("long", msgPrinter.Sprintf("When listing all of the nodes, show the entire resource of each node, instead of just the name.")).Short('l').Bool()
+	exNodeOutput := exNodeListCmd.Flag("output", msgPrinter.Sprintf("The format of the output, json or csv. The json output is written in the format of --output-format when it is given, csv can not be used with --output-format. The csv output has one row per node, with the entire resource available as columns.")).Default(cliutils.OUTPUT_FORMAT_JSON).Enum(cliutils.OUTPUT_FORMAT_JSON, cliutils.OUTPUT_FORMAT_CSV)
+	exNodeColumns := exNodeListCmd.Flag("columns", msgPrinter.Sprintf("The comma separated list of the columns of the csv output. A column is the name of a field of the resource, the field of a nested object is named with dots. The id column is the id of the resource.")).String()
 	exNodeCreateCmd := exNodeCmd.Command("create", msgPrinter.Sprintf("Create the node resource in the Horizon Exchange."))
 	exNodeCreateNodeIdTok := exNodeCreateCmd.Flag("node-id-tok", msgPrinter.Sprintf("The Horizon Exchange node ID and token to be created. The node ID must be unique within the organization.")).Short('n').PlaceHolder("ID:TOK").String()
 	exNodeCreateNodeArch := exNodeCreateCmd.Flag("arch", msgPrinter.Sprintf("Your node architecture. If not specified, architecture will be left blank.")).Short('a').String()
@@ -173,22 +230,39 @@ Environment Variables:
 	exNodeCreateNodeType := exNodeCreateCmd.Flag("node-type", msgPrinter.Sprintf("The type of your node. The valid values are: device, cluster. If omitted, the default is device. However, the node type stays unchanged if the node already exists, only the node token will be updated.")).Short('T').Default("device").String()
 	exNodeCreateNode := exNodeCreateCmd.Arg("node", msgPrinter.Sprintf("The node to be created.")).String()
 	exNodeCreateToken := exNodeCreateCmd.Arg("token", msgPrinter.Sprintf("The token the new node should have.")).String()
+	exNodeCreateBulkCmd := exNodeCmd.Command("create-bulk", msgPrinter.Sprintf("Create many node resources in the Horizon Exchange from a manifest, and display a report of the nodes that were created with their tokens. The nodes that already exist are not changed."))
+	exNodeCreateBulkFile := exNodeCreateBulkCmd.Flag("file", msgPrinter.Sprintf("The manifest of the nodes to create, a CSV file with a header or a JSON array of objects. The columns or fields are: %v. Only the id is required, a random token is generated for the nodes without one. A pattern without an org is in the org of the node. Specify -f- to read from stdin.", strings.Join(exchange.NodeBulkColumns, ", "))).Short('f').Required().String()
+	exNodeCreateBulkConcurrency := exNodeCreateBulkCmd.Flag("concurrency", msgPrinter.Sprintf("The maximum number of nodes to create in the Horizon Exchange at the same time.")).Default("5").Int()
+	exNodeCreateBulkScriptDir := exNodeCreateBulkCmd.Flag("script-dir", msgPrinter.Sprintf("Write a script for each node that was created to this directory, <node>.sh, that registers the node with its credentials, for a provisioning tool to run on the node. The scripts contain the tokens of the nodes.")).PlaceHolder("DIR").String()
+	exNodeCreateBulkSummaryFile := exNodeCreateBulkCmd.Flag("summary-file", msgPrinter.Sprintf("Write a JSON summary of the nodes that could not be created to this file. The command exits with %d when some but not all of the nodes could not be created.", cliutils.PARTIAL_SUCCESS)).String()
 	exNodeUpdateCmd := exNodeCmd.Command("update", msgPrinter.Sprintf("Update an attribute of the node in the Horizon Exchange."))
 	exNodeUpdateNode := exNodeUpdateCmd.Arg("node", msgPrinter.Sprintf("The node to be updated.")).Required().String()
 	exNodeUpdateIdTok := exNodeUpdateCmd.Flag("node-id-tok", msgPrinter.Sprintf("The Horizon Exchange node ID and token to be used as credentials to query and modify the node resources if -u flag is not specified. HZN_EXCHANGE_NODE_AUTH will be used as a default for -n. If you don't prepend it with the node's org, it will automatically be prepended with the -o value.")).Short('n').PlaceHolder("ID:TOK").String()
 	exNodeUpdateJsonFile := exNodeUpdateCmd.Flag("json-file", msgPrinter.Sprintf("The path to a json file containing the changed attribute to be updated in the Horizon Exchange. Specify -f- to read from stdin.")).Short('f').Required().String()
+	exNodeUpdateMergePatch := exNodeUpdateCmd.Flag("merge-patch", msgPrinter.Sprintf("Treat each attribute in the json file as a JSON merge patch (RFC 7386) of the current value of the attribute in the Horizon Exchange, so that the fields that are not specified are preserved. A field set to null is removed. Arrays are replaced as a whole.")).Bool()
+	exNodeUpdateIfLastUpdated := exNodeUpdateCmd.Flag("if-last-updated", msgPrinter.Sprintf("Only update the node if its lastUpdated time in the Horizon Exchange is still this one, the lastUpdated of the node that the json file was prepared from, as shown by 'hzn exchange node list'. Exits with %d if the node was changed since.", cliutils.CONFLICT_ERROR)).PlaceHolder("TIME").String()
 	exNodeSetTokCmd := exNodeCmd.Command("settoken", msgPrinter.Sprintf("Change the token of a node resource in the Horizon Exchange."))
 	exNodeSetTokNode := exNodeSetTokCmd.Arg("node", msgPrinter.Sprintf("The node to be changed.")).Required().String()
-	exNodeSetTokToken := exNodeSetTokCmd.Arg("token", msgPrinter.Sprintf("The new token for the node.")).Required().String()
+	exNodeSetTokToken := exNodeSetTokCmd.Arg("token", msgPrinter.Sprintf("The new token for the node. Omit it when --rotate is specified.")).String()
+	exNodeSetTokRotate := exNodeSetTokCmd.Flag("rotate", msgPrinter.Sprintf("Generate a strong token for the node, verify it with the Horizon Exchange and, if the local agent is registered as this node, switch the agent to the new token.")).Bool()
 	exNodeSetTokNodeIdTok := exNodeSetTokCmd.Flag("node-id-tok", msgPrinter.Sprintf("The Horizon Exchange node ID and token to be used as credentials to query and modify the node resources if -u flag is not specified. HZN_EXCHANGE_NODE_AUTH will be used as a default for -n. If you don't prepend it with the node's org, it will automatically be prepended with the -o value.")).Short('n').PlaceHolder("ID:TOK").String()
-	exNodeConfirmCmd := exNodeCmd.Command("confirm", msgPrinter.Sprintf("Check to see if the specified node and token are valid in the Horizon Exchange."))
+	exNodeHeartbeatCmd := exNodeCmd.Command("heartbeat", msgPrinter.Sprintf("Send a heartbeat for the node to the Horizon Exchange, which updates the node's lastHeartbeat time."))
+	exNodeHeartbeatNode := exNodeHeartbeatCmd.Arg("node", msgPrinter.Sprintf("The node to send the heartbeat for. If not specified, the node id from the -n flag will be used.")).String()
+	exNodeHeartbeatNodeIdTok := exNodeHeartbeatCmd.Flag("node-id-tok", msgPrinter.Sprintf("The Horizon Exchange node ID and token to be used as credentials to send the heartbeat if -u flag is not specified. HZN_EXCHANGE_NODE_AUTH will be used as a default for -n. If you don't prepend it with the node's org, it will automatically be prepended with the -o value.")).Short('n').PlaceHolder("ID:TOK").String()
+	exNodeConfirmCmd := exNodeCmd.Command("confirm", msgPrinter.Sprintf("Check to see if the specified node and token are valid in the Horizon Exchange. If -u, --user-auth or HZN_EXCHANGE_USER_AUTH is given, also check that the node exists. Exits with %d if the node does not exist, %d if the token is not valid and %d if the node has not heartbeated within --heartbeat-within.", cliutils.NOT_FOUND, cliutils.INVALID_CREDS, cliutils.HEARTBEAT_STALE))
 	exNodeConfirmNodeIdTok := exNodeConfirmCmd.Flag("node-id-tok", msgPrinter.Sprintf("The Horizon exchange node ID and token to be checked. If not specified, HZN_EXCHANGE_NODE_AUTH will be used as a default. Mutually exclusive with <node> and <token> arguments.")).Short('n').PlaceHolder("ID:TOK").String()
 	exNodeConfirmNode := exNodeConfirmCmd.Arg("node", msgPrinter.Sprintf("The node id to be checked. Mutually exclusive with -n flag.")).String()
 	exNodeConfirmToken := exNodeConfirmCmd.Arg("token", msgPrinter.Sprintf("The token for the node. Mutually exclusive with -n flag.")).String()
+	exNodeConfirmHeartbeatWithin := exNodeConfirmCmd.Flag("heartbeat-within", msgPrinter.Sprintf("Also check that the node has heartbeated to the Horizon Exchange within this age, for example 10m or 1h.")).PlaceHolder("AGE").String()
+	exNodeAuthCmd := exNodeCmd.Command("auth", msgPrinter.Sprintf("Check node credentials in the Horizon Exchange."))
+	exNodeAuthVerifyCmd := exNodeAuthCmd.Command("verify", msgPrinter.Sprintf("Verify that the node credentials are valid in the Horizon Exchange, and display the org they resolve to. Exits with a non-zero code if the credentials are not valid."))
+	exNodeAuthVerifyNodeIdTok := exNodeAuthVerifyCmd.Flag("node-id-tok", msgPrinter.Sprintf("The Horizon Exchange node ID and token to be verified. If not specified, HZN_EXCHANGE_NODE_AUTH will be used as a default. If you don't prepend it with the node's org, it will automatically be prepended with the -o value.")).Short('n').PlaceHolder("ID:TOK").String()
 	exNodeDelCmd := exNodeCmd.Command("remove", msgPrinter.Sprintf("Remove a node resource from the Horizon Exchange. Do NOT do this when an edge node is registered with this node id."))
 	exNodeRemoveNodeIdTok := exNodeDelCmd.Flag("node-id-tok", msgPrinter.Sprintf("The Horizon Exchange node ID and token to be used as credentials to query and modfy the node resources if -u flag is not specified. HZN_EXCHANGE_NODE_AUTH will be used as a default for -n. If you don't prepend it with the node's org, it will automatically be prepended with the -o value.")).Short('n').PlaceHolder("ID:TOK").String()
-	exDelNode := exNodeDelCmd.Arg("node", msgPrinter.Sprintf("The node to remove.")).Required().String()
+	exDelNode := exNodeDelCmd.Arg("node", msgPrinter.Sprintf("The node to remove. A wildcard pattern, such as 'edge_*', removes all the nodes that match it, after listing them.")).Required().String()
 	exNodeDelForce := exNodeDelCmd.Flag("force", msgPrinter.Sprintf("Skip the 'are you sure?' prompt.")).Short('f').Bool()
+	exNodeDelStale := exNodeDelCmd.Flag("stale", msgPrinter.Sprintf("Only remove the nodes that have not heartbeated to the Horizon Exchange within this age, such as 30d or 12h. The nodes that have never heartbeated are kept, unless --include-never-heartbeated is specified. The nodes that match the node argument are listed before they are removed.")).PlaceHolder("AGE").String()
+	exNodeDelIncludeNever := exNodeDelCmd.Flag("include-never-heartbeated", msgPrinter.Sprintf("With --stale, also remove the nodes that have never heartbeated to the Horizon Exchange, such as the nodes that were created but never registered.")).Bool()
 	exNodeListPolicyCmd := exNodeCmd.Command("listpolicy", msgPrinter.Sprintf("Display the node policy from the Horizon Exchange."))
 	exNodeListPolicyIdTok := exNodeListPolicyCmd.Flag("node-id-tok", msgPrinter.Sprintf("The Horizon Exchange node ID and token to be used as credentials to query and modify the node resources if -u flag is not specified. HZN_EXCHANGE_NODE_AUTH will be used as a default for -n. If you don't prepend it with the node's org, it will automatically be prepended with the -o value.")).Short('n').PlaceHolder("ID:TOK").String()
 	exNodeListPolicyNode := exNodeListPolicyCmd.Arg("node", msgPrinter.Sprintf("List policy for this node.")).Required().String()
@@ -208,14 +282,22 @@ Environment Variables:
 	exNodeErrorsListIdTok := exNodeErrorsList.Flag("node-id-tok", msgPrinter.Sprintf("The Horizon Exchange node ID and token to be used as credentials to query and modify the node resources if -u flag is not specified. HZN_EXCHANGE_NODE_AUTH will be used as a default for -n. If you don't prepend it with the node's org, it will automatically be prepended with the -o value.")).Short('n').PlaceHolder("ID:TOK").String()
 	exNodeErrorsListNode := exNodeErrorsList.Arg("node", msgPrinter.Sprintf("List surfaced errors for this node.")).Required().String()
 	exNodeErrorsListLong := exNodeErrorsList.Flag("long", msgPrinter.Sprintf("Show the full eventlog object of the errors currently surfaced to the Exchange.")).Short('l').Bool()
-	exNodeStatusList := exNodeCmd.Command("liststatus", msgPrinter.Sprintf("List the run-time status of the node."))
+	exNodeStatusList := exNodeCmd.Command("liststatus", msgPrinter.Sprintf("List the run-time status of the node, or of all the nodes in the org if no node is specified."))
 	exNodeStatusIdTok := exNodeStatusList.Flag("node-id-tok", msgPrinter.Sprintf("The Horizon Exchange node ID and token to be used as credentials to query and modify the node resources if -u flag is not specified. HZN_EXCHANGE_NODE_AUTH will be used as a default for -n. If you don't prepend it with the node's org, it will automatically be prepended with the -o value.")).Short('n').PlaceHolder("ID:TOK").String()
-	exNodeStatusListNode := exNodeStatusList.Arg("node", msgPrinter.Sprintf("List status for this node")).Required().String()
+	exNodeStatusListNode := exNodeStatusList.Arg("node", msgPrinter.Sprintf("List status for this node. Use '*' or omit it to list the status of all the nodes in the org.")).String()
+	exNodeStatusConcurrency := exNodeStatusList.Flag("concurrency", msgPrinter.Sprintf("The maximum number of node statuses to read from the Horizon Exchange at the same time, when listing the status of all the nodes.")).Default("5").Int()
+	exNodeStatusSummaryFile := exNodeStatusList.Flag("summary-file", msgPrinter.Sprintf("When listing the status of all the nodes, write a JSON summary of the nodes whose status could not be read to this file. The command exits with %d when the status of some but not all of the nodes could not be read.", cliutils.PARTIAL_SUCCESS)).String()
 
 	exAgbotCmd := exchangeCmd.Command("agbot", msgPrinter.Sprintf("List and manage agbots in the Horizon Exchange"))
 	exAgbotListCmd := exAgbotCmd.Command("list", msgPrinter.Sprintf("Display the agbot resources from the Horizon Exchange."))
 	exAgbot := exAgbotListCmd.Arg("agbot", msgPrinter.Sprintf("List just this one agbot.")).String()
 	exAgbotLong := exAgbotListCmd.Flag("long", msgPrinter.Sprintf("When listing all of the agbots, show the entire resource of each agbots, instead of just the name.")).Short('l').Bool()
+	exAgbotCreateCmd := exAgbotCmd.Command("create", msgPrinter.Sprintf("Create the agbot resource in the Horizon Exchange, or change the name and token of an existing agbot. The token that the agbot uses to authenticate to the Horizon Exchange is prompted for, or read from stdin. Then add the patterns and deployment policies that it serves with addpattern and adddeploymentpol."))
+	exAgbotCreateAgbot := exAgbotCreateCmd.Arg("agbot", msgPrinter.Sprintf("The id of the agbot to create.")).Required().String()
+	exAgbotCreateName := exAgbotCreateCmd.Flag("name", msgPrinter.Sprintf("The name of the agbot. Defaults to the agbot id.")).Short('m').String()
+	exAgbotDelCmd := exAgbotCmd.Command("remove", msgPrinter.Sprintf("Remove an agbot resource from the Horizon Exchange, with the patterns and deployment policies that it serves."))
+	exAgbotDelAgbot := exAgbotDelCmd.Arg("agbot", msgPrinter.Sprintf("The agbot to remove.")).Required().String()
+	exAgbotDelForce := exAgbotDelCmd.Flag("force", msgPrinter.Sprintf("Skip the 'are you sure?' prompt.")).Short('f').Bool()
 	exAgbotListPatsCmd := exAgbotCmd.Command("listpattern", msgPrinter.Sprintf("Display the patterns that this agbot is serving."))
 	exAgbotLP := exAgbotListPatsCmd.Arg("agbot", msgPrinter.Sprintf("The agbot to list the patterns for.")).Required().String()
 	exAgbotLPPatOrg := exAgbotListPatsCmd.Arg("patternorg", msgPrinter.Sprintf("The organization of the 1 pattern to list.")).String()
@@ -245,7 +327,9 @@ Environment Variables:
 	exPatternListNodeIdTok := exPatternListCmd.Flag("node-id-tok", msgPrinter.Sprintf("The Horizon Exchange node ID and token to be used as credentials to query and modify the node resources if -u flag is not specified. HZN_EXCHANGE_NODE_AUTH will be used as a default for -n. If you don't prepend it with the node's org, it will automatically be prepended with the -o value.")).Short('n').PlaceHolder("ID:TOK").String()
 	exPattern := exPatternListCmd.Arg("pattern", msgPrinter.Sprintf("List just this one pattern. Use <org>/<pat> to specify a public pattern in another org, or <org>/ to list all of the public patterns in another org.")).String()
 	exPatternLong := exPatternListCmd.Flag("long", msgPrinter.Sprintf("When listing all of the patterns, show the entire resource of each pattern, instead of just the name.")).Short('l').Bool()
-	exPatternPublishCmd := exPatternCmd.Command("publish", msgPrinter.Sprintf("Sign and create/update the pattern resource in the Horizon Exchange."))
+	exPatternOutput := exPatternListCmd.Flag("output", msgPrinter.Sprintf("The format of the output, json or csv. The json output is written in the format of --output-format when it is given, csv can not be used with --output-format. The csv output has one row per pattern, with the entire resource available as columns.")).Default(cliutils.OUTPUT_FORMAT_JSON).Enum(cliutils.OUTPUT_FORMAT_JSON, cliutils.OUTPUT_FORMAT_CSV)
+	exPatternColumns := exPatternListCmd.Flag("columns", msgPrinter.Sprintf("The comma separated list of the columns of the csv output. A column is the name of a field of the resource, the field of a nested object is named with dots. The id column is the id of the resource.")).String()
+	exPatternPublishCmd := exPatternCmd.Command("publish", msgPrinter.Sprintf("Sign and create/update the pattern resource in the Horizon Exchange. The service versions of the pattern must be in the Exchange for the architectures of the services. When the pattern is updated, the changes to it are displayed, use --dry-run to only display them."))
 	exPatJsonFile := exPatternPublishCmd.Flag("json-file", msgPrinter.Sprintf("The path of a JSON file containing the metadata necessary to create/update the pattern in the Horizon exchange. See %v/pattern.json. Specify -f- to read from stdin.", sample_dir)).Short('f').Required().String()
 	exPatKeyFile := exPatternPublishCmd.Flag("private-key-file", msgPrinter.Sprintf("The path of a private key file to be used to sign the pattern. If not specified, the environment variable HZN_PRIVATE_KEY_FILE will be used. If none of them are set, ~/.hzn/keys/service.private.key is the default.")).Short('k').ExistingFile()
 	exPatPubPubKeyFile := exPatternPublishCmd.Flag("public-key-file", msgPrinter.Sprintf("The path of public key file (that corresponds to the private key) that should be stored with the pattern, to be used by the Horizon Agent to verify the signature. If both this and -k flags are not specified, the environment variable HZN_PUBLIC_KEY_FILE will be used. If HZN_PUBLIC_KEY_FILE is not set, ~/.hzn/keys/service.public.pem is the default. If -k is specified and this flag is not specified, then no public key file will be stored with the pattern. The Horizon Agent needs to import the public key to verify the signature.")).Short('K').ExistingFile()
@@ -258,8 +342,9 @@ Environment Variables:
 	exPatUpdateNodeIdTok := exPatUpdateCmd.Flag("node-id-tok", msgPrinter.Sprintf("The Horizon Exchange node ID and token to be used as credentials to query and modify the node resources if -u flag is not specified. HZN_EXCHANGE_NODE_AUTH will be used as a default for -n. If you don't prepend it with the node's org, it will automatically be prepended with the -o value.")).Short('n').PlaceHolder("ID:TOK").String()
 	exPatUpdatePattern := exPatUpdateCmd.Arg("pattern", msgPrinter.Sprintf("The name of the pattern in the Horizon Exchange to publish.")).Required().String()
 	exPatUpdateJsonFile := exPatUpdateCmd.Flag("json-file", msgPrinter.Sprintf("The path to a json file containing the updated attribute of the pattern to be put in the Horizon Exchange. Specify -f- to read from stdin.")).Short('f').Required().String()
+	exPatUpdateIfLastUpdated := exPatUpdateCmd.Flag("if-last-updated", msgPrinter.Sprintf("Only update the pattern if its lastUpdated time in the Horizon Exchange is still this one, the lastUpdated of the pattern that the json file was prepared from, as shown by 'hzn exchange pattern list'. Exits with %d if the pattern was changed since.", cliutils.CONFLICT_ERROR)).PlaceHolder("TIME").String()
 	exPatDelCmd := exPatternCmd.Command("remove", msgPrinter.Sprintf("Remove a pattern resource from the Horizon Exchange."))
-	exDelPat := exPatDelCmd.Arg("pattern", msgPrinter.Sprintf("The pattern to remove.")).Required().String()
+	exDelPat := exPatDelCmd.Arg("pattern", msgPrinter.Sprintf("The pattern to remove. A wildcard pattern, such as 'myorg/weather_*', removes all the patterns that match it, after listing them.")).Required().String()
 	exPatDelForce := exPatDelCmd.Flag("force", msgPrinter.Sprintf("Skip the 'are you sure?' prompt.")).Short('f').Bool()
 	exPatternListKeyCmd := exPatternCmd.Command("listkey", msgPrinter.Sprintf("List the signing public keys/certs for this pattern resource in the Horizon Exchange."))
 	exPatternListKeyNodeIdTok := exPatternListKeyCmd.Flag("node-id-tok", msgPrinter.Sprintf("The Horizon Exchange node ID and token to be used as credentials to query and modify the node resources if -u flag is not specified. HZN_EXCHANGE_NODE_AUTH will be used as a default for -n. If you don't prepend it with the node's org, it will automatically be prepended with the -o value.")).Short('n').PlaceHolder("ID:TOK").String()
@@ -274,14 +359,17 @@ Environment Variables:
 	exService := exServiceListCmd.Arg("service", msgPrinter.Sprintf("List just this one service. Use <org>/<svc> to specify a public service in another org, or <org>/ to list all of the public services in another org.")).String()
 	exServiceListNodeIdTok := exServiceListCmd.Flag("node-id-tok", msgPrinter.Sprintf("The Horizon Exchange node ID and token to be used as credentials to query and modify the node resources if -u flag is not specified. HZN_EXCHANGE_NODE_AUTH will be used as a default for -n. If you don't prepend it with the node's org, it will automatically be prepended with the -o value.")).Short('n').PlaceHolder("ID:TOK").String()
 	exServiceLong := exServiceListCmd.Flag("long", msgPrinter.Sprintf("When listing all of the services, show the entire service definition, instead of just the name. When listing a specific service, show more details.")).Short('l').Bool()
+	exServiceOutput := exServiceListCmd.Flag("output", msgPrinter.Sprintf("The format of the output, json or csv. The json output is written in the format of --output-format when it is given, csv can not be used with --output-format. The csv output has one row per service, with the entire resource available as columns.")).Default(cliutils.OUTPUT_FORMAT_JSON).Enum(cliutils.OUTPUT_FORMAT_JSON, cliutils.OUTPUT_FORMAT_CSV)
+	exServiceColumns := exServiceListCmd.Flag("columns", msgPrinter.Sprintf("The comma separated list of the columns of the csv output. A column is the name of a field of the resource, the field of a nested object is named with dots. The id column is the id of the resource.")).String()
 	exSvcOpYamlFilePath := exServiceListCmd.Flag("op-yaml-file", msgPrinter.Sprintf("The name of the file where the cluster deployment operator yaml archive will be saved. This flag is only used when listing a specific service. This flag is ignored when the service does not have a clusterDeployment attribute.")).Short('f').String()
 	exSvcOpYamlForce := exServiceListCmd.Flag("force", msgPrinter.Sprintf("Skip the 'do you want to overwrite?' prompt when -f is specified and the file exists.")).Short('F').Bool()
 	exServicePublishCmd := exServiceCmd.Command("publish", msgPrinter.Sprintf("Sign and create/update the service resource in the Horizon Exchange."))
 	exSvcJsonFile := exServicePublishCmd.Flag("json-file", msgPrinter.Sprintf("The path of a JSON file containing the metadata necessary to create/update the service in the Horizon exchange. See %v/service.json and %v/service_cluster.json. Specify -f- to read from stdin.", sample_dir, sample_dir)).Short('f').Required().String()
-	exSvcPrivKeyFile := exServicePublishCmd.Flag("private-key-file", msgPrinter.Sprintf("The path of a private key file to be used to sign the service. If not specified, the environment variable HZN_PRIVATE_KEY_FILE will be used. If none of them are set, ~/.hzn/keys/service.private.key is the default.")).Short('k').ExistingFile()
+	exSvcPrivKeyFile := exServicePublishCmd.Flag("private-key-file", msgPrinter.Sprintf("The path of a private key file to be used to sign the service. The signature is verified with the public key that is stored with the service before the service is published. If not specified, the environment variable HZN_PRIVATE_KEY_FILE will be used. If none of them are set, ~/.hzn/keys/service.private.key is the default.")).Short('k').ExistingFile()
 	exSvcPubPubKeyFile := exServicePublishCmd.Flag("public-key-file", msgPrinter.Sprintf("The path of public key file (that corresponds to the private key) that should be stored with the service, to be used by the Horizon Agent to verify the signature. If both this and -k flags are not specified, the environment variable HZN_PUBLIC_KEY_FILE will be used. If HZN_PUBLIC_KEY_FILE is not set, ~/.hzn/keys/service.public.pem is the default. If -k is specified and this flag is not specified, then no public key file will be stored with the service. The Horizon Agent needs to import the public key to verify the signature.")).Short('K').ExistingFile()
 	exSvcPubDontTouchImage := exServicePublishCmd.Flag("dont-change-image-tag", msgPrinter.Sprintf("The image paths in the deployment field have regular tags and should not be changed to sha256 digest values. The image will not get automatically uploaded to the repository. This should only be used during development when testing new versions often.")).Short('I').Bool()
 	exSvcPubPullImage := exServicePublishCmd.Flag("pull-image", msgPrinter.Sprintf("Use the image from the image repository. It will pull the image from the image repository and overwrite the local image if exists. This flag is mutually exclusive with -I.")).Short('P').Bool()
+	exSvcPubResolveDigest := exServicePublishCmd.Flag("resolve-digest", msgPrinter.Sprintf("Resolve the tag of each image in the deployment field to its digest with the API of the image registry, and use the digest instead of the tag, without pushing or pulling the image. The image must already be in the registry, the credentials of 'docker login' are used if the registry requires them. This flag is mutually exclusive with -I and -P.")).Bool()
 	exSvcRegistryTokens := exServicePublishCmd.Flag("registry-token", msgPrinter.Sprintf("Docker registry domain and auth that should be stored with the service, to enable the Horizon edge node to access the service's docker images. This flag can be repeated, and each flag should be in the format: registry:user:token")).Short('r').Strings()
 	exSvcOverwrite := exServicePublishCmd.Flag("overwrite", msgPrinter.Sprintf("Overwrite the existing version if the service exists in the Exchange. It will skip the 'do you want to overwrite' prompt.")).Short('O').Bool()
 	exSvcPolicyFile := exServicePublishCmd.Flag("service-policy-file", msgPrinter.Sprintf("The path of the service policy JSON file to be used for the service to be published. This flag is optional")).Short('p').String()
@@ -291,7 +379,7 @@ Environment Variables:
 	exServiceVerifyNodeIdTok := exServiceVerifyCmd.Flag("node-id-tok", msgPrinter.Sprintf("The Horizon Exchange node ID and token to be used as credentials to query and modify the node resources if -u flag is not specified. HZN_EXCHANGE_NODE_AUTH will be used as a default for -n. If you don't prepend it with the node's org, it will automatically be prepended with the -o value.")).Short('n').PlaceHolder("ID:TOK").String()
 	exSvcPubKeyFile := exServiceVerifyCmd.Flag("public-key-file", msgPrinter.Sprintf("The path of a pem public key file to be used to verify the service. If not specified, the environment variable HZN_PUBLIC_KEY_FILE will be used. If none of them are set, ~/.hzn/keys/service.public.pem is the default.")).Short('k').String()
 	exSvcDelCmd := exServiceCmd.Command("remove", msgPrinter.Sprintf("Remove a service resource from the Horizon Exchange."))
-	exDelSvc := exSvcDelCmd.Arg("service", msgPrinter.Sprintf("The service to remove.")).Required().String()
+	exDelSvc := exSvcDelCmd.Arg("service", msgPrinter.Sprintf("The service to remove. A wildcard pattern, such as 'myorg/weather_*', removes all the services that match it, after listing them.")).Required().String()
 	exSvcDelForce := exSvcDelCmd.Flag("force", msgPrinter.Sprintf("Skip the 'are you sure?' prompt.")).Short('f').Bool()
 	exServiceListKeyCmd := exServiceCmd.Command("listkey", msgPrinter.Sprintf("List the signing public keys/certs for this service resource in the Horizon Exchange."))
 	exSvcListKeySvc := exServiceListKeyCmd.Arg("service", msgPrinter.Sprintf("The existing service to list the keys for.")).Required().String()
@@ -344,6 +432,22 @@ Environment Variables:
 	exBusinessRemovePolicyForce := exBusinessRemovePolicyCmd.Flag("force", msgPrinter.Sprintf("Skip the 'are you sure?' prompt.")).Short('f').Bool()
 	exBusinessRemovePolicyPolicy := exBusinessRemovePolicyCmd.Arg("policy", msgPrinter.Sprintf("The name of the deployment policy to be removed.")).Required().String()
 
+	exNMPCmd := exchangeCmd.Command("nmp", msgPrinter.Sprintf("List and manage node management policies in the Horizon Exchange."))
+	exNMPListCmd := exNMPCmd.Command("list", msgPrinter.Sprintf("Display the node management policies from the Horizon Exchange."))
+	exNMPListIdTok := exNMPListCmd.Flag("id-token", msgPrinter.Sprintf("The Horizon ID and password of the user.")).Short('n').PlaceHolder("ID:TOK").String()
+	exNMPListLong := exNMPListCmd.Flag("long", msgPrinter.Sprintf("Display detailed output about the node management policies.")).Short('l').Bool()
+	exNMPListName := exNMPListCmd.Arg("nmp-name", msgPrinter.Sprintf("List just this one node management policy.")).String()
+	exNMPNewCmd := exNMPCmd.Command("new", msgPrinter.Sprintf("Display an empty node management policy template that can be filled in."))
+	exNMPAddCmd := exNMPCmd.Command("add", msgPrinter.Sprintf("Add or replace a node management policy in the Horizon Exchange. The agents of the nodes the policy applies to run its job when its start time comes. Use 'hzn exchange nmp new' for an empty node management policy template."))
+	exNMPAddIdTok := exNMPAddCmd.Flag("id-token", msgPrinter.Sprintf("The Horizon ID and password of the user.")).Short('n').PlaceHolder("ID:TOK").String()
+	exNMPAddName := exNMPAddCmd.Arg("nmp-name", msgPrinter.Sprintf("The name of the node management policy to add or overwrite.")).Required().String()
+	exNMPAddJsonFile := exNMPAddCmd.Flag("json-file", msgPrinter.Sprintf("The path of a JSON file containing the node management policy. Specify -f- to read from stdin.")).Short('f').Required().String()
+	exNMPAddNoConstraint := exNMPAddCmd.Flag("no-constraints", msgPrinter.Sprintf("Allow this node management policy to be published even though it does not have any patterns or constraints.")).Bool()
+	exNMPRemoveCmd := exNMPCmd.Command("remove", msgPrinter.Sprintf("Remove the node management policy from the Horizon Exchange."))
+	exNMPRemoveIdTok := exNMPRemoveCmd.Flag("id-token", msgPrinter.Sprintf("The Horizon ID and password of the user.")).Short('n').PlaceHolder("ID:TOK").String()
+	exNMPRemoveForce := exNMPRemoveCmd.Flag("force", msgPrinter.Sprintf("Skip the 'are you sure?' prompt.")).Short('f').Bool()
+	exNMPRemoveName := exNMPRemoveCmd.Arg("nmp-name", msgPrinter.Sprintf("The name of the node management policy to be removed.")).Required().String()
+
 	exCatalogCmd := exchangeCmd.Command("catalog", msgPrinter.Sprintf("List all public services/patterns in all orgs that have orgType: IBM."))
 	exCatalogServiceListCmd := exCatalogCmd.Command("servicelist", msgPrinter.Sprintf("Display all public services in all orgs that have orgType: IBM."))
 	exCatalogServiceListShort := exCatalogServiceListCmd.Flag("short", msgPrinter.Sprintf("Only display org (IBM) and service names.")).Short('s').Bool()
@@ -352,6 +456,14 @@ Environment Variables:
 	exCatalogPatternListShort := exCatalogPatternListCmd.Flag("short", msgPrinter.Sprintf("Only display org (IBM) and pattern names.")).Short('s').Bool()
 	exCatalogPatternListLong := exCatalogPatternListCmd.Flag("long", msgPrinter.Sprintf("Display detailed output about public patterns in all orgs that have orgType: IBM.")).Short('l').Bool()
 
+	exSearchCmd := exchangeCmd.Command("search", msgPrinter.Sprintf("Search for resources in the Horizon Exchange."))
+	exSearchNodesCmd := exSearchCmd.Command("nodes", msgPrinter.Sprintf("Display the nodes that are running a service, with their last heartbeat, to check the rollout of the service."))
+	exSearchNodesSvcUrl := exSearchNodesCmd.Flag("service-url", msgPrinter.Sprintf("The url of the service. Use <org>/<url> to specify a service from a different org.")).Short('s').Required().String()
+	exSearchNodesVersion := exSearchNodesCmd.Flag("version", msgPrinter.Sprintf("Only search for this version of the service. If omitted, all the versions are searched.")).Short('V').String()
+	exSearchNodesArch := exSearchNodesCmd.Flag("arch", msgPrinter.Sprintf("Only search for the service for this architecture. If omitted, all the architectures are searched.")).Short('a').String()
+	exSearchNodesNodeOrg := exSearchNodesCmd.Flag("node-org", msgPrinter.Sprintf("The organization of the nodes. If omitted, it will be same as the org specified by -o or HZN_ORG_ID.")).Short('O').String()
+	exSearchNodesProps := exSearchNodesCmd.Flag("property", msgPrinter.Sprintf("Only display the nodes whose node policy has this property, specified as name=value. This flag can be repeated.")).Short('p').Strings()
+
 	regInputCmd := app.Command("reginput", msgPrinter.Sprintf("Create an input file template for this pattern that can be used for the 'hzn register' command (once filled in). This examines the services that the specified pattern uses, and determines the node owner input that is required for them."))
 	regInputNodeIdTok := regInputCmd.Flag("node-id-tok", msgPrinter.Sprintf("The Horizon exchange node ID and token (it must already exist).")).Short('n').PlaceHolder("ID:TOK").Required().String()
 	regInputInputFile := regInputCmd.Flag("input-file", msgPrinter.Sprintf("The JSON input template file name that should be created. This file will contain placeholders for you to fill in user input values.")).Short('f').Required().String()
@@ -359,14 +471,15 @@ Environment Variables:
 	regInputPattern := regInputCmd.Arg("pattern", msgPrinter.Sprintf("The Horizon exchange pattern that describes what workloads that should be deployed to this node. If the pattern is from a different organization than the node, use the 'other_org/pattern' format.")).Required().String()
 	regInputArch := regInputCmd.Arg("arch", msgPrinter.Sprintf("The architecture to write the template file for. (Horizon ignores services in patterns whose architecture is different from the target system.) The architecture must be what is returned by 'hzn node list' on the target system.")).Default(cutil.ArchString()).String()
 
-	registerCmd := app.Command("register", msgPrinter.Sprintf("Register this edge node with Horizon."))
+	registerCmd := app.Command("register", msgPrinter.Sprintf("Register this edge node with Horizon. With --dry-run, the credentials, the pattern, the architecture of its services, their user input and host ports are checked and the plan of the registration is displayed, without changing the node or the Exchange."))
 	nodeIdTok := registerCmd.Flag("node-id-tok", msgPrinter.Sprintf("The Horizon exchange node ID and token. The node ID must be unique within the organization. If not specified, HZN_EXCHANGE_NODE_AUTH will be used as a default. If both -n and HZN_EXCHANGE_NODE_AUTH are not specified, the node ID will be created by Horizon from the machine serial number or fully qualified hostname. If the token is not specified, Horizon will create a random token. If node resource in the Exchange identified by the ID and token does not yet exist, you must also specify the -u flag so it can be created.")).Short('n').PlaceHolder("ID:TOK").String()
 	nodeName := registerCmd.Flag("name", msgPrinter.Sprintf("The name of the node. If not specified, it will be the same as the node id.")).Short('m').String()
 	userPw := registerCmd.Flag("user-pw", msgPrinter.Sprintf("User credentials to create the node resource in the Horizon exchange if it does not already exist. If not specified, HZN_EXCHANGE_USER_AUTH will be used as a default.")).Short('u').PlaceHolder("USER:PW").String()
-	inputFile := registerCmd.Flag("input-file", msgPrinter.Sprintf("A JSON file that sets or overrides user input variables needed by the services that will be deployed to this node. See %v/user_input.json. Specify -f- to read from stdin.", sample_dir)).Short('f').String() // not using ExistingFile() because it can be - for stdin
+	inputFile := registerCmd.Flag("input-file", msgPrinter.Sprintf("A JSON file that sets or overrides user input variables needed by the services that will be deployed to this node. See %v/user_input.json. It can also be a node file that declares the org, pattern or node policy, and node id, token and name of the node, which are used when they are not given as flags or arguments. See %v/node.json. Registering a node that is already registered with a node file changes its name, pattern, policy and user input to match the file. Specify -f- to read from stdin.", sample_dir, sample_dir)).Short('f').String() // not using ExistingFile() because it can be - for stdin
 
 	nodeOrgFlag := registerCmd.Flag("nodeorg", msgPrinter.Sprintf("The Horizon exchange organization ID that the node should be registered in. The default is the HZN_ORG_ID environment variable. Mutually exclusive with <nodeorg> and <pattern> arguments.")).Short('o').String()
 	patternFlag := registerCmd.Flag("pattern", msgPrinter.Sprintf("The Horizon exchange pattern that describes what workloads that should be deployed to this node. If the pattern is from a different organization than the node, use the 'other_org/pattern' format. Mutually exclusive with <nodeorg> and <pattern> arguments.")).Short('p').String()
+	patternFileFlag := registerCmd.Flag("pattern-file", msgPrinter.Sprintf("A JSON file that contains the full pattern definition for this node, instead of a pattern in the Horizon exchange. The node is registered without a pattern in the exchange, the services in the file are configured in the Horizon agent and the user input in the file is set on the node. The agent still gets the service definitions from the exchange. See %v/pattern.json. Mutually exclusive with -p and <pattern>.", sample_dir)).PlaceHolder("FILE").String()
 	nodepolicyFlag := registerCmd.Flag("policy", msgPrinter.Sprintf("A JSON file that sets or overrides the node policy for this node that will be used for policy based agreement negotiation.")).String()
 	org := registerCmd.Arg("nodeorg", msgPrinter.Sprintf("The Horizon exchange organization ID that the node should be registered in. Mutually exclusive with -o and -p.")).String()
 	pattern := registerCmd.Arg("pattern", msgPrinter.Sprintf("The Horizon exchange pattern that describes what workloads that should be deployed to this node. If the pattern is from a different organization than the node, use the 'other_org/pattern' format. Mutually exclusive with -o and -p.")).String()
@@ -375,7 +488,7 @@ Environment Variables:
 	waitTimeoutFlag := registerCmd.Flag("timeout", msgPrinter.Sprintf("The number of seconds for the --service to start. The default is 60 seconds, beginning when registration is successful. Ignored if --service is not specified.")).Short('t').Default("60").Int()
 
 	keyCmd := app.Command("key", msgPrinter.Sprintf("List and manage keys for signing and verifying services."))
-	keyListCmd := keyCmd.Command("list", msgPrinter.Sprintf("List the signing keys that have been imported into this Horizon agent."))
+	keyListCmd := keyCmd.Command("list", msgPrinter.Sprintf("List the signing keys that have been imported into this Horizon agent, with their expiration and the fingerprint of their public key."))
 	keyName := keyListCmd.Arg("key-name", msgPrinter.Sprintf("The name of a specific key to show.")).String()
 	keyListAll := keyListCmd.Flag("all", msgPrinter.Sprintf("List the names of all signing keys, even the older public keys not wrapped in a certificate.")).Short('a').Bool()
 	keyCreateCmd := keyCmd.Command("create", msgPrinter.Sprintf("Generate a signing key pair."))
@@ -395,14 +508,26 @@ Environment Variables:
 
 	nodeCmd := app.Command("node", msgPrinter.Sprintf("List and manage general information about this Horizon edge node."))
 	nodeListCmd := nodeCmd.Command("list", msgPrinter.Sprintf("Display general information about this Horizon edge node."))
+	nodeUpdateCmd := nodeCmd.Command("update", msgPrinter.Sprintf("Change the name, pattern or policy properties of this Horizon edge node in place, without unregistering it. They are changed in the exchange by the agent. A new pattern makes the agent end the agreements of the old pattern and register the node again with the new one."))
+	nodeUpdateName := nodeUpdateCmd.Flag("name", msgPrinter.Sprintf("The new name of the node in the exchange.")).String()
+	nodeUpdatePattern := nodeUpdateCmd.Flag("pattern", msgPrinter.Sprintf("The new pattern of the node, as <org>/<pattern> or a pattern in the org of the node. The node must already be registered with a pattern.")).Short('p').String()
+	nodeUpdateProperties := nodeUpdateCmd.Flag("property", msgPrinter.Sprintf("A node policy property to add or change, as name=value. The values that are numbers or true or false are not strings. This flag can be repeated.")).Strings()
+	nodeResourcesCmd := nodeCmd.Command("resources", msgPrinter.Sprintf("Display the hardware resources of this Horizon edge node that the agent detected: its arch, cpus, memory, the disk of the agent's storage and its GPUs. The arch, cpus and memory are also in the openhorizon.arch, openhorizon.cpu and openhorizon.memory built-in properties of the node policy."))
+	nodePurgeCmd := nodeCmd.Command("purge", msgPrinter.Sprintf("Remove the archived agreements, the event logs and the event log archives of this Horizon edge node from the agent, and display a report of what was removed, signed with the node's messaging key. The active agreements and the registration of the node are removed by 'hzn unregister'. Use 'hzn agbot purge' to remove the data an agbot has about the node."))
+	nodePurgeForce := nodePurgeCmd.Flag("force", msgPrinter.Sprintf("Skip the 'are you sure?' prompt.")).Short('f').Bool()
+	nodePurgeReportFile := nodePurgeCmd.Flag("report-file", msgPrinter.Sprintf("Also write the signed deletion report to this file.")).Short('r').String()
 
 	policyCmd := app.Command("policy", msgPrinter.Sprintf("List and manage policy for this Horizon edge node."))
-	policyListCmd := policyCmd.Command("list", msgPrinter.Sprintf("Display this edge node's policy."))
+	policyListCmd := policyCmd.Command("list", msgPrinter.Sprintf("Display this edge node's policy.")).Alias("view")
 	policyNewCmd := policyCmd.Command("new", msgPrinter.Sprintf("Display an empty policy template that can be filled in."))
-	policyUpdateCmd := policyCmd.Command("update", msgPrinter.Sprintf("Create or replace the node's policy. The node's built-in properties cannot be modified or deleted by this command, with the exception of openhorizon.allowPrivileged."))
+	policyUpdateCmd := policyCmd.Command("update", msgPrinter.Sprintf("Create or replace the node's policy. The changes to the current node policy are displayed. The node's built-in properties cannot be modified or deleted by this command, with the exception of openhorizon.allowPrivileged."))
 	policyUpdateInputFile := policyUpdateCmd.Flag("input-file", msgPrinter.Sprintf("The JSON input file name containing the node policy. Specify -f- to read from stdin.")).Short('f').Required().String()
 	policyPatchCmd := policyCmd.Command("patch", msgPrinter.Sprintf("(DEPRECATED) This command is deprecated. Please use 'hzn policy update' to update the node policy. This command is used to update either the node policy properties or the constraints, but not both."))
 	policyPatchInput := policyPatchCmd.Arg("patch", msgPrinter.Sprintf("The new constraints or properties in the format '%s' or '%s'.", "{\"constraints\":[<constraint list>]}", "{\"properties\":[<property list>]}")).Required().String()
+	policyCompatCmd := policyCmd.Command("compat", msgPrinter.Sprintf("Run the policy matching locally between a node policy file and a deployment policy file, and show which constraints passed or failed. Nothing is read from the Horizon Exchange or the agent, so the node's built-in properties are only used if they are in the node policy file."))
+	policyCompatNodePolFile := policyCompatCmd.Flag("node-pol", msgPrinter.Sprintf("The JSON input file name containing the node policy.")).Short('n').Required().String()
+	policyCompatBusPolFile := policyCompatCmd.Flag("deployment-pol", msgPrinter.Sprintf("The JSON input file name containing the deployment policy.")).Short('b').Required().String()
+	policyCompatSvcPolFile := policyCompatCmd.Flag("service-pol", msgPrinter.Sprintf("(optional) The JSON input file name containing the service policy. Its properties and constraints are merged into the deployment policy before matching.")).Short('s').String()
 	policyRemoveCmd := policyCmd.Command("remove", msgPrinter.Sprintf("Remove the node's policy."))
 	policyRemoveForce := policyRemoveCmd.Flag("force", msgPrinter.Sprintf("Skip the 'are you sure?' prompt.")).Short('f').Bool()
 
@@ -453,16 +578,36 @@ Environment Variables:
 	agreementListCmd := agreementCmd.Command("list", msgPrinter.Sprintf("List the active or archived agreements this edge node has made with a Horizon agreement bot."))
 	listAgreementId := agreementListCmd.Arg("agreement-id", msgPrinter.Sprintf("Show the details of this active or archived agreement.")).String()
 	listArchivedAgreements := agreementListCmd.Flag("archived", msgPrinter.Sprintf("List archived agreements instead of the active agreements.")).Short('r').Bool()
+	listAgreementOutput := agreementListCmd.Flag("output", msgPrinter.Sprintf("The format of the output, json or csv. The json output is written in the format of --output-format when it is given, csv can not be used with --output-format. The csv output has one row per agreement.")).Short('o').Default(cliutils.OUTPUT_FORMAT_JSON).Enum(cliutils.OUTPUT_FORMAT_JSON, cliutils.OUTPUT_FORMAT_CSV)
+	listAgreementColumns := agreementListCmd.Flag("columns", msgPrinter.Sprintf("The comma separated list of the columns of the csv output. A column is the name of a field of the agreement, the field of a nested object is named with dots, for example workload_to_run.url.")).String()
+	listAgreementWorkload := agreementListCmd.Flag("workload", msgPrinter.Sprintf("Only list the agreements for this service, the url of the service or org/url.")).String()
+	listAgreementState := agreementListCmd.Flag("state", msgPrinter.Sprintf("Only list the agreements in this state: proposed, accepted, finalized, executing or, with --archived, terminated.")).Enum(agreement.AgreementStates...)
+	listAgreementSince := agreementListCmd.Flag("since", msgPrinter.Sprintf("Only list the agreements made since this time, or with --archived, terminated since this time. It is an age such as 12h or 7d, or a date such as 2006-01-02 or 2006-01-02T15:04:05Z.")).String()
+	listAgreementRaw := agreementListCmd.Flag("raw", msgPrinter.Sprintf("When showing the details of an agreement, display the proposal as the JSON string stored in the agreement instead of decoding it.")).Bool()
 	agreementCancelCmd := agreementCmd.Command("cancel", msgPrinter.Sprintf("Cancel 1 or all of the active agreements this edge node has made with a Horizon agreement bot. Usually an agbot will immediately negotiated a new agreement. If you want to cancel all agreements and not have this edge accept new agreements, run 'hzn unregister'."))
 	cancelAllAgreements := agreementCancelCmd.Flag("all", msgPrinter.Sprintf("Cancel all of the current agreements.")).Short('a').Bool()
 	cancelAgreementId := agreementCancelCmd.Arg("agreement-id", msgPrinter.Sprintf("The active agreement to cancel.")).String()
+	cancelAgreementPattern := agreementCancelCmd.Flag("pattern", msgPrinter.Sprintf("With -a, only cancel the agreements made for this pattern.")).String()
+	cancelAgreementOrg := agreementCancelCmd.Flag("org", msgPrinter.Sprintf("With -a, only cancel the agreements for services in this organization.")).String()
+	cancelAgreementOlderThan := agreementCancelCmd.Flag("older-than", msgPrinter.Sprintf("With -a, only cancel the agreements made longer ago than this age, for example 12h or 7d.")).String()
+	cancelAgreementConcurrency := agreementCancelCmd.Flag("concurrency", msgPrinter.Sprintf("The maximum number of agreements to cancel at the same time.")).Default("5").Int()
+	cancelAgreementSummaryFile := agreementCancelCmd.Flag("summary-file", msgPrinter.Sprintf("Write a JSON summary of the agreements that could not be canceled to this file. The command exits with %d when some but not all of the agreements could not be canceled.", cliutils.PARTIAL_SUCCESS)).String()
 
 	meteringCmd := app.Command("metering", msgPrinter.Sprintf("List or manage the metering (payment) information for the active or archived agreements."))
 	meteringListCmd := meteringCmd.Command("list", msgPrinter.Sprintf("List the metering (payment) information for the active or archived agreements."))
 	listArchivedMetering := meteringListCmd.Flag("archived", msgPrinter.Sprintf("List archived agreement metering information instead of metering for the active agreements.")).Short('r').Bool()
+	listMeteringReport := meteringListCmd.Flag("report", msgPrinter.Sprintf("List one row per agreement with the tokens accrued, the rate in tokens per time unit and the last meter time, so that the credits can be checked. Use it with '-o table' for a table.")).Bool()
 
 	attributeCmd := app.Command("attribute", msgPrinter.Sprintf("List or manage the global attributes that are currently registered on this Horizon edge node."))
 	attributeListCmd := attributeCmd.Command("list", msgPrinter.Sprintf("List the global attributes that are currently registered on this Horizon edge node."))
+	attributeAddCmd := attributeCmd.Command("add", msgPrinter.Sprintf("Add a global attribute to this Horizon edge node. The attribute is checked before it is sent to the Horizon Agent."))
+	attributeAddFilePath := attributeAddCmd.Flag("file-path", msgPrinter.Sprintf("The file path to the json file with the attribute, in the format of the output of 'hzn attribute list': type, label, service_specs and variables, and optionally publishable and host_only. Specify -f- to read from stdin.")).Short('f').Required().String()
+	attributeUpdateCmd := attributeCmd.Command("update", msgPrinter.Sprintf("Replace a global attribute of this Horizon edge node with the attribute in a file. The attribute is checked before it is sent to the Horizon Agent."))
+	attributeUpdateId := attributeUpdateCmd.Arg("id", msgPrinter.Sprintf("The id of the attribute, as shown by 'hzn attribute list'.")).Required().String()
+	attributeUpdateFilePath := attributeUpdateCmd.Flag("file-path", msgPrinter.Sprintf("The file path to the json file with the attribute, in the same format as for 'hzn attribute add'. Specify -f- to read from stdin.")).Short('f').Required().String()
+	attributeRemoveCmd := attributeCmd.Command("remove", msgPrinter.Sprintf("Remove a global attribute from this Horizon edge node."))
+	attributeRemoveId := attributeRemoveCmd.Arg("id", msgPrinter.Sprintf("The id of the attribute, as shown by 'hzn attribute list'.")).Required().String()
+	attributeRemoveForce := attributeRemoveCmd.Flag("force", msgPrinter.Sprintf("Skip the 'Are you sure?' prompt.")).Short('f').Bool()
 
 	userinputCmd := app.Command("userinput", msgPrinter.Sprintf("List or manage the service user inputs that are currently registered on this Horizon edge node."))
 	userinputListCmd := userinputCmd.Command("list", msgPrinter.Sprintf("List the service user inputs currently registered on this Horizon edge node."))
@@ -471,11 +616,25 @@ Environment Variables:
 	userinputAddFilePath := userinputAddCmd.Flag("file-path", msgPrinter.Sprintf("The file path to the json file with the user input object. Specify -f- to read from stdin.")).Short('f').Required().String()
 	userinputUpdateCmd := userinputCmd.Command("update", msgPrinter.Sprintf("Update an existing user input object for this Horizon edge node."))
 	userinputUpdateFilePath := userinputUpdateCmd.Flag("file-path", msgPrinter.Sprintf("The file path to the json file with the updated user input object. Specify -f- to read from stdin.")).Short('f').Required().String()
-	userinputRemoveCmd := userinputCmd.Command("remove", msgPrinter.Sprintf("Remove the user inputs that are currently registered on this Horizon edge node."))
+	userinputGetCmd := userinputCmd.Command("get", msgPrinter.Sprintf("Display the user input of a service on this Horizon edge node, or the value of one of its variables."))
+	userinputGetService := userinputGetCmd.Arg("service", msgPrinter.Sprintf("The service, <org>/<url>. The org defaults to the organization of the node.")).Required().String()
+	userinputGetVariable := userinputGetCmd.Arg("variable", msgPrinter.Sprintf("The variable to display the value of.")).String()
+	userinputGetArch := userinputGetCmd.Flag("arch", msgPrinter.Sprintf("Only the user input for this service architecture.")).Short('a').String()
+	userinputGetVersionRange := userinputGetCmd.Flag("version-range", msgPrinter.Sprintf("Only the user input for this service version range.")).Short('r').String()
+	userinputSetCmd := userinputCmd.Command("set", msgPrinter.Sprintf("Set variables of the user input of a service on this Horizon edge node. The variables are checked against the definition of the service that the Horizon Agent has, the changes are displayed, and the agreements of the service are re-evaluated with the new user input."))
+	userinputSetService := userinputSetCmd.Arg("service", msgPrinter.Sprintf("The service, <org>/<url>. The org defaults to the organization of the node.")).Required().String()
+	userinputSetVariables := userinputSetCmd.Arg("variables", msgPrinter.Sprintf("The variables to set, name=value. A value of type list of strings is a json array or a comma separated list.")).Required().Strings()
+	userinputSetArch := userinputSetCmd.Flag("arch", msgPrinter.Sprintf("The service architecture of the user input. Omit to change the user input of the service for all the architectures.")).Short('a').String()
+	userinputSetVersionRange := userinputSetCmd.Flag("version-range", msgPrinter.Sprintf("The service version range of the user input. Omit to change the user input of the service for all the versions.")).Short('r').String()
+	userinputRemoveCmd := userinputCmd.Command("remove", msgPrinter.Sprintf("Remove the user inputs that are currently registered on this Horizon edge node, or only the user input or variables of a service."))
 	userinputRemoveForce := userinputRemoveCmd.Flag("force", msgPrinter.Sprintf("Skip the 'Are you sure?' prompt.")).Short('f').Bool()
+	userinputRemoveService := userinputRemoveCmd.Arg("service", msgPrinter.Sprintf("Only remove the user input of this service, <org>/<url>. The org defaults to the organization of the node.")).String()
+	userinputRemoveVariables := userinputRemoveCmd.Arg("variables", msgPrinter.Sprintf("Only remove these variables of the user input of the service.")).Strings()
+	userinputRemoveArch := userinputRemoveCmd.Flag("arch", msgPrinter.Sprintf("Only the user input for this service architecture.")).Short('a').String()
+	userinputRemoveVersionRange := userinputRemoveCmd.Flag("version-range", msgPrinter.Sprintf("Only the user input for this service version range.")).Short('r').String()
 
 	serviceCmd := app.Command("service", msgPrinter.Sprintf("List or manage the services that are currently registered on this Horizon edge node."))
-	serviceLogCmd := serviceCmd.Command("log", msgPrinter.Sprintf("Show the container logs for a service."))
+	serviceLogCmd := serviceCmd.Command("log", msgPrinter.Sprintf("Show the container logs for a service. When ServiceLogAPI is enabled in the agent config, the Horizon Agent reads the logs from docker, so access to docker is not needed. Otherwise, or if the agent can not read them, they are read from the system log or docker of this host."))
 	logServiceName := serviceLogCmd.Arg("service", msgPrinter.Sprintf("The name of the service whose log records should be displayed. The service name is the same as the url field of a service definition. Displays log records similar to tail behavior and returns .")).Required().String()
 	logTail := serviceLogCmd.Flag("tail", msgPrinter.Sprintf("Continuously polls the service's logs to display the most recent records, similar to tail -F behavior.")).Short('f').Bool()
 	serviceListCmd := serviceCmd.Command("list", msgPrinter.Sprintf("List the services variable configuration that has been done on this Horizon edge node."))
@@ -496,11 +655,27 @@ Environment Variables:
 
 	forceUnregister := unregisterCmd.Flag("force", msgPrinter.Sprintf("Skip the 'are you sure?' prompt.")).Short('f').Bool()
 	removeNodeUnregister := unregisterCmd.Flag("remove", msgPrinter.Sprintf("Also remove this node resource from the Horizon exchange (because you no longer want to use this node with Horizon).")).Short('r').Bool()
-	deepCleanUnregister := unregisterCmd.Flag("deep-clean", msgPrinter.Sprintf("Also remove all the previous registration information. Use it only after the 'hzn unregister' command failed. Please capture the logs by running 'hzn eventlog list -a -l' command before using this flag.")).Short('D').Bool()
+	deepCleanUnregister := unregisterCmd.Flag("deep-clean", msgPrinter.Sprintf("Also remove all the previous registration information, so that the node is in a pristine state: the service containers and docker networks are removed. If the Horizon agent fails to unregister the node, its local databases and policy files are removed too, and with -r the node is removed from the Horizon exchange with the exchange user or node credentials. The eventlog is saved in /tmp first. Please capture the logs by running 'hzn eventlog list -a -l' command before using this flag.")).Short('D').Bool()
+	removeImagesUnregister := unregisterCmd.Flag("remove-images", msgPrinter.Sprintf("Also remove the docker images of the service containers once the node is unregistered. An image that another container still uses is not removed.")).Bool()
 	timeoutUnregister := unregisterCmd.Flag("timeout", msgPrinter.Sprintf("The number of minutes to wait for unregistration to complete. The default is zero which will wait forever.")).Short('t').Default("0").Int()
 
 	statusCmd := app.Command("status", msgPrinter.Sprintf("Display the current horizon internal status for the node."))
 	statusLong := statusCmd.Flag("long", msgPrinter.Sprintf("Show detailed status")).Short('l').Bool()
+	statusHealth := statusCmd.Flag("health", msgPrinter.Sprintf("Check the Horizon Agent API and its workers, the docker daemon, the Horizon Exchange and the agreements of the node, and display them in one report with a verdict of healthy or degraded. Exits with code 15 when the node is degraded, so that it can be used as a monitoring probe.")).Bool()
+
+	wiotpCmd := app.Command("wiotp", msgPrinter.Sprintf("Display the configuration of the Watson IoT Platform organization that the Horizon Exchange is in. The Watson IoT Platform credentials are used as wiotp:KEY:TOKEN, or as KEY:TOKEN when HZN_EXCHANGE_AUTH_SCHEME is wiotp, with every command."))
+	wiotpUserPw := wiotpCmd.Flag("user-pw", msgPrinter.Sprintf("The Watson IoT Platform api key and authentication token, with or without the wiotp: prefix. If not specified, HZN_EXCHANGE_USER_AUTH will be used as a default.")).Short('u').PlaceHolder("KEY:TOKEN").String()
+	wiotpOrgCmd := wiotpCmd.Command("org", msgPrinter.Sprintf("Display the details of the Watson IoT Platform organization from the Watson IoT Platform api."))
+	wiotpConfigCmd := wiotpCmd.Command("config", msgPrinter.Sprintf("Display the settings of hzn and of the Horizon agents for the Watson IoT Platform organization: the organization id, the Horizon Exchange url of hzn and of the agents, the credentials scheme and the configuration of the organization."))
+
+	supportDumpCmd := app.Command("support-dump", msgPrinter.Sprintf("Gather the status of the Horizon Agent, its event logs, its agreements without their proposals, the horizon docker containers and the configuration of the agent and of hzn into a tar.gz file to attach to a support ticket. The values of the settings and json fields that are credentials are redacted, but the free text of the event logs and of the journal is not, review the bundle before sharing it. What cannot be collected is listed in the summary.txt file of the bundle."))
+	supportDumpFile := supportDumpCmd.Flag("file", msgPrinter.Sprintf("The tar.gz file to write the support bundle to. The default is hzn-support-<hostname>-<time>.tar.gz in the current directory.")).Short('f').String()
+	supportDumpIncludeJournal := supportDumpCmd.Flag("include-journal", msgPrinter.Sprintf("Also include the recent journald entries of the horizon service. The credentials that look like name=value are redacted from them, other sensitive text is not.")).Bool()
+	supportDumpJournalLines := supportDumpCmd.Flag("journal-lines", msgPrinter.Sprintf("The number of the most recent journald entries of the horizon service to include with --include-journal.")).Default("1000").Int()
+
+	watchCmd := app.Command("watch", msgPrinter.Sprintf("Display the state of the node, its agreements and its service containers, updated continuously, to watch a registration converge. On a terminal the view is redrawn in place, otherwise a view is written each time it changes. Press Ctrl-C to stop."))
+	watchInterval := watchCmd.Flag("interval", msgPrinter.Sprintf("The number of seconds between the polls of the Horizon Agent API.")).Short('i').Default("2").Int()
+	watchCount := watchCmd.Flag("count", msgPrinter.Sprintf("Stop after displaying this many views. The default, 0, is to watch until the command is interrupted.")).Default("0").Int()
 
 	eventlogCmd := app.Command("eventlog", msgPrinter.Sprintf("List the event logs for the current or all registrations."))
 	eventlogListCmd := eventlogCmd.Command("list", msgPrinter.Sprintf("List the event logs for the current or all registrations."))
@@ -508,9 +683,26 @@ Environment Variables:
 	listAllEventlogs := eventlogListCmd.Flag("all", msgPrinter.Sprintf("List all the event logs including the previous registrations.")).Short('a').Bool()
 	listDetailedEventlogs := eventlogListCmd.Flag("long", msgPrinter.Sprintf("List event logs with details.")).Short('l').Bool()
 	listSelectedEventlogs := eventlogListCmd.Flag("select", msgPrinter.Sprintf("Selection string. This flag can be repeated which means 'AND'. Each flag should be in the format of attribute=value, attribute~value, \"attribute>value\" or \"attribute<value\", where '~' means contains. The common attribute names are timestamp, severity, message, event_code, source_type, agreement_id, service_url etc. Use the '-l' flag to see all the attribute names.")).Short('s').Strings()
+	listEventlogsSince := eventlogListCmd.Flag("since", msgPrinter.Sprintf("Only list the event logs since this time. It can be an age, such as 7d or 12h, or a time, such as 2020-10-20 or 2020-10-20T15:04:05Z.")).String()
+	listEventlogsSeverity := eventlogListCmd.Flag("severity", msgPrinter.Sprintf("Only list the event logs of this severity or a more severe one: info, warning, error or fatal.")).Enum(eventlog.Severities...)
+	eventlogTailCmd := eventlogCmd.Command("tail", msgPrinter.Sprintf("Display the last event logs of the current registration, then keep displaying the new ones as the agent logs them, until the command is interrupted."))
+	tailEventlogsLines := eventlogTailCmd.Flag("lines", msgPrinter.Sprintf("The number of the last event logs to display first.")).Short('n').Default("10").Int()
+	tailDetailedEventlogs := eventlogTailCmd.Flag("long", msgPrinter.Sprintf("Display the event logs with details.")).Short('l').Bool()
+	tailSelectedEventlogs := eventlogTailCmd.Flag("select", msgPrinter.Sprintf("Selection string, in the same format as for 'hzn eventlog list'. This flag can be repeated which means 'AND'.")).Short('s').Strings()
+	tailEventlogsSeverity := eventlogTailCmd.Flag("severity", msgPrinter.Sprintf("Only display the event logs of this severity or a more severe one: info, warning, error or fatal.")).Enum(eventlog.Severities...)
+	eventlogExportCmd := eventlogCmd.Command("export", msgPrinter.Sprintf("Export the event logs, with all their details, to a file."))
+	exportAllEventlogs := eventlogExportCmd.Flag("all", msgPrinter.Sprintf("Export all the event logs including the previous registrations.")).Short('a').Bool()
+	exportEventlogsSince := eventlogExportCmd.Flag("since", msgPrinter.Sprintf("Only export the event logs since this time. It can be an age, such as 7d or 12h, or a time, such as 2020-10-20 or 2020-10-20T15:04:05Z.")).String()
+	exportEventlogsFormat := eventlogExportCmd.Flag("format", msgPrinter.Sprintf("The format of the exported event logs, json or csv.")).Default("json").Enum("json", "csv")
+	exportEventlogsFile := eventlogExportCmd.Flag("file", msgPrinter.Sprintf("The file to export the event logs to. Specify -f- to write to stdout.")).Short('f').Required().String()
+	exportSelectedEventlogs := eventlogExportCmd.Flag("select", msgPrinter.Sprintf("Selection string, in the same format as for 'hzn eventlog list'. This flag can be repeated which means 'AND'.")).Short('s').Strings()
 	surfaceErrorsEventlogs := eventlogCmd.Command("surface", msgPrinter.Sprintf("List all the active errors that will be shared with the Exchange if the node is online."))
 	surfaceErrorsEventlogsLong := surfaceErrorsEventlogs.Flag("long", msgPrinter.Sprintf("List the full event logs of the surface errors.")).Short('l').Bool()
 
+	nmpCmd := app.Command("nmp", msgPrinter.Sprintf("List the node management policy jobs of this node."))
+	nmpStatusCmd := nmpCmd.Command("status", msgPrinter.Sprintf("Display the status of the node management policy jobs on this node."))
+	nmpStatusName := nmpStatusCmd.Arg("nmp-name", msgPrinter.Sprintf("Display the status of just this node management policy.")).String()
+
 	devCmd := app.Command("dev", msgPrinter.Sprintf("Development tools for creation of services."))
 	devHomeDirectory := devCmd.Flag("directory", msgPrinter.Sprintf("Directory containing Horizon project metadata. If omitted, a subdirectory called 'horizon' under current directory will be used.")).Short('d').String()
 
@@ -524,7 +716,7 @@ Environment Variables:
 	devServiceNewCmdNoPattern := devServiceNewCmd.Flag("noPattern", msgPrinter.Sprintf("Indicates no pattern definition file will be created.")).Bool()
 	devServiceNewCmdNoPolicy := devServiceNewCmd.Flag("noPolicy", msgPrinter.Sprintf("Indicate no policy file will be created.")).Bool()
 	devServiceNewCmdCfg := devServiceNewCmd.Flag("dconfig", msgPrinter.Sprintf("Indicates the type of deployment configuration that will be used, native (the default), or %v. This flag can be specified more than once to create a service with more than 1 kind of deployment configuration.", kube_deployment.KUBE_DEPLOYMENT_CONFIG_TYPE)).Short('c').Default("native").Strings()
-	devServiceStartTestCmd := devServiceCmd.Command("start", msgPrinter.Sprintf("Run a service in a mocked Horizon Agent environment. This command is not supported for services using the %v deployment configuration.", kube_deployment.KUBE_DEPLOYMENT_CONFIG_TYPE))
+	devServiceStartTestCmd := devServiceCmd.Command("start", msgPrinter.Sprintf("Run a service in a mocked Horizon Agent environment. The node does not need to be registered. The node policy of the mocked environment is read from the file in the HZN_DEV_NODE_POLICY_FILE environment variable, or from the local Horizon Agent if it is not set. This command is not supported for services using the %v deployment configuration.", kube_deployment.KUBE_DEPLOYMENT_CONFIG_TYPE))
 	devServiceUserInputFile := devServiceStartTestCmd.Flag("userInputFile", msgPrinter.Sprintf("File containing user input values for running a test. If omitted, the userinput file for the project will be used.")).Short('f').String()
 	devServiceConfigFile := devServiceStartTestCmd.Flag("configFile", msgPrinter.Sprintf("File to be made available through the sync service APIs. This flag can be repeated to populate multiple files.")).Short('m').Strings()
 	devServiceConfigType := devServiceStartTestCmd.Flag("type", msgPrinter.Sprintf("The type of file to be made available through the sync service APIs. All config files are presumed to be of the same type. This flag is required if any configFiles are specified.")).Short('t').String()
@@ -542,7 +734,7 @@ Environment Variables:
 	devDependencyCmdSpecRef := devDependencyCmd.Flag("specRef", msgPrinter.Sprintf("The URL of the service dependency in the Exchange. Mutually exclusive with -p and --url.")).Short('s').String()
 	devDependencyCmdURL := devDependencyCmd.Flag("url", msgPrinter.Sprintf("The URL of the service dependency in the Exchange. Mutually exclusive with -p and --specRef.")).String()
 	devDependencyCmdOrg := devDependencyCmd.Flag("org", msgPrinter.Sprintf("The Org of the service dependency in the Exchange. Mutually exclusive with -p.")).Short('o').String()
-	devDependencyCmdVersion := devDependencyCmd.Flag("ver", msgPrinter.Sprintf("(optional) The Version of the service dependency in the Exchange. Mutually exclusive with -p.")).String()
+	devDependencyCmdVersion := devDependencyCmd.Flag("ver", msgPrinter.Sprintf("(optional) The Version of the service dependency in the Exchange. If omitted, the highest version is used. The dependencies of the dependency are fetched at the highest version within their version range, like the Horizon Agent does. Mutually exclusive with -p.")).String()
 	devDependencyCmdArch := devDependencyCmd.Flag("arch", msgPrinter.Sprintf("(optional) The hardware Architecture of the service dependency in the Exchange. Mutually exclusive with -p.")).Short('a').String()
 	devDependencyFetchCmd := devDependencyCmd.Command("fetch", msgPrinter.Sprintf("Retrieving Horizon metadata for a new dependency."))
 	devDependencyFetchCmdProject := devDependencyFetchCmd.Flag("project", msgPrinter.Sprintf("Horizon project containing the definition of a dependency. Mutually exclusive with -s -o --ver -a and --url.")).Short('p').ExistingDir()
@@ -553,6 +745,11 @@ Environment Variables:
 
 	agbotCmd := app.Command("agbot", msgPrinter.Sprintf("List and manage Horizon agreement bot resources."))
 
+	agbotAgentUpgradeCmd := agbotCmd.Command("agentupgrade", msgPrinter.Sprintf("List the agent upgrade rollouts this Horizon agreement bot schedules."))
+	agbotAgentUpgradeListCmd := agbotAgentUpgradeCmd.Command("list", msgPrinter.Sprintf("Display the progress of the agent upgrade rollouts, one for each node management policy with an %v job.", "agentUpgrade"))
+	agbotAgentUpgradeListOrg := agbotAgentUpgradeListCmd.Flag("org", msgPrinter.Sprintf("The organization of the node management policy. The default is HZN_ORG_ID environment variable.")).Short('o').String()
+	agbotAgentUpgradeListName := agbotAgentUpgradeListCmd.Arg("nmp-name", msgPrinter.Sprintf("Display the rollout of this node management policy.")).String()
+	agbotAgentUpgradeListLong := agbotAgentUpgradeListCmd.Flag("long", msgPrinter.Sprintf("Display the upgrade state of each node.")).Short('l').Bool()
 	agbotCacheCmd := agbotCmd.Command("cache", msgPrinter.Sprintf("Manage cached agbot-serving organizations, patterns, and deployment policies."))
 	agbotCacheServedOrg := agbotCacheCmd.Command("servedorg", msgPrinter.Sprintf("List served pattern orgs and deployment policy orgs."))
 	agbotCacheServedOrgList := agbotCacheServedOrg.Command("list", msgPrinter.Sprintf("Display served pattern orgs and deployment policy orgs."))
@@ -572,24 +769,43 @@ Environment Variables:
 	agbotAgreementListCmd := agbotAgreementCmd.Command("list", msgPrinter.Sprintf("List the active or archived agreements this Horizon agreement bot has with edge nodes."))
 	agbotlistArchivedAgreements := agbotAgreementListCmd.Flag("archived", msgPrinter.Sprintf("List archived agreements instead of the active agreements.")).Short('r').Bool()
 	agbotAgreement := agbotAgreementListCmd.Arg("agreement", msgPrinter.Sprintf("List just this one agreement.")).String()
+	agbotAgreementListNode := agbotAgreementListCmd.Flag("node", msgPrinter.Sprintf("Only list the agreements with this edge node, <org>/<node> or <node>.")).Short('n').String()
+	agbotAgreementListService := agbotAgreementListCmd.Flag("service", msgPrinter.Sprintf("Only list the agreements for this service, <org>/<url> or <url>.")).Short('s').String()
 	agbotAgreementCancelCmd := agbotAgreementCmd.Command("cancel", msgPrinter.Sprintf("Cancel 1 or all of the active agreements this Horizon agreement bot has with edge nodes. Usually an agbot will immediately negotiated a new agreement. "))
 	agbotCancelAllAgreements := agbotAgreementCancelCmd.Flag("all", msgPrinter.Sprintf("Cancel all of the current agreements.")).Short('a').Bool()
 	agbotCancelAgreementId := agbotAgreementCancelCmd.Arg("agreement", msgPrinter.Sprintf("The active agreement to cancel.")).String()
+	agbotCancelAgreementPattern := agbotAgreementCancelCmd.Flag("pattern", msgPrinter.Sprintf("With -a, only cancel the agreements made for this pattern.")).String()
+	agbotCancelAgreementOrg := agbotAgreementCancelCmd.Flag("org", msgPrinter.Sprintf("With -a, only cancel the agreements made with a policy in this organization.")).String()
+	agbotCancelAgreementOlderThan := agbotAgreementCancelCmd.Flag("older-than", msgPrinter.Sprintf("With -a, only cancel the agreements made longer ago than this age, for example 12h or 7d.")).String()
+	agbotCancelAgreementNode := agbotAgreementCancelCmd.Flag("node", msgPrinter.Sprintf("With -a, only cancel the agreements with this edge node, <org>/<node> or <node>.")).String()
+	agbotCancelAgreementService := agbotAgreementCancelCmd.Flag("service", msgPrinter.Sprintf("With -a, only cancel the agreements for this service, <org>/<url> or <url>.")).String()
+	agbotCancelAgreementConcurrency := agbotAgreementCancelCmd.Flag("concurrency", msgPrinter.Sprintf("The maximum number of agreements to cancel at the same time.")).Default("5").Int()
+	agbotCancelAgreementSummaryFile := agbotAgreementCancelCmd.Flag("summary-file", msgPrinter.Sprintf("Write a JSON summary of the agreements that could not be canceled to this file. The command exits with %d when some but not all of the agreements could not be canceled.", cliutils.PARTIAL_SUCCESS)).String()
 	agbotPolicyCmd := agbotCmd.Command("policy", msgPrinter.Sprintf("List the policies this Horizon agreement bot hosts."))
 	agbotPolicyListCmd := agbotPolicyCmd.Command("list", msgPrinter.Sprintf("List policies this Horizon agreement bot hosts."))
 	agbotPolicyOrg := agbotPolicyListCmd.Arg("org", msgPrinter.Sprintf("The organization the policy belongs to.")).String()
 	agbotPolicyName := agbotPolicyListCmd.Arg("name", msgPrinter.Sprintf("The policy name.")).String()
+	agbotPurgeCmd := agbotCmd.Command("purge", msgPrinter.Sprintf("Remove the archived agreements and the workload usages this Horizon agreement bot has for an edge node, and display a report of what was removed, signed with the agbot's messaging key. The agreements the agbot has with the node must be cancelled first."))
+	agbotPurgeNode := agbotPurgeCmd.Arg("node", msgPrinter.Sprintf("The node whose data is removed, in the form org/id.")).Required().String()
+	agbotPurgeForce := agbotPurgeCmd.Flag("force", msgPrinter.Sprintf("Skip the 'are you sure?' prompt.")).Short('f').Bool()
+	agbotPurgeReportFile := agbotPurgeCmd.Flag("report-file", msgPrinter.Sprintf("Also write the signed deletion report to this file.")).Short('r').String()
 	agbotStatusCmd := agbotCmd.Command("status", msgPrinter.Sprintf("Display the current horizon internal status for the Horizon agreement bot."))
 	agbotStatusLong := agbotStatusCmd.Flag("long", msgPrinter.Sprintf("Show detailed status")).Short('l').Bool()
 
 	utilCmd := app.Command("util", msgPrinter.Sprintf("Utility commands."))
-	utilSignCmd := utilCmd.Command("sign", msgPrinter.Sprintf("Sign the text in stdin. The signature is sent to stdout."))
-	utilSignPrivKeyFile := utilSignCmd.Flag("private-key-file", msgPrinter.Sprintf("The path of a private key file to be used to sign the stdin. ")).Short('k').Required().ExistingFile()
-	utilVerifyCmd := utilCmd.Command("verify", msgPrinter.Sprintf("Verify that the signature specified via -s is a valid signature for the text in stdin."))
-	utilVerifyPubKeyFile := utilVerifyCmd.Flag("public-key-file", msgPrinter.Sprintf("The path of public key file (that corresponds to the private key that was used to sign) to verify the signature of stdin.")).Short('K').Required().ExistingFile()
-	utilVerifySig := utilVerifyCmd.Flag("signature", msgPrinter.Sprintf("The supposed signature of stdin.")).Short('s').Required().String()
+	utilSignCmd := utilCmd.Command("sign", msgPrinter.Sprintf("Sign the text in stdin, or in the file specified via -f. The signature is sent to stdout. It is in the format of the deployment signatures that the Horizon Agent verifies, so that a build pipeline can sign a deployment without publishing it."))
+	utilSignPrivKeyFile := utilSignCmd.Flag("private-key-file", msgPrinter.Sprintf("The path of a private key file to be used to sign the stdin. If not specified, the environment variable HZN_PRIVATE_KEY_FILE will be used. If none of them are set, the default is ~/.hzn/keys/service.private.key.")).Short('k').ExistingFile()
+	utilSignFile := utilSignCmd.Flag("file", msgPrinter.Sprintf("The path of the file to sign, instead of stdin.")).Short('f').Default("-").String()
+	utilVerifyCmd := utilCmd.Command("verify", msgPrinter.Sprintf("Verify that the signature specified via -s or --signature-file is a valid signature for the text in stdin, or in the file specified via -f. Exits with %v when it is not.", cliutils.SIGNATURE_INVALID))
+	utilVerifyPubKeyFile := utilVerifyCmd.Flag("public-key-file", msgPrinter.Sprintf("The path of public key file (that corresponds to the private key that was used to sign) to verify the signature of stdin. If not specified, the environment variable HZN_PUBLIC_KEY_FILE will be used. If none of them are set, the default is ~/.hzn/keys/service.public.pem.")).Short('K').ExistingFile()
+	utilVerifySig := utilVerifyCmd.Flag("signature", msgPrinter.Sprintf("The supposed signature of stdin.")).Short('s').String()
+	utilVerifySigFile := utilVerifyCmd.Flag("signature-file", msgPrinter.Sprintf("The path of a file that has the supposed signature, for example the output of 'hzn util sign'.")).String()
+	utilVerifyFile := utilVerifyCmd.Flag("file", msgPrinter.Sprintf("The path of the file whose signature is verified, instead of stdin.")).Short('f').Default("-").String()
 	utilConfigConvCmd := utilCmd.Command("configconv", msgPrinter.Sprintf("Convert the configuration file from JSON format to a shell script."))
 	utilConfigConvFile := utilConfigConvCmd.Flag("config-file", msgPrinter.Sprintf("The path of a configuration file to be converted. ")).Short('f').Required().ExistingFile()
+	utilValidateCmd := utilCmd.Command("validate", msgPrinter.Sprintf("Validate a service definition, pattern, deployment policy or node management policy file against its schema, listing the line and field of every error. A field that is not known to this version of hzn is only a warning. The publish and add commands do this before sending the file to the Horizon Exchange."))
+	utilValidateFile := utilValidateCmd.Arg("file", msgPrinter.Sprintf("The path of the file to validate, or - to read it from stdin.")).Required().String()
+	utilValidateType := utilValidateCmd.Flag("type", msgPrinter.Sprintf("The kind of resource in the file. When it is not specified it is guessed from the fields of the file.")).Short('t').Enum(schema.Kinds()...)
 
 	mmsCmd := app.Command("mms", msgPrinter.Sprintf("List and manage Horizon Model Management Service resources."))
 	mmsOrg := mmsCmd.Flag("org", msgPrinter.Sprintf("The Horizon organization ID. If not specified, HZN_ORG_ID will be used as a default.")).Short('o').String()
@@ -626,7 +842,7 @@ Environment Variables:
 	mmsObjectDownloadCmd := mmsObjectCmd.Command("download", msgPrinter.Sprintf("Download data of the given object in the Horizon Model Management Service."))
 	mmsObjectDownloadType := mmsObjectDownloadCmd.Flag("type", msgPrinter.Sprintf("The type of the object to download data. This flag must be used with -i.")).Short('t').Required().String()
 	mmsObjectDownloadId := mmsObjectDownloadCmd.Flag("id", msgPrinter.Sprintf("The id of the object to download data. This flag must be used with -t.")).Short('i').Required().String()
-	mmsObjectDownloadFile := mmsObjectDownloadCmd.Flag("file", msgPrinter.Sprintf("The file that the data of downloaded object is written to. This flag must be used with -f. If omit, will use default file name in format of objectType_objectID and save in current directory")).Short('f').String()
+	mmsObjectDownloadFile := mmsObjectDownloadCmd.Flag("file", msgPrinter.Sprintf("The file that the data of downloaded object is written to. This flag must be used with -f. If omit, will use default file name in format of objectType_objectID and save in current directory. Use - to write the data to stdout.")).Short('f').String()
 	mmsObjectDownloadOverwrite := mmsObjectDownloadCmd.Flag("overwrite", msgPrinter.Sprintf("Overwrite the existing file if it exists in the file system.")).Short('O').Bool()
 
 	voucherCmd := app.Command("voucher", msgPrinter.Sprintf("List and manage Horizon SDO ownership vouchers."))
@@ -645,6 +861,8 @@ Environment Variables:
 	voucherListCmd := voucherCmd.Command("list", msgPrinter.Sprintf("List the imported SDO ownership vouchers."))
 	voucherToList := voucherListCmd.Arg("voucher", msgPrinter.Sprintf("List the full details of this SDO ownership voucher.")).String()
 	voucherListLong := voucherListCmd.Flag("long", msgPrinter.Sprintf("When a voucher uuid is specified the full contents of the voucher will be listed, otherwise the full contents of all the imported vouchers will be listed.")).Short('l').Bool()
+	voucherStatusCmd := voucherCmd.Command("status", msgPrinter.Sprintf("Show the onboarding status of the devices of the imported SDO ownership vouchers: 'no node' if the exchange does not have the node of the device, 'waiting' until the device has booted and registered, 'registered' until its agent has heartbeated, and then 'heartbeating'."))
+	voucherToStatus := voucherStatusCmd.Arg("voucher", msgPrinter.Sprintf("Show the onboarding status of the device of this SDO ownership voucher.")).String()
 
 	app.VersionFlag = nil
 
@@ -657,9 +875,17 @@ Environment Variables:
 	*/
 
 	// Parse cmd and apply env var defaults
-	fullCmd := kingpin.MustParse(app.Parse(os.Args[1:]))
+	fullCmd, parseErr := app.Parse(os.Args[1:])
+	cliutils.ParseError(os.Args[1:], parseErr)
+	fullCmd = kingpin.MustParse(fullCmd, parseErr)
 	//cliutils.Verbose("Full command: %s", fullCmd)
 
+	// apply the settings of the profile, 'hzn config set' can create the profile
+	cliconfig.UseProfile(*profileName, fullCmd != configSetCmd.FullCommand())
+
+	// Ctrl-C cancels the requests in flight
+	cliutils.CancelOnInterrupt()
+
 	// mms command is not supported for on a cluster node
 	if strings.HasPrefix(fullCmd, "mms ") {
 		if _, err := rest.InClusterConfig(); err == nil {
@@ -693,6 +919,10 @@ Environment Variables:
 			credToUse = cliutils.GetExchangeAuth(*exUserPw, *exNodeSetTokNodeIdTok)
 		case "node remove":
 			credToUse = cliutils.GetExchangeAuth(*exUserPw, *exNodeRemoveNodeIdTok)
+		case "node heartbeat":
+			credToUse = cliutils.GetExchangeAuth(*exUserPw, *exNodeHeartbeatNodeIdTok)
+		case "node auth verify":
+			//do nothing because it uses the node id and token given in the -n flag or HZN_EXCHANGE_NODE_AUTH as the credential
 		case "node confirm":
 			//do nothing because it uses the node id and token given in the argument as the credential
 		case "node listpolicy":
@@ -737,11 +967,18 @@ Environment Variables:
 			credToUse = cliutils.GetExchangeAuth(*exUserPw, *exBusinessAddPolicyIdTok)
 		case "deployment removepolicy":
 			credToUse = cliutils.GetExchangeAuth(*exUserPw, *exBusinessRemovePolicyIdTok)
+		case "nmp list":
+			credToUse = cliutils.GetExchangeAuth(*exUserPw, *exNMPListIdTok)
+		case "nmp add":
+			credToUse = cliutils.GetExchangeAuth(*exUserPw, *exNMPAddIdTok)
+		case "nmp remove":
+			credToUse = cliutils.GetExchangeAuth(*exUserPw, *exNMPRemoveIdTok)
 		case "version":
 			credToUse = cliutils.GetExchangeAuthVersion(*exUserPw)
 		default:
-			// get HZN_EXCHANGE_USER_AUTH as default if exUserPw is empty
-			exUserPw = cliutils.RequiredWithDefaultEnvVar(exUserPw, "HZN_EXCHANGE_USER_AUTH", msgPrinter.Sprintf("exchange user authentication must be specified with either the -u flag or HZN_EXCHANGE_USER_AUTH"))
+			// get --user-auth or HZN_EXCHANGE_USER_AUTH as default if exUserPw is empty
+			userAuth := cliutils.RequiredUserAuth(*exUserPw)
+			exUserPw = &userAuth
 		}
 
 		if exVersion := exchange.LoadExchangeVersion(false, *exOrg, credToUse, *exUserPw); exVersion != "" {
@@ -752,11 +989,13 @@ Environment Variables:
 	}
 
 	if strings.HasPrefix(fullCmd, "register") {
-		// use HZN_EXCHANGE_USER_AUTH for -u
-		userPw = cliutils.WithDefaultEnvVar(userPw, "HZN_EXCHANGE_USER_AUTH")
+		// use --user-auth or HZN_EXCHANGE_USER_AUTH for -u
+		userAuth := cliutils.GetUserAuth(*userPw)
+		userPw = &userAuth
 
-		// use HZN_EXCHANGE_NODE_AUTH for -n and trim the org
-		nodeIdTok = cliutils.WithDefaultEnvVar(nodeIdTok, "HZN_EXCHANGE_NODE_AUTH")
+		// use --node-auth or HZN_EXCHANGE_NODE_AUTH for -n
+		nodeAuth := cliutils.GetNodeAuth(*nodeIdTok)
+		nodeIdTok = &nodeAuth
 
 		// use HZN_ORG_ID or org provided by -o for version check
 		verCheckOrg := cliutils.WithDefaultEnvVar(org, "HZN_ORG_ID")
@@ -770,7 +1009,8 @@ Environment Variables:
 
 	if strings.HasPrefix(fullCmd, "deploycheck") {
 		deploycheckOrg = cliutils.WithDefaultEnvVar(deploycheckOrg, "HZN_ORG_ID")
-		deploycheckUserPw = cliutils.WithDefaultEnvVar(deploycheckUserPw, "HZN_EXCHANGE_USER_AUTH")
+		deploycheckUserAuth := cliutils.GetUserAuth(*deploycheckUserPw)
+		deploycheckUserPw = &deploycheckUserAuth
 		if *policyCompBPolId == "" {
 			policyCompBPolId = policyCompDepPolId
 		}
@@ -800,7 +1040,8 @@ Environment Variables:
 	// For the mms command family, make sure that org and exchange credentials are specified in some way.
 	if strings.HasPrefix(fullCmd, "mms") {
 		mmsOrg = cliutils.RequiredWithDefaultEnvVar(mmsOrg, "HZN_ORG_ID", msgPrinter.Sprintf("organization ID must be specified with either the -o flag or HZN_ORG_ID"))
-		mmsUserPw = cliutils.RequiredWithDefaultEnvVar(mmsUserPw, "HZN_EXCHANGE_USER_AUTH", msgPrinter.Sprintf("exchange user authentication must be specified with either the -u flag or HZN_EXCHANGE_USER_AUTH"))
+		mmsUserAuth := cliutils.RequiredUserAuth(*mmsUserPw)
+		mmsUserPw = &mmsUserAuth
 
 		if *mmsObjectListId == "" {
 			mmsObjectListId = mmsObjectListObjId
@@ -813,11 +1054,13 @@ Environment Variables:
 	// For the voucher import command family, make sure that org and exchange credentials are specified in some way.
 	if strings.HasPrefix(fullCmd, "voucher import") {
 		voucherOrg = cliutils.RequiredWithDefaultEnvVar(voucherOrg, "HZN_ORG_ID", msgPrinter.Sprintf("organization ID must be specified with either the -o flag or HZN_ORG_ID"))
-		voucherUserPw = cliutils.RequiredWithDefaultEnvVar(voucherUserPw, "HZN_EXCHANGE_USER_AUTH", msgPrinter.Sprintf("exchange user authentication must be specified with either the -u flag or HZN_EXCHANGE_USER_AUTH"))
+		voucherUserAuth := cliutils.RequiredUserAuth(*voucherUserPw)
+		voucherUserPw = &voucherUserAuth
 	}
-	if strings.HasPrefix(fullCmd, "voucher list") {
+	if strings.HasPrefix(fullCmd, "voucher list") || strings.HasPrefix(fullCmd, "voucher status") {
 		voucherOrg = cliutils.RequiredWithDefaultEnvVar(voucherOrg, "HZN_ORG_ID", msgPrinter.Sprintf("organization ID must be specified with either the -o flag or HZN_ORG_ID"))
-		voucherUserPw = cliutils.RequiredWithDefaultEnvVar(voucherUserPw, "HZN_EXCHANGE_USER_AUTH", msgPrinter.Sprintf("exchange user authentication must be specified with either the -u flag or HZN_EXCHANGE_USER_AUTH"))
+		voucherUserAuth := cliutils.RequiredUserAuth(*voucherUserPw)
+		voucherUserPw = &voucherUserAuth
 	}
 
 	// key file defaults
@@ -833,6 +1076,10 @@ Environment Variables:
 		exSvcPubKeyFile = cliutils.WithDefaultEnvVar(exSvcPubKeyFile, "HZN_PUBLIC_KEY_FILE")
 	case "key import":
 		keyImportPubKeyFile = cliutils.WithDefaultEnvVar(keyImportPubKeyFile, "HZN_PUBLIC_KEY_FILE")
+	case "util sign":
+		utilSignPrivKeyFile = cliutils.WithDefaultEnvVar(utilSignPrivKeyFile, "HZN_PRIVATE_KEY_FILE")
+	case "util verify":
+		utilVerifyPubKeyFile = cliutils.WithDefaultEnvVar(utilVerifyPubKeyFile, "HZN_PUBLIC_KEY_FILE")
 	}
 
 	// set env variable ARCH if it is not set
@@ -841,11 +1088,24 @@ Environment Variables:
 	// Decide which command to run
 	switch fullCmd {
 	case envCmd.FullCommand():
+		if *envCheck {
+			node.EnvCheck()
+			break
+		} else if *envLong {
+			node.EnvLong()
+			break
+		}
 		envOrg := os.Getenv("HZN_ORG_ID")
 		envUserPw := os.Getenv("HZN_EXCHANGE_USER_AUTH")
 		envExchUrl := cliutils.GetExchangeUrl()
 		envCcsUrl := cliutils.GetMMSUrl()
 		node.Env(envOrg, envUserPw, envExchUrl, envCcsUrl)
+	case configSetCmd.FullCommand():
+		cliconfig.ConfigSet(*profileName, *configSetKey, *configSetValue)
+	case configUseCmd.FullCommand():
+		cliconfig.ConfigUse(*configUseProfile)
+	case configViewCmd.FullCommand():
+		cliconfig.ConfigView(*profileName)
 	case versionCmd.FullCommand():
 		node.Version()
 	case archCmd.FullCommand():
@@ -872,20 +1132,30 @@ Environment Variables:
 		exchange.UserSetAdmin(*exOrg, *exUserPw, *exUserSetAdminUser, *exUserSetAdminBool)
 	case exUserSetHubAdminCmd.FullCommand():
 		exchange.UserSetHubAdmin(*exOrg, *exUserPw, *exUserSetHubAminUser, *exUserSetHubAdminBool)
+	case exUserChangePwCmd.FullCommand():
+		exchange.UserChangePassword(*exOrg, *exUserPw, *exUserChangePwUser, *exUserChangePwPw)
+	case exUserAuthVerifyCmd.FullCommand():
+		exchange.UserAuthVerify(*exOrg, *exUserPw)
 	case exUserDelCmd.FullCommand():
 		exchange.UserRemove(*exOrg, *exUserPw, *exDelUser, *exUserDelForce)
 	case exNodeListCmd.FullCommand():
-		exchange.NodeList(*exOrg, credToUse, *exNode, !*exNodeLong)
+		exchange.NodeList(*exOrg, credToUse, *exNode, !*exNodeLong, *exNodeOutput, *exNodeColumns)
 	case exNodeUpdateCmd.FullCommand():
-		exchange.NodeUpdate(*exOrg, credToUse, *exNodeUpdateNode, *exNodeUpdateJsonFile)
+		exchange.NodeUpdate(*exOrg, credToUse, *exNodeUpdateNode, *exNodeUpdateJsonFile, *exNodeUpdateMergePatch, *exNodeUpdateIfLastUpdated)
 	case exNodeCreateCmd.FullCommand():
 		exchange.NodeCreate(*exOrg, *exNodeCreateNodeIdTok, *exNodeCreateNode, *exNodeCreateToken, *exUserPw, *exNodeCreateNodeArch, *exNodeCreateNodeName, *exNodeCreateNodeType, true)
+	case exNodeCreateBulkCmd.FullCommand():
+		exchange.NodeCreateBulk(*exOrg, *exUserPw, *exNodeCreateBulkFile, *exNodeCreateBulkConcurrency, *exNodeCreateBulkScriptDir, *exNodeCreateBulkSummaryFile)
 	case exNodeSetTokCmd.FullCommand():
-		exchange.NodeSetToken(*exOrg, credToUse, *exNodeSetTokNode, *exNodeSetTokToken)
+		exchange.NodeSetToken(*exOrg, credToUse, *exNodeSetTokNode, *exNodeSetTokToken, *exNodeSetTokRotate)
+	case exNodeHeartbeatCmd.FullCommand():
+		exchange.NodeHeartbeat(*exOrg, credToUse, *exNodeHeartbeatNode)
 	case exNodeConfirmCmd.FullCommand():
-		exchange.NodeConfirm(*exOrg, *exNodeConfirmNode, *exNodeConfirmToken, *exNodeConfirmNodeIdTok)
+		exchange.NodeConfirm(*exOrg, *exNodeConfirmNode, *exNodeConfirmToken, *exNodeConfirmNodeIdTok, *exUserPw, *exNodeConfirmHeartbeatWithin)
+	case exNodeAuthVerifyCmd.FullCommand():
+		exchange.NodeAuthVerify(*exOrg, *exNodeAuthVerifyNodeIdTok)
 	case exNodeDelCmd.FullCommand():
-		exchange.NodeRemove(*exOrg, credToUse, *exDelNode, *exNodeDelForce)
+		exchange.NodeRemove(*exOrg, credToUse, *exDelNode, *exNodeDelForce, *exNodeDelStale, *exNodeDelIncludeNever)
 	case exNodeListPolicyCmd.FullCommand():
 		exchange.NodeListPolicy(*exOrg, credToUse, *exNodeListPolicyNode)
 	case exNodeAddPolicyCmd.FullCommand():
@@ -897,8 +1167,11 @@ Environment Variables:
 	case exNodeErrorsList.FullCommand():
 		exchange.NodeListErrors(*exOrg, credToUse, *exNodeErrorsListNode, *exNodeErrorsListLong)
 	case exNodeStatusList.FullCommand():
-		exchange.NodeListStatus(*exOrg, credToUse, *exNodeStatusListNode)
+		exchange.NodeListStatus(*exOrg, credToUse, *exNodeStatusListNode, *exNodeStatusConcurrency, *exNodeStatusSummaryFile)
 
+	case agbotAgentUpgradeListCmd.FullCommand():
+		agbotAgentUpgradeListOrg = cliutils.WithDefaultEnvVar(agbotAgentUpgradeListOrg, "HZN_ORG_ID")
+		agreementbot.AgentUpgradeList(*agbotAgentUpgradeListOrg, *agbotAgentUpgradeListName, *agbotAgentUpgradeListLong)
 	case agbotCacheServedOrgList.FullCommand():
 		agreementbot.GetServedOrgs()
 	case agbotCachePatternList.FullCommand():
@@ -908,6 +1181,10 @@ Environment Variables:
 
 	case exAgbotListCmd.FullCommand():
 		exchange.AgbotList(*exOrg, *exUserPw, *exAgbot, !*exAgbotLong)
+	case exAgbotCreateCmd.FullCommand():
+		exchange.AgbotCreate(*exOrg, *exUserPw, *exAgbotCreateAgbot, "", *exAgbotCreateName)
+	case exAgbotDelCmd.FullCommand():
+		exchange.AgbotRemove(*exOrg, *exUserPw, *exAgbotDelAgbot, *exAgbotDelForce)
 	case exAgbotListPatsCmd.FullCommand():
 		exchange.AgbotListPatterns(*exOrg, *exUserPw, *exAgbotLP, *exAgbotLPPatOrg, *exAgbotLPPat, *exAgbotLPNodeOrg)
 	case exAgbotAddPatCmd.FullCommand():
@@ -921,7 +1198,7 @@ Environment Variables:
 	case exAgbotDelPolCmd.FullCommand():
 		exchange.AgbotRemoveBusinessPolicy(*exOrg, *exUserPw, *exAgbotDPolAg, *exAgbotDPPolOrg)
 	case exPatternListCmd.FullCommand():
-		exchange.PatternList(*exOrg, credToUse, *exPattern, !*exPatternLong)
+		exchange.PatternList(*exOrg, credToUse, *exPattern, !*exPatternLong, *exPatternOutput, *exPatternColumns)
 	case exPatternPublishCmd.FullCommand():
 		exchange.PatternPublish(*exOrg, *exUserPw, *exPatJsonFile, *exPatKeyFile, *exPatPubPubKeyFile, *exPatName)
 	case exPatternVerifyCmd.FullCommand():
@@ -931,13 +1208,13 @@ Environment Variables:
 	case exPatternListKeyCmd.FullCommand():
 		exchange.PatternListKey(*exOrg, credToUse, *exPatListKeyPat, *exPatListKeyKey)
 	case exPatUpdateCmd.FullCommand():
-		exchange.PatternUpdate(*exOrg, credToUse, *exPatUpdatePattern, *exPatUpdateJsonFile)
+		exchange.PatternUpdate(*exOrg, credToUse, *exPatUpdatePattern, *exPatUpdateJsonFile, *exPatUpdateIfLastUpdated)
 	case exPatternRemKeyCmd.FullCommand():
 		exchange.PatternRemoveKey(*exOrg, *exUserPw, *exPatRemKeyPat, *exPatRemKeyKey)
 	case exServiceListCmd.FullCommand():
-		exchange.ServiceList(*exOrg, credToUse, *exService, !*exServiceLong, *exSvcOpYamlFilePath, *exSvcOpYamlForce)
+		exchange.ServiceList(*exOrg, credToUse, *exService, !*exServiceLong, *exSvcOpYamlFilePath, *exSvcOpYamlForce, *exServiceOutput, *exServiceColumns)
 	case exServicePublishCmd.FullCommand():
-		exchange.ServicePublish(*exOrg, *exUserPw, *exSvcJsonFile, *exSvcPrivKeyFile, *exSvcPubPubKeyFile, *exSvcPubDontTouchImage, *exSvcPubPullImage, *exSvcRegistryTokens, *exSvcOverwrite, *exSvcPolicyFile, *exSvcPublic)
+		exchange.ServicePublish(*exOrg, *exUserPw, *exSvcJsonFile, *exSvcPrivKeyFile, *exSvcPubPubKeyFile, *exSvcPubDontTouchImage, *exSvcPubPullImage, *exSvcPubResolveDigest, *exSvcRegistryTokens, *exSvcOverwrite, *exSvcPolicyFile, *exSvcPublic)
 	case exServiceVerifyCmd.FullCommand():
 		exchange.ServiceVerify(*exOrg, credToUse, *exVerService, *exSvcPubKeyFile)
 	case exSvcDelCmd.FullCommand():
@@ -970,14 +1247,24 @@ Environment Variables:
 		exchange.BusinessUpdatePolicy(*exOrg, credToUse, *exBusinessUpdatePolicyPolicy, *exBusinessUpdatePolicyJsonFile)
 	case exBusinessRemovePolicyCmd.FullCommand():
 		exchange.BusinessRemovePolicy(*exOrg, credToUse, *exBusinessRemovePolicyPolicy, *exBusinessRemovePolicyForce)
+	case exNMPListCmd.FullCommand():
+		exchange.NMPList(*exOrg, credToUse, *exNMPListName, !*exNMPListLong)
+	case exNMPNewCmd.FullCommand():
+		exchange.NMPNew()
+	case exNMPAddCmd.FullCommand():
+		exchange.NMPAdd(*exOrg, credToUse, *exNMPAddName, *exNMPAddJsonFile, *exNMPAddNoConstraint)
+	case exNMPRemoveCmd.FullCommand():
+		exchange.NMPRemove(*exOrg, credToUse, *exNMPRemoveName, *exNMPRemoveForce)
 	case exCatalogServiceListCmd.FullCommand():
 		exchange.CatalogServiceList(*exOrg, *exUserPw, *exCatalogServiceListShort, *exCatalogServiceListLong)
 	case exCatalogPatternListCmd.FullCommand():
 		exchange.CatalogPatternList(*exOrg, *exUserPw, *exCatalogPatternListShort, *exCatalogPatternListLong)
+	case exSearchNodesCmd.FullCommand():
+		exchange.SearchNodes(*exOrg, *exUserPw, *exSearchNodesSvcUrl, *exSearchNodesVersion, *exSearchNodesArch, *exSearchNodesNodeOrg, *exSearchNodesProps)
 	case regInputCmd.FullCommand():
 		register.CreateInputFile(*regInputOrg, *regInputPattern, *regInputArch, *regInputNodeIdTok, *regInputInputFile)
 	case registerCmd.FullCommand():
-		register.DoIt(*org, *pattern, *nodeIdTok, *userPw, *inputFile, *nodeOrgFlag, *patternFlag, *nodeName, *nodepolicyFlag, *waitServiceFlag, *waitServiceOrgFlag, *waitTimeoutFlag)
+		register.DoIt(*org, *pattern, *nodeIdTok, *userPw, *inputFile, *nodeOrgFlag, *patternFlag, *patternFileFlag, *nodeName, *nodepolicyFlag, *waitServiceFlag, *waitServiceOrgFlag, *waitTimeoutFlag)
 	case keyListCmd.FullCommand():
 		key.List(*keyName, *keyListAll)
 	case keyCreateCmd.FullCommand():
@@ -988,6 +1275,12 @@ Environment Variables:
 		key.Remove(*keyDelName)
 	case nodeListCmd.FullCommand():
 		node.List()
+	case nodeUpdateCmd.FullCommand():
+		node.Update(*nodeUpdateName, *nodeUpdatePattern, *nodeUpdateProperties)
+	case nodeResourcesCmd.FullCommand():
+		node.Resources()
+	case nodePurgeCmd.FullCommand():
+		node.Purge(*nodePurgeForce, *nodePurgeReportFile)
 	case policyListCmd.FullCommand():
 		policy.List()
 	case policyNewCmd.FullCommand():
@@ -996,6 +1289,8 @@ Environment Variables:
 		policy.Update(*policyUpdateInputFile)
 	case policyPatchCmd.FullCommand():
 		policy.Patch(*policyPatchInput)
+	case policyCompatCmd.FullCommand():
+		policy.Compat(*policyCompatNodePolFile, *policyCompatBusPolFile, *policyCompatSvcPolFile)
 	case policyRemoveCmd.FullCommand():
 		policy.Remove(*policyRemoveForce)
 	case policyCompCmd.FullCommand():
@@ -1005,13 +1300,19 @@ Environment Variables:
 	case allCompCmd.FullCommand():
 		deploycheck.AllCompatible(*deploycheckOrg, *deploycheckUserPw, *allCompNodeId, *allCompNodeArch, *allCompNodeType, *allCompNodePolFile, *allCompNodeUIFile, *allCompBPolId, *allCompBPolFile, *allCompPatternId, *allCompPatternFile, *allCompSPolFile, *allCompSvcFile, *deploycheckCheckAll, *deploycheckLong)
 	case agreementListCmd.FullCommand():
-		agreement.List(*listArchivedAgreements, *listAgreementId)
+		agreement.List(*listArchivedAgreements, *listAgreementId, *listAgreementRaw, *listAgreementOutput, *listAgreementColumns, *listAgreementWorkload, *listAgreementState, *listAgreementSince)
 	case agreementCancelCmd.FullCommand():
-		agreement.Cancel(*cancelAgreementId, *cancelAllAgreements)
+		agreement.Cancel(*cancelAgreementId, *cancelAllAgreements, *cancelAgreementPattern, *cancelAgreementOrg, *cancelAgreementOlderThan, *cancelAgreementConcurrency, *cancelAgreementSummaryFile)
 	case meteringListCmd.FullCommand():
-		metering.List(*listArchivedMetering)
+		metering.List(*listArchivedMetering, *listMeteringReport)
 	case attributeListCmd.FullCommand():
 		attribute.List()
+	case attributeAddCmd.FullCommand():
+		attribute.Add(*attributeAddFilePath)
+	case attributeUpdateCmd.FullCommand():
+		attribute.Update(*attributeUpdateId, *attributeUpdateFilePath)
+	case attributeRemoveCmd.FullCommand():
+		attribute.Remove(*attributeRemoveId, *attributeRemoveForce)
 	case userinputListCmd.FullCommand():
 		userinput.List()
 	case userinputNewCmd.FullCommand():
@@ -1020,8 +1321,16 @@ Environment Variables:
 		userinput.Add(*userinputAddFilePath)
 	case userinputUpdateCmd.FullCommand():
 		userinput.Update(*userinputUpdateFilePath)
+	case userinputGetCmd.FullCommand():
+		userinput.Get(*userinputGetService, *userinputGetVariable, *userinputGetArch, *userinputGetVersionRange)
+	case userinputSetCmd.FullCommand():
+		userinput.Set(*userinputSetService, *userinputSetArch, *userinputSetVersionRange, *userinputSetVariables)
 	case userinputRemoveCmd.FullCommand():
-		userinput.Remove(*userinputRemoveForce)
+		if *userinputRemoveService == "" {
+			userinput.Remove(*userinputRemoveForce)
+		} else {
+			userinput.RemoveVariables(*userinputRemoveService, *userinputRemoveArch, *userinputRemoveVersionRange, *userinputRemoveVariables, *userinputRemoveForce)
+		}
 	case serviceListCmd.FullCommand():
 		service.List()
 	case serviceLogCmd.FullCommand():
@@ -1035,13 +1344,31 @@ Environment Variables:
 	case serviceConfigStateActiveCmd.FullCommand():
 		service.Resume(*resumeAllServices, *resumeServiceOrg, *resumeServiceName)
 	case unregisterCmd.FullCommand():
-		unregister.DoIt(*forceUnregister, *removeNodeUnregister, *deepCleanUnregister, *timeoutUnregister)
+		unregister.DoIt(*forceUnregister, *removeNodeUnregister, *deepCleanUnregister, *removeImagesUnregister, *timeoutUnregister)
 	case statusCmd.FullCommand():
-		status.DisplayStatus(*statusLong, false)
+		if *statusHealth {
+			status.DisplayHealth()
+		} else {
+			status.DisplayStatus(*statusLong, false)
+		}
+	case wiotpOrgCmd.FullCommand():
+		wiotp.Org(*wiotpUserPw)
+	case wiotpConfigCmd.FullCommand():
+		wiotp.Config(*wiotpUserPw)
+	case supportDumpCmd.FullCommand():
+		support.Dump(*supportDumpFile, *supportDumpIncludeJournal, *supportDumpJournalLines)
+	case watchCmd.FullCommand():
+		watch.Watch(*watchInterval, *watchCount)
 	case eventlogListCmd.FullCommand():
-		eventlog.List(*listAllEventlogs, *listDetailedEventlogs, *listSelectedEventlogs, *listTail)
+		eventlog.List(*listAllEventlogs, *listDetailedEventlogs, *listSelectedEventlogs, *listTail, *listEventlogsSince, *listEventlogsSeverity)
+	case eventlogTailCmd.FullCommand():
+		eventlog.Tail(*tailDetailedEventlogs, *tailSelectedEventlogs, *tailEventlogsSeverity, *tailEventlogsLines)
+	case eventlogExportCmd.FullCommand():
+		eventlog.Export(*exportAllEventlogs, *exportSelectedEventlogs, *exportEventlogsSince, *exportEventlogsFormat, *exportEventlogsFile)
 	case surfaceErrorsEventlogs.FullCommand():
 		eventlog.ListSurfaced(*surfaceErrorsEventlogsLong)
+	case nmpStatusCmd.FullCommand():
+		nmp.Status(*nmpStatusName)
 	case devServiceNewCmd.FullCommand():
 		dev.ServiceNew(*devHomeDirectory, *devServiceNewCmdOrg, *devServiceNewCmdName, *devServiceNewCmdVer, *devServiceNewCmdImage, *devServiceNewCmdNoImageGen, *devServiceNewCmdCfg, *devServiceNewCmdNoPattern, *devServiceNewCmdNoPolicy)
 	case devServiceStartTestCmd.FullCommand():
@@ -1059,21 +1386,25 @@ Environment Variables:
 	case devDependencyRemoveCmd.FullCommand():
 		dev.DependencyRemove(*devHomeDirectory, *devDependencyCmdSpecRef, *devDependencyCmdURL, *devDependencyCmdVersion, *devDependencyCmdArch, *devDependencyCmdOrg)
 	case agbotAgreementListCmd.FullCommand():
-		agreementbot.AgreementList(*agbotlistArchivedAgreements, *agbotAgreement)
+		agreementbot.AgreementList(*agbotlistArchivedAgreements, *agbotAgreement, *agbotAgreementListNode, *agbotAgreementListService)
 	case agbotAgreementCancelCmd.FullCommand():
-		agreementbot.AgreementCancel(*agbotCancelAgreementId, *agbotCancelAllAgreements)
+		agreementbot.AgreementCancel(*agbotCancelAgreementId, *agbotCancelAllAgreements, *agbotCancelAgreementPattern, *agbotCancelAgreementOrg, *agbotCancelAgreementOlderThan, *agbotCancelAgreementNode, *agbotCancelAgreementService, *agbotCancelAgreementConcurrency, *agbotCancelAgreementSummaryFile)
 	case agbotListCmd.FullCommand():
 		agreementbot.List()
 	case agbotPolicyListCmd.FullCommand():
 		agreementbot.PolicyList(*agbotPolicyOrg, *agbotPolicyName)
 	case utilSignCmd.FullCommand():
-		utilcmds.Sign(*utilSignPrivKeyFile)
+		utilcmds.Sign(*utilSignPrivKeyFile, *utilSignFile)
 	case utilVerifyCmd.FullCommand():
-		utilcmds.Verify(*utilVerifyPubKeyFile, *utilVerifySig)
+		utilcmds.Verify(*utilVerifyPubKeyFile, *utilVerifySig, *utilVerifySigFile, *utilVerifyFile)
+	case agbotPurgeCmd.FullCommand():
+		agreementbot.Purge(*agbotPurgeNode, *agbotPurgeForce, *agbotPurgeReportFile)
 	case agbotStatusCmd.FullCommand():
 		status.DisplayStatus(*agbotStatusLong, true)
 	case utilConfigConvCmd.FullCommand():
 		utilcmds.ConvertConfig(*utilConfigConvFile)
+	case utilValidateCmd.FullCommand():
+		utilcmds.Validate(*utilValidateFile, *utilValidateType)
 	case mmsStatusCmd.FullCommand():
 		sync_service.Status(*mmsOrg, *mmsUserPw)
 	case mmsObjectListCmd.FullCommand():
@@ -1092,5 +1423,7 @@ Environment Variables:
 		sdo.VoucherImport(*voucherOrg, *voucherUserPw, *voucherImportFile, *voucherImportExample, *voucherImportPolicy, *voucherImportPattern)
 	case voucherListCmd.FullCommand():
 		sdo.VoucherList(*voucherOrg, *voucherUserPw, *voucherToList, !*voucherListLong)
+	case voucherStatusCmd.FullCommand():
+		sdo.VoucherStatus(*voucherOrg, *voucherUserPw, *voucherToStatus)
 	}
 }
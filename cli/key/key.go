@@ -1,7 +1,6 @@
 package key
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/open-horizon/anax/api"
 	"github.com/open-horizon/anax/cli/cliutils"
@@ -21,6 +20,8 @@ type KeyPairSimpleOutput struct {
 	SerialNumber     string `json:"serial_number"`
 	NotValidBefore   string `json:"not_valid_before"`
 	NotValidAfter    string `json:"not_valid_after"`
+	Expired          bool   `json:"expired"`
+	Fingerprint      string `json:"fingerprint"`
 }
 
 type KeyList struct {
@@ -34,11 +35,7 @@ func List(keyName string, listAll bool) {
 	if keyName == "" && listAll {
 		var apiOutput KeyList
 		cliutils.HorizonGet("trust", []int{200}, &apiOutput, false)
-		jsonBytes, err := json.MarshalIndent(apiOutput.Pem, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'key list' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(apiOutput.Pem, "key list")
 	} else if keyName == "" {
 		// Getting all of the keys only returns the names
 		var apiOutput map[string][]api.KeyPairSimpleRecord
@@ -61,14 +58,12 @@ func List(keyName string, listAll bool) {
 				OrganizationName: kps.SubjectNames["organizationName (O)"].(string),
 				NotValidBefore:   kps.NotValidBefore.String(),
 				NotValidAfter:    kps.NotValidAfter.String(),
+				Expired:          time.Now().After(kps.NotValidAfter),
+				Fingerprint:      kps.Fingerprint,
 			})
 		}
 
-		jsonBytes, err := json.MarshalIndent(certsSimpleOutput, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'key list' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(certsSimpleOutput, "key list")
 	} else {
 		// Get the content of 1 key, which is not json
 		var apiOutput string
@@ -134,9 +129,14 @@ func Import(pubKeyFile string) {
 	//take default key if empty, make sure the key exists
 	pubKeyFile = cliutils.VerifySigningKeyInput(pubKeyFile, true)
 
-	bodyBytes := cliutils.ReadFile(pubKeyFile)
+	// the key is streamed from the file
+	keyFile, err := os.Open(pubKeyFile)
+	if err != nil {
+		cliutils.Fatal(cliutils.FILE_IO_ERROR, i18n.GetMessagePrinter().Sprintf("unable to open %v: %v", pubKeyFile, err))
+	}
+	defer keyFile.Close()
 	baseName := filepath.Base(pubKeyFile)
-	cliutils.HorizonPutPost(http.MethodPut, "trust/"+baseName, []int{201, 200}, bodyBytes, true)
+	cliutils.HorizonPutPost(http.MethodPut, "trust/"+baseName, []int{201, 200}, keyFile, true)
 }
 
 func Remove(keyName string) {
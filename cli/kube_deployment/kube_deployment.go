@@ -1,6 +1,8 @@
 package kube_deployment
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -10,9 +12,11 @@ import (
 	"github.com/open-horizon/anax/cli/plugin_registry"
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/rsapss-tool/sign"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 const KUBE_DEPLOYMENT_CONFIG_TYPE = "cluster"
@@ -50,6 +54,11 @@ func (p *KubeDeploymentConfigPlugin) Sign(dep map[string]interface{}, keyFilePat
 		operatorFilePath = filepath.Join(currentDir, operatorFilePath)
 	}
 
+	// Make sure the agent will be able to install the operator before it is published.
+	if err := ValidateOperatorArchive(operatorFilePath); err != nil {
+		return true, "", "", errors.New(msgPrinter.Sprintf("the kube operator %v can not be deployed, error %v", dep["operatorYamlArchive"], err))
+	}
+
 	// Get the base 64 encoding of the kube operator, and put it into the deployment config.
 	if b64, err := ConvertFileToB64String(operatorFilePath); err != nil {
 		return true, "", "", errors.New(msgPrinter.Sprintf("unable to read kube operator %v, error %v", dep["operatorYamlArchive"], err))
@@ -100,6 +109,11 @@ func (p *KubeDeploymentConfigPlugin) Validate(dep interface{}, cdep interface{})
 	if dc, ok := cdep.(map[string]interface{}); !ok {
 		return false, nil
 	} else if c, ok := dc["operatorYamlArchive"]; !ok {
+		// A docker deployment config put in the cluster deployment by mistake is reported here, rather than as a
+		// deployment config that no plugin supports.
+		if _, ok := dc["services"]; ok {
+			return true, errors.New(msgPrinter.Sprintf("clusterDeployment must have an operatorYamlArchive, the docker 'services' belong in the deployment"))
+		}
 		return false, nil
 	} else if ca, ok := c.(string); !ok {
 		return true, errors.New(msgPrinter.Sprintf("operatorYamlArchive must have a string type value, has %T", c))
@@ -168,6 +182,43 @@ func (p *KubeDeploymentConfigPlugin) StopTest(homeDirectory string) bool {
 	return true
 }
 
+// ValidateOperatorArchive checks that the file is a gzipped tar archive that has at least one yaml file, which is what
+// the agent on a cluster node expects the operatorYamlArchive to be. The input filepath is assumed to be absolute.
+func ValidateOperatorArchive(filePath string) error {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	zipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return errors.New(msgPrinter.Sprintf("it is not a gzipped tar archive: %v", err))
+	}
+	tarReader := tar.NewReader(zipReader)
+
+	yamlFiles := 0
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return errors.New(msgPrinter.Sprintf("it is not a gzipped tar archive: %v", err))
+		} else if header.Typeflag == tar.TypeDir {
+			continue
+		}
+		if ext := strings.ToLower(filepath.Ext(header.Name)); ext == ".yaml" || ext == ".yml" {
+			yamlFiles++
+		}
+	}
+	if yamlFiles == 0 {
+		return errors.New(msgPrinter.Sprintf("the archive does not have any yaml files"))
+	}
+	return nil
+}
+
 // Convert a file into a base 64 encoded string. The input filepath is assumed to be absolute.
 func ConvertFileToB64String(filePath string) (string, error) {
 
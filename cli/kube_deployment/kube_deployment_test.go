@@ -0,0 +1,98 @@
+// +build unit
+
+package kube_deployment
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Write a gzipped tar archive of the files, by name, into the dir.
+func writeArchive(t *testing.T, dir string, name string, files map[string]string) string {
+	path := filepath.Join(dir, name)
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("unable to create %v: %v", path, err)
+	}
+	defer file.Close()
+
+	zipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(zipWriter)
+	for fileName, content := range files {
+		if err := tarWriter.WriteHeader(&tar.Header{Name: fileName, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		} else if _, err := tarWriter.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	} else if err := zipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func Test_ValidateOperatorArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-operator-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	operator := writeArchive(t, dir, "operator.tar.gz", map[string]string{"deploy/operator.yaml": "kind: Deployment", "README.md": "docs"})
+	if err := ValidateOperatorArchive(operator); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	noYaml := writeArchive(t, dir, "docs.tar.gz", map[string]string{"README.md": "docs"})
+	if err := ValidateOperatorArchive(noYaml); err == nil || !strings.Contains(err.Error(), "does not have any yaml files") {
+		t.Errorf("expected an archive without yaml files to be rejected, found %v", err)
+	}
+
+	notArchive := filepath.Join(dir, "operator.yaml")
+	if err := ioutil.WriteFile(notArchive, []byte("kind: Deployment"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateOperatorArchive(notArchive); err == nil || !strings.Contains(err.Error(), "not a gzipped tar archive") {
+		t.Errorf("expected a yaml file to be rejected, found %v", err)
+	}
+
+	if err := ValidateOperatorArchive(filepath.Join(dir, "missing.tar.gz")); err == nil {
+		t.Errorf("expected a missing file to be rejected")
+	}
+}
+
+func Test_Validate(t *testing.T) {
+	p := NewKubeDeploymentConfigPlugin()
+
+	for _, tc := range []struct {
+		dep      interface{}
+		cdep     interface{}
+		owned    bool
+		errorMsg string
+	}{
+		{nil, map[string]interface{}{"operatorYamlArchive": "operator.tar.gz"}, true, ""},
+		{map[string]interface{}{"services": map[string]interface{}{}}, map[string]interface{}{"operatorYamlArchive": "operator.tar.gz"}, false, ""},
+		{nil, map[string]interface{}{"services": map[string]interface{}{}}, true, "the docker 'services' belong in the deployment"},
+		{nil, map[string]interface{}{"other": "config"}, false, ""},
+		{nil, map[string]interface{}{"operatorYamlArchive": 5}, true, "must have a string type value"},
+		{nil, map[string]interface{}{"operatorYamlArchive": ""}, true, "must be non-empty"},
+		{nil, "not a map", false, ""},
+	} {
+		owned, err := p.Validate(tc.dep, tc.cdep)
+		if owned != tc.owned {
+			t.Errorf("expected %v to be owned %v, found %v", tc.cdep, tc.owned, owned)
+		}
+		if tc.errorMsg == "" && err != nil {
+			t.Errorf("unexpected error %v for %v", err, tc.cdep)
+		} else if tc.errorMsg != "" && (err == nil || !strings.Contains(err.Error(), tc.errorMsg)) {
+			t.Errorf("expected the error %q for %v, found %v", tc.errorMsg, tc.cdep, err)
+		}
+	}
+}
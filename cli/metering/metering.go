@@ -1,8 +1,8 @@
 package metering
 
 import (
-	"encoding/json"
 	"fmt"
+	"github.com/open-horizon/anax/cli/agreement"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/persistence"
@@ -89,7 +89,50 @@ func (a *ArchivedMetering) CopyAgreementInto(agreement persistence.EstablishedAg
 	a.MeteringNotificationMsg.CopyMeteringInto(agreement.MeteringNotificationMsg)
 }
 
-func List(archivedMetering bool) {
+// MeteringReport is a row of 'hzn metering list --report', the tokens that the node was credited for one agreement.
+type MeteringReport struct {
+	CurrentAgreementId    string `json:"current_agreement_id"`
+	Name                  string `json:"name"`
+	ConsumerId            string `json:"consumer_id"`
+	TokensAccrued         uint64 `json:"tokens_accrued"`                      // the total tokens accrued since the metering started, as of the most recent metering notification
+	Rate                  string `json:"rate,omitempty"`                      // the tokens per time unit of the metering policy of the agreement
+	NotificationInterval  int    `json:"notification_interval,omitempty"`     // the seconds between the metering notifications
+	MissedTime            uint64 `json:"missed_time"`                         // the seconds that the consumer detected missing data
+	StartTime             string `json:"start_time"`                          // when the metering of the agreement started
+	LastMeterTime         string `json:"last_meter_time"`                     // when the most recent metering notification was sent
+	AgreementTerminatedAt string `json:"agreement_terminated_time,omitempty"` // only for the archived agreements
+}
+
+// Create the report of the metering of an agreement. The rate comes from the metering policy in the terms and
+// conditions of the proposal, it is empty when the agreement is not metered.
+func newMeteringReport(ag persistence.EstablishedAgreement, archived bool) MeteringReport {
+	m := ag.MeteringNotificationMsg
+	r := MeteringReport{
+		CurrentAgreementId: ag.CurrentAgreementId,
+		Name:               ag.Name,
+		ConsumerId:         ag.ConsumerId,
+		TokensAccrued:      m.Amount,
+		MissedTime:         m.MissedTime,
+		StartTime:          cliutils.ConvertTime(m.StartTime),
+		LastMeterTime:      cliutils.ConvertTime(m.CurrentTime),
+	}
+	if archived {
+		r.AgreementTerminatedAt = cliutils.ConvertTime(ag.AgreementTerminatedTime)
+	}
+	if ag.Proposal != "" {
+		if prop, err := agreement.DecodeProposal(ag.Proposal); err != nil {
+			cliutils.Verbose(i18n.GetMessagePrinter().Sprintf("Unable to decode the proposal of agreement %v: %v", ag.CurrentAgreementId, err))
+		} else if prop.TsAndCs != nil {
+			if meter := prop.TsAndCs.DataVerify.Metering; meter.Tokens != 0 {
+				r.Rate = fmt.Sprintf("%v/%v", meter.Tokens, meter.PerTimeUnit)
+				r.NotificationInterval = meter.NotificationIntervalS
+			}
+		}
+	}
+	return r
+}
+
+func List(archivedMetering bool, report bool) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
@@ -109,25 +152,23 @@ func List(archivedMetering bool) {
 	}
 
 	// Go thru the apiAgreements and convert into our output struct and then print
-	if !archivedMetering {
+	if report {
+		rows := make([]MeteringReport, len(apiAgreements))
+		for i := range apiAgreements {
+			rows[i] = newMeteringReport(apiAgreements[i], archivedMetering)
+		}
+		cliutils.Output(rows, "hzn metering list")
+	} else if !archivedMetering {
 		metering := make([]ActiveMetering, len(apiAgreements))
 		for i := range apiAgreements {
 			metering[i].CopyAgreementInto(apiAgreements[i])
 		}
-		jsonBytes, err := json.MarshalIndent(metering, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn metering list' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(metering, "hzn metering list")
 	} else {
 		metering := make([]ArchivedMetering, len(apiAgreements))
 		for i := range apiAgreements {
 			metering[i].CopyAgreementInto(apiAgreements[i])
 		}
-		jsonBytes, err := json.MarshalIndent(metering, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn metering list' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(metering, "hzn metering list")
 	}
 }
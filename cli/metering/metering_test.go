@@ -0,0 +1,48 @@
+// +build unit
+
+package metering
+
+import (
+	"encoding/json"
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_List_report(t *testing.T) {
+	h := clitest.New(t)
+
+	tsandcs := `{"header":{"name":"netspeed","version":"2.0"},"dataVerification":{"enabled":true,"metering":{"tokens":2,"per_time_unit":"min","notification_interval":30}}}`
+	proposal, _ := json.Marshal(map[string]interface{}{"type": "proposal", "protocol": "Basic", "version": 1, "agreementId": "a1", "tsandcs": tsandcs})
+	h.Agent.Handle(http.MethodGet, "agreement", http.StatusOK, map[string]interface{}{"agreements": map[string]interface{}{
+		"active": []interface{}{
+			map[string]interface{}{"current_agreement_id": "a1", "name": "netspeed", "consumer_id": "myorg/agbot", "proposal": string(proposal),
+				"metering_notification": map[string]interface{}{"amount": 42, "start_time": 1600000000, "current_time": 1600001260, "missed_time": 10}},
+			map[string]interface{}{"current_agreement_id": "a2", "name": "gps", "consumer_id": "myorg/agbot"},
+		},
+		"archived": []interface{}{},
+	}})
+
+	res := h.Run(func() { List(false, true) })
+	if res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	var report []MeteringReport
+	res.JSON(t, &report)
+	if len(report) != 2 {
+		t.Fatalf("expected 2 agreements, found %v", report)
+	} else if r := report[0]; r.TokensAccrued != 42 || r.Rate != "2/min" || r.NotificationInterval != 30 || r.MissedTime != 10 || r.LastMeterTime != cliutils.ConvertTime(1600001260) {
+		t.Errorf("wrong metering of a1: %+v", r)
+	} else if r := report[1]; r.TokensAccrued != 0 || r.Rate != "" {
+		t.Errorf("a2 is not metered: %+v", r)
+	}
+
+	// a row per agreement in the table
+	h.Setenv("HZN_OUTPUT_FORMAT", "table")
+	res = h.Run(func() { List(false, true) })
+	if lines := strings.Split(strings.TrimSpace(res.Stdout), "\n"); res.ExitCode != 0 || len(lines) != 3 || !strings.Contains(lines[1], "2/min") {
+		t.Errorf("wrong table, exit code %v, output: %v", res.ExitCode, res.Stdout)
+	}
+}
@@ -38,7 +38,7 @@ func (p *NativeDeploymentConfigPlugin) Sign(dep map[string]interface{}, keyFileP
 
 	// Since the deployment config has been validated as ours, we can assume it is structured correctly.
 	services := dep["services"].(map[string]interface{})
-	var dontTouchImage, pullImage, ok bool
+	var dontTouchImage, pullImage, resolveDigest, ok bool
 	dontTouchImage, ok = (ctx.Get("dontTouchImage")).(bool)
 	if !ok {
 		dontTouchImage = false
@@ -47,12 +47,16 @@ func (p *NativeDeploymentConfigPlugin) Sign(dep map[string]interface{}, keyFileP
 	if !ok {
 		pullImage = false
 	}
+	resolveDigest, ok = (ctx.Get("resolveDigest")).(bool)
+	if !ok {
+		resolveDigest = false
+	}
 
 	for _, svc := range services {
 		service := svc.(map[string]interface{})
 		image := service["image"].(string)
 
-		newImage := cliutils.GetNewDockerImageName(image, dontTouchImage, pullImage)
+		newImage := cliutils.GetNewDockerImageName(image, dontTouchImage, pullImage, resolveDigest)
 		if newImage != image {
 			msgPrinter.Printf("Using '%s' in 'deployment' field instead of '%s'", newImage, image)
 			msgPrinter.Println()
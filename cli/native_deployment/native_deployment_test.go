@@ -0,0 +1,140 @@
+// +build unit
+
+package native_deployment
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/cli/exchange"
+	exchangeapi "github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/rsapss-tool/generatekeys"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The publishing of a service signs its deployment with this plugin.
+func Test_ServicePublish_keyPair(t *testing.T) {
+	h := clitest.New(t)
+	dir, err := ioutil.TempDir("", "clitest-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	keys := func(name string) (string, string) {
+		keyDir := filepath.Join(dir, name)
+		os.Mkdir(keyDir, 0700)
+		files, err := generatekeys.Write(keyDir, 2048, "me@myorg.com", clitest.FAKE_ORG, time.Now().AddDate(0, 0, 1))
+		if err != nil {
+			t.Fatalf("could not create the keys, error %v", err)
+		}
+		privKey, pubKey := "", ""
+		for _, f := range files {
+			if strings.HasSuffix(f, "-public.pem") {
+				pubKey = f
+			} else {
+				privKey = f
+			}
+		}
+		return privKey, pubKey
+	}
+	privKey, pubKey := keys("keys")
+	_, otherPubKey := keys("other")
+
+	svcFile := filepath.Join(dir, "service.json")
+	svc := `{"org": "myorg", "label": "netspeed", "url": "netspeed", "version": "1.0.0", "arch": "amd64", "sharable": "multiple",
+		"deployment": {"services": {"netspeed": {"image": "myorg/netspeed:1.0.0"}}}}`
+	if err := ioutil.WriteFile(svcFile, []byte(svc), 0644); err != nil {
+		t.Fatalf("unable to write %v: %v", svcFile, err)
+	}
+	h.Exchange.Handle(http.MethodPost, "orgs/myorg/services", http.StatusCreated, map[string]interface{}{})
+	h.Exchange.Handle(http.MethodPut, "orgs/myorg/services/netspeed_1.0.0_amd64/keys/"+filepath.Base(pubKey), http.StatusCreated, map[string]interface{}{})
+
+	publish := func(pubKey string) clitest.Result {
+		return h.Run(func() {
+			exchange.ServicePublish(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, svcFile, privKey, pubKey, true, false, false, nil, true, "", "")
+		})
+	}
+	if res := publish(otherPubKey); res.ExitCode != cliutils.SIGNATURE_INVALID || !strings.Contains(res.Stderr, "is not the public key of the private key") {
+		t.Errorf("expected an invalid signature, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if reqs := h.Exchange.RequestsTo(http.MethodPost, "orgs/myorg/services"); len(reqs) != 0 {
+		t.Errorf("the service should not be published with the wrong public key")
+	}
+
+	if res := publish(pubKey); res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if len(h.Exchange.RequestsTo(http.MethodPost, "orgs/myorg/services")) != 1 || len(h.Exchange.RequestsTo(http.MethodPut, "orgs/myorg/services/netspeed_1.0.0_amd64/keys/"+filepath.Base(pubKey))) != 1 {
+		t.Errorf("expected the service and its public key to be published")
+	}
+}
+
+func Test_ServicePublish_resolveDigest(t *testing.T) {
+	h := clitest.New(t)
+	dir, err := ioutil.TempDir("", "clitest-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files, err := generatekeys.Write(dir, 2048, "me@myorg.com", clitest.FAKE_ORG, time.Now().AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("could not create the keys, error %v", err)
+	}
+	privKey, pubKey := "", ""
+	for _, f := range files {
+		if strings.HasSuffix(f, "-public.pem") {
+			pubKey = f
+		} else {
+			privKey = f
+		}
+	}
+
+	// the registry has the tag of the image, which is not in the local docker
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	registry := clitest.NewFakeServer(t)
+	registry.HandleFunc(http.MethodGet, "v2/myorg/netspeed/manifests/1.0.0", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.Write([]byte(`{}`))
+	})
+	domain := strings.TrimPrefix(registry.URL, "http://")
+
+	svcFile := filepath.Join(dir, "service.json")
+	svc := fmt.Sprintf(`{"org": "myorg", "label": "netspeed", "url": "netspeed", "version": "1.0.0", "arch": "amd64", "sharable": "multiple",
+		"deployment": {"services": {"netspeed": {"image": "%v/myorg/netspeed:1.0.0"}}}}`, domain)
+	if err := ioutil.WriteFile(svcFile, []byte(svc), 0644); err != nil {
+		t.Fatalf("unable to write %v: %v", svcFile, err)
+	}
+	h.Exchange.Handle(http.MethodPost, "orgs/myorg/services", http.StatusCreated, map[string]interface{}{})
+	h.Exchange.Handle(http.MethodPut, "orgs/myorg/services/netspeed_1.0.0_amd64/keys/"+filepath.Base(pubKey), http.StatusCreated, map[string]interface{}{})
+
+	res := h.Run(func() {
+		exchange.ServicePublish(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, svcFile, privKey, pubKey, false, false, true, nil, true, "", "")
+	})
+	if res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	reqs := h.Exchange.RequestsTo(http.MethodPost, "orgs/myorg/services")
+	if len(reqs) != 1 {
+		t.Fatalf("expected the service to be published, found %v requests", len(reqs))
+	}
+	published := exchangeapi.ServiceDefinition{}
+	if err := json.Unmarshal(reqs[0].Body, &published); err != nil {
+		t.Fatalf("the published service is not JSON: %v", err)
+	} else if expected := domain + "/myorg/netspeed@" + digest; !strings.Contains(published.Deployment, expected) {
+		t.Errorf("the deployment should use %v, found %v", expected, published.Deployment)
+	}
+
+	// the digest is not resolved with -I
+	if res := h.Run(func() {
+		exchange.ServicePublish(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, svcFile, privKey, pubKey, true, false, true, nil, true, "", "")
+	}); res.ExitCode != cliutils.CLI_INPUT_ERROR {
+		t.Errorf("expected exit code %v, found %v", cliutils.CLI_INPUT_ERROR, res.ExitCode)
+	}
+}
@@ -0,0 +1,38 @@
+package nmp
+
+import (
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/persistence"
+)
+
+// Status displays the status of the node management policy jobs on this node, or of just the one policy if a name is
+// given. A name without an org is in the org of the node.
+func Status(nmpName string) {
+
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	statuses := make(map[string]persistence.NMPStatus)
+	cliutils.HorizonGet("nodemanagement/status", []int{200}, &statuses, false)
+
+	if nmpName != "" {
+		status, ok := statuses[nmpName]
+		if !ok {
+			// look for the name in each org, a node only sees the policies of its own org
+			for name, s := range statuses {
+				if _, id := cliutils.TrimOrg("", name); id == nmpName {
+					status, ok = s, true
+					break
+				}
+			}
+		}
+		if !ok {
+			cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("Node management policy %v has no status on this node.", nmpName))
+		}
+		cliutils.Output(status, "nmp status")
+		return
+	}
+
+	cliutils.Output(statuses, "nmp status")
+}
@@ -0,0 +1,151 @@
+package node
+
+import (
+	"fmt"
+	"github.com/open-horizon/anax/apicommon"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/i18n"
+	"os"
+	"strings"
+	"time"
+)
+
+// EnvCheck shows the effective configuration like EnvLong, then checks that hzn can reach the Horizon Agent API, the
+// Exchange, with the credentials, the Model Management Service and the agbot API, so that the user can see which
+// endpoint or credential is wrong. Each check is tried once, without the retries. Exits with an error if a check fails.
+func EnvCheck() {
+	msgPrinter := i18n.GetMessagePrinter()
+	EnvLong()
+
+	// a check should not wait for the retries of an endpoint that is down
+	if os.Getenv("HZN_HTTP_RETRIES") == "" {
+		os.Setenv("HZN_HTTP_RETRIES", "0")
+	}
+
+	fmt.Println()
+	msgPrinter.Printf("Connectivity checks:")
+	msgPrinter.Println()
+	checks, failed := 0, 0
+	check := func(name string, endpoint string, f func() (string, error)) {
+		checks++
+		start := time.Now()
+		result, err := f()
+		elapsed := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			failed++
+			msgPrinter.Printf("  %s %s: FAILED: %s", name, endpoint, strings.TrimSpace(err.Error()))
+		} else {
+			msgPrinter.Printf("  %s %s: OK, %s (%v)", name, endpoint, result, elapsed)
+		}
+		msgPrinter.Println()
+	}
+	skip := func(name string, reason string) {
+		msgPrinter.Printf("  %s: skipped, %s", name, reason)
+		msgPrinter.Println()
+	}
+
+	// the agent api and its version
+	check(msgPrinter.Sprintf("Horizon Agent API"), cliutils.GetHorizonUrlBase(), func() (string, error) {
+		status := apicommon.Info{}
+		if _, err := cliutils.HorizonGetE("status", []int{200}, &status); err != nil {
+			return "", err
+		} else if status.Configuration == nil {
+			return msgPrinter.Sprintf("no agent configuration"), nil
+		}
+		return msgPrinter.Sprintf("agent version %v", status.Configuration.HorizonVersion), nil
+	})
+
+	// the exchange, and the identity of the credentials in it
+	exchUrl := ""
+	if err := cliutils.Try(func() { exchUrl = cliutils.GetExchangeUrl() }); err != nil || exchUrl == "" {
+		skip(msgPrinter.Sprintf("Exchange"), msgPrinter.Sprintf("the exchange URL is not set"))
+	} else {
+		check(msgPrinter.Sprintf("Exchange"), exchUrl, func() (string, error) {
+			var output []byte
+			if _, err := cliutils.ExchangeGetE("Exchange", exchUrl, "admin/version", "", []int{200}, &output); err != nil {
+				return "", err
+			}
+			return msgPrinter.Sprintf("exchange version %v", strings.TrimSpace(string(output))), nil
+		})
+	}
+
+	// the credential helper can fail too
+	org := os.Getenv("HZN_ORG_ID")
+	creds, credsPath := "", "users"
+	credsErr := cliutils.Try(func() { creds = cliutils.GetUserAuth("") })
+	if creds == "" && credsErr == nil {
+		creds, credsPath = cliutils.GetNodeAuth(""), "nodes"
+	}
+	if credsErr != nil {
+		check(msgPrinter.Sprintf("Exchange credentials"), os.Getenv(cliutils.CREDENTIAL_HELPER_ENV), func() (string, error) { return "", credsErr })
+	} else if exchUrl == "" {
+		skip(msgPrinter.Sprintf("Exchange credentials"), msgPrinter.Sprintf("the exchange URL is not set"))
+	} else if creds == "" {
+		skip(msgPrinter.Sprintf("Exchange credentials"), msgPrinter.Sprintf("no exchange credentials are set"))
+	} else {
+		cliutils.SetWhetherUsingWiotp(creds)
+		scheme, credsOrg, _ := cliutils.GetAuthScheme(creds)
+		if credsOrg != "" {
+			org = credsOrg
+		}
+		id, _ := cliutils.SplitIdToken(creds)
+		id = id[strings.Index(id, "/")+1:]
+
+		// the user or node can read itself, the credentials with only a token have no id
+		urlPath, identity := "orgs/"+org, org
+		if scheme == cliutils.AUTH_SCHEME_BASIC {
+			urlPath, identity = urlPath+"/"+credsPath+"/"+id, org+"/"+id
+		}
+		if org == "" {
+			skip(msgPrinter.Sprintf("Exchange credentials"), msgPrinter.Sprintf("the organization is not set"))
+		} else {
+			check(msgPrinter.Sprintf("Exchange credentials"), identity, func() (string, error) {
+				if _, err := cliutils.ExchangeGetE("Exchange", exchUrl, urlPath, cliutils.OrgAndCreds(org, creds), []int{200}, nil); err != nil {
+					return "", err
+				}
+				return msgPrinter.Sprintf("%v auth", scheme), nil
+			})
+		}
+	}
+
+	// the model management service, it needs the credentials
+	mmsUrl := os.Getenv("HZN_FSS_CSSURL")
+	if mmsUrl == "" {
+		mmsUrl = cliutils.GetMMSUrlFromAnax()
+	}
+	if mmsUrl == "" {
+		skip(msgPrinter.Sprintf("Model Management Service"), msgPrinter.Sprintf("the model management service URL is not set"))
+	} else if creds == "" || org == "" {
+		skip(msgPrinter.Sprintf("Model Management Service"), msgPrinter.Sprintf("no exchange credentials are set"))
+	} else {
+		mmsUrl = strings.TrimSuffix(mmsUrl, "/")
+		check(msgPrinter.Sprintf("Model Management Service"), mmsUrl, func() (string, error) {
+			if _, err := cliutils.ExchangeGetE("Model Management Service", mmsUrl, "api/v1/health", cliutils.OrgAndCreds(org, creds), []int{200}, nil); err != nil {
+				return "", err
+			}
+			return msgPrinter.Sprintf("healthy"), nil
+		})
+	}
+
+	// the agbot api is only checked when it is not the agent api, the check sends the rest of the calls to the agbot
+	if agbotUrl := os.Getenv("HZN_AGBOT_API"); agbotUrl == "" || agbotUrl == cliutils.GetHorizonUrlBase() {
+		skip(msgPrinter.Sprintf("Horizon Agbot API"), msgPrinter.Sprintf("HZN_AGBOT_API is not set"))
+	} else {
+		cliutils.UseAgbotUrlBase()
+		check(msgPrinter.Sprintf("Horizon Agbot API"), agbotUrl, func() (string, error) {
+			status := apicommon.Info{}
+			if _, err := cliutils.HorizonGetE("status", []int{200}, &status); err != nil {
+				return "", err
+			} else if status.Configuration == nil {
+				return msgPrinter.Sprintf("no agbot configuration"), nil
+			}
+			return msgPrinter.Sprintf("agbot version %v", status.Configuration.HorizonVersion), nil
+		})
+	}
+
+	if failed > 0 {
+		cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf("%v of the %v connectivity checks failed", failed, checks))
+	}
+	msgPrinter.Printf("All the %v connectivity checks passed.", checks)
+	msgPrinter.Println()
+}
@@ -0,0 +1,38 @@
+// +build unit
+
+package node
+
+import (
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_EnvCheck(t *testing.T) {
+	h := clitest.New(t)
+	h.Exchange.AddResource("orgs/"+clitest.FAKE_ORG+"/users/myuser", map[string]interface{}{"email": "me@example.com"})
+	h.Exchange.Handle(http.MethodGet, "api/v1/health", http.StatusOK, map[string]interface{}{"general": map[string]interface{}{"healthStatus": "green"}})
+	h.Setenv("HZN_FSS_CSSURL", h.Exchange.URL)
+	h.Setenv("HZN_HTTP_RETRIES", "")
+	os.Unsetenv("HZN_HTTP_RETRIES")
+
+	res := h.Run(func() { EnvCheck() })
+	if res.ExitCode != 0 || strings.Contains(res.Stdout, "FAILED") || strings.Contains(res.Stdout, "mypw") {
+		t.Fatalf("expected every check to pass, exit code %v, output %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+	for _, expected := range []string{"agent version", "exchange version " + clitest.FAKE_EXCHANGE_VERSION, "Exchange credentials myorg/myuser: OK", "Model Management Service " + h.Exchange.URL + ": OK", "Horizon Agbot API " + h.Agbot.URL + ": OK"} {
+		if !strings.Contains(res.Stdout, expected) {
+			t.Errorf("expected %q in the output, found %v", expected, res.Stdout)
+		}
+	}
+
+	// a user that is not in the exchange fails its check only
+	h.Setenv("HZN_EXCHANGE_USER_AUTH", "other:mypw")
+	res = h.Run(func() { EnvCheck() })
+	if res.ExitCode != cliutils.HTTP_ERROR || !strings.Contains(res.Stdout, "Exchange credentials myorg/other: FAILED") || strings.Count(res.Stdout, "FAILED") != 1 {
+		t.Errorf("expected the credentials check to fail, exit code %v, output %v", res.ExitCode, res.Stdout)
+	}
+}
@@ -5,10 +5,17 @@ import (
 	"fmt"
 	"github.com/open-horizon/anax/api"
 	"github.com/open-horizon/anax/apicommon"
+	"github.com/open-horizon/anax/cli/cliconfig"
 	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/config"
 	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/externalpolicy"
 	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/semanticversion"
 	"github.com/open-horizon/anax/version"
+	"os"
+	"strconv"
 	"strings"
 )
 
@@ -80,13 +87,11 @@ func List() {
 	nodeInfo.CopyStatusInto(&status)
 
 	// Output the combined info
-	jsonBytes, err := json.MarshalIndent(nodeInfo, "", cliutils.JSON_INDENT)
-	if err != nil {
-		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn node list' output: %v", err))
-	}
-	fmt.Printf("%s\n", jsonBytes) //todo: is there a way to output with json syntax highlighting like jq does?
+	cliutils.Output(nodeInfo, "hzn node list")
 }
 
+// Version shows the versions of hzn, of the Horizon Agent and of the Exchange that the node uses, and warns about the
+// combinations of them that are known not to work.
 func Version() {
 	// Show hzn version
 	msgPrinter := i18n.GetMessagePrinter()
@@ -104,9 +109,77 @@ func Version() {
 		if err != nil {
 			cliutils.Verbose(err.Error())
 		}
+		status.Configuration = nil
 		msgPrinter.Printf("Horizon Agent version: failed to get.")
 		msgPrinter.Println()
 	}
+
+	// Show exchange version, the exchange does not need credentials for it
+	exchVersion := ""
+	exchUrl := ""
+	if err := cliutils.Try(func() { exchUrl = cliutils.GetExchangeUrl() }); err != nil {
+		cliutils.Verbose(err.Error())
+	} else if exchUrl != "" {
+		var output []byte
+		if _, err := cliutils.ExchangeGetE("Exchange", exchUrl, "admin/version", "", []int{200}, &output); err != nil {
+			cliutils.Verbose(err.Error())
+		} else {
+			exchVersion = strings.TrimSpace(string(output))
+		}
+	}
+	if exchVersion != "" {
+		msgPrinter.Printf("Horizon Exchange version: %s", exchVersion)
+	} else {
+		msgPrinter.Printf("Horizon Exchange version: failed to get.")
+	}
+	msgPrinter.Println()
+
+	agentVersion, agentMinExchVersion := "", ""
+	if status.Configuration != nil {
+		agentVersion, agentMinExchVersion = status.Configuration.HorizonVersion, status.Configuration.MinExchVersion
+	}
+	for _, w := range VersionIncompatibilities(version.HORIZON_VERSION, agentVersion, agentMinExchVersion, exchVersion) {
+		cliutils.Warning(w)
+	}
+}
+
+// VersionIncompatibilities returns the reasons why the versions of hzn, of the agent and of the exchange are known not
+// to work together: an exchange older than the minimum exchange version of hzn or of the agent, an agent with another
+// major version than hzn, or an agent newer than hzn. The versions that are empty or are not release versions, like a
+// local build, are not checked.
+func VersionIncompatibilities(cliVersion string, agentVersion string, agentMinExchVersion string, exchVersion string) []string {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	cliVersion, agentVersion = releaseVersion(cliVersion), releaseVersion(agentVersion)
+	agentMinExchVersion, exchVersion = releaseVersion(agentMinExchVersion), releaseVersion(exchVersion)
+	older := func(v1 string, v2 string) bool {
+		comp, err := semanticversion.CompareVersions(v1, v2)
+		return err == nil && comp < 0
+	}
+
+	reasons := []string{}
+	if older(exchVersion, version.MINIMUM_EXCHANGE_VERSION) {
+		reasons = append(reasons, msgPrinter.Sprintf("the Horizon Exchange version %v is older than the version %v that the Horizon CLI requires.", exchVersion, version.MINIMUM_EXCHANGE_VERSION))
+	}
+	if agentMinExchVersion != version.MINIMUM_EXCHANGE_VERSION && older(exchVersion, agentMinExchVersion) {
+		reasons = append(reasons, msgPrinter.Sprintf("the Horizon Exchange version %v is older than the version %v that the Horizon Agent requires.", exchVersion, agentMinExchVersion))
+	}
+	if semanticversion.IsVersionString(cliVersion) && semanticversion.IsVersionString(agentVersion) {
+		if strings.Split(cliVersion, ".")[0] != strings.Split(agentVersion, ".")[0] {
+			reasons = append(reasons, msgPrinter.Sprintf("the Horizon CLI version %v and the Horizon Agent version %v are different major versions, which are not compatible. Install the Horizon CLI of the agent version.", cliVersion, agentVersion))
+		} else if older(cliVersion, agentVersion) {
+			reasons = append(reasons, msgPrinter.Sprintf("the Horizon CLI version %v is older than the Horizon Agent version %v, some of the agent features can not be used with it.", cliVersion, agentVersion))
+		}
+	}
+	return reasons
+}
+
+// The release version part of a version, without the build number of a version like 2.28.0-338.
+func releaseVersion(v string) string {
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		return v[:i]
+	}
+	return v
 }
 
 func Architecture() {
@@ -114,20 +187,39 @@ func Architecture() {
 	fmt.Printf("%s\n", cutil.ArchString())
 }
 
+// Resources displays the hardware resources of this node that the agent detected: its arch, cpus, memory, disk and
+// GPUs.
+func Resources() {
+	var resources persistence.NodeResources
+	if httpCode, _ := cliutils.HorizonGet("node/resources", []int{200, 404}, &resources, false); httpCode == 404 {
+		cliutils.Fatal(cliutils.NOT_FOUND, i18n.GetMessagePrinter().Sprintf("The agent has not detected the resources of this node yet."))
+	}
+	cliutils.Output(resources, "hzn node resources")
+}
+
+// hide the password or token part of the credentials
+func maskCredentials(creds string) string {
+	mask := "******"
+	if strings.Contains(creds, "iamapikey:") {
+		creds = "iamapikey:" + mask
+	} else if strings.ContainsAny(creds, ":") {
+		user := strings.Split(creds, ":")
+		creds = user[0] + ":" + mask
+	} else if scheme, _, _ := cliutils.GetAuthScheme(creds); creds != "" && scheme != cliutils.AUTH_SCHEME_BASIC {
+		// the credentials are only the token
+		creds = mask
+	}
+	return creds
+}
+
 func Env(org, userPw, exchUrl, cssUrl string) {
 	// Show hzn Environment Variables
-	mask := "******"
 	msgPrinter := i18n.GetMessagePrinter()
 	msgPrinter.Printf("Horizon Agent HZN Environment Variables are:")
 	msgPrinter.Println()
 	msgPrinter.Printf("HZN_ORG_ID: %s", org)
 	msgPrinter.Println()
-	if strings.Contains(userPw, "iamapikey:") {
-		userPw = "iamapikey:" + mask
-	} else if strings.ContainsAny(userPw, ":") {
-		user := strings.Split(userPw, ":")
-		userPw = user[0] + ":" + mask
-	}
+	userPw = maskCredentials(userPw)
 	msgPrinter.Printf("HZN_EXCHANGE_USER_AUTH: %s", userPw)
 	msgPrinter.Println()
 	msgPrinter.Printf("HZN_EXCHANGE_URL: %s", exchUrl)
@@ -135,3 +227,263 @@ func Env(org, userPw, exchUrl, cssUrl string) {
 	msgPrinter.Printf("HZN_FSS_CSSURL: %s", cssUrl)
 	msgPrinter.Println()
 }
+
+// Returns the value of the env var and where it came from, or the default value if the env var is not set.
+func envVarWithSource(name string, defaultValue string, defaultSource string) (string, string) {
+	if value := os.Getenv(name); value != "" {
+		return value, name + " (" + cliconfig.GetEnvVarSource(name) + ")"
+	}
+	return defaultValue, defaultSource
+}
+
+// EnvLong shows each configuration input that hzn uses, with its effective value and where the value came from.
+func EnvLong() {
+	msgPrinter := i18n.GetMessagePrinter()
+	notSet := msgPrinter.Sprintf("not set")
+	defaultSource := msgPrinter.Sprintf("default")
+
+	type configInput struct {
+		name   string
+		value  string
+		source string
+	}
+	inputs := []configInput{}
+	add := func(name, value, source string) {
+		inputs = append(inputs, configInput{name: name, value: value, source: source})
+	}
+
+	// the profile of ~/.hzn/config that the settings below can come from
+	profile, profileSource := cliconfig.GetActiveProfile()
+	if profileSource == cliconfig.PROFILE_ENV {
+		profileSource += " (" + cliconfig.GetEnvVarSource(profileSource) + ")"
+	}
+	add(msgPrinter.Sprintf("Profile"), profile, profileSource)
+
+	// the horizon agent and agbot apis
+	agentUrl, agentUrlSource := envVarWithSource("HORIZON_URL", cliutils.GetHorizonUrlBase(), defaultSource)
+	if cliutils.Opts.HorizonUrl != nil && *cliutils.Opts.HorizonUrl != "" {
+		agentUrl, agentUrlSource = cliutils.GetHorizonUrlBase(), "--horizon-url"
+	}
+	add(msgPrinter.Sprintf("Horizon Agent URL"), agentUrl, agentUrlSource)
+	agbotUrl, agbotUrlSource := envVarWithSource("HZN_AGBOT_API", agentUrl, agentUrlSource)
+	add(msgPrinter.Sprintf("Horizon Agbot URL"), agbotUrl, agbotUrlSource)
+
+	// the exchange url used by hzn and the one the agent is configured with
+	anaxExchUrl := cliutils.GetExchangeUrlFromAnax()
+	if (cliutils.Opts.ExchangeUrl != nil && *cliutils.Opts.ExchangeUrl != "") || os.Getenv("HZN_EXCHANGE_URL") != "" || os.Getenv(cliutils.EXCHANGE_URL_BASE_ENV) != "" || anaxExchUrl != "" {
+		source := cliutils.GetExchangeUrlLocation()
+		if source == "HZN_EXCHANGE_URL" || source == cliutils.EXCHANGE_URL_BASE_ENV {
+			source += " (" + cliconfig.GetEnvVarSource(source) + ")"
+		}
+		add(msgPrinter.Sprintf("Exchange URL"), cliutils.GetExchangeUrl(), source)
+	} else {
+		add(msgPrinter.Sprintf("Exchange URL"), "", "")
+	}
+	if anaxExchUrl != "" {
+		add(msgPrinter.Sprintf("Exchange URL in the agent configuration"), strings.TrimSuffix(anaxExchUrl, "/"), cliutils.GetExchangeUrlLocationFromAnax())
+	} else {
+		add(msgPrinter.Sprintf("Exchange URL in the agent configuration"), "", "")
+	}
+
+	// the model management service url
+	if cssUrl, source := envVarWithSource("HZN_FSS_CSSURL", cliutils.GetMMSUrlFromAnax(), cliutils.ANAX_CONFIG_FILE); cssUrl != "" {
+		add(msgPrinter.Sprintf("Model Management Service URL"), cssUrl, source)
+	} else {
+		add(msgPrinter.Sprintf("Model Management Service URL"), "", "")
+	}
+
+	// org and credentials
+	org, source := envVarWithSource("HZN_ORG_ID", "", "")
+	add(msgPrinter.Sprintf("Organization"), org, source)
+	userAuth, source := envVarWithSource("HZN_EXCHANGE_USER_AUTH", "", "")
+	if cliutils.Opts.UserAuth != nil && *cliutils.Opts.UserAuth != "" {
+		userAuth, source = *cliutils.Opts.UserAuth, "--user-auth"
+	}
+	add(msgPrinter.Sprintf("Exchange user credentials"), maskCredentials(userAuth), source)
+	nodeAuth, source := envVarWithSource("HZN_EXCHANGE_NODE_AUTH", "", "")
+	if cliutils.Opts.NodeAuth != nil && *cliutils.Opts.NodeAuth != "" {
+		nodeAuth, source = *cliutils.Opts.NodeAuth, "--node-auth"
+	}
+	add(msgPrinter.Sprintf("Exchange node credentials"), maskCredentials(nodeAuth), source)
+	credHelper, source := envVarWithSource(cliutils.CREDENTIAL_HELPER_ENV, "", "")
+	add(msgPrinter.Sprintf("Exchange credential helper"), credHelper, source)
+	authScheme, source := envVarWithSource(cliutils.EXCHANGE_AUTH_SCHEME_ENV, cliutils.AUTH_SCHEME_BASIC, defaultSource)
+	add(msgPrinter.Sprintf("Exchange auth scheme"), authScheme, source)
+	iamTokenUrl, source := envVarWithSource(cliutils.IAM_TOKEN_URL_ENV, "", "")
+	add(msgPrinter.Sprintf("IAM token service URL"), iamTokenUrl, source)
+
+	// the management hub certificate
+	certSource := ""
+	if os.Getenv(cliutils.EXCHANGE_CA_CERT_ENV) != "" {
+		_, certSource = envVarWithSource(cliutils.EXCHANGE_CA_CERT_ENV, "", "")
+	} else if os.Getenv(config.OldMgmtHubCertPath) != "" {
+		_, certSource = envVarWithSource(config.OldMgmtHubCertPath, "", "")
+	} else if os.Getenv(config.ManagementHubCertPath) != "" {
+		_, certSource = envVarWithSource(config.ManagementHubCertPath, "", "")
+	} else if cliutils.GetIcpCertPath() != "" {
+		certSource = cliutils.ANAX_OVERWRITE_FILE
+	}
+	add(msgPrinter.Sprintf("Management hub certificate"), cliutils.GetIcpCertPath(), certSource)
+	clientCert, source := envVarWithSource(cliutils.EXCHANGE_CLIENT_CERT_ENV, "", "")
+	if cliutils.Opts.ClientCert != nil && *cliutils.Opts.ClientCert != "" {
+		clientCert, source = *cliutils.Opts.ClientCert, "--client-cert"
+	}
+	add(msgPrinter.Sprintf("Management hub client certificate"), clientCert, source)
+	clientKey, source := envVarWithSource(cliutils.EXCHANGE_CLIENT_KEY_ENV, "", "")
+	if cliutils.Opts.ClientKey != nil && *cliutils.Opts.ClientKey != "" {
+		clientKey, source = *cliutils.Opts.ClientKey, "--client-key"
+	}
+	add(msgPrinter.Sprintf("Management hub client key"), clientKey, source)
+	skipVerify, source := envVarWithSource("HZN_SSL_SKIP_VERIFY", "", "")
+	if cliutils.Opts.Insecure != nil && *cliutils.Opts.Insecure {
+		skipVerify, source = "true", "--insecure"
+	}
+	add(msgPrinter.Sprintf("Skip TLS verification"), skipVerify, source)
+
+	// proxies
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY"} {
+		proxy, source := envVarWithSource(name, "", "")
+		if proxyFlag := cliutils.GetProxy(); proxyFlag != "" {
+			proxy, source = proxyFlag, "--proxy"
+		}
+		add(msgPrinter.Sprintf("%v proxy", name), cliutils.MaskProxyCredentials(proxy), source)
+	}
+	noProxy, source := envVarWithSource("NO_PROXY", "", "")
+	add(msgPrinter.Sprintf("Hosts reached without a proxy"), noProxy, source)
+
+	// http timeouts and retries
+	timeout, source := envVarWithSource(config.HTTPRequestTimeoutOverride, strconv.Itoa(config.HTTPRequestTimeoutS), defaultSource)
+	if cliutils.Opts.Timeout != nil && *cliutils.Opts.Timeout > 0 {
+		timeout, source = strconv.Itoa(*cliutils.Opts.Timeout), "--http-timeout"
+	}
+	add(msgPrinter.Sprintf("HTTP request timeout (seconds)"), timeout, source)
+	retries, source := envVarWithSource("HZN_HTTP_RETRIES", "5", defaultSource)
+	add(msgPrinter.Sprintf("HTTP retries"), retries, source)
+	retryInterval, source := envVarWithSource("HZN_HTTP_RETRY_INTERVAL", "2", defaultSource)
+	add(msgPrinter.Sprintf("HTTP retry interval (seconds)"), retryInterval, source)
+	rateLimitRetries, source := envVarWithSource(cliutils.HTTP_RATE_LIMIT_RETRIES_ENV, strconv.Itoa(cliutils.DEFAULT_HTTP_RATE_LIMIT_RETRIES), defaultSource)
+	add(msgPrinter.Sprintf("HTTP retries when rate limited"), rateLimitRetries, source)
+	maxIdleConns, source := envVarWithSource(cliutils.HTTP_MAX_IDLE_CONNS_ENV, strconv.Itoa(config.MaxHTTPIdleConnections), defaultSource)
+	add(msgPrinter.Sprintf("HTTP maximum idle connections"), maxIdleConns, source)
+	maxBodySize, source := envVarWithSource("HZN_HTTP_MAX_BODY_SIZE", strconv.Itoa(cliutils.DEFAULT_MAX_RESPONSE_BODY_SIZE), defaultSource)
+	add(msgPrinter.Sprintf("HTTP maximum response body size (bytes)"), maxBodySize, source)
+	httpTrace, source := envVarWithSource(cliutils.HTTP_TRACE_ENV, "", "")
+	if cliutils.Opts.DumpHttp != nil && *cliutils.Opts.DumpHttp && httpTrace == "" {
+		source = "--dump-http"
+	}
+	add(msgPrinter.Sprintf("HTTP trace"), cliutils.GetHttpTrace(), source)
+	outputFormat, source := envVarWithSource(cliutils.OUTPUT_FORMAT_ENV, cliutils.OUTPUT_FORMAT_JSON, defaultSource)
+	if cliutils.Opts.OutputFmt != nil && *cliutils.Opts.OutputFmt != "" {
+		outputFormat, source = *cliutils.Opts.OutputFmt, "--output-format"
+	}
+	add(msgPrinter.Sprintf("Output format"), outputFormat, source)
+	_, source = envVarWithSource("HZN_COOKIE_JAR", "", "")
+	add(msgPrinter.Sprintf("Session cookie jar"), cliutils.GetCookieJarFile(), source)
+	_, source = envVarWithSource(cliutils.EXCHANGE_CACHE_ENV, "", "")
+	add(msgPrinter.Sprintf("Exchange response cache"), cliutils.GetExchangeCacheDir(), source)
+	nonInteractive, source := envVarWithSource(cliutils.NONINTERACTIVE_ENV, "false", defaultSource)
+	if cliutils.Opts.AssumeYes != nil && *cliutils.Opts.AssumeYes {
+		nonInteractive, source = "true", "--yes"
+	}
+	add(msgPrinter.Sprintf("Skip the confirmation prompts"), nonInteractive, source)
+
+	// the hzn config files that were read
+	add(msgPrinter.Sprintf("Package config file"), cliconfig.PACKAGE_CONFIG_FILE, "")
+	add(msgPrinter.Sprintf("User config file"), cliconfig.USER_CONFIG_FILE, "")
+	add(msgPrinter.Sprintf("Profiles file"), cliconfig.GetProfilesFile(), "")
+
+	msgPrinter.Printf("Effective hzn configuration:")
+	msgPrinter.Println()
+	for _, input := range inputs {
+		if input.value == "" {
+			msgPrinter.Printf("  %s: %s", input.name, notSet)
+			msgPrinter.Println()
+		} else if input.source == "" {
+			fmt.Printf("  %s: %s\n", input.name, input.value)
+		} else {
+			msgPrinter.Printf("  %s: %s (from %s)", input.name, input.value, input.source)
+			msgPrinter.Println()
+		}
+	}
+}
+
+// Purge removes the records of the past activity of this node from the agent, and prints the signed report of what
+// was removed.
+func Purge(force bool, reportFile string) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if !force {
+		cliutils.ConfirmRemove(msgPrinter.Sprintf("Are you sure you want to remove the archived agreements, the event logs and the event log archives of this node?"))
+	}
+
+	_, respBody, _ := cliutils.HorizonPutPost("POST", "node/purge", []int{200}, "", true)
+	cliutils.OutputDeletionReport(respBody, reportFile)
+}
+
+// Update changes the name, pattern or policy properties of the registered node in place, with a PATCH of the node
+// settings in the agent, which changes them in the exchange. Only the flags that are given are changed. A property is
+// name=value, the values that are numbers or true or false are not strings. A new pattern makes the agent end the
+// agreements of the old pattern and register the node again with the new one.
+func Update(name string, pattern string, properties []string) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	settings := api.NodeSettings{}
+	if name != "" {
+		settings.Name = &name
+	}
+	if pattern != "" {
+		settings.Pattern = &pattern
+	}
+	if len(properties) > 0 {
+		props := externalpolicy.PropertyList{}
+		for _, p := range properties {
+			parts := strings.SplitN(p, "=", 2)
+			if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+				cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the property %v must be name=value", p))
+			}
+			props = append(props, externalpolicy.Property{Name: strings.TrimSpace(parts[0]), Value: propertyValue(parts[1])})
+		}
+		settings.Properties = &props
+	}
+	if settings.Name == nil && settings.Pattern == nil && settings.Properties == nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("at least one of --name, --pattern or --property must be specified"))
+	}
+
+	// the pattern can only be compared after the agent has prefixed it with the org
+	horDevice := api.HorizonDevice{}
+	cliutils.HorizonGet("node", []int{200}, &horDevice, false)
+	if horDevice.Org == nil || *horDevice.Org == "" {
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf(cliutils.MUST_REGISTER_FIRST))
+	}
+
+	if cliutils.IsDryRun() {
+		msgPrinter.Printf("Would update the node %v/%v: %v", *horDevice.Org, *horDevice.Id, settings)
+		msgPrinter.Println()
+		return
+	}
+
+	_, respBody, _ := cliutils.HorizonPatch("node/settings", []int{200}, settings, true)
+	updated := api.HorizonDevice{}
+	if err := json.Unmarshal([]byte(respBody), &updated); err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal 'PATCH node/settings' output: %v", err))
+	}
+
+	msgPrinter.Printf("Node %v/%v updated.", *horDevice.Org, *horDevice.Id)
+	msgPrinter.Println()
+	if horDevice.Pattern != nil && updated.Pattern != nil && *horDevice.Pattern != *updated.Pattern {
+		msgPrinter.Printf("The agent will end the agreements of the pattern %v and register the node again with the pattern %v. Run 'hzn node list' to see when the node is configured.", *horDevice.Pattern, *updated.Pattern)
+		msgPrinter.Println()
+	}
+}
+
+// The value of a property from the command line, a number or boolean if it is one, otherwise a string.
+func propertyValue(s string) interface{} {
+	if s == "true" || s == "false" {
+		return s == "true"
+	} else if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	} else if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
@@ -0,0 +1,116 @@
+// +build unit
+
+package node
+
+import (
+	"encoding/json"
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/cutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_List_outputFormat(t *testing.T) {
+	h := clitest.New(t)
+	h.Agent.SetNode(clitest.FAKE_ORG, "mynode", "netspeed", "configured")
+
+	h.Setenv(cliutils.OUTPUT_FORMAT_ENV, "go-template={{.id}} {{.configstate.state}}")
+	if res := h.Run(func() { List() }); res.ExitCode != 0 || res.Stdout != "mynode configured" {
+		t.Errorf("expected the template output, found %q, stderr: %v", res.Stdout, res.Stderr)
+	}
+
+	h.Setenv(cliutils.OUTPUT_FORMAT_ENV, "yaml")
+	if res := h.Run(func() { List() }); res.ExitCode != 0 || !strings.Contains(res.Stdout, "\nid: mynode\n") {
+		t.Errorf("expected the yaml output, found %q, stderr: %v", res.Stdout, res.Stderr)
+	}
+
+	h.Setenv(cliutils.OUTPUT_FORMAT_ENV, "xml")
+	if res := h.Run(func() { List() }); res.ExitCode != cliutils.CLI_INPUT_ERROR {
+		t.Errorf("expected exit code %v, found %v", cliutils.CLI_INPUT_ERROR, res.ExitCode)
+	}
+}
+
+func Test_Update(t *testing.T) {
+	h := clitest.New(t)
+	h.Agent.SetNode(clitest.FAKE_ORG, "mynode", clitest.FAKE_ORG+"/netspeed", "configured")
+	h.Agent.Handle(http.MethodPatch, "node/settings", http.StatusOK, map[string]interface{}{"id": "mynode", "organization": clitest.FAKE_ORG, "pattern": clitest.FAKE_ORG + "/gps"})
+
+	res := h.Run(func() { Update("", "gps", []string{"location=building-3", "cpus=4", "camera=true"}) })
+	if res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if !strings.Contains(res.Stdout, "Node myorg/mynode updated.") || !strings.Contains(res.Stdout, "register the node again with the pattern myorg/gps") {
+		t.Errorf("expected the update and the pattern change, found %v", res.Stdout)
+	}
+	reqs := h.Agent.RequestsTo(http.MethodPatch, "node/settings")
+	if len(reqs) != 1 {
+		t.Fatalf("expected 1 update, found %v", len(reqs))
+	}
+	settings := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(reqs[0].Body), &settings); err != nil {
+		t.Fatalf("the settings are not json: %v", err)
+	}
+	if _, ok := settings["name"]; ok || settings["pattern"] != "gps" {
+		t.Errorf("only the given settings should be sent: %v", settings)
+	} else if props, ok := settings["properties"].([]interface{}); !ok || len(props) != 3 || props[1].(map[string]interface{})["value"] != float64(4) || props[2].(map[string]interface{})["value"] != true {
+		t.Errorf("wrong properties %v", settings["properties"])
+	}
+
+	for _, bad := range [][]string{nil, {"location"}} {
+		if res := h.Run(func() { Update("", "", bad) }); res.ExitCode != cliutils.CLI_INPUT_ERROR {
+			t.Errorf("expected an input error for %v, exit code %v", bad, res.ExitCode)
+		}
+	}
+}
+
+func Test_Version(t *testing.T) {
+	h := clitest.New(t)
+	h.Setenv("HZN_HTTP_RETRIES", "0")
+
+	res := h.Run(func() { Version() })
+	if res.ExitCode != 0 || !strings.Contains(res.Stdout, "Horizon Exchange version: "+clitest.FAKE_EXCHANGE_VERSION) || res.Stderr != "" {
+		t.Fatalf("unexpected version output %v, stderr: %v", res.Stdout, res.Stderr)
+	}
+
+	// an exchange that is too old is flagged
+	h.Exchange.Handle(http.MethodGet, "admin/version", http.StatusOK, "1.0.0")
+	res = h.Run(func() { Version() })
+	if !strings.Contains(res.Stdout, "Horizon Exchange version: 1.0.0") || !strings.Contains(res.Stderr, "is older than the version") {
+		t.Errorf("expected a warning for the exchange version, output %v, stderr: %v", res.Stdout, res.Stderr)
+	}
+}
+
+func Test_VersionIncompatibilities(t *testing.T) {
+	if reasons := VersionIncompatibilities("2.28.0-338", "2.28.0-338", "2.44.0", "2.50.0"); len(reasons) != 0 {
+		t.Errorf("expected no incompatibility, found %v", reasons)
+	}
+	if reasons := VersionIncompatibilities("local build", "local build", "", ""); len(reasons) != 0 {
+		t.Errorf("expected the local builds not to be checked, found %v", reasons)
+	}
+	if reasons := VersionIncompatibilities("2.28.0", "3.0.1", "2.44.0", "2.50.0"); len(reasons) != 1 || !strings.Contains(reasons[0], "different major versions") {
+		t.Errorf("expected the major versions to be flagged, found %v", reasons)
+	}
+	if reasons := VersionIncompatibilities("2.27.0", "2.28.0", "2.60.0", "2.50.0"); len(reasons) != 2 || !strings.Contains(reasons[0], "that the Horizon Agent requires") {
+		t.Errorf("expected the exchange and the older CLI to be flagged, found %v", reasons)
+	}
+}
+
+func Test_Resources(t *testing.T) {
+	h := clitest.New(t)
+	h.Agent.Handle(http.MethodGet, "node/resources", http.StatusOK, `{"arch":"amd64","cpus":4,"memoryMB":7821,"diskMB":50176,"gpus":[{"vendor":"nvidia","model":"Tesla T4","device":"0000:01:00.0"}],"detectedTime":1602632290}`)
+
+	res := h.Run(func() { Resources() })
+	var resources cutil.NodeResources
+	if res.ExitCode != 0 {
+		t.Fatalf("hzn node resources failed with exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if res.JSON(t, &resources); resources.CPUs != 4 || resources.MemoryMB != 7821 || len(resources.GPUs) != 1 || resources.GPUs[0].Model != "Tesla T4" {
+		t.Errorf("unexpected node resources %v", res.Stdout)
+	}
+
+	// the agent has not detected the resources yet
+	h.Agent.Handle(http.MethodGet, "node/resources", http.StatusNotFound, `{"error":"the agent has not detected the node resources yet"}`)
+	if res := h.Run(func() { Resources() }); res.ExitCode != cliutils.NOT_FOUND {
+		t.Errorf("expected exit code %v when the resources are not detected yet, found %v", cliutils.NOT_FOUND, res.ExitCode)
+	}
+}
@@ -0,0 +1,81 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/open-horizon/anax/businesspolicy"
+	"github.com/open-horizon/anax/cli/cliconfig"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/externalpolicy"
+	_ "github.com/open-horizon/anax/externalpolicy/text_language"
+	"github.com/open-horizon/anax/i18n"
+)
+
+// Run the constraint and property matching between a node policy and a deployment (business) policy read from
+// local files, and explain which of the constraints on each side are satisfied by the properties of the other side.
+// Nothing is read from the Exchange or the agent, so the node's built-in properties are not included unless
+// they are given in the node policy file.
+func Compat(nodePolFile string, busPolFile string, servicePolFile string) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	nodePol := new(externalpolicy.ExternalPolicy)
+	readInputFile(nodePolFile, nodePol)
+	if err := nodePol.ValidateAndNormalize(); err != nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the node policy in %v is not valid: %v", nodePolFile, err))
+	}
+
+	busPol := new(businesspolicy.BusinessPolicy)
+	newBytes := cliconfig.ReadJsonFileWithLocalConfig(busPolFile)
+	if err := json.Unmarshal(newBytes, busPol); err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal json input file %s: %v", busPolFile, err))
+	}
+
+	// the deployment side of the match is the deployment policy merged with the service policy, if one is given
+	depProps := busPol.Properties
+	depConstraints := busPol.Constraints
+	if servicePolFile != "" {
+		svcPol := new(externalpolicy.ExternalPolicy)
+		readInputFile(servicePolFile, svcPol)
+		if err := svcPol.ValidateAndNormalize(); err != nil {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the service policy in %v is not valid: %v", servicePolFile, err))
+		}
+		(&depProps).MergeWith(&svcPol.Properties, false)
+		(&depConstraints).MergeWith(&svcPol.Constraints)
+	}
+
+	depPassed := explainConstraints(msgPrinter.Sprintf("Deployment constraints checked against the node properties:"), depConstraints, nodePol.Properties)
+	nodePassed := explainConstraints(msgPrinter.Sprintf("Node constraints checked against the deployment properties:"), nodePol.Constraints, depProps)
+
+	if depPassed && nodePassed {
+		msgPrinter.Printf("Result: the policies are compatible.")
+	} else {
+		msgPrinter.Printf("Result: the policies are not compatible.")
+	}
+	msgPrinter.Println()
+}
+
+// Check each constraint on its own against the given properties and display the outcome. Returns true
+// if every constraint is satisfied.
+func explainConstraints(title string, constraints externalpolicy.ConstraintExpression, props externalpolicy.PropertyList) bool {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	fmt.Println(title)
+	if len(constraints) == 0 {
+		msgPrinter.Printf("  no constraints, nothing to satisfy")
+		msgPrinter.Println()
+		return true
+	}
+
+	allPassed := true
+	for _, c := range constraints {
+		single := externalpolicy.ConstraintExpression{c}
+		if err := (&single).IsSatisfiedBy(props); err != nil {
+			allPassed = false
+			msgPrinter.Printf("  FAILED: %v (%v)", c, err)
+		} else {
+			msgPrinter.Printf("  passed: %v", c)
+		}
+		msgPrinter.Println()
+	}
+	return allPassed
+}
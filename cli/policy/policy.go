@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"github.com/open-horizon/anax/cli/cliconfig"
 	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/cutil"
 	"github.com/open-horizon/anax/externalpolicy"
 	"github.com/open-horizon/anax/i18n"
 	"net/http"
+	"sort"
+	"strings"
 )
 
 func List() {
@@ -16,12 +19,7 @@ func List() {
 	cliutils.HorizonGet("node/policy", []int{200}, &nodePolicy, false)
 
 	// Output the combined info
-	output, err := cliutils.DisplayAsJson(nodePolicy)
-	if err != nil {
-		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn policy list' output: %v", err))
-	}
-
-	fmt.Println(output)
+	cliutils.Output(nodePolicy, "hzn policy list")
 }
 
 func Update(fileName string) {
@@ -46,6 +44,21 @@ func Update(fileName string) {
 		msgPrinter.Println()
 	}
 
+	// show what changes, without the read-only built-in properties that the agent keeps
+	current := externalpolicy.ExternalPolicy{}
+	if _, err := cliutils.HorizonGetE("node/policy", []int{200}, &current); err != nil {
+		cliutils.Verbose(msgPrinter.Sprintf("Unable to get the current node policy: %v", err))
+	} else if diff, err := cliutils.DiffJson(withoutReadOnly(current, readOnlyBuiltIns), withoutReadOnly(*ep, readOnlyBuiltIns)); err != nil {
+		cliutils.Verbose(msgPrinter.Sprintf("Unable to compare the node policies: %v", err))
+	} else if diff == nil {
+		msgPrinter.Printf("The node policy is unchanged.")
+		msgPrinter.Println()
+	} else {
+		msgPrinter.Printf("Changes to the node policy:")
+		msgPrinter.Println()
+		fmt.Println(strings.Join(diff, "\n"))
+	}
+
 	cliutils.HorizonPutPost(http.MethodPost, "node/policy", []int{201, 200}, ep, true)
 
 	msgPrinter.Printf("Updating Horizon node policy and re-evaluating all agreements based on this node policy. Existing agreements might be cancelled and re-negotiated.")
@@ -53,12 +66,29 @@ func Update(fileName string) {
 
 }
 
+// The properties and constraints of a node policy without the read-only built-in properties, sorted by name so that
+// two policies can be compared.
+func withoutReadOnly(pol externalpolicy.ExternalPolicy, readOnly []string) externalpolicy.ExternalPolicy {
+	props := externalpolicy.PropertyList{}
+	for _, prop := range pol.Properties {
+		if !cutil.SliceContains(readOnly, prop.Name) {
+			props = append(props, prop)
+		}
+	}
+	sort.Slice(props, func(i, j int) bool { return props[i].Name < props[j].Name })
+	constraints := externalpolicy.ConstraintExpression{}
+	if pol.Constraints != nil {
+		constraints = pol.Constraints
+	}
+	return externalpolicy.ExternalPolicy{Properties: props, Constraints: constraints}
+}
+
 func Patch(patch string) {
 	msgPrinter := i18n.GetMessagePrinter()
 	msgPrinter.Printf("Warning: This command is deprecated. It will continue to be supported until the next major release. Please use 'hzn policy update' to update the node policy.")
 	msgPrinter.Println()
 
-	cliutils.HorizonPutPost(http.MethodPatch, "node/policy", []int{201, 200}, patch, true)
+	cliutils.HorizonPatch("node/policy", []int{201, 200}, patch, true)
 
 	msgPrinter.Printf("Horizon node policy updated.")
 	msgPrinter.Println()
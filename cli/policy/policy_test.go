@@ -0,0 +1,47 @@
+// +build unit
+
+package policy
+
+import (
+	"github.com/open-horizon/anax/cli/clitest"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Update(t *testing.T) {
+	h := clitest.New(t)
+	h.Agent.Handle(http.MethodGet, "node/policy", http.StatusOK, map[string]interface{}{
+		"properties":  []interface{}{map[string]interface{}{"name": "openhorizon.cpu", "value": 4}, map[string]interface{}{"name": "type", "value": "camera"}},
+		"constraints": []interface{}{"location == home"},
+	})
+	h.Agent.Handle(http.MethodPost, "node/policy", http.StatusCreated, "")
+	dir, err := ioutil.TempDir("", "clitest")
+	if err != nil {
+		t.Fatalf("unable to create a temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name string, content string) string {
+		file := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatalf("unable to write %v: %v", file, err)
+		}
+		return file
+	}
+
+	same := write("same.json", `{"properties": [{"name": "type", "value": "camera"}], "constraints": ["location == home"]}`)
+	if res := h.Run(func() { Update(same) }); res.ExitCode != 0 || !strings.Contains(res.Stdout, "The node policy is unchanged.") {
+		t.Errorf("expected the node policy to be unchanged, exit code %v, output %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+	changed := write("changed.json", `{"properties": [{"name": "type", "value": "gateway"}], "constraints": ["location == home"]}`)
+	res := h.Run(func() { Update(changed) })
+	if res.ExitCode != 0 || !strings.Contains(res.Stdout, `-       "value": "camera"`) || !strings.Contains(res.Stdout, `+       "value": "gateway"`) || strings.Contains(res.Stdout, "openhorizon.cpu") {
+		t.Errorf("unexpected diff, exit code %v, output %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+	if reqs := h.Agent.RequestsTo(http.MethodPost, "node/policy"); len(reqs) != 2 {
+		t.Errorf("expected the node policy to be updated twice, found %v", len(reqs))
+	}
+}
@@ -0,0 +1,252 @@
+package register
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/common"
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/semanticversion"
+	"net"
+	"sort"
+	"strings"
+)
+
+// registrationPlan is what 'hzn register --dry-run' found that the registration would configure, and the problems
+// that would make it fail.
+type registrationPlan struct {
+	steps    []string
+	problems []string
+}
+
+func (p *registrationPlan) step(msg string) {
+	p.steps = append(p.steps, msg)
+}
+
+func (p *registrationPlan) problem(msg string) {
+	p.problems = append(p.problems, msg)
+}
+
+// Print the plan. It exits with an error when the registration would fail.
+func (p *registrationPlan) print() {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	msgPrinter.Printf("Registration plan (dry run, nothing was changed):")
+	msgPrinter.Println()
+	for i, s := range p.steps {
+		fmt.Printf("  %d. %v\n", i+1, s)
+	}
+	if len(p.problems) == 0 {
+		msgPrinter.Printf("All the checks passed, run the command without --dry-run to register this node.")
+		msgPrinter.Println()
+		return
+	}
+	msgPrinter.Printf("The registration would fail:")
+	msgPrinter.Println()
+	for _, s := range p.problems {
+		fmt.Printf("  - %v\n", s)
+	}
+	cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the registration would fail with %d problems.", len(p.problems)))
+}
+
+// A service that the pattern would run on the node: the highest version of it within the version ranges that refer to
+// it.
+type plannedService struct {
+	org  string
+	url  string
+	arch string
+	def  exchange.ServiceDefinition
+}
+
+// planRegistration adds to the plan the rest of a registration with the pattern, or with a node policy when pat is
+// nil: the services that the pattern runs on the node and the user input and host ports that they need, without
+// changing anything. exchCreds are the credentials to read the services in the exchange with, and nodeInputs the
+// user input of the node in the exchange.
+func planRegistration(plan *registrationPlan, exchCreds string, org string, nodeId string, nodeName string, nodeType string, arch string, pattern string, pat *exchange.Pattern, nodePol *externalpolicy.ExternalPolicy, userInputFileObj *common.UserInputFile, patternFileObj *common.PatternFile, nodeInputs []policy.UserInput) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if nodePol != nil {
+		plan.step(msgPrinter.Sprintf("Update the policy of node %v/%v in the Exchange with %d properties and %d constraints.", org, nodeId, len(nodePol.Properties), len(nodePol.Constraints)))
+	}
+
+	newUserInputs := nodeUserInputs(userInputFileObj, patternFileObj)
+	if pat == nil {
+		plan.step(msgPrinter.Sprintf("Register with the node policy. The services are deployed by the deployment policies that match it, they are not checked."))
+	} else {
+		if patternFileObj != nil {
+			plan.step(msgPrinter.Sprintf("Register without a pattern in the Exchange, and configure the services of the pattern %v from the file in the Horizon agent.", pattern))
+		} else {
+			plan.step(msgPrinter.Sprintf("Register with the pattern %v.", pattern))
+		}
+		inputs := policy.MergeUserInputArrays(nodeInputs, pat.UserInput, true)
+		inputs = policy.MergeUserInputArrays(inputs, newUserInputs, true)
+		services := patternServices(plan, exchCreds, pattern, *pat, arch)
+		for _, s := range services {
+			plan.step(msgPrinter.Sprintf("Run version %v of service %v/%v for %v.", s.def.Version, s.org, s.url, s.arch))
+			checkServiceUserInput(plan, s, inputs)
+		}
+		if nodeType == persistence.DEVICE_TYPE_DEVICE {
+			checkHostPorts(plan, services)
+		}
+	}
+
+	if userInputFileObj != nil && !userInputFileObj.IsGlobalsEmpty() {
+		plan.step(msgPrinter.Sprintf("Set %d sets of global variables in the Horizon agent.", len(userInputFileObj.GetGlobal())))
+	}
+	if len(newUserInputs) > 0 {
+		plan.step(msgPrinter.Sprintf("Set the variables of %d services in the Horizon agent.", len(newUserInputs)))
+	}
+	plan.step(msgPrinter.Sprintf("Initialize the Horizon agent as node %v/%v, named %v, with node type %v.", org, nodeId, nodeName, nodeType))
+	plan.step(msgPrinter.Sprintf("Change the state of the Horizon agent to configured to register the node."))
+}
+
+// The services of the pattern for the arch of the node, with all their required services. The pattern refers to the
+// exact versions of its services, and an arch of * or no arch is the one of the node.
+func patternServices(plan *registrationPlan, exchCreds string, pattern string, pat exchange.Pattern, arch string) []plannedService {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	nodeArch := func(svcArch string) string {
+		if svcArch == "" || svcArch == "*" {
+			return arch
+		}
+		return svcArch
+	}
+
+	services := map[string]*plannedService{}
+	seen := map[string]bool{}
+	var add func(org, url, svcArch, versionRange string)
+	add = func(org, url, svcArch, versionRange string) {
+		svcArch = nodeArch(svcArch)
+		if seen[formSvcKey(org, url, svcArch)+"_"+versionRange] {
+			return
+		}
+		seen[formSvcKey(org, url, svcArch)+"_"+versionRange] = true
+		def, err := findHighestService(exchCreds, org, url, svcArch, []string{versionRange})
+		if err != nil {
+			plan.problem(err.Error())
+			return
+		}
+		key := formSvcKey(org, url, svcArch)
+		if s, ok := services[key]; ok {
+			// the highest version of a service that several version ranges refer to is used
+			if c, err := semanticversion.CompareVersions(s.def.Version, def.Version); err == nil && c < 0 {
+				s.def = *def
+			}
+		} else {
+			services[key] = &plannedService{org: org, url: url, arch: svcArch, def: *def}
+		}
+		for _, r := range def.RequiredServices {
+			add(r.Org, r.URL, r.Arch, r.VersionRange)
+		}
+	}
+
+	archs := []string{}
+	for _, svc := range pat.Services {
+		if nodeArch(svc.ServiceArch) != arch {
+			archs = append(archs, svc.ServiceArch)
+			continue
+		}
+		for _, v := range svc.ServiceVersions {
+			add(svc.ServiceOrg, svc.ServiceURL, svc.ServiceArch, "["+v.Version+","+v.Version+"]")
+		}
+	}
+	if len(services) == 0 && len(archs) == len(pat.Services) {
+		plan.problem(msgPrinter.Sprintf("the pattern %v has no services for the architecture %v of this node, only for: %v.", pattern, arch, strings.Join(archs, ", ")))
+	}
+
+	planned := make([]plannedService, 0, len(services))
+	for _, s := range services {
+		planned = append(planned, *s)
+	}
+	sort.Slice(planned, func(i, j int) bool { return planned[i].org+"/"+planned[i].url < planned[j].org+"/"+planned[j].url })
+	return planned
+}
+
+// Check that every variable of the service without a default value is set in the user input.
+func checkServiceUserInput(plan *registrationPlan, s plannedService, inputs []policy.UserInput) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	for _, ui := range s.def.UserInputs {
+		if ui.DefaultValue != "" {
+			continue
+		}
+		set := false
+		for _, input := range inputs {
+			if input.ServiceOrgid == s.org && input.ServiceUrl == s.url && (input.ServiceVersionRange == "" || isWithinRanges(s.def.Version, []string{input.ServiceVersionRange})) && input.FindInput(ui.Name) != nil {
+				set = true
+				break
+			}
+		}
+		if !set {
+			plan.problem(msgPrinter.Sprintf("the variable %v of service %v/%v has no default value and is not set in the input file, the pattern or the node in the Exchange.", ui.Name, s.org, s.url))
+		}
+	}
+}
+
+// Check that the host ports of the containers of the services are not used by 2 of the containers or by another
+// process on this node.
+func checkHostPorts(plan *registrationPlan, services []plannedService) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	used := map[string]string{}
+	for _, s := range services {
+		if s.def.Deployment == "" {
+			continue
+		}
+		deployment := containermessage.DeploymentDescription{}
+		if err := json.Unmarshal([]byte(s.def.Deployment), &deployment); err != nil {
+			plan.problem(msgPrinter.Sprintf("the deployment of service %v/%v is not valid: %v", s.org, s.url, err))
+			continue
+		}
+		containers := make([]string, 0, len(deployment.Services))
+		for name := range deployment.Services {
+			containers = append(containers, name)
+		}
+		sort.Strings(containers)
+		for _, name := range containers {
+			container := deployment.Services[name]
+			for _, binding := range append(container.Ports, container.SpecificPorts...) {
+				port := containermessage.GetSpecificHostPort(binding.HostPort)
+				if port == "" {
+					continue
+				}
+				protocol := "tcp"
+				if i := strings.LastIndex(binding.HostPort, "/"); i >= 0 {
+					protocol = binding.HostPort[i+1:]
+				}
+				key := port + "/" + protocol
+				owner := msgPrinter.Sprintf("container %v of service %v/%v", name, s.org, s.url)
+				if other, ok := used[key]; ok {
+					plan.problem(msgPrinter.Sprintf("the host port %v is used by both the %v and the %v.", key, other, owner))
+					continue
+				}
+				used[key] = owner
+				if err := hostPortAvailable(binding.HostIP, port, protocol); err != nil {
+					plan.problem(msgPrinter.Sprintf("the host port %v of the %v is already in use on this node: %v", key, owner, err))
+				}
+			}
+		}
+	}
+}
+
+// Returns an error if the port cannot be bound on this node.
+func hostPortAvailable(ip string, port string, protocol string) error {
+	address := net.JoinHostPort(ip, port)
+	if protocol == "udp" {
+		conn, err := net.ListenPacket("udp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return listener.Close()
+}
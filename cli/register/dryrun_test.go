@@ -0,0 +1,111 @@
+// +build unit
+
+package register
+
+import (
+	"fmt"
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_DryRun(t *testing.T) {
+	h := clitest.New(t)
+	h.Setenv("HZN_HTTP_RETRIES", "0")
+
+	dir, err := ioutil.TempDir("", "clitest-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a host port of the service that another process uses
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer listener.Close()
+	busyPort := listener.Addr().(*net.TCPAddr).Port
+
+	service := func(deployment string) map[string]interface{} {
+		return map[string]interface{}{"services": map[string]interface{}{"IBM/ibm.netspeed_1.2.0_amd64": map[string]interface{}{
+			"url": "ibm.netspeed", "version": "1.2.0", "arch": "amd64", "deployment": deployment,
+			"userInput": []interface{}{
+				map[string]interface{}{"name": "var1", "type": "string", "defaultValue": ""},
+				map[string]interface{}{"name": "var2", "type": "int", "defaultValue": "5"},
+			},
+		}}}
+	}
+	h.Exchange.Handle(http.MethodGet, "orgs/"+clitest.FAKE_ORG+"/nodes/mynode", http.StatusNotFound, map[string]interface{}{})
+	// the pattern refers to the exact version of the service, for any arch
+	pattern := func(arch string, version string) map[string]interface{} {
+		return map[string]interface{}{"patterns": map[string]interface{}{clitest.FAKE_ORG + "/netspeed": map[string]interface{}{
+			"services": []interface{}{
+				map[string]interface{}{"serviceUrl": "ibm.netspeed", "serviceOrgid": "IBM", "serviceArch": arch, "serviceVersions": []interface{}{map[string]interface{}{"version": version}}},
+				map[string]interface{}{"serviceUrl": "ibm.netspeed", "serviceOrgid": "IBM", "serviceArch": "arm64", "serviceVersions": []interface{}{map[string]interface{}{"version": "1.0.0"}}},
+			},
+		}}}
+	}
+	h.Exchange.Handle(http.MethodGet, "orgs/"+clitest.FAKE_ORG+"/patterns/netspeed", http.StatusOK, pattern("*", "1.2.0"))
+	h.Exchange.Handle(http.MethodGet, "orgs/IBM/services", http.StatusOK, service(fmt.Sprintf(`{"services":{"netspeed":{"image":"netspeed:1.2.0","ports":[{"HostPort":"%d:80/tcp"}]}}}`, busyPort)))
+
+	registerDryRun := func(inputFile string) clitest.Result {
+		return h.Run(func() {
+			dryRun := true
+			cliutils.Opts.IsDryRun = &dryRun
+			DoIt(clitest.FAKE_ORG, "netspeed", "mynode:mytoken", clitest.FAKE_USER_AUTH, inputFile, "", "", "", "", "", "", "", 60)
+		})
+	}
+
+	res := registerDryRun("")
+	if res.ExitCode != cliutils.CLI_INPUT_ERROR {
+		t.Fatalf("the dry run should find problems, exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+	for _, expected := range []string{"Create node myorg/mynode in the Exchange", "Register with the pattern netspeed", "Run version 1.2.0 of service IBM/ibm.netspeed for amd64",
+		"the variable var1 of service IBM/ibm.netspeed has no default value", fmt.Sprintf("the host port %d/tcp of the container netspeed of service IBM/ibm.netspeed is already in use", busyPort)} {
+		if !strings.Contains(res.Stdout, expected) {
+			t.Errorf("the plan should contain %q: %v", expected, res.Stdout)
+		}
+	}
+	if strings.Contains(res.Stdout, "var2") {
+		t.Errorf("a variable with a default value does not need to be set: %v", res.Stdout)
+	}
+
+	// with the variable set and a free port, the registration would work
+	inputFile := filepath.Join(dir, "userinput.json")
+	if err := ioutil.WriteFile(inputFile, []byte(`{"services":[{"org":"IBM","url":"ibm.netspeed","versionRange":"[0.0.0,INFINITY)","variables":{"var1":"aString"}}]}`), 0644); err != nil {
+		t.Fatalf("unable to write %v: %v", inputFile, err)
+	}
+	h.Exchange.Handle(http.MethodGet, "orgs/IBM/services", http.StatusOK, service(`{"services":{"netspeed":{"image":"netspeed:1.2.0"}}}`))
+	if res := registerDryRun(inputFile); res.ExitCode != 0 || !strings.Contains(res.Stdout, "All the checks passed") || !strings.Contains(res.Stdout, "Set the variables of 1 services") {
+		t.Errorf("the dry run should pass, exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+
+	// a higher version than the one of the pattern is not used
+	h.Exchange.Handle(http.MethodGet, "orgs/"+clitest.FAKE_ORG+"/patterns/netspeed", http.StatusOK, pattern("amd64", "1.0.0"))
+	if res := registerDryRun(inputFile); res.ExitCode != cliutils.CLI_INPUT_ERROR || !strings.Contains(res.Stdout, "found no services in the Exchange matched") {
+		t.Errorf("the dry run should not find version 1.0.0, exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+	h.Exchange.Handle(http.MethodGet, "orgs/"+clitest.FAKE_ORG+"/patterns/netspeed", http.StatusOK, pattern("", "1.2.0"))
+
+	// nothing was changed
+	for _, server := range []*clitest.FakeServer{h.Agent, h.Exchange.FakeServer} {
+		for _, req := range server.Requests() {
+			if req.Method != http.MethodGet {
+				t.Errorf("the dry run should not change anything, found %v %v", req.Method, req.Path)
+			}
+		}
+	}
+
+	// invalid user credentials are reported
+	h.Exchange.Handle(http.MethodGet, "orgs/"+clitest.FAKE_ORG+"/nodes/mynode", http.StatusUnauthorized, map[string]interface{}{})
+	if res := registerDryRun(inputFile); res.ExitCode != cliutils.INVALID_CREDS {
+		t.Errorf("expected invalid credentials, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+}
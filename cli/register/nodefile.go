@@ -0,0 +1,264 @@
+package register
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/open-horizon/anax/api"
+	"github.com/open-horizon/anax/cli/cliconfig"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/common"
+	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/policy"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// NodeFile declares the whole registration of a node in the input file of 'hzn register -f', so that a fleet of nodes
+// can be provisioned from files kept in source control. It is a user input file, with the global and services user
+// input, that also has the org, the pattern or node policy, and the node id, token and name. Registering a node that is
+// already registered with its node file changes the registration to match the file, so the command can be run again.
+type NodeFile struct {
+	Org     string                         `json:"org,omitempty"`
+	Pattern string                         `json:"pattern,omitempty"`
+	Node    *NodeFileNode                  `json:"node,omitempty"`
+	Policy  *externalpolicy.ExternalPolicy `json:"policy,omitempty"`
+}
+
+func (nf NodeFile) String() string {
+	return fmt.Sprintf("Org: %v, Pattern: %v, Node: %v, Policy: %v", nf.Org, nf.Pattern, nf.Node, nf.Policy)
+}
+
+// The node in a node file. The token is only used to create the node, it is not compared with the registered node.
+type NodeFileNode struct {
+	Id    string `json:"id,omitempty"`
+	Token string `json:"token,omitempty"`
+	Name  string `json:"name,omitempty"`
+}
+
+func (n NodeFileNode) String() string {
+	return fmt.Sprintf("Id: %v, Name: %v", n.Id, n.Name)
+}
+
+// The id and token of the node. When the file has no token, the token of the default credentials is kept if they are
+// for the same node.
+func (n NodeFileNode) idTok(defaultIdTok string) string {
+	if n.Token != "" {
+		return n.Id + ":" + n.Token
+	}
+	if id, token := cliutils.SplitIdToken(defaultIdTok); token != "" && (id == n.Id || strings.HasSuffix(id, "/"+n.Id)) {
+		return n.Id + ":" + token
+	}
+	return n.Id
+}
+
+// ReadInputFile reads the input file of hzn register, which is a user input file or a node file. The node file is nil
+// when the file does not declare the node. The file is read once, so that it can be stdin.
+func ReadInputFile(filePath string) (*NodeFile, *common.UserInputFile) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	newBytes := cliconfig.ReadJsonFileWithLocalConfig(filePath)
+	uif, err := common.NewUserInputFileFromJsonBytes(newBytes)
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Unable to create UserInputFile object from file %s. %v", filePath, err))
+	}
+
+	// the new format of the user input file is a list, which cannot be a node file
+	var nf NodeFile
+	if err := json.Unmarshal(newBytes, &nf); err != nil || (nf.Org == "" && nf.Pattern == "" && nf.Node == nil && nf.Policy == nil) {
+		return nil, uif
+	}
+	if nf.Policy != nil {
+		if err := nf.Policy.ValidateAndNormalize(); err != nil {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Incorrect node policy format in file %s: %v", filePath, err))
+		}
+	}
+	return &nf, uif
+}
+
+// Change the registration of this node to match the node file. Only the settings that differ are changed, and the
+// user input that the file does not set is kept. The org and node id cannot be changed, nor can the node switch
+// between a pattern and a node policy, without registering the node again.
+func convergeNode(horDevice api.HorizonDevice, inputFile string, org string, pattern string, patternFileObj *common.PatternFile, nodeIdTok string, nodeName string, nodePol *externalpolicy.ExternalPolicy, userInputFileObj *common.UserInputFile, anaxArch string, timeout int) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	unregisterFirst := msgPrinter.Sprintf("If you want to register it differently, run 'hzn unregister' first.")
+	current := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+
+	if current(horDevice.Org) != org {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("this Horizon node is registered in the organization %v, not %v. %v", current(horDevice.Org), org, unregisterFirst))
+	}
+	nodeId, _ := cliutils.SplitIdToken(nodeIdTok)
+	if _, nodeId = cliutils.TrimOrg(org, nodeId); nodeId != "" && nodeId != current(horDevice.Id) {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("this Horizon node is registered as node %v, not %v. %v", current(horDevice.Id), nodeId, unregisterFirst))
+	}
+	msgPrinter.Printf("Horizon node %v/%v is already registered, changing it to match %v...", org, current(horDevice.Id), inputFile)
+	msgPrinter.Println()
+	changed := false
+
+	// the name and pattern
+	settings := api.NodeSettings{}
+	currentPattern := current(horDevice.Pattern)
+	if currentPattern == "" && pattern != "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("this Horizon node is registered with a node policy, not the pattern %v. %v", pattern, unregisterFirst))
+	} else if currentPattern != "" && pattern == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("this Horizon node is registered with the pattern %v, not a node policy. %v", currentPattern, unregisterFirst))
+	} else if cliutils.AddOrg(org, pattern) != cliutils.AddOrg(org, currentPattern) {
+		newPattern := cliutils.AddOrg(org, pattern)
+		settings.Pattern = &newPattern
+		msgPrinter.Printf("Changing the pattern from %v to %v...", currentPattern, newPattern)
+		msgPrinter.Println()
+	}
+	if nodeName != "" && nodeName != current(horDevice.Name) {
+		settings.Name = &nodeName
+		msgPrinter.Printf("Changing the node name from %v to %v...", current(horDevice.Name), nodeName)
+		msgPrinter.Println()
+	}
+	if settings.Name != nil || settings.Pattern != nil {
+		cliutils.HorizonPatch("node/settings", []int{200}, settings, true)
+		changed = true
+	}
+
+	// the node policy, without the built-in properties that the agent adds to it
+	if nodePol != nil {
+		currentPol := externalpolicy.ExternalPolicy{}
+		cliutils.HorizonGet("node/policy", []int{200}, &currentPol, false)
+		if !isSameNodePolicy(currentPol, *nodePol) {
+			msgPrinter.Printf("Updating the node policy...")
+			msgPrinter.Println()
+			cliutils.HorizonPutPost(http.MethodPost, "node/policy", []int{201, 200}, nodePol, true)
+			changed = true
+		}
+	}
+
+	// the global variables
+	if userInputFileObj != nil && !userInputFileObj.IsGlobalsEmpty() {
+		currentAttrs := map[string][]api.Attribute{}
+		cliutils.HorizonGet("attribute", []int{200}, &currentAttrs, false)
+		for _, g := range userInputFileObj.GetGlobal() {
+			attr := globalAttribute(g)
+			existing := findAttribute(currentAttrs["attributes"], *attr)
+			if existing != nil && reflect.DeepEqual(existing.Mappings, attr.Mappings) {
+				continue
+			} else if existing != nil && existing.Id != nil {
+				// the attribute is replaced, so that the node does not end up with two of them for the same services
+				msgPrinter.Printf("Changing the global variables of type %v...", g.Type)
+				msgPrinter.Println()
+				attr.Id = existing.Id
+				cliutils.HorizonPutPost(http.MethodPut, "attribute/"+url.PathEscape(*existing.Id), []int{200, 201}, attr, true)
+			} else {
+				msgPrinter.Printf("Setting the global variables of type %v...", g.Type)
+				msgPrinter.Println()
+				if err := SetUserInput(timeout, "attribute", attr); err != nil {
+					cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf("Error setting user input variables: %v", err))
+				}
+			}
+			changed = true
+		}
+	}
+
+	// the service variables
+	if newUserInputs := nodeUserInputs(userInputFileObj, patternFileObj); len(newUserInputs) > 0 {
+		currentUserInputs := []policy.UserInput{}
+		cliutils.HorizonGet("node/userinput", []int{200}, &currentUserInputs, false)
+		patch := []policy.UserInput{}
+		for _, ui := range newUserInputs {
+			if !hasUserInput(currentUserInputs, ui) {
+				patch = append(patch, ui)
+			}
+		}
+		if len(patch) > 0 {
+			msgPrinter.Printf("Setting the service variables of %v services...", len(patch))
+			msgPrinter.Println()
+			cliutils.HorizonPatch("node/userinput", []int{201, 200}, patch, true)
+			changed = true
+		}
+	}
+
+	// the services of the pattern file that are not configured in the agent yet
+	if patternFileObj != nil {
+		currentConfigs := map[string][]api.MicroserviceConfig{}
+		cliutils.HorizonGet("service/config", []int{200}, &currentConfigs, false)
+		for _, svc := range patternServiceConfigs(patternFileObj, anaxArch) {
+			configured := false
+			for _, c := range currentConfigs["config"] {
+				if c.SensorUrl == *svc.Url && c.SensorOrg == *svc.Org {
+					configured = true
+					break
+				}
+			}
+			if !configured {
+				msgPrinter.Printf("Configuring the service %v/%v...", *svc.Org, *svc.Url)
+				msgPrinter.Println()
+				cliutils.HorizonPutPost(http.MethodPost, "service/config", []int{200, 201}, svc, true)
+				changed = true
+			}
+		}
+	}
+
+	if !changed {
+		msgPrinter.Printf("Horizon node is already registered as declared in %v, nothing to change.", inputFile)
+	} else if cliutils.IsDryRun() {
+		msgPrinter.Printf("Dry run, the changes above to match %v were not made.", inputFile)
+	} else {
+		msgPrinter.Printf("Horizon node is registered as declared in %v.", inputFile)
+	}
+	msgPrinter.Println()
+}
+
+// Returns true if the node policy has the properties and constraints of the file. The built-in properties that the
+// agent adds to the node policy are ignored.
+func isSameNodePolicy(currentPol externalpolicy.ExternalPolicy, nodePol externalpolicy.ExternalPolicy) bool {
+	readOnly := externalpolicy.ListReadOnlyProperties()
+	props := externalpolicy.PropertyList{}
+	for _, prop := range currentPol.Properties {
+		if !cutil.SliceContains(readOnly, prop.Name) {
+			props = append(props, prop)
+		}
+	}
+	return nodePol.Properties.IsSame(props) && props.IsSame(nodePol.Properties) &&
+		nodePol.Constraints.IsSame(currentPol.Constraints) && currentPol.Constraints.IsSame(nodePol.Constraints)
+}
+
+// Returns the attribute of the same type and for the same services as the attribute, or nil. Its variables can differ.
+func findAttribute(attrs []api.Attribute, attr api.Attribute) *api.Attribute {
+	for i, a := range attrs {
+		if a.Type == nil || *a.Type != *attr.Type {
+			continue
+		}
+		if (a.ServiceSpecs == nil || len(*a.ServiceSpecs) == 0) && len(*attr.ServiceSpecs) == 0 {
+			return &attrs[i]
+		} else if a.ServiceSpecs != nil && reflect.DeepEqual(*a.ServiceSpecs, *attr.ServiceSpecs) {
+			return &attrs[i]
+		}
+	}
+	return nil
+}
+
+// Returns true if the user input of the same service has all the variables of the user input, with the same values.
+func hasUserInput(userInputs []policy.UserInput, userInput policy.UserInput) bool {
+	for _, ui := range userInputs {
+		if ui.ServiceOrgid != userInput.ServiceOrgid || ui.ServiceUrl != userInput.ServiceUrl || ui.ServiceVersionRange != userInput.ServiceVersionRange {
+			continue
+		} else if ui.ServiceArch != "" && userInput.ServiceArch != "" && ui.ServiceArch != userInput.ServiceArch {
+			continue
+		}
+		for _, input := range userInput.Inputs {
+			if found := ui.FindInput(input.Name); found == nil || !found.IsSame(input) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
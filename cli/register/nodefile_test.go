@@ -0,0 +1,106 @@
+// +build unit
+
+package register
+
+import (
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_RegisterNodeFile(t *testing.T) {
+	h := clitest.New(t)
+	dir, err := ioutil.TempDir("", "clitest-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	nodeFile := filepath.Join(dir, "node.yaml")
+	content := `org: myorg
+pattern: netspeed
+node:
+  id: mynode
+  name: My Node
+global:
+- type: MeteringAttributes
+  variables:
+    tokens: 1
+services:
+- org: IBM
+  url: ibm.netspeed
+  versionRange: "[0.0.0,INFINITY)"
+  variables:
+    var1: aString
+    var2: 5
+`
+	if err := ioutil.WriteFile(nodeFile, []byte(content), 0644); err != nil {
+		t.Fatalf("unable to write %v: %v", nodeFile, err)
+	}
+
+	h.Agent.SetNode(clitest.FAKE_ORG, "mynode", clitest.FAKE_ORG+"/netspeed", "configured")
+	h.Agent.Handle(http.MethodGet, "attribute", http.StatusOK, map[string]interface{}{"attributes": []interface{}{
+		map[string]interface{}{"type": "MeteringAttributes", "mappings": map[string]interface{}{"tokens": 1}},
+	}})
+	h.Agent.Handle(http.MethodGet, "node/userinput", http.StatusOK, []interface{}{
+		map[string]interface{}{"serviceOrgid": "IBM", "serviceUrl": "ibm.netspeed", "serviceVersionRange": "[0.0.0,INFINITY)", "inputs": []interface{}{
+			map[string]interface{}{"name": "var1", "value": "aString"},
+		}},
+	})
+	h.Agent.Handle(http.MethodPatch, "node/settings", http.StatusOK, map[string]interface{}{})
+	h.Agent.Handle(http.MethodPatch, "node/userinput", http.StatusCreated, map[string]interface{}{})
+
+	run := func() clitest.Result {
+		return h.Run(func() { DoIt("", "", "", "", nodeFile, "", "", "", "", "", "", "", 60) })
+	}
+	if res := run(); res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if !strings.Contains(res.Stdout, "Changing the node name from  to My Node") || strings.Contains(res.Stdout, "global variables") {
+		t.Errorf("expected only the name and user input to change, found %v", res.Stdout)
+	}
+	if reqs := h.Agent.RequestsTo(http.MethodPatch, "node/settings"); len(reqs) != 1 || string(reqs[0].Body) != `{"name":"My Node"}` {
+		t.Errorf("expected the name to be set, found %v", reqs)
+	}
+	reqs := h.Agent.RequestsTo(http.MethodPatch, "node/userinput")
+	if len(reqs) != 1 || !strings.Contains(string(reqs[0].Body), `"name":"var2","value":5`) {
+		t.Fatalf("expected the user input to be set, found %v", reqs)
+	}
+
+	// registering again when the node matches the file changes nothing
+	h.Agent.Handle(http.MethodGet, "node", http.StatusOK, map[string]interface{}{"id": "mynode", "organization": clitest.FAKE_ORG, "pattern": clitest.FAKE_ORG + "/netspeed", "name": "My Node",
+		"configstate": map[string]interface{}{"state": "configured"}})
+	h.Agent.Handle(http.MethodGet, "node/userinput", http.StatusOK, string(reqs[0].Body))
+	if res := run(); res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if !strings.Contains(res.Stdout, "nothing to change") {
+		t.Errorf("expected no change, found %v", res.Stdout)
+	}
+	if len(h.Agent.RequestsTo(http.MethodPatch, "node/settings")) != 1 || len(h.Agent.RequestsTo(http.MethodPatch, "node/userinput")) != 1 {
+		t.Errorf("expected no more updates")
+	}
+
+	// a global variable that changed replaces the attribute that sets it
+	h.Agent.Handle(http.MethodGet, "attribute", http.StatusOK, map[string]interface{}{"attributes": []interface{}{
+		map[string]interface{}{"id": "a1", "type": "MeteringAttributes", "mappings": map[string]interface{}{"tokens": 2}},
+	}})
+	h.Agent.Handle(http.MethodPut, "attribute/a1", http.StatusOK, map[string]interface{}{"id": "a1"})
+	if res := run(); res.ExitCode != 0 || !strings.Contains(res.Stdout, "Changing the global variables of type MeteringAttributes") {
+		t.Fatalf("unexpected exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+	if reqs := h.Agent.RequestsTo(http.MethodPut, "attribute/a1"); len(reqs) != 1 || !strings.Contains(string(reqs[0].Body), `"tokens":1`) || !strings.Contains(string(reqs[0].Body), `"id":"a1"`) {
+		t.Errorf("expected the attribute to be replaced, found %v", reqs)
+	} else if reqs := h.Agent.RequestsTo(http.MethodPost, "attribute"); len(reqs) != 0 {
+		t.Errorf("expected no new attribute, found %v", reqs)
+	}
+
+	// a node registered differently is not changed
+	h.Agent.SetNode(clitest.FAKE_ORG, "othernode", clitest.FAKE_ORG+"/netspeed", "configured")
+	if res := run(); res.ExitCode != cliutils.CLI_INPUT_ERROR || !strings.Contains(res.Stderr, "hzn unregister") {
+		t.Errorf("expected an input error, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+}
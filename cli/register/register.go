@@ -2,6 +2,7 @@ package register
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/open-horizon/anax/api"
 	"github.com/open-horizon/anax/apicommon"
@@ -66,27 +67,96 @@ func ReadAndVerifyPolicFile(jsonFilePath string, nodePol *externalpolicy.Externa
 	}
 }
 
-// DoIt registers this node to Horizon with a pattern
-func DoIt(org, pattern, nodeIdTok, userPw, inputFile string, nodeOrgFromFlag string, patternFromFlag string, nodeName string, nodepolicyFlag string, waitService string, waitOrg string, waitTimeout int) {
+// read a pattern definition file and verify that it can be used for registration. It returns the pattern
+// object and the name of the pattern. The name is prefixed with the org if the file specifies one.
+func ReadPatternFile(filePath string) (*common.PatternFile, string) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	// check the input
-	org, pattern, waitService, waitOrg = verifyRegisterParamters(org, pattern, nodeOrgFromFlag, patternFromFlag, waitService, waitOrg, nodeIdTok)
+	newBytes := cliconfig.ReadJsonFileWithLocalConfig(filePath)
+	var pf common.PatternFile
+	if err := json.Unmarshal(newBytes, &pf); err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal pattern json input file %s: %v", filePath, err))
+	}
+
+	if pf.Name == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("The pattern file %s must have the 'name' attribute set.", filePath))
+	} else if len(pf.Services) == 0 {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Cannot proceed with the pattern in file %s because it does not include any services.", filePath))
+	}
+
+	if pf.Org == "" {
+		return &pf, pf.Name
+	}
+	return &pf, cliutils.AddOrg(pf.Org, pf.Name)
+}
 
-	cliutils.SetWhetherUsingApiKey(nodeIdTok) // if we have to use userPw later in NodeCreate(), it will set this appropriately for userPw
+// DoIt registers this node to Horizon with a pattern
+func DoIt(org, pattern, nodeIdTok, userPw, inputFile string, nodeOrgFromFlag string, patternFromFlag string, patternFile string, nodeName string, nodepolicyFlag string, waitService string, waitOrg string, waitTimeout int) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	// the input file can be a node file that declares the whole registration. The flags and arguments take
+	// precedence over the node file.
+	var nodeFileObj *NodeFile
 	var userInputFileObj *common.UserInputFile
+	var nodePol *externalpolicy.ExternalPolicy
 	if inputFile != "" {
 		msgPrinter.Printf("Reading input file %s...", inputFile)
 		msgPrinter.Println()
-		userInputFileObj = ReadUserInputFile(inputFile)
+		nodeFileObj, userInputFileObj = ReadInputFile(inputFile)
 		cliutils.Verbose(msgPrinter.Sprintf("Retrieved user input object from file %v: %v", inputFile, userInputFileObj))
 	}
+	if nodeFileObj != nil {
+		cliutils.Verbose(msgPrinter.Sprintf("Retrieved node declaration from file %v: %v", inputFile, nodeFileObj))
+		if org == "" && pattern == "" {
+			if nodeOrgFromFlag == "" {
+				nodeOrgFromFlag = nodeFileObj.Org
+			}
+			if patternFromFlag == "" && patternFile == "" {
+				patternFromFlag = nodeFileObj.Pattern
+			}
+		} else if pattern == "" && patternFile == "" {
+			pattern = nodeFileObj.Pattern
+		}
+		if node := nodeFileObj.Node; node != nil {
+			// the node id and token from HZN_EXCHANGE_NODE_AUTH do not take precedence over the file
+			if node.Id != "" && (nodeIdTok == "" || nodeIdTok == cliutils.GetNodeAuth("")) {
+				nodeIdTok = node.idTok(nodeIdTok)
+			}
+			if nodeName == "" {
+				nodeName = node.Name
+			}
+		}
+		if nodepolicyFlag == "" {
+			nodePol = nodeFileObj.Policy
+		}
+	}
+
+	// a pattern from a local file replaces the pattern in the Exchange, so the 2 cannot both be specified. The node
+	// is registered without a pattern, the services of the pattern are configured in the agent instead.
+	var patternFileObj *common.PatternFile
+	var patName string
+	if patternFile != "" {
+		if pattern != "" || patternFromFlag != "" {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("--pattern-file is mutually exclusive with -p and the <pattern> argument."))
+		}
+
+		msgPrinter.Printf("Reading pattern file %s...", patternFile)
+		msgPrinter.Println()
+		patternFileObj, patName = ReadPatternFile(patternFile)
+		cliutils.Verbose(msgPrinter.Sprintf("Retrieved pattern %v from file %v: %v", patName, patternFile, patternFileObj))
+	}
+
+	// check the input
+	org, pattern, waitService, waitOrg = verifyRegisterParamters(org, pattern, nodeOrgFromFlag, patternFromFlag, waitService, waitOrg, nodeIdTok)
+
+	cliutils.SetWhetherUsingWiotp(nodeIdTok) // if we have to use userPw later in NodeCreate(), it will set this appropriately for userPw
 
 	// read and verify the node policy if it specified
-	var nodePol externalpolicy.ExternalPolicy
 	if nodepolicyFlag != "" {
-		ReadAndVerifyPolicFile(nodepolicyFlag, &nodePol)
+		nodePol = new(externalpolicy.ExternalPolicy)
+		ReadAndVerifyPolicFile(nodepolicyFlag, nodePol)
 	}
 
 	// get the arch from anax
@@ -120,8 +190,13 @@ func DoIt(org, pattern, nodeIdTok, userPw, inputFile string, nodeOrgFromFlag str
 	horDevice := api.HorizonDevice{}
 	cliutils.HorizonGet("node", []int{200}, &horDevice, false)
 
-	// exit if the node is already registered
+	// exit if the node is already registered, unless the node file declares it. Then the registration is changed to
+	// match the file.
 	if horDevice.Config != nil && horDevice.Config.State != nil && (*horDevice.Config.State != persistence.CONFIGSTATE_UNCONFIGURED) {
+		if nodeFileObj != nil && *horDevice.Config.State == persistence.CONFIGSTATE_CONFIGURED {
+			convergeNode(horDevice, inputFile, org, pattern, patternFileObj, nodeIdTok, nodeName, nodePol, userInputFileObj, anaxArch, timeout)
+			return
+		}
 		cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf("this Horizon node is already registered or in the process of being registered. If you want to register it differently, run 'hzn unregister' first."))
 	}
 
@@ -188,6 +263,12 @@ func DoIt(org, pattern, nodeIdTok, userPw, inputFile string, nodeOrgFromFlag str
 		nodeType = persistence.DEVICE_TYPE_CLUSTER
 	}
 
+	// with --dry-run the registration is only checked and its plan displayed, nothing is changed. The exchange is read
+	// with the user credentials when the node does not exist yet.
+	dryRun := cliutils.IsDryRun()
+	plan := &registrationPlan{}
+	exchCreds := cliutils.OrgAndCreds(org, nodeIdTok)
+
 	// See if the node exists in the exchange, and create if it doesn't
 	var devicesResp exchange.GetDevicesResponse
 	exchangePattern := ""
@@ -198,20 +279,34 @@ func DoIt(org, pattern, nodeIdTok, userPw, inputFile string, nodeOrgFromFlag str
 			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("node '%s/%s' does not exist in the Exchange with the specified token, and the -u flag was not specified to provide exchange user credentials to create/update it.", org, nodeId))
 		}
 
-		cliutils.SetWhetherUsingApiKey(userPw)
+		cliutils.SetWhetherUsingWiotp(userPw)
 		userOrg, userAuth := cliutils.TrimOrg(org, userPw)
 		httpCode1 := cliutils.ExchangeGet("Exchange", exchUrlBase, "orgs/"+org+"/nodes/"+nodeId, cliutils.OrgAndCreds(userOrg, userAuth), nil, &devicesResp)
+		if dryRun {
+			exchCreds = cliutils.OrgAndCreds(userOrg, userAuth)
+			if httpCode1 == http.StatusUnauthorized || httpCode1 == http.StatusForbidden {
+				cliutils.Fatal(cliutils.INVALID_CREDS, msgPrinter.Sprintf("the user credentials cannot read or create node %s/%s in the Exchange, HTTP code %d.", org, nodeId, httpCode1))
+			}
+		}
 		if httpCode1 != 200 {
 			// node does not exist, create it
-			msgPrinter.Printf("Node %s/%s does not exist in the Exchange with the specified token, creating/updating it...", org, nodeId)
-			msgPrinter.Println()
-			cliexchange.NodeCreate(org, "", nodeId, nodeToken, userPw, anaxArch, nodeName, nodeType, false)
+			if dryRun {
+				plan.step(msgPrinter.Sprintf("Create node %s/%s in the Exchange with arch %v and node type %v.", org, nodeId, anaxArch, nodeType))
+			} else {
+				msgPrinter.Printf("Node %s/%s does not exist in the Exchange with the specified token, creating/updating it...", org, nodeId)
+				msgPrinter.Println()
+				cliexchange.NodeCreate(org, "", nodeId, nodeToken, userPw, anaxArch, nodeName, nodeType, false)
+			}
 		} else {
 			// node exists but the token is new, update the node token
-			msgPrinter.Printf("Updating node token...")
-			msgPrinter.Println()
-			patchNodeReq := cliexchange.NodeExchangePatchToken{Token: nodeToken}
-			cliutils.ExchangePutPost("Exchange", http.MethodPatch, cliutils.GetExchangeUrl(), "orgs/"+org+"/nodes/"+nodeId, cliutils.OrgAndCreds(userOrg, userAuth), []int{201}, patchNodeReq, nil)
+			if dryRun {
+				plan.step(msgPrinter.Sprintf("Change the token of node %s/%s in the Exchange.", org, nodeId))
+			} else {
+				msgPrinter.Printf("Updating node token...")
+				msgPrinter.Println()
+				patchNodeReq := cliexchange.NodeExchangePatchToken{Token: nodeToken}
+				cliutils.ExchangePatch("Exchange", cliutils.GetExchangeUrl(), "orgs/"+org+"/nodes/"+nodeId, cliutils.OrgAndCreds(userOrg, userAuth), []int{201}, patchNodeReq, nil)
+			}
 			for nId, n := range devicesResp.Devices {
 				exchangePattern = n.Pattern
 
@@ -225,6 +320,7 @@ func DoIt(org, pattern, nodeIdTok, userPw, inputFile string, nodeOrgFromFlag str
 	} else {
 		msgPrinter.Printf("Node %s/%s exists in the Exchange", org, nodeId)
 		msgPrinter.Println()
+		plan.step(msgPrinter.Sprintf("Use node %s/%s of the Exchange, its credentials are valid.", org, nodeId))
 		for nId, n := range devicesResp.Devices {
 			exchangePattern = n.Pattern
 
@@ -240,13 +336,15 @@ func DoIt(org, pattern, nodeIdTok, userPw, inputFile string, nodeOrgFromFlag str
 	// Use the exchange node pattern if any
 	if pattern == "" {
 		if exchangePattern == "" {
-			if nodepolicyFlag == "" {
+			if nodePol == nil {
 				msgPrinter.Printf("No pattern or node policy is specified. Will proceeed with the existing node policy.")
 				msgPrinter.Println()
 			} else {
 				msgPrinter.Printf("Will proceeed with the given node policy.")
 				msgPrinter.Println()
 			}
+		} else if patternFileObj != nil {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Cannot proceed with the pattern %s from file %s because the node is defined with the pattern %s on the Exchange. Remove the pattern from the node in the Exchange first.", patName, patternFile, exchangePattern))
 		} else {
 			msgPrinter.Printf("Pattern %s defined for the node on the Exchange.", exchangePattern)
 			msgPrinter.Println()
@@ -262,11 +360,23 @@ func DoIt(org, pattern, nodeIdTok, userPw, inputFile string, nodeOrgFromFlag str
 	}
 
 	var pat exchange.Pattern
-	if checkPattern {
+	if patternFileObj != nil {
+		// use the pattern from the local file instead of the one in the exchange
+		pat = exchange.Pattern{
+			Label:              patternFileObj.Label,
+			Description:        patternFileObj.Description,
+			Public:             patternFileObj.Public,
+			Services:           patternFileObj.GetServices(),
+			AgreementProtocols: patternFileObj.AgreementProtocols,
+			UserInput:          patternFileObj.GetUserInputs(),
+		}
+		msgPrinter.Printf("Will proceed with the pattern %s from file %s.", patName, patternFile)
+		msgPrinter.Println()
+	} else if checkPattern {
 		var output exchange.GetPatternResponse
 		var patorg, patname string
 		patorg, patname = cliutils.TrimOrg(org, pattern)
-		httpCode := cliutils.ExchangeGet("Exchange", exchUrlBase, "orgs/"+patorg+"/patterns"+cliutils.AddSlash(patname), cliutils.OrgAndCreds(org, nodeIdTok), []int{200, 404, 405}, &output)
+		httpCode := cliutils.ExchangeGet("Exchange", exchUrlBase, "orgs/"+patorg+"/patterns"+cliutils.AddSlash(patname), exchCreds, []int{200, 404, 405}, &output)
 		if httpCode != 200 {
 			cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("pattern '%s/%s' not found from the Exchange.", patorg, patname))
 		}
@@ -279,8 +389,29 @@ func DoIt(org, pattern, nodeIdTok, userPw, inputFile string, nodeOrgFromFlag str
 		}
 	}
 
+	if dryRun {
+		var patToRun *exchange.Pattern
+		planPattern := pattern
+		if checkPattern {
+			patToRun = &pat
+		} else if patternFileObj != nil {
+			patToRun = &pat
+			planPattern = patName
+		}
+		var nodeInputs []policy.UserInput
+		for _, n := range devicesResp.Devices {
+			nodeInputs = n.UserInput
+			if n.Arch != "" && n.Arch != anaxArch {
+				plan.problem(msgPrinter.Sprintf("the node %s/%s in the Exchange has the arch %v, not the arch %v of this node.", org, nodeId, n.Arch, anaxArch))
+			}
+		}
+		planRegistration(plan, exchCreds, org, nodeId, nodeName, nodeType, anaxArch, planPattern, patToRun, nodePol, userInputFileObj, patternFileObj, nodeInputs)
+		plan.print()
+		return
+	}
+
 	// Update node policy if specified
-	if nodepolicyFlag != "" {
+	if nodePol != nil {
 		msgPrinter.Printf("Updating the node policy...")
 		msgPrinter.Println()
 		cliutils.ExchangePutPost("Exchange", http.MethodPut, cliutils.GetExchangeUrl(), "orgs/"+org+"/nodes/"+nodeId+"/policy", cliutils.OrgAndCreds(org, nodeIdTok), []int{201}, nodePol, nil)
@@ -306,18 +437,8 @@ func DoIt(org, pattern, nodeIdTok, userPw, inputFile string, nodeOrgFromFlag str
 			// Set the global variables as attributes with no url (or in the case of HTTPSBasicAuthAttributes, with url equal to image svr)
 			msgPrinter.Printf("Setting global variables...")
 			msgPrinter.Println()
-			attr := api.NewAttribute("", "Global variables", false, false, map[string]interface{}{}) // we reuse this for each GlobalSet
 			for _, g := range userInputFileObj.GetGlobal() {
-				attr.Type = &g.Type
-				attr.ServiceSpecs = &g.ServiceSpecs
-				attr.Mappings = &g.Variables
-
-				// set HostOnly to true for these 2 types
-				switch g.Type {
-				case "HTTPSBasicAuthAttributes", "DockerRegistryAuthAttributes":
-					host_only := true
-					attr.HostOnly = &host_only
-				}
+				attr := globalAttribute(g)
 				//cliutils.HorizonPutPost(http.MethodPost, "attribute", []int{201, 200}, attr)
 				err := SetUserInput(timeout, "attribute", attr)
 				if err != nil {
@@ -327,14 +448,26 @@ func DoIt(org, pattern, nodeIdTok, userPw, inputFile string, nodeOrgFromFlag str
 				}
 			}
 		}
+	}
 
-		// Set the service variables using new format
-		newUserInputs, _ := userInputFileObj.GetNewFormat(true)
-		if newUserInputs != nil && len(newUserInputs) > 0 {
-			// use policy.UserInput struct
-			err := SetUserInput(timeout, "node/userinput", newUserInputs)
-			if err != nil {
-				msgPrinter.Printf("Error setting user input variables: %v", err)
+	// Set the service variables
+	if newUserInputs := nodeUserInputs(userInputFileObj, patternFileObj); len(newUserInputs) > 0 {
+		// use policy.UserInput struct
+		err := SetUserInput(timeout, "node/userinput", newUserInputs)
+		if err != nil {
+			msgPrinter.Printf("Error setting user input variables: %v", err)
+			msgPrinter.Println()
+			RegistrationFailure()
+		}
+	}
+
+	// Configure the services of the pattern file, the node has no pattern in the exchange to run them with
+	if patternFileObj != nil {
+		msgPrinter.Printf("Configuring the services of pattern %s...", patName)
+		msgPrinter.Println()
+		for _, svc := range patternServiceConfigs(patternFileObj, anaxArch) {
+			if err := SetUserInput(timeout, "service/config", svc); err != nil {
+				msgPrinter.Printf("Error configuring the service %v/%v: %v", *svc.Org, *svc.Url, err)
 				msgPrinter.Println()
 				RegistrationFailure()
 			}
@@ -380,6 +513,55 @@ func DoIt(org, pattern, nodeIdTok, userPw, inputFile string, nodeOrgFromFlag str
 
 }
 
+// The attribute that sets a global variable of the user input file.
+func globalAttribute(g common.GlobalSet) *api.Attribute {
+	attr := api.NewAttribute("", "Global variables", false, false, map[string]interface{}{})
+	attr.Type = &g.Type
+	attr.ServiceSpecs = &g.ServiceSpecs
+	attr.Mappings = &g.Variables
+
+	// set HostOnly to true for these 2 types
+	switch g.Type {
+	case "HTTPSBasicAuthAttributes", "DockerRegistryAuthAttributes":
+		host_only := true
+		attr.HostOnly = &host_only
+	}
+	return attr
+}
+
+// The service variables of the user input file in the new format. The user input in the pattern file is set on
+// the node so that the agent does not depend on the pattern in the exchange to have it. The user input from the
+// input file takes precedence.
+func nodeUserInputs(userInputFileObj *common.UserInputFile, patternFileObj *common.PatternFile) []policy.UserInput {
+	var newUserInputs []policy.UserInput
+	if userInputFileObj != nil {
+		newUserInputs, _ = userInputFileObj.GetNewFormat(true)
+	}
+	if patternFileObj != nil {
+		newUserInputs = policy.MergeUserInputArrays(patternFileObj.GetUserInputs(), newUserInputs, true)
+	}
+	return newUserInputs
+}
+
+// The services of the pattern file that run on a node with the arch, as configured in the agent. The pattern refers
+// to the exact versions of its services, the first one of them is configured.
+func patternServiceConfigs(patternFileObj *common.PatternFile, arch string) []api.Service {
+	services := []api.Service{}
+	for _, sref := range patternFileObj.GetServices() {
+		if sref.ServiceArch != "" && sref.ServiceArch != "*" && sref.ServiceArch != arch {
+			continue
+		}
+		svcUrl, svcOrg, svcArch := sref.ServiceURL, sref.ServiceOrg, arch
+		svc := api.Service{Url: &svcUrl, Org: &svcOrg, Arch: &svcArch}
+		if len(sref.ServiceVersions) > 0 && sref.ServiceVersions[0].Version != "" {
+			versionRange := "[" + sref.ServiceVersions[0].Version + "," + sref.ServiceVersions[0].Version + "]"
+			svc.VersionRange = &versionRange
+		}
+		services = append(services, svc)
+	}
+	return services
+}
+
 // RegistrationFailure attempts to unregister the node if a critical error is encountered during registration.
 // This function will not return. It ends with a call to cliutils.Fatal
 func RegistrationFailure() {
@@ -675,15 +857,24 @@ func isWithinRanges(version string, versionRanges []string) bool {
 
 // GetHighestService queries the exchange for all versions of this service and returns the highest version that is within at least 1 of the version ranges
 func GetHighestService(nodeCreds, org, url, arch string, versionRanges []string) exchange.ServiceDefinition {
+	svc, err := findHighestService(nodeCreds, org, url, arch, versionRanges)
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, err.Error())
+	}
+	return *svc
+}
+
+// findHighestService is GetHighestService, it returns an error when no version of the service is within the version ranges.
+func findHighestService(nodeCreds, org, url, arch string, versionRanges []string) (*exchange.ServiceDefinition, error) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
 	route := "orgs/" + org + "/services?url=" + url + "&arch=" + arch // get all services of this org, url, and arch
 	var svcOutput exchange.GetServicesResponse
-	cliutils.SetWhetherUsingApiKey(nodeCreds)
+	cliutils.SetWhetherUsingWiotp(nodeCreds)
 	cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), route, nodeCreds, []int{200}, &svcOutput)
 	if len(svcOutput.Services) == 0 {
-		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("found no services in the Exchange matching: org=%s, url=%s, arch=%s", org, url, arch))
+		return nil, errors.New(msgPrinter.Sprintf("found no services in the Exchange matching: org=%s, url=%s, arch=%s", org, url, arch))
 	}
 
 	// Loop thru the returned services and pick out the highest version that is within one of the versionRanges
@@ -706,9 +897,10 @@ func GetHighestService(nodeCreds, org, url, arch string, versionRanges []string)
 	}
 
 	if highestKey == "" {
-		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("found no services in the Exchange matched: org=%s, specRef=%s, version range=%s, arch=%s", org, url, versionRanges, arch))
+		return nil, errors.New(msgPrinter.Sprintf("found no services in the Exchange matched: org=%s, specRef=%s, version range=%s, arch=%s", org, url, versionRanges, arch))
 	}
-	return svcOutput.Services[highestKey]
+	svc := svcOutput.Services[highestKey]
+	return &svc, nil
 }
 
 func formSvcKey(org, url, arch string) string {
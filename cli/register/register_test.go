@@ -0,0 +1,84 @@
+// +build unit
+
+package register
+
+import (
+	"encoding/json"
+	"github.com/open-horizon/anax/api"
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_RegisterPatternFile(t *testing.T) {
+	h := clitest.New(t)
+	dir, err := ioutil.TempDir("", "clitest-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	patternFile := filepath.Join(dir, "pattern.json")
+	content := `{"name": "netspeed", "label": "netspeed",
+  "services": [
+    {"serviceUrl": "ibm.netspeed", "serviceOrgid": "IBM", "serviceArch": "amd64", "serviceVersions": [{"version": "1.2.0"}]},
+    {"serviceUrl": "ibm.netspeed", "serviceOrgid": "IBM", "serviceArch": "arm64", "serviceVersions": [{"version": "1.0.0"}]}
+  ],
+  "userInput": [{"serviceOrgid": "IBM", "serviceUrl": "ibm.netspeed", "serviceVersionRange": "[0.0.0,INFINITY)", "inputs": [{"name": "var1", "value": "aString"}]}]
+}`
+	if err := ioutil.WriteFile(patternFile, []byte(content), 0644); err != nil {
+		t.Fatalf("unable to write %v: %v", patternFile, err)
+	}
+
+	// the node and its pattern are not in the exchange
+	h.Exchange.Handle(http.MethodGet, "orgs/"+clitest.FAKE_ORG+"/nodes/mynode", http.StatusOK, map[string]interface{}{"nodes": map[string]interface{}{clitest.FAKE_ORG + "/mynode": map[string]interface{}{"nodeType": "device"}}})
+	h.Agent.Handle(http.MethodPost, "node", http.StatusCreated, map[string]interface{}{})
+	h.Agent.Handle(http.MethodPost, "node/userinput", http.StatusCreated, map[string]interface{}{})
+	h.Agent.Handle(http.MethodPost, "service/config", http.StatusCreated, map[string]interface{}{})
+	h.Agent.Handle(http.MethodPut, "node/configstate", http.StatusCreated, map[string]interface{}{})
+
+	res := h.Run(func() { DoIt(clitest.FAKE_ORG, "", "mynode:mytoken", "", "", "", "", patternFile, "", "", "", "", 60) })
+	if res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+
+	reqs := h.Agent.RequestsTo(http.MethodPost, "node")
+	if len(reqs) != 1 {
+		t.Fatalf("expected the node to be initialized, found %v", reqs)
+	}
+	var node api.HorizonDevice
+	if err := json.Unmarshal(reqs[0].Body, &node); err != nil {
+		t.Fatalf("unable to unmarshal %v: %v", string(reqs[0].Body), err)
+	} else if node.Pattern != nil && *node.Pattern != "" {
+		t.Errorf("expected the node to be registered without a pattern, found %v", *node.Pattern)
+	}
+	if reqs := h.Agent.RequestsTo(http.MethodPost, "node/userinput"); len(reqs) != 1 || !strings.Contains(string(reqs[0].Body), `"name":"var1","value":"aString"`) {
+		t.Errorf("expected the user input of the pattern to be set, found %v", reqs)
+	}
+	reqs = h.Agent.RequestsTo(http.MethodPost, "service/config")
+	if len(reqs) != 1 {
+		t.Fatalf("expected only the service for the arch of the node to be configured, found %v", reqs)
+	}
+	var svc api.Service
+	if err := json.Unmarshal(reqs[0].Body, &svc); err != nil {
+		t.Fatalf("unable to unmarshal %v: %v", string(reqs[0].Body), err)
+	} else if *svc.Url != "ibm.netspeed" || *svc.Org != "IBM" || *svc.Arch != "amd64" || *svc.VersionRange != "[1.2.0,1.2.0]" {
+		t.Errorf("expected version 1.2.0 of IBM/ibm.netspeed for amd64, found %v", svc.String())
+	}
+	for _, req := range h.Exchange.Requests() {
+		if strings.Contains(req.Path, "/patterns") {
+			t.Errorf("the pattern should not be read from the exchange, found %v %v", req.Method, req.Path)
+		}
+	}
+
+	// a node with a pattern in the exchange cannot use the pattern file
+	h.Exchange.Handle(http.MethodGet, "orgs/"+clitest.FAKE_ORG+"/nodes/mynode", http.StatusOK, map[string]interface{}{"nodes": map[string]interface{}{clitest.FAKE_ORG + "/mynode": map[string]interface{}{"nodeType": "device", "pattern": clitest.FAKE_ORG + "/other"}}})
+	if res := h.Run(func() { DoIt(clitest.FAKE_ORG, "", "mynode:mytoken", "", "", "", "", patternFile, "", "", "", "", 60) }); res.ExitCode != cliutils.CLI_INPUT_ERROR || !strings.Contains(res.Stderr, "myorg/other") {
+		t.Errorf("expected an input error, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+}
@@ -42,6 +42,93 @@ func CheckService(name string, org string, arch string, version string, nodeType
 	return false
 }
 
+// The event log codes that mark the major steps of agreement formation and service startup on the node,
+// and the progress message displayed to the user when each of them is found in the event log.
+var progressEventCodes = map[string]string{
+	persistence.EC_RECEIVED_PROPOSAL:                      "proposal received",
+	persistence.EC_AGREEMENT_REACHED:                      "agreement reached",
+	persistence.EC_START_SERVICE:                          "image pull started",
+	persistence.EC_START_DEPENDENT_SERVICE:                "dependent service image pull started",
+	persistence.EC_START_AGREEMENTLESS_SERVICE:            "agreementless service image pull started",
+	persistence.EC_IMAGE_LOADED:                           "image pull completed",
+	persistence.EC_COMPLETE_DEPENDENT_SERVICE:             "dependent service started",
+	persistence.EC_COMPLETE_AGREEMENTLESS_SERVICE_STARTUP: "agreementless service started",
+	persistence.EC_CONTAINER_RUNNING:                      "service started",
+	persistence.EC_AGREEMENT_CANCELED:                     "agreement canceled",
+}
+
+// eventProgress keeps track of the event log records that have already been reported to the user.
+type eventProgress struct {
+	lastRecordId string
+	since        uint64
+}
+
+// DisplayEventProgress reads the node's event log records that were created since the last call and prints a progress message
+// for the ones that mark a major step of agreement formation or service startup. Errors are always displayed.
+func (ep *eventProgress) DisplayEventProgress() {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	// only get the records that have not been seen yet
+	url := fmt.Sprintf("eventlog?timestamp=>%v", ep.since-1)
+	if ep.lastRecordId != "" {
+		url = fmt.Sprintf("eventlog?record_id=>%v", ep.lastRecordId)
+	}
+
+	eLogs := make([]persistence.EventLogRaw, 0)
+	if _, err := cliutils.HorizonGet(url, []int{200}, &eLogs, true); err != nil {
+		cliutils.Verbose(msgPrinter.Sprintf("Unable to get the event log from the Horizon agent: %v", err))
+		return
+	}
+
+	for _, el := range eLogs {
+		ep.lastRecordId = el.Id
+		t := time.Unix(int64(el.Timestamp), 0).Format("2006-01-02 15:04:05")
+		if progress, ok := progressEventCodes[el.EventCode]; ok {
+			msgPrinter.Printf("\t%v %v: %v", t, progress, el.Message)
+			msgPrinter.Println()
+		} else if el.Severity == persistence.SEVERITY_ERROR {
+			msgPrinter.Printf("\t%v error: %v", t, el.Message)
+			msgPrinter.Println()
+		}
+	}
+}
+
+// displayDiagnosticState shows the state of the node, its agreements and service instances. It is used
+// when the services being waited for did not start in time.
+func displayDiagnosticState(services api.AllServices) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	msgPrinter.Println()
+	msgPrinter.Printf("Current state of the node:")
+	msgPrinter.Println()
+
+	state := api.Configstate{}
+	if _, err := cliutils.HorizonGet("node/configstate", []int{200}, &state, true); err != nil {
+		msgPrinter.Printf("\tUnable to get the node configuration state: %v", err)
+		msgPrinter.Println()
+	} else if state.State != nil {
+		msgPrinter.Printf("\tNode configuration state: %v", *state.State)
+		msgPrinter.Println()
+	}
+
+	for _, ag := range agreement.GetAgreements(false) {
+		msgPrinter.Printf("\tAgreement %v for service %v/%v: created %v, accepted %v, execution started %v", ag.CurrentAgreementId, ag.RunningWorkload.Org, ag.RunningWorkload.URL,
+			cliutils.ConvertTime(ag.AgreementCreationTime), cliutils.ConvertTime(ag.AgreementAcceptedTime), cliutils.ConvertTime(ag.AgreementExecutionStartTime))
+		msgPrinter.Println()
+	}
+
+	for _, si := range services.Instances["active"] {
+		if si.ExecutionFailureCode != 0 {
+			msgPrinter.Printf("\tService instance %v/%v %v: failed, %v", si.Org, si.SpecRef, si.Version, si.ExecutionFailureDesc)
+		} else if si.ExecutionStartTime != 0 {
+			msgPrinter.Printf("\tService instance %v/%v %v: execution started %v", si.Org, si.SpecRef, si.Version, cliutils.ConvertTime(si.ExecutionStartTime))
+		} else {
+			msgPrinter.Printf("\tService instance %v/%v %v: created %v, not started", si.Org, si.SpecRef, si.Version, cliutils.ConvertTime(si.InstanceCreationTime))
+		}
+		msgPrinter.Println()
+	}
+}
+
 type WaitingStatus int
 
 const (
@@ -144,8 +231,13 @@ func WaitForService(org string, waitService string, waitTimeout int, pattern str
 	// Start monitoring the agent's /service API, looking for the presence of the input waitService.
 	updateCounter := UpdateThreshold
 	now := uint64(time.Now().Unix())
+	progress := eventProgress{since: now}
 	for uint64(time.Now().Unix())-now < uint64(waitTimeout) {
 		time.Sleep(time.Duration(3) * time.Second)
+
+		// report the progress of agreement formation and service startup from the node's event log
+		progress.DisplayEventProgress()
+
 		if _, err := cliutils.HorizonGet("service", []int{200}, &services, true); err != nil {
 			cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, err.Error())
 		}
@@ -366,6 +458,8 @@ func WaitForService(org string, waitService string, waitTimeout int, pattern str
 		}
 	}
 
+	displayDiagnosticState(services)
+
 	// Done analyzing
 	msgPrinter.Printf("Analysis complete.")
 	msgPrinter.Println()
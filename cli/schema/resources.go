@@ -0,0 +1,297 @@
+package schema
+
+// The schemas of the resource files that hzn sends to the exchange. They follow the structs the files are
+// unmarshaled into: common.ServiceFile, common.PatternFile, businesspolicy.BusinessPolicy and
+// exchange.ExchangeNodeManagementPolicy. The fields whose content is free form, e.g. the deployment of a service,
+// allow any field.
+var resourceSchemas = map[string]string{
+	KIND_SERVICE:    serviceSchema,
+	KIND_PATTERN:    patternSchema,
+	KIND_DEPLOYMENT: deploymentSchema,
+	KIND_NMP:        nmpSchema,
+}
+
+const serviceSchema = `{
+  "type": "object",
+  "description": "A service definition",
+  "required": ["url", "version", "arch"],
+  "additionalProperties": false,
+  "properties": {
+    "owner": {"type": "string"},
+    "org": {"type": "string"},
+    "label": {"type": "string"},
+    "description": {"type": "string"},
+    "public": {"type": "boolean"},
+    "documentation": {"type": "string"},
+    "url": {"type": "string"},
+    "version": {"type": "string"},
+    "arch": {"type": "string"},
+    "sharable": {"type": "string", "enum": ["", "exclusive", "single", "singleton", "multiple"]},
+    "matchHardware": {"type": "object"},
+    "requiredServices": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["url", "org"],
+        "additionalProperties": false,
+        "properties": {
+          "url": {"type": "string"},
+          "org": {"type": "string"},
+          "version": {"type": "string"},
+          "versionRange": {"type": "string"},
+          "arch": {"type": "string"}
+        }
+      }
+    },
+    "userInput": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["name"],
+        "additionalProperties": false,
+        "properties": {
+          "name": {"type": "string"},
+          "label": {"type": "string"},
+          "type": {"type": "string"},
+          "defaultValue": {"type": "string"}
+        }
+      }
+    },
+    "deployment": {"type": ["object", "string"]},
+    "deploymentSignature": {"type": "string"},
+    "clusterDeployment": {"type": ["object", "string"]},
+    "clusterDeploymentSignature": {"type": "string"},
+    "lastUpdated": {"type": "string"}
+  }
+}`
+
+// The user input of a pattern or a deployment policy, policy.UserInput.
+const userInputSchema = `{
+  "type": "array",
+  "items": {
+    "type": "object",
+    "required": ["serviceUrl"],
+    "additionalProperties": false,
+    "properties": {
+      "serviceOrgid": {"type": "string"},
+      "serviceUrl": {"type": "string"},
+      "serviceArch": {"type": "string"},
+      "serviceVersionRange": {"type": "string"},
+      "inputs": {
+        "type": "array",
+        "items": {
+          "type": "object",
+          "required": ["name"],
+          "additionalProperties": false,
+          "properties": {
+            "name": {"type": "string"},
+            "value": {}
+          }
+        }
+      }
+    }
+  }
+}`
+
+const prioritySchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {
+    "priority_value": {"type": "integer"},
+    "retries": {"type": "integer"},
+    "retry_durations": {"type": "integer"},
+    "verified_durations": {"type": "integer"}
+  }
+}`
+
+const upgradePolicySchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {
+    "lifecycle": {"type": "string"},
+    "time": {"type": "string"}
+  }
+}`
+
+const nodeHealthSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "properties": {
+    "missing_heartbeat_interval": {"type": "integer"},
+    "check_agreement_status": {"type": "integer"}
+  }
+}`
+
+const patternSchema = `{
+  "type": "object",
+  "description": "A deployment pattern",
+  "required": ["services"],
+  "additionalProperties": false,
+  "properties": {
+    "name": {"type": "string"},
+    "owner": {"type": "string"},
+    "org": {"type": "string"},
+    "label": {"type": "string"},
+    "description": {"type": "string"},
+    "public": {"type": "boolean"},
+    "services": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["serviceUrl", "serviceOrgid", "serviceArch", "serviceVersions"],
+        "additionalProperties": false,
+        "properties": {
+          "serviceUrl": {"type": "string"},
+          "serviceOrgid": {"type": "string"},
+          "serviceArch": {"type": "string"},
+          "agreementLess": {"type": "boolean"},
+          "serviceVersions": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["version"],
+              "additionalProperties": false,
+              "properties": {
+                "version": {"type": "string"},
+                "priority": ` + prioritySchema + `,
+                "upgradePolicy": ` + upgradePolicySchema + `,
+                "deployment_overrides": {"type": ["object", "string"]},
+                "deployment_overrides_signature": {"type": "string"}
+              }
+            }
+          },
+          "dataVerification": {
+            "type": "object",
+            "additionalProperties": false,
+            "properties": {
+              "enabled": {"type": "boolean"},
+              "URL": {"type": "string"},
+              "user": {"type": "string"},
+              "password": {"type": "string"},
+              "interval": {"type": "integer"},
+              "check_rate": {"type": "integer"},
+              "metering": {
+                "type": "object",
+                "additionalProperties": false,
+                "properties": {
+                  "tokens": {"type": "integer"},
+                  "per_time_unit": {"type": "string", "enum": ["", "min", "hour", "day"]},
+                  "notification_interval": {"type": "integer"}
+                }
+              }
+            }
+          },
+          "nodeHealth": ` + nodeHealthSchema + `
+        }
+      }
+    },
+    "agreementProtocols": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "additionalProperties": false,
+        "properties": {
+          "name": {"type": "string"},
+          "protocolVersion": {"type": "integer"},
+          "blockchains": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "additionalProperties": false,
+              "properties": {
+                "type": {"type": "string"},
+                "name": {"type": "string"},
+                "organization": {"type": "string"}
+              }
+            }
+          }
+        }
+      }
+    },
+    "userInput": ` + userInputSchema + `,
+    "lastUpdated": {"type": "string"}
+  }
+}`
+
+// The properties and constraints of a policy, externalpolicy.PropertyList and externalpolicy.ConstraintExpression.
+const propertiesSchema = `{
+  "type": "array",
+  "items": {
+    "type": "object",
+    "required": ["name", "value"],
+    "additionalProperties": false,
+    "properties": {
+      "name": {"type": "string"},
+      "value": {},
+      "type": {"type": "string"}
+    }
+  }
+}`
+
+const constraintsSchema = `{"type": "array", "items": {"type": "string"}}`
+
+const deploymentSchema = `{
+  "type": "object",
+  "description": "A deployment policy",
+  "required": ["service"],
+  "additionalProperties": false,
+  "properties": {
+    "owner": {"type": "string"},
+    "label": {"type": "string"},
+    "description": {"type": "string"},
+    "service": {
+      "type": "object",
+      "required": ["name"],
+      "additionalProperties": false,
+      "properties": {
+        "name": {"type": "string"},
+        "org": {"type": "string"},
+        "arch": {"type": "string"},
+        "serviceVersions": {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "required": ["version"],
+            "additionalProperties": false,
+            "properties": {
+              "version": {"type": "string"},
+              "priority": ` + prioritySchema + `,
+              "upgradePolicy": ` + upgradePolicySchema + `
+            }
+          }
+        },
+        "nodeHealth": ` + nodeHealthSchema + `
+      }
+    },
+    "properties": ` + propertiesSchema + `,
+    "constraints": ` + constraintsSchema + `,
+    "userInput": ` + userInputSchema + `
+  }
+}`
+
+const nmpSchema = `{
+  "type": "object",
+  "description": "A node management policy",
+  "required": ["job"],
+  "additionalProperties": false,
+  "properties": {
+    "owner": {"type": "string"},
+    "label": {"type": "string"},
+    "description": {"type": "string"},
+    "constraints": ` + constraintsSchema + `,
+    "properties": ` + propertiesSchema + `,
+    "patterns": {"type": "array", "items": {"type": "string"}},
+    "enabled": {"type": "boolean"},
+    "start": {"type": "string"},
+    "job": {
+      "type": "object",
+      "required": ["type"],
+      "additionalProperties": false,
+      "properties": {
+        "type": {"type": "string", "enum": ["certUpdate", "agentUpgrade"]},
+        "config": {"type": "object"}
+      }
+    },
+    "lastUpdated": {"type": "string"}
+  }
+}`
@@ -0,0 +1,476 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/i18n"
+	"math"
+	"sort"
+	"strings"
+)
+
+// The kinds of resource files that the CLI validates before sending them to the exchange.
+const (
+	KIND_SERVICE    = "service"
+	KIND_PATTERN    = "pattern"
+	KIND_DEPLOYMENT = "deployment"
+	KIND_NMP        = "nmp"
+)
+
+// Kinds returns the kinds of resource files that have a schema.
+func Kinds() []string {
+	return []string{KIND_SERVICE, KIND_PATTERN, KIND_DEPLOYMENT, KIND_NMP}
+}
+
+// Schema is the subset of JSON schema that the resource schemas use: type, properties, required,
+// additionalProperties, items and enum. Property names match case insensitively, the same as when the CLI
+// unmarshals the file, and null matches every type because it unmarshals to the zero value.
+type Schema struct {
+	Type                 typeList           `json:"type,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+}
+
+// The type of a schema is a type name or a list of them.
+type typeList []string
+
+func (t *typeList) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = typeList{name}
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	*t = typeList(names)
+	return nil
+}
+
+// ValidationError is a value of a resource file that does not match its schema. An unknown field is only a warning,
+// the exchange ignores it and a newer exchange can know fields that the schema of this CLI does not.
+type ValidationError struct {
+	Line    int    // the line of the value in the file, 0 when it is not known
+	Field   string // the path of the value, e.g. services[0].serviceUrl, empty for the whole file
+	Msg     string
+	Warning bool // the value is an unknown field
+}
+
+func (e ValidationError) Error() string {
+	field := e.Field
+	if field == "" {
+		field = i18n.GetMessagePrinter().Sprintf("the file")
+	}
+	if e.Line == 0 {
+		return fmt.Sprintf("%v: %v", field, e.Msg)
+	}
+	return i18n.GetMessagePrinter().Sprintf("line %v: %v: %v", e.Line, field, e.Msg)
+}
+
+// Get returns the schema of a kind of resource file.
+func Get(kind string) (*Schema, error) {
+	text, ok := resourceSchemas[kind]
+	if !ok {
+		return nil, fmt.Errorf(i18n.GetMessagePrinter().Sprintf("unknown resource kind %v, it must be one of %v", kind, strings.Join(Kinds(), ", ")))
+	}
+	var s Schema
+	if err := json.Unmarshal([]byte(text), &s); err != nil {
+		return nil, fmt.Errorf(i18n.GetMessagePrinter().Sprintf("the %v schema is invalid: %v", kind, err))
+	}
+	return &s, nil
+}
+
+// GuessKind returns the kind of a resource file from its top level fields, or an empty string when it does not
+// look like any of them.
+func GuessKind(data []byte) string {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return ""
+	}
+	has := func(name string) bool {
+		for k := range fields {
+			if strings.EqualFold(k, name) {
+				return true
+			}
+		}
+		return false
+	}
+	if has("job") {
+		return KIND_NMP
+	} else if has("services") {
+		return KIND_PATTERN
+	} else if has("service") {
+		return KIND_DEPLOYMENT
+	} else if has("url") || has("deployment") || has("clusterDeployment") {
+		return KIND_SERVICE
+	}
+	return ""
+}
+
+// Validate checks a resource file of a kind against its schema. It returns every value that does not match, in the
+// order they appear in the file, including the unknown fields that are only warnings. The returned error is set when the file is not JSON or the kind is unknown.
+func Validate(kind string, data []byte) ([]ValidationError, error) {
+	s, err := Get(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		if syntaxErr, ok := err.(*json.SyntaxError); ok {
+			return []ValidationError{{Line: lineAt(data, syntaxErr.Offset), Msg: syntaxErr.Error()}}, nil
+		}
+		return []ValidationError{{Msg: err.Error()}}, nil
+	}
+
+	v := &validator{lines: valueLines(data)}
+	v.validate(s, doc, "")
+	sort.SliceStable(v.errs, func(i, j int) bool { return v.errs[i].Line < v.errs[j].Line })
+	return v.errs, nil
+}
+
+// ValidateFile checks a resource file that ReadJsonFile read, like Validate. The errors of a YAML file, which
+// ReadJsonFile converted to the json data, are at the lines of the values in the YAML.
+func ValidateFile(kind string, filePath string, data []byte) ([]ValidationError, error) {
+	errs, err := Validate(kind, data)
+	if lines := cliutils.YamlLines(filePath); err == nil && lines != nil {
+		for i := range errs {
+			errs[i].Line = yamlLine(lines, errs[i].Field)
+		}
+		sort.SliceStable(errs, func(i, j int) bool { return errs[i].Line < errs[j].Line })
+	}
+	return errs, err
+}
+
+// Returns the line of a value in a YAML file. A value inside a flow mapping or sequence has the line of the nearest
+// value that contains it.
+func yamlLine(lines map[string]int, path string) int {
+	for {
+		if line, ok := lines[path]; ok {
+			return line
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			return lines[""]
+		}
+		path = path[:i]
+	}
+}
+
+// Check validates a resource file that the CLI is about to send to the exchange. The unknown fields are displayed as
+// a warning, and the command ends with a CLI_INPUT_ERROR that lists every invalid value when a value has the wrong
+// type or a required field is missing.
+func Check(kind string, filePath string, data []byte) {
+	errs, err := ValidateFile(kind, filePath, data)
+	if err != nil {
+		cliutils.Fatal(cliutils.INTERNAL_ERROR, "%v", err)
+	}
+	errs, warnings := SplitWarnings(errs)
+	if len(warnings) != 0 {
+		cliutils.Warning("%v", FormatWarnings(filePath, warnings))
+	}
+	if len(errs) != 0 {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "%v", FormatErrors(filePath, errs))
+	}
+	cliutils.Verbose(i18n.GetMessagePrinter().Sprintf("%v is a valid %v file", filePath, kind))
+}
+
+// SplitWarnings returns the validation errors that make a file invalid, and the ones that are only warnings.
+func SplitWarnings(all []ValidationError) (errs []ValidationError, warnings []ValidationError) {
+	for _, e := range all {
+		if e.Warning {
+			warnings = append(warnings, e)
+		} else {
+			errs = append(errs, e)
+		}
+	}
+	return
+}
+
+// FormatErrors returns the message that lists the validation errors of a file, one per line.
+func FormatErrors(filePath string, errs []ValidationError) string {
+	return formatList(i18n.GetMessagePrinter().Sprintf("%v is not valid:", filePath), errs)
+}
+
+// FormatWarnings returns the message that lists the unknown fields of a file, one per line.
+func FormatWarnings(filePath string, warnings []ValidationError) string {
+	return formatList(i18n.GetMessagePrinter().Sprintf("%v has fields that are not known to this version of hzn, they are ignored:", filePath), warnings)
+}
+
+func formatList(header string, errs []ValidationError) string {
+	lines := []string{header}
+	for _, e := range errs {
+		lines = append(lines, "  "+e.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+type validator struct {
+	lines map[string]int
+	errs  []ValidationError
+}
+
+func (v *validator) fail(path string, msg string, args ...interface{}) {
+	v.errs = append(v.errs, ValidationError{Line: v.lines[path], Field: path, Msg: i18n.GetMessagePrinter().Sprintf(msg, args...)})
+}
+
+func (v *validator) warn(path string, msg string, args ...interface{}) {
+	v.fail(path, msg, args...)
+	v.errs[len(v.errs)-1].Warning = true
+}
+
+func (v *validator) validate(s *Schema, value interface{}, path string) {
+	if value == nil {
+		return
+	}
+
+	if len(s.Type) != 0 && !matchesType(s.Type, value) {
+		v.fail(path, "must be %v, found %v", strings.Join(s.Type, " or "), typeName(value))
+		return
+	}
+
+	if len(s.Enum) != 0 && !inEnum(s.Enum, value) {
+		allowed := []string{}
+		for _, e := range s.Enum {
+			b, _ := json.Marshal(e)
+			allowed = append(allowed, string(b))
+		}
+		v.fail(path, "must be one of %v", strings.Join(allowed, ", "))
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		v.validateObject(s, val, path)
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range val {
+				v.validate(s.Items, item, fmt.Sprintf("%v[%v]", path, i))
+			}
+		}
+	}
+}
+
+func (v *validator) validateObject(s *Schema, obj map[string]interface{}, path string) {
+	for _, name := range s.Required {
+		if key, found := findKey(obj, name); !found || obj[key] == nil {
+			v.fail(path, "the required field %v is missing", name)
+		}
+	}
+
+	// visit the fields in a stable order, the errors are sorted by line afterwards
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fieldPath := k
+		if path != "" {
+			fieldPath = path + "." + k
+		}
+		if prop := findProperty(s.Properties, k); prop != nil {
+			v.validate(prop, obj[k], fieldPath)
+		} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+			if suggestion := closestName(k, s.Properties); suggestion != "" {
+				v.warn(fieldPath, "is not a known field, did you mean %v?", suggestion)
+			} else {
+				v.warn(fieldPath, "is not a known field")
+			}
+		}
+	}
+}
+
+// Find the key of an object that unmarshals into a field with the given name.
+func findKey(obj map[string]interface{}, name string) (string, bool) {
+	if _, ok := obj[name]; ok {
+		return name, true
+	}
+	for k := range obj {
+		if strings.EqualFold(k, name) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+func findProperty(props map[string]*Schema, key string) *Schema {
+	if p, ok := props[key]; ok {
+		return p
+	}
+	for name, p := range props {
+		if strings.EqualFold(name, key) {
+			return p
+		}
+	}
+	return nil
+}
+
+// Returns the known field that an unknown one is most likely a typo of, or an empty string.
+func closestName(key string, props map[string]*Schema) string {
+	best, bestDist := "", 3
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if d := editDistance(strings.ToLower(key), strings.ToLower(name)); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func matchesType(types []string, value interface{}) bool {
+	for _, t := range types {
+		switch val := value.(type) {
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case json.Number:
+			if t == "number" {
+				return true
+			} else if f, err := val.Float64(); t == "integer" && err == nil && f == math.Trunc(f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	}
+	return "null"
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the line of every value in a JSON document, by its path. The line of an object field is the line of its
+// name.
+func valueLines(data []byte) map[string]int {
+	lines := map[string]int{"": 1}
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	var walk func(path string) error
+	walk = func(path string) error {
+		lines[path] = lineAt(data, skipSpace(data, dec.InputOffset()))
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				key, _ := keyTok.(string)
+				fieldPath := key
+				if path != "" {
+					fieldPath = path + "." + key
+				}
+				line := lineAt(data, dec.InputOffset())
+				if err := walk(fieldPath); err != nil {
+					return err
+				}
+				lines[fieldPath] = line
+			}
+			_, err = dec.Token()
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				if err := walk(fmt.Sprintf("%v[%v]", path, i)); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		}
+		return err
+	}
+	walk("")
+	lines[""] = 1
+	return lines
+}
+
+// Returns the offset of the next token after an offset, skipping the spaces and the separators.
+func skipSpace(data []byte, offset int64) int64 {
+	for offset < int64(len(data)) && strings.IndexByte(" \t\r\n,:", data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
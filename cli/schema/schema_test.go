@@ -0,0 +1,170 @@
+// +build unit
+
+package schema
+
+import (
+	"github.com/open-horizon/anax/cli/cliutils"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Validate_samples(t *testing.T) {
+	for file, kind := range map[string]string{
+		"../samples/service.json":         KIND_SERVICE,
+		"../samples/service_cluster.json": KIND_SERVICE,
+		"../samples/pattern.json":         KIND_PATTERN,
+		"../samples/business_policy.json": KIND_DEPLOYMENT,
+	} {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("unable to read %v: %v", file, err)
+		}
+		if guessed := GuessKind(data); guessed != kind {
+			t.Errorf("%v should be a %v file, guessed %v", file, kind, guessed)
+		}
+		if errs, err := Validate(kind, data); err != nil || len(errs) != 0 {
+			t.Errorf("%v should be valid, found %v %v", file, errs, err)
+		}
+	}
+}
+
+func Test_Validate_errors(t *testing.T) {
+	pattern := `{
+  "label": "netspeed",
+  "public": "yes",
+  "services": [
+    {
+      "serviceurl": "netspeed",
+      "serviceOrgid": "myorg",
+      "serviceArch": "amd64",
+      "serviceVersions": [{"version": "1.0.0", "priorty": {}}],
+      "dataVerification": {"metering": {"per_time_unit": "week"}},
+      "nodeHealth": {"missing_heartbeat_interval": 1.5}
+    },
+    {"serviceVersions": null}
+  ]
+}`
+
+	errs, err := Validate(KIND_PATTERN, []byte(pattern))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	expected := []string{
+		`line 3: public: must be boolean, found string`,
+		`line 9: services[0].serviceVersions[0].priorty: is not a known field, did you mean priority?`,
+		`line 10: services[0].dataVerification.metering.per_time_unit: must be one of "", "min", "hour", "day"`,
+		`line 11: services[0].nodeHealth.missing_heartbeat_interval: must be integer, found number`,
+		`line 13: services[1]: the required field serviceUrl is missing`,
+	}
+	if len(errs) != len(expected)+3 {
+		t.Errorf("expected %v errors, found %v", len(expected)+3, errs)
+	}
+	msgs := []string{}
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	for _, e := range expected {
+		found := false
+		for _, m := range msgs {
+			found = found || m == e
+		}
+		if !found {
+			t.Errorf("expected the error %v, found %v", e, msgs)
+		}
+	}
+
+	// the field names match case insensitively and null is the zero value, both unmarshal
+	for _, m := range msgs {
+		if strings.Contains(m, "serviceurl") || strings.Contains(m, "services[0]: the required") {
+			t.Errorf("serviceurl should match serviceUrl, found %v", m)
+		}
+	}
+
+	// only the unknown field is a warning
+	errs, warnings := SplitWarnings(errs)
+	if len(warnings) != 1 || warnings[0].Field != "services[0].serviceVersions[0].priorty" {
+		t.Errorf("expected the unknown field to be the only warning, found %v", warnings)
+	} else if len(errs) != len(expected)+2 {
+		t.Errorf("expected %v errors that are not warnings, found %v", len(expected)+2, errs)
+	}
+
+	// the errors are in the order of the file
+	for i := 1; i < len(errs); i++ {
+		if errs[i].Line < errs[i-1].Line {
+			t.Errorf("the errors should be sorted by line: %v", msgs)
+		}
+	}
+}
+
+func Test_Validate_syntax(t *testing.T) {
+	errs, err := Validate(KIND_NMP, []byte("{\n  \"job\": {\"type\": \"agentUpgrade\"},\n  \"enabled\": true,,\n}"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	} else if len(errs) != 1 || errs[0].Line != 3 {
+		t.Errorf("expected a syntax error on line 3, found %v", errs)
+	}
+
+	if _, err := Validate("workload", []byte("{}")); err == nil {
+		t.Errorf("an unknown kind should be an error")
+	}
+
+	errs, _ = Validate(KIND_NMP, []byte(`{"enabled": true, "job": {"type": "reboot"}}`))
+	if len(errs) != 1 || errs[0].Field != "job.type" || errs[0].Line != 1 {
+		t.Errorf("expected an invalid job type, found %v", errs)
+	}
+
+	if errs, _ = Validate(KIND_DEPLOYMENT, []byte(`{}`)); len(errs) != 1 || errs[0].Error() != "line 1: the file: the required field service is missing" {
+		t.Errorf("expected a missing service, found %v", errs)
+	}
+}
+
+func Test_ValidateFile_yaml(t *testing.T) {
+	verbose := false
+	cliutils.Opts.Verbose = &verbose
+	defer func() { cliutils.Opts.Verbose = nil }()
+
+	dir, err := ioutil.TempDir("", "schema-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "pattern.yaml")
+	content := `label: netspeed
+# the services
+services:
+  - serviceUrl: netspeed
+    serviceOrgid: myorg
+    serviceArch: amd64
+    serviceVersions: [{version: 1.0.0, priorty: {}}]
+    nodeHealth:
+      missing_heartbeat_interval: 1.5
+public: "yes"
+`
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatalf("unable to write %v: %v", file, err)
+	}
+
+	// the lines are the ones of the yaml, not of the json it is converted to
+	errs, err := ValidateFile(KIND_PATTERN, file, cliutils.ReadJsonFile(file))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := []string{
+		`line 7: services[0].serviceVersions[0].priorty: is not a known field, did you mean priority?`,
+		`line 9: services[0].nodeHealth.missing_heartbeat_interval: must be integer, found number`,
+		`line 10: public: must be boolean, found string`,
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %v, found %v", expected, errs)
+	}
+	for i, e := range errs {
+		if e.Error() != expected[i] {
+			t.Errorf("expected the error %v, found %v", expected[i], e.Error())
+		}
+	}
+}
@@ -65,8 +65,7 @@ func VoucherInspect(voucherFile *os.File) {
 		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("parsing the json from %s: %v", voucherFile.Name(), err))
 	}
 
-	output := cliutils.MarshalIndent(outStruct, "voucher inspect")
-	fmt.Println(output)
+	cliutils.Output(outStruct, "voucher inspect")
 }
 
 func parseVoucherBytes(voucherBytes []byte, outStruct *InspectOutput) error {
@@ -178,19 +177,16 @@ func getVouchers(org, userCreds, apiMsg string, voucher string) ([]byte, string)
 	httpCode := resp.StatusCode
 	cliutils.Verbose(msgPrinter.Sprintf("HTTP code: %d", httpCode))
 
-	respBodyBytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf("failed to read exchange body response from %s: %v", apiMsg, err))
-	}
 	if httpCode != 200 {
-		cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf("bad HTTP code %d from %s: %s", httpCode, apiMsg, string(respBodyBytes)))
+		cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf("bad HTTP code %d from %s: %s", httpCode, apiMsg, cliutils.GetErrorRespBody(resp)))
 	}
+	respBodyBytes = cliutils.ReadRespBody(resp.Body, apiMsg)
 
 	return respBodyBytes, apiMsg
 }
 
 // called when the -l flag is used to list the full voucher
-func listFullVoucher(respBodyBytes []byte, apiMsg string) []byte {
+func listFullVoucher(respBodyBytes []byte, apiMsg string) interface{} {
 	msgPrinter := i18n.GetMessagePrinter()
 	cliutils.Verbose(msgPrinter.Sprintf("Listing imported SDO vouchers."))
 
@@ -200,13 +196,7 @@ func listFullVoucher(respBodyBytes []byte, apiMsg string) []byte {
 	if err != nil {
 		cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf("json unmarshalling HTTP response '%s' from %s: %v", string(respBodyBytes), apiMsg, err))
 	}
-
-	jsonBytes, err := json.MarshalIndent(output, "", cliutils.JSON_INDENT)
-	if err != nil {
-		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn exchange service list' output: %v", err))
-	}
-
-	return jsonBytes
+	return output
 }
 
 // list the all the uploaded SDO vouchers, or a single voucher
@@ -227,20 +217,14 @@ func VoucherList(org, userCreds, voucher string, namesOnly bool) {
 			cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf("json unmarshalling HTTP response '%s' from %s: %v", string(respBodyBytes), apiMsg, err))
 		}
 
-		jsonBytes, err := json.MarshalIndent(output, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn exchange service list' output: %v", err))
-		}
-
 		// list only the uuid's of imported vouchers
 		if namesOnly {
-			fmt.Printf("%s\n", jsonBytes)
+			cliutils.Output(output, "hzn voucher list")
 
 		} else { // list full details of all imported vouchers
 			for i := range output {
 				respBodyBytes, apiMsg = getVouchers(org, userCreds, apiMsg, output[i])
-				jsonBytes := listFullVoucher(respBodyBytes, apiMsg)
-				fmt.Printf("%s\n", jsonBytes)
+				cliutils.Output(listFullVoucher(respBodyBytes, apiMsg), "hzn voucher list")
 				fmt.Printf("\n")
 			}
 		}
@@ -256,15 +240,10 @@ func VoucherList(org, userCreds, voucher string, namesOnly bool) {
 				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("parsing the json from %s: %v", voucher, err))
 			}
 
-			jsonBytes, err := json.MarshalIndent(vouch, "", cliutils.JSON_INDENT)
-			if err != nil {
-				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn exchange service list' output: %v", err))
-			}
-			fmt.Printf("%s\n", jsonBytes)
+			cliutils.Output(vouch, "hzn voucher list")
 
 		} else { // list full details of voucher
-			jsonBytes := listFullVoucher(respBodyBytes, apiMsg)
-			fmt.Printf("%s\n", jsonBytes)
+			cliutils.Output(listFullVoucher(respBodyBytes, apiMsg), "hzn voucher list")
 		}
 	}
 }
@@ -409,14 +388,11 @@ func SdoPostVoucher(url string, creds string, requestBodyBytes []byte, respBody
 	defer resp.Body.Close()
 	httpCode := resp.StatusCode
 	cliutils.Verbose(msgPrinter.Sprintf("HTTP code: %d", httpCode))
-	respBodyBytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf("failed to read exchange body response from %s: %v", apiMsg, err))
-	}
 	if httpCode != 201 {
-		cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf("bad HTTP code %d from %s: %s", httpCode, apiMsg, string(respBodyBytes)))
+		cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf("bad HTTP code %d from %s: %s", httpCode, apiMsg, cliutils.GetErrorRespBody(resp)))
 	}
-	err = json.Unmarshal(respBodyBytes, respBody)
+	respBodyBytes := cliutils.ReadRespBody(resp.Body, apiMsg)
+	err := json.Unmarshal(respBodyBytes, respBody)
 	if err != nil {
 		cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf("json unmarshalling HTTP response '%s' from %s: %v", string(respBodyBytes), apiMsg, err))
 	}
@@ -469,3 +445,66 @@ func NodeAddPolicyString(org, credToUse, node, policyStr string, quieter bool) {
 	//msgPrinter.Printf("Node policy updated.")
 	//msgPrinter.Println()
 }
+
+// The onboarding states of the device of a voucher, shown by hzn voucher status.
+const (
+	ONBOARDING_NO_NODE      = "no node"    // the exchange does not have the node that the voucher import creates
+	ONBOARDING_WAITING      = "waiting"    // the device has not booted and registered yet
+	ONBOARDING_REGISTERED   = "registered" // the agent of the device has registered, but has not heartbeated yet
+	ONBOARDING_HEARTBEATING = "heartbeating"
+)
+
+type VoucherOnboardingStatus struct {
+	DeviceUuid    string `json:"deviceUuid"`
+	Status        string `json:"status"`
+	Pattern       string `json:"pattern,omitempty"`
+	LastHeartbeat string `json:"lastHeartbeat,omitempty"`
+}
+
+// onboardingStatus returns the onboarding state of a device from its node in the exchange, the node is nil if there is none.
+func onboardingStatus(node *anaxExchange.Device) string {
+	if node == nil {
+		return ONBOARDING_NO_NODE
+	} else if node.PublicKey == "" {
+		return ONBOARDING_WAITING
+	} else if node.LastHeartbeat == "" {
+		return ONBOARDING_REGISTERED
+	}
+	return ONBOARDING_HEARTBEATING
+}
+
+// VoucherStatus shows how far the devices of the imported vouchers, or of the one voucher, have gotten in booting and
+// registering with the management hub. The node that the voucher import pre-creates in the exchange is used to tell.
+func VoucherStatus(org, userCreds, voucher string) {
+	msgPrinter := i18n.GetMessagePrinter()
+	cliutils.Verbose(msgPrinter.Sprintf("Getting the onboarding status of the SDO vouchers."))
+
+	uuids := []string{voucher}
+	if voucher == "" {
+		respBodyBytes, apiMsg := getVouchers(org, userCreds, "", "")
+		if err := json.Unmarshal(respBodyBytes, &uuids); err != nil {
+			cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf("json unmarshalling HTTP response '%s' from %s: %v", string(respBodyBytes), apiMsg, err))
+		}
+	} else {
+		// make sure the voucher was imported
+		getVouchers(org, userCreds, "", voucher)
+	}
+
+	statuses := make([]VoucherOnboardingStatus, 0, len(uuids))
+	for _, deviceUuid := range uuids {
+		var nodes exchange.ExchangeNodes
+		status := VoucherOnboardingStatus{DeviceUuid: deviceUuid}
+		if httpCode := cliutils.ExchangeGet("Exchange", cliutils.GetExchangeUrl(), "orgs/"+org+"/nodes/"+deviceUuid, cliutils.OrgAndCreds(org, userCreds), []int{200, 404}, &nodes); httpCode == 404 {
+			status.Status = onboardingStatus(nil)
+		} else if node, ok := nodes.Nodes[org+"/"+deviceUuid]; ok {
+			status.Status = onboardingStatus(&node)
+			status.Pattern = node.Pattern
+			status.LastHeartbeat = node.LastHeartbeat
+		} else {
+			status.Status = onboardingStatus(nil)
+		}
+		statuses = append(statuses, status)
+	}
+
+	cliutils.Output(statuses, "hzn voucher status")
+}
@@ -0,0 +1,73 @@
+// +build unit
+
+package sdo
+
+import (
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	anaxExchange "github.com/open-horizon/anax/exchange"
+	"net/http"
+	"testing"
+)
+
+func Test_onboardingStatus(t *testing.T) {
+	for _, tc := range []struct {
+		node     *anaxExchange.Device
+		expected string
+	}{
+		{nil, ONBOARDING_NO_NODE},
+		{&anaxExchange.Device{}, ONBOARDING_WAITING},
+		{&anaxExchange.Device{PublicKey: "key"}, ONBOARDING_REGISTERED},
+		{&anaxExchange.Device{PublicKey: "key", LastHeartbeat: "2020-10-01T12:00:00Z"}, ONBOARDING_HEARTBEATING},
+	} {
+		if status := onboardingStatus(tc.node); status != tc.expected {
+			t.Errorf("expected status %v for node %v, found %v", tc.expected, tc.node, status)
+		}
+	}
+}
+
+func Test_VoucherStatus(t *testing.T) {
+	h := clitest.New(t)
+	sdoSvc := clitest.NewFakeServer(t)
+	h.Setenv("HZN_SDO_SVC_URL", sdoSvc.URL)
+	sdoSvc.Handle(http.MethodGet, "vouchers", http.StatusOK, []string{"d1", "d2", "d3"})
+	sdoSvc.Handle(http.MethodGet, "vouchers/d2", http.StatusOK, map[string]interface{}{"oh": map[string]interface{}{}})
+
+	h.Exchange.AddResource("orgs/myorg/nodes/d2", map[string]string{"pattern": "myorg/netspeed"})
+	h.Exchange.AddResource("orgs/myorg/nodes/d3", map[string]string{"pattern": "myorg/netspeed", "publicKey": "key", "lastHeartbeat": "2020-10-01T12:00:00Z"})
+
+	// all the imported vouchers
+	res := h.Run(func() { VoucherStatus(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "") })
+	if res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	var statuses []VoucherOnboardingStatus
+	res.JSON(t, &statuses)
+	expected := []VoucherOnboardingStatus{
+		{DeviceUuid: "d1", Status: ONBOARDING_NO_NODE},
+		{DeviceUuid: "d2", Status: ONBOARDING_WAITING, Pattern: "myorg/netspeed"},
+		{DeviceUuid: "d3", Status: ONBOARDING_HEARTBEATING, Pattern: "myorg/netspeed", LastHeartbeat: "2020-10-01T12:00:00Z"},
+	}
+	if len(statuses) != len(expected) {
+		t.Fatalf("expected %v, found %v", expected, statuses)
+	}
+	for i := range expected {
+		if statuses[i] != expected[i] {
+			t.Errorf("expected %v, found %v", expected[i], statuses[i])
+		}
+	}
+
+	// one voucher
+	res = h.Run(func() { VoucherStatus(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "d2") })
+	statuses = nil
+	if res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if res.JSON(t, &statuses); len(statuses) != 1 || statuses[0] != expected[1] {
+		t.Errorf("expected %v, found %v", expected[1], statuses)
+	}
+
+	// a voucher that was not imported
+	if res := h.Run(func() { VoucherStatus(clitest.FAKE_ORG, clitest.FAKE_USER_AUTH, "d4") }); res.ExitCode != cliutils.HTTP_ERROR {
+		t.Errorf("expected an http error, exit code %v, stdout: %v", res.ExitCode, res.Stdout)
+	}
+}
@@ -2,7 +2,6 @@ package service
 
 import (
 	"encoding/json"
-	"fmt"
 	"github.com/open-horizon/anax/api"
 	"github.com/open-horizon/anax/apicommon"
 	"github.com/open-horizon/anax/cli/cliutils"
@@ -13,6 +12,8 @@ import (
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
 	"net/http"
+	"net/url"
+	"os"
 	"runtime"
 	"strings"
 )
@@ -73,11 +74,7 @@ func List() {
 	}
 
 	// Convert to json and output
-	jsonBytes, err := json.MarshalIndent(services, "", cliutils.JSON_INDENT)
-	if err != nil {
-		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn service list' output: %v", err))
-	}
-	fmt.Printf("%s\n", jsonBytes)
+	cliutils.Output(services, "hzn service list")
 }
 
 func Log(serviceName string, tailing bool) {
@@ -100,11 +97,12 @@ func Log(serviceName string, tailing bool) {
 	// Search the list of services to find one that matches the input service name. The service's instance Id
 	// is what appears in the syslog, so we need to save that.
 	serviceFound := false
-	var instanceId string
+	var instanceId, instanceService string
 	org, name := cutil.SplitOrgSpecUrl(refUrl)
 	for _, serviceInstance := range runningServices.Instances["active"] {
 		if (serviceInstance.SpecRef == name && serviceInstance.Org == org) || strings.Contains(serviceInstance.SpecRef, refUrl) {
 			instanceId = serviceInstance.InstanceId
+			instanceService = serviceInstance.Org + "/" + serviceInstance.SpecRef
 			serviceFound = true
 			msgPrinter.Printf("Displaying log messages for service %v with service id %v.", serviceInstance.SpecRef, instanceId)
 			msgPrinter.Println()
@@ -119,6 +117,23 @@ func Log(serviceName string, tailing bool) {
 		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Service %v is not running on the node.", refUrl))
 	}
 
+	// The agent reads the logs of the service containers from docker, so that they can be read without access to docker
+	// or the system log. An agent without the log API or where it is not enabled, or with containers whose logs docker
+	// can not read, returns 404 or 500, then the logs are read from where the log driver of the service writes them.
+	query := url.Values{}
+	query.Set("service", instanceService)
+	query.Set("instance", instanceId)
+	if tailing {
+		query.Set("follow", "true")
+	}
+	if httpCode, err := cliutils.HorizonStream("service/log?"+query.Encode(), os.Stdout); err == nil {
+		return
+	} else if httpCode != http.StatusNotFound && httpCode != http.StatusInternalServerError {
+		cliutils.Fatal(cliutils.HTTP_ERROR, err.Error())
+	} else {
+		cliutils.Verbose(msgPrinter.Sprintf("Unable to get the logs from the Horizon Agent, reading them from the node: %v", err))
+	}
+
 	// Check service's log-driver to read logs from correct place
 	var nonDefaultLogDriverUsed bool
 	for _, v := range runningServices.Definitions["active"] {
@@ -166,11 +181,7 @@ func Registered() {
 	}
 
 	// Convert to json and output
-	jsonBytes, err := json.MarshalIndent(apiOutput, "", cliutils.JSON_INDENT)
-	if err != nil {
-		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn service registered' output: %v", err))
-	}
-	fmt.Printf("%s\n", jsonBytes)
+	cliutils.Output(apiOutput, "hzn service registered")
 }
 
 func ListConfigState() {
@@ -184,11 +195,7 @@ func ListConfigState() {
 	}
 
 	// Convert to json and output
-	jsonBytes, err := json.MarshalIndent(apiOutput, "", cliutils.JSON_INDENT)
-	if err != nil {
-		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn service configstate' output: %v", err))
-	}
-	fmt.Printf("%s\n", jsonBytes)
+	cliutils.Output(apiOutput, "hzn service configstate")
 }
 
 func Suspend(forceSuspend bool, applyAll bool, serviceOrg string, serviceUrl string) {
@@ -0,0 +1,42 @@
+// +build unit
+
+package service
+
+import (
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func Test_Logs(t *testing.T) {
+	h := clitest.New(t)
+	h.Agent.SetNode(clitest.FAKE_ORG, "mynode", "netspeed", "configured")
+	h.Agent.Handle(http.MethodGet, "service", http.StatusOK, map[string]interface{}{"instances": map[string]interface{}{
+		"active": []interface{}{map[string]interface{}{"ref_url": "netspeed", "organization": "IBM", "version": "1.0.0", "instance_id": "ag1"}},
+	}})
+	h.Agent.Handle(http.MethodGet, "service/log", http.StatusOK, "speed test started\ndownload 93.41 Mbit/s\n")
+
+	res := h.Run(func() { Log("netspeed", true) })
+	if res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if !strings.Contains(res.Stdout, "speed test started\ndownload 93.41 Mbit/s\n") {
+		t.Errorf("expected the logs from the agent, found %v", res.Stdout)
+	}
+	reqs := h.Agent.RequestsTo(http.MethodGet, "service/log")
+	if len(reqs) != 1 {
+		t.Fatalf("expected 1 request for the logs, found %v", len(reqs))
+	}
+	query, _ := url.ParseQuery(reqs[0].Query)
+	if query.Get("service") != "IBM/netspeed" || query.Get("instance") != "ag1" || query.Get("follow") != "true" {
+		t.Errorf("wrong log request %v", query)
+	}
+
+	// an error that is not from an older agent or docker is not hidden by reading the logs from the node
+	h.Agent.Handle(http.MethodGet, "service/log", http.StatusBadRequest, `{"error":"bad input"}`)
+	if res := h.Run(func() { Log("netspeed", false) }); res.ExitCode != cliutils.HTTP_ERROR || !strings.Contains(res.Stderr, "bad input") {
+		t.Errorf("expected the error of the agent, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+}
@@ -0,0 +1,147 @@
+package status
+
+import (
+	"errors"
+	"fmt"
+	dockerclient "github.com/fsouza/go-dockerclient"
+	"github.com/open-horizon/anax/apicommon"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/worker"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The verdicts of the health report.
+const (
+	HEALTH_HEALTHY  = "healthy"
+	HEALTH_DEGRADED = "degraded"
+)
+
+// How long the docker daemon has to answer the health check.
+const DOCKER_HEALTH_TIMEOUT = 10 * time.Second
+
+// HealthReport is the output of 'hzn status --health'. The verdict is degraded when one of the checks is not healthy.
+type HealthReport struct {
+	Verdict          string        `json:"verdict"`
+	ActiveAgreements int           `json:"activeAgreements"`
+	Checks           []HealthCheck `json:"checks"`
+}
+
+// The result of one of the checks of the health report.
+type HealthCheck struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Detail  string `json:"detail,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// DisplayHealth checks the Horizon Agent API and its workers, the docker daemon, the Exchange and the agreements of
+// the node, and displays them in one report with an overall verdict. Each check is tried once, without the retries, so
+// that the command can be a monitoring probe: it exits with DEGRADED, after displaying the report, when a check fails.
+// The number of active agreements is reported, having none is not a failure because the node can be unregistered.
+func DisplayHealth() {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	// a probe should not wait for the retries of an endpoint that is down
+	if os.Getenv("HZN_HTTP_RETRIES") == "" {
+		os.Setenv("HZN_HTTP_RETRIES", "0")
+	}
+
+	report := HealthReport{Verdict: HEALTH_HEALTHY, Checks: []HealthCheck{}}
+	check := func(name string, f func() (string, error)) {
+		result := HealthCheck{Name: name, Healthy: true}
+		if detail, err := f(); err != nil {
+			result.Healthy = false
+			result.Error = strings.TrimSpace(err.Error())
+			report.Verdict = HEALTH_DEGRADED
+		} else {
+			result.Detail = detail
+		}
+		report.Checks = append(report.Checks, result)
+	}
+
+	// the agent api and its workers
+	check("agent", func() (string, error) {
+		status := apicommon.Info{}
+		if _, err := cliutils.HorizonGetE("status", []int{200}, &status); err != nil {
+			return "", err
+		} else if status.Configuration == nil {
+			return msgPrinter.Sprintf("no agent configuration"), nil
+		}
+		return msgPrinter.Sprintf("agent version %v", status.Configuration.HorizonVersion), nil
+	})
+	check("workers", func() (string, error) {
+		workers := worker.NewWorkerStatusManager()
+		if _, err := cliutils.HorizonGetE("status/workers", []int{200}, workers); err != nil {
+			return "", err
+		}
+		failed := []string{}
+		for name, w := range workers.Workers {
+			if w.Status == worker.STATUS_INIT_FAILED || w.Status == worker.STATUS_TERMINATED {
+				failed = append(failed, fmt.Sprintf("%v (%v)", name, w.Status))
+			}
+		}
+		if len(failed) > 0 {
+			sort.Strings(failed)
+			return "", errors.New(msgPrinter.Sprintf("the agent workers are not running: %v", strings.Join(failed, ", ")))
+		}
+		return msgPrinter.Sprintf("%v workers", len(workers.Workers)), nil
+	})
+
+	// the docker daemon that runs the service containers
+	check("docker", func() (string, error) {
+		client, err := dockerclient.NewClientFromEnv()
+		if err != nil {
+			return "", errors.New(msgPrinter.Sprintf("unable to create docker client: %v", err))
+		}
+		client.SetTimeout(DOCKER_HEALTH_TIMEOUT)
+		if err := client.Ping(); err != nil {
+			return "", errors.New(msgPrinter.Sprintf("unable to ping the docker daemon at %v: %v", client.Endpoint(), err))
+		}
+		if env, err := client.Version(); err == nil {
+			return msgPrinter.Sprintf("docker version %v", env.Get("Version")), nil
+		}
+		return msgPrinter.Sprintf("the docker daemon is responding"), nil
+	})
+
+	// the exchange that the node uses
+	check("exchange", func() (string, error) {
+		exchUrl := ""
+		if err := cliutils.Try(func() { exchUrl = cliutils.GetExchangeUrl() }); err != nil {
+			return "", err
+		} else if exchUrl == "" {
+			return "", errors.New(msgPrinter.Sprintf("the exchange URL is not set"))
+		}
+		var output []byte
+		if _, err := cliutils.ExchangeGetE("Exchange", exchUrl, "admin/version", "", []int{200}, &output); err != nil {
+			return "", err
+		}
+		return msgPrinter.Sprintf("exchange version %v at %v", strings.TrimSpace(string(output)), exchUrl), nil
+	})
+
+	// the agreements of the node
+	check("agreements", func() (string, error) {
+		agreements := map[string]map[string][]persistence.EstablishedAgreement{}
+		if _, err := cliutils.HorizonGetE("agreement", []int{200}, &agreements); err != nil {
+			return "", err
+		}
+		report.ActiveAgreements = len(agreements["agreements"]["active"])
+		return msgPrinter.Sprintf("%v active agreements", report.ActiveAgreements), nil
+	})
+
+	cliutils.Output(report, "hzn status --health")
+
+	if report.Verdict != HEALTH_HEALTHY {
+		failed := []string{}
+		for _, c := range report.Checks {
+			if !c.Healthy {
+				failed = append(failed, c.Name)
+			}
+		}
+		cliutils.Fatal(cliutils.DEGRADED, msgPrinter.Sprintf("the Horizon node is degraded, the health checks that failed: %v", strings.Join(failed, ", ")))
+	}
+}
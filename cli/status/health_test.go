@@ -0,0 +1,50 @@
+// +build unit
+
+package status
+
+import (
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_Health(t *testing.T) {
+	h := clitest.New(t)
+	h.Agent.Handle(http.MethodGet, "status/workers", http.StatusOK, map[string]interface{}{"workers": map[string]interface{}{
+		"Agreement": map[string]interface{}{"name": "Agreement", "status": "initialized"},
+	}})
+	h.Agent.Handle(http.MethodGet, "agreement", http.StatusOK, map[string]interface{}{"agreements": map[string]interface{}{
+		"active":   []interface{}{map[string]interface{}{"current_agreement_id": "ag1"}, map[string]interface{}{"current_agreement_id": "ag2"}},
+		"archived": []interface{}{},
+	}})
+	docker := clitest.NewFakeServer(t)
+	docker.Handle(http.MethodGet, "_ping", http.StatusOK, "OK")
+	docker.Handle(http.MethodGet, "version", http.StatusOK, map[string]interface{}{"Version": "19.03.8"})
+	h.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(docker.URL, "http://"))
+	h.Setenv("HZN_HTTP_RETRIES", "0")
+
+	health := func(expectedCode int) HealthReport {
+		res := h.Run(func() { DisplayHealth() })
+		if res.ExitCode != expectedCode {
+			t.Fatalf("expected exit code %v, found %v, stderr: %v", expectedCode, res.ExitCode, res.Stderr)
+		}
+		report := HealthReport{}
+		res.JSON(t, &report)
+		return report
+	}
+	if report := health(0); report.Verdict != HEALTH_HEALTHY || report.ActiveAgreements != 2 || len(report.Checks) != 5 || report.Checks[2].Detail != "docker version 19.03.8" {
+		t.Errorf("unexpected report %v", report)
+	}
+
+	// a worker that failed and a docker daemon that is down degrade the node
+	h.Agent.Handle(http.MethodGet, "status/workers", http.StatusOK, map[string]interface{}{"workers": map[string]interface{}{
+		"Agreement": map[string]interface{}{"name": "Agreement", "status": "initialization failed"},
+	}})
+	docker.Handle(http.MethodGet, "_ping", http.StatusInternalServerError, "down")
+	report := health(cliutils.DEGRADED)
+	if report.Verdict != HEALTH_DEGRADED || report.Checks[1].Healthy || report.Checks[2].Healthy || !report.Checks[3].Healthy || !strings.Contains(report.Checks[1].Error, "Agreement (initialization failed)") {
+		t.Errorf("unexpected degraded report %v", report)
+	}
+}
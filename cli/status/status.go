@@ -1,12 +1,8 @@
 package status
 
 import (
-	"encoding/json"
-	"fmt"
 	"github.com/open-horizon/anax/cli/cliutils"
-	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/anax/worker"
-	"os"
 )
 
 func getStatus(agbot bool) (apiOutput *worker.WorkerStatusManager) {
@@ -14,9 +10,7 @@ func getStatus(agbot bool) (apiOutput *worker.WorkerStatusManager) {
 
 	if agbot {
 		// set env to call agbot url
-		if err := os.Setenv("HORIZON_URL", cliutils.GetAgbotUrlBase()); err != nil {
-			cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, "%v", err)
-		}
+		cliutils.UseAgbotUrlBase()
 	}
 
 	// Get horizon api worker status
@@ -27,25 +21,15 @@ func getStatus(agbot bool) (apiOutput *worker.WorkerStatusManager) {
 
 // Display status for node or agbot
 func DisplayStatus(details bool, agbot bool) {
-	// get message printer
-	msgPrinter := i18n.GetMessagePrinter()
 
 	status := getStatus(agbot)
 
 	if details {
-		jsonBytes, err := json.MarshalIndent(status, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn status -l' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(status, "hzn status -l")
 	} else {
 		workers := make(map[string]map[string]*worker.WorkerStatus)
 		workers["workers"] = status.Workers
 
-		jsonBytes, err := json.MarshalIndent(workers, "", cliutils.JSON_INDENT)
-		if err != nil {
-			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to marshal 'hzn status' output: %v", err))
-		}
-		fmt.Printf("%s\n", jsonBytes)
+		cliutils.Output(workers, "hzn status")
 	}
 }
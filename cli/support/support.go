@@ -0,0 +1,276 @@
+package support
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/persistence"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The name of the file of the support bundle that lists what was collected and what could not be.
+const SUMMARY_FILE = "summary.txt"
+
+// A file of the support bundle, and how its content is collected.
+type bundleFile struct {
+	name    string
+	collect func() ([]byte, error)
+}
+
+// DefaultDumpFile is the name of the support bundle of this host at this time, in the current directory.
+func DefaultDumpFile() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "node"
+	}
+	return fmt.Sprintf("hzn-support-%v-%v.tar.gz", host, time.Now().Format("20060102-150405"))
+}
+
+// Dump gathers what support needs to troubleshoot this node into a tar.gz file: the status of the agent, its event
+// logs, its agreements without their proposals, the horizon containers, the configuration of the agent and of hzn, and
+// the recent journald entries of the horizon service when they are asked for. The values of the json fields, of the
+// name/value pairs and of the settings whose names show that they are credentials are redacted, but the free text of
+// the event logs and of the journal is included as it is, except for the credentials that look like name=value, so
+// the bundle should be reviewed before it is shared. What cannot be collected, for example because the agent is not
+// running, is listed in the summary of the bundle instead of failing the command.
+func Dump(file string, includeJournal bool, journalLines int) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if file == "" {
+		file = DefaultDumpFile()
+	}
+	if journalLines <= 0 {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("--journal-lines must be greater than 0"))
+	}
+
+	files := []bundleFile{
+		{"agent/node.json", agentApi("node")},
+		{"agent/status.json", agentApi("status")},
+		{"agent/status-workers.json", agentApi("status/workers")},
+		{"agent/configstate.json", agentApi("node/configstate")},
+		{"agent/policy.json", agentApi("node/policy")},
+		{"agent/agreements.json", agreements},
+		{"agent/eventlog.json", agentApi("eventlog/all")},
+		{"system/docker-ps.txt", horizonContainers},
+		{"config/horizon", envFile(cliutils.ANAX_OVERWRITE_FILE)},
+		{"config/anax.json", jsonFile(cliutils.ANAX_CONFIG_FILE)},
+		{"config/hzn-env.txt", hznEnv},
+	}
+	if includeJournal {
+		files = append(files, bundleFile{"system/journal.txt", redactedText(command("journalctl", "-u", "horizon.service", "--no-pager", "-n", strconv.Itoa(journalLines)))})
+	}
+
+	dir := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(file), ".gz"), ".tar")
+	contents := map[string][]byte{}
+	summary := &bytes.Buffer{}
+	fmt.Fprintf(summary, "%v\n\n", msgPrinter.Sprintf("Horizon support bundle created at %v", time.Now().Format(time.RFC3339)))
+	failed := 0
+	for _, f := range files {
+		content, err := f.collect()
+		if err != nil {
+			failed++
+			cliutils.Warning(msgPrinter.Sprintf("unable to collect %v: %v", f.name, err))
+			fmt.Fprintf(summary, "%v: %v\n", f.name, msgPrinter.Sprintf("not collected, %v", err))
+			continue
+		}
+		contents[f.name] = content
+		fmt.Fprintf(summary, "%v: %v\n", f.name, msgPrinter.Sprintf("%d bytes", len(content)))
+	}
+	contents[SUMMARY_FILE] = summary.Bytes()
+
+	if err := writeTarGz(file, dir, contents); err != nil {
+		cliutils.Fatal(cliutils.FILE_IO_ERROR, msgPrinter.Sprintf("unable to write the support bundle %v: %v", file, err))
+	}
+	msgPrinter.Printf("Wrote the support bundle %v with %d files.", file, len(contents))
+	msgPrinter.Println()
+	if failed > 0 {
+		msgPrinter.Printf("%d items could not be collected, they are listed in %v/%v.", failed, dir, SUMMARY_FILE)
+		msgPrinter.Println()
+	}
+}
+
+// The output of an API of the agent, with the credentials redacted.
+func agentApi(path string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		body := &bytes.Buffer{}
+		if _, err := cliutils.HorizonGet(path, []int{200}, body, true); err != nil {
+			return nil, err
+		}
+		return redactedJson(body.Bytes()), nil
+	}
+}
+
+// The active and archived agreements of the agent. The proposals are removed, they have the deployment of the services
+// with their environment variables.
+func agreements() ([]byte, error) {
+	apiOutput := map[string]map[string][]persistence.EstablishedAgreement{}
+	if _, err := cliutils.HorizonGet("agreement", []int{200}, &apiOutput, true); err != nil {
+		return nil, err
+	}
+	for _, ags := range apiOutput["agreements"] {
+		for i := range ags {
+			ags[i].Proposal, ags[i].ProposalSig = "", ""
+		}
+	}
+	jsonBytes, err := json.Marshal(apiOutput)
+	if err != nil {
+		return nil, err
+	}
+	return redactedJson(jsonBytes), nil
+}
+
+// The output of a command, the error has the output of the command when it fails.
+func command(name string, args ...string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		cliutils.Verbose(i18n.GetMessagePrinter().Sprintf("running: %v %v", name, strings.Join(args, " ")))
+		out, err := exec.Command(name, args...).CombinedOutput()
+		if err != nil {
+			if msg := strings.TrimSpace(string(out)); msg != "" {
+				return nil, fmt.Errorf("%v: %v", err, msg)
+			}
+			return nil, err
+		}
+		return out, nil
+	}
+}
+
+// The credentials in free text that look like a setting, PASSWORD=value or "token": "value", or like a name/value
+// pair of json.
+var reTextCredential = regexp.MustCompile(`(?i)((?:password|token|secret|apikey|privatekey|_auth)[\w.-]*"?\s*[=:]\s*"?)[^\s",]+`)
+var reTextPair = regexp.MustCompile(`(?i)("name"\s*:\s*"[^"]*(?:password|token|secret|apikey|privatekey|_auth)[^"]*"\s*,\s*"value"\s*:\s*)("[^"]*"|[^\s,}]+)`)
+
+// The output of the collect function, with the credentials that can be recognized in free text redacted.
+func redactedText(collect func() ([]byte, error)) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		out, err := collect()
+		if err != nil {
+			return nil, err
+		}
+		out = reTextPair.ReplaceAll(out, []byte(`${1}"`+cliutils.REDACTED+`"`))
+		return reTextCredential.ReplaceAll(out, []byte("${1}"+cliutils.REDACTED)), nil
+	}
+}
+
+// The docker containers of the agent and of the services that it runs, which all have horizon in their names or
+// labels.
+func horizonContainers() ([]byte, error) {
+	out, err := command("docker", "ps", "--all", "--no-trunc", "--format", "table {{.ID}}\t{{.Image}}\t{{.Command}}\t{{.CreatedAt}}\t{{.Status}}\t{{.Names}}\t{{.Labels}}")()
+	if err != nil {
+		return nil, err
+	}
+	containers := &bytes.Buffer{}
+	for i, line := range strings.Split(strings.TrimSuffix(string(out), "\n"), "\n") {
+		if i == 0 || strings.Contains(line, "horizon") {
+			containers.WriteString(line + "\n")
+		}
+	}
+	return containers.Bytes(), nil
+}
+
+// A file of NAME=VALUE settings, with the values of the credentials redacted.
+func envFile(path string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		redacted := &bytes.Buffer{}
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			line := scanner.Text()
+			if parts := strings.SplitN(line, "=", 2); len(parts) == 2 && !strings.HasPrefix(strings.TrimSpace(line), "#") && isRedactedSetting(parts[0]) {
+				line = parts[0] + "=" + cliutils.REDACTED
+			}
+			redacted.WriteString(line + "\n")
+		}
+		return redacted.Bytes(), scanner.Err()
+	}
+}
+
+// A json file, with the values of the credentials redacted.
+func jsonFile(path string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		} else if !json.Valid(content) {
+			return nil, errors.New(i18n.GetMessagePrinter().Sprintf("%v is not valid json", path))
+		}
+		return redactedJson(content), nil
+	}
+}
+
+// The environment variables that configure hzn, with the values of the credentials redacted.
+func hznEnv() ([]byte, error) {
+	settings := []string{}
+	for _, env := range os.Environ() {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 || !(strings.HasPrefix(parts[0], "HZN_") || strings.HasPrefix(parts[0], "HORIZON_") || strings.HasSuffix(strings.ToUpper(parts[0]), "_PROXY")) {
+			continue
+		}
+		if isRedactedSetting(parts[0]) {
+			parts[1] = cliutils.REDACTED
+		} else if strings.HasSuffix(strings.ToUpper(parts[0]), "_PROXY") {
+			parts[1] = cliutils.MaskProxyCredentials(parts[1])
+		}
+		settings = append(settings, parts[0]+"="+parts[1])
+	}
+	sort.Strings(settings)
+	return []byte(strings.Join(settings, "\n") + "\n"), nil
+}
+
+// Returns true if a setting can have credentials, like HZN_EXCHANGE_USER_AUTH.
+func isRedactedSetting(name string) bool {
+	name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "export "))
+	return cliutils.IsRedactedName(name) || strings.HasSuffix(strings.ToUpper(name), "_AUTH")
+}
+
+func redactedJson(data []byte) []byte {
+	return []byte(cliutils.RedactJson(data) + "\n")
+}
+
+// Write the contents, by the names of their files, to a tar.gz file in which they are in dir.
+func writeTarGz(file string, dir string, contents map[string][]byte) error {
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	names := make([]string, 0, len(contents))
+	for name := range contents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	now := time.Now()
+	for _, name := range names {
+		hdr := &tar.Header{Name: dir + "/" + name, Mode: 0600, Size: int64(len(contents[name])), ModTime: now}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		} else if _, err := tw.Write(contents[name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	} else if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,122 @@
+// +build unit
+
+package support
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_SupportDump(t *testing.T) {
+	h := clitest.New(t)
+	h.Setenv("HZN_HTTP_RETRIES", "0")
+	h.Setenv("HZN_EXCHANGE_USER_AUTH", "me:mysecret")
+
+	dir, err := ioutil.TempDir("", "clitest-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the journal and the docker containers come from commands in the PATH
+	scripts := map[string]string{
+		"journalctl": "#!/bin/sh\necho \"horizon started $*\"\necho 'registering with HZN_EXCHANGE_USER_AUTH=me:journalsecret'\n",
+		"docker":     "#!/bin/sh\necho 'CONTAINER ID   IMAGE   NAMES'\necho 'abc   openhorizon/amd64_anax   horizon1'\necho 'def   ubuntu   other'\n",
+	}
+	for name, script := range scripts {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+			t.Fatalf("unable to write %v: %v", name, err)
+		}
+	}
+	h.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	h.Agent.Handle(http.MethodGet, "node", http.StatusOK, `{"id":"mynode","organization":"myorg","token":"mynodetoken","config":{"state":"configured"}}`)
+	h.Agent.Handle(http.MethodGet, "agreement", http.StatusOK, `{"agreements":{"active":[{"name":"ag1","current_agreement_id":"a1","proposal":"the deployment with its secrets","proposal_sig":"sig"}],"archived":[]}}`)
+	h.Agent.Handle(http.MethodGet, "eventlog/all", http.StatusOK, `[{"record_id":"1","message":"Start node configuration/registration for node mynode.","event_source":{"user_input":[{"name":"DB_PASSWORD","value":"eventsecret"}]}}]`)
+	h.Agent.Handle(http.MethodGet, "status/workers", http.StatusInternalServerError, `{"error":"failed"}`)
+
+	file := filepath.Join(dir, "bundle.tar.gz")
+	res := h.Run(func() { Dump(file, true, 20) })
+	if res.ExitCode != 0 {
+		t.Fatalf("hzn support-dump failed with exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if !strings.Contains(res.Stdout, "Wrote the support bundle "+file) || !strings.Contains(res.Stderr, "unable to collect agent/status-workers.json") {
+		t.Errorf("unexpected output %v, stderr: %v", res.Stdout, res.Stderr)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatalf("unable to open the bundle: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("the bundle is not gzipped: %v", err)
+	}
+	contents := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("unable to read the bundle: %v", err)
+		}
+		content, _ := ioutil.ReadAll(tr)
+		contents[strings.TrimPrefix(hdr.Name, "bundle/")] = string(content)
+	}
+
+	if node := contents["agent/node.json"]; !strings.Contains(node, `"id": "mynode"`) || strings.Contains(node, "mynodetoken") {
+		t.Errorf("the node should be in the bundle with its token redacted: %v", node)
+	}
+	if ags := contents["agent/agreements.json"]; !strings.Contains(ags, `"current_agreement_id": "a1"`) || strings.Contains(ags, "secrets") || strings.Contains(ags, `"sig"`) {
+		t.Errorf("the agreements should be in the bundle without their proposals: %v", ags)
+	}
+	if events := contents["agent/eventlog.json"]; !strings.Contains(events, "Start node configuration") || strings.Contains(events, "eventsecret") {
+		t.Errorf("the event logs should be in the bundle with the values of the credentials redacted: %v", events)
+	}
+	if journal := contents["system/journal.txt"]; !strings.Contains(journal, "horizon started -u horizon.service --no-pager -n 20") || strings.Contains(journal, "journalsecret") {
+		t.Errorf("unexpected journal: %v", journal)
+	} else if !strings.Contains(journal, "HZN_EXCHANGE_USER_AUTH="+cliutils.REDACTED) {
+		t.Errorf("the credentials in the journal should be redacted: %v", journal)
+	}
+	if containers := contents["system/docker-ps.txt"]; !strings.Contains(containers, "horizon1") || strings.Contains(containers, "ubuntu") {
+		t.Errorf("only the horizon containers should be in the bundle: %v", containers)
+	}
+	if env := contents["config/hzn-env.txt"]; !strings.Contains(env, "HZN_EXCHANGE_USER_AUTH="+cliutils.REDACTED) || strings.Contains(env, "mysecret") {
+		t.Errorf("the credentials should be redacted: %v", env)
+	}
+	if _, ok := contents["agent/status-workers.json"]; ok {
+		t.Errorf("the workers status should not be in the bundle")
+	} else if summary := contents[SUMMARY_FILE]; !strings.Contains(summary, "agent/status-workers.json: not collected") || !strings.Contains(summary, "agent/node.json: ") {
+		t.Errorf("unexpected summary: %v", summary)
+	}
+
+	if res := h.Run(func() { Dump(file, true, 0) }); res.ExitCode != cliutils.CLI_INPUT_ERROR {
+		t.Errorf("expected an input error for 0 journal lines, got %v", res.ExitCode)
+	}
+
+	// the journal is only included when it is asked for
+	if res := h.Run(func() { Dump(file, false, 20) }); res.ExitCode != 0 {
+		t.Fatalf("hzn support-dump failed with exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if f, err := os.Open(file); err != nil {
+		t.Fatalf("unable to open the bundle: %v", err)
+	} else {
+		defer f.Close()
+		gz, _ := gzip.NewReader(f)
+		tr := tar.NewReader(gz)
+		for hdr, err := tr.Next(); err == nil; hdr, err = tr.Next() {
+			if strings.HasSuffix(hdr.Name, "system/journal.txt") {
+				t.Errorf("the journal should not be in the bundle without --include-journal")
+			}
+		}
+	}
+}
@@ -9,7 +9,7 @@ import (
 	"strings"
 )
 
-// ObjectDownLoad is to download data to a file named ${objectType}_${objectId}
+// ObjectDownLoad is to download data to a file named ${objectType}_${objectId}, or to the file or stdout (-) of filePath
 func ObjectDownLoad(org string, userPw string, objType string, objId string, filePath string, overwrite bool) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
@@ -20,15 +20,7 @@ func ObjectDownLoad(org string, userPw string, objType string, objId string, fil
 
 	// For this command, object type and id are required parameters, No null checking is needed.
 	// Set the API key env var if that's what we're using.
-	cliutils.SetWhetherUsingApiKey(userPw)
-
-	// Call the MMS service over HTTP to download the object data.
-	var data []byte
-	urlPath := path.Join("api/v1/objects/", org, objType, objId, "/data")
-	httpCode := cliutils.ExchangeGet("Model Management Service", cliutils.GetMMSUrl(), urlPath, cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &data)
-	if httpCode == 404 {
-		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("object '%s' of type '%s' not found in org %s", objId, objType, org))
-	}
+	cliutils.SetWhetherUsingWiotp(userPw)
 
 	var fileName string
 	// if no fileName and filePath specified, data will be saved in current dir, with name {objectType}_{objectId}
@@ -67,18 +59,16 @@ func ObjectDownLoad(org string, userPw string, objType string, objId string, fil
 		}
 	}
 
-	file, err := os.Create(fileName)
-	if err != nil {
-		cliutils.Fatal(cliutils.INTERNAL_ERROR, msgPrinter.Sprintf("Failed to create file: %s", fileName))
+	// Call the MMS service over HTTP to stream the object data to the file, its size is not limited by the memory of hzn.
+	urlPath := path.Join("api/v1/objects/", org, objType, objId, "/data")
+	httpCode := cliutils.ExchangeGetToFile("Model Management Service", cliutils.GetMMSUrl(), urlPath, cliutils.OrgAndCreds(org, userPw), []int{200, 404}, fileName)
+	if httpCode == 404 {
+		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("object '%s' of type '%s' not found in org %s", objId, objType, org))
 	}
 
-	defer file.Close()
-
-	if _, err := file.Write(data); err != nil {
-		cliutils.Fatal(cliutils.INTERNAL_ERROR, msgPrinter.Sprintf("Failed to save data for object '%s' of type '%s' to file %s", objId, objType, fileName))
+	if fileName != "-" {
+		msgPrinter.Printf("Data of object %v saved to file %v", objId, fileName)
+		msgPrinter.Println()
 	}
 
-	msgPrinter.Printf("Data of object %v saved to file %v", objId, fileName)
-	msgPrinter.Println()
-
 }
@@ -37,7 +37,7 @@ func ObjectList(org string, userPw string, objType string, objId string, destPol
 	}
 
 	// Set the API key env var if that's what we're using.
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 
 	var objectsMeta []common.MetaData
 
@@ -118,7 +118,7 @@ func ObjectList(org string, userPw string, objType string, objId string, destPol
 		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("no objects found in org %s", org))
 	}
 
-	output := ""
+	var output interface{}
 
 	if details {
 		// Cut the objectsMeta into batches of size 50. For each batch, process the API call concurrently. Use batches strategy to 1) reduce the processing time, 2) avoid overwhelming API calls sent to CSS server at one time
@@ -182,7 +182,7 @@ func ObjectList(org string, userPw string, objType string, objId string, destPol
 			}
 		}
 
-		output = cliutils.MarshalIndent(mmsObjects, "mms object list")
+		output = mmsObjects
 	} else {
 		if !long {
 			mmsObjects := make([]MMSObjectInfo, 0)
@@ -193,19 +193,15 @@ func ObjectList(org string, userPw string, objType string, objId string, destPol
 				}
 				mmsObjects = append(mmsObjects, mmsObjectInfo)
 			}
-			output = cliutils.MarshalIndent(mmsObjects, "mms object list")
+			output = mmsObjects
 		} else {
-			var err1 error
-			output, err1 = cliutils.DisplayAsJson(objectsMeta)
-			if err1 != nil {
-				cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("failed to marshal 'hzn mms object list' output: %v", err1))
-			}
+			output = objectsMeta
 		}
 	}
 
 	msgPrinter.Printf("Listing objects in org %v:", org)
 	msgPrinter.Println()
-	fmt.Println(output)
+	cliutils.Output(output, "hzn mms object list")
 }
 
 func ObjectNew(org string) {
@@ -375,7 +371,7 @@ func ObjectDelete(org string, userPw string, objType string, objId string) {
 	// For this command, object type and id are required parameters, No null checking is needed.
 
 	// Set the API key env var if that's what we're using.
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 
 	// Call the MMS service over HTTP to delete the object.
 	urlPath := path.Join("api/v1/objects/", org, objType, objId)
@@ -1,7 +1,6 @@
 package sync_service
 
 import (
-	"fmt"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/edge-sync-service/common"
@@ -22,7 +21,7 @@ func Status(org string, userPw string) {
 	}
 
 	// Set the API key env var if that's what we're using.
-	cliutils.SetWhetherUsingApiKey(userPw)
+	cliutils.SetWhetherUsingWiotp(userPw)
 
 	// Display the minimal health status.
 	var healthData MMSHealth
@@ -35,6 +34,5 @@ func Status(org string, userPw string) {
 	if httpCode != 200 {
 		cliutils.Fatal(cliutils.HTTP_ERROR, msgPrinter.Sprintf("health status API returned HTTP code %v", httpCode))
 	}
-	output := cliutils.MarshalIndent(healthData, "mms health")
-	fmt.Println(output)
+	cliutils.Output(healthData, "mms health")
 }
@@ -490,7 +490,7 @@ func putFile(url string, org string, metadata *cssFileMeta, file []byte) error {
 
 	// First put the metadata into the CSS.
 	httpClient := cliutils.GetHTTPClient(0)
-	req, err := http.NewRequest(http.MethodPut, url, requestBody)
+	req, err := http.NewRequestWithContext(cliutils.Context(), http.MethodPut, url, requestBody)
 	if err != nil {
 		return errors.New(msgPrinter.Sprintf("unable to create CSS file PUT request for %v, error %v", *metadata, err))
 	}
@@ -598,7 +598,7 @@ func checkCSSStatus(org string, timeout int) error {
 	cliutils.Verbose(apiMsg)
 
 	httpClient := cliutils.GetHTTPClient(0)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(cliutils.Context(), http.MethodGet, url, nil)
 	if err != nil {
 		return errors.New(msgPrinter.Sprintf("unable to create get CSS status request, error %v", err))
 	}
@@ -18,6 +18,12 @@ import (
 	"time"
 )
 
+// The docker API of the node, and the label that the agent puts on the service containers.
+const (
+	DOCKER_ENDPOINT    = "unix:///var/run/docker.sock"
+	SERVICE_NAME_LABEL = "openhorizon.anax.service_name"
+)
+
 type ApiAttribute struct {
 	Id string `json:"id"`
 }
@@ -26,8 +32,13 @@ type ApiAttributes struct {
 	Attributes []ApiAttribute `json:"attributes"`
 }
 
-// DoIt unregisters this Horizon edge node and resets it so it can be registered again
-func DoIt(forceUnregister, removeNodeUnregister bool, deepClean bool, timeout int) {
+// The functions that find and remove the images of the service containers, which the tests replace.
+var listServiceImages = ServiceImages
+var deleteServiceImages = removeServiceImages
+
+// DoIt unregisters this Horizon edge node and resets it so it can be registered again. With removeImages the images of
+// the service containers are removed once the node is unregistered.
+func DoIt(forceUnregister, removeNodeUnregister bool, deepClean bool, removeImages bool, timeout int) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
@@ -47,6 +58,16 @@ func DoIt(forceUnregister, removeNodeUnregister bool, deepClean bool, timeout in
 		}
 	}
 
+	// the images of the service containers are found before the agent removes the containers
+	var serviceImages []string
+	if removeImages && !isClusterNode() {
+		var err error
+		if serviceImages, err = listServiceImages(); err != nil {
+			msgPrinter.Printf("Cannot find the images of the service containers: %v", err)
+			msgPrinter.Println()
+		}
+	}
+
 	if horDevice.Org == nil || *horDevice.Org == "" {
 		msgPrinter.Printf("The node is not registered.")
 		msgPrinter.Println()
@@ -57,6 +78,7 @@ func DoIt(forceUnregister, removeNodeUnregister bool, deepClean bool, timeout in
 				fmt.Println(err.Error())
 			}
 		}
+		deleteServiceImages(serviceImages)
 	} else {
 		// start unregistering the node
 		msgPrinter.Printf("Unregistering this node, cancelling all agreements, stopping all workloads, and restarting Horizon...")
@@ -64,6 +86,7 @@ func DoIt(forceUnregister, removeNodeUnregister bool, deepClean bool, timeout in
 
 		// call horizon DELETE /node api, default timeout is to wait forever.
 		unregErr := DeleteHorizonNode(removeNodeUnregister, deepClean, timeout)
+		externalClean := false
 
 		// deep clean if anax failed to do it
 		if unregErr != nil {
@@ -74,11 +97,17 @@ func DoIt(forceUnregister, removeNodeUnregister bool, deepClean bool, timeout in
 					msgPrinter.Println()
 				}
 
+				externalClean = true
 				if err := DeepClean(); err != nil {
 					fmt.Println(err.Error())
 				} else {
 					unregErr = nil
 				}
+
+				// the agent did not get to remove the node from the exchange
+				if removeNodeUnregister {
+					deleteExchangeNode(*horDevice.Org, *horDevice.Id)
+				}
 			} else {
 				msgPrinter.Printf("The node was not successfully unregistered, please use 'hzn unregister -D' to ensure the node is completely reset. Specific anax API error is: %v", unregErr.Error())
 				msgPrinter.Println()
@@ -99,6 +128,56 @@ func DoIt(forceUnregister, removeNodeUnregister bool, deepClean bool, timeout in
 				msgPrinter.Println()
 			}
 		}
+
+		// remove what the agent left behind, so that the node is in a pristine state
+		if unregErr == nil && deepClean && !externalClean && !isClusterNode() {
+			msgPrinter.Printf("Deleting the remaining service containers and networks...")
+			msgPrinter.Println()
+			if err := RemoveServiceContainers(); err != nil {
+				fmt.Println(err.Error())
+			}
+		}
+		if unregErr == nil {
+			deleteServiceImages(serviceImages)
+		}
+	}
+}
+
+// Returns true if the agent runs in an edge cluster, where the services are not docker containers.
+func isClusterNode() bool {
+	_, err := cutil.NewKubeConfig()
+	return err == nil
+}
+
+// Delete the node from the exchange, with the user credentials if they are set, otherwise with the node credentials.
+// Only a warning is displayed if it fails, the node is unregistered anyway.
+func deleteExchangeNode(org string, nodeId string) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	creds := cliutils.GetUserAuth("")
+	if creds == "" {
+		creds = cliutils.GetNodeAuth("")
+	}
+	if creds == "" {
+		msgPrinter.Printf("WARNING: node %v/%v is not removed from the Exchange, no exchange credentials are set. Run 'hzn exchange node remove %v' to remove it.", org, nodeId, nodeId)
+		msgPrinter.Println()
+		return
+	}
+
+	msgPrinter.Printf("Removing node %v/%v from the Exchange...", org, nodeId)
+	msgPrinter.Println()
+	httpCode := 0
+	err := cliutils.Try(func() {
+		cliutils.SetWhetherUsingWiotp(creds)
+		httpCode = cliutils.ExchangeDelete("Exchange", cliutils.GetExchangeUrl(), "orgs/"+org+"/nodes/"+nodeId, cliutils.OrgAndCreds(org, creds), nil)
+	})
+	if err == nil && httpCode != http.StatusNoContent && httpCode != http.StatusNotFound {
+		err = fmt.Errorf(msgPrinter.Sprintf("HTTP code %v", httpCode))
+	}
+	if err != nil {
+		msgPrinter.Printf("WARNING: failed to remove node %v/%v from the Exchange: %v", org, nodeId, strings.TrimSpace(err.Error()))
+		msgPrinter.Println()
 	}
 }
 
@@ -183,7 +262,7 @@ func DeepClean() error {
 
 	// detect the node type
 	nodeType := persistence.DEVICE_TYPE_DEVICE
-	if _, err := cutil.NewKubeConfig(); err == nil {
+	if isClusterNode() {
 		nodeType = persistence.DEVICE_TYPE_CLUSTER
 	}
 
@@ -216,7 +295,7 @@ func DeepClean() error {
 		msgPrinter.Printf("Deleting service containers...")
 		msgPrinter.Println()
 		if err := RemoveServiceContainers(); err != nil {
-			fmt.Printf(err.Error())
+			fmt.Println(err.Error())
 		}
 
 		msgPrinter.Printf("Starting horizon...")
@@ -258,8 +337,7 @@ func RemoveServiceContainers() error {
 	msgPrinter := i18n.GetMessagePrinter()
 
 	// get docker client
-	dockerEP := "unix:///var/run/docker.sock"
-	client, derr := docker.NewClient(dockerEP)
+	client, derr := docker.NewClient(DOCKER_ENDPOINT)
 	if derr != nil {
 		return derr
 	}
@@ -281,7 +359,7 @@ func RemoveServiceContainers() error {
 			continue
 		} else {
 			for k, _ := range c.Labels {
-				if k == "openhorizon.anax.service_name" {
+				if k == SERVICE_NAME_LABEL {
 					if err := client.RemoveContainer(docker.RemoveContainerOptions{ID: c.ID, RemoveVolumes: true, Force: true}); err != nil {
 						err_string += msgPrinter.Sprintf("Error deleting container %v. %v\n", c.Names[0], err)
 					} else {
@@ -305,6 +383,56 @@ func RemoveServiceContainers() error {
 	}
 }
 
+// ServiceImages returns the images of the horizon service containers, so that they can be removed after the
+// containers are removed.
+func ServiceImages() ([]string, error) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	client, err := docker.NewClient(DOCKER_ENDPOINT)
+	if err != nil {
+		return nil, err
+	}
+	containers, err := client.ListContainers(docker.ListContainersOptions{All: true, Filters: map[string][]string{"label": {SERVICE_NAME_LABEL}}})
+	if err != nil {
+		return nil, fmt.Errorf(msgPrinter.Sprintf("unable to list containers, %v", err))
+	}
+
+	images := []string{}
+	for _, c := range containers {
+		if !cutil.SliceContains(images, c.Image) {
+			images = append(images, c.Image)
+		}
+	}
+	return images, nil
+}
+
+// Remove the images of the service containers. An image that another container still uses is not removed.
+func removeServiceImages(images []string) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if len(images) == 0 {
+		return
+	}
+	msgPrinter.Printf("Deleting service images...")
+	msgPrinter.Println()
+
+	client, err := docker.NewClient(DOCKER_ENDPOINT)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	for _, image := range images {
+		if err := client.RemoveImageExtended(image, docker.RemoveImageOptions{Force: false}); err != nil && err != docker.ErrNoSuchImage {
+			msgPrinter.Printf("Error deleting image %v. %v", image, err)
+			msgPrinter.Println()
+		} else if err == nil {
+			cliutils.Verbose(msgPrinter.Sprintf("Removed service image: %v", image))
+		}
+	}
+}
+
 // backupEventLogs loads eventlogs from eventlog API , marshals them into the JSON format
 // and saves the bkp file into horizon folder with name of backup time
 func backupEventLogs() error {
@@ -0,0 +1,115 @@
+// +build unit
+
+package unregister
+
+import (
+	"github.com/open-horizon/anax/cli/clitest"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// The GET /node response of the fake agent.
+func node(org string, id string, configState string) clitest.Response {
+	return clitest.Response{Code: http.StatusOK, Body: map[string]interface{}{
+		"id":           id,
+		"organization": org,
+		"configstate":  map[string]interface{}{"state": configState, "last_update_time": 0},
+	}}
+}
+
+// Replace the docker calls for the images of the service containers, and return the images that are removed.
+func fakeServiceImages(t *testing.T, images ...string) *[]string {
+	listed, removed := false, []string{}
+	prevList, prevDelete := listServiceImages, deleteServiceImages
+	listServiceImages = func() ([]string, error) {
+		listed = true
+		return images, nil
+	}
+	deleteServiceImages = func(images []string) {
+		if len(images) != 0 && !listed {
+			t.Errorf("the images should be listed before they are removed")
+		}
+		removed = append(removed, images...)
+	}
+	t.Cleanup(func() { listServiceImages, deleteServiceImages = prevList, prevDelete })
+	return &removed
+}
+
+func Test_DoIt_removeImages(t *testing.T) {
+	h := clitest.New(t)
+	h.Agent.Handle(http.MethodDelete, "node", http.StatusNoContent, nil)
+
+	// the images are only removed with the flag
+	removed := fakeServiceImages(t, "myorg/netspeed:1.0", "myorg/cpu:2.0")
+	h.Agent.HandleSequence(http.MethodGet, "node", node("myorg", "edge1", "configured"), node("", "", "unconfigured"))
+	if res := h.Run(func() { DoIt(true, false, false, false, 0) }); res.ExitCode != 0 || !strings.Contains(res.Stdout, "Horizon node unregistered") {
+		t.Fatalf("unexpected exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	} else if len(*removed) != 0 {
+		t.Errorf("the images should not be removed without the flag, found %v", *removed)
+	}
+
+	h.Agent.HandleSequence(http.MethodGet, "node", node("myorg", "edge1", "configured"), node("", "", "unconfigured"))
+	if res := h.Run(func() { DoIt(true, false, false, true, 0) }); res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	} else if strings.Join(*removed, ",") != "myorg/netspeed:1.0,myorg/cpu:2.0" {
+		t.Errorf("the images of the service containers should be removed, found %v", *removed)
+	}
+
+	// the images are kept when the node is not unregistered
+	removed = fakeServiceImages(t, "myorg/netspeed:1.0")
+	h.Agent.Handle(http.MethodDelete, "node", http.StatusInternalServerError, "unable to unregister")
+	h.Agent.HandleSequence(http.MethodGet, "node", node("myorg", "edge1", "configured"))
+	if res := h.Run(func() { DoIt(true, false, false, true, 0) }); res.ExitCode != 0 || !strings.Contains(res.Stdout, "not successfully unregistered") {
+		t.Fatalf("unexpected exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	} else if len(*removed) != 0 {
+		t.Errorf("the images should not be removed when the unregistration failed, found %v", *removed)
+	}
+
+	// a node that is not registered only has its images removed
+	h.Agent.HandleSequence(http.MethodGet, "node", node("", "", "unconfigured"))
+	if res := h.Run(func() { DoIt(true, false, false, true, 0) }); res.ExitCode != 0 || !strings.Contains(res.Stdout, "The node is not registered") {
+		t.Fatalf("unexpected exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	} else if strings.Join(*removed, ",") != "myorg/netspeed:1.0" {
+		t.Errorf("the images of the service containers should be removed, found %v", *removed)
+	}
+}
+
+func Test_deleteExchangeNode(t *testing.T) {
+	h := clitest.New(t)
+	h.Exchange.AddResource("orgs/myorg/nodes/edge1", map[string]string{"name": "edge1"})
+
+	if res := h.Run(func() { deleteExchangeNode(clitest.FAKE_ORG, "edge1") }); res.ExitCode != 0 || strings.Contains(res.Stdout, "WARNING") {
+		t.Fatalf("unexpected exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	} else if h.Exchange.GetResource("orgs/myorg/nodes/edge1", &map[string]string{}) {
+		t.Errorf("the node should be removed from the exchange")
+	}
+
+	// a node that is not in the exchange is not an error
+	if res := h.Run(func() { deleteExchangeNode(clitest.FAKE_ORG, "edge1") }); res.ExitCode != 0 || strings.Contains(res.Stdout, "WARNING") {
+		t.Errorf("unexpected exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+
+	// a failure is only a warning
+	h.Exchange.Handle(http.MethodDelete, "orgs/myorg/nodes/edge2", http.StatusForbidden, "access denied")
+	if res := h.Run(func() { deleteExchangeNode(clitest.FAKE_ORG, "edge2") }); res.ExitCode != 0 || !strings.Contains(res.Stdout, "WARNING: failed to remove node myorg/edge2") {
+		t.Errorf("expected a warning, exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+
+	// the node credentials are used without user credentials
+	h.Setenv("HZN_EXCHANGE_USER_AUTH", "")
+	h.Setenv("HZN_EXCHANGE_NODE_AUTH", "edge3:tok")
+	h.Exchange.AddResource("orgs/myorg/nodes/edge3", map[string]string{"name": "edge3"})
+	if res := h.Run(func() { deleteExchangeNode(clitest.FAKE_ORG, "edge3") }); res.ExitCode != 0 || strings.Contains(res.Stdout, "WARNING") {
+		t.Errorf("unexpected exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	} else if reqs := h.Exchange.RequestsTo(http.MethodDelete, "orgs/myorg/nodes/edge3"); len(reqs) != 1 {
+		t.Errorf("expected the node to be removed with its credentials, found %v", reqs)
+	} else if user, _, _ := (&http.Request{Header: reqs[0].Header}).BasicAuth(); user != "myorg/edge3" {
+		t.Errorf("expected the node credentials, found %v", user)
+	}
+
+	h.Setenv("HZN_EXCHANGE_NODE_AUTH", "")
+	if res := h.Run(func() { deleteExchangeNode(clitest.FAKE_ORG, "edge4") }); res.ExitCode != 0 || !strings.Contains(res.Stdout, "no exchange credentials are set") {
+		t.Errorf("expected a warning without credentials, exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+}
@@ -15,11 +15,7 @@ func List() {
 	var inputs []policy.UserInput
 	cliutils.HorizonGet("node/userinput", []int{200}, &inputs, false)
 
-	output, err := cliutils.DisplayAsJson(inputs)
-	if err != nil {
-		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, i18n.GetMessagePrinter().Sprintf("Unable to marshal userinput object: %v", err))
-	}
-	fmt.Println(output)
+	cliutils.Output(inputs, "hzn userinput list")
 }
 
 func New() {
@@ -79,7 +75,7 @@ func Update(filePath string) {
 		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("Error unmarshaling userInput json file: %v", err))
 	}
 
-	cliutils.HorizonPutPost(http.MethodPatch, "node/userinput", []int{200, 201}, inputs, true)
+	cliutils.HorizonPatch("node/userinput", []int{200, 201}, inputs, true)
 	msgPrinter.Printf("Horizon node user inputs updated.")
 	msgPrinter.Println()
 }
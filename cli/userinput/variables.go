@@ -0,0 +1,291 @@
+package userinput
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/open-horizon/anax/api"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Get displays the user input of a service, <org>/<url>, or the value of one of its variables. The org defaults to
+// the org of the node. The arch and version range only select the user input for them, when they are not empty.
+func Get(service string, variable string, arch string, versionRange string) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	org, url := serviceOrgUrl(service)
+	var inputs []policy.UserInput
+	cliutils.HorizonGet("node/userinput", []int{200}, &inputs, false)
+	selected := selectUserInputs(inputs, org, url, arch, versionRange)
+	if len(selected) == 0 {
+		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("no user input for service %v/%v on this node.", org, url))
+	}
+
+	if variable == "" {
+		output := []policy.UserInput{}
+		for _, i := range selected {
+			output = append(output, inputs[i])
+		}
+		cliutils.Output(output, "hzn userinput get")
+		return
+	}
+	for _, i := range selected {
+		if input := inputs[i].FindInput(variable); input != nil {
+			cliutils.Output(input.Value, "hzn userinput get")
+			return
+		}
+	}
+	cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("variable %v of service %v/%v is not set on this node.", variable, org, url))
+}
+
+// Set sets variables, name=value, of the user input of a service on the node. The values are converted to the types
+// of the variables in the definition of the service that the agent has, and a variable that the service does not
+// define or a value of the wrong type is rejected. The changes are displayed as a diff of the user input, then the
+// agent re-evaluates the agreements of the service with the new user input.
+func Set(service string, arch string, versionRange string, assignments []string) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	org, url := serviceOrgUrl(service)
+	vars := serviceVariables(org, url, arch)
+	if vars == nil {
+		cliutils.Warning(msgPrinter.Sprintf("the definition of service %v/%v is not on this node, the types of the variables are checked by the Horizon Agent.", org, url))
+	}
+
+	newInputs := []policy.Input{}
+	for _, a := range assignments {
+		parts := strings.SplitN(a, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the variable %v must be name=value", a))
+		}
+		varType, defined := vars[parts[0]]
+		if vars != nil && !defined {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("service %v/%v does not define the variable %v, its variables are: %v", org, url, parts[0], strings.Join(variableNames(vars), ", ")))
+		}
+		value, err := variableValue(parts[1], varType, defined)
+		if err != nil {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the value of variable %v of service %v/%v is not valid: %v", parts[0], org, url, err))
+		}
+		newInputs = append(newInputs, policy.Input{Name: parts[0], Value: value})
+	}
+
+	var inputs []policy.UserInput
+	cliutils.HorizonGet("node/userinput", []int{200}, &inputs, false)
+	changed := make([]policy.UserInput, 0, len(inputs)+1)
+	for _, ui := range inputs {
+		changed = append(changed, ui.Copy())
+	}
+	selected := selectUserInputs(changed, org, url, arch, versionRange)
+	if len(selected) > 1 {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the node has more than one user input for service %v/%v, specify the one to change with --arch or --version-range.", org, url))
+	} else if len(selected) == 0 {
+		changed = append(changed, policy.UserInput{ServiceOrgid: org, ServiceUrl: url, ServiceArch: arch, ServiceVersionRange: versionRange, Inputs: []policy.Input{}})
+		selected = []int{len(changed) - 1}
+	}
+	ui := &changed[selected[0]]
+	for _, input := range newInputs {
+		set := false
+		for i := range ui.Inputs {
+			if ui.Inputs[i].Name == input.Name {
+				ui.Inputs[i].Value, set = input.Value, true
+			}
+		}
+		if !set {
+			ui.Inputs = append(ui.Inputs, input)
+		}
+	}
+
+	saveUserInputs(inputs, changed, org, url)
+}
+
+// RemoveVariables removes variables of the user input of a service from the node, or the whole user input of the
+// service when no variable is given. The changes are displayed as a diff of the user input.
+func RemoveVariables(service string, arch string, versionRange string, variables []string, force bool) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	org, url := serviceOrgUrl(service)
+	var inputs []policy.UserInput
+	cliutils.HorizonGet("node/userinput", []int{200}, &inputs, false)
+	selected := selectUserInputs(inputs, org, url, arch, versionRange)
+	if len(selected) == 0 {
+		cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("no user input for service %v/%v on this node.", org, url))
+	}
+
+	found := map[string]bool{}
+	changed := make([]policy.UserInput, 0, len(inputs))
+	for i, ui := range inputs {
+		isSelected := false
+		for _, s := range selected {
+			isSelected = isSelected || s == i
+		}
+		if !isSelected {
+			changed = append(changed, ui.Copy())
+			continue
+		} else if len(variables) == 0 {
+			continue
+		}
+		kept := ui.Copy()
+		kept.Inputs = []policy.Input{}
+		for _, input := range ui.Inputs {
+			if cutil.SliceContains(variables, input.Name) {
+				found[input.Name] = true
+			} else {
+				kept.Inputs = append(kept.Inputs, input)
+			}
+		}
+		if len(kept.Inputs) > 0 {
+			changed = append(changed, kept)
+		}
+	}
+	for _, v := range variables {
+		if !found[v] {
+			cliutils.Fatal(cliutils.NOT_FOUND, msgPrinter.Sprintf("variable %v of service %v/%v is not set on this node.", v, org, url))
+		}
+	}
+
+	if !force {
+		if len(variables) == 0 {
+			cliutils.ConfirmRemove(msgPrinter.Sprintf("Are you sure you want to remove the user input of service %v/%v?", org, url))
+		} else {
+			cliutils.ConfirmRemove(msgPrinter.Sprintf("Are you sure you want to remove the variables %v of service %v/%v?", strings.Join(variables, ", "), org, url))
+		}
+	}
+	saveUserInputs(inputs, changed, org, url)
+}
+
+// Display the diff of the user input of the node and replace it with the changed user input. The agent re-evaluates
+// the agreements of the services whose user input changed.
+func saveUserInputs(inputs []policy.UserInput, changed []policy.UserInput, org string, url string) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	diff, err := cliutils.DiffJson(inputs, changed)
+	if err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to compare the user input: %v", err))
+	} else if diff == nil {
+		msgPrinter.Printf("The user input of service %v/%v is unchanged.", org, url)
+		msgPrinter.Println()
+		return
+	}
+	msgPrinter.Printf("Changes to the node user input:")
+	msgPrinter.Println()
+	fmt.Println(strings.Join(diff, "\n"))
+
+	cliutils.HorizonPutPost(http.MethodPost, "node/userinput", []int{200, 201}, changed, true)
+	msgPrinter.Printf("Horizon node user inputs updated, the agreements of service %v/%v are re-evaluated with them.", org, url)
+	msgPrinter.Println()
+}
+
+// The org and url of a service, <org>/<url>. The org defaults to the org of the node.
+func serviceOrgUrl(service string) (string, string) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if strings.Contains(service, "/") {
+		return cliutils.TrimOrg("", service)
+	}
+	horDevice := api.HorizonDevice{}
+	cliutils.HorizonGet("node", []int{200}, &horDevice, false)
+	if horDevice.Org == nil || *horDevice.Org == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("this node is not registered, specify the service as <org>/<url>."))
+	}
+	return *horDevice.Org, service
+}
+
+// The indexes of the user inputs of a service. The arch and version range only select the user input for them when
+// they are not empty.
+func selectUserInputs(inputs []policy.UserInput, org string, url string, arch string, versionRange string) []int {
+	selected := []int{}
+	for i, ui := range inputs {
+		if ui.ServiceOrgid == org && ui.ServiceUrl == url && (arch == "" || ui.ServiceArch == arch) && (versionRange == "" || ui.ServiceVersionRange == versionRange) {
+			selected = append(selected, i)
+		}
+	}
+	return selected
+}
+
+// The types of the variables of a service, from the definitions of the service that the agent has. Nil is returned
+// when the agent has no definition of the service.
+func serviceVariables(org string, url string, arch string) map[string]string {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	services := api.AllServices{}
+	cliutils.HorizonGet("service", []int{200}, &services, false)
+	var vars map[string]string
+	for _, v := range services.Definitions["active"] {
+		def := persistence.MicroserviceDefinition{}
+		defBytes, _ := json.Marshal(v)
+		if err := json.Unmarshal(defBytes, &def); err != nil {
+			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("Service definition unmarshalling error: %v", err))
+		}
+		if def.Org != org || def.SpecRef != url || (arch != "" && def.Arch != arch) {
+			continue
+		}
+		if vars == nil {
+			vars = map[string]string{}
+		}
+		for _, ui := range def.UserInputs {
+			vars[ui.Name] = ui.Type
+		}
+	}
+	return vars
+}
+
+func variableNames(vars map[string]string) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Convert the value of a variable from the command line to its type. A list of strings is a json array or a comma
+// separated list. When the type is not known, a value that is json is set as json, otherwise as a string.
+func variableValue(raw string, varType string, known bool) (interface{}, error) {
+	var value interface{}
+	// unlike a decoder, unmarshal rejects a value that is followed by anything else
+	decode := func() error {
+		return json.Unmarshal([]byte(raw), &value)
+	}
+
+	if !known {
+		if err := decode(); err != nil {
+			return raw, nil
+		}
+		return value, nil
+	}
+	switch varType {
+	case "", "string":
+		value = raw
+	case "bool", "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, errors.New(i18n.GetMessagePrinter().Sprintf("%v is not a boolean", raw))
+		}
+		value = b
+	case "list of strings":
+		if strings.HasPrefix(strings.TrimSpace(raw), "[") {
+			if err := decode(); err != nil {
+				return nil, err
+			}
+		} else {
+			list := []interface{}{}
+			for _, s := range strings.Split(raw, ",") {
+				list = append(list, strings.TrimSpace(s))
+			}
+			value = list
+		}
+	default:
+		value = json.Number(raw)
+	}
+	if err := cutil.VerifyWorkloadVarTypes(value, varType); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,70 @@
+// +build unit
+
+package userinput
+
+import (
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_Variables(t *testing.T) {
+	h := clitest.New(t)
+	h.Agent.SetNode(clitest.FAKE_ORG, "mynode", "", "configured")
+	h.Agent.Handle(http.MethodGet, "node/userinput", http.StatusOK, []interface{}{
+		map[string]interface{}{"serviceOrgid": clitest.FAKE_ORG, "serviceUrl": "netspeed", "serviceVersionRange": "[0.0.0,INFINITY)", "inputs": []interface{}{
+			map[string]interface{}{"name": "var1", "value": "aString"},
+			map[string]interface{}{"name": "var2", "value": 5},
+		}},
+		map[string]interface{}{"serviceOrgid": "IBM", "serviceUrl": "gps", "inputs": []interface{}{map[string]interface{}{"name": "HZN_LAT", "value": 1.5}}},
+	})
+	h.Agent.Handle(http.MethodGet, "service", http.StatusOK, map[string]interface{}{
+		"instances": map[string]interface{}{"active": []interface{}{}, "archived": []interface{}{}},
+		"definitions": map[string]interface{}{"active": []interface{}{map[string]interface{}{
+			"specRef": "netspeed", "organization": clitest.FAKE_ORG, "version": "1.0.0", "arch": "amd64", "userInput": []interface{}{
+				map[string]interface{}{"name": "var1", "type": "string"},
+				map[string]interface{}{"name": "var2", "type": "int"},
+				map[string]interface{}{"name": "var3", "type": "list of strings"},
+			}}}, "archived": []interface{}{}},
+	})
+	h.Agent.Handle(http.MethodPost, "node/userinput", http.StatusCreated, "")
+
+	if res := h.Run(func() { Get("netspeed", "var2", "", "") }); res.ExitCode != 0 || strings.TrimSpace(res.Stdout) != "5" {
+		t.Errorf("unexpected value of var2 %v, exit code %v, stderr: %v", res.Stdout, res.ExitCode, res.Stderr)
+	}
+	if res := h.Run(func() { Get("IBM/gps", "HZN_LON", "", "") }); res.ExitCode != cliutils.NOT_FOUND {
+		t.Errorf("expected the variable not to be found, exit code %v", res.ExitCode)
+	}
+
+	res := h.Run(func() { Set("netspeed", "", "", []string{"var2=7", "var3=a, b"}) })
+	if res.ExitCode != 0 || !strings.Contains(res.Stdout, "-         \"value\": 5") || !strings.Contains(res.Stdout, "+         \"value\": 7") {
+		t.Fatalf("unexpected exit code %v, output %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+	reqs := h.Agent.RequestsTo(http.MethodPost, "node/userinput")
+	if len(reqs) != 1 || !strings.Contains(string(reqs[0].Body), `{"name":"var2","value":7},{"name":"var3","value":["a","b"]}`) || !strings.Contains(string(reqs[0].Body), `"serviceUrl":"gps"`) {
+		t.Errorf("unexpected user input posted %v", reqs)
+	}
+
+	// the variables that the service does not define and the values of the wrong type are rejected
+	for _, assignment := range []string{"var4=1", "var2=abc", "var2=1.5", `var3=["a"] x`} {
+		if res := h.Run(func() { Set("netspeed", "", "", []string{assignment}) }); res.ExitCode != cliutils.CLI_INPUT_ERROR {
+			t.Errorf("expected %v to be rejected, exit code %v, stderr: %v", assignment, res.ExitCode, res.Stderr)
+		}
+	}
+	if res := h.Run(func() { Set("netspeed", "", "", []string{"var2=5"}) }); res.ExitCode != 0 || !strings.Contains(res.Stdout, "unchanged") {
+		t.Errorf("expected the user input to be unchanged, output %v, stderr: %v", res.Stdout, res.Stderr)
+	}
+
+	if res := h.Run(func() { RemoveVariables("IBM/gps", "", "", nil, true) }); res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	reqs = h.Agent.RequestsTo(http.MethodPost, "node/userinput")
+	if len(reqs) != 2 || strings.Contains(string(reqs[1].Body), "gps") || !strings.Contains(string(reqs[1].Body), `"name":"var2","value":5`) {
+		t.Errorf("expected only the user input of gps to be removed, found %v", string(reqs[1].Body))
+	}
+	if res := h.Run(func() { RemoveVariables("netspeed", "", "", []string{"var9"}, true) }); res.ExitCode != cliutils.NOT_FOUND {
+		t.Errorf("expected the variable not to be found, exit code %v", res.ExitCode)
+	}
+}
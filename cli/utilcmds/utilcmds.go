@@ -4,39 +4,68 @@ import (
 	"fmt"
 	"github.com/open-horizon/anax/cli/cliconfig"
 	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/cli/schema"
 	"github.com/open-horizon/anax/i18n"
 	"github.com/open-horizon/rsapss-tool/sign"
 	"github.com/open-horizon/rsapss-tool/verify"
 	"os"
+	"strings"
 )
 
-func Sign(privKeyFilePath string) {
-	stdinBytes := cliutils.ReadStdin()
-	signature, err := sign.Input(privKeyFilePath, stdinBytes)
+// Sign signs the content of the file, or stdin when the file is "-", with the private key and sends the signature to
+// stdout. The signature has the format of the deployment signatures that the agent verifies. The private key defaults
+// to the signing key that 'hzn key create' makes.
+func Sign(privKeyFilePath string, filePath string) {
+	privKeyFilePath = cliutils.VerifySigningKeyInput(privKeyFilePath, false)
+	contentBytes := cliutils.ReadFile(filePath)
+	signature, err := sign.Input(privKeyFilePath, contentBytes)
 	if err != nil {
-		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, i18n.GetMessagePrinter().Sprintf("problem signing stdin with %s: %v", privKeyFilePath, err))
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, i18n.GetMessagePrinter().Sprintf("problem signing %s with %s: %v", contentName(filePath), privKeyFilePath, err))
 	}
 	fmt.Println(signature)
 }
 
-func Verify(pubKeyFilePath, signature string) {
+// Verify verifies that the signature, or the signature in the signature file, is a valid signature of the content of
+// the file, or stdin when the file is "-", for the public key. It exits with SIGNATURE_INVALID when it is not. The
+// public key defaults to the signing key that 'hzn key create' makes.
+func Verify(pubKeyFilePath string, signature string, signatureFile string, filePath string) {
 	// get message printer
 	msgPrinter := i18n.GetMessagePrinter()
 
-	stdinBytes := cliutils.ReadStdin()
-	verified, err := verify.Input(pubKeyFilePath, signature, stdinBytes)
+	if signature != "" && signatureFile != "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("-s and --signature-file are mutually exclusive."))
+	} else if signatureFile != "" {
+		if signatureFile == "-" && filePath == "-" {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the signature and the content to verify cannot both be read from stdin, specify the content with -f."))
+		}
+		signature = strings.TrimSpace(string(cliutils.ReadFile(signatureFile)))
+	} else if signature == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("either -s or --signature-file must be specified."))
+	}
+	pubKeyFilePath = cliutils.VerifySigningKeyInput(pubKeyFilePath, true)
+
+	contentBytes := cliutils.ReadFile(filePath)
+	verified, err := verify.Input(pubKeyFilePath, signature, contentBytes)
 	if err != nil {
-		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("problem verifying deployment string with %s: %v", pubKeyFilePath, err))
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, msgPrinter.Sprintf("problem verifying the signature of %s with %s: %v", contentName(filePath), pubKeyFilePath, err))
 	} else if !verified {
-		msgPrinter.Printf("This is not a valid signature for stdin.")
+		msgPrinter.Printf("This is not a valid signature for %s.", contentName(filePath))
 		msgPrinter.Println()
-		os.Exit(cliutils.SIGNATURE_INVALID)
+		cliutils.Exit(cliutils.SIGNATURE_INVALID)
 	} else {
 		msgPrinter.Printf("Signature is valid.")
 		msgPrinter.Println()
 	}
 }
 
+// The name of the content that is signed or verified, for the messages.
+func contentName(filePath string) string {
+	if filePath == "-" {
+		return "stdin"
+	}
+	return filePath
+}
+
 // convert the given json file to shell export commands and output it to stdout
 func ConvertConfig(cofigFile string) {
 	// get the env vars from the file
@@ -53,3 +82,32 @@ func ConvertConfig(cofigFile string) {
 		fmt.Printf("export %v=%v\n", k, v)
 	}
 }
+
+// validate a service, pattern, deployment policy or node management policy file against its schema, without
+// sending it to the exchange
+func Validate(filePath string, kind string) {
+	// get message printer
+	msgPrinter := i18n.GetMessagePrinter()
+
+	fileBytes := cliconfig.ReadJsonFileWithLocalConfig(filePath)
+	if kind == "" {
+		if kind = schema.GuessKind(fileBytes); kind == "" {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("Unable to tell the kind of resource in %v, specify it with --type.", filePath))
+		}
+		cliutils.Verbose(msgPrinter.Sprintf("Validating %v as a %v file", filePath, kind))
+	}
+
+	errs, err := schema.ValidateFile(kind, filePath, fileBytes)
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "%v", err)
+	}
+	errs, warnings := schema.SplitWarnings(errs)
+	if len(warnings) != 0 {
+		cliutils.Warning("%v", schema.FormatWarnings(filePath, warnings))
+	}
+	if len(errs) != 0 {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "%v", schema.FormatErrors(filePath, errs))
+	}
+	msgPrinter.Printf("%v is a valid %v file.", filePath, kind)
+	msgPrinter.Println()
+}
@@ -0,0 +1,67 @@
+// +build unit
+
+package utilcmds
+
+import (
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/rsapss-tool/generatekeys"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_SignVerify(t *testing.T) {
+	h := clitest.New(t)
+	dir, err := ioutil.TempDir("", "clitest-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files, err := generatekeys.Write(dir, 2048, "me@myorg.com", clitest.FAKE_ORG, time.Now().AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("could not create the keys, error %v", err)
+	}
+	privKey, pubKey := "", ""
+	for _, f := range files {
+		if strings.HasSuffix(f, "-public.pem") {
+			pubKey = f
+		} else {
+			privKey = f
+		}
+	}
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("unable to write %v: %v", path, err)
+		}
+		return path
+	}
+	deployment := write("deployment.json", `{"services":{"netspeed":{"image":"myorg/netspeed:1.0.0"}}}`)
+
+	// the signature of the file is the signature of the same content in stdin
+	res := h.Run(func() { Sign(privKey, deployment) })
+	if res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	sigFile := write("deployment.sig", res.Stdout)
+	if res := h.Run(func() { Verify(pubKey, "", sigFile, deployment) }); res.ExitCode != 0 || !strings.Contains(res.Stdout, "Signature is valid") {
+		t.Errorf("the signature should be valid, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	content, _ := ioutil.ReadFile(deployment)
+	if res := h.RunWithInput(string(content), func() { Verify(pubKey, strings.TrimSpace(res.Stdout), "", "-") }); res.ExitCode != 0 {
+		t.Errorf("the signature of stdin should be valid, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+
+	other := write("other.json", `{"services":{"netspeed":{"image":"myorg/netspeed:2.0.0"}}}`)
+	if res := h.Run(func() { Verify(pubKey, "", sigFile, other) }); res.ExitCode != cliutils.SIGNATURE_INVALID {
+		t.Errorf("expected exit code %v, found %v", cliutils.SIGNATURE_INVALID, res.ExitCode)
+	}
+	if res := h.Run(func() { Verify(pubKey, "", "", deployment) }); res.ExitCode != cliutils.CLI_INPUT_ERROR {
+		t.Errorf("expected exit code %v, found %v", cliutils.CLI_INPUT_ERROR, res.ExitCode)
+	}
+}
@@ -0,0 +1,182 @@
+package watch
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/open-horizon/anax/api"
+	"github.com/open-horizon/anax/cli/agreement"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/persistence"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// The escape sequence that moves the cursor home and clears the terminal, to redraw the view in place.
+const CLEAR_SCREEN = "\033[H\033[2J"
+
+// Returns true if stdout is a terminal, where the view is redrawn in place.
+var stdoutIsTerminal = func() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Waits for the next poll, replaced by the tests.
+var after = time.After
+
+// Watch polls the node, agreement and service APIs of the agent every interval seconds and displays the state of the
+// node, its agreements and its service containers, until the command is interrupted or count views have been
+// displayed, 0 is no limit. On a terminal the view is redrawn in place. Otherwise a view is only written when it
+// changes, so that the output is a log of the changes. That view leaves out the columns that change at every poll,
+// the age of the agreements and the docker status of the containers, which is replaced by their state. The agent has
+// no event stream to subscribe to, so it is polled.
+func Watch(interval int, count int) {
+	msgPrinter := i18n.GetMessagePrinter()
+	if interval < 1 {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, msgPrinter.Sprintf("the interval must be at least 1 second, not %v", interval))
+	}
+
+	terminal := stdoutIsTerminal()
+	lastView := ""
+	for views := 0; count == 0 || views < count; {
+		view := new(bytes.Buffer)
+		writeView(view, !terminal)
+
+		header := msgPrinter.Sprintf("Every %vs: hzn watch, %v", interval, time.Now().Format("2006-01-02 15:04:05"))
+		if terminal {
+			fmt.Print(CLEAR_SCREEN + header + "\n\n" + view.String())
+			views++
+		} else if view.String() != lastView {
+			if views > 0 {
+				fmt.Println()
+			}
+			fmt.Print(header + "\n\n" + view.String())
+			views++
+		}
+		lastView = view.String()
+
+		if count != 0 && views >= count {
+			break
+		}
+		select {
+		case <-cliutils.Context().Done():
+			return
+		case <-after(time.Duration(interval) * time.Second):
+		}
+	}
+}
+
+// Write the state of the node, its agreements and its services, without the columns that change at every poll when
+// stable is true. The agent can be down while it is watched, so the errors are part of the view instead of ending
+// the command.
+func writeView(w io.Writer, stable bool) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	horDevice := api.HorizonDevice{}
+	if _, err := cliutils.HorizonGetE("node", []int{200}, &horDevice); err != nil {
+		fmt.Fprintln(w, msgPrinter.Sprintf("Unable to get the node from the Horizon Agent: %v", strings.TrimSpace(err.Error())))
+		return
+	}
+	state := ""
+	if horDevice.Config != nil && horDevice.Config.State != nil {
+		state = *horDevice.Config.State
+	}
+	fmt.Fprintln(w, msgPrinter.Sprintf("Node: %v, organization: %v, pattern: %v, state: %v", value(horDevice.Id), value(horDevice.Org), value(horDevice.Pattern), state))
+
+	agreements := map[string]map[string][]persistence.EstablishedAgreement{}
+	if _, err := cliutils.HorizonGetE("agreement", []int{200}, &agreements); err != nil {
+		fmt.Fprintln(w, "\n"+msgPrinter.Sprintf("Unable to get the agreements from the Horizon Agent: %v", strings.TrimSpace(err.Error())))
+	} else {
+		active := agreements["agreements"]["active"]
+		sort.Slice(active, func(i, j int) bool { return active[i].AgreementCreationTime < active[j].AgreementCreationTime })
+		fmt.Fprintln(w, "\n"+msgPrinter.Sprintf("Agreements: %v", len(active)))
+		if len(active) > 0 {
+			tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+			if stable {
+				fmt.Fprintln(tw, msgPrinter.Sprintf("  AGREEMENT\tSERVICE\tVERSION\tSTATE"))
+			} else {
+				fmt.Fprintln(tw, msgPrinter.Sprintf("  AGREEMENT\tSERVICE\tVERSION\tSTATE\tAGE"))
+			}
+			for _, ag := range active {
+				fmt.Fprintf(tw, "  %v\t%v\t%v\t%v", ag.CurrentAgreementId, ag.RunningWorkload.Org+"/"+ag.RunningWorkload.URL, ag.RunningWorkload.Version, agreement.State(ag))
+				if !stable {
+					fmt.Fprintf(tw, "\t%v", age(ag.AgreementCreationTime))
+				}
+				fmt.Fprintln(tw)
+			}
+			tw.Flush()
+		}
+	}
+
+	services := api.AllServices{}
+	if _, err := cliutils.HorizonGetE("service", []int{200}, &services); err != nil {
+		fmt.Fprintln(w, "\n"+msgPrinter.Sprintf("Unable to get the services from the Horizon Agent: %v", strings.TrimSpace(err.Error())))
+		return
+	}
+	instances := services.Instances["active"]
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].Org+"/"+instances[i].SpecRef < instances[j].Org+"/"+instances[j].SpecRef
+	})
+	fmt.Fprintln(w, "\n"+msgPrinter.Sprintf("Services: %v", len(instances)))
+	if len(instances) > 0 {
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, msgPrinter.Sprintf("  SERVICE\tVERSION\tSTATE\tCONTAINERS"))
+		for _, inst := range instances {
+			fmt.Fprintf(tw, "  %v\t%v\t%v\t%v\n", inst.Org+"/"+inst.SpecRef, inst.Version, serviceState(inst), containers(inst, stable))
+		}
+		tw.Flush()
+	}
+}
+
+// The state of a service instance, from its execution times.
+func serviceState(inst *api.MicroserviceInstanceOutput) string {
+	msgPrinter := i18n.GetMessagePrinter()
+	if inst.CleanupStartTime != 0 {
+		return msgPrinter.Sprintf("stopping")
+	} else if inst.ExecutionFailureCode != 0 {
+		return msgPrinter.Sprintf("failed: %v", inst.ExecutionFailureDesc)
+	} else if inst.ExecutionStartTime != 0 {
+		return msgPrinter.Sprintf("running")
+	}
+	return msgPrinter.Sprintf("starting")
+}
+
+// The names and docker status of the containers of a service instance, or their state, e.g. running, when stable is
+// true.
+func containers(inst *api.MicroserviceInstanceOutput, stable bool) string {
+	if inst.Containers == nil {
+		return ""
+	}
+	statuses := []string{}
+	for _, c := range *inst.Containers {
+		name := c.ID
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		status := c.Status
+		if stable {
+			status = c.State
+		}
+		statuses = append(statuses, fmt.Sprintf("%v (%v)", name, status))
+	}
+	return strings.Join(statuses, ", ")
+}
+
+// How long ago a time in seconds since the epoch was, rounded to the second.
+func age(since uint64) string {
+	if since == 0 {
+		return ""
+	}
+	return time.Since(time.Unix(int64(since), 0)).Round(time.Second).String()
+}
+
+func value(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,92 @@
+// +build unit
+
+package watch
+
+import (
+	"github.com/open-horizon/anax/cli/clitest"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Make the agent of the harness run a node with an agreement and a service.
+func setAgent(h *clitest.Harness, state string) {
+	h.Agent.SetNode(clitest.FAKE_ORG, "mynode", "netspeed", state)
+	now := time.Now().Unix()
+	h.Agent.Handle(http.MethodGet, "agreement", http.StatusOK, map[string]interface{}{"agreements": map[string]interface{}{
+		"active": []interface{}{map[string]interface{}{"current_agreement_id": "ag1", "agreement_creation_time": now - 60, "agreement_accepted_time": now - 50,
+			"workload_to_run": map[string]interface{}{"url": "netspeed", "org": "IBM", "version": "1.0.0"}}},
+		"archived": []interface{}{},
+	}})
+	h.Agent.Handle(http.MethodGet, "service", http.StatusOK, map[string]interface{}{"instances": map[string]interface{}{
+		"active": []interface{}{map[string]interface{}{"ref_url": "netspeed", "organization": "IBM", "version": "1.0.0", "execution_start_time": now - 30,
+			"containers": []interface{}{map[string]interface{}{"Id": "123", "Names": []string{"/netspeed"}, "Status": "Up 30 seconds", "State": "running"}}}},
+	}})
+}
+
+func Test_Watch(t *testing.T) {
+	h := clitest.New(t)
+	setAgent(h, "configured")
+
+	res := h.Run(func() { Watch(1, 1) })
+	if res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	for _, expected := range []string{"Node: mynode, organization: myorg, pattern: netspeed, state: configured", "Agreements: 1", "ag1", "IBM/netspeed", "accepted", "Services: 1", "running", "netspeed (running)"} {
+		if !strings.Contains(res.Stdout, expected) {
+			t.Errorf("expected %q in the view, found %v", expected, res.Stdout)
+		}
+	}
+	if strings.Contains(res.Stdout, "AGE") || strings.Contains(res.Stdout, "Up 30 seconds") {
+		t.Errorf("the columns that change at every poll should not be in the view that is not on a terminal, found %v", res.Stdout)
+	}
+
+	// the terminal view has the age of the agreements and the docker status of the containers
+	isTerminal := stdoutIsTerminal
+	stdoutIsTerminal = func() bool { return true }
+	res = h.Run(func() { Watch(1, 1) })
+	stdoutIsTerminal = isTerminal
+	if !strings.HasPrefix(res.Stdout, CLEAR_SCREEN) || !strings.Contains(res.Stdout, "AGE") || !strings.Contains(res.Stdout, "netspeed (Up 30 seconds)") {
+		t.Errorf("expected the full view on a terminal, found %v", res.Stdout)
+	}
+
+	// the agent can go down while it is watched
+	h.Agent.Handle(http.MethodGet, "node", http.StatusInternalServerError, "down")
+	h.Setenv("HZN_HTTP_RETRIES", "0")
+	if res := h.Run(func() { Watch(1, 1) }); res.ExitCode != 0 || !strings.Contains(res.Stdout, "Unable to get the node") {
+		t.Errorf("expected the error in the view, exit code %v, found %v", res.ExitCode, res.Stdout)
+	}
+}
+
+func Test_Watch_unchanged(t *testing.T) {
+	h := clitest.New(t)
+	setAgent(h, "configured")
+
+	// the node is unconfigured at the 3rd poll, the views of the polls before it are the same
+	polls := 0
+	after = func(d time.Duration) <-chan time.Time {
+		polls++
+		if polls == 3 {
+			h.Agent.SetNode(clitest.FAKE_ORG, "mynode", "netspeed", "unconfiguring")
+		}
+		c := make(chan time.Time, 1)
+		c <- time.Now()
+		return c
+	}
+	defer func() { after = time.After }()
+
+	res := h.Run(func() { Watch(1, 2) })
+	if res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	if polls != 3 {
+		t.Errorf("expected 4 polls, found %v", polls+1)
+	}
+	if n := strings.Count(res.Stdout, "hzn watch"); n != 2 {
+		t.Errorf("only the views that changed should be written, found %v in %v", n, res.Stdout)
+	}
+	if !strings.Contains(res.Stdout, "state: configured") || !strings.Contains(res.Stdout, "state: unconfiguring") {
+		t.Errorf("expected the view before and after the change, found %v", res.Stdout)
+	}
+}
@@ -0,0 +1,67 @@
+package wiotp
+
+import (
+	"encoding/json"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/i18n"
+)
+
+// The organization of Watson IoT Platform, as its api returns it.
+type WiotpOrg struct {
+	Id     string          `json:"id"`
+	Name   string          `json:"name"`
+	Type   string          `json:"type,omitempty"`
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+// OrgConfig is the configuration of hzn and of the agents for the organization of Watson IoT Platform of the exchange.
+type OrgConfig struct {
+	OrgId            string          `json:"orgId"`
+	OrgName          string          `json:"orgName"`
+	ApiUrl           string          `json:"apiUrl"`
+	ExchangeUrl      string          `json:"exchangeUrl"`      // the exchange url of hzn
+	AgentExchangeUrl string          `json:"agentExchangeUrl"` // the HZN_EXCHANGE_URL of the agents
+	AuthScheme       string          `json:"authScheme"`
+	Config           json.RawMessage `json:"config,omitempty"` // the configuration of the organization in WIoTP
+}
+
+// Org displays the details of the organization of Watson IoT Platform that the exchange is in, from the WIoTP api.
+// The credentials are an api key and its authentication token, with or without the wiotp: prefix.
+func Org(userPw string) {
+	var org map[string]interface{}
+	apiUrl, creds := wiotpApi(userPw)
+	getOrg(apiUrl, creds, &org)
+	cliutils.Output(org, "hzn wiotp org")
+}
+
+// Config displays the settings of hzn and of the agents for the organization of Watson IoT Platform that the exchange
+// is in: the id of the organization, the url of the exchange for hzn and for the agents, the scheme of the
+// credentials and the configuration of the organization in WIoTP.
+func Config(userPw string) {
+	var org WiotpOrg
+	apiUrl, creds := wiotpApi(userPw)
+	getOrg(apiUrl, creds, &org)
+
+	config := OrgConfig{OrgId: org.Id, OrgName: org.Name, ApiUrl: apiUrl, ExchangeUrl: apiUrl + cliutils.WIOTP_HZN_API,
+		AgentExchangeUrl: cliutils.WiotpAgentExchangeUrl(apiUrl), AuthScheme: cliutils.AUTH_SCHEME_WIOTP, Config: org.Config}
+	cliutils.Output(config, "hzn wiotp config")
+}
+
+// The url of the api of Watson IoT Platform of the exchange, and the wiotp credentials to use it with.
+func wiotpApi(userPw string) (string, string) {
+	creds := cliutils.WiotpCredentials(cliutils.RequiredUserAuth(userPw))
+	cliutils.SetWhetherUsingWiotp(creds)
+	return cliutils.WiotpApiUrl(cliutils.GetExchangeUrl()), creds
+}
+
+func getOrg(apiUrl string, creds string, org interface{}) {
+	msgPrinter := i18n.GetMessagePrinter()
+
+	var output []byte
+	if httpCode := cliutils.ExchangeGet("Watson IoT Platform", apiUrl, "", creds, []int{200, 401, 403}, &output); httpCode != 200 {
+		cliutils.Fatal(cliutils.INVALID_CREDS, msgPrinter.Sprintf("the Watson IoT Platform api at %v did not accept the credentials (HTTP code %d).", apiUrl, httpCode))
+	}
+	if err := json.Unmarshal(output, org); err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, msgPrinter.Sprintf("failed to unmarshal the organization from the Watson IoT Platform api %s: %v", output, err))
+	}
+}
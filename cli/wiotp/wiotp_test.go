@@ -0,0 +1,103 @@
+// +build unit
+
+package wiotp
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/cli/clitest"
+	"github.com/open-horizon/anax/cli/exchange"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_Wiotp(t *testing.T) {
+	h := clitest.New(t)
+	h.Setenv("HZN_EXCHANGE_URL", h.Exchange.URL+"/api/v0002/edgenode")
+	h.Setenv("HZN_EXCHANGE_USER_AUTH", "wiotp:a-abc123-key:authtok")
+
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("a-abc123-key:authtok"))
+	h.Exchange.HandleFunc(http.MethodGet, "api/v0002", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != basic {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"id":"abc123","name":"My Org","type":"Standard","config":{"edge":{"enabled":true}}}`)
+	})
+
+	res := h.Run(func() { Config("") })
+	if res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+	config := OrgConfig{}
+	res.JSON(t, &config)
+	apiUrl := h.Exchange.URL + "/api/v0002"
+	if config.OrgId != "abc123" || config.OrgName != "My Org" || config.ApiUrl != apiUrl || config.ExchangeUrl != apiUrl+"/edge" || config.AgentExchangeUrl != apiUrl+"/edgenode" || config.AuthScheme != "wiotp" || !strings.Contains(string(config.Config), `"enabled": true`) {
+		t.Errorf("unexpected config: %+v", config)
+	}
+
+	// the credentials can be given without the prefix
+	res = h.Run(func() { Org("a-abc123-key:authtok") })
+	org := map[string]interface{}{}
+	if res.ExitCode != 0 {
+		t.Fatalf("unexpected exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	} else if res.JSON(t, &org); org["type"] != "Standard" {
+		t.Errorf("unexpected org: %v", org)
+	}
+	if res := h.Run(func() { Org("a-abc123-key:wrongtok") }); res.ExitCode != cliutils.INVALID_CREDS {
+		t.Errorf("expected invalid credentials, exit code %v, stderr: %v", res.ExitCode, res.Stderr)
+	}
+
+	// the exchange commands use the edge api of hzn, without the org in the credentials
+	h.Exchange.HandleFunc(http.MethodGet, "api/v0002/edge/orgs/myorg/nodes/node1", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != basic {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"nodes":{"myorg/node1":{"name":"node1"}}}`)
+	})
+	if res := h.Run(func() { exchange.NodeList(clitest.FAKE_ORG, "wiotp:a-abc123-key:authtok", "node1", false, "", "") }); res.ExitCode != 0 || !strings.Contains(res.Stdout, `"myorg/node1"`) {
+		t.Errorf("unexpected exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	}
+}
+
+// The credentials that were used before the wiotp scheme still work, with a deprecation warning.
+func Test_Wiotp_usingApiKey(t *testing.T) {
+	h := clitest.New(t)
+	h.Setenv("HZN_EXCHANGE_URL", h.Exchange.URL+"/api/v0002/edgenode")
+
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("a-abc123-key:authtok"))
+	h.Exchange.HandleFunc(http.MethodGet, "api/v0002/edge/orgs/myorg/nodes/node1", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != basic {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"nodes":{"myorg/node1":{"name":"node1"}}}`)
+	})
+
+	// an api key of WIoTP is detected
+	res := h.Run(func() { exchange.NodeList(clitest.FAKE_ORG, "a-abc123-key:authtok", "node1", false, "", "") })
+	if res.ExitCode != 0 || !strings.Contains(res.Stdout, `"myorg/node1"`) {
+		t.Errorf("unexpected exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	} else if !strings.Contains(res.Stderr, "deprecated") {
+		t.Errorf("expected a deprecation warning, stderr: %v", res.Stderr)
+	}
+
+	// USING_API_KEY=0 turns the detection off
+	h.Setenv(cliutils.USING_API_KEY_ENV, "0")
+	if res := h.Run(func() { exchange.NodeList(clitest.FAKE_ORG, "a-abc123-key:authtok", "node1", false, "", "") }); res.ExitCode == 0 {
+		t.Errorf("the credentials should not be used as wiotp credentials, stdout: %v", res.Stdout)
+	}
+
+	// USING_API_KEY=1 uses any credentials as the ones of WIoTP
+	basic = "Basic " + base64.StdEncoding.EncodeToString([]byte("otherkey:authtok"))
+	h.Setenv(cliutils.USING_API_KEY_ENV, "1")
+	res = h.Run(func() { exchange.NodeList(clitest.FAKE_ORG, "otherkey:authtok", "node1", false, "", "") })
+	if res.ExitCode != 0 || !strings.Contains(res.Stdout, `"myorg/node1"`) {
+		t.Errorf("unexpected exit code %v, stdout: %v, stderr: %v", res.ExitCode, res.Stdout, res.Stderr)
+	} else if !strings.Contains(res.Stderr, "deprecated") {
+		t.Errorf("expected a deprecation warning, stderr: %v", res.Stderr)
+	}
+}
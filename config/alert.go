@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// The configuration of the agbot alerts that are sent when agreements keep failing. An alert is sent to the configured
+// webhooks when more than CancelThreshold agreements for the same workload are cancelled within WindowM minutes.
+type AlertConfig struct {
+	WebhookURL      string // The URL that alerts are posted to as a JSON document
+	SlackWebhookURL string // The URL of a Slack compatible incoming webhook that alerts are posted to as a message
+	CancelThreshold int    // The number of cancellations of agreements for one workload within the window that triggers an alert. Zero means alerts are turned off.
+	WindowM         int    // The length of the window in minutes, the default is 10
+	CooldownM       int    // The minimum number of minutes between alerts for the same workload, the default is 60
+}
+
+func (a AlertConfig) IsConfigured() bool {
+	return (a.WebhookURL != "" || a.SlackWebhookURL != "") && a.CancelThreshold > 0
+}
+
+func (a AlertConfig) GetWindowM() int {
+	if a.WindowM <= 0 {
+		return 10
+	}
+	return a.WindowM
+}
+
+func (a AlertConfig) GetCooldownM() int {
+	if a.CooldownM <= 0 {
+		return 60
+	}
+	return a.CooldownM
+}
+
+// The webhook urls carry their credentials in the path or the query, so only the scheme and the host are logged.
+func (a AlertConfig) String() string {
+	return fmt.Sprintf("WebhookURL: %v, SlackWebhookURL: %v, CancelThreshold: %v, WindowM: %v, CooldownM: %v",
+		MaskWebhookURL(a.WebhookURL), MaskWebhookURL(a.SlackWebhookURL), a.CancelThreshold, a.WindowM, a.CooldownM)
+}
+
+// MaskWebhookURL returns the scheme and the host of the webhook url, with the rest of it masked.
+func MaskWebhookURL(webhookURL string) string {
+	if webhookURL == "" {
+		return ""
+	}
+	u, err := url.Parse(webhookURL)
+	if err != nil || u.Host == "" {
+		return "******"
+	}
+	return u.Scheme + "://" + u.Host + "/******"
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"fmt"
+)
+
+// The configuration of the scheduled backups of the agbot's bolt database. The snapshots are encrypted with the key
+// in EncryptionKeyFile and uploaded to an S3 compatible object storage bucket.
+type BackupConfig struct {
+	IntervalS         int    // The number of seconds between backups. Zero means backups are turned off.
+	Endpoint          string // The URL of the S3 compatible object storage service, e.g. https://s3.us-east.cloud-object-storage.appdomain.cloud
+	Region            string // The region used to sign the object storage requests, the default is us-east-1
+	Bucket            string // The bucket that the backups are stored in
+	Prefix            string // A prefix for the names of the backup objects, so that several agbots can use the same bucket
+	AccessKeyId       string // The HMAC access key id for the object storage service
+	SecretAccessKey   string // The HMAC secret access key for the object storage service
+	EncryptionKeyFile string // The path to a file containing the 32 byte AES key, raw or hex encoded, used to encrypt the backups
+	RetentionCount    int    // The number of most recent backups that are always kept. Zero means keep all of them, unless RetentionDays is set.
+	RetentionDays     int    // Backups older than this number of days are deleted, except the RetentionCount most recent ones. Zero means no age limit.
+}
+
+func (b BackupConfig) IsConfigured() bool {
+	return b.IntervalS != 0 && b.Bucket != ""
+}
+
+func (b BackupConfig) GetRegion() string {
+	if b.Region == "" {
+		return "us-east-1"
+	}
+	return b.Region
+}
+
+func (b BackupConfig) String() string {
+	return fmt.Sprintf("IntervalS: %v, Endpoint: %v, Region: %v, Bucket: %v, Prefix: %v, AccessKeyId: %v, SecretAccessKey: %v, EncryptionKeyFile: %v, RetentionCount: %v, RetentionDays: %v",
+		b.IntervalS, b.Endpoint, b.Region, b.Bucket, b.Prefix, b.AccessKeyId, "******", b.EncryptionKeyFile, b.RetentionCount, b.RetentionDays)
+}
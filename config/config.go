@@ -16,6 +16,9 @@ const OldMgmtHubCertPath = "HZN_ICP_CA_CERT_PATH"
 const ManagementHubCertPath = "HZN_MGMT_HUB_CERT_PATH"
 const AnaxAPIPort = "HZN_AGENT_PORT"
 
+// An APIListen like unix:///var/run/horizon/horizon.sock makes the API listen on that unix domain socket.
+const APIUnixSocketPrefix = "unix://"
+
 type HorizonConfig struct {
 	Edge          Config
 	AgreementBot  AGConfig
@@ -26,7 +29,7 @@ type HorizonConfig struct {
 // This is the configuration options for Edge component flavor of Anax
 type Config struct {
 	ServiceStorage                   string // The base storage directory where the service can write or get the data.
-	APIListen                        string
+	APIListen                        string // Host and port for the API to listen on, or a unix:// url of a unix domain socket
 	DBPath                           string
 	DockerEndpoint                   string
 	DockerCredFilePath               string
@@ -66,6 +69,12 @@ type Config struct {
 	SurfaceErrorAgreementPersistentS int       // How long an agreement needs to persist before it is considered persistent and the related errors are dismisse. Default is 90 seconds
 	InitialPollingBuffer             int       // the number of seconds to wait before increasing the polling interval while there is no agreement on the node.
 	MaxAgreementPrelaunchTimeM       int64     // The maximum numbers of minutes to wait for workload to start in an agreement
+	EventLogMaxRecords               int       // The maximum number of event logs kept in the database, older ones are archived. Zero means no limit.
+	EventLogMaxAgeDays               int       // Event logs older than this number of days are archived. Zero means no limit.
+	EventLogArchivePath              string    // The directory that archived event logs are written to, the default is the eventlog_archive directory in DBPath
+	AgentUpgradeCommand              string    // The command that upgrades the agent software, run with the version to upgrade to as its argument. The agent upgrade jobs of node management policies fail when it is not set.
+	RequireSignedProposals           bool      // When true, proposals that are not signed by the agbot are rejected. The default is false, so that the proposals of older agbots are accepted.
+	ServiceLogAPI                    bool      // When true, the /service/log API returns the logs of the service containers. The default is false, because the agent API is not authenticated and the logs of a service can hold its secrets.
 
 	// these Ids could be provided in config or discovered after startup by the system
 	BlockchainAccountId        string
@@ -77,7 +86,15 @@ type AGConfig struct {
 	TxLostDelayTolerationSeconds int
 	AgreementWorkers             int
 	DBPath                       string
+	DBBucketPrefix               string           // A prefix for the names of the bolt DB buckets, so that agbots in the same process can share one bolt DB file
+	DBBucketPrefixMigrate        bool             // When true, the buckets without a prefix are moved to DBBucketPrefix when the agbot starts
+	DBShardByOrg                 bool             // When true, the bolt DB agreements of each org are kept in their own file
+	ReplicaDBPath                string           // When set, agreements are replicated to a read only bolt DB file in this directory, which is used by reporting queries
+	FieldEncryptionKeyPath       string           // When set, the sensitive fields of agreements are encrypted in the DB with the keys in this directory
+	FieldEncryptionKeyId         string           // The id of the key in FieldEncryptionKeyPath that new fields are encrypted with, the key file is <id>.key
 	Postgresql                   PostgresqlConfig // The Postgresql config if it is being used
+	Backup                       BackupConfig     // The config of the scheduled backups of the bolt DB, if they are being used
+	Alert                        AlertConfig      // The config of the alerts sent when agreements for a workload keep being cancelled
 	PartitionStale               uint64           // Number of seconds to wait before declaring a partition to be stale (i.e. the previous owner has unexpectedly terminated).
 	ProtocolTimeoutS             uint64           // Number of seconds to wait before declaring proposal response is lost
 	AgreementTimeoutS            uint64           // Number of seconds to wait before declaring agreement not finalized in blockchain
@@ -98,6 +115,7 @@ type AGConfig struct {
 	ExchangeMessageTTL           int              // The number of seconds the exchange will keep this message before automatically deleting it
 	MessageKeyPath               string           // The path to the location of messaging keys
 	MessageKeyCheck              int              // The interval (in seconds) indicating how often the agbot checks its own object in the exchange to ensure that the message key is still available.
+	RequireSignedReplies         bool             // When true, proposal replies that are not signed by the node are rejected. The default is false, so that the replies of older agents are accepted.
 	DefaultWorkloadPW            string           // The default workload password if none is specified in the policy file
 	APIListen                    string           // Host and port for the API to listen on
 	SecureAPIListenHost          string           // The host for the secure API to listen on
@@ -129,6 +147,13 @@ func (c *HorizonConfig) UserPublicKeyPath() string {
 	return c.Edge.UserPublicKeyPath
 }
 
+func (c *HorizonConfig) GetEventLogArchivePath() string {
+	if c.Edge.EventLogArchivePath == "" {
+		return path.Join(c.Edge.DBPath, "eventlog_archive")
+	}
+	return c.Edge.EventLogArchivePath
+}
+
 func (c *HorizonConfig) IsBoltDBConfigured() bool {
 	return len(c.AgreementBot.DBPath) != 0
 }
@@ -173,6 +198,15 @@ func (c *HorizonConfig) GetAgbotPolicyOrder() bool {
 	return c.AgreementBot.PolicySearchOrder
 }
 
+// Return the path of the unix domain socket that the API listens on, or empty string if the API listens on a host
+// and port.
+func (c *HorizonConfig) GetAPIUnixSocket() string {
+	if strings.HasPrefix(c.Edge.APIListen, APIUnixSocketPrefix) {
+		return strings.TrimPrefix(c.Edge.APIListen, APIUnixSocketPrefix)
+	}
+	return ""
+}
+
 func getDefaultBase() string {
 	basePath := os.Getenv("HZN_VAR_BASE")
 	if basePath == "" {
@@ -197,7 +231,8 @@ func enrichFromEnvvars(config *HorizonConfig) error {
 		config.Edge.ExchangeMessageDynamicPoll = false
 	}
 
-	if apiPort := os.Getenv(AnaxAPIPort); apiPort != "" {
+	// the agent port does not apply to an API on a unix domain socket
+	if apiPort := os.Getenv(AnaxAPIPort); apiPort != "" && config.GetAPIUnixSocket() == "" {
 		if config.Edge.APIListen != "" {
 			listen := strings.Split(config.Edge.APIListen, ":")
 			if len(listen) == 2 {
@@ -390,7 +425,15 @@ func (agc *AGConfig) String() string {
 	return fmt.Sprintf("TxLostDelayTolerationSeconds: %v"+
 		", AgreementWorkers: %v"+
 		", DBPath: %v"+
+		", DBBucketPrefix: %v"+
+		", DBBucketPrefixMigrate: %v"+
+		", DBShardByOrg: %v"+
+		", ReplicaDBPath: %v"+
+		", FieldEncryptionKeyPath: %v"+
+		", FieldEncryptionKeyId: %v"+
 		", Postgresql: {%v}"+
+		", Backup: {%v}"+
+		", Alert: {%v}"+
 		", PartitionStale: %v"+
 		", ProtocolTimeoutS: %v"+
 		", AgreementTimeoutS: %v"+
@@ -410,6 +453,7 @@ func (agc *AGConfig) String() string {
 		", ActiveDeviceTimeoutS: %v"+
 		", ExchangeMessageTTL: %v"+
 		", MessageKeyPath: %v"+
+		", RequireSignedReplies: %v"+
 		", DefaultWorkloadPW: %v"+
 		", APIListen: %v"+
 		", SecureAPIListenHost: %v"+
@@ -421,11 +465,11 @@ func (agc *AGConfig) String() string {
 		", CSSURL: %v"+
 		", CSSSSLCert: %v"+
 		", AgreementBatchSize: %v",
-		agc.TxLostDelayTolerationSeconds, agc.AgreementWorkers, agc.DBPath, agc.Postgresql.String(),
+		agc.TxLostDelayTolerationSeconds, agc.AgreementWorkers, agc.DBPath, agc.DBBucketPrefix, agc.DBBucketPrefixMigrate, agc.DBShardByOrg, agc.ReplicaDBPath, agc.FieldEncryptionKeyPath, agc.FieldEncryptionKeyId, agc.Postgresql.String(), agc.Backup.String(), agc.Alert.String(),
 		agc.PartitionStale, agc.ProtocolTimeoutS, agc.AgreementTimeoutS, agc.NoDataIntervalS, agc.ActiveAgreementsURL,
 		agc.ActiveAgreementsUser, mask, agc.PolicyPath, agc.NewContractIntervalS, agc.ProcessGovernanceIntervalS,
 		agc.IgnoreContractWithAttribs, agc.ExchangeURL, agc.ExchangeHeartbeat, agc.ExchangeId,
-		mask, agc.DVPrefix, agc.ActiveDeviceTimeoutS, agc.ExchangeMessageTTL, agc.MessageKeyPath, mask, agc.APIListen,
+		mask, agc.DVPrefix, agc.ActiveDeviceTimeoutS, agc.ExchangeMessageTTL, agc.MessageKeyPath, agc.RequireSignedReplies, mask, agc.APIListen,
 		agc.SecureAPIListenHost, agc.SecureAPIListenPort, agc.SecureAPIServerCert, agc.SecureAPIServerKey,
 		agc.PurgeArchivedAgreementHours, agc.CheckUpdatedPolicyS, agc.CSSURL, agc.CSSSSLCert, agc.AgreementBatchSize)
 }
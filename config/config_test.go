@@ -4,6 +4,7 @@ package config
 
 import (
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -65,3 +66,17 @@ func Test_enrichFromEnvvars_success(t *testing.T) {
 	}
 
 }
+
+func Test_AlertConfig_String(t *testing.T) {
+	alert := AlertConfig{WebhookURL: "https://alerts.example.com/hook?token=secret1", SlackWebhookURL: "https://hooks.slack.com/services/T000/B000/secret2", CancelThreshold: 3}
+	s := alert.String()
+	if strings.Contains(s, "secret") {
+		t.Errorf("the webhook urls should be masked, found %v", s)
+	}
+	if !strings.Contains(s, "https://hooks.slack.com/******") {
+		t.Errorf("the host of the webhook should be kept, found %v", s)
+	}
+	if MaskWebhookURL("") != "" {
+		t.Errorf("an empty url should stay empty")
+	}
+}
@@ -1360,7 +1360,7 @@ func (b *ContainerWorker) CommandHandler(command worker.Command) bool {
 
 		agreementId := cmd.AgreementLaunchContext.AgreementId
 
-		if ags, err := persistence.FindEstablishedAgreements(b.db, cmd.AgreementLaunchContext.AgreementProtocol, []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(agreementId)}); err != nil {
+		if ags, err := persistence.FindActiveEstablishedAgreements(b.db, cmd.AgreementLaunchContext.AgreementProtocol, []persistence.EAFilter{persistence.IdEAFilter(agreementId)}); err != nil {
 			glog.Errorf("Unable to retrieve agreement %v from database, error %v", agreementId, err)
 		} else if len(ags) != 1 {
 			glog.Infof("Ignoring the configure event for agreement %v, the agreement is archived.", agreementId)
@@ -1484,7 +1484,7 @@ func (b *ContainerWorker) CommandHandler(command worker.Command) bool {
 		for _, ag := range lc.GetAgreementIds() {
 			glog.V(5).Infof("ContainerWorker checking agreement %v", ag)
 
-			if ags, err := persistence.FindEstablishedAgreementsAllProtocols(b.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(ag), notTerminatedFilter()}); err != nil {
+			if ags, err := persistence.FindActiveEstablishedAgreementsAllProtocols(b.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.IdEAFilter(ag), notTerminatedFilter()}); err != nil {
 				glog.Errorf("Unable to retrieve agreement %v from database, error %v", ag, err)
 			} else if len(ags) != 1 {
 				glog.Infof("Ignoring the configure event for agreement %v, the agreement is no longer active.", ag)
@@ -1856,7 +1856,7 @@ func (b *ContainerWorker) syncupResources() {
 	glog.V(3).Infof("ContainerWorker beginning sync up of docker resources.")
 
 	// First get all the agreements from the DB.
-	if agreements, err := persistence.FindEstablishedAgreementsAllProtocols(b.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter()}); err != nil {
+	if agreements, err := persistence.FindActiveEstablishedAgreementsAllProtocols(b.db, policy.AllAgreementProtocols(), []persistence.EAFilter{}); err != nil {
 		fail(fmt.Sprintf("ContainerWorker unable to retrieve agreements from database, error %v", err))
 	} else {
 
@@ -2323,7 +2323,7 @@ func (b *ContainerWorker) findParentContainersForService(msinst_key string) ([]d
 	// the instances of top level services are stored in the "established_agreements" table.
 	// here we convert them to MicroserviceInstances for easier processing
 	top_level_msinsts := make([]persistence.MicroserviceInstance, 0)
-	eas, err := persistence.FindEstablishedAgreementsAllProtocols(b.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter()})
+	eas, err := persistence.FindActiveEstablishedAgreementsAllProtocols(b.db, policy.AllAgreementProtocols(), []persistence.EAFilter{})
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("unable to read active agreements from db, error %v", err))
 	} else {
@@ -0,0 +1,114 @@
+package cutil
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/sha3"
+)
+
+// The record kinds that a deletion report counts.
+const DELETED_ARCHIVED_AGREEMENTS = "archived_agreements"
+const DELETED_EVENT_LOGS = "event_logs"
+const DELETED_EVENT_LOG_ARCHIVES = "event_log_archives"
+const DELETED_SURFACE_ERRORS = "surface_errors"
+const DELETED_WORKLOAD_USAGES = "workload_usages"
+
+// A record of the data that was removed about a node, kept by whoever asked for the removal as evidence that it was
+// done. The report is signed by the node or agbot that removed the data with its messaging key, the public key of
+// which is registered in the exchange. The public key is also in the report, for convenience, but a verifier should
+// check that it is the key registered in the exchange for the reporter.
+type DeletionReport struct {
+	Subject      string         `json:"subject"`  // the org/id of the node whose data was removed
+	Reporter     string         `json:"reporter"` // the org/id of the node or agbot that removed the data
+	Time         uint64         `json:"time"`     // when the data was removed, in seconds since the epoch
+	Removed      map[string]int `json:"removed"`  // the number of records removed, by record kind
+	AgreementIds []string       `json:"agreement_ids"`
+	Files        []string       `json:"files"`
+	PublicKey    string         `json:"public_key"` // the PEM encoded public key that verifies the signature
+	Signature    string         `json:"signature"`  // base64 RSA-PSS SHA3-256 signature of the report without the signature
+}
+
+func (r DeletionReport) String() string {
+	return fmt.Sprintf("Subject: %v, Reporter: %v, Time: %v, Removed: %v, AgreementIds: %v, Files: %v", r.Subject, r.Reporter, r.Time, r.Removed, r.AgreementIds, r.Files)
+}
+
+func NewDeletionReport(subject string, reporter string, time uint64) *DeletionReport {
+	return &DeletionReport{
+		Subject:      subject,
+		Reporter:     reporter,
+		Time:         time,
+		Removed:      make(map[string]int),
+		AgreementIds: make([]string, 0),
+		Files:        make([]string, 0),
+	}
+}
+
+func (r *DeletionReport) digest() ([]byte, error) {
+	unsigned := *r
+	unsigned.Signature = ""
+	if content, err := json.Marshal(unsigned); err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to marshal deletion report, error: %v", err))
+	} else {
+		d := sha3.Sum256(content)
+		return d[:], nil
+	}
+}
+
+// Sign the report with the private key, the public key is added to the report.
+func (r *DeletionReport) Sign(privateKey *rsa.PrivateKey) error {
+	if pubBytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey); err != nil {
+		return errors.New(fmt.Sprintf("unable to marshal public key, error: %v", err))
+	} else {
+		r.PublicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubBytes}))
+	}
+
+	digest, err := r.digest()
+	if err != nil {
+		return err
+	}
+	if sig, err := rsa.SignPSS(rand.Reader, privateKey, crypto.SHA3_256, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto}); err != nil {
+		return errors.New(fmt.Sprintf("unable to sign deletion report, error: %v", err))
+	} else {
+		r.Signature = base64.StdEncoding.EncodeToString(sig)
+	}
+	return nil
+}
+
+// Verify the signature of the report with the public key. When the public key is nil, the public key in the report
+// is used.
+func (r *DeletionReport) Verify(publicKey *rsa.PublicKey) error {
+	if r.Signature == "" {
+		return errors.New("the deletion report is not signed")
+	}
+
+	if publicKey == nil {
+		block, _ := pem.Decode([]byte(r.PublicKey))
+		if block == nil {
+			return errors.New("the deletion report does not have a PEM encoded public key")
+		} else if key, err := x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			return errors.New(fmt.Sprintf("unable to parse the public key of the deletion report, error: %v", err))
+		} else if rsaKey, ok := key.(*rsa.PublicKey); !ok {
+			return errors.New("the public key of the deletion report is not an RSA key")
+		} else {
+			publicKey = rsaKey
+		}
+	}
+
+	digest, err := r.digest()
+	if err != nil {
+		return err
+	}
+	if sig, err := base64.StdEncoding.DecodeString(r.Signature); err != nil {
+		return errors.New(fmt.Sprintf("the deletion report signature is not base64 encoded, error: %v", err))
+	} else if err := rsa.VerifyPSS(publicKey, crypto.SHA3_256, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto}); err != nil {
+		return errors.New(fmt.Sprintf("the deletion report signature is not valid, error: %v", err))
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+// +build unit
+
+package cutil
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+)
+
+func Test_DeletionReport_SignVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := NewDeletionReport("myorg/node1", "myorg/agbot1", 1602632290)
+	report.Removed[DELETED_ARCHIVED_AGREEMENTS] = 2
+	report.AgreementIds = append(report.AgreementIds, "a1", "a2")
+
+	if err := report.Verify(nil); err == nil {
+		t.Errorf("expected an error verifying an unsigned report")
+	}
+
+	if err := report.Sign(key); err != nil {
+		t.Fatal(err)
+	} else if err := report.Verify(&key.PublicKey); err != nil {
+		t.Errorf("unexpected error verifying with the signing key: %v", err)
+	} else if err := report.Verify(nil); err != nil {
+		t.Errorf("unexpected error verifying with the key in the report: %v", err)
+	} else if err := report.Verify(&otherKey.PublicKey); err == nil {
+		t.Errorf("expected an error verifying with another key")
+	}
+
+	// A changed report does not verify.
+	report.Removed[DELETED_ARCHIVED_AGREEMENTS] = 1
+	if err := report.Verify(nil); err == nil {
+		t.Errorf("expected an error verifying a changed report")
+	}
+}
@@ -0,0 +1,195 @@
+package cutil
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// The vendors of the GPUs, by their PCI vendor id.
+var gpuVendors = map[string]string{
+	"0x10de": "nvidia",
+	"0x1002": "amd",
+	"0x8086": "intel",
+}
+
+// NodeResources is the hardware capacity of the node: its arch, cpus, memory, the disk of the agent's storage and
+// its GPUs. The memory and disk are in MegaBytes. The sizes are the capacity of the node, not what is free, so that
+// the resources only change when the hardware of the node changes.
+type NodeResources struct {
+	Arch         string `json:"arch"`
+	CPUs         int    `json:"cpus"`
+	MemoryMB     uint64 `json:"memoryMB"`
+	DiskMB       uint64 `json:"diskMB,omitempty"`
+	GPUs         []GPU  `json:"gpus"`
+	DetectedTime uint64 `json:"detectedTime"` // when the resources were detected, in seconds since 1970
+}
+
+func (r NodeResources) String() string {
+	return fmt.Sprintf("Arch: %v, CPUs: %v, MemoryMB: %v, DiskMB: %v, GPUs: %v, DetectedTime: %v", r.Arch, r.CPUs, r.MemoryMB, r.DiskMB, r.GPUs, r.DetectedTime)
+}
+
+// IsSame returns true if the resources are the same, regardless of when they were detected.
+func (r NodeResources) IsSame(other NodeResources) bool {
+	if r.Arch != other.Arch || r.CPUs != other.CPUs || r.MemoryMB != other.MemoryMB || r.DiskMB != other.DiskMB || len(r.GPUs) != len(other.GPUs) {
+		return false
+	}
+	for i := range r.GPUs {
+		if r.GPUs[i] != other.GPUs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// A GPU of the node. The device is the PCI bus id of the GPU, or its drm card when the bus id is not known.
+type GPU struct {
+	Vendor string `json:"vendor"`
+	Model  string `json:"model,omitempty"`
+	Device string `json:"device"`
+}
+
+func (g GPU) String() string {
+	return fmt.Sprintf("Vendor: %v, Model: %v, Device: %v", g.Vendor, g.Model, g.Device)
+}
+
+// GetNodeResources detects the resources of the local node. The disk is the file system of diskPath, it is not
+// detected when diskPath is empty. The files of /proc and /sys are read under rootDir, which is / when it is empty, so
+// that the detection also works from a container that has the host file systems mounted.
+func GetNodeResources(diskPath string, rootDir string) (*NodeResources, error) {
+	if rootDir == "" {
+		rootDir = "/"
+	}
+	res := NodeResources{Arch: ArchString(), GPUs: []GPU{}, DetectedTime: uint64(time.Now().Unix())}
+
+	if cpus, err := GetCPUCount(filepath.Join(rootDir, "proc/cpuinfo")); err == nil && cpus > 0 {
+		res.CPUs = cpus
+	} else {
+		res.CPUs = runtime.NumCPU()
+	}
+	if totalMem, _, err := GetMemInfo(filepath.Join(rootDir, "proc/meminfo")); err == nil {
+		res.MemoryMB = totalMem
+	}
+	if diskPath != "" {
+		var stat syscall.Statfs_t
+		if err := syscall.Statfs(diskPath, &stat); err != nil {
+			return nil, fmt.Errorf("Failed to get the size of the file system of %v: %v", diskPath, err)
+		}
+		res.DiskMB = uint64(stat.Blocks) * uint64(stat.Bsize) / (1024 * 1024)
+	}
+
+	gpus, err := GetGPUs(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	res.GPUs = gpus
+	return &res, nil
+}
+
+// DetectNodeResources detects the resources of a device node, or of the kubernetes cluster of a cluster node. The disk
+// of a device is the file system of diskPath.
+func DetectNodeResources(isCluster bool, diskPath string) (*NodeResources, error) {
+	if isCluster {
+		return GetClusterNodeResources()
+	}
+	return GetNodeResources(diskPath, "")
+}
+
+// GetClusterNodeResources returns the resources of the nodes of the kubernetes cluster of a cluster node. The cluster
+// has no disk or GPUs in its resources.
+func GetClusterNodeResources() (*NodeResources, error) {
+	_, totalMem, cpus, arch, _, err := GetClusterCountInfo()
+	if err != nil {
+		return nil, err
+	}
+	return &NodeResources{Arch: arch, CPUs: int(cpus), MemoryMB: uint64(math.Round(totalMem)), GPUs: []GPU{}, DetectedTime: uint64(time.Now().Unix())}, nil
+}
+
+// GetGPUs returns the GPUs of the node, sorted by device. The NVIDIA GPUs are found with their models from the driver
+// in /proc/driver/nvidia/gpus, the other GPUs from the drm cards in /sys/class/drm, with the PCI id of their model.
+func GetGPUs(rootDir string) ([]GPU, error) {
+	gpus := []GPU{}
+	nvidiaDir := filepath.Join(rootDir, "proc/driver/nvidia/gpus")
+	if entries, err := ioutil.ReadDir(nvidiaDir); err == nil {
+		for _, e := range entries {
+			gpu := GPU{Vendor: "nvidia", Device: strings.ToLower(e.Name())}
+			gpu.Model, _ = readInfoField(filepath.Join(nvidiaDir, e.Name(), "information"), "Model")
+			gpus = append(gpus, gpu)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("Failed to read the NVIDIA GPUs in %v: %v", nvidiaDir, err)
+	}
+
+	drmDir := filepath.Join(rootDir, "sys/class/drm")
+	reCard := regexp.MustCompile(`^card[0-9]+$`)
+	if entries, err := ioutil.ReadDir(drmDir); err == nil {
+		for _, e := range entries {
+			if !reCard.MatchString(e.Name()) {
+				continue
+			}
+			deviceDir := filepath.Join(drmDir, e.Name(), "device")
+			vendorId := readSysValue(filepath.Join(deviceDir, "vendor"))
+			vendor, ok := gpuVendors[vendorId]
+			if !ok {
+				continue
+			}
+			device := e.Name()
+			if link, err := os.Readlink(deviceDir); err == nil {
+				device = strings.ToLower(filepath.Base(link))
+			}
+			if vendor == "nvidia" && hasGPU(gpus, device) {
+				continue
+			}
+			gpus = append(gpus, GPU{Vendor: vendor, Model: readSysValue(filepath.Join(deviceDir, "device")), Device: device})
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("Failed to read the drm cards in %v: %v", drmDir, err)
+	}
+
+	sort.Slice(gpus, func(i, j int) bool { return gpus[i].Device < gpus[j].Device })
+	return gpus, nil
+}
+
+func hasGPU(gpus []GPU, device string) bool {
+	for _, g := range gpus {
+		if g.Device == device {
+			return true
+		}
+	}
+	return false
+}
+
+// The value of a field, "name: value", of an information file of a driver.
+func readInfoField(file string, name string) (string, error) {
+	fh, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer fh.Close()
+
+	scanner := bufio.NewScanner(fh)
+	for scanner.Scan() {
+		if parts := strings.SplitN(scanner.Text(), ":", 2); len(parts) == 2 && strings.TrimSpace(parts[0]) == name {
+			return strings.TrimSpace(parts[1]), nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+// The value of a file of /sys, empty when it cannot be read.
+func readSysValue(file string) string {
+	value, err := ioutil.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(value))
+}
@@ -0,0 +1,68 @@
+// +build unit
+
+package cutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_GetNodeResources(t *testing.T) {
+	root, err := ioutil.TempDir("", "cutil-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(root)
+
+	write := func(path string, content string) {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("unable to create the dir of %v: %v", path, err)
+		} else if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("unable to write %v: %v", path, err)
+		}
+	}
+	for _, f := range []string{"cpuinfo", "meminfo"} {
+		content, err := ioutil.ReadFile(filepath.Join("test", f))
+		if err != nil {
+			t.Fatalf("unable to read test/%v: %v", f, err)
+		}
+		write("proc/"+f, string(content))
+	}
+
+	// an NVIDIA GPU from its driver, which is also a drm card, and an Intel GPU that only is a drm card
+	write("proc/driver/nvidia/gpus/0000:01:00.0/information", "Model: \t\t Tesla T4\nIRQ:   \t\t 150\n")
+	write("sys/devices/pci0000:00/0000:01:00.0/vendor", "0x10de\n")
+	write("sys/devices/pci0000:00/0000:00:02.0/vendor", "0x8086\n")
+	write("sys/devices/pci0000:00/0000:00:02.0/device", "0x3e92\n")
+	write("sys/devices/pci0000:00/0000:00:1f.3/vendor", "0x1234\n")
+	for card, device := range map[string]string{"card0": "0000:00:02.0", "card1": "0000:01:00.0", "card2": "0000:00:1f.3"} {
+		if err := os.MkdirAll(filepath.Join(root, "sys/class/drm", card), 0755); err != nil {
+			t.Fatalf("unable to create %v: %v", card, err)
+		} else if err := os.Symlink(filepath.Join(root, "sys/devices/pci0000:00", device), filepath.Join(root, "sys/class/drm", card, "device")); err != nil {
+			t.Fatalf("unable to link %v: %v", card, err)
+		}
+	}
+
+	res, err := GetNodeResources(root, root)
+	if err != nil {
+		t.Fatalf("GetNodeResources should not get error but got: %v", err)
+	} else if res.CPUs != 2 || res.MemoryMB != 3946 || res.DiskMB == 0 || res.Arch != ArchString() {
+		t.Errorf("wrong resources: %v", res)
+	}
+	expected := []GPU{{Vendor: "intel", Model: "0x3e92", Device: "0000:00:02.0"}, {Vendor: "nvidia", Model: "Tesla T4", Device: "0000:01:00.0"}}
+	if len(res.GPUs) != len(expected) || res.GPUs[0] != expected[0] || res.GPUs[1] != expected[1] {
+		t.Errorf("expected the GPUs %v, found %v", expected, res.GPUs)
+	}
+
+	// the time of the detection does not make the resources different
+	other := *res
+	other.DetectedTime++
+	if !res.IsSame(other) {
+		t.Errorf("the resources should be the same")
+	} else if other.GPUs = other.GPUs[1:]; res.IsSame(other) {
+		t.Errorf("the resources should not be the same without a GPU")
+	}
+}
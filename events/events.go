@@ -68,6 +68,7 @@ const (
 	// exchange-related
 	NEW_DEVICE_REG             EventId = "NEW_DEVICE_REG"
 	NEW_DEVICE_CONFIG_COMPLETE EventId = "NEW_DEVICE_CONFIG_COMPLETE"
+	NODE_TOKEN_UPDATED         EventId = "NODE_TOKEN_UPDATED"
 	NEW_AGBOT_REG              EventId = "NEW_AGBOT_REG"
 
 	// agreement-related
@@ -708,6 +709,38 @@ func NewEdgeRegisteredExchangeMessage(evId EventId, device_id string, token stri
 	}
 }
 
+// This event indicates that the exchange token of the edge device was changed, so that the workers switch to the new token
+type NodeTokenUpdatedMessage struct {
+	event Event
+	token string
+}
+
+func (e NodeTokenUpdatedMessage) String() string {
+	return fmt.Sprintf("event: %v, token: %v", e.event, "********")
+}
+
+func (e NodeTokenUpdatedMessage) ShortString() string {
+	return e.String()
+}
+
+func (e *NodeTokenUpdatedMessage) Event() Event {
+	return e.event
+}
+
+func (e *NodeTokenUpdatedMessage) Token() string {
+	return e.token
+}
+
+func NewNodeTokenUpdatedMessage(evId EventId, token string) *NodeTokenUpdatedMessage {
+
+	return &NodeTokenUpdatedMessage{
+		event: Event{
+			Id: evId,
+		},
+		token: token,
+	}
+}
+
 // This event indicates that the edge device configuration is complete
 type EdgeConfigCompleteMessage struct {
 	event Event
@@ -295,6 +295,10 @@ func UpdateCacheNodePatchWriteThru(nodeOrg string, nodeId string, cachedDevice *
 			cachedDevice.UserInput = *pdr.UserInput
 			pdr.UserInput = nil
 		}
+		if pdr.Name != nil && *pdr.Name != "" {
+			cachedDevice.Name = *pdr.Name
+			pdr.Name = nil
+		}
 		if pdr.Pattern != nil && *pdr.Pattern != "" {
 			cachedDevice.Pattern = *pdr.Pattern
 			pdr.Pattern = nil
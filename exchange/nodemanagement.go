@@ -0,0 +1,372 @@
+package exchange
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/open-horizon/anax/i18n"
+	"github.com/open-horizon/anax/semanticversion"
+	"time"
+)
+
+// The jobs that a node management policy can run on the nodes it applies to.
+const NMP_JOB_CERT_UPDATE = "certUpdate"     // replace the CA certs that the agent trusts, the job config has the PEM "cert"
+const NMP_JOB_AGENT_UPGRADE = "agentUpgrade" // upgrade the agent software, the agbots roll the job out to the nodes in waves
+
+// The status that an agbot saves for a node in the exchange when it is the turn of the node to run a job that the
+// agbots roll out. The agent saves the other statuses, the ones it saves when a job is done are the same as the
+// statuses in the persistence package.
+const NMP_STATUS_SCHEDULED = "scheduled"
+const NMP_STATUS_SUCCESSFUL = "successful"
+const NMP_STATUS_FAILED = "failed"
+
+// Start a node management policy as soon as a node sees it.
+const NMP_START_NOW = "now"
+
+// A node management policy (NMP) tells the agents of the nodes it applies to run a management job. It applies to the
+// nodes that use one of its patterns or, for nodes that use a policy, whose policy is compatible with its policy.
+type ExchangeNodeManagementPolicy struct {
+	Owner       string                              `json:"owner,omitempty"`
+	Label       string                              `json:"label"`
+	Description string                              `json:"description"`
+	Constraints externalpolicy.ConstraintExpression `json:"constraints"`
+	Properties  externalpolicy.PropertyList         `json:"properties"`
+	Patterns    []string                            `json:"patterns"`
+	Enabled     bool                                `json:"enabled"`
+	Start       string                              `json:"start"` // "now" or an RFC3339 time
+	Job         NMPJob                              `json:"job"`
+	LastUpdated string                              `json:"lastUpdated,omitempty"`
+}
+
+func (e ExchangeNodeManagementPolicy) String() string {
+	return fmt.Sprintf("Owner: %v, Label: %v, Description: %v, Constraints: %v, Properties: %v, Patterns: %v, Enabled: %v, Start: %v, Job: %v, LastUpdated: %v",
+		e.Owner, e.Label, e.Description, e.Constraints, e.Properties, e.Patterns, e.Enabled, e.Start, e.Job, e.LastUpdated)
+}
+
+// The start time of the policy, zero when it starts now.
+func (e ExchangeNodeManagementPolicy) StartTime() (time.Time, error) {
+	if e.Start == "" || e.Start == NMP_START_NOW {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, e.Start)
+}
+
+// Validate the node management policy before it is saved in the exchange. This function is called by the CLI.
+func (e *ExchangeNodeManagementPolicy) Validate() error {
+
+	msgPrinter := i18n.GetMessagePrinter()
+
+	if len(e.Patterns) != 0 && (len(e.Constraints) != 0 || len(e.Properties) != 0) {
+		return errors.New(msgPrinter.Sprintf("a node management policy can have patterns, or properties and constraints, but not both"))
+	}
+
+	if err := e.Properties.Validate(); err != nil {
+		return errors.New(msgPrinter.Sprintf("properties contains an invalid property: %v", err))
+	}
+
+	if len(e.Constraints) != 0 {
+		if _, err := e.Constraints.Validate(); err != nil {
+			return errors.New(msgPrinter.Sprintf("constraints contains an invalid expression: %v", err))
+		}
+	}
+
+	if _, err := e.StartTime(); err != nil {
+		return errors.New(msgPrinter.Sprintf("start must be '%v' or an RFC3339 time, error %v", NMP_START_NOW, err))
+	}
+
+	return e.Job.Validate()
+}
+
+// The management job that a node management policy runs.
+type NMPJob struct {
+	Type   string                 `json:"type"`
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+func (j NMPJob) String() string {
+	// the config can have certs or other large values, so only the names are shown
+	names := make([]string, 0, len(j.Config))
+	for name := range j.Config {
+		names = append(names, name)
+	}
+	return fmt.Sprintf("Type: %v, Config: %v", j.Type, names)
+}
+
+// Validate the type and the config of the job.
+func (j NMPJob) Validate() error {
+	switch j.Type {
+	case NMP_JOB_CERT_UPDATE:
+		_, err := j.GetCert()
+		return err
+	case NMP_JOB_AGENT_UPGRADE:
+		_, err := j.GetAgentUpgrade()
+		return err
+	case "":
+		return errors.New(i18n.GetMessagePrinter().Sprintf("the job type is empty"))
+	}
+	return errors.New(i18n.GetMessagePrinter().Sprintf("the job type %v is not supported, the supported types are: %v", j.Type, []string{NMP_JOB_CERT_UPDATE, NMP_JOB_AGENT_UPGRADE}))
+}
+
+// Returns true if the agbots decide when each node runs the job, instead of the nodes running it at the start time.
+func (j NMPJob) IsScheduledByAgbot() bool {
+	return j.Type == NMP_JOB_AGENT_UPGRADE
+}
+
+// Get the PEM encoded certs of a cert update job, checking that each of them can be parsed.
+func (j NMPJob) GetCert() ([]byte, error) {
+
+	msgPrinter := i18n.GetMessagePrinter()
+
+	certs, ok := j.Config["cert"].(string)
+	if !ok || certs == "" {
+		return nil, errors.New(msgPrinter.Sprintf("the config of the %v job must have a non-empty cert", j.Type))
+	}
+
+	rest := []byte(certs)
+	found := 0
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		} else if block.Type != "CERTIFICATE" {
+			return nil, errors.New(msgPrinter.Sprintf("the cert of the %v job has a %v block, only certificates are allowed", j.Type, block.Type))
+		} else if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return nil, errors.New(msgPrinter.Sprintf("the cert of the %v job can not be parsed, error %v", j.Type, err))
+		}
+		found++
+	}
+	if found == 0 {
+		return nil, errors.New(msgPrinter.Sprintf("the cert of the %v job has no PEM encoded certificate", j.Type))
+	}
+	return []byte(certs), nil
+}
+
+// The config of an agent upgrade job. The agbots schedule the nodes in waves of WaveSize nodes, starting the next wave
+// WaveIntervalS seconds after every node of the previous wave is done. The rollout stops when more than
+// FailureThreshold percent of the nodes that are done have failed, and a node that is not done NodeTimeoutS seconds
+// after it was scheduled is counted as failed. A FailureThreshold of 0 is replaced by the default, and 100 means that
+// the rollout never stops.
+type AgentUpgradeJob struct {
+	Version          string `json:"version"`
+	WaveSize         int    `json:"waveSize"`
+	WaveIntervalS    int    `json:"waveIntervalS"`
+	FailureThreshold int    `json:"failureThreshold"`
+	NodeTimeoutS     int    `json:"nodeTimeoutS"`
+}
+
+func (a AgentUpgradeJob) String() string {
+	return fmt.Sprintf("Version: %v, WaveSize: %v, WaveIntervalS: %v, FailureThreshold: %v, NodeTimeoutS: %v",
+		a.Version, a.WaveSize, a.WaveIntervalS, a.FailureThreshold, a.NodeTimeoutS)
+}
+
+// The defaults for the agent upgrade job config.
+const AGENT_UPGRADE_DEFAULT_WAVE_SIZE = 10
+const AGENT_UPGRADE_DEFAULT_NODE_TIMEOUT_S = 3600
+const AGENT_UPGRADE_DEFAULT_FAILURE_THRESHOLD = 10
+
+// Get the config of an agent upgrade job, with the defaults filled in.
+func (j NMPJob) GetAgentUpgrade() (*AgentUpgradeJob, error) {
+
+	msgPrinter := i18n.GetMessagePrinter()
+
+	upgrade := &AgentUpgradeJob{}
+	if configBytes, err := json.Marshal(j.Config); err != nil {
+		return nil, errors.New(msgPrinter.Sprintf("the config of the %v job can not be read, error %v", j.Type, err))
+	} else if err := json.Unmarshal(configBytes, upgrade); err != nil {
+		return nil, errors.New(msgPrinter.Sprintf("the config of the %v job is not valid, error %v", j.Type, err))
+	}
+
+	if upgrade.Version == "" {
+		return nil, errors.New(msgPrinter.Sprintf("the config of the %v job must have a version", j.Type))
+	} else if !semanticversion.IsVersionString(upgrade.Version) {
+		return nil, errors.New(msgPrinter.Sprintf("the version %v of the %v job is not a valid version", upgrade.Version, j.Type))
+	} else if upgrade.WaveSize < 0 || upgrade.WaveIntervalS < 0 || upgrade.NodeTimeoutS < 0 {
+		return nil, errors.New(msgPrinter.Sprintf("the waveSize, waveIntervalS and nodeTimeoutS of the %v job can not be negative", j.Type))
+	} else if upgrade.FailureThreshold < 0 || upgrade.FailureThreshold > 100 {
+		return nil, errors.New(msgPrinter.Sprintf("the failureThreshold of the %v job must be a percentage between 0 and 100", j.Type))
+	}
+
+	if upgrade.WaveSize == 0 {
+		upgrade.WaveSize = AGENT_UPGRADE_DEFAULT_WAVE_SIZE
+	}
+	if upgrade.NodeTimeoutS == 0 {
+		upgrade.NodeTimeoutS = AGENT_UPGRADE_DEFAULT_NODE_TIMEOUT_S
+	}
+	if upgrade.FailureThreshold == 0 {
+		upgrade.FailureThreshold = AGENT_UPGRADE_DEFAULT_FAILURE_THRESHOLD
+	}
+	return upgrade, nil
+}
+
+type GetNodeManagementPolicyResponse struct {
+	Policies  map[string]ExchangeNodeManagementPolicy `json:"managementPolicy"`
+	LastIndex int                                     `json:"lastIndex"`
+}
+
+// The outcome of a node management policy job on a node, which the agent saves in the exchange.
+type NodeManagementPolicyStatus struct {
+	Status       string `json:"status"`
+	StartTime    string `json:"startTime,omitempty"`
+	EndTime      string `json:"endTime,omitempty"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
+}
+
+func (s NodeManagementPolicyStatus) String() string {
+	return fmt.Sprintf("Status: %v, StartTime: %v, EndTime: %v, ErrorMessage: %v", s.Status, s.StartTime, s.EndTime, s.ErrorMessage)
+}
+
+// Get the node management policies of the org.
+func GetNodeManagementPolicies(ec ExchangeContext, org string) (map[string]ExchangeNodeManagementPolicy, error) {
+	glog.V(3).Infof(rpclogString(fmt.Sprintf("getting node management policies for org %v.", org)))
+
+	var resp interface{}
+	resp = new(GetNodeManagementPolicyResponse)
+
+	targetURL := fmt.Sprintf("%vorgs/%v/managementpolicies", ec.GetExchangeURL(), org)
+
+	retryCount := ec.GetHTTPFactory().RetryCount
+	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	for {
+		if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "GET", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp); err != nil {
+			glog.Errorf(rpclogString(fmt.Sprintf(err.Error())))
+			return nil, err
+		} else if tpErr != nil {
+			glog.Warningf(rpclogString(fmt.Sprintf(tpErr.Error())))
+			if ec.GetHTTPFactory().RetryCount == 0 {
+				time.Sleep(time.Duration(retryInterval) * time.Second)
+				continue
+			} else if retryCount == 0 {
+				return nil, fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
+			} else {
+				retryCount--
+				time.Sleep(time.Duration(retryInterval) * time.Second)
+				continue
+			}
+		} else {
+			policies := resp.(*GetNodeManagementPolicyResponse).Policies
+			if policies == nil {
+				policies = make(map[string]ExchangeNodeManagementPolicy)
+			}
+			glog.V(5).Infof(rpclogString(fmt.Sprintf("returning %v node management policies for org %v.", len(policies), org)))
+			return policies, nil
+		}
+	}
+}
+
+// Save the status of a node management policy job on the node in the exchange. The policy name is in the form
+// org/name.
+func PutNodeManagementPolicyStatus(ec ExchangeContext, deviceId string, policyName string, status *NodeManagementPolicyStatus) error {
+	glog.V(3).Infof(rpclogString(fmt.Sprintf("saving the status of node management policy %v for %v: %v", policyName, deviceId, status)))
+
+	var resp interface{}
+	resp = new(PutDeviceResponse)
+	targetURL := fmt.Sprintf("%vorgs/%v/nodes/%v/managementStatus/%v", ec.GetExchangeURL(), GetOrg(deviceId), GetId(deviceId), GetId(policyName))
+
+	retryCount := ec.GetHTTPFactory().RetryCount
+	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	for {
+		if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "PUT", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), status, &resp); err != nil {
+			return err
+		} else if tpErr != nil {
+			glog.Warningf(tpErr.Error())
+			if ec.GetHTTPFactory().RetryCount == 0 {
+				time.Sleep(time.Duration(retryInterval) * time.Second)
+				continue
+			} else if retryCount == 0 {
+				return fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
+			} else {
+				retryCount--
+				time.Sleep(time.Duration(retryInterval) * time.Second)
+				continue
+			}
+		} else {
+			glog.V(5).Infof(rpclogString(fmt.Sprintf("saved the status of node management policy %v for %v", policyName, deviceId)))
+			return nil
+		}
+	}
+}
+
+// Get the status of a node management policy job on a node from the exchange, nil if the node has no status for the
+// policy. The policy name is in the form org/name.
+func GetNodeManagementPolicyStatus(ec ExchangeContext, deviceId string, policyName string) (*NodeManagementPolicyStatus, error) {
+	glog.V(3).Infof(rpclogString(fmt.Sprintf("getting the status of node management policy %v for %v", policyName, deviceId)))
+
+	var resp interface{}
+	resp = new(GetNodeManagementPolicyStatusResponse)
+	targetURL := fmt.Sprintf("%vorgs/%v/nodes/%v/managementStatus/%v", ec.GetExchangeURL(), GetOrg(deviceId), GetId(deviceId), GetId(policyName))
+
+	retryCount := ec.GetHTTPFactory().RetryCount
+	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	for {
+		if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "GET", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp); err != nil {
+			glog.Errorf(rpclogString(fmt.Sprintf(err.Error())))
+			return nil, err
+		} else if tpErr != nil {
+			glog.Warningf(rpclogString(fmt.Sprintf(tpErr.Error())))
+			if ec.GetHTTPFactory().RetryCount == 0 {
+				time.Sleep(time.Duration(retryInterval) * time.Second)
+				continue
+			} else if retryCount == 0 {
+				return nil, fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
+			} else {
+				retryCount--
+				time.Sleep(time.Duration(retryInterval) * time.Second)
+				continue
+			}
+		} else {
+			status, ok := resp.(*GetNodeManagementPolicyStatusResponse).Statuses[policyName]
+			if !ok {
+				glog.V(5).Infof(rpclogString(fmt.Sprintf("node %v has no status for node management policy %v", deviceId, policyName)))
+				return nil, nil
+			}
+			glog.V(5).Infof(rpclogString(fmt.Sprintf("returning the status of node management policy %v for %v: %v", policyName, deviceId, status)))
+			return &status, nil
+		}
+	}
+}
+
+type GetNodeManagementPolicyStatusResponse struct {
+	Statuses map[string]NodeManagementPolicyStatus `json:"managementStatus"`
+}
+
+// Get the nodes of an org.
+func GetOrgNodes(ec ExchangeContext, org string) (map[string]Device, error) {
+	glog.V(3).Infof(rpclogString(fmt.Sprintf("getting the nodes of org %v", org)))
+
+	var resp interface{}
+	resp = new(GetDevicesResponse)
+	targetURL := fmt.Sprintf("%vorgs/%v/nodes", ec.GetExchangeURL(), org)
+
+	retryCount := ec.GetHTTPFactory().RetryCount
+	retryInterval := ec.GetHTTPFactory().GetRetryInterval()
+	for {
+		if err, tpErr := InvokeExchange(ec.GetHTTPFactory().NewHTTPClient(nil), "GET", targetURL, ec.GetExchangeId(), ec.GetExchangeToken(), nil, &resp); err != nil {
+			glog.Errorf(rpclogString(fmt.Sprintf(err.Error())))
+			return nil, err
+		} else if tpErr != nil {
+			glog.Warningf(rpclogString(fmt.Sprintf(tpErr.Error())))
+			if ec.GetHTTPFactory().RetryCount == 0 {
+				time.Sleep(time.Duration(retryInterval) * time.Second)
+				continue
+			} else if retryCount == 0 {
+				return nil, fmt.Errorf("Exceeded %v retries for error: %v", ec.GetHTTPFactory().RetryCount, tpErr)
+			} else {
+				retryCount--
+				time.Sleep(time.Duration(retryInterval) * time.Second)
+				continue
+			}
+		} else {
+			nodes := resp.(*GetDevicesResponse).Devices
+			if nodes == nil {
+				nodes = make(map[string]Device)
+			}
+			glog.V(5).Infof(rpclogString(fmt.Sprintf("returning %v nodes of org %v", len(nodes), org)))
+			return nodes, nil
+		}
+	}
+}
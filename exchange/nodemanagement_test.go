@@ -0,0 +1,28 @@
+// +build unit
+
+package exchange
+
+import (
+	"testing"
+)
+
+func TestGetAgentUpgradeDefaults(t *testing.T) {
+	job := NMPJob{Type: NMP_JOB_AGENT_UPGRADE, Config: map[string]interface{}{"version": "2.30.0"}}
+	if upgrade, err := job.GetAgentUpgrade(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if upgrade.WaveSize != AGENT_UPGRADE_DEFAULT_WAVE_SIZE || upgrade.NodeTimeoutS != AGENT_UPGRADE_DEFAULT_NODE_TIMEOUT_S || upgrade.FailureThreshold != AGENT_UPGRADE_DEFAULT_FAILURE_THRESHOLD {
+		t.Errorf("the defaults should have been filled in, found %v", upgrade)
+	}
+
+	job.Config["failureThreshold"] = 100
+	if upgrade, err := job.GetAgentUpgrade(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if upgrade.FailureThreshold != 100 {
+		t.Errorf("the failureThreshold should be kept, found %v", upgrade)
+	}
+
+	job.Config["failureThreshold"] = 101
+	if _, err := job.GetAgentUpgrade(); err == nil {
+		t.Errorf("a failureThreshold above 100 should be rejected")
+	}
+}
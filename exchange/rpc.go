@@ -407,6 +407,7 @@ func (p PutDeviceRequest) ShortString() string {
 // Please patch one field at a time.
 type PatchDeviceRequest struct {
 	UserInput          *[]policy.UserInput `json:"userInput,omitempty"`
+	Name               *string             `json:"name,omitempty"`
 	Pattern            *string             `json:"pattern,omitempty"`
 	Arch               *string             `json:"arch,omitempty"`
 	RegisteredServices *[]Microservice     `json:"registeredServices,omitempty"`
@@ -421,7 +422,11 @@ func (p PatchDeviceRequest) String() string {
 	if p.Arch != nil {
 		arch = *p.Arch
 	}
-	return fmt.Sprintf("UserInput: %v, RegisteredServices: %v, Pattern: %v, Arch: %v", p.UserInput, p.RegisteredServices, pattern, arch)
+	name := "nil"
+	if p.Name != nil {
+		name = *p.Name
+	}
+	return fmt.Sprintf("UserInput: %v, RegisteredServices: %v, Name: %v, Pattern: %v, Arch: %v", p.UserInput, p.RegisteredServices, name, pattern, arch)
 }
 
 func (p PatchDeviceRequest) ShortString() string {
@@ -450,7 +455,12 @@ func (p PatchDeviceRequest) ShortString() string {
 		arch = *p.Arch
 	}
 
-	return fmt.Sprintf("UserInput: %v, RegisteredServices: %v, Pattern: %v, Arch: %v", userInput, registeredServices, pattern, arch)
+	name := "nil"
+	if p.Name != nil {
+		name = *p.Name
+	}
+
+	return fmt.Sprintf("UserInput: %v, RegisteredServices: %v, Name: %v, Pattern: %v, Arch: %v", userInput, registeredServices, name, pattern, arch)
 }
 
 type PostMessage struct {
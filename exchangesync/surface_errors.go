@@ -111,7 +111,7 @@ func HasPersistentAgreement(db *bolt.DB, serviceResolverHandler exchange.Service
 // get the all the top level and dependent services the given agreements are using
 func getAllServicesFromAgreements(db *bolt.DB, serviceResolverHandler exchange.ServiceResolverHandler, agreementPersistentTime int) (*policy.APISpecList, error) {
 
-	ags, err := persistence.FindEstablishedAgreementsAllProtocols(db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter(), PersistingEAFilter(agreementPersistentTime)})
+	ags, err := persistence.FindActiveEstablishedAgreementsAllProtocols(db, policy.AllAgreementProtocols(), []persistence.EAFilter{PersistingEAFilter(agreementPersistentTime)})
 
 	if err != nil {
 		return nil, fmt.Errorf("Unable to retrieve unarchived agreements from database. %v", err)
@@ -37,6 +37,8 @@ const MICROSERVICE_GOVERNOR = "MicroserviceGovernor"
 const BC_GOVERNOR = "BlockchainGovernor"
 const SURFACEERRORS = "SurfaceExchErrors"
 const NODESTATUS = "NodeStatus"
+const EVENTLOG_ARCHIVE = "EventLogArchive"
+const NODE_RESOURCES = "NodeResources"
 
 // Keys for the exchange errors cache in the worker
 const EXCHANGE_ERRORS = "ExchangeErrors"
@@ -112,6 +114,12 @@ func (w *GovernanceWorker) NewEvent(incoming events.Message) {
 		w.deviceType = msg.DeviceType()
 		w.limitedRetryEC = newLimitedRetryExchangeContext(w.EC)
 
+	case *events.NodeTokenUpdatedMessage:
+		// The exchange context already has the new token.
+		if w.EC != nil {
+			w.limitedRetryEC = newLimitedRetryExchangeContext(w.EC)
+		}
+
 	case *events.EdgeConfigCompleteMessage:
 		// Start any services that run without needing an agreement.
 		cmd := w.NewStartAgreementLessServicesCommand()
@@ -164,7 +172,7 @@ func (w *GovernanceWorker) NewEvent(incoming events.Message) {
 				reason = w.producerPH[lc.AgreementProtocol].GetTerminationCode(producer.TERM_REASON_IMAGE_FETCH_FAILURE)
 			}
 
-			if ags, err := persistence.FindEstablishedAgreements(w.db, lc.AgreementProtocol, []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(lc.AgreementId)}); err != nil {
+			if ags, err := persistence.FindActiveEstablishedAgreements(w.db, lc.AgreementProtocol, []persistence.EAFilter{persistence.IdEAFilter(lc.AgreementId)}); err != nil {
 				glog.Errorf(logString(fmt.Sprintf("unable to retrieve agreement %v from database, error %v", lc.AgreementId, err)))
 				eventlog.LogDatabaseEvent(w.db, persistence.SEVERITY_ERROR,
 					persistence.NewMessageMeta(EL_GOV_ERR_RETRIEVE_AG_FROM_DB, lc.AgreementId, err.Error()),
@@ -398,7 +406,7 @@ func (w *GovernanceWorker) governAgreements() {
 		}
 	}
 
-	if establishedAgreements, err := persistence.FindEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter(), notYetFinalFilter()}); err != nil {
+	if establishedAgreements, err := persistence.FindActiveEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{notYetFinalFilter()}); err != nil {
 		glog.Errorf(logString(fmt.Sprintf("Unable to retrieve not yet final agreements from database. Error: %v", err)))
 	} else {
 
@@ -548,7 +556,7 @@ func (w *GovernanceWorker) governContainers() int {
 		}
 	}
 
-	if establishedAgreements, err := persistence.FindEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter(), runningFilter()}); err != nil {
+	if establishedAgreements, err := persistence.FindActiveEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{runningFilter()}); err != nil {
 		glog.Errorf(logString(fmt.Sprintf("Unable to retrieve running agreements from database, error: %v", err)))
 	} else {
 		for _, ag := range establishedAgreements {
@@ -579,7 +587,7 @@ func (w *GovernanceWorker) reportBlockchains() int {
 
 			// Make a map of all blockchain orgs and names that we need to have running
 			neededBCs := make(map[string]map[string]bool)
-			if agreements, err := persistence.FindEstablishedAgreements(w.db, agp, []persistence.EAFilter{persistence.UnarchivedEAFilter()}); err == nil {
+			if agreements, err := persistence.FindActiveEstablishedAgreements(w.db, agp, []persistence.EAFilter{}); err == nil {
 				for _, ag := range agreements {
 					_, bcName, bcOrg := w.producerPH[agp].GetKnownBlockchain(&ag)
 					if bcName != "" {
@@ -749,6 +757,14 @@ func (w *GovernanceWorker) Initialize() bool {
 	// Fire up the microservice governor
 	w.DispatchSubworker(MICROSERVICE_GOVERNOR, w.governMicroservices, 60, false)
 
+	// detect the hardware of the node now and periodically, and publish it to the exchange when it changes
+	w.DispatchSubworker(NODE_RESOURCES, w.reportNodeResources, 1, false)
+
+	// Keep the event log within the configured size and age limits
+	if w.Config.Edge.EventLogMaxRecords != 0 || w.Config.Edge.EventLogMaxAgeDays != 0 {
+		w.DispatchSubworker(EVENTLOG_ARCHIVE, w.archiveEventLogs, 3600, false)
+	}
+
 	// for the policy case update the exchange with the latest registeredServices
 	if w.devicePattern == "" {
 		w.UpdateRegisteredServicesWithAgreement()
@@ -790,7 +806,7 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 		cmd, _ := command.(*CleanupExecutionCommand)
 
 		agreementId := cmd.AgreementId
-		if ags, err := persistence.FindEstablishedAgreements(w.db, cmd.AgreementProtocol, []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(agreementId)}); err != nil {
+		if ags, err := persistence.FindActiveEstablishedAgreements(w.db, cmd.AgreementProtocol, []persistence.EAFilter{persistence.IdEAFilter(agreementId)}); err != nil {
 			glog.Errorf(logString(fmt.Sprintf("unable to retrieve agreement %v from database, error %v", agreementId, err)))
 		} else if len(ags) != 1 {
 			glog.V(5).Infof(logString(fmt.Sprintf("ignoring the event, unable to retrieve unarchived single agreement %v from the database.", agreementId)))
@@ -851,7 +867,7 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 				ags := []persistence.EstablishedAgreement{}
 				var err error
 
-				if ags, err = persistence.FindEstablishedAgreements(w.db, msgProtocol, []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(replyAck.AgreementId())}); err != nil {
+				if ags, err = persistence.FindActiveEstablishedAgreements(w.db, msgProtocol, []persistence.EAFilter{persistence.IdEAFilter(replyAck.AgreementId())}); err != nil {
 					glog.Errorf(logString(fmt.Sprintf("unable to retrieve agreement %v from database, error %v", replyAck.AgreementId(), err)))
 					eventlog.LogDatabaseEvent(w.db, persistence.SEVERITY_ERROR,
 						persistence.NewMessageMeta(EL_GOV_ERR_RETRIEVE_AG_FROM_DB_FOR_RAM, replyAck.AgreementId(), err.Error()),
@@ -912,7 +928,7 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 				ags := []persistence.EstablishedAgreement{}
 				var err error
 
-				if ags, err = persistence.FindEstablishedAgreements(w.db, msgProtocol, []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(dataReceived.AgreementId())}); err != nil {
+				if ags, err = persistence.FindActiveEstablishedAgreements(w.db, msgProtocol, []persistence.EAFilter{persistence.IdEAFilter(dataReceived.AgreementId())}); err != nil {
 					glog.Errorf(logString(fmt.Sprintf("unable to retrieve agreement %v from database, error %v", dataReceived.AgreementId(), err)))
 					eventlog.LogDatabaseEvent(w.db, persistence.SEVERITY_ERROR,
 						persistence.NewMessageMeta(EL_GOV_ERR_RETRIEVE_AG_FROM_DB_FOR_DRM, dataReceived.AgreementId(), err.Error()),
@@ -953,7 +969,7 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 				ags := []persistence.EstablishedAgreement{}
 				var err error
 
-				if ags, err = persistence.FindEstablishedAgreements(w.db, msgProtocol, []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(mnReceived.AgreementId())}); err != nil {
+				if ags, err = persistence.FindActiveEstablishedAgreements(w.db, msgProtocol, []persistence.EAFilter{persistence.IdEAFilter(mnReceived.AgreementId())}); err != nil {
 					glog.Errorf(logString(fmt.Sprintf("unable to retrieve agreement %v from database, error %v", mnReceived.AgreementId(), err)))
 					eventlog.LogDatabaseEvent(w.db, persistence.SEVERITY_ERROR,
 						persistence.NewMessageMeta(EL_GOV_ERR_RETRIEVE_AG_FROM_DB_FOR_MNM, mnReceived.AgreementId(), err.Error()),
@@ -998,7 +1014,7 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 				ags := []persistence.EstablishedAgreement{}
 				var err error
 
-				if ags, err = persistence.FindEstablishedAgreements(w.db, msgProtocol, []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(canReceived.AgreementId())}); err != nil {
+				if ags, err = persistence.FindActiveEstablishedAgreements(w.db, msgProtocol, []persistence.EAFilter{persistence.IdEAFilter(canReceived.AgreementId())}); err != nil {
 					glog.Errorf(logString(fmt.Sprintf("unable to retrieve agreement %v from database, error %v", canReceived.AgreementId(), err)))
 					eventlog.LogDatabaseEvent(w.db, persistence.SEVERITY_ERROR,
 						persistence.NewMessageMeta(EL_GOV_ERR_RETRIEVE_AG_FROM_DB_FOR_CANM, canReceived.AgreementId(), err.Error()),
@@ -1053,7 +1069,7 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 				} else if cancel {
 					reason := w.producerPH[msgProtocol].GetTerminationCode(producer.TERM_REASON_AGBOT_REQUESTED)
 
-					if ags, err := persistence.FindEstablishedAgreements(w.db, msgProtocol, []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(agid)}); err != nil {
+					if ags, err := persistence.FindActiveEstablishedAgreements(w.db, msgProtocol, []persistence.EAFilter{persistence.IdEAFilter(agid)}); err != nil {
 						glog.Errorf(logString(fmt.Sprintf("unable to retrieve agreement %v from database, error %v", agid, err)))
 						eventlog.LogDatabaseEvent(
 							w.db,
@@ -1105,7 +1121,7 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 			} else if termination {
 
 				// If we have that agreement in our DB, then cancel it
-				if ags, err := persistence.FindEstablishedAgreements(w.db, protocol, []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(agreementId)}); err != nil {
+				if ags, err := persistence.FindActiveEstablishedAgreements(w.db, protocol, []persistence.EAFilter{persistence.IdEAFilter(agreementId)}); err != nil {
 					glog.Errorf(logString(fmt.Sprintf("unable to retrieve agreement %v from database, error %v", agreementId, err)))
 				} else if len(ags) != 1 {
 					glog.V(5).Infof(logString(fmt.Sprintf("ignoring event, not our agreement id")))
@@ -1124,7 +1140,7 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 			} else if creation {
 
 				// If we have that agreement in our DB and it's not already terminating, then finalize it
-				if ags, err := persistence.FindEstablishedAgreements(w.db, protocol, []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(agreementId)}); err != nil {
+				if ags, err := persistence.FindActiveEstablishedAgreements(w.db, protocol, []persistence.EAFilter{persistence.IdEAFilter(agreementId)}); err != nil {
 					glog.Errorf(logString(fmt.Sprintf("unable to retrieve agreement %v from database, error %v", agreementId, err)))
 				} else if len(ags) != 1 {
 					glog.V(5).Infof(logString(fmt.Sprintf("ignoring event, not our agreement id")))
@@ -1142,7 +1158,7 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 		cmd, _ := command.(*CleanupStatusCommand)
 
 		glog.V(5).Infof(logString(fmt.Sprintf("Received CleanupStatusCommand: %v.", cmd)))
-		if ags, err := persistence.FindEstablishedAgreements(w.db, cmd.AgreementProtocol, []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(cmd.AgreementId)}); err != nil {
+		if ags, err := persistence.FindActiveEstablishedAgreements(w.db, cmd.AgreementProtocol, []persistence.EAFilter{persistence.IdEAFilter(cmd.AgreementId)}); err != nil {
 			glog.Errorf(logString(fmt.Sprintf("unable to retrieve agreement %v from database, error %v", cmd.AgreementId, err)))
 		} else if len(ags) != 1 {
 			glog.V(5).Infof(logString(fmt.Sprintf("ignoring event, not our agreement id")))
@@ -1895,9 +1911,8 @@ func (w *GovernanceWorker) FindEstablishedAgreementsWithIds(agreementIds []strin
 
 	// find all agreements in db
 	var filters []persistence.EAFilter
-	filters = append(filters, persistence.UnarchivedEAFilter())
 	filters = append(filters, multiIdFilter(agreementIds))
-	return persistence.FindEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), filters)
+	return persistence.FindActiveEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), filters)
 }
 
 // Check if the agreement uses any of the given services
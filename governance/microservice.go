@@ -223,7 +223,7 @@ func (w *GovernanceWorker) GetEnvVarsForServiceDepolyment(msdef *persistence.Mic
 
 	if agreementId != "" {
 		// this the first time this dependent service is brought up
-		ags, err := persistence.FindEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(agreementId)})
+		ags, err := persistence.FindActiveEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.IdEAFilter(agreementId)})
 		if err != nil {
 			return nil, fmt.Errorf(logString(fmt.Sprintf("failed to retrieve agreement %v from database, error %v", agreementId, err)))
 		} else if len(ags) == 0 {
@@ -848,7 +848,7 @@ func (w *GovernanceWorker) handleServiceSuspended(service_cs []events.ServiceCon
 	// We need to go through both to get all the agreements that the user want to stop because we do not know from the input, service_cs, if the given
 	// service is top level to dependent.
 	agreements_to_cancel := make(map[string]persistence.EstablishedAgreement, 10)
-	establishedAgreements, err := persistence.FindEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter()})
+	establishedAgreements, err := persistence.FindActiveEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{})
 	if err != nil {
 		eventlog.LogDatabaseEvent(w.db, persistence.SEVERITY_ERROR,
 			persistence.NewMessageMeta(EL_GOV_ERR_RETRIEVE_MATCH_AGS_FROM_DB, fmt.Sprintf("%v", service_cs), err.Error()),
@@ -915,7 +915,7 @@ func (w *GovernanceWorker) handleServiceSuspended(service_cs []events.ServiceCon
 // get the all the top level and dependent services the given agreements are using
 func (w *GovernanceWorker) getAllServicesFromAgreements() (*policy.APISpecList, error) {
 
-	ags, err := persistence.FindEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter()})
+	ags, err := persistence.FindActiveEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{})
 	if err != nil {
 		return nil, fmt.Errorf("Unable to retrieve unarchived agreements from database. %v", err)
 	}
@@ -36,7 +36,7 @@ func (c ChangePattern) Reset() {
 func (w *GovernanceWorker) handleNodeHeartbeatRestored() error {
 	glog.V(5).Infof(logString(fmt.Sprintf("handling agreements after node heartbeat restored.")))
 
-	if ags, err := persistence.FindEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter()}); err != nil {
+	if ags, err := persistence.FindActiveEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{}); err != nil {
 		eventlog.LogDatabaseEvent(w.db, persistence.SEVERITY_ERROR,
 			persistence.NewMessageMeta(EL_GOV_ERR_RETRIEVE_UNARCHIVED_AG_FROM_DB, err.Error()),
 			persistence.EC_DATABASE_ERROR)
@@ -84,7 +84,7 @@ func (w *GovernanceWorker) handleNodeUserInputUpdated(svcSpecs persistence.Servi
 	}
 
 	// get all the unarchived agreements
-	agreements, err := persistence.FindEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter()})
+	agreements, err := persistence.FindActiveEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{})
 	if err != nil {
 		glog.Errorf(logString(fmt.Sprintf("Unable to retrieve all the  from the database, error %v", err)))
 		return
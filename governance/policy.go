@@ -48,7 +48,7 @@ func (w *GovernanceWorker) handleNodePolicyUpdated() {
 	glog.V(5).Infof(logString(fmt.Sprintf("handling node policy changes")))
 
 	// get all the unarchived agreements
-	agreements, err := persistence.FindEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter()})
+	agreements, err := persistence.FindActiveEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{})
 	if err != nil {
 		glog.Errorf(logString(fmt.Sprintf("Unable to retrieve all the  from the database, error %v", err)))
 		return
@@ -150,6 +150,12 @@ func (w *GovernanceWorker) nodeShutdown(cmd *NodeShutdownCommand) {
 		return
 	}
 
+	// Delete the node management policy job statuses from local db
+	if err := persistence.DeleteAllNMPStatuses(w.db); err != nil {
+		w.completedWithError(logString(err.Error()))
+		return
+	}
+
 	// remove the docker volumes that are created by anax if device type is "device"
 	if w.deviceType == persistence.DEVICE_TYPE_DEVICE {
 		if err := container.DeleteLeftoverDockerVolumes(w.db, w.Config); err != nil {
@@ -298,7 +304,7 @@ func (w *GovernanceWorker) terminateAllAgreements(reason string) error {
 		}
 	}
 
-	establishedAgreements, err := persistence.FindEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter(), notYetFinalFilter()})
+	establishedAgreements, err := persistence.FindActiveEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{notYetFinalFilter()})
 	if err != nil {
 		return errors.New(fmt.Sprintf("unable to retrieve agreements from database, error: %v", err))
 	}
@@ -321,7 +327,7 @@ func (w *GovernanceWorker) terminateAllAgreements(reason string) error {
 	// Wait until there are no active agreements in the local DB. Agreements dont get archived until the workload containers have stopped.
 	runtime.Gosched()
 	for {
-		remainingAgreements, err := persistence.FindEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter()})
+		remainingAgreements, err := persistence.FindActiveEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{})
 		if err != nil {
 			return errors.New(fmt.Sprintf("unable to retrieve agreements from database, error: %v", err))
 		} else if len(remainingAgreements) != 0 {
@@ -231,7 +231,7 @@ func (w *GovernanceWorker) getWorkloadStatus(containers []docker.APIContainers)
 
 	status := make([]WorkloadStatus, 0)
 
-	if establishedAgreements, err := persistence.FindEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter()}); err != nil {
+	if establishedAgreements, err := persistence.FindActiveEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{}); err != nil {
 		return nil, fmt.Errorf(logString(fmt.Sprintf("Unable to retrieve not yet final agreements from database: %v. Error: %v", err, err)))
 	} else {
 		for _, ag := range establishedAgreements {
@@ -424,6 +424,17 @@ func (w *GovernanceWorker) surfaceErrors() int {
 	return exchangesync.UpdateSurfaceErrors(w.db, *pDevice, currentExchangeErrors.ErrorList, putErrorsHandler, serviceResolverHandler, w.BaseWorker.Manager.Config.Edge.SurfaceErrorTimeoutS, w.BaseWorker.Manager.Config.Edge.SurfaceErrorAgreementPersistentS)
 }
 
+// Archive the event logs that are beyond the configured size or age limits.
+func (w *GovernanceWorker) archiveEventLogs() int {
+	maxAge := time.Duration(w.Config.Edge.EventLogMaxAgeDays) * 24 * time.Hour
+	if removed, err := persistence.ArchiveEventLogs(w.db, w.Config.Edge.EventLogMaxRecords, maxAge, w.Config.GetEventLogArchivePath()); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("Error archiving event logs. %v", err)))
+	} else if removed != 0 {
+		glog.V(3).Infof(logString(fmt.Sprintf("archived %v event logs to %v", removed, w.Config.GetEventLogArchivePath())))
+	}
+	return 0
+}
+
 func changeInWorkloadStatuses(newStatuses []WorkloadStatus, oldStatuses []persistence.WorkloadStatus) bool {
 	if len(oldStatuses) != len(newStatuses) {
 		return true
@@ -493,3 +504,29 @@ func converContainerStatusToPersistenceType(containers []ContainerStatus) []pers
 	}
 	return persistentCStatuses
 }
+
+// How often the resources of the node are detected.
+const NODE_RESOURCES_INTERVAL_S = 3600
+
+// Detect the hardware resources of the node and save them for the /node/resources API. The node in the exchange has no
+// field for the resources, its arch, cpus and memory reach the exchange as the openhorizon.arch, openhorizon.cpu and
+// openhorizon.memory built-in properties of the node policy, which the agent updates when they change. The disk and
+// the GPUs are only known locally.
+func (w *GovernanceWorker) reportNodeResources() int {
+	detected, err := cutil.DetectNodeResources(w.deviceType == persistence.DEVICE_TYPE_CLUSTER, w.Config.Edge.DBPath)
+	if err != nil {
+		glog.Errorf(logString(fmt.Sprintf("Failed to detect the resources of the node: %v", err)))
+		return NODE_RESOURCES_INTERVAL_S
+	}
+
+	if saved, err := persistence.FindNodeResources(w.db); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("Failed to retrieve the node resources from local database: %v", err)))
+	} else if saved == nil || !saved.IsSame(*detected) {
+		glog.V(3).Infof(logString(fmt.Sprintf("detected the node resources: %v", detected)))
+	}
+
+	if err := persistence.SaveNodeResources(w.db, &persistence.NodeResources{NodeResources: *detected}); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("Failed to save the node resources in local database: %v", err)))
+	}
+	return NODE_RESOURCES_INTERVAL_S
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/agreement"
 	"github.com/open-horizon/anax/agreementbot"
+	"github.com/open-horizon/anax/agreementbot/backup"
 	agbotPersistence "github.com/open-horizon/anax/agreementbot/persistence"
 	_ "github.com/open-horizon/anax/agreementbot/persistence/bolt"
 	_ "github.com/open-horizon/anax/agreementbot/persistence/postgresql"
@@ -21,6 +22,7 @@ import (
 	_ "github.com/open-horizon/anax/i18n_messages"
 	"github.com/open-horizon/anax/imagefetch"
 	"github.com/open-horizon/anax/kube_operator"
+	"github.com/open-horizon/anax/nodemanagement"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/resource"
@@ -42,6 +44,7 @@ import (
 func main() {
 	configFile := flag.String("config", "/etc/colonus/anax.config", "Config file location")
 	cpuprofile := flag.String("cpuprofile", "", "write cpu profile to file")
+	restoreBackup := flag.String("restore-agbot-backup", "", "restore the named agbot database backup, or 'latest', then exit")
 
 	flag.Parse()
 
@@ -63,6 +66,19 @@ func main() {
 	glog.V(2).Infof("Using config: %v", cfg.String())
 	glog.V(2).Infof("GOMAXPROCS: %v", runtime.GOMAXPROCS(-1))
 
+	// restore the agbot database from a backup, before the database is opened
+	if *restoreBackup != "" {
+		if name, err := backup.Restore(cfg, cfg.Collaborators.HTTPClientFactory.NewHTTPClient(nil), *restoreBackup); err != nil {
+			glog.Errorf("Unable to restore Agreement Bot database backup %v: %v", *restoreBackup, err)
+			glog.Flush()
+			os.Exit(1)
+		} else {
+			glog.Infof("Restored Agreement Bot database backup %v", name)
+			glog.Flush()
+			os.Exit(0)
+		}
+	}
+
 	// initialize the message printer for globalization, the anax will produce English messages.
 	// However, in order to extract messages for eventlog for translation, we need to use the message printer for
 	// eventlog messages.
@@ -84,6 +100,15 @@ func main() {
 
 	// open Agreement Bot DB if necessary
 
+	// encrypt the sensitive agreement fields in the Agreement Bot DB, if a field encryption key is configured
+	if cfg.AgreementBot.FieldEncryptionKeyPath != "" {
+		if provider, err := agbotPersistence.NewFileFieldKeyProvider(cfg.AgreementBot.FieldEncryptionKeyPath, cfg.AgreementBot.FieldEncryptionKeyId); err != nil {
+			panic(fmt.Sprintf("Unable to initialize Agreement Bot field encryption: %v", err))
+		} else {
+			agbotPersistence.SetFieldKeyProvider(provider)
+		}
+	}
+
 	var agbotDB agbotPersistence.AgbotDatabase
 	agbotDB, dberr := agbotPersistence.InitDatabase(cfg)
 	if db == nil && dberr != nil {
@@ -92,6 +117,17 @@ func main() {
 		glog.Warningf("Unable to initialize Agreement Bot database on this node: %v", dberr)
 	}
 
+	// start replicating the agreements to the read replica used by reporting queries, if one is configured
+	var agbotReportingDB agbotPersistence.AgreementReader
+	if agbotDB != nil {
+		if replica, err := agbotPersistence.InitReplica(agbotDB, cfg); err != nil {
+			glog.Errorf("Unable to initialize the Agreement Bot replica, reporting queries will use the Agreement Bot database: %v", err)
+			agbotReportingDB = agbotDB
+		} else {
+			agbotReportingDB = replica
+		}
+	}
+
 	// start control signal handler
 	control := make(chan os.Signal, 1)
 	signal.Notify(control, os.Interrupt)
@@ -129,6 +165,11 @@ func main() {
 		panic(err)
 	}
 
+	// The established agreements might also have been written by an older level of code.
+	if err := persistence.MigrateEstablishedAgreements(db); err != nil {
+		panic(err)
+	}
+
 	// Get the device side policy manager started early so that all the workers can use it.
 	// Make sure the policy directory is in place.
 	var pm *policy.PolicyManager
@@ -152,7 +193,7 @@ func main() {
 
 	workers.Add(agreementbot.NewAgreementBotWorker("AgBot", cfg, agbotDB))
 	if cfg.AgreementBot.APIListen != "" {
-		workers.Add(agreementbot.NewAPIListener("AgBot API", cfg, agbotDB, *configFile))
+		workers.Add(agreementbot.NewAPIListener("AgBot API", cfg, agbotDB, agbotReportingDB, *configFile))
 	}
 	if cfg.AgreementBot.SecureAPIListenHost != "" {
 		workers.Add(agreementbot.NewSecureAPIListener("AgBot Secure API", cfg, agbotDB, *configFile))
@@ -175,6 +216,7 @@ func main() {
 		workers.Add(kube_operator.NewKubeWorker("Kube", cfg, db))
 		workers.Add(resource.NewResourceWorker("Resource", cfg, db, authm))
 		workers.Add(changes.NewChangesWorker("ExchangeChanges", cfg, db))
+		workers.Add(nodemanagement.NewNodeManagementWorker("NodeManagement", cfg, db))
 	}
 
 	// Get into the event processing loop until anax shuts itself down.
@@ -0,0 +1,32 @@
+package nodemanagement
+
+import (
+	"fmt"
+	"github.com/open-horizon/anax/events"
+)
+
+// Commands used to communicate with the worker, directing it to do something, usually based on the
+// arrival of an event from the internal message bus.
+
+type NodeRegisteredCommand struct {
+	Msg *events.EdgeRegisteredExchangeMessage
+}
+
+func (c NodeRegisteredCommand) ShortString() string {
+	return fmt.Sprintf("NodeRegisteredCommand Msg: %v", c.Msg)
+}
+
+func NewNodeRegisteredCommand(msg *events.EdgeRegisteredExchangeMessage) *NodeRegisteredCommand {
+	return &NodeRegisteredCommand{Msg: msg}
+}
+
+type CheckPoliciesCommand struct {
+}
+
+func (c CheckPoliciesCommand) ShortString() string {
+	return fmt.Sprintf("CheckPoliciesCommand")
+}
+
+func NewCheckPoliciesCommand() *CheckPoliciesCommand {
+	return &CheckPoliciesCommand{}
+}
@@ -0,0 +1,127 @@
+package nodemanagement
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/open-horizon/anax/semanticversion"
+	"github.com/open-horizon/anax/version"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Returns true if the node management policy applies to the node. A node that uses a pattern must have its pattern
+// in the patterns of the policy, while for a node that uses a policy, the constraints of each policy must be
+// satisfied by the properties of the other. If the policy does not apply, the reason is returned.
+func IsNMPCompatible(nmp exchange.ExchangeNodeManagementPolicy, nodePattern string, nodeOrg string, nodePolicy *externalpolicy.ExternalPolicy) (bool, string) {
+
+	if nodePattern != "" {
+		for _, p := range nmp.Patterns {
+			// a pattern without an org is in the org of the node
+			if p == nodePattern || fmt.Sprintf("%v/%v", nodeOrg, p) == nodePattern {
+				return true, ""
+			}
+		}
+		return false, fmt.Sprintf("pattern %v is not one of %v", nodePattern, nmp.Patterns)
+	}
+
+	if len(nmp.Patterns) != 0 {
+		return false, "the policy is for pattern nodes"
+	}
+
+	var nodeProps externalpolicy.PropertyList
+	var nodeConstraints externalpolicy.ConstraintExpression
+	if nodePolicy != nil {
+		nodeProps = nodePolicy.Properties
+		nodeConstraints = nodePolicy.Constraints
+	}
+
+	if err := nmp.Constraints.IsSatisfiedBy(nodeProps); err != nil {
+		return false, fmt.Sprintf("the node properties do not satisfy the constraints: %v", err)
+	} else if err := nodeConstraints.IsSatisfiedBy(nmp.Properties); err != nil {
+		return false, fmt.Sprintf("the properties do not satisfy the node constraints: %v", err)
+	}
+	return true, ""
+}
+
+// Run a node management job on this node.
+func RunJob(job exchange.NMPJob, cfg *config.HorizonConfig) error {
+	if err := job.Validate(); err != nil {
+		return err
+	}
+
+	switch job.Type {
+	case exchange.NMP_JOB_CERT_UPDATE:
+		cert, _ := job.GetCert()
+		return updateCACerts(cfg.Edge.CACertsPath, cert)
+	case exchange.NMP_JOB_AGENT_UPGRADE:
+		upgrade, _ := job.GetAgentUpgrade()
+		return upgradeAgent(cfg.Edge.AgentUpgradeCommand, version.HORIZON_VERSION, upgrade.Version)
+	}
+	return nil
+}
+
+// Replace the CA certs that the agent trusts. The current file is kept with a .bak suffix and the new file is written
+// atomically so that the agent never reads a partial file. The agent reads the file when it starts, so it must be
+// restarted to use the new certs.
+func updateCACerts(caCertsPath string, cert []byte) error {
+	if caCertsPath == "" {
+		return errors.New("the agent is not configured with a CA certs file")
+	}
+
+	if current, err := ioutil.ReadFile(caCertsPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to read %v, error %v", caCertsPath, err)
+	} else if err == nil {
+		if err := ioutil.WriteFile(caCertsPath+".bak", current, 0644); err != nil {
+			return fmt.Errorf("unable to back up %v, error %v", caCertsPath, err)
+		}
+	}
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(caCertsPath), filepath.Base(caCertsPath)+".tmp")
+	if err != nil {
+		return fmt.Errorf("unable to create a temporary file for %v, error %v", caCertsPath, err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(cert); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("unable to write %v, error %v", tmpFile.Name(), err)
+	} else if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("unable to write %v, error %v", tmpFile.Name(), err)
+	} else if err := os.Chmod(tmpFile.Name(), 0644); err != nil {
+		return fmt.Errorf("unable to set the mode of %v, error %v", tmpFile.Name(), err)
+	} else if err := os.Rename(tmpFile.Name(), caCertsPath); err != nil {
+		return fmt.Errorf("unable to replace %v, error %v", caCertsPath, err)
+	}
+
+	glog.Infof(nmwlog(fmt.Sprintf("updated the CA certs in %v, the agent must be restarted to use them", caCertsPath)))
+	return nil
+}
+
+// Upgrade the agent software by running the configured upgrade command with the version to upgrade to. The command
+// usually restarts the agent, and the job is then run again by the new agent, which finds that it is already at the
+// version.
+func upgradeAgent(upgradeCommand string, currentVersion string, targetVersion string) error {
+
+	// a development build is not a version, so it is always upgraded
+	if c, err := semanticversion.CompareVersions(currentVersion, targetVersion); err == nil && c >= 0 {
+		glog.V(3).Infof(nmwlog(fmt.Sprintf("the agent version %v is already at or above %v", currentVersion, targetVersion)))
+		return nil
+	}
+
+	if upgradeCommand == "" {
+		return fmt.Errorf("the agent is not configured with an upgrade command, unable to upgrade from version %v to %v", currentVersion, targetVersion)
+	}
+
+	glog.Infof(nmwlog(fmt.Sprintf("upgrading the agent from version %v to %v with %v", currentVersion, targetVersion, upgradeCommand)))
+	if out, err := exec.Command(upgradeCommand, targetVersion).CombinedOutput(); err != nil {
+		return fmt.Errorf("the upgrade command %v failed, error %v, output: %v", upgradeCommand, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+// +build unit
+
+package nodemanagement
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/externalpolicy"
+	_ "github.com/open-horizon/anax/externalpolicy/text_language"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_IsNMPCompatible_pattern(t *testing.T) {
+
+	nmp := exchange.ExchangeNodeManagementPolicy{Patterns: []string{"netspeed", "otherorg/gps"}}
+
+	if ok, reason := IsNMPCompatible(nmp, "myorg/netspeed", "myorg", nil); !ok {
+		t.Errorf("pattern without an org should be in the org of the node: %v", reason)
+	} else if ok, reason := IsNMPCompatible(nmp, "otherorg/gps", "myorg", nil); !ok {
+		t.Errorf("pattern with an org should match: %v", reason)
+	} else if ok, _ := IsNMPCompatible(nmp, "myorg/gps", "myorg", nil); ok {
+		t.Errorf("pattern myorg/gps should not match")
+	} else if ok, _ := IsNMPCompatible(nmp, "", "myorg", nil); ok {
+		t.Errorf("a policy for pattern nodes should not apply to a node that uses a policy")
+	}
+}
+
+func Test_IsNMPCompatible_policy(t *testing.T) {
+
+	nodePolicy := &externalpolicy.ExternalPolicy{
+		Properties:  externalpolicy.PropertyList{*externalpolicy.Property_Factory("group", "east")},
+		Constraints: externalpolicy.ConstraintExpression{"managed == true"},
+	}
+
+	nmp := exchange.ExchangeNodeManagementPolicy{
+		Properties:  externalpolicy.PropertyList{*externalpolicy.Property_Factory("managed", true)},
+		Constraints: externalpolicy.ConstraintExpression{"group == east"},
+	}
+	if ok, reason := IsNMPCompatible(nmp, "", "myorg", nodePolicy); !ok {
+		t.Errorf("policies should be compatible: %v", reason)
+	} else if ok, _ := IsNMPCompatible(nmp, "myorg/netspeed", "myorg", nodePolicy); ok {
+		t.Errorf("a policy without patterns should not apply to a pattern node")
+	}
+
+	nmp.Constraints = externalpolicy.ConstraintExpression{"group == west"}
+	if ok, _ := IsNMPCompatible(nmp, "", "myorg", nodePolicy); ok {
+		t.Errorf("the node properties should not satisfy the constraints")
+	}
+
+	nmp.Constraints = externalpolicy.ConstraintExpression{}
+	nmp.Properties = externalpolicy.PropertyList{}
+	if ok, _ := IsNMPCompatible(nmp, "", "myorg", nodePolicy); ok {
+		t.Errorf("the properties should not satisfy the node constraints")
+	} else if ok, reason := IsNMPCompatible(nmp, "", "myorg", nil); !ok {
+		t.Errorf("a policy without constraints should apply to a node without a policy: %v", reason)
+	}
+}
+
+func Test_RunJob_certUpdate(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "nmp-")
+	if err != nil {
+		t.Fatalf("unable to create the test dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certPath := filepath.Join(dir, "agent-install.crt")
+	if err := ioutil.WriteFile(certPath, []byte("old certs"), 0644); err != nil {
+		t.Fatalf("unable to write %v: %v", certPath, err)
+	}
+
+	cfg := &config.HorizonConfig{Edge: config.Config{CACertsPath: certPath}}
+	cert := newTestCert(t)
+
+	// a job with an invalid cert is rejected and the certs are not changed
+	job := exchange.NMPJob{Type: exchange.NMP_JOB_CERT_UPDATE, Config: map[string]interface{}{"cert": "not a cert"}}
+	if err := RunJob(job, cfg); err == nil {
+		t.Errorf("a job with an invalid cert should fail")
+	} else if content, _ := ioutil.ReadFile(certPath); string(content) != "old certs" {
+		t.Errorf("the certs should not be changed, found %v", string(content))
+	}
+
+	job.Config["cert"] = cert
+	if err := RunJob(job, cfg); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if content, _ := ioutil.ReadFile(certPath); string(content) != cert {
+		t.Errorf("the certs should be updated, found %v", string(content))
+	} else if backup, _ := ioutil.ReadFile(certPath + ".bak"); string(backup) != "old certs" {
+		t.Errorf("the old certs should be backed up, found %v", string(backup))
+	}
+
+	if files, _ := ioutil.ReadDir(dir); len(files) != 2 {
+		t.Errorf("the temporary file should be removed, found %v files", len(files))
+	}
+
+	job = exchange.NMPJob{Type: "reboot"}
+	if err := RunJob(job, cfg); err == nil {
+		t.Errorf("an unsupported job type should fail")
+	}
+}
+
+// Create a self signed PEM encoded certificate.
+func newTestCert(t *testing.T) string {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("unable to create a key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unable to create a cert: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func Test_upgradeAgent(t *testing.T) {
+
+	if err := upgradeAgent("", "2.30.0", "2.29.0"); err != nil {
+		t.Errorf("an agent above the version should not be upgraded: %v", err)
+	} else if err := upgradeAgent("", "2.30.0", "2.30.0"); err != nil {
+		t.Errorf("an agent at the version should not be upgraded: %v", err)
+	} else if err := upgradeAgent("", "2.29.0", "2.30.0"); err == nil {
+		t.Errorf("an upgrade without a command should fail")
+	} else if err := upgradeAgent("false", "local build", "2.30.0"); err == nil {
+		t.Errorf("a failed upgrade command should fail the job")
+	} else if err := upgradeAgent("true", "2.29.0", "2.30.0"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+package nodemanagement
+
+import (
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/worker"
+	"time"
+)
+
+// How often the node management policies are read from the exchange.
+const NMP_CHECK_INTERVAL_S = 60
+
+// The node management worker runs the jobs of the node management policies that apply to this node, and saves the
+// status of each job locally and in the exchange.
+type NodeManagementWorker struct {
+	worker.BaseWorker // embedded field
+	db                *bolt.DB
+}
+
+func NewNodeManagementWorker(name string, cfg *config.HorizonConfig, db *bolt.DB) *NodeManagementWorker {
+
+	var ec *worker.BaseExchangeContext
+	dev, _ := persistence.FindExchangeDevice(db)
+	if dev != nil {
+		ec = worker.NewExchangeContext(fmt.Sprintf("%v/%v", dev.Org, dev.Id), dev.Token, cfg.Edge.ExchangeURL, cfg.GetCSSURL(), cfg.Collaborators.HTTPClientFactory)
+	}
+
+	worker := &NodeManagementWorker{
+		BaseWorker: worker.NewBaseWorker(name, cfg, ec),
+		db:         db,
+	}
+
+	glog.Info(nmwlog(fmt.Sprintf("Starting Node Management worker")))
+	worker.Start(worker, NMP_CHECK_INTERVAL_S)
+	return worker
+}
+
+func (w *NodeManagementWorker) Messages() chan events.Message {
+	return w.BaseWorker.Manager.Messages
+}
+
+// Handle events that are propogated to this worker from the internal event bus.
+func (w *NodeManagementWorker) NewEvent(incoming events.Message) {
+
+	switch incoming.(type) {
+
+	case *events.EdgeRegisteredExchangeMessage:
+		msg, _ := incoming.(*events.EdgeRegisteredExchangeMessage)
+		w.Commands <- NewNodeRegisteredCommand(msg)
+
+	case *events.NodePolicyMessage:
+		// a change to the node policy can change which management policies apply to the node
+		w.Commands <- NewCheckPoliciesCommand()
+
+	case *events.NodeShutdownCompleteMessage:
+		msg, _ := incoming.(*events.NodeShutdownCompleteMessage)
+		switch msg.Event().Id {
+		case events.UNCONFIGURE_COMPLETE:
+			w.Commands <- worker.NewTerminateCommand("shutdown")
+		}
+
+	default: //nothing
+
+	}
+
+	return
+}
+
+// Handle commands that are placed on the command queue.
+func (w *NodeManagementWorker) CommandHandler(command worker.Command) bool {
+
+	switch command.(type) {
+	case *NodeRegisteredCommand:
+		cmd, _ := command.(*NodeRegisteredCommand)
+		msg := cmd.Msg
+		w.EC = worker.NewExchangeContext(fmt.Sprintf("%v/%v", msg.Org(), msg.DeviceId()), msg.Token(), w.Config.Edge.ExchangeURL, w.Config.GetCSSURL(), w.Config.Collaborators.HTTPClientFactory)
+		w.checkPolicies()
+
+	case *CheckPoliciesCommand:
+		w.checkPolicies()
+
+	default:
+		return false
+	}
+	return true
+}
+
+// Check the node management policies periodically, for the new and changed policies and for the policies whose start
+// time has come.
+func (w *NodeManagementWorker) NoWorkHandler() {
+	w.checkPolicies()
+}
+
+// Find the node management policies that apply to this node and run the jobs that have not run yet.
+func (w *NodeManagementWorker) checkPolicies() {
+
+	// Dont look for policies until the node is registered.
+	if w.GetExchangeToken() == "" {
+		glog.V(5).Infof(nmwlog(fmt.Sprintf("waiting for exchange registration")))
+		return
+	}
+
+	dev, err := persistence.FindExchangeDevice(w.db)
+	if err != nil {
+		glog.Errorf(nmwlog(fmt.Sprintf("unable to read the node, error %v", err)))
+		return
+	} else if dev == nil || !dev.IsState(persistence.CONFIGSTATE_CONFIGURED) {
+		glog.V(5).Infof(nmwlog(fmt.Sprintf("waiting for the node to be configured")))
+		return
+	}
+
+	nodePolicy, err := persistence.FindNodePolicy(w.db)
+	if err != nil {
+		glog.Errorf(nmwlog(fmt.Sprintf("unable to read the node policy, error %v", err)))
+		return
+	}
+
+	policies, err := exchange.GetNodeManagementPolicies(w, dev.Org)
+	if err != nil {
+		glog.Errorf(nmwlog(fmt.Sprintf("unable to get the node management policies, error %v", err)))
+		return
+	}
+
+	statuses, err := persistence.FindNMPStatuses(w.db)
+	if err != nil {
+		glog.Errorf(nmwlog(fmt.Sprintf("unable to read the node management statuses, error %v", err)))
+		return
+	}
+
+	for name, nmp := range policies {
+		if !nmp.Enabled {
+			continue
+		} else if compatible, reason := IsNMPCompatible(nmp, dev.Pattern, dev.Org, nodePolicy); !compatible {
+			glog.V(5).Infof(nmwlog(fmt.Sprintf("node management policy %v does not apply to this node: %v", name, reason)))
+			continue
+		}
+
+		// the status of an older version of the policy does not count, the job runs again when the policy changes
+		var current *persistence.NMPStatus
+		if status, found := statuses[name]; found && status.PolicyLastUpdated == nmp.LastUpdated {
+			if status.IsFinished() {
+				continue
+			}
+			current = &status
+		}
+		w.runPolicy(name, nmp, current)
+	}
+
+	// forget the statuses of the policies that were removed
+	for name := range statuses {
+		if _, ok := policies[name]; !ok {
+			if err := persistence.DeleteNMPStatus(w.db, name); err != nil {
+				glog.Errorf(nmwlog(fmt.Sprintf("unable to delete the status of node management policy %v, error %v", name, err)))
+			}
+		}
+	}
+}
+
+// Run the job of a policy if its start time has come, otherwise record that the job is waiting. The current status
+// is the unfinished status of this version of the policy, if there is one. A job that the agbots schedule also
+// waits until an agbot has scheduled it on this node.
+func (w *NodeManagementWorker) runPolicy(name string, nmp exchange.ExchangeNodeManagementPolicy, current *persistence.NMPStatus) {
+
+	status := &persistence.NMPStatus{PolicyName: name, JobType: nmp.Job.Type, PolicyLastUpdated: nmp.LastUpdated}
+	waiting := current != nil && current.Status == persistence.NMP_STATUS_WAITING
+	running := current != nil && current.Status == persistence.NMP_STATUS_RUNNING
+
+	if start, err := nmp.StartTime(); err != nil {
+		status.Status = persistence.NMP_STATUS_FAILED
+		status.ErrorMessage = fmt.Sprintf("invalid start time %v, error %v", nmp.Start, err)
+		w.saveStatus(status)
+		return
+	} else if start.After(time.Now()) {
+		if !waiting {
+			status.Status = persistence.NMP_STATUS_WAITING
+			w.saveLocalStatus(status, nmp.Job.IsScheduledByAgbot())
+		}
+		return
+	}
+
+	// A job that was running when the agent stopped, as it does when the agent is upgraded, is run again. The job
+	// knows when it has nothing more to do.
+	if nmp.Job.IsScheduledByAgbot() && !running {
+		if exStatus, err := exchange.GetNodeManagementPolicyStatus(w, w.GetExchangeId(), name); err != nil {
+			glog.Errorf(nmwlog(fmt.Sprintf("unable to get the status of node management policy %v from the exchange, error %v", name, err)))
+			return
+		} else if exStatus == nil || exStatus.Status != exchange.NMP_STATUS_SCHEDULED {
+			if !waiting {
+				status.Status = persistence.NMP_STATUS_WAITING
+				w.saveLocalStatus(status, true)
+			}
+			return
+		}
+	}
+
+	glog.V(3).Infof(nmwlog(fmt.Sprintf("running the %v job of node management policy %v", nmp.Job.Type, name)))
+	status.Status = persistence.NMP_STATUS_RUNNING
+	status.StartTime = time.Now().UTC().Format(time.RFC3339)
+	if running && current.StartTime != "" {
+		status.StartTime = current.StartTime
+	}
+	w.saveStatus(status)
+
+	if err := RunJob(nmp.Job, w.Config); err != nil {
+		glog.Errorf(nmwlog(fmt.Sprintf("the %v job of node management policy %v failed, error %v", nmp.Job.Type, name, err)))
+		status.Status = persistence.NMP_STATUS_FAILED
+		status.ErrorMessage = err.Error()
+	} else {
+		glog.V(3).Infof(nmwlog(fmt.Sprintf("the %v job of node management policy %v is done", nmp.Job.Type, name)))
+		status.Status = persistence.NMP_STATUS_SUCCESSFUL
+	}
+	status.EndTime = time.Now().UTC().Format(time.RFC3339)
+	w.saveStatus(status)
+}
+
+// Save the status in the local db, and in the exchange unless the status in the exchange belongs to the agbots.
+func (w *NodeManagementWorker) saveLocalStatus(status *persistence.NMPStatus, localOnly bool) {
+	if !localOnly {
+		w.saveStatus(status)
+	} else if err := persistence.SaveNMPStatus(w.db, status); err != nil {
+		glog.Errorf(nmwlog(fmt.Sprintf("unable to save the status of node management policy %v, error %v", status.PolicyName, err)))
+	}
+}
+
+// Save the status in the local db and in the exchange, so that it can be seen from the management hub.
+func (w *NodeManagementWorker) saveStatus(status *persistence.NMPStatus) {
+	if err := persistence.SaveNMPStatus(w.db, status); err != nil {
+		glog.Errorf(nmwlog(fmt.Sprintf("unable to save the status of node management policy %v, error %v", status.PolicyName, err)))
+	}
+
+	exStatus := &exchange.NodeManagementPolicyStatus{Status: status.Status, StartTime: status.StartTime, EndTime: status.EndTime, ErrorMessage: status.ErrorMessage}
+	if err := exchange.PutNodeManagementPolicyStatus(w, w.GetExchangeId(), status.PolicyName, exStatus); err != nil {
+		glog.Errorf(nmwlog(fmt.Sprintf("unable to save the status of node management policy %v in the exchange, error %v", status.PolicyName, err)))
+	}
+}
+
+// Utility logging function
+var nmwlog = func(v interface{}) string {
+	return fmt.Sprintf("Node Management Worker: %v", v)
+}
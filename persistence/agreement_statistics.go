@@ -0,0 +1,180 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/cutil"
+	"time"
+)
+
+// The ways that established agreement statistics can be grouped.
+const EA_STATS_GROUP_BY_STATE = "state"
+const EA_STATS_GROUP_BY_PROTOCOL = "protocol"
+const EA_STATS_GROUP_BY_WORKLOAD = "workload"
+const EA_STATS_GROUP_BY_AGBOT = "agbot"
+
+// The lifecycle states that an established agreement is counted in.
+const EA_STATE_PROPOSED = "proposed"       // the proposal was received, the agreement has not been accepted yet
+const EA_STATE_ACCEPTED = "accepted"       // the proposal was accepted, the workload is not running yet
+const EA_STATE_EXECUTING = "executing"     // the workload is running
+const EA_STATE_TERMINATING = "terminating" // the agreement was terminated but is not archived yet
+const EA_STATE_ARCHIVED = "archived"       // the agreement has ended
+
+func ValidEAStatisticsGroupBy(groupBy string) bool {
+	return groupBy == EA_STATS_GROUP_BY_STATE || groupBy == EA_STATS_GROUP_BY_PROTOCOL || groupBy == EA_STATS_GROUP_BY_WORKLOAD || groupBy == EA_STATS_GROUP_BY_AGBOT
+}
+
+// The aggregated counts and rates for one group of established agreements. The counts by state include all agreements
+// that existed at some point in the time window. The rates are per hour over the time window.
+type EAStatisticsGroup struct {
+	Total               int64            `json:"total"`
+	States              map[string]int64 `json:"states"`
+	Started             int64            `json:"started"`               // agreements that were created in the window
+	Executing           int64            `json:"executing"`             // agreements whose workload started in the window
+	Terminated          int64            `json:"terminated"`            // agreements that were terminated in the window
+	StartedPerHour      float64          `json:"started_per_hour"`      // rate at which agreements were created
+	TerminatedPerHour   float64          `json:"terminated_per_hour"`   // rate at which agreements were terminated
+	ExecutingRatio      float64          `json:"executing_ratio"`       // the fraction of the agreements created in the window whose workload has started
+	AvgExecutionSeconds float64          `json:"avg_execution_seconds"` // average time from creation to the workload start, for workloads started in the window
+}
+
+type EAStatistics struct {
+	GroupBy string                        `json:"group_by"`
+	Since   uint64                        `json:"since"` // the start of the time window
+	Until   uint64                        `json:"until"` // the end of the time window
+	Groups  map[string]*EAStatisticsGroup `json:"groups"`
+}
+
+func (s EAStatistics) String() string {
+	return fmt.Sprintf("GroupBy: %v, Since: %v, Until: %v, Groups: %v", s.GroupBy, s.Since, s.Until, s.Groups)
+}
+
+// Return the lifecycle state of the established agreement, used to group agreements by state.
+func EstablishedAgreementState(e *EstablishedAgreement) string {
+	if e.Archived {
+		return EA_STATE_ARCHIVED
+	} else if e.AgreementTerminatedTime != 0 {
+		return EA_STATE_TERMINATING
+	} else if e.AgreementExecutionStartTime != 0 {
+		return EA_STATE_EXECUTING
+	} else if e.AgreementAcceptedTime != 0 {
+		return EA_STATE_ACCEPTED
+	}
+	return EA_STATE_PROPOSED
+}
+
+func eaGroupKey(e *EstablishedAgreement, groupBy string) string {
+	switch groupBy {
+	case EA_STATS_GROUP_BY_PROTOCOL:
+		return e.AgreementProtocol
+	case EA_STATS_GROUP_BY_WORKLOAD:
+		return cutil.FormOrgSpecUrl(e.RunningWorkload.URL, e.RunningWorkload.Org)
+	case EA_STATS_GROUP_BY_AGBOT:
+		return e.ConsumerId
+	default:
+		return EstablishedAgreementState(e)
+	}
+}
+
+// Returns true if the established agreement existed at some point in the time window.
+func StatisticsWindowEAFilter(since uint64, until uint64) EAFilter {
+	return func(e EstablishedAgreement) bool {
+		return e.AgreementCreationTime <= until && (e.AgreementTerminatedTime == 0 || e.AgreementTerminatedTime >= since)
+	}
+}
+
+// Aggregate the established agreements in the given protocols that existed in the time window [since, until]. A since
+// of 0 means the window starts with the oldest agreement. The aggregation is the same as for the agbot agreements, so
+// that the statistics of both sides of the agreements can be compared.
+func GetEstablishedAgreementStatistics(db *bolt.DB, protocols []string, groupBy string, since uint64, until uint64) (*EAStatistics, error) {
+
+	if groupBy == "" {
+		groupBy = EA_STATS_GROUP_BY_STATE
+	} else if !ValidEAStatisticsGroupBy(groupBy) {
+		return nil, errors.New(fmt.Sprintf("unsupported agreement statistics group by %v", groupBy))
+	}
+
+	if until == 0 {
+		until = uint64(time.Now().Unix())
+	}
+	if since > until {
+		return nil, errors.New(fmt.Sprintf("the start of the agreement statistics time window %v is after the end %v", since, until))
+	}
+
+	stats := &EAStatistics{
+		GroupBy: groupBy,
+		Since:   since,
+		Until:   until,
+		Groups:  make(map[string]*EAStatisticsGroup),
+	}
+
+	all := make([]EstablishedAgreement, 0)
+	for _, protocol := range protocols {
+		if eas, err := FindEstablishedAgreements(db, protocol, []EAFilter{StatisticsWindowEAFilter(since, until)}); err != nil {
+			return nil, err
+		} else {
+			all = append(all, eas...)
+		}
+	}
+
+	// When the window is all time, it starts with the oldest agreement so that the rates are meaningful.
+	if since == 0 {
+		stats.Since = until
+		for _, ea := range all {
+			if ea.AgreementCreationTime < stats.Since {
+				stats.Since = ea.AgreementCreationTime
+			}
+		}
+	}
+
+	inWindow := func(t uint64) bool { return t != 0 && t >= stats.Since && t <= stats.Until }
+	executionSeconds := make(map[string]uint64)
+	startedAndExecuting := make(map[string]int64)
+
+	for _, ea := range all {
+		key := eaGroupKey(&ea, groupBy)
+		group, ok := stats.Groups[key]
+		if !ok {
+			group = &EAStatisticsGroup{States: make(map[string]int64)}
+			stats.Groups[key] = group
+		}
+
+		group.Total += 1
+		group.States[EstablishedAgreementState(&ea)] += 1
+		if inWindow(ea.AgreementCreationTime) {
+			group.Started += 1
+			if ea.AgreementExecutionStartTime != 0 {
+				startedAndExecuting[key] += 1
+			}
+		}
+		if inWindow(ea.AgreementExecutionStartTime) {
+			group.Executing += 1
+			if ea.AgreementExecutionStartTime >= ea.AgreementCreationTime {
+				executionSeconds[key] += ea.AgreementExecutionStartTime - ea.AgreementCreationTime
+			}
+		}
+		if inWindow(ea.AgreementTerminatedTime) {
+			group.Terminated += 1
+		}
+	}
+
+	// Compute the rates now that all the counts are known. A window shorter than a second is treated as one second.
+	hours := float64(stats.Until-stats.Since) / 3600
+	if stats.Until == stats.Since {
+		hours = float64(1) / 3600
+	}
+
+	for key, group := range stats.Groups {
+		group.StartedPerHour = float64(group.Started) / hours
+		group.TerminatedPerHour = float64(group.Terminated) / hours
+		if group.Started != 0 {
+			group.ExecutingRatio = float64(startedAndExecuting[key]) / float64(group.Started)
+		}
+		if group.Executing != 0 {
+			group.AvgExecutionSeconds = float64(executionSeconds[key]) / float64(group.Executing)
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,57 @@
+// +build unit
+
+package persistence
+
+import (
+	"testing"
+)
+
+func Test_GetEstablishedAgreementStatistics(t *testing.T) {
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanTestDir(dir)
+	defer db.Close()
+
+	wi1, _ := NewWorkloadInfo("netspeed", "myorg", "1.0.0", "")
+	wi2, _ := NewWorkloadInfo("gps", "myorg", "1.0.0", "")
+	for _, id := range []string{"a1", "a2", "a3"} {
+		wi := wi1
+		if id == "a3" {
+			wi = wi2
+		}
+		if _, err := NewEstablishedAgreement(db, id, id, "agbot1", "proposal", "Basic", 1, []ServiceSpec{}, "signature", "address", "", "", "", wi); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := AgreementStateExecutionStarted(db, "a1", "Basic"); err != nil {
+		t.Fatal(err)
+	} else if _, err := AgreementStateAccepted(db, "a2", "Basic"); err != nil {
+		t.Fatal(err)
+	} else if _, err := AgreementStateTerminated(db, "a3", 1, "cancelled", "Basic"); err != nil {
+		t.Fatal(err)
+	} else if _, err := ArchiveEstablishedAgreement(db, "a3", "Basic"); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats, err := GetEstablishedAgreementStatistics(db, []string{"Basic"}, "", 0, 0); err != nil {
+		t.Fatal(err)
+	} else if group, ok := stats.Groups[EA_STATE_EXECUTING]; !ok || group.Total != 1 || group.Executing != 1 || group.ExecutingRatio != 1 {
+		t.Errorf("unexpected executing statistics %v", stats)
+	} else if group, ok := stats.Groups[EA_STATE_ACCEPTED]; !ok || group.Total != 1 {
+		t.Errorf("unexpected accepted statistics %v", stats)
+	} else if group, ok := stats.Groups[EA_STATE_ARCHIVED]; !ok || group.Total != 1 || group.Terminated != 1 {
+		t.Errorf("unexpected archived statistics %v", stats)
+	}
+
+	if stats, err := GetEstablishedAgreementStatistics(db, []string{"Basic"}, EA_STATS_GROUP_BY_WORKLOAD, 0, 0); err != nil {
+		t.Fatal(err)
+	} else if len(stats.Groups) != 2 || stats.Groups["myorg/netspeed"].Total != 2 || stats.Groups["myorg/gps"].States[EA_STATE_ARCHIVED] != 1 {
+		t.Errorf("unexpected workload statistics %v", stats)
+	}
+
+	if _, err := GetEstablishedAgreementStatistics(db, []string{"Basic"}, "color", 0, 0); err == nil {
+		t.Errorf("expected an error for an unsupported group by")
+	}
+}
@@ -176,6 +176,17 @@ func (e *ExchangeDevice) SetPattern(db *bolt.DB, deviceId string, pattern string
 	})
 }
 
+func (e *ExchangeDevice) SetName(db *bolt.DB, deviceId string, name string) (*ExchangeDevice, error) {
+	if deviceId == "" || name == "" {
+		return nil, errors.New("The argument deviceId or name cannot be empty.")
+	}
+
+	return updateExchangeDevice(db, e, deviceId, false, func(d ExchangeDevice) *ExchangeDevice {
+		d.Name = name
+		return &d
+	})
+}
+
 func (e *ExchangeDevice) IsState(state string) bool {
 	return e.Config.State == state
 }
@@ -236,6 +247,11 @@ func updateExchangeDevice(db *bolt.DB, self *ExchangeDevice, deviceId string, in
 				mod.Pattern = update.Pattern
 			}
 
+			// Update the name
+			if mod.Name != update.Name {
+				mod.Name = update.Name
+			}
+
 			// note: DEVICES is used as the key b/c we only want to store one value in this bucket
 
 			if serialized, err := json.Marshal(mod); err != nil {
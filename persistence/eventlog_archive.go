@@ -0,0 +1,133 @@
+package persistence
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// The event logs that are removed from the database by ArchiveEventLogs are written to gzip compressed files with one
+// JSON event log record per line, so that the event history is not lost when the database is kept small.
+const EVENT_LOG_ARCHIVE_PREFIX = "eventlog-"
+const EVENT_LOG_ARCHIVE_SUFFIX = ".json.gz"
+
+type archivedEventLog struct {
+	id        uint64
+	timestamp uint64
+	record    []byte
+}
+
+// Remove the oldest event logs when there are more than maxRecords of them, and the event logs older than maxAge.
+// A zero maxRecords or maxAge means no limit. The event logs of the errors that are currently surfaced to the exchange
+// are kept. When archiveDir is set, the removed records are written to a new archive file in it before they are removed.
+// Returns the number of event logs removed.
+func ArchiveEventLogs(db *bolt.DB, maxRecords int, maxAge time.Duration, archiveDir string) (int, error) {
+
+	// The surfaced errors refer to their event log records, so they are not removed.
+	keep := make(map[string]bool)
+	if surfaced, err := FindSurfaceErrors(db); err != nil {
+		return 0, fmt.Errorf("Unable to read the surfaced errors. Error: %v", err)
+	} else {
+		for _, se := range surfaced {
+			keep[se.Record_id] = true
+		}
+	}
+
+	removed := 0
+	err := db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(EVENT_LOGS))
+		if b == nil {
+			return nil
+		}
+
+		// The keys are sequence numbers, so they are sorted numerically to find the oldest records.
+		all := make([]archivedEventLog, 0)
+		if err := b.ForEach(func(k, v []byte) error {
+			id, err := strconv.ParseUint(string(k), 10, 64)
+			if err != nil {
+				return nil
+			}
+			var base EventLogBase
+			if err := json.Unmarshal(v, &base); err != nil {
+				return nil
+			}
+			all = append(all, archivedEventLog{id: id, timestamp: base.Timestamp, record: append([]byte{}, v...)})
+			return nil
+		}); err != nil {
+			return err
+		}
+		sort.Slice(all, func(i, j int) bool { return all[i].id < all[j].id })
+
+		cutoff := uint64(0)
+		if maxAge != 0 {
+			cutoff = uint64(time.Now().Add(-maxAge).Unix())
+		}
+
+		excess := 0
+		if maxRecords != 0 && len(all) > maxRecords {
+			excess = len(all) - maxRecords
+		}
+
+		expired := make([]archivedEventLog, 0)
+		for _, el := range all {
+			if keep[strconv.FormatUint(el.id, 10)] {
+				continue
+			} else if excess > 0 || el.timestamp < cutoff {
+				expired = append(expired, el)
+				excess -= 1
+			}
+		}
+		if len(expired) == 0 {
+			return nil
+		}
+
+		if archiveDir != "" {
+			if err := writeEventLogArchive(archiveDir, expired); err != nil {
+				return err
+			}
+		}
+
+		for _, el := range expired {
+			if err := b.Delete([]byte(strconv.FormatUint(el.id, 10))); err != nil {
+				return err
+			}
+		}
+		removed = len(expired)
+		return nil
+	})
+
+	return removed, err
+}
+
+func writeEventLogArchive(archiveDir string, records []archivedEventLog) error {
+	if err := os.MkdirAll(archiveDir, 0700); err != nil {
+		return fmt.Errorf("Unable to create event log archive directory %v. Error: %v", archiveDir, err)
+	}
+
+	name := path.Join(archiveDir, fmt.Sprintf("%v%v-%v%v", EVENT_LOG_ARCHIVE_PREFIX, records[0].id, records[len(records)-1].id, EVENT_LOG_ARCHIVE_SUFFIX))
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("Unable to create event log archive %v. Error: %v", name, err)
+	}
+
+	zw := gzip.NewWriter(f)
+	for _, el := range records {
+		if _, err := zw.Write(append(el.record, '\n')); err != nil {
+			f.Close()
+			os.Remove(name)
+			return fmt.Errorf("Unable to write event log archive %v. Error: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		os.Remove(name)
+		return fmt.Errorf("Unable to write event log archive %v. Error: %v", name, err)
+	}
+	return f.Close()
+}
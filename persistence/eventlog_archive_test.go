@@ -0,0 +1,84 @@
+// +build unit
+
+package persistence
+
+import (
+	"bufio"
+	"compress/gzip"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_ArchiveEventLogs(t *testing.T) {
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanTestDir(dir)
+	defer db.Close()
+
+	// 10 event logs, the first 3 are 10 days old
+	for i := 0; i < 10; i++ {
+		el := NewEventLog(SEVERITY_INFO, NewMessageMeta("test message %v", i), EC_DATABASE_ERROR, SRC_TYPE_DB, NewDatabaseEventSource())
+		if i < 3 {
+			el.Timestamp = uint64(time.Now().AddDate(0, 0, -10).Unix())
+		}
+		if err := SaveEventLog(db, el); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The second event log is surfaced to the exchange, it is kept.
+	if err := SaveSurfaceErrors(db, []SurfaceError{{Record_id: "2"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	archiveDir := path.Join(dir, "archive")
+	if removed, err := ArchiveEventLogs(db, 0, 7*24*time.Hour, archiveDir); err != nil {
+		t.Errorf("unexpected error archiving: %v", err)
+	} else if removed != 2 {
+		t.Errorf("expected 2 event logs older than 7 days to be archived, got %v", removed)
+	}
+
+	// Keep at most 5, the surfaced error is kept.
+	if removed, err := ArchiveEventLogs(db, 5, 0, archiveDir); err != nil {
+		t.Errorf("unexpected error archiving: %v", err)
+	} else if removed != 3 {
+		t.Errorf("expected 3 event logs to be archived, got %v", removed)
+	}
+
+	if els, err := FindAllEventLogs(db); err != nil {
+		t.Fatal(err)
+	} else if len(els) != 5 {
+		t.Errorf("expected 5 event logs left, got %v", len(els))
+	} else if _, err := FindEventLogWithKey(db, "2"); err != nil {
+		t.Errorf("expected the surfaced event log to be kept: %v", err)
+	}
+
+	// Every archived record is in an archive file.
+	files, _ := filepath.Glob(path.Join(archiveDir, EVENT_LOG_ARCHIVE_PREFIX+"*"+EVENT_LOG_ARCHIVE_SUFFIX))
+	if len(files) != 2 {
+		t.Fatalf("expected 2 archive files, got %v", files)
+	}
+	archived := 0
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for scanner := bufio.NewScanner(zr); scanner.Scan(); {
+			archived += 1
+		}
+		f.Close()
+	}
+	if archived != 5 {
+		t.Errorf("expected 5 archived records, got %v", archived)
+	}
+}
@@ -0,0 +1,179 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"strconv"
+	"strings"
+)
+
+// The schema version of each kind of record that has migrations is kept in this bucket, so that an anax runtime
+// upgraded over an existing database only runs the migrations that have not been run yet.
+const SCHEMA_VERSIONS = "schema_versions"
+
+const ESTABLISHED_AGREEMENTS_SCHEMA = "established_agreements"
+
+// A migration of the established agreements, run in the same transaction for every agreement protocol bucket.
+// It returns the number of records that it changed.
+type agreementMigration func(tx *bolt.Tx, protocol string) (int, error)
+
+// The established agreement migrations, in the order they are run. Migration n brings the schema to version n+1.
+// New migrations must be appended.
+var establishedAgreementMigrations = []agreementMigration{
+	migrateAgreementSensorUrls,
+	migrateArchivedAgreements,
+}
+
+// Bring the established agreements in the database to the current schema version. This is called when anax starts,
+// before any of the workers read the agreements. Each migration runs in its own transaction, so a migration that
+// fails is retried from the start the next time anax starts. The established agreements are only kept in bolt, there is
+// no other database to migrate.
+func MigrateEstablishedAgreements(db *bolt.DB) error {
+	if db == nil {
+		return fmt.Errorf("Unable to migrate the established agreements, there is no database")
+	}
+
+	version, err := getSchemaVersion(db, ESTABLISHED_AGREEMENTS_SCHEMA)
+	if err != nil {
+		return err
+	}
+
+	for ; version < len(establishedAgreementMigrations); version++ {
+		migration := establishedAgreementMigrations[version]
+		changed := 0
+
+		err := db.Update(func(tx *bolt.Tx) error {
+			for _, protocol := range establishedAgreementProtocols(tx) {
+				if n, err := migration(tx, protocol); err != nil {
+					return err
+				} else {
+					changed += n
+				}
+			}
+			return setSchemaVersion(tx, ESTABLISHED_AGREEMENTS_SCHEMA, version+1)
+		})
+
+		if err != nil {
+			return fmt.Errorf("Unable to migrate the established agreements to schema version %v. Error: %v", version+1, err)
+		}
+		glog.V(3).Infof("Migrated %v established agreements to schema version %v", changed, version+1)
+	}
+
+	return nil
+}
+
+func getSchemaVersion(db *bolt.DB, name string) (int, error) {
+	version := 0
+	err := db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(SCHEMA_VERSIONS)); b != nil {
+			if v := b.Get([]byte(name)); v != nil {
+				if n, err := strconv.Atoi(string(v)); err != nil {
+					return fmt.Errorf("Invalid schema version %v for %v. Error: %v", string(v), name, err)
+				} else {
+					version = n
+				}
+			}
+		}
+		return nil
+	})
+	return version, err
+}
+
+func setSchemaVersion(tx *bolt.Tx, name string, version int) error {
+	if b, err := tx.CreateBucketIfNotExists([]byte(SCHEMA_VERSIONS)); err != nil {
+		return err
+	} else {
+		return b.Put([]byte(name), []byte(strconv.Itoa(version)))
+	}
+}
+
+// The agreement protocols that have an established agreement bucket in the database.
+func establishedAgreementProtocols(tx *bolt.Tx) []string {
+	protocols := make([]string, 0)
+	prefix := E_AGREEMENTS + "-"
+	tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		if strings.HasPrefix(string(name), prefix) {
+			protocols = append(protocols, strings.TrimPrefix(string(name), prefix))
+		}
+		return nil
+	})
+	return protocols
+}
+
+// The agreements written before the dependent services were introduced have a list of sensor urls. They are converted
+// each time they are read, this migration writes the converted record so that the conversion is done only once.
+func migrateAgreementSensorUrls(tx *bolt.Tx, protocol string) (int, error) {
+	b := tx.Bucket([]byte(E_AGREEMENTS + "-" + protocol))
+	if b == nil {
+		return 0, nil
+	}
+
+	converted := make(map[string][]byte)
+	if err := b.ForEach(func(k, v []byte) error {
+		var raw map[string]interface{}
+		if err := json.Unmarshal(v, &raw); err != nil {
+			glog.Errorf("Unable to deserialize db record to EstablishedAgreement: %v", string(v))
+			return nil
+		} else if _, ok := raw["sensor_url"]; !ok {
+			return nil
+		}
+
+		if e, err := unmarshalEstablishedAgreement(v); err != nil {
+			glog.Errorf("Unable to deserialize db record to EstablishedAgreement: %v", string(v))
+		} else if serialized, err := json.Marshal(e); err != nil {
+			return fmt.Errorf("Failed to serialize agreement record: %v. Error: %v", *e, err)
+		} else {
+			converted[string(k)] = serialized
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	// The records are written after the iteration, a bucket must not be changed while it is iterated.
+	for k, v := range converted {
+		if err := b.Put([]byte(k), v); err != nil {
+			return 0, fmt.Errorf("Failed to write agreement record with key: %v. Error: %v", k, err)
+		}
+	}
+	return len(converted), nil
+}
+
+// The agreements archived before the archive buckets were introduced are moved to the archive bucket.
+func migrateArchivedAgreements(tx *bolt.Tx, protocol string) (int, error) {
+	b := tx.Bucket([]byte(E_AGREEMENTS + "-" + protocol))
+	if b == nil {
+		return 0, nil
+	}
+
+	archived := make(map[string][]byte)
+	if err := b.ForEach(func(k, v []byte) error {
+		if e, err := unmarshalEstablishedAgreement(v); err != nil {
+			glog.Errorf("Unable to deserialize db record to EstablishedAgreement: %v", string(v))
+		} else if e.Archived {
+			archived[string(k)] = append([]byte{}, v...)
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	if len(archived) == 0 {
+		return 0, nil
+	}
+
+	archive, err := tx.CreateBucketIfNotExists([]byte(E_AGREEMENTS_ARCHIVE + "-" + protocol))
+	if err != nil {
+		return 0, err
+	}
+	for k, v := range archived {
+		if err := archive.Put([]byte(k), v); err != nil {
+			return 0, fmt.Errorf("Failed to write archived agreement record with key: %v. Error: %v", k, err)
+		} else if err := b.Delete([]byte(k)); err != nil {
+			return 0, fmt.Errorf("Failed to remove archived agreement record with key: %v. Error: %v", k, err)
+		}
+	}
+	return len(archived), nil
+}
@@ -0,0 +1,132 @@
+// +build unit
+
+package persistence
+
+import (
+	"encoding/json"
+	"github.com/boltdb/bolt"
+	"testing"
+)
+
+func Test_ArchiveEstablishedAgreement_bucket(t *testing.T) {
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanTestDir(dir)
+	defer db.Close()
+
+	wi, _ := NewWorkloadInfo("myurl", "myorg", "1.0.0", "")
+	if _, err := NewEstablishedAgreement(db, "ag1", "a1", "agbot1", "proposal", "Basic", 1, []ServiceSpec{}, "signature", "address", "", "", "", wi); err != nil {
+		t.Fatal(err)
+	} else if _, err := ArchiveEstablishedAgreement(db, "a1", "Basic"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The archived agreement is only in the archive bucket, and it is still found.
+	db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(E_AGREEMENTS+"-Basic")).Get([]byte("a1")) != nil {
+			t.Errorf("expected the archived agreement to be removed from the active bucket")
+		} else if b := tx.Bucket([]byte(E_AGREEMENTS_ARCHIVE + "-Basic")); b == nil || b.Get([]byte("a1")) == nil {
+			t.Errorf("expected the archived agreement to be in the archive bucket")
+		}
+		return nil
+	})
+
+	if eas, err := FindEstablishedAgreements(db, "Basic", []EAFilter{}); err != nil {
+		t.Fatal(err)
+	} else if len(eas) != 1 || !eas[0].Archived {
+		t.Errorf("expected to find the archived agreement, got %v", eas)
+	}
+
+	// The archive bucket is not read for the active agreements. A record that is not marked archived is put in the
+	// archive bucket to tell whether it is read.
+	db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(E_AGREEMENTS_ARCHIVE+"-Basic")).Put([]byte("a2"), []byte(`{"current_agreement_id":"a2","archived":false}`))
+	})
+	if eas, err := FindActiveEstablishedAgreements(db, "Basic", []EAFilter{}); err != nil {
+		t.Fatal(err)
+	} else if len(eas) != 0 {
+		t.Errorf("expected the archive bucket not to be read, got %v", eas)
+	}
+	if eas, err := FindEstablishedAgreements(db, "Basic", []EAFilter{UnarchivedEAFilter(), IdEAFilter("a2")}); err != nil {
+		t.Fatal(err)
+	} else if len(eas) != 1 {
+		t.Errorf("expected the archive bucket to be read by FindEstablishedAgreements, got %v", eas)
+	}
+
+	// An agreement with the same id can be made again and archived again.
+	if _, err := NewEstablishedAgreement(db, "ag1", "a1", "agbot1", "proposal", "Basic", 1, []ServiceSpec{}, "signature", "address", "", "", "", wi); err != nil {
+		t.Errorf("unexpected error making an agreement with an archived id: %v", err)
+	} else if _, err := ArchiveEstablishedAgreement(db, "a1", "Basic"); err != nil {
+		t.Errorf("unexpected error archiving: %v", err)
+	} else if _, err := ArchiveEstablishedAgreement(db, "a1", "Basic"); err == nil {
+		t.Errorf("expected an error archiving an agreement that is already archived")
+	}
+}
+
+func Test_MigrateEstablishedAgreements(t *testing.T) {
+	if err := MigrateEstablishedAgreements(nil); err == nil {
+		t.Errorf("expected an error migrating without a database")
+	}
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanTestDir(dir)
+	defer db.Close()
+
+	// An agreement with the old structure, an archived agreement in the active bucket and an active agreement.
+	wi, _ := NewWorkloadInfo("myurl", "myorg", "1.0.0", "")
+	if _, err := NewEstablishedAgreement_Old(db, "old", "a1", "agbot1", "proposal", "Basic", 1, []string{"url1"}, "signature", "address", "", "", "", wi); err != nil {
+		t.Fatal(err)
+	}
+	archived := EstablishedAgreement{Name: "archived", CurrentAgreementId: "a2", Archived: true, AgreementProtocol: "Basic", DependentServices: []ServiceSpec{}}
+	db.Update(func(tx *bolt.Tx) error {
+		b, _ := tx.CreateBucketIfNotExists([]byte(E_AGREEMENTS + "-Basic"))
+		serialized, _ := json.Marshal(archived)
+		return b.Put([]byte("a2"), serialized)
+	})
+	if _, err := NewEstablishedAgreement(db, "active", "a3", "agbot1", "proposal", "Basic", 1, []ServiceSpec{}, "signature", "address", "", "", "", wi); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := MigrateEstablishedAgreements(db); err != nil {
+		t.Fatalf("unexpected error migrating: %v", err)
+	} else if version, err := getSchemaVersion(db, ESTABLISHED_AGREEMENTS_SCHEMA); err != nil || version != len(establishedAgreementMigrations) {
+		t.Errorf("expected schema version %v, got %v %v", len(establishedAgreementMigrations), version, err)
+	}
+
+	db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(E_AGREEMENTS + "-Basic"))
+		var raw map[string]interface{}
+		if v := b.Get([]byte("a1")); v == nil {
+			t.Errorf("expected the old agreement to be in the active bucket")
+		} else if err := json.Unmarshal(v, &raw); err != nil {
+			t.Error(err)
+		} else if _, ok := raw["sensor_url"]; ok {
+			t.Errorf("expected the old agreement to be converted, got %v", string(v))
+		}
+		if b.Get([]byte("a2")) != nil || tx.Bucket([]byte(E_AGREEMENTS_ARCHIVE+"-Basic")).Get([]byte("a2")) == nil {
+			t.Errorf("expected the archived agreement to be moved to the archive bucket")
+		}
+		if b.Get([]byte("a3")) == nil {
+			t.Errorf("expected the active agreement to be in the active bucket")
+		}
+		return nil
+	})
+
+	if ea, err := findActiveEstablishedAgreement(db, "a1", "Basic"); err != nil || ea == nil {
+		t.Errorf("expected to find the old agreement, got %v %v", ea, err)
+	} else if len(ea.DependentServices) != 1 || ea.DependentServices[0].Url != "url1" {
+		t.Errorf("expected the sensor url to be converted to a dependent service, got %v", ea.DependentServices)
+	}
+
+	// Running the migrations again changes nothing.
+	if err := MigrateEstablishedAgreements(db); err != nil {
+		t.Errorf("unexpected error migrating again: %v", err)
+	} else if eas, err := FindEstablishedAgreements(db, "Basic", []EAFilter{}); err != nil || len(eas) != 3 {
+		t.Errorf("expected 3 agreements, got %v %v", eas, err)
+	}
+}
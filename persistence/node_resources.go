@@ -0,0 +1,60 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/cutil"
+)
+
+const NODE_RESOURCES = "node_resources"
+
+// NodeResources is the last detected hardware capacity of the node.
+type NodeResources struct {
+	cutil.NodeResources
+}
+
+func (r NodeResources) String() string {
+	return r.NodeResources.String()
+}
+
+// FindNodeResources returns the node resources in the local db, nil when they have not been detected yet.
+func FindNodeResources(db *bolt.DB) (*NodeResources, error) {
+	var resources *NodeResources
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(NODE_RESOURCES)); b != nil {
+			if v := b.Get([]byte(NODE_RESOURCES)); v != nil {
+				resources = new(NodeResources)
+				if err := json.Unmarshal(v, resources); err != nil {
+					return fmt.Errorf("Unable to deserialize node resources record: %v", v)
+				}
+			}
+		}
+
+		return nil // end transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return resources, nil
+}
+
+// SaveNodeResources saves the provided node resources to the local db
+func SaveNodeResources(db *bolt.DB, resources *NodeResources) error {
+	writeErr := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(NODE_RESOURCES))
+		if err != nil {
+			return err
+		}
+
+		if serial, err := json.Marshal(resources); err != nil {
+			return fmt.Errorf("Failed to serialize node resources: %v. Error: %v", resources, err)
+		} else {
+			return b.Put([]byte(NODE_RESOURCES), serial)
+		}
+	})
+
+	return writeErr
+}
@@ -0,0 +1,100 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+)
+
+const NODE_MANAGEMENT_STATUS = "node_management_status"
+
+// The states of a node management policy job on this node.
+const (
+	NMP_STATUS_WAITING    = "waiting"    // the start time of the policy has not come yet
+	NMP_STATUS_RUNNING    = "running"    // the job has started
+	NMP_STATUS_SUCCESSFUL = "successful" // the job is done
+	NMP_STATUS_FAILED     = "failed"     // the job failed, it is not run again until the policy is changed
+)
+
+// The status of the job of a node management policy on this node. The LastUpdated time of the policy is kept with the
+// status so that the job runs again when the policy changes.
+type NMPStatus struct {
+	PolicyName        string `json:"policyName"` // org/name
+	JobType           string `json:"jobType"`
+	Status            string `json:"status"`
+	StartTime         string `json:"startTime,omitempty"`
+	EndTime           string `json:"endTime,omitempty"`
+	ErrorMessage      string `json:"errorMessage,omitempty"`
+	PolicyLastUpdated string `json:"policyLastUpdated"`
+}
+
+func (s NMPStatus) String() string {
+	return fmt.Sprintf("PolicyName: %v, JobType: %v, Status: %v, StartTime: %v, EndTime: %v, ErrorMessage: %v, PolicyLastUpdated: %v",
+		s.PolicyName, s.JobType, s.Status, s.StartTime, s.EndTime, s.ErrorMessage, s.PolicyLastUpdated)
+}
+
+// Returns true if the job is over, either successful or failed.
+func (s NMPStatus) IsFinished() bool {
+	return s.Status == NMP_STATUS_SUCCESSFUL || s.Status == NMP_STATUS_FAILED
+}
+
+// FindNMPStatuses returns the status of the node management policy jobs of this node, keyed by policy name.
+func FindNMPStatuses(db *bolt.DB) (map[string]NMPStatus, error) {
+	statuses := make(map[string]NMPStatus)
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(NODE_MANAGEMENT_STATUS)); b != nil {
+			return b.ForEach(func(k, v []byte) error {
+				var s NMPStatus
+				if err := json.Unmarshal(v, &s); err != nil {
+					return fmt.Errorf("Unable to deserialize node management status record: %v", v)
+				}
+				statuses[string(k)] = s
+				return nil
+			})
+		}
+
+		return nil // end transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return statuses, nil
+}
+
+// SaveNMPStatus saves the status of the job of a node management policy, replacing the previous status of the policy.
+func SaveNMPStatus(db *bolt.DB, status *NMPStatus) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(NODE_MANAGEMENT_STATUS))
+		if err != nil {
+			return err
+		}
+
+		if serial, err := json.Marshal(status); err != nil {
+			return fmt.Errorf("Failed to serialize node management status: %v. Error: %v", status, err)
+		} else {
+			return b.Put([]byte(status.PolicyName), serial)
+		}
+	})
+}
+
+// DeleteNMPStatus removes the status of a node management policy that no longer applies to this node.
+func DeleteNMPStatus(db *bolt.DB, policyName string) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(NODE_MANAGEMENT_STATUS)); b != nil {
+			return b.Delete([]byte(policyName))
+		}
+		return nil
+	})
+}
+
+// DeleteAllNMPStatuses removes the status of all the node management policies, when the node is unregistered.
+func DeleteAllNMPStatuses(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(NODE_MANAGEMENT_STATUS)); b != nil {
+			return tx.DeleteBucket([]byte(NODE_MANAGEMENT_STATUS))
+		}
+		return nil
+	})
+}
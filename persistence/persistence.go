@@ -13,6 +13,10 @@ import (
 // ephemeral as of v2.1.0
 const E_AGREEMENTS = "established_agreements" // may or may not be in agreements
 
+// Archived agreements are moved to their own buckets, so that the lookups of the active agreements do not have to
+// read the archived ones.
+const E_AGREEMENTS_ARCHIVE = "established_agreements_archive"
+
 const DEVMODE = "devmode"
 
 var RemoveDatabaseOnExit bool
@@ -130,14 +134,10 @@ func NewEstablishedAgreement(db *bolt.DB, name string, agreementId string, consu
 		return nil, errors.New("Agreement id, consumer id, proposal, protocol, or protocol version are empty, cannot persist")
 	}
 
-	var filters []EAFilter
-	filters = append(filters, UnarchivedEAFilter())
-	filters = append(filters, IdEAFilter(agreementId))
-
-	if agreements, err := FindEstablishedAgreements(db, protocol, filters); err != nil {
+	if existing, err := findActiveEstablishedAgreement(db, agreementId, protocol); err != nil {
 		return nil, err
-	} else if len(agreements) != 0 {
-		return nil, fmt.Errorf("Not expecting any records with id: %v, found %v", agreementId, agreements)
+	} else if existing != nil {
+		return nil, fmt.Errorf("Not expecting any records with id: %v, found %v", agreementId, *existing)
 	}
 
 	newAg := &EstablishedAgreement{
@@ -343,14 +343,10 @@ func DeleteEstablishedAgreement(db *bolt.DB, agreementId string, protocol string
 		return errors.New("Agreement id empty, cannot remove")
 	} else {
 
-		filters := make([]EAFilter, 0)
-		filters = append(filters, UnarchivedEAFilter())
-		filters = append(filters, IdEAFilter(agreementId))
-
-		if agreements, err := FindEstablishedAgreements(db, protocol, filters); err != nil {
+		if agreement, err := findActiveEstablishedAgreement(db, agreementId, protocol); err != nil {
 			return err
-		} else if len(agreements) != 1 {
-			return fmt.Errorf("Expecting 1 records with id: %v, found %v", agreementId, agreements)
+		} else if agreement == nil {
+			return fmt.Errorf("Expecting 1 records with id: %v, found none", agreementId)
 		} else {
 
 			return db.Update(func(tx *bolt.Tx) error {
@@ -368,19 +364,13 @@ func DeleteEstablishedAgreement(db *bolt.DB, agreementId string, protocol string
 }
 
 func agreementStateUpdate(db *bolt.DB, dbAgreementId string, protocol string, fn func(EstablishedAgreement) *EstablishedAgreement) (*EstablishedAgreement, error) {
-	filters := make([]EAFilter, 0)
-	filters = append(filters, UnarchivedEAFilter())
-	filters = append(filters, IdEAFilter(dbAgreementId))
-
-	if agreements, err := FindEstablishedAgreements(db, protocol, filters); err != nil {
+	if agreement, err := findActiveEstablishedAgreement(db, dbAgreementId, protocol); err != nil {
 		return nil, err
-	} else if len(agreements) > 1 {
-		return nil, fmt.Errorf("Expected only one record for dbAgreementId: %v, but retrieved: %v", dbAgreementId, agreements)
-	} else if len(agreements) == 0 {
+	} else if agreement == nil {
 		return nil, fmt.Errorf("No record with id: %v", dbAgreementId)
 	} else {
 		// run this single contract through provided update function and persist it
-		updated := fn(agreements[0])
+		updated := fn(*agreement)
 		return updated, persistUpdatedAgreement(db, dbAgreementId, protocol, updated)
 	}
 }
@@ -465,9 +455,21 @@ func persistUpdatedAgreement(db *bolt.DB, dbAgreementId string, protocol string,
 					mod.ProposalSig = update.ProposalSig
 				}
 
+				// An archived agreement is moved to the archive bucket in the same transaction.
+				target := b
+				if mod.Archived {
+					if archive, err := tx.CreateBucketIfNotExists([]byte(E_AGREEMENTS_ARCHIVE + "-" + protocol)); err != nil {
+						return err
+					} else if err := b.Delete([]byte(dbAgreementId)); err != nil {
+						return fmt.Errorf("Failed to remove archived contract record with key: %v. Error: %v", dbAgreementId, err)
+					} else {
+						target = archive
+					}
+				}
+
 				if serialized, err := json.Marshal(mod); err != nil {
 					return fmt.Errorf("Failed to serialize contract record: %v. Error: %v", mod, err)
-				} else if err := b.Put([]byte(dbAgreementId), serialized); err != nil {
+				} else if err := target.Put([]byte(dbAgreementId), serialized); err != nil {
 					return fmt.Errorf("Failed to write contract record with key: %v. Error: %v", dbAgreementId, err)
 				} else {
 					glog.V(2).Infof("Succeeded updating agreement id record to %v", mod)
@@ -495,50 +497,44 @@ type SensorUrls struct {
 }
 
 func FindEstablishedAgreements(db *bolt.DB, protocol string, filters []EAFilter) ([]EstablishedAgreement, error) {
+	// fetch contracts, the active ones first and then the archived ones
+	return findEstablishedAgreements(db, protocol, []string{E_AGREEMENTS, E_AGREEMENTS_ARCHIVE}, filters)
+}
+
+// FindActiveEstablishedAgreements returns the unarchived agreements that pass the filters. It does not read the
+// archive bucket, so it is the one to use when the archived agreements are not wanted.
+func FindActiveEstablishedAgreements(db *bolt.DB, protocol string, filters []EAFilter) ([]EstablishedAgreement, error) {
+	return findEstablishedAgreements(db, protocol, []string{E_AGREEMENTS}, append([]EAFilter{UnarchivedEAFilter()}, filters...))
+}
+
+func findEstablishedAgreements(db *bolt.DB, protocol string, buckets []string, filters []EAFilter) ([]EstablishedAgreement, error) {
 	agreements := make([]EstablishedAgreement, 0)
 
-	// fetch contracts
 	readErr := db.View(func(tx *bolt.Tx) error {
 
-		if b := tx.Bucket([]byte(E_AGREEMENTS + "-" + protocol)); b != nil {
-			b.ForEach(func(k, v []byte) error {
-
-				var e EstablishedAgreement
+		for _, bucket := range buckets {
+			if b := tx.Bucket([]byte(bucket + "-" + protocol)); b != nil {
+				b.ForEach(func(k, v []byte) error {
 
-				if err := json.Unmarshal(v, &e); err != nil {
-					glog.Errorf("Unable to deserialize db record to EstablishedAgreement: %v", v)
-				} else {
-					// this might be agreement from the old EstablishedAgreement structure where SensorUrl was used.
-					// will convert it to new using DependentServices
-					if e.DependentServices == nil {
-						var sensor_urls SensorUrls
-						if err := json.Unmarshal(v, &sensor_urls); err != nil {
-							glog.Errorf("Unable to deserialize db record to SensorUrl: %v", v)
-						} else {
-							e.DependentServices = []ServiceSpec{}
-							if sensor_urls.SensorUrl != nil && len(sensor_urls.SensorUrl) > 0 {
-								for _, url := range sensor_urls.SensorUrl {
-									e.DependentServices = append(e.DependentServices, ServiceSpec{Url: url})
-								}
+					if e, err := unmarshalEstablishedAgreement(v); err != nil {
+						glog.Errorf("Unable to deserialize db record to EstablishedAgreement: %v", v)
+					} else {
+						if !e.Archived {
+							glog.V(5).Infof("Demarshalled agreement in DB: %v", e)
+						}
+						exclude := false
+						for _, filterFn := range filters {
+							if !filterFn(*e) {
+								exclude = true
 							}
 						}
-					}
-
-					if !e.Archived {
-						glog.V(5).Infof("Demarshalled agreement in DB: %v", e)
-					}
-					exclude := false
-					for _, filterFn := range filters {
-						if !filterFn(e) {
-							exclude = true
+						if !exclude {
+							agreements = append(agreements, *e)
 						}
 					}
-					if !exclude {
-						agreements = append(agreements, e)
-					}
-				}
-				return nil
-			})
+					return nil
+				})
+			}
 		}
 
 		return nil // end the transaction
@@ -551,10 +547,70 @@ func FindEstablishedAgreements(db *bolt.DB, protocol string, filters []EAFilter)
 	}
 }
 
+// Returns the unarchived agreement with the given id, or nil if there is none. The agreements are keyed by their id,
+// so this does not have to read the other agreements.
+func findActiveEstablishedAgreement(db *bolt.DB, agreementId string, protocol string) (*EstablishedAgreement, error) {
+	var agreement *EstablishedAgreement
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(E_AGREEMENTS + "-" + protocol)); b != nil {
+			if v := b.Get([]byte(agreementId)); v != nil {
+				if e, err := unmarshalEstablishedAgreement(v); err != nil {
+					return fmt.Errorf("Unable to deserialize db record to EstablishedAgreement: %v. Error: %v", string(v), err)
+				} else if !e.Archived {
+					agreement = e
+				}
+			}
+		}
+		return nil
+	})
+
+	return agreement, readErr
+}
+
+func unmarshalEstablishedAgreement(v []byte) (*EstablishedAgreement, error) {
+	var e EstablishedAgreement
+
+	if err := json.Unmarshal(v, &e); err != nil {
+		return nil, err
+	}
+
+	// this might be agreement from the old EstablishedAgreement structure where SensorUrl was used.
+	// will convert it to new using DependentServices
+	if e.DependentServices == nil {
+		var sensor_urls SensorUrls
+		if err := json.Unmarshal(v, &sensor_urls); err != nil {
+			glog.Errorf("Unable to deserialize db record to SensorUrl: %v", v)
+		} else {
+			e.DependentServices = []ServiceSpec{}
+			if sensor_urls.SensorUrl != nil && len(sensor_urls.SensorUrl) > 0 {
+				for _, url := range sensor_urls.SensorUrl {
+					e.DependentServices = append(e.DependentServices, ServiceSpec{Url: url})
+				}
+			}
+		}
+	}
+
+	return &e, nil
+}
+
 func FindEstablishedAgreementsAllProtocols(db *bolt.DB, protocols []string, filters []EAFilter) ([]EstablishedAgreement, error) {
+	return findAllProtocols(protocols, func(protocol string) ([]EstablishedAgreement, error) {
+		return FindEstablishedAgreements(db, protocol, filters)
+	})
+}
+
+// FindActiveEstablishedAgreementsAllProtocols is FindActiveEstablishedAgreements for all the protocols.
+func FindActiveEstablishedAgreementsAllProtocols(db *bolt.DB, protocols []string, filters []EAFilter) ([]EstablishedAgreement, error) {
+	return findAllProtocols(protocols, func(protocol string) ([]EstablishedAgreement, error) {
+		return FindActiveEstablishedAgreements(db, protocol, filters)
+	})
+}
+
+func findAllProtocols(protocols []string, find func(protocol string) ([]EstablishedAgreement, error)) ([]EstablishedAgreement, error) {
 	agreements := make([]EstablishedAgreement, 0)
 	for _, protocol := range protocols {
-		if ags, err := FindEstablishedAgreements(db, protocol, filters); err != nil {
+		if ags, err := find(protocol); err != nil {
 			return nil, err
 		} else {
 			agreements = append(agreements, ags...)
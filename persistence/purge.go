@@ -0,0 +1,97 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/cutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Remove the records of the past activity of this node, to satisfy a request to remove the data about the node: the
+// archived agreements, the event logs, the surfaced errors and the event log archive files in archiveDir. The active
+// agreements and the registration of the node are kept, they are removed when the node is unregistered. What was
+// removed is added to the report.
+func PurgeNodeRecords(db *bolt.DB, archiveDir string, report *cutil.DeletionReport) error {
+
+	err := db.Update(func(tx *bolt.Tx) error {
+
+		// The archived agreements are in their own buckets, the buckets are removed.
+		archives := make([]string, 0)
+		tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			if strings.HasPrefix(string(name), E_AGREEMENTS_ARCHIVE+"-") {
+				archives = append(archives, string(name))
+			}
+			return nil
+		})
+		for _, name := range archives {
+			if err := tx.Bucket([]byte(name)).ForEach(func(k, v []byte) error {
+				report.AgreementIds = append(report.AgreementIds, string(k))
+				report.Removed[cutil.DELETED_ARCHIVED_AGREEMENTS] += 1
+				return nil
+			}); err != nil {
+				return err
+			} else if err := tx.DeleteBucket([]byte(name)); err != nil {
+				return fmt.Errorf("Unable to delete archived agreements bucket %v. Error: %v", name, err)
+			}
+		}
+
+		// The event logs are removed one by one, so that the sequence of the event log ids is kept and the ids of the
+		// event logs written after the purge do not collide with the ids in the event log archives that are kept
+		// elsewhere.
+		if b := tx.Bucket([]byte(EVENT_LOGS)); b != nil {
+			keys := make([][]byte, 0)
+			if err := b.ForEach(func(k, v []byte) error {
+				keys = append(keys, append([]byte{}, k...))
+				return nil
+			}); err != nil {
+				return err
+			}
+			for _, k := range keys {
+				if err := b.Delete(k); err != nil {
+					return fmt.Errorf("Unable to delete event log %v. Error: %v", string(k), err)
+				}
+			}
+			report.Removed[cutil.DELETED_EVENT_LOGS] = len(keys)
+		}
+
+		// The surfaced errors refer to the event logs that were removed.
+		if b := tx.Bucket([]byte(NODE_SURFACEERR)); b != nil {
+			if v := b.Get([]byte(NODE_SURFACEERR)); v != nil {
+				var surfaceErrors []SurfaceError
+				if err := json.Unmarshal(v, &surfaceErrors); err == nil {
+					report.Removed[cutil.DELETED_SURFACE_ERRORS] = len(surfaceErrors)
+				}
+				if err := b.Delete([]byte(NODE_SURFACEERR)); err != nil {
+					return fmt.Errorf("Unable to delete node surface error object: %v", err)
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// The event log archive files are removed after the database records, so that a failure to remove the records does
+	// not lose the archives.
+	if archiveDir != "" {
+		files, err := filepath.Glob(path.Join(archiveDir, EVENT_LOG_ARCHIVE_PREFIX+"*"+EVENT_LOG_ARCHIVE_SUFFIX))
+		if err != nil {
+			return fmt.Errorf("Unable to list the event log archives in %v. Error: %v", archiveDir, err)
+		}
+		for _, file := range files {
+			if err := os.Remove(file); err != nil {
+				return fmt.Errorf("Unable to remove event log archive %v. Error: %v", file, err)
+			}
+			report.Files = append(report.Files, file)
+			report.Removed[cutil.DELETED_EVENT_LOG_ARCHIVES] += 1
+		}
+	}
+
+	return nil
+}
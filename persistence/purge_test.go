@@ -0,0 +1,83 @@
+// +build unit
+
+package persistence
+
+import (
+	"github.com/open-horizon/anax/cutil"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func Test_PurgeNodeRecords(t *testing.T) {
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanTestDir(dir)
+	defer db.Close()
+
+	// An active and an archived agreement, event logs and a surfaced error.
+	wi, _ := NewWorkloadInfo("myurl", "myorg", "1.0.0", "")
+	for _, id := range []string{"a1", "a2"} {
+		if _, err := NewEstablishedAgreement(db, id, id, "agbot1", "proposal", "Basic", 1, []ServiceSpec{}, "signature", "address", "", "", "", wi); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := ArchiveEstablishedAgreement(db, "a2", "Basic"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		el := NewEventLog(SEVERITY_INFO, NewMessageMeta("test message %v", i), EC_DATABASE_ERROR, SRC_TYPE_DB, NewDatabaseEventSource())
+		if err := SaveEventLog(db, el); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := SaveSurfaceErrors(db, []SurfaceError{{Record_id: "2"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	archiveDir := path.Join(dir, "archive")
+	os.MkdirAll(archiveDir, 0700)
+	archive := path.Join(archiveDir, EVENT_LOG_ARCHIVE_PREFIX+"1-5"+EVENT_LOG_ARCHIVE_SUFFIX)
+	if err := ioutil.WriteFile(archive, []byte{}, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	report := cutil.NewDeletionReport("myorg/node1", "myorg/node1", uint64(time.Now().Unix()))
+	if err := PurgeNodeRecords(db, archiveDir, report); err != nil {
+		t.Fatalf("unexpected error purging: %v", err)
+	}
+
+	if report.Removed[cutil.DELETED_ARCHIVED_AGREEMENTS] != 1 || len(report.AgreementIds) != 1 || report.AgreementIds[0] != "a2" {
+		t.Errorf("expected the archived agreement to be reported, got %v", report)
+	} else if report.Removed[cutil.DELETED_EVENT_LOGS] != 3 || report.Removed[cutil.DELETED_SURFACE_ERRORS] != 1 || report.Removed[cutil.DELETED_EVENT_LOG_ARCHIVES] != 1 {
+		t.Errorf("unexpected removed counts %v", report.Removed)
+	}
+
+	// The active agreement is kept, everything else is gone.
+	if eas, err := FindEstablishedAgreements(db, "Basic", []EAFilter{}); err != nil {
+		t.Fatal(err)
+	} else if len(eas) != 1 || eas[0].CurrentAgreementId != "a1" {
+		t.Errorf("expected only the active agreement to be kept, got %v", eas)
+	}
+	if els, err := FindAllEventLogs(db); err != nil || len(els) != 0 {
+		t.Errorf("expected no event logs, got %v %v", els, err)
+	}
+	if ses, err := FindSurfaceErrors(db); err != nil || len(ses) != 0 {
+		t.Errorf("expected no surfaced errors, got %v %v", ses, err)
+	}
+	if _, err := os.Stat(archive); !os.IsNotExist(err) {
+		t.Errorf("expected the event log archive to be removed")
+	}
+
+	// New event logs do not reuse the ids of the purged ones.
+	el := NewEventLog(SEVERITY_INFO, NewMessageMeta("after purge"), EC_DATABASE_ERROR, SRC_TYPE_DB, NewDatabaseEventSource())
+	if err := SaveEventLog(db, el); err != nil {
+		t.Fatal(err)
+	} else if el.Id != "4" {
+		t.Errorf("expected the event log id sequence to be kept, got id %v", el.Id)
+	}
+}
@@ -1,6 +1,7 @@
 package producer
 
 import (
+	"crypto/rsa"
 	"errors"
 	"fmt"
 	"github.com/boltdb/bolt"
@@ -22,6 +23,13 @@ type BasicProtocolHandler struct {
 
 func NewBasicProtocolHandler(name string, cfg *config.HorizonConfig, db *bolt.DB, pm *policy.PolicyManager, ec exchange.ExchangeContext) *BasicProtocolHandler {
 	if name == basicprotocol.PROTOCOL_NAME {
+		// Proposal replies are signed with the node's messaging key.
+		agreementPH := basicprotocol.NewProtocolHandler(cfg.Collaborators.HTTPClientFactory.NewHTTPClient(nil), pm)
+		agreementPH.SetSigningKeySource(func() (*rsa.PrivateKey, error) {
+			_, key, err := exchange.GetKeys("")
+			return key, err
+		})
+
 		return &BasicProtocolHandler{
 			BaseProducerProtocolHandler: &BaseProducerProtocolHandler{
 				name:   name,
@@ -30,7 +38,7 @@ func NewBasicProtocolHandler(name string, cfg *config.HorizonConfig, db *bolt.DB
 				config: cfg,
 				ec:     ec,
 			},
-			agreementPH: basicprotocol.NewProtocolHandler(cfg.Collaborators.HTTPClientFactory.NewHTTPClient(nil), pm),
+			agreementPH: agreementPH,
 		}
 	} else {
 		return nil
@@ -115,7 +123,7 @@ func (c *BasicProtocolHandler) HandleExtensionMessages(msg *events.ExchangeDevic
 		// This is a request to verify that an agreement exists.
 		exists := false
 		sendReply := true
-		agreements, err := persistence.FindEstablishedAgreements(c.db, c.Name(), []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(verify.AgreementId())})
+		agreements, err := persistence.FindActiveEstablishedAgreements(c.db, c.Name(), []persistence.EAFilter{persistence.IdEAFilter(verify.AgreementId())})
 		if err != nil {
 			glog.Errorf(BPHlogString(fmt.Sprintf("unable to retrieve agreement %v from database, error %v", verify.AgreementId(), err)))
 			sendReply = false
@@ -176,7 +176,7 @@ func (w *BaseProducerProtocolHandler) HandleProposal(ph abstractprotocol.Protoco
 
 	handled := false
 
-	if agAlreadyExists, err := persistence.FindEstablishedAgreements(w.db, w.Name(), []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(proposal.AgreementId())}); err != nil {
+	if agAlreadyExists, err := persistence.FindActiveEstablishedAgreements(w.db, w.Name(), []persistence.EAFilter{persistence.IdEAFilter(proposal.AgreementId())}); err != nil {
 		glog.Errorf(BPPHlogString(w.Name(), fmt.Sprintf("unable to retrieve agreements from database, error %v", err)))
 	} else if len(agAlreadyExists) != 0 {
 		glog.Errorf(BPPHlogString(w.Name(), fmt.Sprintf("Agreement %v already exists, ignoring proposal: %v", proposal.AgreementId(), proposal.ShortString())))
@@ -275,6 +275,10 @@ func (w *BaseProducerProtocolHandler) HandleProposal(ph abstractprotocol.Protoco
 				proposal.ConsumerId(),
 				proposal.Protocol())
 			handled = true
+		} else if err := w.verifyProposalSignature(proposal, exchangeMsg); err != nil {
+			glog.Errorf(BPPHlogString(w.Name(), fmt.Sprintf("rejecting proposal %v, %v", proposal.AgreementId(), err)))
+			err_log_event = fmt.Sprintf("Rejecting proposal, %v", err)
+			handled = true
 		} else if messageTarget, err := exchange.CreateMessageTarget(exchangeMsg.AgbotId, nil, exchangeMsg.AgbotPubKey, ""); err != nil {
 			glog.Errorf(BPPHlogString(w.Name(), fmt.Sprintf("error creating message target: %v", err)))
 			err_log_event = fmt.Sprintf("Error creating message target: %v", err)
@@ -324,6 +328,21 @@ func (w *BaseProducerProtocolHandler) HandleProposal(ph abstractprotocol.Protoco
 	return handled, nil, nil
 }
 
+// Verify the agbot's signature of the proposal before the proposal is acted on. Proposals from older agbots are not
+// signed, they are accepted unless signatures are required.
+func (w *BaseProducerProtocolHandler) verifyProposalSignature(proposal abstractprotocol.Proposal, exchangeMsg *exchange.DeviceMessage) error {
+	if pubKey, err := exchange.DemarshalPublicKey(exchangeMsg.AgbotPubKey); err != nil {
+		return errors.New(fmt.Sprintf("unable to demarshal the agbot %v public key, error: %v", exchangeMsg.AgbotId, err))
+	} else if signed, err := abstractprotocol.VerifyProposalSignature(proposal, pubKey); err != nil {
+		return errors.New(fmt.Sprintf("proposal signature from agbot %v is not valid: %v", exchangeMsg.AgbotId, err))
+	} else if !signed && w.config.Edge.RequireSignedProposals {
+		return errors.New(fmt.Sprintf("proposal from agbot %v is not signed and signed proposals are required", exchangeMsg.AgbotId))
+	} else if !signed {
+		glog.Warningf(BPPHlogString(w.Name(), fmt.Sprintf("proposal %v from agbot %v is not signed", proposal.AgreementId(), exchangeMsg.AgbotId)))
+	}
+	return nil
+}
+
 // This function gets the pattern and workload's signing keys and save them to anax
 func (w *BaseProducerProtocolHandler) saveSigningKeys(pol *policy.Policy) error {
 	// do nothing if the config does not allow using the certs from the org on the exchange
@@ -442,7 +461,7 @@ func (w *BaseProducerProtocolHandler) FindAgreementWithSameWorkload(ph abstractp
 		}
 	}
 
-	if ags, err := persistence.FindEstablishedAgreements(w.db, w.Name(), []persistence.EAFilter{notTerminated(), persistence.UnarchivedEAFilter()}); err != nil {
+	if ags, err := persistence.FindActiveEstablishedAgreements(w.db, w.Name(), []persistence.EAFilter{notTerminated()}); err != nil {
 		return nil, false, fmt.Errorf(BPPHlogString(w.Name(), fmt.Sprintf("error retrieving unarchived agreements from db: %v", err)))
 	} else {
 		for _, ag := range ags {
@@ -462,7 +481,7 @@ func (w *BaseProducerProtocolHandler) FindAgreementWithSameWorkload(ph abstractp
 func (w *BaseProducerProtocolHandler) PersistProposal(proposal abstractprotocol.Proposal, reply abstractprotocol.ProposalReply, tcPolicy *policy.Policy, protocolMsg string) {
 	if wi, err := persistence.NewWorkloadInfo(tcPolicy.Workloads[0].WorkloadURL, tcPolicy.Workloads[0].Org, tcPolicy.Workloads[0].Version, tcPolicy.Workloads[0].Arch); err != nil {
 		glog.Errorf(BPPHlogString(w.Name(), fmt.Sprintf("error creating workload info object from %v, error: %v", tcPolicy.Workloads[0], err)))
-	} else if _, err := persistence.NewEstablishedAgreement(w.db, tcPolicy.Header.Name, proposal.AgreementId(), proposal.ConsumerId(), protocolMsg, w.Name(), proposal.Version(), ConvertToServiceSpecs(tcPolicy.APISpecs), "", proposal.ConsumerId(), "", "", "", wi); err != nil {
+	} else if _, err := persistence.NewEstablishedAgreement(w.db, tcPolicy.Header.Name, proposal.AgreementId(), proposal.ConsumerId(), protocolMsg, w.Name(), proposal.Version(), ConvertToServiceSpecs(tcPolicy.APISpecs), proposal.Signature(), proposal.ConsumerId(), "", "", "", wi); err != nil {
 		glog.Errorf(BPPHlogString(w.Name(), fmt.Sprintf("error persisting new agreement: %v, error: %v", proposal.AgreementId(), err)))
 	}
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/open-horizon/edge-sync-service/core/security"
 	"net/http"
 	"strings"
+	"sync"
 )
 
 // FSSAuthenticate is the plugin for authenticating FSS (ESS) API calls from a service to anax.
@@ -16,9 +17,17 @@ type FSSAuthenticate struct {
 	nodeOrg   string
 	nodeID    string
 	nodeToken string
+	tokenLock sync.RWMutex
 	AuthMgr   *AuthenticationManager
 }
 
+// Change the node token that the ESS uses to communicate with the CSS.
+func (auth *FSSAuthenticate) SetNodeToken(token string) {
+	auth.tokenLock.Lock()
+	defer auth.tokenLock.Unlock()
+	auth.nodeToken = token
+}
+
 // Start initializes the HorizonAuthenticate plugin.
 func (auth *FSSAuthenticate) Start() {
 	glog.V(3).Infof(essALS("Starting"))
@@ -83,6 +92,8 @@ func (auth *FSSAuthenticate) KeyandSecretForURL(url string) (string, string) {
 
 	if strings.HasPrefix(url, common.HTTPCSSURL) {
 		id := common.Configuration.OrgID + "/" + common.Configuration.DestinationType + "/" + common.Configuration.DestinationID
+		auth.tokenLock.RLock()
+		defer auth.tokenLock.RUnlock()
 		glog.V(6).Infof(essALS(fmt.Sprintf("returning credentials %v %v", id, auth.nodeToken)))
 		return id, auth.nodeToken
 	}
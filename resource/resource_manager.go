@@ -24,6 +24,7 @@ type ResourceManager struct {
 	pattern string
 	id      string
 	token   string
+	auth    *FSSAuthenticate // the authenticator of the embedded ESS, once it is started
 }
 
 func NewResourceManager(cfg *config.HorizonConfig, org string, pattern string, id string, token string) *ResourceManager {
@@ -50,6 +51,14 @@ func (r *ResourceManager) NodeConfigUpdate(org string, pattern string, id string
 	r.token = token
 }
 
+// Switch the embedded ESS to the new exchange token of the node.
+func (r *ResourceManager) NodeTokenUpdate(token string) {
+	r.token = token
+	if r.auth != nil {
+		r.auth.SetNodeToken(token)
+	}
+}
+
 func (r ResourceManager) String() string {
 	return fmt.Sprintf("ResourceManager: Org %v"+
 		", Pattern: %v"+
@@ -149,7 +158,8 @@ func (r ResourceManager) StartFileSyncService(am *AuthenticationManager) error {
 	censorAndDumpConfig()
 
 	// Set the authenticator that we're going to use.
-	security.SetAuthentication(&FSSAuthenticate{nodeOrg: r.org, nodeID: r.id, nodeToken: r.token, AuthMgr: am})
+	r.auth = &FSSAuthenticate{nodeOrg: r.org, nodeID: r.id, nodeToken: r.token, AuthMgr: am}
+	security.SetAuthentication(r.auth)
 
 	// Start the embedded ESS.
 	if err := base.Start("", true); err != nil {
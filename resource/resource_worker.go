@@ -77,6 +77,10 @@ func (w *ResourceWorker) NewEvent(incoming events.Message) {
 		w.EC = worker.NewExchangeContext(fmt.Sprintf("%v/%v", msg.Org(), msg.DeviceId()), msg.Token(), w.Config.Edge.ExchangeURL, w.Config.GetCSSURL(), w.Config.Collaborators.HTTPClientFactory)
 		w.Commands <- NewNodeConfigCommand(msg)
 
+	case *events.NodeTokenUpdatedMessage:
+		msg, _ := incoming.(*events.NodeTokenUpdatedMessage)
+		w.rm.NodeTokenUpdate(msg.Token())
+
 	case *events.NodeShutdownCompleteMessage:
 		msg, _ := incoming.(*events.NodeShutdownCompleteMessage)
 		switch msg.Event().Id {
@@ -162,6 +162,14 @@ func (w *BaseWorker) GetCSSURL() string {
 	}
 }
 
+// Switch the exchange context of the worker to a new token. The context is replaced rather than changed, so that a
+// context that was already handed out keeps the token it was created with.
+func (w *BaseWorker) SetExchangeToken(token string) {
+	if w.EC != nil {
+		w.EC = NewExchangeContext(w.EC.Id, token, w.EC.URL, w.EC.CSSURL, w.EC.HTTPFactory)
+	}
+}
+
 func (w *BaseWorker) GetHTTPFactory() *config.HTTPClientFactory {
 	if w.EC != nil {
 		return w.EC.HTTPFactory
@@ -536,6 +544,14 @@ func eventHandler(incoming events.Message, workers *MessageHandlerRegistry) (str
 	// Dispatch the message to all workers
 	for name, worker := range workers.Handlers {
 		glog.V(5).Infof(mdLogString(fmt.Sprintf("Delivering message to %v", name)))
+
+		// The exchange token of every worker that has one is switched before the worker sees the event, so that the
+		// workers do not have to handle the event just to keep their exchange context current.
+		if msg, ok := incoming.(*events.NodeTokenUpdatedMessage); ok {
+			if setter, ok := (*worker).(interface{ SetExchangeToken(string) }); ok {
+				setter.SetExchangeToken(msg.Token())
+			}
+		}
 		(*worker).NewEvent(incoming)
 		glog.V(5).Infof(mdLogString(fmt.Sprintf("Delivered message to %v", name)))
 	}